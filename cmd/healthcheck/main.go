@@ -11,21 +11,29 @@
 // - TCP connection success = service is listening = container is healthy
 // - TCP connection failure = service is down = container needs restart
 //
-// For more detailed health checks (database connectivity, dependency status, etc.),
-// use application-level monitoring or Kubernetes liveness/readiness probes.
+// With --http the tool instead requests /health/ready, which checks the
+// database, store, cache subscriptions and request log backlog, and succeeds
+// only on a 2xx status. This suits Kubernetes readiness probes. The request is
+// written by hand over the TCP connection to keep net/http out of the binary.
 package main
 
 import (
+	"bufio"
+	"errors"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	defaultPort = "3001"
-	dialTimeout = 5 * time.Second
-	exitSuccess = 0
-	exitFailure = 1
+	defaultPort    = "3001"
+	dialTimeout    = 5 * time.Second
+	requestTimeout = 10 * time.Second
+	readyPath      = "/health/ready"
+	exitSuccess    = 0
+	exitFailure    = 1
 )
 
 func main() {
@@ -36,6 +44,14 @@ func main() {
 
 	address := buildAddress(port)
 
+	if hasFlag(os.Args[1:], "http") {
+		if err := checkHTTP(address, readyPath); err != nil {
+			_, _ = os.Stderr.WriteString("Health check failed: " + err.Error() + "\n")
+			os.Exit(exitFailure)
+		}
+		os.Exit(exitSuccess)
+	}
+
 	// Use TCP dial instead of HTTP GET to minimize binary size
 	// This checks if the service is listening on the port
 	conn, err := net.DialTimeout("tcp", address, dialTimeout)
@@ -57,3 +73,57 @@ func main() {
 func buildAddress(port string) string {
 	return "127.0.0.1:" + port
 }
+
+// hasFlag reports whether args contain the boolean flag name as -name or --name.
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "-"+name || arg == "--"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHTTP sends a plain HTTP/1.0 GET for path and succeeds on a 2xx status.
+func checkHTTP(address, path string) error {
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(requestTimeout)); err != nil {
+		return err
+	}
+	request := "GET " + path + " HTTP/1.0\r\nHost: " + address + "\r\nConnection: close\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return err
+	}
+
+	statusLine, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	code, err := parseStatusCode(statusLine)
+	if err != nil {
+		return err
+	}
+	if code < 200 || code > 299 {
+		return errors.New(path + " returned status " + strconv.Itoa(code))
+	}
+	return nil
+}
+
+// parseStatusCode extracts the status code from an HTTP status line such as
+// "HTTP/1.1 503 Service Unavailable".
+func parseStatusCode(statusLine string) (int, error) {
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return 0, errors.New("malformed HTTP status line")
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, errors.New("malformed HTTP status code")
+	}
+	return code, nil
+}
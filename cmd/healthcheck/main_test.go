@@ -2,6 +2,9 @@ package main
 
 import (
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -60,6 +63,67 @@ func TestBuildAddressUsesIPv4(t *testing.T) {
 	}
 }
 
+// TestHasFlag verifies both single and double dash flag forms
+func TestHasFlag(t *testing.T) {
+	if !hasFlag([]string{"--http"}, "http") || !hasFlag([]string{"-http"}, "http") {
+		t.Error("hasFlag should accept -http and --http")
+	}
+	if hasFlag([]string{"--https"}, "http") || hasFlag(nil, "http") {
+		t.Error("hasFlag should only match the exact flag")
+	}
+}
+
+// TestParseStatusCode verifies status line parsing
+func TestParseStatusCode(t *testing.T) {
+	tests := []struct {
+		line    string
+		code    int
+		wantErr bool
+	}{
+		{line: "HTTP/1.1 200 OK\r\n", code: 200},
+		{line: "HTTP/1.0 503 Service Unavailable\r\n", code: 503},
+		{line: "garbage\r\n", wantErr: true},
+		{line: "HTTP/1.1 abc OK\r\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		code, err := parseStatusCode(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStatusCode(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+		}
+		if code != tt.code {
+			t.Errorf("parseStatusCode(%q) = %d, want %d", tt.line, code, tt.code)
+		}
+	}
+}
+
+// TestCheckHTTP verifies that only 2xx readiness responses pass
+func TestCheckHTTP(t *testing.T) {
+	ready := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != readyPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write([]byte(`{"status":"ready"}`))
+	}))
+	defer server.Close()
+
+	address := strings.TrimPrefix(server.URL, "http://")
+	if err := checkHTTP(address, readyPath); err != nil {
+		t.Fatalf("checkHTTP() error = %v, want nil", err)
+	}
+
+	ready = false
+	err := checkHTTP(address, readyPath)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("checkHTTP() error = %v, want status 503", err)
+	}
+}
+
 func BenchmarkBuildAddress(b *testing.B) {
 	// Go 1.26 supports B.Loop and lets testing manage benchmark timing.
 	for b.Loop() {
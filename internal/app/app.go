@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,9 +14,13 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/db"
 	dbmigrations "gpt-load/internal/db/migrations"
+	"gpt-load/internal/declarative"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/leader"
+	"gpt-load/internal/logsink"
 	"gpt-load/internal/models"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
@@ -39,6 +44,8 @@ type App struct {
 	childGroupService        *services.ChildGroupService
 	proxyPoolService         *services.ProxyPoolService
 	logCleanupService        *services.LogCleanupService
+	encryptionRotation       *services.EncryptionRotationService
+	groupScheduleService     *services.GroupScheduleService
 	requestLogService        *services.RequestLogService
 	autoCheckinService       *sitemanagement.AutoCheckinService
 	balanceService           *sitemanagement.BalanceService
@@ -48,9 +55,17 @@ type App struct {
 	dynamicWeightManager     *services.DynamicWeightManager
 	dynamicWeightPersistence *services.DynamicWeightPersistence
 	httpClientManager        *httpclient.HTTPClientManager
+	logSinks                 *logsink.Manager
 	storage                  store.Store
 	db                       *gorm.DB
 	httpServer               *http.Server
+	elector                  *leader.Elector
+	reconciler               *declarative.Reconciler
+
+	// leaderMu guards the master-only services, which start and stop with leadership.
+	leaderMu              sync.Mutex
+	leaderServicesRunning bool
+	stopping              bool
 }
 
 // AppParams defines the dependencies for the App.
@@ -65,6 +80,8 @@ type AppParams struct {
 	ChildGroupService     *services.ChildGroupService
 	ProxyPoolService      *services.ProxyPoolService
 	LogCleanupService     *services.LogCleanupService
+	EncryptionRotation    *services.EncryptionRotationService
+	GroupScheduleService  *services.GroupScheduleService
 	RequestLogService     *services.RequestLogService
 	AutoCheckinService    *sitemanagement.AutoCheckinService
 	BalanceService        *sitemanagement.BalanceService
@@ -72,16 +89,59 @@ type AppParams struct {
 	KeyPoolProvider       *keypool.KeyProvider
 	ProxyServer           *proxy.ProxyServer
 	DynamicWeightManager  *services.DynamicWeightManager
+	SemanticCacheService  *services.SemanticCacheService
+	DebugCaptureService   *services.DebugCaptureService
+	LogSinks              *logsink.Manager
 	HTTPClientManager     *httpclient.HTTPClientManager // HTTP client manager for connection pool management
 	Storage               store.Store
 	DB                    *gorm.DB
+	Elector               *leader.Elector
+	Reconciler            *declarative.Reconciler
 	HubService            *centralizedmgmt.HubService // Hub service for centralized management
 }
 
+// SchemaModels returns the models whose tables are auto-migrated at startup.
+func SchemaModels() []any {
+	return []any{
+		&models.SystemSetting{},
+		&models.Group{},
+		&models.GroupSubGroup{},
+		&models.APIKey{},
+		&models.RequestLog{},
+		&models.GroupHourlyStat{},
+		&models.ModelTokenHourlyStat{},
+		&models.DynamicWeightMetric{},
+		&models.ProxyPoolItem{},
+		&models.SemanticCacheEntry{},
+		&models.DebugCapture{},
+		&sitemanagement.ManagedSite{},
+		&sitemanagement.ManagedSiteCheckinLog{},
+		&sitemanagement.ManagedSiteSetting{},
+		&encryption.DataKey{},
+		&models.GroupRevision{},
+		&models.GroupScheduledChange{},
+		&models.GroupExperiment{},
+	}
+}
+
+// DataModels returns the models of every table the application stores data
+// in, parents before the tables that reference them.
+func DataModels() []any {
+	return append(SchemaModels(),
+		&centralizedmgmt.HubAccessKey{},
+		&centralizedmgmt.HubModelGroupPriority{},
+		&centralizedmgmt.HubSettings{},
+		dbmigrations.DataMigrationModel(),
+	)
+}
+
 // NewApp is the constructor for App, with dependencies injected by dig.
 func NewApp(params AppParams) *App {
 	// Set dynamic weight manager on proxy server for adaptive load balancing
 	params.ProxyServer.SetDynamicWeightManager(params.DynamicWeightManager)
+	params.ProxyServer.SetSemanticCacheService(params.SemanticCacheService)
+	params.ProxyServer.SetDebugCaptureService(params.DebugCaptureService)
+	params.RequestLogService.SetSinks(params.LogSinks)
 
 	// Set Hub model pool cache invalidation callback on GroupService
 	// This ensures the Hub cache is invalidated when groups are created, updated, or deleted
@@ -152,6 +212,8 @@ func NewApp(params AppParams) *App {
 		childGroupService:        params.ChildGroupService,
 		proxyPoolService:         params.ProxyPoolService,
 		logCleanupService:        params.LogCleanupService,
+		encryptionRotation:       params.EncryptionRotation,
+		groupScheduleService:     params.GroupScheduleService,
 		requestLogService:        params.RequestLogService,
 		autoCheckinService:       params.AutoCheckinService,
 		balanceService:           params.BalanceService,
@@ -161,8 +223,11 @@ func NewApp(params AppParams) *App {
 		dynamicWeightManager:     params.DynamicWeightManager,
 		dynamicWeightPersistence: dwPersistence,
 		httpClientManager:        params.HTTPClientManager,
+		logSinks:                 params.LogSinks,
 		storage:                  params.Storage,
 		db:                       params.DB,
+		elector:                  params.Elector,
+		reconciler:               params.Reconciler,
 	}
 }
 
@@ -174,43 +239,26 @@ func (a *App) Start() error {
 	}
 	logrus.Info("i18n initialized successfully.")
 
-	// Master node performs initialization
-	if a.configManager.IsMaster() {
-		logrus.Info("Starting as Master Node.")
-
-		if err := a.storage.Clear(); err != nil {
-			return fmt.Errorf("cache cleanup failed: %w", err)
+	// With leader election any node may become the leader later, so every node
+	// migrates at startup under a shared lock. Otherwise only the master does.
+	if a.elector.Enabled() {
+		if err := a.elector.WithMigrationLock(context.Background(), a.migrate); err != nil {
+			return err
 		}
+	}
 
-		// Database migration
-		dbmigrations.HandleLegacyIndexes(a.db)
-		if err := a.db.AutoMigrate(
-			&models.SystemSetting{},
-			&models.Group{},
-			&models.GroupSubGroup{},
-			&models.APIKey{},
-			&models.RequestLog{},
-			&models.GroupHourlyStat{},
-			&models.ModelTokenHourlyStat{},
-			&models.DynamicWeightMetric{},
-			&models.ProxyPoolItem{},
-			&sitemanagement.ManagedSite{},
-			&sitemanagement.ManagedSiteCheckinLog{},
-			&sitemanagement.ManagedSiteSetting{},
-		); err != nil {
-			return fmt.Errorf("database auto-migration failed: %w", err)
-		}
-		// Data migration
-		if err := dbmigrations.MigrateDatabase(a.db); err != nil {
-			return fmt.Errorf("database data migration failed: %w", err)
-		}
-		logrus.Info("Database auto-migration completed.")
+	// Master node performs initialization
+	if a.elector.TryAcquire() {
+		logrus.Info("Starting as Master Node.")
 
-		// Sync child group upstream URLs to match parent groups
-		// This ensures: 1) correct PORT after changes, 2) upstream URLs match parent names (fixes stale data)
-		if err := a.childGroupService.SyncChildGroupUpstreams(context.Background()); err != nil {
-			logrus.WithError(err).Warn("Failed to sync child group upstream URLs")
-			// Non-fatal: continue startup even if sync fails
+		// With leader election the store is shared with running nodes and holds the lease.
+		if !a.elector.Enabled() {
+			if err := a.storage.Clear(); err != nil {
+				return fmt.Errorf("cache cleanup failed: %w", err)
+			}
+			if err := a.migrate(); err != nil {
+				return err
+			}
 		}
 
 		// Initialize system settings
@@ -219,7 +267,7 @@ func (a *App) Start() error {
 		}
 		logrus.Info("System settings initialized in DB.")
 
-		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
+		a.settingsManager.Initialize(a.storage, a.groupManager, a.elector.IsLeader)
 
 		// Initialize group cache BEFORE starting any background services that may hold write locks.
 		// This prevents startup failures on SQLite where a long-running writer (e.g., log cleanup)
@@ -228,13 +276,10 @@ func (a *App) Start() error {
 			return fmt.Errorf("failed to initialize group manager: %w", err)
 		}
 
-		// Load dynamic weight metrics from database and start persistence service.
-		// This ensures health scores are preserved across restarts.
-		if a.dynamicWeightManager != nil && a.dynamicWeightPersistence != nil {
-			if err := a.dynamicWeightPersistence.LoadFromDatabase(); err != nil {
-				logrus.WithError(err).Warn("Failed to load dynamic weight metrics from database (non-fatal)")
+		if configDir := a.configManager.GetEffectiveServerConfig().ConfigDir; configDir != "" {
+			if err := a.applyDeclarativeConfig(configDir); err != nil {
+				return err
 			}
-			a.dynamicWeightPersistence.Start()
 		}
 
 		// Load keys from database to Redis
@@ -243,16 +288,12 @@ func (a *App) Start() error {
 		}
 		logrus.Debug("API keys loaded into Redis cache by master.")
 
-		// Services that only start on Master node
-		a.requestLogService.Start()
-		a.autoCheckinService.Start()
-		a.balanceService.Start()
-		a.logCleanupService.Start()
-		a.proxyPoolService.StartGatewayProxyAutoTest()
-		a.cronChecker.Start()
+		a.leaderMu.Lock()
+		a.startLeaderServices()
+		a.leaderMu.Unlock()
 	} else {
 		logrus.Info("Starting as Slave Node.")
-		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
+		a.settingsManager.Initialize(a.storage, a.groupManager, a.elector.IsLeader)
 
 		// Initialize group cache early for slave nodes as well.
 		if err := a.groupManager.Initialize(); err != nil {
@@ -260,6 +301,14 @@ func (a *App) Start() error {
 		}
 	}
 
+	// Logs are recorded on every node, so every node publishes them to live viewers.
+	a.requestLogService.StartStreamPublisher()
+
+	a.elector.Start(func(bool) {
+		// Run outside the election loop so lease renewal never waits on service shutdown.
+		go a.reconcileLeaderServices()
+	})
+
 	// Display configuration and start all background services
 	a.configManager.DisplayServerConfig()
 
@@ -311,24 +360,19 @@ func (a *App) Stop(ctx context.Context) {
 
 	// Use the original total timeout context to continue shutting down other background services
 	stoppableServices := []func(context.Context){
+		a.proxyServer.StopShadowMirror,
+		a.requestLogService.StopStreamPublisher,
 		a.groupManager.Stop,
 		a.settingsManager.Stop,
 	}
 
-	if serverConfig.IsMaster {
-		stoppableServices = append(stoppableServices,
-			a.cronChecker.Stop,
-			a.autoCheckinService.Stop,
-			a.balanceService.Stop,
-			a.logCleanupService.Stop,
-			a.proxyPoolService.Stop,
-			a.requestLogService.Stop,
-		)
-		// Stop dynamic weight persistence service
-		if a.dynamicWeightPersistence != nil {
-			stoppableServices = append(stoppableServices, a.dynamicWeightPersistence.Stop)
-		}
+	a.leaderMu.Lock()
+	a.stopping = true
+	if a.leaderServicesRunning {
+		stoppableServices = append(stoppableServices, a.leaderServiceStops()...)
+		a.leaderServicesRunning = false
 	}
+	a.leaderMu.Unlock()
 
 	// Stop KeyProvider worker pool (runs on both master and slave)
 	logrus.Debug("Stopping KeyProvider worker pool...")
@@ -336,30 +380,19 @@ func (a *App) Stop(ctx context.Context) {
 	a.keyPoolProvider.Stop()
 	logrus.Debugf("KeyProvider worker pool stopped. (took %v)", time.Since(keyProviderStart))
 
-	var wg sync.WaitGroup
-	wg.Add(len(stoppableServices))
-
-	for _, stopFunc := range stoppableServices {
-		go func(stop func(context.Context)) {
-			defer wg.Done()
-			stop(ctx)
-		}(stopFunc)
-	}
-
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
 	bgServicesStart := time.Now()
-	select {
-	case <-done:
+	if stopServices(ctx, stoppableServices) {
 		logrus.Infof("All background services stopped. (took %v)", time.Since(bgServicesStart))
-	case <-ctx.Done():
+	} else {
 		logrus.Warnf("Shutdown timed out after %v, some services may not have stopped gracefully.", time.Since(bgServicesStart))
 	}
 
+	// Release the leader lease only after the master-only services have stopped.
+	a.elector.Stop(ctx)
+
+	// Drain external log sinks after the final request log flush.
+	a.logSinks.Stop(ctx)
+
 	// Close idle HTTP connections for all managed clients to free resources
 	if a.httpClientManager != nil {
 		logrus.Debug("Closing idle HTTP connections...")
@@ -398,6 +431,154 @@ func (a *App) Stop(ctx context.Context) {
 	logrus.Info("Server exited gracefully")
 }
 
+// migrate runs the schema and data migrations and prepares the data that the
+// master-only services rely on.
+func (a *App) migrate() error {
+	// Database migration
+	dbmigrations.HandleLegacyIndexes(a.db)
+	if err := a.db.AutoMigrate(SchemaModels()...); err != nil {
+		return fmt.Errorf("database auto-migration failed: %w", err)
+	}
+	// Data migration
+	if err := dbmigrations.MigrateDatabase(a.db); err != nil {
+		return fmt.Errorf("database data migration failed: %w", err)
+	}
+	logrus.Info("Database auto-migration completed.")
+
+	// Create the encryption keyring on the first start with ENCRYPTION_KEY
+	if err := a.encryptionRotation.Initialize(context.Background()); err != nil {
+		return fmt.Errorf("failed to initialize encryption keyring: %w", err)
+	}
+
+	// Sync child group upstream URLs to match parent groups
+	// This ensures: 1) correct PORT after changes, 2) upstream URLs match parent names (fixes stale data)
+	if err := a.childGroupService.SyncChildGroupUpstreams(context.Background()); err != nil {
+		logrus.WithError(err).Warn("Failed to sync child group upstream URLs")
+		// Non-fatal: continue startup even if sync fails
+	}
+	return nil
+}
+
+// applyDeclarativeConfig makes the database match the configuration directory.
+// Errors fail startup so that a broken configuration is never half applied
+// without anyone noticing.
+func (a *App) applyDeclarativeConfig(dir string) error {
+	spec, err := declarative.Load(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load declarative configuration: %w", err)
+	}
+	ctx := context.Background()
+	plan, err := a.reconciler.Plan(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to plan declarative configuration: %w", err)
+	}
+	if plan.Empty() {
+		logrus.Infof("Declarative configuration from %s is up to date.", dir)
+		return nil
+	}
+	var planText strings.Builder
+	_ = plan.Write(&planText)
+	logrus.Infof("Applying declarative configuration from %s:\n%s", dir, planText.String())
+	if err := a.reconciler.Apply(ctx, plan); err != nil {
+		return fmt.Errorf("failed to apply declarative configuration: %w", err)
+	}
+	return nil
+}
+
+// startLeaderServices starts the services that only run on the master node.
+// The caller must hold leaderMu.
+func (a *App) startLeaderServices() {
+	// Load dynamic weight metrics from database and start persistence service.
+	// This ensures health scores are preserved across restarts and leader changes.
+	if a.dynamicWeightManager != nil && a.dynamicWeightPersistence != nil {
+		if err := a.dynamicWeightPersistence.LoadFromDatabase(); err != nil {
+			logrus.WithError(err).Warn("Failed to load dynamic weight metrics from database (non-fatal)")
+		}
+		a.dynamicWeightPersistence.Start()
+	}
+
+	a.requestLogService.Start()
+	a.autoCheckinService.Start()
+	a.balanceService.Start()
+	a.logCleanupService.Start()
+	a.proxyPoolService.StartGatewayProxyAutoTest()
+	a.cronChecker.Start()
+	a.encryptionRotation.Start()
+	a.groupScheduleService.Start()
+	a.leaderServicesRunning = true
+}
+
+// leaderServiceStops returns the stop functions of the master-only services.
+func (a *App) leaderServiceStops() []func(context.Context) {
+	stops := []func(context.Context){
+		a.cronChecker.Stop,
+		a.autoCheckinService.Stop,
+		a.balanceService.Stop,
+		a.logCleanupService.Stop,
+		a.proxyPoolService.Stop,
+		a.requestLogService.Stop,
+		a.encryptionRotation.Stop,
+		a.groupScheduleService.Stop,
+	}
+	// Stop dynamic weight persistence service
+	if a.dynamicWeightPersistence != nil {
+		stops = append(stops, a.dynamicWeightPersistence.Stop)
+	}
+	return stops
+}
+
+// reconcileLeaderServices starts or stops the master-only services to match
+// the current leadership.
+func (a *App) reconcileLeaderServices() {
+	a.leaderMu.Lock()
+	defer a.leaderMu.Unlock()
+
+	isLeader := a.elector.IsLeader()
+	if a.stopping || isLeader == a.leaderServicesRunning {
+		return
+	}
+	if isLeader {
+		logrus.Info("Became leader, starting master-only services.")
+		a.startLeaderServices()
+		return
+	}
+
+	logrus.Warn("Lost leadership, stopping master-only services.")
+	timeout := time.Duration(a.configManager.GetEffectiveServerConfig().GracefulShutdownTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if !stopServices(ctx, a.leaderServiceStops()) {
+		logrus.Warn("Timed out stopping master-only services after losing leadership.")
+	}
+	a.leaderServicesRunning = false
+}
+
+// stopServices runs the stop functions concurrently and reports whether they
+// all returned before ctx was done.
+func stopServices(ctx context.Context, stops []func(context.Context)) bool {
+	var wg sync.WaitGroup
+	wg.Add(len(stops))
+	for _, stopFunc := range stops {
+		go func(stop func(context.Context)) {
+			defer wg.Done()
+			stop(ctx)
+		}(stopFunc)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // closeDBConnection handles GORM database shutdown.
 // It closes prepared statements first, then closes the database pool.
 func closeDBConnection(gormDB *gorm.DB, name string) {
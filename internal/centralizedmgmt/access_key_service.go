@@ -11,9 +11,11 @@ import (
 
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/guardrail"
 	"gpt-load/internal/utils"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -103,19 +105,25 @@ func (s *HubAccessKeyService) CreateAccessKey(ctx context.Context, params Create
 		return nil, "", fmt.Errorf("failed to serialize allowed models: %w", err)
 	}
 
+	guardrailPolicy, err := encodeGuardrailPolicy(params.GuardrailPolicy)
+	if err != nil {
+		return nil, "", err
+	}
+
 	key := &HubAccessKey{
-		Name:          name,
-		KeyHash:       keyHash,
-		KeyValue:      encryptedKey,
-		AllowedModels: allowedModelsJSON,
-		Enabled:       params.Enabled,
+		Name:            name,
+		KeyHash:         keyHash,
+		KeyValue:        encryptedKey,
+		AllowedModels:   allowedModelsJSON,
+		Enabled:         params.Enabled,
+		GuardrailPolicy: guardrailPolicy,
 	}
 
 	// Create the key using Exec to bypass GORM's default value handling
 	// This ensures Enabled=false is properly stored
 	result := s.db.WithContext(ctx).Exec(
-		"INSERT INTO hub_access_keys (name, key_hash, key_value, allowed_models, enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		key.Name, key.KeyHash, key.KeyValue, key.AllowedModels, key.Enabled, time.Now(), time.Now(),
+		"INSERT INTO hub_access_keys (name, key_hash, key_value, allowed_models, enabled, guardrail_policy, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		key.Name, key.KeyHash, key.KeyValue, key.AllowedModels, key.Enabled, key.GuardrailPolicy, time.Now(), time.Now(),
 	)
 	if result.Error != nil {
 		return nil, "", app_errors.ParseDBError(result.Error)
@@ -283,6 +291,14 @@ func (s *HubAccessKeyService) UpdateAccessKey(ctx context.Context, id uint, para
 		key.Enabled = *params.Enabled
 	}
 
+	if params.GuardrailPolicy != nil {
+		guardrailPolicy, err := encodeGuardrailPolicy(params.GuardrailPolicy)
+		if err != nil {
+			return nil, err
+		}
+		key.GuardrailPolicy = guardrailPolicy
+	}
+
 	if err := s.db.WithContext(ctx).Save(&key).Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
@@ -361,6 +377,7 @@ func (s *HubAccessKeyService) toDTO(key *HubAccessKey) *HubAccessKeyDTO {
 		AllowedModels:     allowedModels,
 		AllowedModelsMode: mode,
 		Enabled:           key.Enabled,
+		GuardrailPolicy:   decodeGuardrailPolicy(key.GuardrailPolicy),
 		UsageCount:        key.UsageCount,
 		LastUsedAt:        key.LastUsedAt,
 		CreatedAt:         key.CreatedAt,
@@ -426,10 +443,11 @@ func (s *HubAccessKeyService) ExportAccessKeys(ctx context.Context) ([]HubAccess
 		}
 
 		exports = append(exports, HubAccessKeyExportInfo{
-			Name:          key.Name,
-			KeyValue:      key.KeyValue, // Keep encrypted
-			AllowedModels: allowedModels,
-			Enabled:       key.Enabled,
+			Name:            key.Name,
+			KeyValue:        key.KeyValue, // Keep encrypted
+			AllowedModels:   allowedModels,
+			Enabled:         key.Enabled,
+			GuardrailPolicy: json.RawMessage(key.GuardrailPolicy),
 		})
 	}
 
@@ -495,8 +513,8 @@ func (s *HubAccessKeyService) ImportAccessKeys(ctx context.Context, tx *gorm.DB,
 		// Use Exec to bypass GORM's default value handling for Enabled field
 		now := time.Now()
 		result := tx.WithContext(ctx).Exec(
-			"INSERT INTO hub_access_keys (name, key_hash, key_value, allowed_models, enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
-			uniqueName, keyHash, keyInfo.KeyValue, allowedModelsJSON, keyInfo.Enabled, now, now,
+			"INSERT INTO hub_access_keys (name, key_hash, key_value, allowed_models, enabled, guardrail_policy, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			uniqueName, keyHash, keyInfo.KeyValue, allowedModelsJSON, keyInfo.Enabled, importedGuardrailPolicy(keyInfo.GuardrailPolicy), now, now,
 		)
 		if result.Error != nil {
 			skipped++
@@ -685,3 +703,44 @@ func (s *HubAccessKeyService) GetAccessKeyPlaintext(ctx context.Context, id uint
 
 	return plaintext, nil
 }
+
+// encodeGuardrailPolicy validates a key-level guardrail policy and returns its
+// stored form. Empty policies are stored as NULL.
+func encodeGuardrailPolicy(cfg *guardrail.Config) (datatypes.JSON, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	policy, err := guardrail.New(*cfg)
+	if err != nil {
+		return nil, app_errors.NewValidationError(err.Error())
+	}
+	if policy == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize guardrail policy: %w", err)
+	}
+	return datatypes.JSON(data), nil
+}
+
+// decodeGuardrailPolicy returns the stored policy, or nil when unset or unreadable.
+func decodeGuardrailPolicy(data datatypes.JSON) *guardrail.Config {
+	if len(data) == 0 {
+		return nil
+	}
+	var cfg guardrail.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil
+	}
+	return &cfg
+}
+
+// importedGuardrailPolicy drops invalid policies from import data instead of
+// failing the whole key.
+func importedGuardrailPolicy(raw json.RawMessage) datatypes.JSON {
+	if policy, err := guardrail.Parse(raw); err != nil || policy == nil {
+		return nil
+	}
+	return datatypes.JSON(raw)
+}
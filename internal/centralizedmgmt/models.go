@@ -1,8 +1,11 @@
 package centralizedmgmt
 
 import (
+	"encoding/json"
 	"time"
 
+	"gpt-load/internal/guardrail"
+
 	"gorm.io/datatypes"
 )
 
@@ -15,6 +18,8 @@ type HubAccessKey struct {
 	KeyValue      string         `gorm:"type:text;not null" json:"-"`                                                 // Encrypted, never exposed in JSON
 	AllowedModels datatypes.JSON `gorm:"type:json;not null" json:"allowed_models"`                                    // JSON array, empty array means all models
 	Enabled       bool           `gorm:"not null;default:true;index:idx_hub_access_keys_enabled" json:"enabled"`
+	// GuardrailPolicy is a guardrail.Config JSON object; NULL means no key-level policy.
+	GuardrailPolicy datatypes.JSON `gorm:"type:json" json:"guardrail_policy"`
 	// Usage statistics
 	UsageCount int64      `gorm:"not null;default:0" json:"usage_count"`                   // Total number of API calls
 	LastUsedAt *time.Time `gorm:"index:idx_hub_access_keys_last_used" json:"last_used_at"` // Last usage timestamp
@@ -30,24 +35,26 @@ func (HubAccessKey) TableName() string {
 // HubAccessKeyDTO is the API response format with masked key value.
 // Used for listing and displaying access keys without exposing actual key values.
 type HubAccessKeyDTO struct {
-	ID                uint       `json:"id"`
-	Name              string     `json:"name"`
-	MaskedKey         string     `json:"masked_key"`          // e.g., "sk-xxx...xxx"
-	AllowedModels     []string   `json:"allowed_models"`      // Parsed from JSON
-	AllowedModelsMode string     `json:"allowed_models_mode"` // "all" or "specific"
-	Enabled           bool       `json:"enabled"`
-	UsageCount        int64      `json:"usage_count"`  // Total API calls
-	LastUsedAt        *time.Time `json:"last_used_at"` // Last usage timestamp
-	CreatedAt         time.Time  `json:"created_at"`
-	UpdatedAt         time.Time  `json:"updated_at"`
+	ID                uint              `json:"id"`
+	Name              string            `json:"name"`
+	MaskedKey         string            `json:"masked_key"`          // e.g., "sk-xxx...xxx"
+	AllowedModels     []string          `json:"allowed_models"`      // Parsed from JSON
+	AllowedModelsMode string            `json:"allowed_models_mode"` // "all" or "specific"
+	Enabled           bool              `json:"enabled"`
+	GuardrailPolicy   *guardrail.Config `json:"guardrail_policy,omitempty"`
+	UsageCount        int64             `json:"usage_count"`  // Total API calls
+	LastUsedAt        *time.Time        `json:"last_used_at"` // Last usage timestamp
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
 // CreateAccessKeyParams defines parameters for creating a new Hub access key
 type CreateAccessKeyParams struct {
-	Name          string   `json:"name" binding:"required"`
-	KeyValue      string   `json:"key_value,omitempty"`      // Optional, auto-generated if empty
-	AllowedModels []string `json:"allowed_models,omitempty"` // Empty means all models
-	Enabled       bool     `json:"enabled"`
+	Name            string            `json:"name" binding:"required"`
+	KeyValue        string            `json:"key_value,omitempty"`      // Optional, auto-generated if empty
+	AllowedModels   []string          `json:"allowed_models,omitempty"` // Empty means all models
+	Enabled         bool              `json:"enabled"`
+	GuardrailPolicy *guardrail.Config `json:"guardrail_policy,omitempty"`
 }
 
 // UpdateAccessKeyParams defines parameters for updating a Hub access key
@@ -55,6 +62,8 @@ type UpdateAccessKeyParams struct {
 	Name          *string  `json:"name,omitempty"`
 	AllowedModels []string `json:"allowed_models,omitempty"`
 	Enabled       *bool    `json:"enabled,omitempty"`
+	// GuardrailPolicy replaces the key policy when set; an empty object clears it.
+	GuardrailPolicy *guardrail.Config `json:"guardrail_policy,omitempty"`
 }
 
 // MaskKeyValue masks a key value for display, showing only first and last few characters.
@@ -70,10 +79,11 @@ func MaskKeyValue(keyValue string) string {
 // HubAccessKeyExportInfo represents exported Hub access key data.
 // Key values remain encrypted (same as database storage) for security.
 type HubAccessKeyExportInfo struct {
-	Name          string   `json:"name"`
-	KeyValue      string   `json:"key_value"`      // Encrypted value (same as storage)
-	AllowedModels []string `json:"allowed_models"` // Parsed from JSON for readability
-	Enabled       bool     `json:"enabled"`
+	Name            string          `json:"name"`
+	KeyValue        string          `json:"key_value"`      // Encrypted value (same as storage)
+	AllowedModels   []string        `json:"allowed_models"` // Parsed from JSON for readability
+	Enabled         bool            `json:"enabled"`
+	GuardrailPolicy json.RawMessage `json:"guardrail_policy,omitempty"`
 }
 
 // BatchAccessKeyOperationParams defines parameters for batch operations on access keys.
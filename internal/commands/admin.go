@@ -0,0 +1,1043 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"gpt-load/internal/centralizedmgmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// taskPollInterval is how often background task progress is checked.
+var taskPollInterval = time.Second
+
+// adminCLI runs admin subcommands against an admin API client.
+type adminCLI struct {
+	client *adminClient
+	out    io.Writer
+	json   bool
+}
+
+// adminRunFunc runs a subcommand with its positional arguments.
+type adminRunFunc func(ctx context.Context, cli *adminCLI, args []string) error
+
+// adminUsage prints the help text of an admin command.
+func adminUsage(title string, lines []string, fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Println(title)
+		fmt.Println()
+		fmt.Println("Usage:")
+		for _, line := range lines {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+		fmt.Println("Arguments:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Notes:")
+		fmt.Println("  1. Without --server the command works on the local database (offline)")
+		fmt.Println("  2. Offline changes reach running nodes only when REDIS_DSN is shared")
+	}
+}
+
+// runAdminCommand parses flags, opens the client and runs a subcommand.
+// Flags may appear before or after positional arguments.
+func runAdminCommand(fs *flag.FlagSet, opts *adminOptions, args []string, run adminRunFunc) {
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+
+	client, err := openAdminClient(*opts)
+	if err != nil {
+		logrus.Fatalf("Failed to connect: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = run(ctx, &adminCLI{client: client, out: os.Stdout, json: opts.json}, positional)
+	stop()
+	client.Close()
+	if err != nil {
+		logrus.Fatalf("Command failed: %v", err)
+	}
+}
+
+// parseInterspersed parses flags that may be mixed with positional arguments
+// and returns the positional ones.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// newAdminFlagSet creates the flag set of a subcommand with the shared flags.
+func newAdminFlagSet(name string) (*flag.FlagSet, *adminOptions) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts := &adminOptions{}
+	opts.register(fs)
+	return fs, opts
+}
+
+// splitSubcommand returns the subcommand and its arguments, printing usage
+// when none is given.
+func splitSubcommand(args []string, usage func()) (string, []string) {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage()
+		os.Exit(2)
+	}
+	return args[0], args[1:]
+}
+
+// exactArgs wraps a run function that needs exactly n positional arguments.
+func exactArgs(n int, usage string, run adminRunFunc) adminRunFunc {
+	return func(ctx context.Context, cli *adminCLI, args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("usage: gpt-load %s", usage)
+		}
+		return run(ctx, cli, args)
+	}
+}
+
+// RunGroups handles the groups command entry point.
+func RunGroups(args []string) {
+	lines := []string{
+		"gpt-load groups list",
+		"gpt-load groups show <group>",
+		"gpt-load groups enable <group>",
+		"gpt-load groups disable <group>",
+		"gpt-load groups history <group>",
+		"gpt-load groups diff <group> <revision> [to-revision]",
+		"gpt-load groups rollback <group> <revision>",
+	}
+	sub, args := splitSubcommand(args, func() { printCommandList("GPT-Load Group Management", lines) })
+	fs, opts := newAdminFlagSet("groups " + sub)
+	fs.Usage = adminUsage("GPT-Load Group Management", lines, fs)
+
+	var run adminRunFunc
+	switch sub {
+	case "list":
+		run = exactArgs(0, "groups list", func(ctx context.Context, cli *adminCLI, _ []string) error {
+			return cli.listGroups(ctx)
+		})
+	case "show":
+		run = exactArgs(1, "groups show <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.showGroup(ctx, args[0])
+		})
+	case "enable", "disable":
+		enabled := sub == "enable"
+		run = exactArgs(1, "groups "+sub+" <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.setGroupEnabled(ctx, args[0], enabled)
+		})
+	case "history":
+		run = exactArgs(1, "groups history <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.groupHistory(ctx, args[0])
+		})
+	case "diff":
+		run = func(ctx context.Context, cli *adminCLI, args []string) error {
+			if len(args) < 2 || len(args) > 3 {
+				return fmt.Errorf("usage: gpt-load groups diff <group> <revision> [to-revision]")
+			}
+			return cli.diffGroupRevisions(ctx, args[0], args[1:])
+		}
+	case "rollback":
+		run = exactArgs(2, "groups rollback <group> <revision>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.rollbackGroup(ctx, args[0], args[1])
+		})
+	default:
+		unknownSubcommand("groups", sub, fs)
+	}
+	runAdminCommand(fs, opts, args, run)
+}
+
+// RunKeys handles the keys command entry point.
+func RunKeys(args []string) {
+	lines := []string{
+		"gpt-load keys import <group> [--file keys.txt]",
+		"gpt-load keys export <group> [--status all|active|invalid] [--out keys.txt]",
+		"gpt-load keys validate <group> [--status active|invalid]",
+		"gpt-load keys restore <group>",
+	}
+	sub, args := splitSubcommand(args, func() { printCommandList("GPT-Load Key Management", lines) })
+	fs, opts := newAdminFlagSet("keys " + sub)
+	fs.Usage = adminUsage("GPT-Load Key Management", lines, fs)
+
+	var run adminRunFunc
+	switch sub {
+	case "import":
+		file := fs.String("file", "-", "File with one key per line, - for stdin")
+		run = exactArgs(1, "keys import <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.importKeys(ctx, args[0], *file)
+		})
+	case "export":
+		status := fs.String("status", "all", "Keys to export: all, active or invalid")
+		out := fs.String("out", "-", "Output file, - for stdout")
+		run = exactArgs(1, "keys export <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.exportKeys(ctx, args[0], *status, *out)
+		})
+	case "validate":
+		status := fs.String("status", "", "Only validate keys with this status: active or invalid")
+		run = exactArgs(1, "keys validate <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.validateKeys(ctx, args[0], *status)
+		})
+	case "restore":
+		run = exactArgs(1, "keys restore <group>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.restoreKeys(ctx, args[0])
+		})
+	default:
+		unknownSubcommand("keys", sub, fs)
+	}
+	runAdminCommand(fs, opts, args, run)
+}
+
+// RunSettings handles the settings command entry point.
+func RunSettings(args []string) {
+	lines := []string{
+		"gpt-load settings get [key...]",
+		"gpt-load settings set <key>=<value> [<key>=<value>...]",
+	}
+	sub, args := splitSubcommand(args, func() { printCommandList("GPT-Load System Settings", lines) })
+	fs, opts := newAdminFlagSet("settings " + sub)
+	fs.Usage = adminUsage("GPT-Load System Settings", lines, fs)
+
+	var run adminRunFunc
+	switch sub {
+	case "get":
+		run = func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.getSettings(ctx, args)
+		}
+	case "set":
+		run = func(ctx context.Context, cli *adminCLI, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("usage: gpt-load settings set <key>=<value>")
+			}
+			return cli.setSettings(ctx, args)
+		}
+	default:
+		unknownSubcommand("settings", sub, fs)
+	}
+	runAdminCommand(fs, opts, args, run)
+}
+
+// RunLogs handles the logs command entry point.
+func RunLogs(args []string) {
+	lines := []string{
+		"gpt-load logs tail [-n 20] [--group name] [--follow]",
+	}
+	sub, args := splitSubcommand(args, func() { printCommandList("GPT-Load Request Logs", lines) })
+	fs, opts := newAdminFlagSet("logs " + sub)
+	fs.Usage = adminUsage("GPT-Load Request Logs", lines, fs)
+
+	var run adminRunFunc
+	switch sub {
+	case "tail":
+		limit := fs.Int("n", 20, "Number of recent logs to print")
+		group := fs.String("group", "", "Only show logs of this group")
+		follow := fs.Bool("follow", false, "Keep printing new logs until interrupted")
+		interval := fs.Duration("interval", 2*time.Second, "Poll interval with --follow")
+		run = exactArgs(0, "logs tail", func(ctx context.Context, cli *adminCLI, _ []string) error {
+			return cli.tailLogs(ctx, tailOptions{limit: *limit, group: *group, follow: *follow, interval: *interval})
+		})
+	default:
+		unknownSubcommand("logs", sub, fs)
+	}
+	runAdminCommand(fs, opts, args, run)
+}
+
+// RunHub handles the hub command entry point.
+func RunHub(args []string) {
+	lines := []string{
+		"gpt-load hub access-keys list",
+		"gpt-load hub access-keys create --name ci [--models gpt-4o,claude-3-5-sonnet] [--key value] [--disabled]",
+		"gpt-load hub access-keys revoke <name|id>",
+	}
+	usage := func() { printCommandList("GPT-Load Hub Access Keys", lines) }
+	resource, args := splitSubcommand(args, usage)
+	if resource != "access-keys" {
+		fmt.Printf("Unknown hub resource: %s\n", resource)
+		usage()
+		os.Exit(2)
+	}
+	sub, args := splitSubcommand(args, usage)
+	fs, opts := newAdminFlagSet("hub access-keys " + sub)
+	fs.Usage = adminUsage("GPT-Load Hub Access Keys", lines, fs)
+
+	var run adminRunFunc
+	switch sub {
+	case "list":
+		run = exactArgs(0, "hub access-keys list", func(ctx context.Context, cli *adminCLI, _ []string) error {
+			return cli.listAccessKeys(ctx)
+		})
+	case "create":
+		name := fs.String("name", "", "Access key name")
+		allowedModels := fs.String("models", "", "Comma-separated models the key may use (all when empty)")
+		keyValue := fs.String("key", "", "Key value (generated when empty)")
+		disabled := fs.Bool("disabled", false, "Create the key disabled")
+		run = exactArgs(0, "hub access-keys create --name <name>", func(ctx context.Context, cli *adminCLI, _ []string) error {
+			return cli.createAccessKey(ctx, *name, splitList(*allowedModels), *keyValue, !*disabled)
+		})
+	case "revoke":
+		run = exactArgs(1, "hub access-keys revoke <name|id>", func(ctx context.Context, cli *adminCLI, args []string) error {
+			return cli.revokeAccessKey(ctx, args[0])
+		})
+	default:
+		unknownSubcommand("hub access-keys", sub, fs)
+	}
+	runAdminCommand(fs, opts, args, run)
+}
+
+// RunExport handles the export command entry point.
+func RunExport(args []string) {
+	fs, opts := newAdminFlagSet("export")
+	mode := fs.String("mode", "encrypted", "Key format: encrypted or plain")
+	out := fs.String("out", "-", "Output file, - for stdout")
+	fs.Usage = adminUsage("GPT-Load System Export", []string{"gpt-load export [--mode plain] [--out backup.json]"}, fs)
+
+	runAdminCommand(fs, opts, args, exactArgs(0, "export", func(ctx context.Context, cli *adminCLI, _ []string) error {
+		return cli.exportSystem(ctx, *mode, *out)
+	}))
+}
+
+// RunImport handles the import command entry point.
+func RunImport(args []string) {
+	fs, opts := newAdminFlagSet("import")
+	mode := fs.String("mode", "", "Key format of the file: encrypted or plain (detected when empty)")
+	fs.Usage = adminUsage("GPT-Load System Import", []string{"gpt-load import backup.json"}, fs)
+
+	runAdminCommand(fs, opts, args, exactArgs(1, "import <file>", func(ctx context.Context, cli *adminCLI, args []string) error {
+		return cli.importSystem(ctx, args[0], *mode)
+	}))
+}
+
+// printCommandList prints the subcommands of an admin command.
+func printCommandList(title string, lines []string) {
+	fmt.Println(title)
+	fmt.Println()
+	fmt.Println("Usage:")
+	for _, line := range lines {
+		fmt.Println("  " + line)
+	}
+	fmt.Println()
+	fmt.Println("Use --help after a subcommand for its arguments.")
+}
+
+func unknownSubcommand(command, sub string, fs *flag.FlagSet) {
+	fmt.Printf("Unknown %s subcommand: %s\n\n", command, sub)
+	fs.Usage()
+	os.Exit(2)
+}
+
+// groupSummary is an entry of GET /api/groups.
+type groupSummary struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	GroupType   string `json:"group_type"`
+	ChannelType string `json:"channel_type"`
+	Enabled     bool   `json:"enabled"`
+	Sort        int    `json:"sort"`
+	TestModel   string `json:"test_model"`
+}
+
+// printJSON writes v as indented JSON.
+func (cli *adminCLI) printJSON(v any) error {
+	encoder := json.NewEncoder(cli.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// table returns a writer that aligns tab-separated columns.
+func (cli *adminCLI) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(cli.out, 0, 0, 2, ' ', 0)
+}
+
+func (cli *adminCLI) groups(ctx context.Context) ([]groupSummary, json.RawMessage, error) {
+	resp, err := cli.client.send(ctx, http.MethodGet, "/api/groups", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var groups []groupSummary
+	if err := json.Unmarshal(resp.Data, &groups); err != nil {
+		return nil, nil, err
+	}
+	return groups, resp.Data, nil
+}
+
+// findGroup resolves a group by name.
+func (cli *adminCLI) findGroup(ctx context.Context, name string) (*groupSummary, error) {
+	groups, _, err := cli.groups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].Name == name {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("group %s not found", name)
+}
+
+func (cli *adminCLI) listGroups(ctx context.Context) error {
+	groups, raw, err := cli.groups(ctx)
+	if err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(raw)
+	}
+
+	tw := cli.table()
+	fmt.Fprintln(tw, "ID\tNAME\tTYPE\tCHANNEL\tENABLED\tSORT")
+	for _, group := range groups {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%t\t%d\n", group.ID, group.Name, group.GroupType, group.ChannelType, group.Enabled, group.Sort)
+	}
+	return tw.Flush()
+}
+
+func (cli *adminCLI) showGroup(ctx context.Context, name string) error {
+	groups, raw, err := cli.groups(ctx)
+	if err != nil {
+		return err
+	}
+	var details []json.RawMessage
+	if err := json.Unmarshal(raw, &details); err != nil {
+		return err
+	}
+	var summary *groupSummary
+	var group json.RawMessage
+	for i := range groups {
+		if groups[i].Name == name {
+			summary, group = &groups[i], details[i]
+			break
+		}
+	}
+	if summary == nil {
+		return fmt.Errorf("group %s not found", name)
+	}
+
+	var stats struct {
+		KeyStats struct {
+			TotalKeys   int64 `json:"total_keys"`
+			ActiveKeys  int64 `json:"active_keys"`
+			InvalidKeys int64 `json:"invalid_keys"`
+		} `json:"key_stats"`
+	}
+	statsRaw, err := cli.client.send(ctx, http.MethodGet, fmt.Sprintf("/api/groups/%d/stats", summary.ID), nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(statsRaw.Data, &stats); err != nil {
+		return err
+	}
+
+	if cli.json {
+		return cli.printJSON(map[string]json.RawMessage{"group": group, "stats": statsRaw.Data})
+	}
+
+	tw := cli.table()
+	fmt.Fprintf(tw, "ID:\t%d\n", summary.ID)
+	fmt.Fprintf(tw, "Name:\t%s\n", summary.Name)
+	fmt.Fprintf(tw, "Display name:\t%s\n", summary.DisplayName)
+	fmt.Fprintf(tw, "Type:\t%s\n", summary.GroupType)
+	fmt.Fprintf(tw, "Channel:\t%s\n", summary.ChannelType)
+	fmt.Fprintf(tw, "Enabled:\t%t\n", summary.Enabled)
+	fmt.Fprintf(tw, "Test model:\t%s\n", summary.TestModel)
+	fmt.Fprintf(tw, "Keys:\t%d total, %d active, %d invalid\n",
+		stats.KeyStats.TotalKeys, stats.KeyStats.ActiveKeys, stats.KeyStats.InvalidKeys)
+	return tw.Flush()
+}
+
+func (cli *adminCLI) setGroupEnabled(ctx context.Context, name string, enabled bool) error {
+	group, err := cli.findGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/api/groups/%d/toggle-enabled", group.ID)
+	if err := cli.client.call(ctx, http.MethodPut, path, nil, map[string]bool{"enabled": enabled}, nil); err != nil {
+		return err
+	}
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	fmt.Fprintf(cli.out, "Group %s %s.\n", name, state)
+	return nil
+}
+
+func (cli *adminCLI) importKeys(ctx context.Context, groupName, file string) error {
+	group, err := cli.findGroup(ctx, groupName)
+	if err != nil {
+		return err
+	}
+	text, err := readInput(file)
+	if err != nil {
+		return err
+	}
+
+	resp, err := cli.client.send(ctx, http.MethodPost, "/api/keys/add-multiple", nil, map[string]any{
+		"group_id":  group.ID,
+		"keys_text": string(text),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Large imports run as a background task.
+	result := resp.Data
+	var task services.TaskStatus
+	if json.Unmarshal(resp.Data, &task) == nil && task.TaskType != "" {
+		if result, err = cli.waitForTask(ctx); err != nil {
+			return err
+		}
+	}
+	if cli.json {
+		return cli.printJSON(result)
+	}
+
+	var added services.AddKeysResult
+	if err := json.Unmarshal(result, &added); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Imported %d keys into %s (%d ignored, %d in group).\n",
+		added.AddedCount, groupName, added.IgnoredCount, added.TotalInGroup)
+	return nil
+}
+
+func (cli *adminCLI) exportKeys(ctx context.Context, groupName, status, file string) error {
+	group, err := cli.findGroup(ctx, groupName)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"group_id": {strconv.FormatUint(uint64(group.ID), 10)}, "status": {status}}
+
+	if file == "-" {
+		return cli.client.stream(ctx, "/api/keys/export", query, cli.out)
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := cli.client.stream(ctx, "/api/keys/export", query, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (cli *adminCLI) validateKeys(ctx context.Context, groupName, status string) error {
+	group, err := cli.findGroup(ctx, groupName)
+	if err != nil {
+		return err
+	}
+	body := map[string]any{"group_id": group.ID}
+	if status != "" {
+		body["status"] = status
+	}
+	if err := cli.client.call(ctx, http.MethodPost, "/api/keys/validate-group", nil, body, nil); err != nil {
+		return err
+	}
+
+	result, err := cli.waitForTask(ctx)
+	if err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(result)
+	}
+
+	var validation services.ManualValidationResult
+	if err := json.Unmarshal(result, &validation); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Validated %d keys in %s: %d valid, %d invalid.\n",
+		validation.TotalKeys, groupName, validation.ValidKeys, validation.InvalidKeys)
+	return nil
+}
+
+func (cli *adminCLI) restoreKeys(ctx context.Context, groupName string) error {
+	group, err := cli.findGroup(ctx, groupName)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.client.send(ctx, http.MethodPost, "/api/keys/restore-all-invalid", nil, map[string]any{"group_id": group.ID})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.out, resp.Message)
+	return nil
+}
+
+// waitForTask polls the background task until it finishes and returns its
+// result.
+func (cli *adminCLI) waitForTask(ctx context.Context) (json.RawMessage, error) {
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var status struct {
+			services.TaskStatus
+			Result json.RawMessage `json:"result,omitempty"`
+		}
+		if err := cli.client.call(ctx, http.MethodGet, "/api/tasks/status", nil, nil, &status); err != nil {
+			return nil, err
+		}
+		if !status.IsRunning {
+			if status.Error != "" {
+				return nil, fmt.Errorf("%s task failed: %s", status.TaskType, status.Error)
+			}
+			return status.Result, nil
+		}
+		if !cli.json && status.Total > 0 {
+			fmt.Fprintf(os.Stderr, "%s: %d/%d\n", status.TaskType, status.Processed, status.Total)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cli *adminCLI) settings(ctx context.Context) ([]models.SystemSettingInfo, error) {
+	var categories []models.CategorizedSettings
+	if err := cli.client.call(ctx, http.MethodGet, "/api/settings", nil, nil, &categories); err != nil {
+		return nil, err
+	}
+	var settings []models.SystemSettingInfo
+	for _, category := range categories {
+		settings = append(settings, category.Settings...)
+	}
+	return settings, nil
+}
+
+func (cli *adminCLI) getSettings(ctx context.Context, keys []string) error {
+	settings, err := cli.settings(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(keys) > 0 {
+		byKey := make(map[string]models.SystemSettingInfo, len(settings))
+		for _, setting := range settings {
+			byKey[setting.Key] = setting
+		}
+		selected := make([]models.SystemSettingInfo, 0, len(keys))
+		for _, key := range keys {
+			setting, ok := byKey[key]
+			if !ok {
+				return fmt.Errorf("unknown setting %s", key)
+			}
+			selected = append(selected, setting)
+		}
+		settings = selected
+	}
+
+	if cli.json {
+		values := make(map[string]any, len(settings))
+		for _, setting := range settings {
+			values[setting.Key] = setting.Value
+		}
+		return cli.printJSON(values)
+	}
+	if len(keys) == 1 {
+		fmt.Fprintln(cli.out, formatSettingValue(settings[0].Value))
+		return nil
+	}
+
+	tw := cli.table()
+	fmt.Fprintln(tw, "KEY\tVALUE\tCATEGORY")
+	for _, setting := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", setting.Key, formatSettingValue(setting.Value), setting.Category)
+	}
+	return tw.Flush()
+}
+
+func (cli *adminCLI) setSettings(ctx context.Context, assignments []string) error {
+	settings, err := cli.settings(ctx)
+	if err != nil {
+		return err
+	}
+	types := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		types[setting.Key] = setting.Type
+	}
+
+	update := make(map[string]any, len(assignments))
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return fmt.Errorf("invalid assignment %q, expected key=value", assignment)
+		}
+		// Settings hidden from the listing, such as legacy ones, are
+		// typed from their value; the server rejects unknown keys.
+		parsed, err := parseSettingValue(types[key], value)
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+		update[key] = parsed
+	}
+
+	if err := cli.client.call(ctx, http.MethodPut, "/api/settings", nil, update, nil); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(update))
+	for key := range update {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(cli.out, "Updated %s.\n", strings.Join(keys, ", "))
+	return nil
+}
+
+// parseSettingValue converts a command-line value to the JSON type the
+// settings API expects for the setting type.
+func parseSettingValue(settingType, value string) (any, error) {
+	switch settingType {
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return b, nil
+	case "string":
+		return value, nil
+	default:
+		if n, err := strconv.Atoi(value); err == nil {
+			return n, nil
+		}
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b, nil
+		}
+		return value, nil
+	}
+}
+
+func formatSettingValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// tailOptions configures logs tail.
+type tailOptions struct {
+	limit    int
+	group    string
+	follow   bool
+	interval time.Duration
+}
+
+func (cli *adminCLI) fetchLogs(ctx context.Context, opts tailOptions, since time.Time, pageSize int) ([]models.RequestLog, error) {
+	query := url.Values{"page": {"1"}, "page_size": {strconv.Itoa(pageSize)}}
+	if opts.group != "" {
+		query.Set("group_name", opts.group)
+	}
+	if !since.IsZero() {
+		query.Set("start_time", since.Format(time.RFC3339Nano))
+	}
+	var page struct {
+		Items []models.RequestLog `json:"items"`
+	}
+	if err := cli.client.call(ctx, http.MethodGet, "/api/logs", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (cli *adminCLI) tailLogs(ctx context.Context, opts tailOptions) error {
+	if opts.limit <= 0 {
+		opts.limit = 20
+	}
+	logs, err := cli.fetchLogs(ctx, opts, time.Time{}, opts.limit)
+	if err != nil {
+		return err
+	}
+
+	var tw *tabwriter.Writer
+	if !cli.json {
+		tw = cli.table()
+		fmt.Fprintln(tw, "TIME\tGROUP\tMODEL\tSTATUS\tDURATION\tKEY\tPATH\tERROR")
+	}
+
+	// The API returns newest first; print oldest first like tail.
+	var last time.Time
+	seen := make(map[string]bool)
+	print := func(entries []models.RequestLog) error {
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			if seen[entry.ID] {
+				continue
+			}
+			if entry.Timestamp.After(last) {
+				last = entry.Timestamp
+				seen = make(map[string]bool)
+			}
+			seen[entry.ID] = true
+			if err := cli.printLog(tw, entry); err != nil {
+				return err
+			}
+		}
+		if tw != nil {
+			return tw.Flush()
+		}
+		return nil
+	}
+	if err := print(logs); err != nil {
+		return err
+	}
+	if !opts.follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		since := last
+		if since.IsZero() {
+			since = time.Now().Add(-opts.interval)
+		}
+		// Logs sharing the last timestamp are fetched again and skipped.
+		logs, err := cli.fetchLogs(ctx, opts, since, 100)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+		if err := print(logs); err != nil {
+			return err
+		}
+	}
+}
+
+func (cli *adminCLI) printLog(tw *tabwriter.Writer, entry models.RequestLog) error {
+	entry.KeyValue = utils.MaskAPIKey(entry.KeyValue)
+	if cli.json {
+		entry.RequestBody = ""
+		entry.ResponseBody = ""
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cli.out, string(data))
+		return err
+	}
+	_, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%dms\t%s\t%s\t%s\n",
+		entry.Timestamp.Local().Format(time.DateTime), entry.GroupName, entry.Model, entry.StatusCode,
+		entry.Duration, entry.KeyValue, entry.RequestPath, truncate(entry.ErrorMessage, 80))
+	return err
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func (cli *adminCLI) accessKeys(ctx context.Context) ([]centralizedmgmt.HubAccessKeyDTO, json.RawMessage, error) {
+	resp, err := cli.client.send(ctx, http.MethodGet, "/hub/admin/access-keys", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var list struct {
+		AccessKeys []centralizedmgmt.HubAccessKeyDTO `json:"access_keys"`
+	}
+	if err := json.Unmarshal(resp.Data, &list); err != nil {
+		return nil, nil, err
+	}
+	return list.AccessKeys, resp.Data, nil
+}
+
+func (cli *adminCLI) listAccessKeys(ctx context.Context) error {
+	keys, raw, err := cli.accessKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(raw)
+	}
+
+	tw := cli.table()
+	fmt.Fprintln(tw, "ID\tNAME\tKEY\tENABLED\tMODELS")
+	for _, key := range keys {
+		allowed := "all"
+		if len(key.AllowedModels) > 0 {
+			allowed = strings.Join(key.AllowedModels, ",")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%t\t%s\n", key.ID, key.Name, key.MaskedKey, key.Enabled, allowed)
+	}
+	return tw.Flush()
+}
+
+func (cli *adminCLI) createAccessKey(ctx context.Context, name string, allowedModels []string, keyValue string, enabled bool) error {
+	if name == "" {
+		return fmt.Errorf("--name is required")
+	}
+	body := map[string]any{
+		"name":           name,
+		"allowed_models": allowedModels,
+		"enabled":        enabled,
+	}
+	if keyValue != "" {
+		body["key_value"] = keyValue
+	}
+
+	resp, err := cli.client.send(ctx, http.MethodPost, "/hub/admin/access-keys", nil, body)
+	if err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(resp.Data)
+	}
+
+	var created struct {
+		AccessKey centralizedmgmt.HubAccessKeyDTO `json:"access_key"`
+		KeyValue  string                          `json:"key_value"`
+	}
+	if err := json.Unmarshal(resp.Data, &created); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Created access key %s (id %d).\n", created.AccessKey.Name, created.AccessKey.ID)
+	fmt.Fprintf(cli.out, "Key: %s\n", created.KeyValue)
+	fmt.Fprintln(cli.out, "Store it now; it is not shown again.")
+	return nil
+}
+
+func (cli *adminCLI) revokeAccessKey(ctx context.Context, ref string) error {
+	keys, _, err := cli.accessKeys(ctx)
+	if err != nil {
+		return err
+	}
+	var target *centralizedmgmt.HubAccessKeyDTO
+	for i := range keys {
+		if keys[i].Name == ref || strconv.FormatUint(uint64(keys[i].ID), 10) == ref {
+			if target != nil {
+				return fmt.Errorf("%s matches more than one access key; use the id", ref)
+			}
+			target = &keys[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("access key %s not found", ref)
+	}
+
+	if err := cli.client.call(ctx, http.MethodDelete, fmt.Sprintf("/hub/admin/access-keys/%d", target.ID), nil, nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Revoked access key %s (id %d).\n", target.Name, target.ID)
+	return nil
+}
+
+func (cli *adminCLI) exportSystem(ctx context.Context, mode, file string) error {
+	if mode != "plain" && mode != "encrypted" {
+		return fmt.Errorf("--mode must be plain or encrypted")
+	}
+	resp, err := cli.client.send(ctx, http.MethodGet, "/api/system/export", url.Values{"mode": {mode}}, nil)
+	if err != nil {
+		return err
+	}
+
+	if file == "-" {
+		return cli.printJSON(resp.Data)
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(resp.Data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported system snapshot to %s.\n", file)
+	return nil
+}
+
+func (cli *adminCLI) importSystem(ctx context.Context, file, mode string) error {
+	data, err := readInput(file)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("%s is not valid JSON", file)
+	}
+
+	query := url.Values{"filename": {file}}
+	if mode != "" {
+		query.Set("mode", mode)
+	}
+	resp, err := cli.client.send(ctx, http.MethodPost, "/api/system/import", query, data)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.out, resp.Message)
+	return nil
+}
+
+// readInput reads a file, or stdin for "-".
+func readInput(file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(file)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
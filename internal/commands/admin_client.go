@@ -0,0 +1,295 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/container"
+	"gpt-load/internal/i18n"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// adminRequestTimeout bounds a single admin API request in online mode.
+const adminRequestTimeout = 2 * time.Minute
+
+// adminOptions holds the connection flags shared by the admin subcommands.
+type adminOptions struct {
+	server  string
+	authKey string
+	json    bool
+}
+
+// register adds the shared flags to a subcommand flag set.
+func (o *adminOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.server, "server", os.Getenv("GPT_LOAD_SERVER"), "Admin API base URL, e.g. http://127.0.0.1:3001 (defaults to GPT_LOAD_SERVER; offline when empty)")
+	fs.StringVar(&o.authKey, "auth-key", os.Getenv("AUTH_KEY"), "Admin key for online mode (defaults to AUTH_KEY)")
+	fs.BoolVar(&o.json, "json", false, "Print raw JSON instead of tables")
+}
+
+// adminClient talks to the admin API. Online it goes over the network to a
+// running node; offline the same requests are served in-process by a router
+// built on the local database, so both modes share validation and side effects.
+type adminClient struct {
+	baseURL string
+	authKey string
+	http    *http.Client
+	close   func()
+}
+
+// apiError is an error response of the admin API.
+type apiError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newAPIError decodes an error envelope, falling back to the raw body.
+func newAPIError(status int, body []byte) *apiError {
+	apiErr := &apiError{Status: status}
+	if json.Unmarshal(body, apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
+
+func (e *apiError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("request failed with status %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// openAdminClient connects in online mode when a server is set and builds the
+// offline client otherwise.
+func openAdminClient(opts adminOptions) (*adminClient, error) {
+	if opts.server != "" {
+		if opts.authKey == "" {
+			return nil, fmt.Errorf("--auth-key or AUTH_KEY is required with --server")
+		}
+		return &adminClient{
+			baseURL: strings.TrimRight(opts.server, "/"),
+			authKey: opts.authKey,
+			http:    &http.Client{Timeout: adminRequestTimeout},
+			close:   func() {},
+		}, nil
+	}
+	return openOfflineClient()
+}
+
+// openOfflineClient builds the application container against the configured
+// database and serves admin requests through its router without listening.
+func openOfflineClient() (*adminClient, error) {
+	// Keep request logging out of the command output.
+	logrus.SetOutput(os.Stderr)
+	logrus.SetLevel(logrus.WarnLevel)
+
+	cont, err := container.BuildContainer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build container: %w", err)
+	}
+	if err := cont.Provide(func() embed.FS { return embed.FS{} }); err != nil {
+		return nil, err
+	}
+	if err := cont.Provide(func() []byte { return nil }); err != nil {
+		return nil, err
+	}
+	if err := i18n.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize i18n: %w", err)
+	}
+
+	var client *adminClient
+	err = cont.Invoke(func(
+		engine *gin.Engine,
+		db *gorm.DB,
+		storage store.Store,
+		configManager types.ConfigManager,
+		settingsManager *config.SystemSettingsManager,
+		groupManager *services.GroupManager,
+		keyProvider *keypool.KeyProvider,
+	) error {
+		if !db.Migrator().HasTable(&models.Group{}) {
+			return fmt.Errorf("database is not initialized; start gpt-load once or use --server")
+		}
+		// This process never acts as the master node.
+		if err := settingsManager.Initialize(storage, groupManager, func() bool { return false }); err != nil {
+			return fmt.Errorf("failed to initialize system settings: %w", err)
+		}
+		if err := groupManager.Initialize(); err != nil {
+			return fmt.Errorf("failed to initialize group manager: %w", err)
+		}
+		client = &adminClient{
+			baseURL: "http://offline",
+			authKey: configManager.GetAuthConfig().Key,
+			http:    &http.Client{Transport: handlerTransport{handler: engine}},
+			close: func() {
+				ctx := context.Background()
+				groupManager.Stop(ctx)
+				settingsManager.Stop(ctx)
+				keyProvider.Stop()
+				_ = storage.Close()
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// handlerTransport serves requests with an in-process handler.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Handlers expect a server request, which always has a body.
+	serverReq := req.Clone(req.Context())
+	serverReq.RequestURI = req.URL.RequestURI()
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, serverReq)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// acceptLanguage derives the language of API messages from the locale
+// environment, defaulting to English.
+func acceptLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" || value == "C" || value == "POSIX" || strings.HasPrefix(value, "C.") {
+			continue
+		}
+		lang, _, _ := strings.Cut(value, ".")
+		return strings.ReplaceAll(lang, "_", "-")
+	}
+	return "en-US"
+}
+
+// Close releases the resources of an offline client.
+func (c *adminClient) Close() {
+	c.close()
+}
+
+// newRequest builds an authenticated admin request.
+func (c *adminClient) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.authKey)
+	req.Header.Set("Accept-Language", acceptLanguage())
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// apiResponse is the success envelope of the admin API.
+type apiResponse struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// call sends a request and decodes the data field of the success envelope
+// into out, which may be nil.
+func (c *adminClient) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+// send sends a request and returns the undecoded success envelope.
+func (c *adminClient) send(ctx context.Context, method, path string, query url.Values, body any) (*apiResponse, error) {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(resp.StatusCode, data)
+	}
+
+	var envelope apiResponse
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("unexpected response from %s: %w", path, err)
+	}
+	return &envelope, nil
+}
+
+// stream sends a request and copies the raw response body to w.
+func (c *adminClient) stream(ctx context.Context, path string, query url.Values, w io.Writer) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp.StatusCode, data)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,373 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAuthKey = "test-admin-key"
+
+// fakeAdminAPI records requests to a minimal admin API.
+type fakeAdminAPI struct {
+	mux      *http.ServeMux
+	requests []string
+	bodies   map[string]json.RawMessage
+}
+
+func newFakeAdminAPI() *fakeAdminAPI {
+	api := &fakeAdminAPI{mux: http.NewServeMux(), bodies: make(map[string]json.RawMessage)}
+	api.handle("GET /api/groups", func(r *http.Request) (int, any) {
+		return http.StatusOK, []map[string]any{
+			{"id": 1, "name": "openai", "display_name": "OpenAI", "group_type": "standard", "channel_type": "openai", "enabled": true, "sort": 1, "test_model": "gpt-4o-mini"},
+			{"id": 3, "name": "gemini", "group_type": "standard", "channel_type": "gemini", "enabled": false, "sort": 2},
+		}
+	})
+	return api
+}
+
+// handle registers a handler that answers with the success envelope, or the
+// error envelope for status codes >= 400.
+func (api *fakeAdminAPI) handle(pattern string, handler func(r *http.Request) (int, any)) {
+	api.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAuthKey || r.Header.Get("Accept-Language") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"code": "UNAUTHORIZED", "message": "Authentication failed"})
+			return
+		}
+		api.requests = append(api.requests, r.Method+" "+r.URL.RequestURI())
+		if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+			api.bodies[r.Method+" "+r.URL.Path] = body
+		}
+
+		status, data := handler(r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status >= http.StatusBadRequest {
+			_ = json.NewEncoder(w).Encode(data)
+			return
+		}
+		if message, ok := data.(string); ok {
+			_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "message": message})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"code": 0, "message": "Success", "data": data})
+	})
+}
+
+// cli returns an adminCLI that talks to the fake API in-process.
+func (api *fakeAdminAPI) cli(out io.Writer) *adminCLI {
+	return &adminCLI{
+		client: &adminClient{
+			baseURL: "http://offline",
+			authKey: testAuthKey,
+			http:    &http.Client{Transport: handlerTransport{handler: api.mux}},
+			close:   func() {},
+		},
+		out: out,
+	}
+}
+
+func TestAdminClient_OnlineErrorEnvelope(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("PUT /api/settings", func(r *http.Request) (int, any) {
+		return http.StatusBadRequest, map[string]string{"code": "VALIDATION_ERROR", "message": "request_timeout must be >= 1"}
+	})
+	server := httptest.NewServer(api.mux)
+	defer server.Close()
+
+	client, err := openAdminClient(adminOptions{server: server.URL + "/", authKey: testAuthKey})
+	require.NoError(t, err)
+	err = client.call(context.Background(), http.MethodPut, "/api/settings", nil, map[string]any{"request_timeout": 0}, nil)
+
+	var apiErr *apiError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+	assert.Equal(t, "VALIDATION_ERROR: request_timeout must be >= 1", err.Error())
+
+	_, err = openAdminClient(adminOptions{server: server.URL})
+	assert.ErrorContains(t, err, "--auth-key")
+
+	wrongKey, err := openAdminClient(adminOptions{server: server.URL, authKey: "wrong"})
+	require.NoError(t, err)
+	err = wrongKey.call(context.Background(), http.MethodGet, "/api/groups", nil, nil, nil)
+	assert.ErrorContains(t, err, "UNAUTHORIZED")
+}
+
+func TestGroupsCommands(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("GET /api/groups/{id}/stats", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"key_stats": map[string]any{"total_keys": 5, "active_keys": 4, "invalid_keys": 1}}
+	})
+	api.handle("PUT /api/groups/{id}/toggle-enabled", func(r *http.Request) (int, any) {
+		return http.StatusOK, nil
+	})
+
+	var out bytes.Buffer
+	cli := api.cli(&out)
+	ctx := context.Background()
+
+	require.NoError(t, cli.listGroups(ctx))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"ID", "NAME", "TYPE", "CHANNEL", "ENABLED", "SORT"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"3", "gemini", "standard", "gemini", "false", "2"}, strings.Fields(lines[2]))
+
+	out.Reset()
+	require.NoError(t, cli.showGroup(ctx, "openai"))
+	assert.Contains(t, out.String(), "4 active, 1 invalid")
+	assert.Contains(t, out.String(), "gpt-4o-mini")
+
+	out.Reset()
+	require.NoError(t, cli.setGroupEnabled(ctx, "gemini", true))
+	assert.Equal(t, "Group gemini enabled.\n", out.String())
+	assert.JSONEq(t, `{"enabled":true}`, string(api.bodies["PUT /api/groups/3/toggle-enabled"]))
+
+	assert.EqualError(t, cli.setGroupEnabled(ctx, "missing", false), "group missing not found")
+}
+
+func TestGroupRevisionCommands(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("GET /api/groups/{id}/revisions", func(r *http.Request) (int, any) {
+		return http.StatusOK, []map[string]any{
+			{"revision": 2, "source": "update", "created_at": "2026-10-18T08:00:00Z"},
+			{"revision": 1, "source": "baseline", "created_at": "2026-10-17T08:00:00Z"},
+		}
+	})
+	api.handle("GET /api/groups/{id}/revisions/{revision}/diff", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{
+			"group_id": 1, "from_revision": 1, "to_revision": 2,
+			"changes": []map[string]any{{"field": "test_model", "from": "gpt-4o-mini", "to": "gpt-4.1-mini"}},
+		}
+	})
+	api.handle("POST /api/groups/{id}/revisions/{revision}/rollback", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"group": map[string]any{"id": 1}, "revision": 3}
+	})
+
+	var out bytes.Buffer
+	cli := api.cli(&out)
+	ctx := context.Background()
+
+	require.NoError(t, cli.groupHistory(ctx, "openai"))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"2", "update", "2026-10-18T08:00:00Z"}, strings.Fields(lines[1]))
+
+	out.Reset()
+	require.NoError(t, cli.diffGroupRevisions(ctx, "openai", []string{"1", "2"}))
+	assert.Equal(t, []string{"test_model", "gpt-4o-mini", "gpt-4.1-mini"}, strings.Fields(strings.Split(out.String(), "\n")[1]))
+	assert.Contains(t, api.requests, "GET /api/groups/1/revisions/1/diff?to=2")
+
+	out.Reset()
+	require.NoError(t, cli.rollbackGroup(ctx, "openai", "1"))
+	assert.Equal(t, "Group openai rolled back to revision 1 (now revision 3).\n", out.String())
+
+	assert.EqualError(t, cli.rollbackGroup(ctx, "openai", "x"), `invalid revision "x"`)
+}
+
+func TestKeysImport_WaitsForBackgroundTask(t *testing.T) {
+	taskPollInterval = time.Millisecond
+	t.Cleanup(func() { taskPollInterval = time.Second })
+
+	api := newFakeAdminAPI()
+	api.handle("POST /api/keys/add-multiple", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"task_type": "KEY_IMPORT", "is_running": true, "total": 2}
+	})
+	polls := 0
+	api.handle("GET /api/tasks/status", func(r *http.Request) (int, any) {
+		polls++
+		if polls < 2 {
+			return http.StatusOK, map[string]any{"task_type": "KEY_IMPORT", "is_running": true, "processed": 1, "total": 2}
+		}
+		return http.StatusOK, map[string]any{
+			"task_type":  "KEY_IMPORT",
+			"is_running": false,
+			"result":     map[string]any{"added_count": 2, "ignored_count": 0, "total_in_group": 7},
+		}
+	})
+
+	file := filepath.Join(t.TempDir(), "keys.txt")
+	require.NoError(t, os.WriteFile(file, []byte("sk-a\nsk-b\n"), 0o600))
+
+	var out bytes.Buffer
+	require.NoError(t, api.cli(&out).importKeys(context.Background(), "openai", file))
+	assert.Equal(t, "Imported 2 keys into openai (0 ignored, 7 in group).\n", out.String())
+	assert.Equal(t, 2, polls)
+	assert.JSONEq(t, `{"group_id":1,"keys_text":"sk-a\nsk-b\n"}`, string(api.bodies["POST /api/keys/add-multiple"]))
+}
+
+func TestKeysValidate_ReportsTaskError(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("POST /api/keys/validate-group", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"task_type": "KEY_VALIDATION", "is_running": true}
+	})
+	api.handle("GET /api/tasks/status", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"task_type": "KEY_VALIDATION", "is_running": false, "error": "upstream unreachable"}
+	})
+
+	err := api.cli(io.Discard).validateKeys(context.Background(), "openai", "invalid")
+	assert.EqualError(t, err, "KEY_VALIDATION task failed: upstream unreachable")
+	assert.JSONEq(t, `{"group_id":1,"status":"invalid"}`, string(api.bodies["POST /api/keys/validate-group"]))
+}
+
+func TestKeysExportAndRestore(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.mux.HandleFunc("GET /api/keys/export", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "sk-a\nsk-b\n")
+		api.requests = append(api.requests, r.Method+" "+r.URL.RequestURI())
+	})
+	api.handle("POST /api/keys/restore-all-invalid", func(r *http.Request) (int, any) {
+		return http.StatusOK, "3 keys restored"
+	})
+
+	var out bytes.Buffer
+	cli := api.cli(&out)
+	require.NoError(t, cli.exportKeys(context.Background(), "gemini", "active", "-"))
+	assert.Equal(t, "sk-a\nsk-b\n", out.String())
+	assert.Contains(t, api.requests, "GET /api/keys/export?group_id=3&status=active")
+
+	out.Reset()
+	require.NoError(t, cli.restoreKeys(context.Background(), "openai"))
+	assert.Equal(t, "3 keys restored\n", out.String())
+}
+
+func settingsFixture(r *http.Request) (int, any) {
+	return http.StatusOK, []map[string]any{{
+		"category_name": "basic",
+		"settings": []map[string]any{
+			{"key": "request_timeout", "value": 600, "type": "int", "category": "basic"},
+			{"key": "enable_request_body_logging", "value": false, "type": "bool", "category": "basic"},
+			{"key": "proxy_keys", "value": "sk-proxy", "type": "string", "category": "basic"},
+		},
+	}}
+}
+
+func TestSettingsCommands(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("GET /api/settings", settingsFixture)
+	api.handle("PUT /api/settings", func(r *http.Request) (int, any) {
+		return http.StatusOK, nil
+	})
+
+	var out bytes.Buffer
+	cli := api.cli(&out)
+	ctx := context.Background()
+
+	require.NoError(t, cli.getSettings(ctx, []string{"request_timeout"}))
+	assert.Equal(t, "600\n", out.String())
+
+	out.Reset()
+	require.NoError(t, cli.setSettings(ctx, []string{"request_timeout=900", "enable_request_body_logging=true", "proxy_keys=a,b"}))
+	assert.Equal(t, "Updated enable_request_body_logging, proxy_keys, request_timeout.\n", out.String())
+	assert.JSONEq(t, `{"request_timeout":900,"enable_request_body_logging":true,"proxy_keys":"a,b"}`,
+		string(api.bodies["PUT /api/settings"]))
+
+	assert.EqualError(t, cli.setSettings(ctx, []string{"request_timeout=fast"}), `setting request_timeout: "fast" is not an integer`)
+	require.NoError(t, cli.setSettings(ctx, []string{"request_timeout=900", "legacy_flag=true"}))
+	assert.JSONEq(t, `{"request_timeout":900,"legacy_flag":true}`, string(api.bodies["PUT /api/settings"]))
+	assert.EqualError(t, cli.setSettings(ctx, []string{"request_timeout"}), `invalid assignment "request_timeout", expected key=value`)
+	assert.EqualError(t, cli.getSettings(ctx, []string{"nope"}), "unknown setting nope")
+}
+
+func TestLogsTail_PrintsOldestFirstWithMaskedKeys(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("GET /api/logs", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"items": []map[string]any{
+			{"id": "2", "timestamp": "2026-01-02T10:00:01Z", "group_name": "openai", "model": "gpt-4o", "status_code": 500, "key_value": "sk-abcdefghijklmnop", "error_message": "upstream\nfailed"},
+			{"id": "1", "timestamp": "2026-01-02T10:00:00Z", "group_name": "openai", "model": "gpt-4o", "status_code": 200, "key_value": "sk-abcdefghijklmnop"},
+		}}
+	})
+
+	var out bytes.Buffer
+	require.NoError(t, api.cli(&out).tailLogs(context.Background(), tailOptions{limit: 2, group: "openai"}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[1], " 200 ")
+	assert.Contains(t, lines[2], " 500 ")
+	assert.Contains(t, lines[2], "upstream failed")
+	assert.NotContains(t, out.String(), "sk-abcdefghijklmnop")
+	assert.Contains(t, api.requests, "GET /api/logs?group_name=openai&page=1&page_size=2")
+}
+
+func TestHubAccessKeyCommands(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("GET /hub/admin/access-keys", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"access_keys": []map[string]any{
+			{"id": 4, "name": "ci", "masked_key": "hk-ab...yz", "enabled": true},
+		}}
+	})
+	api.handle("POST /hub/admin/access-keys", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"access_key": map[string]any{"id": 5, "name": "ops"}, "key_value": "hk-generated"}
+	})
+	api.handle("DELETE /hub/admin/access-keys/{id}", func(r *http.Request) (int, any) {
+		return http.StatusOK, nil
+	})
+
+	var out bytes.Buffer
+	cli := api.cli(&out)
+	ctx := context.Background()
+
+	require.NoError(t, cli.createAccessKey(ctx, "ops", []string{"gpt-4o"}, "", true))
+	assert.Contains(t, out.String(), "Key: hk-generated")
+	assert.JSONEq(t, `{"name":"ops","allowed_models":["gpt-4o"],"enabled":true}`, string(api.bodies["POST /hub/admin/access-keys"]))
+
+	out.Reset()
+	require.NoError(t, cli.revokeAccessKey(ctx, "ci"))
+	assert.Equal(t, "Revoked access key ci (id 4).\n", out.String())
+	assert.Contains(t, api.requests, "DELETE /hub/admin/access-keys/4")
+
+	assert.EqualError(t, cli.revokeAccessKey(ctx, "missing"), "access key missing not found")
+	assert.EqualError(t, cli.createAccessKey(ctx, "", nil, "", true), "--name is required")
+}
+
+func TestSystemExportImport(t *testing.T) {
+	api := newFakeAdminAPI()
+	api.handle("GET /api/system/export", func(r *http.Request) (int, any) {
+		return http.StatusOK, map[string]any{"version": "2.0", "groups": []any{}}
+	})
+	api.handle("POST /api/system/import", func(r *http.Request) (int, any) {
+		return http.StatusOK, "Import successful"
+	})
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "backup.json")
+	var out bytes.Buffer
+	cli := api.cli(&out)
+	ctx := context.Background()
+
+	require.NoError(t, cli.exportSystem(ctx, "plain", file))
+	assert.Contains(t, api.requests, "GET /api/system/export?mode=plain")
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version":"2.0","groups":[]}`, string(data))
+
+	require.NoError(t, cli.importSystem(ctx, file, ""))
+	assert.Equal(t, "Import successful\n", out.String())
+	assert.JSONEq(t, string(data), string(api.bodies["POST /api/system/import"]))
+
+	assert.EqualError(t, cli.exportSystem(ctx, "zip", file), "--mode must be plain or encrypted")
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	file := fs.String("file", "-", "")
+	asJSON := fs.Bool("json", false, "")
+
+	positional, err := parseInterspersed(fs, []string{"openai", "--file", "keys.txt", "extra", "--json"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openai", "extra"}, positional)
+	assert.Equal(t, "keys.txt", *file)
+	assert.True(t, *asJSON)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/container"
+	"gpt-load/internal/declarative"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RunPlan handles the plan command entry point.
+func RunPlan(args []string) {
+	runDeclarative("plan", args, false)
+}
+
+// RunApply handles the apply command entry point.
+func RunApply(args []string) {
+	runDeclarative("apply", args, true)
+}
+
+// runDeclarative prints the changes needed to make the database match a
+// configuration directory and, when apply is set, makes them.
+func runDeclarative(name string, args []string, apply bool) {
+	cmd := flag.NewFlagSet(name, flag.ExitOnError)
+	dir := cmd.String("dir", os.Getenv("CONFIG_DIR"), "Configuration file or directory (defaults to CONFIG_DIR)")
+
+	cmd.Usage = func() {
+		fmt.Println("GPT-Load Declarative Configuration")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  Show pending changes: gpt-load plan --dir ./gpt-load.d")
+		fmt.Println("  Apply changes:        gpt-load apply --dir ./gpt-load.d")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		cmd.PrintDefaults()
+		fmt.Println()
+		fmt.Println("Notes:")
+		fmt.Println("  1. The database must have been initialized by starting gpt-load once")
+		fmt.Println("  2. Running nodes only pick up changes immediately when REDIS_DSN is shared")
+	}
+
+	if err := cmd.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if *dir == "" {
+		cmd.Usage()
+		os.Exit(2)
+	}
+
+	spec, err := declarative.Load(*dir)
+	if err != nil {
+		logrus.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+	defer utils.CloseLogger()
+
+	if err := cont.Invoke(func(
+		db *gorm.DB,
+		storage store.Store,
+		settingsManager *config.SystemSettingsManager,
+		groupManager *services.GroupManager,
+		reconciler *declarative.Reconciler,
+	) {
+		if err := executeDeclarative(db, storage, settingsManager, groupManager, reconciler, spec, apply); err != nil {
+			logrus.Fatalf("Configuration %s failed: %v", name, err)
+		}
+	}); err != nil {
+		logrus.Fatalf("Failed to execute %s: %v", name, err)
+	}
+}
+
+func executeDeclarative(
+	db *gorm.DB,
+	storage store.Store,
+	settingsManager *config.SystemSettingsManager,
+	groupManager *services.GroupManager,
+	reconciler *declarative.Reconciler,
+	spec *declarative.Spec,
+	apply bool,
+) error {
+	if !db.Migrator().HasTable(&models.Group{}) {
+		return fmt.Errorf("database is not initialized; start gpt-load once before applying configuration")
+	}
+
+	// The services invalidate these caches after each change, which requires
+	// them to be initialized. This process never acts as the master node.
+	if err := settingsManager.Initialize(storage, groupManager, func() bool { return false }); err != nil {
+		return fmt.Errorf("failed to initialize system settings: %w", err)
+	}
+	if err := groupManager.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize group manager: %w", err)
+	}
+	defer func() {
+		ctx := context.Background()
+		groupManager.Stop(ctx)
+		settingsManager.Stop(ctx)
+	}()
+
+	ctx := context.Background()
+	plan, err := reconciler.Plan(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := plan.Write(os.Stdout); err != nil {
+		return err
+	}
+	if !apply || plan.Empty() {
+		return nil
+	}
+
+	fmt.Println()
+	if err := reconciler.Apply(ctx, plan); err != nil {
+		return err
+	}
+	fmt.Printf("Apply complete: %s.\n", plan.Summary())
+	return nil
+}
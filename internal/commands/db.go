@@ -0,0 +1,264 @@
+package commands
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gpt-load/internal/app"
+	"gpt-load/internal/config"
+	"gpt-load/internal/db"
+	"gpt-load/internal/db/transfer"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// requestLogsTable is the table left out by --skip-request-logs.
+const requestLogsTable = "request_logs"
+
+// RunDB handles the db command entry point.
+func RunDB(args []string) {
+	lines := []string{
+		"gpt-load db backup [--out backup.jsonl.gz] [--dsn <dsn>]",
+		"gpt-load db restore <file> [--dsn <dsn>] [--clean]",
+		"gpt-load db migrate --from <dsn> --to <dsn> [--clean]",
+	}
+	sub, args := splitSubcommand(args, func() { printCommandList("GPT-Load Database Tools", lines) })
+	fs := flag.NewFlagSet("db "+sub, flag.ExitOnError)
+	fs.Usage = dbUsage(lines, fs)
+	skipLogs := fs.Bool("skip-request-logs", false, "Leave out the request_logs table")
+	batchSize := fs.Int("batch-size", transfer.DefaultBatchSize, "Rows read and written per batch")
+
+	var run func(ctx context.Context, opts transfer.Options, args []string) error
+	switch sub {
+	case "backup":
+		dsn := fs.String("dsn", "", "Database to back up (default: DATABASE_DSN)")
+		out := fs.String("out", "-", "Output file, - for stdout; a .gz suffix compresses it")
+		run = func(ctx context.Context, opts transfer.Options, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("usage: gpt-load db backup [--out <file>]")
+			}
+			return backupDatabase(ctx, *dsn, *out, opts)
+		}
+	case "restore":
+		dsn := fs.String("dsn", "", "Database to restore into (default: DATABASE_DSN)")
+		clean := fs.Bool("clean", false, "Delete existing rows in the target before restoring")
+		run = func(ctx context.Context, opts transfer.Options, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("usage: gpt-load db restore <file>")
+			}
+			return restoreDatabase(ctx, *dsn, args[0], *clean, opts)
+		}
+	case "migrate":
+		from := fs.String("from", "", "Source database DSN")
+		to := fs.String("to", "", "Target database DSN")
+		clean := fs.Bool("clean", false, "Delete existing rows in the target before copying")
+		run = func(ctx context.Context, opts transfer.Options, args []string) error {
+			if len(args) != 0 || *from == "" || *to == "" {
+				return fmt.Errorf("usage: gpt-load db migrate --from <dsn> --to <dsn>")
+			}
+			return migrateDatabase(ctx, *from, *to, *clean, opts)
+		}
+	default:
+		unknownSubcommand("db", sub, fs)
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+
+	opts := transfer.Options{
+		BatchSize: *batchSize,
+		Progress: func(table string, rows int64) {
+			fmt.Fprintf(os.Stderr, "%-28s %d rows\n", table, rows)
+		},
+	}
+	if *skipLogs {
+		opts.SkipTables = []string{requestLogsTable}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = run(ctx, opts, positional)
+	stop()
+	if err != nil {
+		logrus.Fatalf("Command failed: %v", err)
+	}
+}
+
+func dbUsage(lines []string, fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Println("GPT-Load Database Tools")
+		fmt.Println()
+		fmt.Println("Usage:")
+		for _, line := range lines {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+		fmt.Println("Arguments:")
+		fs.PrintDefaults()
+		fmt.Println()
+		fmt.Println("⚠️  Important Notes:")
+		fmt.Println("  1. Backups are dialect-neutral and restore into SQLite, MySQL or PostgreSQL")
+		fmt.Println("  2. Stop all gpt-load nodes before restore or migrate")
+		fmt.Println("  3. The target must be empty unless --clean is given")
+		fmt.Println("  4. Restored keys need the same ENCRYPTION_KEY as the source")
+	}
+}
+
+// openDatabase connects to dsn, or to DATABASE_DSN when dsn is empty.
+func openDatabase(dsn string) (*gorm.DB, func(), error) {
+	if dsn == "" {
+		configManager, err := config.NewManager(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		dsn = configManager.GetDatabaseConfig().DSN
+	}
+	gormDB, err := db.Open(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeDB := func() {
+		if sqlDB, err := gormDB.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	}
+	return gormDB, closeDB, nil
+}
+
+// checkSource makes sure a source database was initialized by gpt-load, so a
+// mistyped SQLite path is not read as an empty database.
+func checkSource(source *gorm.DB) error {
+	if !source.Migrator().HasTable(&models.Group{}) {
+		return fmt.Errorf("source database has no gpt-load tables")
+	}
+	return nil
+}
+
+// backupDatabase writes a backup of dsn to out.
+func backupDatabase(ctx context.Context, dsn, out string, opts transfer.Options) (err error) {
+	source, closeSource, err := openDatabase(dsn)
+	if err != nil {
+		return err
+	}
+	defer closeSource()
+	if err := checkSource(source); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				_ = os.Remove(out)
+			}
+		}()
+		w = file
+	}
+	if strings.HasSuffix(out, ".gz") {
+		gz := gzip.NewWriter(w)
+		defer func() {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gz
+	}
+
+	start := time.Now()
+	result, err := transfer.Backup(ctx, source, w, app.DataModels(), opts)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Backed up %d rows from %d tables in %s", result.Rows(), len(result.Tables), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// restoreDatabase loads the backup in file into dsn.
+func restoreDatabase(ctx context.Context, dsn, file string, clean bool, opts transfer.Options) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to open compressed backup: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	target, closeTarget, err := openDatabase(dsn)
+	if err != nil {
+		return err
+	}
+	defer closeTarget()
+
+	dataModels := app.DataModels()
+	if err := transfer.PrepareTarget(ctx, target, dataModels, clean); err != nil {
+		return err
+	}
+	start := time.Now()
+	result, err := transfer.Restore(ctx, target, r, dataModels, opts)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Restored %d rows into %d tables in %s", result.Rows(), len(result.Tables), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// migrateDatabase copies all tables from one database to another.
+func migrateDatabase(ctx context.Context, from, to string, clean bool, opts transfer.Options) error {
+	if from == to {
+		return fmt.Errorf("source and target are the same database")
+	}
+	source, closeSource, err := openDatabase(from)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer closeSource()
+	if err := checkSource(source); err != nil {
+		return err
+	}
+	target, closeTarget, err := openDatabase(to)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	defer closeTarget()
+
+	dataModels := app.DataModels()
+	if err := transfer.PrepareTarget(ctx, target, dataModels, clean); err != nil {
+		return err
+	}
+	start := time.Now()
+	result, err := transfer.Copy(ctx, source, target, dataModels, opts)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Migrated %d rows from %s to %s in %s", result.Rows(),
+		db.Dialect(from), db.Dialect(to), time.Since(start).Round(time.Millisecond))
+	return nil
+}
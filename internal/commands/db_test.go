@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gpt-load/internal/app"
+	"gpt-load/internal/db"
+	"gpt-load/internal/db/transfer"
+	"gpt-load/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func seedDatabase(t *testing.T, dsn string) {
+	t.Helper()
+	source, closeSource, err := openDatabase(dsn)
+	require.NoError(t, err)
+	defer closeSource()
+
+	require.NoError(t, source.AutoMigrate(app.DataModels()...))
+	group := models.Group{Name: "openai", GroupType: "standard", ChannelType: "openai", TestModel: "m", Upstreams: datatypes.JSON(`[{"url":"https://api.openai.com","weight":1}]`)}
+	require.NoError(t, source.Create(&group).Error)
+	require.NoError(t, source.Create(&models.APIKey{GroupID: group.ID, KeyValue: "sk-a", KeyHash: "a", Status: models.KeyStatusActive}).Error)
+	require.NoError(t, source.Create(&models.SystemSetting{SettingKey: "request_timeout", SettingValue: "600"}).Error)
+	require.NoError(t, source.Create(&models.RequestLog{ID: "log-1", GroupID: group.ID, GroupName: "openai"}).Error)
+}
+
+func countTable(t *testing.T, dsn string, model any) int64 {
+	t.Helper()
+	target, closeTarget, err := openDatabase(dsn)
+	require.NoError(t, err)
+	defer closeTarget()
+	var count int64
+	require.NoError(t, target.Model(model).Count(&count).Error)
+	return count
+}
+
+func TestBackupAndRestoreDatabase(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.db")
+	seedDatabase(t, source)
+
+	ctx := context.Background()
+	backup := filepath.Join(dir, "backup.jsonl.gz")
+	require.NoError(t, backupDatabase(ctx, source, backup, transfer.Options{}))
+
+	target := filepath.Join(dir, "target.db")
+	require.NoError(t, restoreDatabase(ctx, target, backup, false, transfer.Options{}))
+	assert.Equal(t, int64(1), countTable(t, target, &models.Group{}))
+	assert.Equal(t, int64(1), countTable(t, target, &models.APIKey{}))
+	assert.Equal(t, int64(1), countTable(t, target, &models.SystemSetting{}))
+	assert.Equal(t, int64(1), countTable(t, target, &models.RequestLog{}))
+
+	err := restoreDatabase(ctx, target, backup, false, transfer.Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not empty")
+	require.NoError(t, restoreDatabase(ctx, target, backup, true, transfer.Options{}))
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.db")
+	seedDatabase(t, source)
+
+	ctx := context.Background()
+	target := filepath.Join(dir, "target.db")
+	opts := transfer.Options{SkipTables: []string{requestLogsTable}}
+	require.NoError(t, migrateDatabase(ctx, source, target, false, opts))
+
+	assert.Equal(t, db.DialectSQLite, db.Dialect(target))
+	assert.Equal(t, int64(1), countTable(t, target, &models.APIKey{}))
+	assert.Zero(t, countTable(t, target, &models.RequestLog{}))
+
+	require.ErrorContains(t, migrateDatabase(ctx, source, source, false, opts), "same database")
+	require.ErrorContains(t, migrateDatabase(ctx, filepath.Join(dir, "missing.db"), filepath.Join(dir, "other.db"), false, opts), "no gpt-load tables")
+}
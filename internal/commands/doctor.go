@@ -0,0 +1,293 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gpt-load/internal/app"
+	"gpt-load/internal/container"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Doctor check results.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// maxOrphanProbe bounds how many deleted group IDs are probed in the store.
+const maxOrphanProbe = 10000
+
+// doctorCheck is the result of one diagnostic.
+type doctorCheck struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Summary string   `json:"summary"`
+	Details []string `json:"details,omitempty"`
+}
+
+// doctor diagnoses a deployment from its database and store.
+type doctor struct {
+	db            *gorm.DB
+	store         store.Store
+	encryptionKey string
+	// sharedStore is set when the store outlives processes (Redis), so its
+	// contents can be compared with the database.
+	sharedStore bool
+}
+
+// RunDoctor handles the doctor command entry point.
+func RunDoctor(args []string) {
+	cmd := flag.NewFlagSet("doctor", flag.ExitOnError)
+	asJSON := cmd.Bool("json", false, "Print results as JSON")
+
+	cmd.Usage = func() {
+		fmt.Println("GPT-Load Doctor")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gpt-load doctor [--json]")
+		fmt.Println()
+		fmt.Println("Checks:")
+		fmt.Println("  encryption   ENCRYPTION_KEY matches the stored keys")
+		fmt.Println("  schema       Database tables and columns are up to date")
+		fmt.Println("  store        Key pools in Redis match the database")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		cmd.PrintDefaults()
+		fmt.Println()
+		fmt.Println("The command exits with status 1 when a check fails.")
+	}
+
+	if err := cmd.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+
+	// Keep log output out of the report.
+	logrus.SetOutput(os.Stderr)
+	logrus.SetLevel(logrus.WarnLevel)
+
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	var checks []doctorCheck
+	if err := cont.Invoke(func(db *gorm.DB, storage store.Store, configManager types.ConfigManager) {
+		defer storage.Close()
+		d := &doctor{
+			db:            db,
+			store:         storage,
+			encryptionKey: configManager.GetEncryptionKey(),
+			sharedStore:   configManager.GetRedisDSN() != "",
+		}
+		checks = d.run(context.Background())
+	}); err != nil {
+		logrus.Fatalf("Failed to run doctor: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(checks); err != nil {
+			logrus.Fatalf("Failed to write report: %v", err)
+		}
+	} else if err := writeDoctorReport(os.Stdout, checks); err != nil {
+		logrus.Fatalf("Failed to write report: %v", err)
+	}
+
+	for _, check := range checks {
+		if check.Status == checkFail {
+			os.Exit(1)
+		}
+	}
+}
+
+// run performs all checks in order.
+func (d *doctor) run(ctx context.Context) []doctorCheck {
+	return []doctorCheck{
+		d.checkEncryption(ctx),
+		d.checkSchema(),
+		d.checkStore(),
+	}
+}
+
+// checkEncryption reports whether ENCRYPTION_KEY matches the stored keys,
+// using the same sampling as the dashboard warning.
+func (d *doctor) checkEncryption(ctx context.Context) doctorCheck {
+	check := doctorCheck{Name: "encryption"}
+	if !d.db.Migrator().HasTable(&models.APIKey{}) {
+		check.Status, check.Summary = checkSkip, "no api_keys table"
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	scenario, err := encryption.DetectScenario(ctx, d.db, d.encryptionKey)
+	if err != nil {
+		check.Status, check.Summary = checkFail, fmt.Sprintf("failed to sample keys: %v", err)
+		return check
+	}
+
+	switch scenario {
+	case encryption.ScenarioDataNotEncrypted:
+		check.Status = checkFail
+		check.Summary = "ENCRYPTION_KEY is set but stored keys are not encrypted"
+		check.Details = []string{"run: gpt-load migrate-keys --to <ENCRYPTION_KEY>"}
+	case encryption.ScenarioKeyNotConfigured:
+		check.Status = checkFail
+		check.Summary = "stored keys are encrypted but ENCRYPTION_KEY is not set"
+		check.Details = []string{"set ENCRYPTION_KEY to the key the data was encrypted with"}
+	case encryption.ScenarioKeyMismatch:
+		check.Status = checkFail
+		check.Summary = "ENCRYPTION_KEY does not decrypt the stored keys"
+		check.Details = []string{"set the original key, or run: gpt-load migrate-keys --from <old> --to <new>"}
+	default:
+		check.Status = checkOK
+		if d.encryptionKey == "" {
+			check.Summary = "encryption disabled, stored keys are plain"
+		} else {
+			check.Summary = "ENCRYPTION_KEY matches the stored keys"
+		}
+	}
+	return check
+}
+
+// checkSchema reports tables and columns that the next master startup would
+// still have to create.
+func (d *doctor) checkSchema() doctorCheck {
+	check := doctorCheck{Name: "schema"}
+	migrator := d.db.Migrator()
+
+	for _, model := range app.DataModels() {
+		stmt := &gorm.Statement{DB: d.db}
+		if err := stmt.Parse(model); err != nil {
+			check.Details = append(check.Details, fmt.Sprintf("cannot parse %T: %v", model, err))
+			continue
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(model) {
+			check.Details = append(check.Details, "missing table "+table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				check.Details = append(check.Details, fmt.Sprintf("missing column %s.%s", table, field.DBName))
+			}
+		}
+	}
+
+	if len(check.Details) == 0 {
+		check.Status, check.Summary = checkOK, "all tables and columns exist"
+		return check
+	}
+	check.Status = checkFail
+	check.Summary = fmt.Sprintf("%d pending migrations; start the master node once to apply them", len(check.Details))
+	return check
+}
+
+// checkStore compares the active key pools in the store with the database
+// and looks for pools of groups that no longer exist.
+func (d *doctor) checkStore() doctorCheck {
+	check := doctorCheck{Name: "store"}
+	if !d.sharedStore {
+		check.Status, check.Summary = checkSkip, "in-memory store; key pools are rebuilt at startup"
+		return check
+	}
+	if !d.db.Migrator().HasTable(&models.Group{}) || !d.db.Migrator().HasTable(&models.APIKey{}) {
+		check.Status, check.Summary = checkSkip, "database is not initialized"
+		return check
+	}
+
+	var groups []models.Group
+	if err := d.db.Select("id", "name").Order("id").Find(&groups).Error; err != nil {
+		check.Status, check.Summary = checkFail, fmt.Sprintf("failed to load groups: %v", err)
+		return check
+	}
+	var counts []struct {
+		GroupID uint
+		Count   int64
+	}
+	if err := d.db.Model(&models.APIKey{}).
+		Select("group_id, COUNT(*) AS count").
+		Where("status = ?", models.KeyStatusActive).
+		Group("group_id").
+		Scan(&counts).Error; err != nil {
+		check.Status, check.Summary = checkFail, fmt.Sprintf("failed to count keys: %v", err)
+		return check
+	}
+	active := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		active[c.GroupID] = c.Count
+	}
+
+	existing := make(map[uint]bool, len(groups))
+	var maxID uint
+	for _, group := range groups {
+		existing[group.ID] = true
+		maxID = max(maxID, group.ID)
+
+		pooled, err := d.store.LLen(activeKeysListKey(group.ID))
+		if err != nil {
+			check.Details = append(check.Details, fmt.Sprintf("group %s: %v", group.Name, err))
+			continue
+		}
+		if pooled != active[group.ID] {
+			check.Details = append(check.Details, fmt.Sprintf("group %s: %d keys in the store pool, %d active in the database",
+				group.Name, pooled, active[group.ID]))
+		}
+	}
+
+	// Pools of deleted groups are left behind when a deletion was interrupted.
+	var orphaned []string
+	for id := uint(1); id <= maxID && id <= maxOrphanProbe; id++ {
+		if existing[id] {
+			continue
+		}
+		if ok, err := d.store.Exists(activeKeysListKey(id)); err == nil && ok {
+			orphaned = append(orphaned, strconv.FormatUint(uint64(id), 10))
+		}
+	}
+	if len(orphaned) > 0 {
+		check.Details = append(check.Details, "orphaned key pools of deleted groups: "+strings.Join(orphaned, ", "))
+	}
+
+	if len(check.Details) == 0 {
+		check.Status, check.Summary = checkOK, fmt.Sprintf("%d key pools match the database", len(groups))
+		return check
+	}
+	check.Status = checkWarn
+	check.Summary = "store and database disagree; restart the master node to reload key pools"
+	return check
+}
+
+func activeKeysListKey(groupID uint) string {
+	return "group:" + strconv.FormatUint(uint64(groupID), 10) + ":active_keys"
+}
+
+// writeDoctorReport prints the checks as a table followed by their details.
+func writeDoctorReport(w io.Writer, checks []doctorCheck) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, check := range checks {
+		fmt.Fprintf(tw, "[%s]\t%s\t%s\n", check.Status, check.Name, check.Summary)
+		for _, detail := range check.Details {
+			fmt.Fprintf(tw, "\t\t  - %s\n", detail)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gpt-load/internal/app"
+	"gpt-load/internal/centralizedmgmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newDoctorDB(t *testing.T, schema ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(schema...))
+	return db
+}
+
+func TestDoctorCheckEncryption(t *testing.T) {
+	db := newDoctorDB(t, &models.APIKey{})
+	encrypted, err := encryption.NewService("doctor-test-key")
+	require.NoError(t, err)
+	for _, value := range []string{"sk-one", "sk-two"} {
+		stored, err := encrypted.Encrypt(value)
+		require.NoError(t, err)
+		require.NoError(t, db.Create(&models.APIKey{GroupID: 1, KeyValue: stored, KeyHash: encrypted.Hash(value)}).Error)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus string
+		wantText   string
+	}{
+		{name: "matching key", key: "doctor-test-key", wantStatus: checkOK, wantText: "matches"},
+		{name: "key not configured", key: "", wantStatus: checkFail, wantText: "ENCRYPTION_KEY is not set"},
+		{name: "wrong key", key: "another-key", wantStatus: checkFail, wantText: "does not decrypt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := (&doctor{db: db, encryptionKey: tt.key}).checkEncryption(context.Background())
+			assert.Equal(t, tt.wantStatus, check.Status)
+			assert.Contains(t, check.Summary, tt.wantText)
+		})
+	}
+}
+
+func TestDoctorCheckSchema(t *testing.T) {
+	db := newDoctorDB(t, app.DataModels()...)
+	check := (&doctor{db: db}).checkSchema()
+	assert.Equal(t, checkOK, check.Status)
+
+	require.NoError(t, db.Migrator().DropColumn(&models.Group{}, "enabled"))
+	require.NoError(t, db.Migrator().DropTable(&centralizedmgmt.HubAccessKey{}))
+
+	check = (&doctor{db: db}).checkSchema()
+	assert.Equal(t, checkFail, check.Status)
+	assert.Equal(t, []string{"missing column groups.enabled", "missing table hub_access_keys"}, check.Details)
+	assert.Contains(t, check.Summary, "2 pending migrations")
+}
+
+func TestDoctorCheckStore(t *testing.T) {
+	db := newDoctorDB(t, &models.Group{}, &models.APIKey{})
+	group := models.Group{Name: "openai", GroupType: "standard", ChannelType: "openai", TestModel: "m", Upstreams: datatypes.JSON("[]")}
+	require.NoError(t, db.Create(&group).Error)
+	deleted := models.Group{Name: "old", GroupType: "standard", ChannelType: "openai", TestModel: "m", Upstreams: datatypes.JSON("[]")}
+	require.NoError(t, db.Create(&deleted).Error)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: group.ID, KeyValue: "sk-a", KeyHash: "a", Status: models.KeyStatusActive}).Error)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: group.ID, KeyValue: "sk-b", KeyHash: "b", Status: models.KeyStatusInvalid}).Error)
+
+	memory := store.NewMemoryStore()
+	require.NoError(t, memory.LPush(activeKeysListKey(group.ID), 1))
+	require.NoError(t, memory.LPush(activeKeysListKey(deleted.ID), 9))
+	require.NoError(t, db.Delete(&deleted).Error)
+	// The highest ID still exists so the deleted group is within the probe range.
+	last := models.Group{Name: "last", GroupType: "standard", ChannelType: "openai", TestModel: "m", Upstreams: datatypes.JSON("[]")}
+	require.NoError(t, db.Create(&last).Error)
+
+	check := (&doctor{db: db, store: memory}).checkStore()
+	assert.Equal(t, checkSkip, check.Status, "an in-memory store is not compared")
+
+	check = (&doctor{db: db, store: memory, sharedStore: true}).checkStore()
+	assert.Equal(t, checkWarn, check.Status)
+	assert.Equal(t, []string{"orphaned key pools of deleted groups: 2"}, check.Details)
+
+	require.NoError(t, memory.Del(activeKeysListKey(deleted.ID)))
+	require.NoError(t, memory.LPush(activeKeysListKey(group.ID), 2))
+	check = (&doctor{db: db, store: memory, sharedStore: true}).checkStore()
+	assert.Equal(t, []string{"group openai: 2 keys in the store pool, 1 active in the database"}, check.Details)
+}
+
+func TestWriteDoctorReport(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, writeDoctorReport(&out, []doctorCheck{
+		{Name: "schema", Status: checkFail, Summary: "1 pending migrations", Details: []string{"missing table groups"}},
+		{Name: "store", Status: checkSkip, Summary: "in-memory store"},
+	}))
+	assert.Contains(t, out.String(), "[fail]  schema")
+	assert.Contains(t, out.String(), "- missing table groups")
+	assert.Contains(t, out.String(), "[skip]  store")
+}
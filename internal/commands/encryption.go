@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"gpt-load/internal/services"
+)
+
+// RunEncryption handles the encryption command entry point.
+func RunEncryption(args []string) {
+	lines := []string{
+		"gpt-load encryption status",
+		"gpt-load encryption rotate [--no-wait]",
+	}
+	sub, args := splitSubcommand(args, func() { printCommandList("GPT-Load Encryption Keyring", lines) })
+	fs, opts := newAdminFlagSet("encryption " + sub)
+	fs.Usage = adminUsage("GPT-Load Encryption Keyring", lines, fs)
+
+	var run adminRunFunc
+	switch sub {
+	case "status":
+		run = exactArgs(0, "encryption status", func(ctx context.Context, cli *adminCLI, _ []string) error {
+			return cli.encryptionStatus(ctx)
+		})
+	case "rotate":
+		noWait := fs.Bool("no-wait", false, "Return without waiting for re-encryption to finish")
+		run = exactArgs(0, "encryption rotate", func(ctx context.Context, cli *adminCLI, _ []string) error {
+			return cli.rotateEncryptionKey(ctx, !*noWait)
+		})
+	default:
+		unknownSubcommand("encryption", sub, fs)
+	}
+	runAdminCommand(fs, opts, args, run)
+}
+
+func (cli *adminCLI) fetchEncryptionStatus(ctx context.Context) (*services.EncryptionStatus, error) {
+	var status services.EncryptionStatus
+	if err := cli.client.call(ctx, http.MethodGet, "/api/encryption", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (cli *adminCLI) encryptionStatus(ctx context.Context) error {
+	status, err := cli.fetchEncryptionStatus(ctx)
+	if err != nil {
+		return err
+	}
+	return cli.printEncryptionStatus(status)
+}
+
+func (cli *adminCLI) printEncryptionStatus(status *services.EncryptionStatus) error {
+	if cli.json {
+		return cli.printJSON(status)
+	}
+	if !status.Enabled {
+		fmt.Fprintln(cli.out, "Encryption is disabled (ENCRYPTION_KEY is not set).")
+		return nil
+	}
+
+	fmt.Fprintf(cli.out, "Active data key: v%d\n\n", status.ActiveVersion)
+	tw := cli.table()
+	fmt.Fprintln(tw, "VERSION\tSTATUS\tCREATED")
+	for _, key := range status.Keys {
+		fmt.Fprintf(tw, "v%d\t%s\t%s\n", key.Version, key.Status, key.CreatedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "TABLE\tCOLUMN\tPENDING")
+	for _, column := range status.Columns {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", column.Table, column.Column, column.Pending)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if run := status.LastRun; run != nil {
+		state := "running"
+		if run.FinishedAt != nil {
+			state = "finished " + run.FinishedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(cli.out, "\nRe-encryption %s: %d re-encrypted, %d failed\n", state, run.Reencrypted, run.Failed)
+		if run.Error != "" {
+			fmt.Fprintf(cli.out, "Error: %s\n", run.Error)
+		}
+	}
+	return nil
+}
+
+func (cli *adminCLI) rotateEncryptionKey(ctx context.Context, wait bool) error {
+	var status services.EncryptionStatus
+	if err := cli.client.call(ctx, http.MethodPost, "/api/encryption/rotate", nil, nil, &status); err != nil {
+		return err
+	}
+	if !cli.json {
+		fmt.Fprintf(os.Stderr, "Rotated to data key v%d.\n", status.ActiveVersion)
+	}
+	if !wait {
+		return cli.printEncryptionStatus(&status)
+	}
+
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for status.Running {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		next, err := cli.fetchEncryptionStatus(ctx)
+		if err != nil {
+			return err
+		}
+		status = *next
+	}
+	if err := cli.printEncryptionStatus(&status); err != nil {
+		return err
+	}
+	if status.LastRun != nil && status.LastRun.Error != "" {
+		return fmt.Errorf("re-encryption failed: %s", status.LastRun.Error)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+)
+
+func (cli *adminCLI) groupHistory(ctx context.Context, name string) error {
+	group, err := cli.findGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+	var revisions []models.GroupRevision
+	if err := cli.client.call(ctx, http.MethodGet, fmt.Sprintf("/api/groups/%d/revisions", group.ID), nil, nil, &revisions); err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(revisions)
+	}
+
+	tw := cli.table()
+	fmt.Fprintln(tw, "REVISION\tSOURCE\tCREATED\tNOTE")
+	for _, rev := range revisions {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", rev.Revision, rev.Source, rev.CreatedAt.Format(time.RFC3339), rev.Note)
+	}
+	return tw.Flush()
+}
+
+func (cli *adminCLI) diffGroupRevisions(ctx context.Context, name string, revisions []string) error {
+	from, err := parseRevisionArg(revisions[0])
+	if err != nil {
+		return err
+	}
+	var query url.Values
+	if len(revisions) > 1 {
+		to, err := parseRevisionArg(revisions[1])
+		if err != nil {
+			return err
+		}
+		query = url.Values{"to": {strconv.Itoa(to)}}
+	}
+	group, err := cli.findGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var diff services.GroupRevisionDiff
+	path := fmt.Sprintf("/api/groups/%d/revisions/%d/diff", group.ID, from)
+	if err := cli.client.call(ctx, http.MethodGet, path, query, nil, &diff); err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(diff)
+	}
+
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(cli.out, "No differences.")
+		return nil
+	}
+	tw := cli.table()
+	fmt.Fprintln(tw, "FIELD\tFROM\tTO")
+	for _, change := range diff.Changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", change.Field, formatDiffValue(change.From), formatDiffValue(change.To))
+	}
+	return tw.Flush()
+}
+
+func (cli *adminCLI) rollbackGroup(ctx context.Context, name, revisionArg string) error {
+	revision, err := parseRevisionArg(revisionArg)
+	if err != nil {
+		return err
+	}
+	group, err := cli.findGroup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Revision int `json:"revision"`
+	}
+	path := fmt.Sprintf("/api/groups/%d/revisions/%d/rollback", group.ID, revision)
+	resp, err := cli.client.send(ctx, http.MethodPost, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if cli.json {
+		return cli.printJSON(resp.Data)
+	}
+	if err := json.Unmarshal(resp.Data, &result); err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.out, "Group %s rolled back to revision %d (now revision %d).\n", name, revision, result.Revision)
+	return nil
+}
+
+func parseRevisionArg(value string) (int, error) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, fmt.Errorf("invalid revision %q", value)
+	}
+	return revision, nil
+}
+
+// formatDiffValue renders a changed value on one line; absent values show as "-".
+func formatDiffValue(value any) string {
+	if value == nil {
+		return "-"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return truncate(string(data), 80)
+}
@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"gpt-load/internal/container"
@@ -89,6 +90,8 @@ type MigrateKeysCommand struct {
 	cacheStore    store.Store
 	fromKey       string
 	toKey         string
+	// hasKeyring is set when stored data uses envelope encryption.
+	hasKeyring bool
 }
 
 // NewMigrateKeysCommand creates a new migration command
@@ -118,6 +121,20 @@ func (cmd *MigrateKeysCommand) Execute() error {
 
 	logrus.Infof("Starting key migration, scenario: %s", scenario)
 
+	// With a keyring, changing the key only re-wraps the data keys; stored
+	// data and hashes stay as they are.
+	if cmd.hasKeyring, err = encryption.HasKeyring(cmd.db); err != nil {
+		return fmt.Errorf("failed to check encryption keyring: %w", err)
+	}
+	if cmd.hasKeyring {
+		if cmd.fromKey == "" {
+			return fmt.Errorf("data is already encrypted, use --from with the current key")
+		}
+		if cmd.toKey != "" {
+			return cmd.rewrapKeyring()
+		}
+	}
+
 	// 2. Pre-check - verify current keys can decrypt all data
 	if err := cmd.preCheck(); err != nil {
 		return fmt.Errorf("pre-check failed: %w", err)
@@ -140,12 +157,24 @@ func (cmd *MigrateKeysCommand) Execute() error {
 		return fmt.Errorf("column switch failed: %w", err)
 	}
 
-	// 6. Clear cache
+	// 6. Migrate the encrypted columns of other tables
+	if err := cmd.migrateSecretColumns(); err != nil {
+		return fmt.Errorf("secret column migration failed: %w", err)
+	}
+
+	// Data is plaintext now, so the keyring is obsolete
+	if cmd.hasKeyring && cmd.toKey == "" {
+		if err := cmd.db.Where("1 = 1").Delete(&encryption.DataKey{}).Error; err != nil {
+			return fmt.Errorf("failed to remove encryption keyring: %w", err)
+		}
+	}
+
+	// 7. Clear cache
 	if err := cmd.clearCache(); err != nil {
 		logrus.Warnf("Cache cleanup failed, recommend manual service restart: %v", err)
 	}
 
-	// 7. Clean up temporary table
+	// 8. Clean up temporary table
 	if err := cmd.dropTempTable(); err != nil {
 		logrus.Warnf("Temporary table cleanup failed, can manually drop temp_migration table: %v", err)
 	}
@@ -198,7 +227,7 @@ func (cmd *MigrateKeysCommand) preCheck() error {
 
 	if cmd.fromKey != "" {
 		// Use fromKey to create encryption service for verification
-		currentService, err = encryption.NewService(cmd.fromKey)
+		currentService, err = cmd.sourceService(cmd.fromKey)
 	} else {
 		// Enable encryption scenario: data should be unencrypted
 		// Use noop service to verify data is not encrypted
@@ -476,12 +505,34 @@ func (cmd *MigrateKeysCommand) dropTempTable() error {
 	return nil
 }
 
+// sourceService returns the service that decrypts the stored data with key.
+func (cmd *MigrateKeysCommand) sourceService(key string) (encryption.Service, error) {
+	if cmd.hasKeyring {
+		return encryption.Open(cmd.db, key)
+	}
+	return encryption.NewService(key)
+}
+
+// rewrapKeyring wraps the data keys with the new key-encryption key.
+func (cmd *MigrateKeysCommand) rewrapKeyring() error {
+	keyring, err := encryption.NewKeyring(cmd.db, cmd.fromKey)
+	if err != nil {
+		return fmt.Errorf("pre-check failed, please check the --from parameter: %w", err)
+	}
+	if err := keyring.Rewrap(context.Background(), cmd.toKey); err != nil {
+		return fmt.Errorf("failed to re-wrap data keys: %w", err)
+	}
+	logrus.Info("Data keys re-wrapped with the new key, stored data is unchanged")
+	logrus.Info("Set ENCRYPTION_KEY to the new key and restart all nodes")
+	return nil
+}
+
 // createMigrationServices creates old and new encryption services for migration
 func (cmd *MigrateKeysCommand) createMigrationServices() (oldService, newService encryption.Service, err error) {
 	// Create old encryption service (for decryption) based on parameters only
 	if cmd.fromKey != "" {
 		// Decrypt with specified key
-		oldService, err = encryption.NewService(cmd.fromKey)
+		oldService, err = cmd.sourceService(cmd.fromKey)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to create old encryption service: %w", err)
 		}
@@ -696,3 +747,82 @@ func (cmd *MigrateKeysCommand) clearCache() error {
 	logrus.Info("Cache cleanup successful")
 	return nil
 }
+
+// secretColumn is an encrypted column outside api_keys.
+type secretColumn struct {
+	table  string
+	column string
+	// hashColumn holds the lookup hash of the plaintext, if any.
+	hashColumn string
+	// plaintextOK marks columns whose readers also accept plaintext; they are
+	// not encrypted when encryption is enabled.
+	plaintextOK bool
+}
+
+var secretColumns = []secretColumn{
+	{table: "hub_access_keys", column: "key_value", hashColumn: "key_hash"},
+	{table: "managed_sites", column: "auth_value"},
+	{table: "managed_sites", column: "user_id", plaintextOK: true},
+}
+
+// migrateSecretColumns re-encrypts the encrypted columns of other tables in
+// place, one transaction per column. Values that do not decrypt are left
+// unchanged and reported.
+func (cmd *MigrateKeysCommand) migrateSecretColumns() error {
+	oldService, newService, err := cmd.createMigrationServices()
+	if err != nil {
+		return err
+	}
+
+	for _, sc := range secretColumns {
+		if !cmd.db.Migrator().HasTable(sc.table) || (sc.plaintextOK && cmd.fromKey == "") {
+			continue
+		}
+		migrated, skipped := 0, 0
+		err := cmd.db.Transaction(func(tx *gorm.DB) error {
+			lastID := uint(0)
+			for {
+				var rows []struct {
+					ID    uint
+					Value string
+				}
+				if err := tx.Table(sc.table).
+					Select("id, "+sc.column+" AS value").
+					Where("id > ? AND "+sc.column+" <> ''", lastID).
+					Order("id").Limit(migrationBatchSize).
+					Scan(&rows).Error; err != nil {
+					return err
+				}
+				if len(rows) == 0 {
+					return nil
+				}
+				for _, row := range rows {
+					plaintext, err := oldService.Decrypt(row.Value)
+					if err != nil {
+						logrus.Warnf("%s.%s ID %d does not decrypt, left unchanged: %v", sc.table, sc.column, row.ID, err)
+						skipped++
+						continue
+					}
+					encrypted, err := newService.Encrypt(plaintext)
+					if err != nil {
+						return fmt.Errorf("%s ID %d encryption failed: %w", sc.table, row.ID, err)
+					}
+					updates := map[string]any{sc.column: encrypted}
+					if sc.hashColumn != "" {
+						updates[sc.hashColumn] = newService.Hash(plaintext)
+					}
+					if err := tx.Table(sc.table).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+						return err
+					}
+					migrated++
+				}
+				lastID = rows[len(rows)-1].ID
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate %s.%s: %w", sc.table, sc.column, err)
+		}
+		logrus.Infof("Migrated %d values of %s.%s (%d left unchanged)", migrated, sc.table, sc.column, skipped)
+	}
+	return nil
+}
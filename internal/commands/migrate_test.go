@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
@@ -462,3 +463,30 @@ func TestMigrateKeysCommand_Fields(t *testing.T) {
 	assert.Equal(t, "from", cmd.fromKey)
 	assert.Equal(t, "to", cmd.toKey)
 }
+
+func TestExecute_RewrapsKeyring(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&encryption.DataKey{}))
+
+	const oldKey, newKey = "old-encryption-key-32-bytes!!!", "new-encryption-key-32-bytes!!!"
+	keyring, err := encryption.NewKeyring(db, oldKey)
+	require.NoError(t, err)
+	require.NoError(t, keyring.Initialize(context.Background()))
+	ciphertext, err := keyring.Encrypt("sk-test")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: 1, KeyValue: ciphertext, KeyHash: keyring.Hash("sk-test")}).Error)
+
+	require.ErrorContains(t, NewMigrateKeysCommand(db, nil, nil, "", newKey).Execute(), "already encrypted")
+	require.NoError(t, NewMigrateKeysCommand(db, nil, nil, oldKey, newKey).Execute())
+
+	// Stored data is unchanged and readable with the new key.
+	var key models.APIKey
+	require.NoError(t, db.First(&key).Error)
+	assert.Equal(t, ciphertext, key.KeyValue)
+	rewrapped, err := encryption.NewKeyring(db, newKey)
+	require.NoError(t, err)
+	plaintext, err := rewrapped.Decrypt(key.KeyValue)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-test", plaintext)
+}
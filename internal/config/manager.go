@@ -64,15 +64,44 @@ func NewManager(settingsManager *SystemSettingsManager) (types.ConfigManager, er
 	return manager, nil
 }
 
+// loadEncryptionKey returns the key-encryption key from ENCRYPTION_KEY, or
+// from the file named by ENCRYPTION_KEY_FILE (e.g. a mounted secret).
+func loadEncryptionKey() (string, error) {
+	key := os.Getenv("ENCRYPTION_KEY")
+	file := strings.TrimSpace(os.Getenv("ENCRYPTION_KEY_FILE"))
+	if file == "" {
+		return key, nil
+	}
+	if key != "" {
+		return "", fmt.Errorf("set only one of ENCRYPTION_KEY and ENCRYPTION_KEY_FILE")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ENCRYPTION_KEY_FILE: %w", err)
+	}
+	key = strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("ENCRYPTION_KEY_FILE %s is empty", file)
+	}
+	return key, nil
+}
+
 // ReloadConfig reloads the configuration from environment variables
 func (m *Manager) ReloadConfig() error {
 	if err := godotenv.Load(); err != nil {
 		logrus.Info("Info: Create .env file to support environment variable configuration")
 	}
 
+	encryptionKey, err := loadEncryptionKey()
+	if err != nil {
+		return err
+	}
+
 	config := &Config{
 		Server: types.ServerConfig{
 			IsMaster:                !utils.ParseBoolean(os.Getenv("IS_SLAVE"), false),
+			LeaderElection:          utils.ParseBoolean(os.Getenv("LEADER_ELECTION"), false),
+			ConfigDir:               strings.TrimSpace(os.Getenv("CONFIG_DIR")),
 			Port:                    utils.ParseInteger(os.Getenv("PORT"), 3001),
 			Host:                    utils.GetEnvOrDefault("HOST", "0.0.0.0"),
 			ReadTimeout:             utils.ParseInteger(os.Getenv("SERVER_READ_TIMEOUT"), 300),
@@ -103,7 +132,7 @@ func (m *Manager) ReloadConfig() error {
 			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
 		},
 		RedisDSN:      os.Getenv("REDIS_DSN"),
-		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
+		EncryptionKey: encryptionKey,
 		DebugMode:     utils.ParseBoolean(os.Getenv("DEBUG_MODE"), false),
 	}
 	m.config = config
@@ -193,6 +222,16 @@ func (m *Manager) Validate() error {
 		m.config.Server.GracefulShutdownTimeout = 10
 	}
 
+	// Leader election needs a store shared by all nodes
+	if m.config.Server.LeaderElection {
+		if m.config.RedisDSN == "" {
+			validationErrors = append(validationErrors, "LEADER_ELECTION requires REDIS_DSN to be set")
+		}
+		if os.Getenv("IS_SLAVE") != "" {
+			logrus.Warn("IS_SLAVE is ignored because LEADER_ELECTION is enabled.")
+		}
+	}
+
 	if m.config.CORS.Enabled {
 		if len(m.config.CORS.AllowedOrigins) == 0 {
 			validationErrors = append(validationErrors, "CORS is enabled but ALLOWED_ORIGINS is not set. UI will not work from a browser.")
@@ -230,6 +269,16 @@ func (m *Manager) DisplayServerConfig() {
 	logrus.Infof("    Read Timeout: %d seconds", serverConfig.ReadTimeout)
 	logrus.Infof("    Write Timeout: %d seconds", serverConfig.WriteTimeout)
 	logrus.Infof("    Idle Timeout: %d seconds", serverConfig.IdleTimeout)
+	if serverConfig.LeaderElection {
+		logrus.Info("    Node Mode: leader election")
+	} else if serverConfig.IsMaster {
+		logrus.Info("    Node Mode: master")
+	} else {
+		logrus.Info("    Node Mode: slave")
+	}
+	if serverConfig.ConfigDir != "" {
+		logrus.Infof("    Declarative Config: %s", serverConfig.ConfigDir)
+	}
 
 	logrus.Info("  --- Performance ---")
 	logrus.Infof("    Max Concurrent Requests: %d", perfConfig.MaxConcurrentRequests)
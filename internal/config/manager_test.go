@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -170,6 +171,30 @@ func TestManagerGetters(t *testing.T) {
 	assert.NotEmpty(t, dbConfig.DSN)
 }
 
+// TestEncryptionKeyFile tests reading the encryption key from a file
+func TestEncryptionKeyFile(t *testing.T) {
+	setupTestEnv(t)
+	defer cleanupTestEnv(t)
+
+	file := filepath.Join(t.TempDir(), "encryption-key")
+	require.NoError(t, os.WriteFile(file, []byte("file-encryption-key-32-bytes!!\n"), 0600))
+	t.Setenv("ENCRYPTION_KEY", "")
+	t.Setenv("ENCRYPTION_KEY_FILE", file)
+
+	manager, err := NewManager(&SystemSettingsManager{})
+	require.NoError(t, err)
+	assert.Equal(t, "file-encryption-key-32-bytes!!", manager.GetEncryptionKey())
+
+	t.Setenv("ENCRYPTION_KEY", "env-encryption-key")
+	_, err = NewManager(&SystemSettingsManager{})
+	assert.ErrorContains(t, err, "only one of")
+
+	t.Setenv("ENCRYPTION_KEY", "")
+	t.Setenv("ENCRYPTION_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = NewManager(&SystemSettingsManager{})
+	assert.ErrorContains(t, err, "ENCRYPTION_KEY_FILE")
+}
+
 // TestManagerCORSValidation tests CORS configuration validation
 func TestManagerCORSValidation(t *testing.T) {
 	tests := []struct {
@@ -373,6 +398,25 @@ func TestManagerSlaveMode(t *testing.T) {
 	assert.False(t, manager.IsMaster())
 }
 
+// TestManagerLeaderElection tests that leader election requires a shared Redis store
+func TestManagerLeaderElection(t *testing.T) {
+	setupTestEnv(t)
+	defer cleanupTestEnv(t)
+
+	t.Setenv("LEADER_ELECTION", "true")
+	t.Setenv("REDIS_DSN", "")
+
+	settingsManager := &SystemSettingsManager{}
+	_, err := NewManager(settingsManager)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LEADER_ELECTION requires REDIS_DSN")
+
+	t.Setenv("REDIS_DSN", "redis://localhost:6379")
+	manager, err := NewManager(settingsManager)
+	require.NoError(t, err)
+	assert.True(t, manager.GetEffectiveServerConfig().LeaderElection)
+}
+
 // TestManagerLogConfig tests log configuration
 func TestManagerLogConfig(t *testing.T) {
 	tests := []struct {
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"gpt-load/internal/db"
 	"gpt-load/internal/failover"
+	"gpt-load/internal/guardrail"
 	"gpt-load/internal/models"
+	"gpt-load/internal/redaction"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/types"
@@ -30,6 +32,25 @@ const MinCodexAffinityAttempts = 1
 // MaxCodexAffinityAttempts keeps write-time validation and proxy runtime clamping aligned.
 const MaxCodexAffinityAttempts = 500
 
+// MaxHedgeAfterMs caps the hedge delay so a misconfigured group cannot hold a
+// second in-flight attempt open for longer than a typical non-stream timeout.
+const MaxHedgeAfterMs = 60000
+
+// MaxSessionAffinityTTLSeconds bounds how long a session stays pinned after its last success.
+const MaxSessionAffinityTTLSeconds = 7 * 24 * 60 * 60
+
+// MaxSessionAffinityEntries bounds the number of live session bindings per aggregate group.
+const MaxSessionAffinityEntries = 1000000
+
+// MaxResponseCacheTTLSeconds bounds how long a cached response is served.
+const MaxResponseCacheTTLSeconds = 7 * 24 * 60 * 60
+
+// MaxResponseCacheEntries bounds the number of cached responses per group.
+const MaxResponseCacheEntries = 1000000
+
+// MaxSemanticCacheTTLSeconds bounds how long a semantically cached completion is served.
+const MaxSemanticCacheTTLSeconds = 30 * 24 * 60 * 60
+
 // SystemSettingsManager manages system configuration.
 type SystemSettingsManager struct {
 	syncer           *syncer.CacheSyncer[types.SystemSettings]
@@ -89,6 +110,11 @@ func validateStringSettingValue(key, val string) error {
 			return fmt.Errorf("invalid value for %s: %w", key, err)
 		}
 	}
+	if key == "log_redaction_rules" {
+		if err := redaction.Validate(val); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
 	if key == "proxy_pool_test_target_url" {
 		parsed, err := url.Parse(strings.TrimSpace(val))
 		if err != nil || parsed == nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
@@ -103,7 +129,8 @@ type groupManager interface {
 }
 
 // Initialize initializes the SystemSettingsManager with database and store dependencies.
-func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager, isMaster bool) error {
+// isMaster is evaluated on every reload because leadership can move between nodes.
+func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager, isMaster func() bool) error {
 	settingsLoader := func() (types.SystemSettings, error) {
 		var dbSettings []models.SystemSetting
 		if err := db.DB.Find(&dbSettings).Error; err != nil {
@@ -151,7 +178,7 @@ func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager,
 	}
 
 	afterLoader := func(newData types.SystemSettings) {
-		if !isMaster {
+		if !isMaster() {
 			return
 		}
 		gm.Invalidate()
@@ -172,6 +199,11 @@ func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager,
 	return nil
 }
 
+// Subscribed reports whether the settings cache is listening for invalidations.
+func (sm *SystemSettingsManager) Subscribed() bool {
+	return sm.syncer != nil && sm.syncer.Subscribed()
+}
+
 // Stop gracefully stops the SystemSettingsManager's background syncer.
 func (sm *SystemSettingsManager) Stop(ctx context.Context) {
 	if sm.syncer != nil {
@@ -504,6 +536,162 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 			continue
 		}
 
+		// Zero disables hedging; enabled delays are bounded to keep duplicate load predictable.
+		if key == "hedge_after_ms" {
+			intVal, err := integerConfigValue(key, value)
+			if err != nil {
+				return err
+			}
+			if intVal < 0 {
+				return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, 0)
+			}
+			if intVal > MaxHedgeAfterMs {
+				return fmt.Errorf("value for %s (%d) exceeds maximum value (%d)", key, intVal, MaxHedgeAfterMs)
+			}
+			continue
+		}
+
+		// Share of requests mirrored to the shadow group.
+		if key == "shadow_sample_percent" {
+			intVal, err := integerConfigValue(key, value)
+			if err != nil {
+				return err
+			}
+			if intVal < 1 {
+				return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, 1)
+			}
+			if intVal > 100 {
+				return fmt.Errorf("value for %s (%d) exceeds maximum value (%d)", key, intVal, 100)
+			}
+			continue
+		}
+
+		if key == "session_affinity_ttl_seconds" || key == "session_affinity_max_entries" ||
+			key == "response_cache_ttl_seconds" || key == "response_cache_max_entries" ||
+			key == "semantic_cache_ttl_seconds" {
+			intVal, err := integerConfigValue(key, value)
+			if err != nil {
+				return err
+			}
+			var maxVal int64
+			switch key {
+			case "session_affinity_ttl_seconds":
+				maxVal = MaxSessionAffinityTTLSeconds
+			case "session_affinity_max_entries":
+				maxVal = MaxSessionAffinityEntries
+			case "response_cache_ttl_seconds":
+				maxVal = MaxResponseCacheTTLSeconds
+			case "semantic_cache_ttl_seconds":
+				maxVal = MaxSemanticCacheTTLSeconds
+			default:
+				maxVal = MaxResponseCacheEntries
+			}
+			if intVal < 1 {
+				return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, 1)
+			}
+			if intVal > maxVal {
+				return fmt.Errorf("value for %s (%d) exceeds maximum value (%d)", key, intVal, maxVal)
+			}
+			continue
+		}
+
+		// Session affinity key source and binding scope for aggregate groups.
+		if key == "session_affinity_source" || key == "session_affinity_scope" {
+			mode, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid type for %s: expected a string, got %T", key, value)
+			}
+			mode = strings.ToLower(strings.TrimSpace(mode))
+			validModes := map[string]bool{"auto": true, "header": true, "json_path": true, "proxy_key": true}
+			message := "must be 'auto', 'header', 'json_path', or 'proxy_key'"
+			if key == "session_affinity_scope" {
+				validModes = map[string]bool{"sub_group": true, "key": true, "both": true}
+				message = "must be 'sub_group', 'key', or 'both'"
+			}
+			if mode == "" {
+				delete(configMap, key)
+				continue
+			}
+			if !validModes[mode] {
+				return fmt.Errorf("invalid value for %s: %s", key, message)
+			}
+			configMap[key] = mode
+			continue
+		}
+
+		// Header name or JSON path read when session_affinity_source is "header" or "json_path".
+		if key == "session_affinity_key" {
+			name, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid type for %s: expected a string, got %T", key, value)
+			}
+			name = strings.TrimSpace(name)
+			if name == "" {
+				delete(configMap, key)
+				continue
+			}
+			configMap[key] = name
+			continue
+		}
+
+		// Minimum cosine similarity for a semantic cache hit.
+		if key == "semantic_cache_threshold" {
+			threshold, ok := value.(float64)
+			if !ok {
+				return fmt.Errorf("invalid type for %s: expected a number, got %T", key, value)
+			}
+			if threshold <= 0 || threshold > 1 {
+				return fmt.Errorf("invalid value for %s: must be greater than 0 and at most 1", key)
+			}
+			continue
+		}
+
+		// Embedding group name and model used by the semantic cache, and the shadow group name.
+		if key == "semantic_cache_embedding_group" || key == "semantic_cache_embedding_model" || key == "shadow_group" {
+			name, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid type for %s: expected a string, got %T", key, value)
+			}
+			name = strings.TrimSpace(name)
+			if name == "" {
+				delete(configMap, key)
+				continue
+			}
+			configMap[key] = name
+			continue
+		}
+
+		// Guardrail policy object; a JSON string is accepted and stored as an object.
+		if key == "guardrail_policy" {
+			var raw []byte
+			switch policy := value.(type) {
+			case string:
+				raw = []byte(policy)
+			case map[string]any:
+				encoded, err := json.Marshal(policy)
+				if err != nil {
+					return fmt.Errorf("invalid value for %s: %w", key, err)
+				}
+				raw = encoded
+			default:
+				return fmt.Errorf("invalid type for %s: expected an object, got %T", key, value)
+			}
+			policy, err := guardrail.Parse(raw)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+			if policy == nil {
+				delete(configMap, key)
+				continue
+			}
+			var normalized map[string]any
+			if err := json.Unmarshal(raw, &normalized); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", key, err)
+			}
+			configMap[key] = normalized
+			continue
+		}
+
 		// Allow group-only override keys that are not part of system-level settings metadata.
 		// Currently this is used for aggregate group sub-group retry configuration.
 		if key == "sub_max_retries" {
@@ -558,7 +746,9 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 			key == "force_stream" || key == "force_non_stream" ||
 			key == "responses_include_encrypted_reasoning" ||
 			key == "codex_degradation_mitigation_enabled" ||
-			key == "codex_affinity_enabled" {
+			key == "codex_affinity_enabled" || key == "session_affinity_enabled" ||
+			key == "response_cache_enabled" || key == "semantic_cache_enabled" ||
+			key == "shadow_log_response_body" {
 			// Accept only boolean values; nil is already skipped above.
 			if _, ok := value.(bool); !ok {
 				return fmt.Errorf("invalid type for %s: expected a boolean, got %T", key, value)
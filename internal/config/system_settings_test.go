@@ -429,6 +429,170 @@ func TestValidateGroupConfigOverrides(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "valid session affinity config",
+			config: map[string]any{
+				"session_affinity_enabled":     true,
+				"session_affinity_source":      " Header ",
+				"session_affinity_key":         " X-Session-Id ",
+				"session_affinity_scope":       "both",
+				"session_affinity_ttl_seconds": float64(3600),
+				"session_affinity_max_entries": float64(MaxSessionAffinityEntries),
+			},
+			expectError: false,
+			assertConfig: func(t *testing.T, config map[string]any) {
+				assert.Equal(t, "header", config["session_affinity_source"])
+				assert.Equal(t, "X-Session-Id", config["session_affinity_key"])
+			},
+		},
+		{
+			name: "invalid session_affinity_source",
+			config: map[string]any{
+				"session_affinity_source": "cookie",
+			},
+			expectError: true,
+			errorMsg:    "invalid value for session_affinity_source",
+		},
+		{
+			name: "invalid session_affinity_scope",
+			config: map[string]any{
+				"session_affinity_scope": "upstream",
+			},
+			expectError: true,
+			errorMsg:    "invalid value for session_affinity_scope",
+		},
+		{
+			name: "session_affinity_ttl_seconds above maximum",
+			config: map[string]any{
+				"session_affinity_ttl_seconds": float64(MaxSessionAffinityTTLSeconds + 1),
+			},
+			expectError: true,
+			errorMsg:    "exceeds maximum value",
+		},
+		{
+			name: "zero session_affinity_max_entries",
+			config: map[string]any{
+				"session_affinity_max_entries": float64(0),
+			},
+			expectError: true,
+			errorMsg:    "below minimum value",
+		},
+		{
+			name: "valid response cache config",
+			config: map[string]any{
+				"response_cache_enabled":     true,
+				"response_cache_ttl_seconds": float64(300),
+				"response_cache_max_entries": float64(1000),
+			},
+			expectError: false,
+		},
+		{
+			name: "response_cache_ttl_seconds above maximum",
+			config: map[string]any{
+				"response_cache_ttl_seconds": float64(MaxResponseCacheTTLSeconds + 1),
+			},
+			expectError: true,
+			errorMsg:    "exceeds maximum value",
+		},
+		{
+			name: "non-boolean response_cache_enabled",
+			config: map[string]any{
+				"response_cache_enabled": "yes",
+			},
+			expectError: true,
+			errorMsg:    "expected a boolean",
+		},
+		{
+			name: "valid semantic cache config",
+			config: map[string]any{
+				"semantic_cache_enabled":         true,
+				"semantic_cache_embedding_group": " embeddings ",
+				"semantic_cache_threshold":       0.92,
+				"semantic_cache_ttl_seconds":     float64(3600),
+			},
+			expectError: false,
+		},
+		{
+			name: "semantic_cache_threshold above one",
+			config: map[string]any{
+				"semantic_cache_threshold": 1.5,
+			},
+			expectError: true,
+			errorMsg:    "must be greater than 0 and at most 1",
+		},
+		{
+			name: "non-numeric semantic_cache_threshold",
+			config: map[string]any{
+				"semantic_cache_threshold": "0.9",
+			},
+			expectError: true,
+			errorMsg:    "expected a number",
+		},
+		{
+			name: "valid shadow config",
+			config: map[string]any{
+				"shadow_group":             " candidate ",
+				"shadow_sample_percent":    float64(10),
+				"shadow_log_response_body": true,
+			},
+			expectError: false,
+			assertConfig: func(t *testing.T, config map[string]any) {
+				assert.Equal(t, "candidate", config["shadow_group"])
+			},
+		},
+		{
+			name: "shadow_sample_percent above 100",
+			config: map[string]any{
+				"shadow_sample_percent": 150,
+			},
+			expectError: true,
+			errorMsg:    "exceeds maximum value",
+		},
+		{
+			name: "valid log_redaction_rules",
+			config: map[string]any{
+				"log_redaction_rules": `{"detectors":["email"],"drop_paths":["messages[*].content"]}`,
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid log_redaction_rules detector",
+			config: map[string]any{
+				"log_redaction_rules": `{"detectors":["ssn"]}`,
+			},
+			expectError: true,
+			errorMsg:    "unknown redaction detector",
+		},
+		{
+			name: "guardrail_policy string is stored as object",
+			config: map[string]any{
+				"guardrail_policy": `{"blocklist":["secret project"],"max_messages":20}`,
+			},
+			expectError: false,
+			assertConfig: func(t *testing.T, config map[string]any) {
+				policy, ok := config["guardrail_policy"].(map[string]any)
+				require.True(t, ok)
+				assert.Equal(t, float64(20), policy["max_messages"])
+			},
+		},
+		{
+			name: "empty guardrail_policy is removed",
+			config: map[string]any{
+				"guardrail_policy": map[string]any{},
+			},
+			expectError: false,
+			assertConfig: func(t *testing.T, config map[string]any) {
+				assert.NotContains(t, config, "guardrail_policy")
+			},
+		},
+		{
+			name: "invalid guardrail_policy pattern",
+			config: map[string]any{
+				"guardrail_policy": map[string]any{"blocklist_patterns": []any{"("}},
+			},
+			expectError: true,
+			errorMsg:    "invalid blocklist pattern",
+		},
 		{
 			name: "valid minimum codex_affinity_max_retries",
 			config: map[string]any{
@@ -498,6 +662,36 @@ func TestValidateGroupConfigOverrides(t *testing.T) {
 			expectError: true,
 			errorMsg:    "expected a number",
 		},
+		{
+			name: "valid hedge_after_ms",
+			config: map[string]any{
+				"hedge_after_ms": float64(250),
+			},
+			expectError: false,
+		},
+		{
+			name: "zero hedge_after_ms disables hedging",
+			config: map[string]any{
+				"hedge_after_ms": float64(0),
+			},
+			expectError: false,
+		},
+		{
+			name: "negative hedge_after_ms",
+			config: map[string]any{
+				"hedge_after_ms": float64(-1),
+			},
+			expectError: true,
+			errorMsg:    "below minimum value",
+		},
+		{
+			name: "hedge_after_ms above maximum",
+			config: map[string]any{
+				"hedge_after_ms": float64(MaxHedgeAfterMs + 1),
+			},
+			expectError: true,
+			errorMsg:    "exceeds maximum value",
+		},
 		{
 			name: "valid retry_delay_ms",
 			config: map[string]any{
@@ -998,7 +1192,7 @@ func TestLegacyRequestTimeoutPersistsSplitTimeoutBackfill(t *testing.T) {
 	})
 
 	manager := NewSystemSettingsManager()
-	require.NoError(t, manager.Initialize(memStore, noopSystemSettingsGroupManager{}, false))
+	require.NoError(t, manager.Initialize(memStore, noopSystemSettingsGroupManager{}, func() bool { return false }))
 	t.Cleanup(func() {
 		manager.Stop(context.Background())
 	})
@@ -7,12 +7,16 @@ import (
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
 	"gpt-load/internal/db"
+	"gpt-load/internal/declarative"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/handler"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/leader"
+	"gpt-load/internal/logsink"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/router"
+	"gpt-load/internal/secrets"
 	"gpt-load/internal/services"
 	"gpt-load/internal/sitemanagement"
 	"gpt-load/internal/store"
@@ -30,8 +34,20 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(config.NewManager); err != nil {
 		return nil, err
 	}
-	if err := container.Provide(func(configManager types.ConfigManager) (encryption.Service, error) {
-		return encryption.NewService(configManager.GetEncryptionKey())
+	// The keyring is nil when encryption is disabled.
+	if err := container.Provide(func(db *gorm.DB, configManager types.ConfigManager) (*encryption.Keyring, error) {
+		if configManager.GetEncryptionKey() == "" {
+			return nil, nil
+		}
+		return encryption.NewKeyring(db, configManager.GetEncryptionKey())
+	}); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(func(keyring *encryption.Keyring) (encryption.Service, error) {
+		if keyring == nil {
+			return encryption.NewService("")
+		}
+		return keyring, nil
 	}); err != nil {
 		return nil, err
 	}
@@ -51,6 +67,9 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(store.NewStore); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(leader.NewElector); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(httpclient.NewHTTPClientManager); err != nil {
 		return nil, err
 	}
@@ -83,12 +102,24 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewEncryptionRotationService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogCleanupService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(logsink.NewManagerFromEnv); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewRequestLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewSemanticCacheService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewDebugCaptureService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewSubGroupManager); err != nil {
 		return nil, err
 	}
@@ -120,7 +151,26 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewChildGroupService); err != nil {
 		return nil, err
 	}
-	if err := container.Provide(keypool.NewProvider); err != nil {
+	if err := container.Provide(services.NewGroupRevisionService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewGroupScheduleService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewGroupExperimentService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewShadowReportService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(secrets.NewResolverFromEnv); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(func(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service, resolver *secrets.Resolver) *keypool.KeyProvider {
+		provider := keypool.NewProvider(db, store, settingsManager, encryptionSvc)
+		provider.SetSecretResolver(resolver)
+		return provider
+	}); err != nil {
 		return nil, err
 	}
 	if err := container.Provide(keypool.NewKeyValidator); err != nil {
@@ -171,6 +221,11 @@ func BuildContainer() (*dig.Container, error) {
 		return nil, err
 	}
 
+	// Declarative configuration
+	if err := container.Provide(declarative.NewReconciler); err != nil {
+		return nil, err
+	}
+
 	// Handlers
 	if err := container.Provide(handler.NewServer); err != nil {
 		return nil, err
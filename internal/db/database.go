@@ -51,6 +51,75 @@ func calculateAdaptivePoolSize() (maxIdle, maxOpen int) {
 	return maxIdle, maxOpen
 }
 
+// Database dialects detected from a DSN.
+const (
+	DialectSQLite   = "sqlite"
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+)
+
+// Dialect returns the database dialect a DSN refers to. Anything that is not
+// a PostgreSQL or MySQL DSN is treated as a SQLite path.
+func Dialect(dsn string) string {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://") ||
+		strings.HasPrefix(dsn, "postgresql://") ||
+		(strings.Contains(dsn, "host=") && strings.Contains(dsn, "dbname=")):
+		return DialectPostgres
+	case strings.Contains(dsn, "@tcp(") || strings.Contains(dsn, "@unix("):
+		return DialectMySQL
+	default:
+		return DialectSQLite
+	}
+}
+
+// Open connects to a DSN with a plain connection pool for command-line tools.
+// Unlike NewDB it does not tune the pool or touch the package-level handles.
+func Open(dsn string) (*gorm.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("database DSN is empty")
+	}
+
+	var dialector gorm.Dialector
+	switch Dialect(dsn) {
+	case DialectPostgres:
+		dialector = postgres.New(postgres.Config{DSN: dsn, PreferSimpleProtocol: true})
+	case DialectMySQL:
+		if !strings.Contains(dsn, "parseTime") {
+			if strings.Contains(dsn, "?") {
+				dsn += "&parseTime=true"
+			} else {
+				dsn += "?parseTime=true"
+			}
+		}
+		dialector = mysql.Open(dsn)
+	default:
+		if !strings.HasPrefix(dsn, "file:") {
+			if err := os.MkdirAll(filepath.Dir(dsn), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create database directory: %w", err)
+			}
+		}
+		delimiter := "?"
+		if strings.Contains(dsn, "?") {
+			delimiter = "&"
+		}
+		dialector = sqlite.Open(dsn + delimiter + "_pragma=foreign_keys(1)&_busy_timeout=10000&_journal_mode=WAL")
+	}
+
+	gormDB, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if Dialect(dsn) == DialectSQLite {
+		sqlDB, err := gormDB.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sql.DB: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return gormDB, nil
+}
+
 func NewDB(configManager types.ConfigManager) (*gorm.DB, error) {
 	dbConfig := configManager.GetDatabaseConfig()
 	dsn := dbConfig.DSN
@@ -73,10 +142,9 @@ func NewDB(configManager types.ConfigManager) (*gorm.DB, error) {
 	}
 
 	// Detect driver types once and reuse
-	isPostgres := strings.HasPrefix(dsn, "postgres://") ||
-		strings.HasPrefix(dsn, "postgresql://") ||
-		(strings.Contains(dsn, "host=") && strings.Contains(dsn, "dbname="))
-	isMySQL := strings.Contains(dsn, "@tcp(") || strings.Contains(dsn, "@unix(")
+	dialect := Dialect(dsn)
+	isPostgres := dialect == DialectPostgres
+	isMySQL := dialect == DialectMySQL
 
 	var dialector gorm.Dialector
 	if isPostgres {
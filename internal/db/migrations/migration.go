@@ -159,7 +159,11 @@ func MigrateDatabase(db *gorm.DB) error {
 		return err
 	}
 	// Run v1.29.0 migration - Add request log RPM dashboard index
-	return V1_29_0_OptimizeRequestLogDashboardIndex(db)
+	if err := V1_29_0_OptimizeRequestLogDashboardIndex(db); err != nil {
+		return err
+	}
+	// Run v1.30.0 migration - Add optional request log full-text search index
+	return V1_30_0_AddRequestLogSearchIndex(db)
 }
 
 // HandleLegacyIndexes removes old indexes from previous versions to prevent migration errors
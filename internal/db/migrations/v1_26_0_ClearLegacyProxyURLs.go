@@ -19,6 +19,12 @@ func (dataMigrationMarker) TableName() string {
 	return "data_migrations"
 }
 
+// DataMigrationModel returns the model of the table that records completed
+// one-off data migrations.
+func DataMigrationModel() any {
+	return &dataMigrationMarker{}
+}
+
 // V1_26_0_ClearLegacyProxyURLs clears pre-pool proxy URL settings.
 // Operators rebuild proxy choices in the proxy pool after upgrading.
 func V1_26_0_ClearLegacyProxyURLs(db *gorm.DB) error {
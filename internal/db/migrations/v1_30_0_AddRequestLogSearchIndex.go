@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// V1_30_0_AddRequestLogSearchIndex creates the optional full-text index over
+// logged bodies and error messages when LOG_SEARCH_INDEX is enabled:
+// an FTS5 table kept in sync by triggers on SQLite, a tsvector GIN index on
+// PostgreSQL and a FULLTEXT index on MySQL. Building it on a large table can
+// take a while, so it is opt-in.
+func V1_30_0_AddRequestLogSearchIndex(db *gorm.DB) error {
+	if !utils.ParseBoolean(os.Getenv("LOG_SEARCH_INDEX"), false) {
+		return nil
+	}
+	if !db.Migrator().HasTable("request_logs") {
+		return nil
+	}
+	logrus.Info("Running migration v1.30.0: Adding request log full-text search index")
+
+	switch db.Dialector.Name() {
+	case "sqlite":
+		return createSQLiteRequestLogSearch(db)
+	case "postgres":
+		sql := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON request_logs USING GIN (%s)", models.RequestLogSearchIndex, models.RequestLogSearchVector)
+		if err := db.Exec(sql).Error; err != nil {
+			return fmt.Errorf("failed to create request log search index: %w", err)
+		}
+	case "mysql":
+		if checkIndexExists(db, "mysql", "request_logs", models.RequestLogSearchIndex) {
+			return nil
+		}
+		sql := fmt.Sprintf("CREATE FULLTEXT INDEX %s ON request_logs(request_body, response_body, error_message)", models.RequestLogSearchIndex)
+		if err := db.Exec(sql).Error; err != nil {
+			return fmt.Errorf("failed to create request log search index: %w", err)
+		}
+	default:
+		logrus.Warnf("Request log search index is not supported for dialect %s, skipping", db.Dialector.Name())
+		return nil
+	}
+	logrus.Info("Request log search index is ready")
+	return nil
+}
+
+// createSQLiteRequestLogSearch creates an external-content FTS5 table over
+// request_logs and the triggers that keep it in sync, then indexes existing rows.
+func createSQLiteRequestLogSearch(db *gorm.DB) error {
+	if db.Migrator().HasTable(models.RequestLogSearchTable) {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			fmt.Sprintf(`CREATE VIRTUAL TABLE %s USING fts5(
+				request_body, response_body, error_message,
+				content='request_logs', content_rowid='rowid'
+			)`, models.RequestLogSearchTable),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS request_logs_fts_ai AFTER INSERT ON request_logs BEGIN
+				INSERT INTO %[1]s(rowid, request_body, response_body, error_message)
+				VALUES (new.rowid, new.request_body, new.response_body, new.error_message);
+			END`, models.RequestLogSearchTable),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS request_logs_fts_ad AFTER DELETE ON request_logs BEGIN
+				INSERT INTO %[1]s(%[1]s, rowid, request_body, response_body, error_message)
+				VALUES ('delete', old.rowid, old.request_body, old.response_body, old.error_message);
+			END`, models.RequestLogSearchTable),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS request_logs_fts_au AFTER UPDATE ON request_logs BEGIN
+				INSERT INTO %[1]s(%[1]s, rowid, request_body, response_body, error_message)
+				VALUES ('delete', old.rowid, old.request_body, old.response_body, old.error_message);
+				INSERT INTO %[1]s(rowid, request_body, response_body, error_message)
+				VALUES (new.rowid, new.request_body, new.response_body, new.error_message);
+			END`, models.RequestLogSearchTable),
+			fmt.Sprintf("INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')", models.RequestLogSearchTable),
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to create request log search index: %w", err)
+			}
+		}
+		return nil
+	})
+}
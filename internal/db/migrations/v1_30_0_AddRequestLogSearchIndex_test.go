@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestV1_30_0_AddRequestLogSearchIndexIsOptIn(t *testing.T) {
+	t.Setenv("LOG_SEARCH_INDEX", "")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.RequestLog{}))
+
+	require.NoError(t, V1_30_0_AddRequestLogSearchIndex(db))
+	assert.False(t, db.Migrator().HasTable(models.RequestLogSearchTable))
+}
+
+func TestV1_30_0_AddRequestLogSearchIndexKeepsFTSInSync(t *testing.T) {
+	t.Setenv("LOG_SEARCH_INDEX", "true")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	require.NoError(t, db.AutoMigrate(&models.RequestLog{}))
+	require.NoError(t, db.Create(&models.RequestLog{ID: "existing", Timestamp: time.Now(), RequestBody: "existing elephant"}).Error)
+
+	require.NoError(t, V1_30_0_AddRequestLogSearchIndex(db))
+	require.NoError(t, V1_30_0_AddRequestLogSearchIndex(db), "migration must be idempotent")
+
+	match := func(q string) []string {
+		var ids []string
+		require.NoError(t, db.Raw(`SELECT id FROM request_logs WHERE rowid IN
+			(SELECT rowid FROM request_logs_fts WHERE request_logs_fts MATCH ?) ORDER BY id`, q).Scan(&ids).Error)
+		return ids
+	}
+	assert.Equal(t, []string{"existing"}, match("elephant"))
+
+	require.NoError(t, db.Create(&models.RequestLog{ID: "inserted", Timestamp: time.Now(), ErrorMessage: "elephant timeout"}).Error)
+	assert.Equal(t, []string{"existing", "inserted"}, match("elephant"))
+
+	require.NoError(t, db.Model(&models.RequestLog{}).Where("id = ?", "existing").Update("request_body", "giraffe").Error)
+	assert.Equal(t, []string{"inserted"}, match("elephant"))
+	assert.Equal(t, []string{"existing"}, match("giraffe"))
+
+	require.NoError(t, db.Delete(&models.RequestLog{}, "id = ?", "inserted").Error)
+	assert.Empty(t, match("elephant"))
+}
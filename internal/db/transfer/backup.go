@@ -0,0 +1,232 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	backupFormat  = "gpt-load-backup"
+	backupVersion = 1
+)
+
+// backupHeader is the first line of a backup file.
+type backupHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Dialect   string    `json:"dialect"`
+}
+
+// backupRecord is any following line of a backup file: a table start
+// ({"table"}), a row ({"row"}) or a table end ({"table","count"}).
+type backupRecord struct {
+	Table string                     `json:"table,omitempty"`
+	Count *int64                     `json:"count,omitempty"`
+	Row   map[string]json.RawMessage `json:"row,omitempty"`
+}
+
+// Backup writes the tables of models to w as JSON Lines. Values are stored in
+// their JSON form, so a backup can be restored into any dialect.
+func Backup(ctx context.Context, db *gorm.DB, w io.Writer, models []any, opts Options) (*Result, error) {
+	tables, err := parseTables(db, models)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	header := backupHeader{
+		Format:    backupFormat,
+		Version:   backupVersion,
+		CreatedAt: time.Now().UTC(),
+		Dialect:   db.Dialector.Name(),
+	}
+	if err := encoder.Encode(header); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	err = snapshot(ctx, db, func(tx *gorm.DB) error {
+		for _, t := range tables {
+			if opts.skip(t.name()) || !tx.Migrator().HasTable(t.name()) {
+				continue
+			}
+			if err := encoder.Encode(map[string]string{"table": t.name()}); err != nil {
+				return err
+			}
+			var written int64
+			if err := readRows(tx, t, opts.batchSize(), func(rows []map[string]any) error {
+				for _, row := range rows {
+					if err := encoder.Encode(map[string]any{"row": row}); err != nil {
+						return err
+					}
+				}
+				written += int64(len(rows))
+				return nil
+			}); err != nil {
+				return err
+			}
+			if err := encoder.Encode(backupRecord{Table: t.name(), Count: &written}); err != nil {
+				return err
+			}
+			result.Tables = append(result.Tables, TableResult{Table: t.name(), Rows: written})
+			opts.progress(t.name(), written)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := buf.Flush(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Restore reads a backup written by Backup into db, which must have been
+// prepared with PrepareTarget. The row count of every table is verified
+// against the count recorded in the backup.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader, models []any, opts Options) (*Result, error) {
+	tables, err := parseTables(db, models)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*table, len(tables))
+	for _, t := range tables {
+		byName[t.name()] = t
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	var header backupHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	if header.Format != backupFormat {
+		return nil, errors.New("not a gpt-load backup file")
+	}
+	if header.Version > backupVersion {
+		return nil, fmt.Errorf("backup version %d is newer than supported version %d", header.Version, backupVersion)
+	}
+
+	db = db.WithContext(ctx)
+	result := &Result{}
+	var current *table
+	var skipped bool
+	var pending []map[string]any
+	var restored int64
+
+	flush := func() error {
+		if err := writeRows(db, current, pending); err != nil {
+			return err
+		}
+		restored += int64(len(pending))
+		pending = pending[:0]
+		return nil
+	}
+
+	for {
+		var record backupRecord
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		switch {
+		case record.Row != nil:
+			if current == nil && !skipped {
+				return nil, errors.New("backup row outside of a table")
+			}
+			if skipped {
+				continue
+			}
+			row, err := decodeRow(current, record.Row)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, row)
+			if len(pending) >= opts.batchSize() {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+
+		case record.Count != nil:
+			if skipped {
+				skipped = false
+				continue
+			}
+			if current == nil || current.name() != record.Table {
+				return nil, fmt.Errorf("unexpected end of table %s", record.Table)
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if restored != *record.Count {
+				return nil, fmt.Errorf("backup of %s is truncated: expected %d rows, found %d", current.name(), *record.Count, restored)
+			}
+			if err := verify(db, current, restored); err != nil {
+				return nil, err
+			}
+			result.Tables = append(result.Tables, TableResult{Table: current.name(), Rows: restored})
+			opts.progress(current.name(), restored)
+			current = nil
+
+		case record.Table != "":
+			if current != nil {
+				return nil, fmt.Errorf("backup of %s is truncated", current.name())
+			}
+			if opts.skip(record.Table) {
+				skipped = true
+				continue
+			}
+			t, ok := byName[record.Table]
+			if !ok {
+				return nil, fmt.Errorf("backup contains unknown table %s", record.Table)
+			}
+			current, restored = t, 0
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("backup of %s is truncated", current.name())
+	}
+
+	if err := Finish(ctx, db, models); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// decodeRow converts the JSON values of a backup row to the Go types of the
+// table's fields. Columns no longer in the model are dropped.
+func decodeRow(t *table, raw map[string]json.RawMessage) (map[string]any, error) {
+	row := make(map[string]any, len(t.fields))
+	for _, field := range t.fields {
+		data, ok := raw[field.DBName]
+		if !ok {
+			continue
+		}
+		// Keep SQL NULL; some types would decode null into a JSON "null".
+		if string(data) == "null" {
+			row[field.DBName] = nil
+			continue
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(data, value.Interface()); err != nil {
+			return nil, fmt.Errorf("invalid value for %s.%s: %w", t.name(), field.DBName, err)
+		}
+		row[field.DBName] = value.Elem().Interface()
+	}
+	return row, nil
+}
@@ -0,0 +1,288 @@
+// Package transfer copies the application tables between databases of any
+// supported dialect, either directly or through a dialect-neutral backup file.
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultBatchSize is the number of rows read and written at a time.
+	DefaultBatchSize = 500
+
+	// maxInsertParams keeps multi-row inserts below the bind parameter limits
+	// of all dialects (SQLite 32766, PostgreSQL and MySQL 65535).
+	maxInsertParams = 30000
+)
+
+// Options configures a backup, restore or copy.
+type Options struct {
+	// BatchSize is the number of rows per read and insert. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+	// SkipTables lists tables that are not transferred, e.g. request_logs.
+	SkipTables []string
+	// Progress, when set, is called after each table is transferred.
+	Progress func(table string, rows int64)
+}
+
+func (o Options) batchSize() int {
+	if o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+func (o Options) skip(table string) bool {
+	return slices.Contains(o.SkipTables, table)
+}
+
+func (o Options) progress(table string, rows int64) {
+	if o.Progress != nil {
+		o.Progress(table, rows)
+	}
+}
+
+// TableResult is the number of rows transferred for a table.
+type TableResult struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// Result summarizes a transfer.
+type Result struct {
+	Tables []TableResult `json:"tables"`
+}
+
+// Rows returns the total number of rows transferred.
+func (r *Result) Rows() int64 {
+	var total int64
+	for _, table := range r.Tables {
+		total += table.Rows
+	}
+	return total
+}
+
+// table is a model with its parsed schema.
+type table struct {
+	model  any
+	schema *schema.Schema
+	fields []*schema.Field
+}
+
+func (t *table) name() string {
+	return t.schema.Table
+}
+
+// parseTables parses the schema of each model. Models must be ordered so that
+// referenced tables come first.
+func parseTables(db *gorm.DB, models []any) ([]*table, error) {
+	cache := &sync.Map{}
+	tables := make([]*table, 0, len(models))
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		if s.PrioritizedPrimaryField == nil {
+			return nil, fmt.Errorf("table %s has no single primary key", s.Table)
+		}
+		t := &table{model: model, schema: s}
+		for _, field := range s.Fields {
+			if field.DBName != "" {
+				t.fields = append(t.fields, field)
+			}
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// snapshot runs fn in a read-only transaction so that all tables are read
+// from the same point in time.
+func snapshot(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var opts *sql.TxOptions
+	switch db.Dialector.Name() {
+	case "postgres", "mysql":
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+	// SQLite transactions read a consistent snapshot in WAL mode.
+	return db.WithContext(ctx).Transaction(fn, opts)
+}
+
+// readRows reads all rows of a table in primary key order, including
+// soft-deleted ones, and passes them to fn in batches as column maps.
+func readRows(tx *gorm.DB, t *table, batchSize int, fn func(rows []map[string]any) error) error {
+	dest := reflect.New(reflect.SliceOf(t.schema.ModelType))
+	result := tx.Unscoped().Model(t.model).FindInBatches(dest.Interface(), batchSize, func(batch *gorm.DB, _ int) error {
+		records := dest.Elem()
+		rows := make([]map[string]any, records.Len())
+		for i := range rows {
+			record := records.Index(i)
+			row := make(map[string]any, len(t.fields))
+			for _, field := range t.fields {
+				value, _ := field.ValueOf(tx.Statement.Context, record)
+				row[field.DBName] = value
+			}
+			rows[i] = row
+		}
+		return fn(rows)
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to read %s: %w", t.name(), result.Error)
+	}
+	return nil
+}
+
+// writeRows inserts rows with their primary keys. Column maps bypass model
+// hooks and column defaults, so zero values are stored as they are.
+func writeRows(db *gorm.DB, t *table, rows []map[string]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	chunk := max(1, maxInsertParams/len(t.fields))
+	for start := 0; start < len(rows); start += chunk {
+		part := rows[start:min(start+chunk, len(rows))]
+		if err := db.Table(t.name()).Create(&part).Error; err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", t.name(), err)
+		}
+	}
+	return nil
+}
+
+// countRows returns the number of rows of a table, including soft-deleted ones.
+func countRows(db *gorm.DB, t *table) (int64, error) {
+	var count int64
+	if err := db.Table(t.name()).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", t.name(), err)
+	}
+	return count, nil
+}
+
+// PrepareTarget creates the tables of models in db and makes sure they are
+// empty. With clean set, existing rows are deleted instead of failing.
+func PrepareTarget(ctx context.Context, db *gorm.DB, models []any, clean bool) error {
+	db = db.WithContext(ctx)
+	if err := db.AutoMigrate(models...); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+	tables, err := parseTables(db, models)
+	if err != nil {
+		return err
+	}
+
+	var nonEmpty []string
+	for _, t := range tables {
+		count, err := countRows(db, t)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			nonEmpty = append(nonEmpty, t.name())
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return nil
+	}
+	if !clean {
+		return fmt.Errorf("target database is not empty (%v); use a new database or clean it first", nonEmpty)
+	}
+
+	// Delete children before the tables they reference.
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i := len(tables) - 1; i >= 0; i-- {
+			if err := tx.Exec("DELETE FROM " + tx.Statement.Quote(tables[i].name())).Error; err != nil {
+				return fmt.Errorf("failed to clean %s: %w", tables[i].name(), err)
+			}
+		}
+		return nil
+	})
+}
+
+// Finish resets the ID sequences of PostgreSQL tables after rows were
+// inserted with explicit IDs. Other dialects advance them on insert.
+func Finish(ctx context.Context, db *gorm.DB, models []any) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	tables, err := parseTables(db, models)
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+	for _, t := range tables {
+		pk := t.schema.PrioritizedPrimaryField
+		if !pk.AutoIncrement {
+			continue
+		}
+		query := fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE((SELECT MAX(%s) FROM %s), 0) + 1, false)",
+			t.name(), pk.DBName, db.Statement.Quote(pk.DBName), db.Statement.Quote(t.name()))
+		if err := db.Exec(query).Error; err != nil {
+			return fmt.Errorf("failed to reset the id sequence of %s: %w", t.name(), err)
+		}
+	}
+	return nil
+}
+
+// verify checks that a table in db holds the expected number of rows.
+func verify(db *gorm.DB, t *table, expected int64) error {
+	count, err := countRows(db, t)
+	if err != nil {
+		return err
+	}
+	if count != expected {
+		return fmt.Errorf("row count mismatch for %s: expected %d, found %d", t.name(), expected, count)
+	}
+	return nil
+}
+
+// Copy copies the tables of models from src to dst, which must have been
+// prepared with PrepareTarget. Rows are read from a consistent snapshot of
+// src and the row count of every table is verified in dst.
+func Copy(ctx context.Context, src, dst *gorm.DB, models []any, opts Options) (*Result, error) {
+	tables, err := parseTables(src, models)
+	if err != nil {
+		return nil, err
+	}
+	dst = dst.WithContext(ctx)
+
+	result := &Result{}
+	err = snapshot(ctx, src, func(tx *gorm.DB) error {
+		for _, t := range tables {
+			if opts.skip(t.name()) || !tx.Migrator().HasTable(t.name()) {
+				continue
+			}
+			expected, err := countRows(tx, t)
+			if err != nil {
+				return err
+			}
+			if err := readRows(tx, t, opts.batchSize(), func(rows []map[string]any) error {
+				return writeRows(dst, t, rows)
+			}); err != nil {
+				return err
+			}
+			if err := verify(dst, t, expected); err != nil {
+				return err
+			}
+			result.Tables = append(result.Tables, TableResult{Table: t.name(), Rows: expected})
+			opts.progress(t.name(), expected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := Finish(ctx, dst, models); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,243 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testGroup struct {
+	ID        uint           `gorm:"primaryKey;autoIncrement"`
+	Name      string         `gorm:"uniqueIndex;not null"`
+	Enabled   bool           `gorm:"default:true"`
+	Config    datatypes.JSON `gorm:"type:json"`
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type testKey struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	GroupID     uint `gorm:"not null;index"`
+	Value       string
+	LastUsedAt  *time.Time
+	RequestCost float64
+	Group       testGroup `gorm:"foreignKey:GroupID"`
+}
+
+type testLog struct {
+	ID      string `gorm:"type:varchar(36);primaryKey"`
+	Message string
+}
+
+func testModels() []any {
+	return []any{&testGroup{}, &testKey{}, &testLog{}}
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=foreign_keys(1)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func seedTestDB(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	require.NoError(t, db.AutoMigrate(testModels()...))
+
+	used := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	groups := []testGroup{
+		{Name: "openai", Enabled: true, Config: datatypes.JSON(`{"a":1}`)},
+		{Name: "disabled", Config: datatypes.JSON(`{}`)},
+		{Name: "deleted", Enabled: true},
+	}
+	require.NoError(t, db.Create(&groups).Error)
+	// The default would turn false into true on a regular model insert.
+	require.NoError(t, db.Model(&groups[1]).Update("enabled", false).Error)
+	require.NoError(t, db.Delete(&groups[2]).Error)
+
+	for i := range 7 {
+		key := testKey{GroupID: groups[i%2].ID, Value: strings.Repeat("k", i+1), RequestCost: 0.5}
+		if i == 0 {
+			key.LastUsedAt = &used
+		}
+		require.NoError(t, db.Create(&key).Error)
+	}
+	require.NoError(t, db.Create(&[]testLog{{ID: "b-2", Message: "second"}, {ID: "a-1", Message: "first"}}).Error)
+}
+
+func assertSameData(t *testing.T, src, dst *gorm.DB) {
+	t.Helper()
+	var srcGroups, dstGroups []testGroup
+	require.NoError(t, src.Unscoped().Order("id").Find(&srcGroups).Error)
+	require.NoError(t, dst.Unscoped().Order("id").Find(&dstGroups).Error)
+	require.Len(t, dstGroups, 3)
+	for i := range srcGroups {
+		assert.Equal(t, srcGroups[i].ID, dstGroups[i].ID)
+		assert.Equal(t, srcGroups[i].Name, dstGroups[i].Name)
+		assert.Equal(t, srcGroups[i].Enabled, dstGroups[i].Enabled)
+		assert.Equal(t, string(srcGroups[i].Config), string(dstGroups[i].Config))
+		assert.True(t, srcGroups[i].CreatedAt.Equal(dstGroups[i].CreatedAt))
+		assert.Equal(t, srcGroups[i].DeletedAt.Valid, dstGroups[i].DeletedAt.Valid)
+	}
+	assert.False(t, dstGroups[1].Enabled)
+
+	var srcKeys, dstKeys []testKey
+	require.NoError(t, src.Order("id").Find(&srcKeys).Error)
+	require.NoError(t, dst.Order("id").Find(&dstKeys).Error)
+	require.Len(t, dstKeys, 7)
+	for i := range srcKeys {
+		assert.Equal(t, srcKeys[i].ID, dstKeys[i].ID)
+		assert.Equal(t, srcKeys[i].GroupID, dstKeys[i].GroupID)
+		assert.Equal(t, srcKeys[i].Value, dstKeys[i].Value)
+		assert.Equal(t, srcKeys[i].RequestCost, dstKeys[i].RequestCost)
+	}
+	require.NotNil(t, dstKeys[0].LastUsedAt)
+	assert.True(t, srcKeys[0].LastUsedAt.Equal(*dstKeys[0].LastUsedAt))
+	assert.Nil(t, dstKeys[1].LastUsedAt)
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openTestDB(t), openTestDB(t)
+	seedTestDB(t, src)
+
+	require.NoError(t, PrepareTarget(ctx, dst, testModels(), false))
+	var progress []string
+	result, err := Copy(ctx, src, dst, testModels(), Options{
+		BatchSize: 2,
+		Progress:  func(table string, rows int64) { progress = append(progress, table) },
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []TableResult{{"test_groups", 3}, {"test_keys", 7}, {"test_logs", 2}}, result.Tables)
+	assert.Equal(t, int64(12), result.Rows())
+	assert.Equal(t, []string{"test_groups", "test_keys", "test_logs"}, progress)
+	assertSameData(t, src, dst)
+
+	// New rows continue after the copied IDs.
+	next := testGroup{Name: "new"}
+	require.NoError(t, dst.Create(&next).Error)
+	assert.Equal(t, uint(4), next.ID)
+}
+
+func TestCopySkipTables(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openTestDB(t), openTestDB(t)
+	seedTestDB(t, src)
+
+	require.NoError(t, PrepareTarget(ctx, dst, testModels(), false))
+	result, err := Copy(ctx, src, dst, testModels(), Options{SkipTables: []string{"test_logs"}})
+	require.NoError(t, err)
+	assert.Len(t, result.Tables, 2)
+
+	var count int64
+	require.NoError(t, dst.Model(&testLog{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestPrepareTarget(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	seedTestDB(t, db)
+
+	err := PrepareTarget(ctx, db, testModels(), false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not empty")
+
+	require.NoError(t, PrepareTarget(ctx, db, testModels(), true))
+	for _, model := range testModels() {
+		var count int64
+		require.NoError(t, db.Model(model).Unscoped().Count(&count).Error)
+		assert.Zero(t, count, "%T", model)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	src, dst := openTestDB(t), openTestDB(t)
+	seedTestDB(t, src)
+
+	var buf bytes.Buffer
+	backup, err := Backup(ctx, src, &buf, testModels(), Options{BatchSize: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), backup.Rows())
+	assert.True(t, strings.HasPrefix(buf.String(), `{"format":"gpt-load-backup","version":1,`))
+
+	require.NoError(t, PrepareTarget(ctx, dst, testModels(), false))
+	restored, err := Restore(ctx, dst, bytes.NewReader(buf.Bytes()), testModels(), Options{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, backup.Tables, restored.Tables)
+	assertSameData(t, src, dst)
+}
+
+func TestRestoreErrors(t *testing.T) {
+	ctx := context.Background()
+	src := openTestDB(t)
+	seedTestDB(t, src)
+	var buf bytes.Buffer
+	_, err := Backup(ctx, src, &buf, testModels(), Options{})
+	require.NoError(t, err)
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	tests := []struct {
+		name    string
+		backup  string
+		wantErr string
+	}{
+		{name: "not a backup", backup: `{"hello":"world"}`, wantErr: "not a gpt-load backup"},
+		{name: "truncated", backup: strings.Join(lines[:4], ""), wantErr: "truncated"},
+		{name: "unknown table", backup: lines[0] + `{"table":"secrets"}` + "\n", wantErr: "unknown table secrets"},
+		{name: "count mismatch", backup: lines[0] + `{"table":"test_logs"}` + "\n" + `{"table":"test_logs","count":1}` + "\n", wantErr: "expected 1 rows, found 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := openTestDB(t)
+			require.NoError(t, PrepareTarget(ctx, dst, testModels(), false))
+			_, err := Restore(ctx, dst, strings.NewReader(tt.backup), testModels(), Options{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestFinishResetsPostgresSequences(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	for _, table := range []string{"test_groups", "test_keys"} {
+		mock.ExpectExec(regexp.QuoteMeta(
+			`SELECT setval(pg_get_serial_sequence('` + table + `', 'id'), COALESCE((SELECT MAX("id") FROM "` + table + `"), 0) + 1, false)`,
+		)).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	// test_logs has a string key and no sequence.
+	require.NoError(t, Finish(context.Background(), db, testModels()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFinishIgnoresOtherDialects(t *testing.T) {
+	db := openTestDB(t)
+	assert.NoError(t, Finish(context.Background(), db, testModels()))
+}
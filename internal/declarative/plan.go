@@ -0,0 +1,92 @@
+package declarative
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Change actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Change is one planned modification. Fields lists what differs; values are
+// never included so that a plan can be printed and logged without leaking
+// secrets.
+type Change struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+	Note   string   `json:"note,omitempty"`
+
+	apply func(ctx context.Context) error
+}
+
+// Plan is the ordered list of changes needed to reach the declared state.
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Notes are informational and do not require any change.
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Empty reports whether the database already matches the configuration.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Summary counts the changes by action.
+func (p *Plan) Summary() string {
+	var create, update, del int
+	for _, change := range p.Changes {
+		switch change.Action {
+		case ActionCreate:
+			create++
+		case ActionUpdate:
+			update++
+		case ActionDelete:
+			del++
+		}
+	}
+	return fmt.Sprintf("%d to create, %d to update, %d to delete", create, update, del)
+}
+
+// Write prints the plan in a diff-like format.
+func (p *Plan) Write(w io.Writer) error {
+	var b strings.Builder
+	if p.Empty() {
+		b.WriteString("No changes. The database matches the configuration.\n")
+	} else {
+		for _, change := range p.Changes {
+			symbol := "~"
+			switch change.Action {
+			case ActionCreate:
+				symbol = "+"
+			case ActionDelete:
+				symbol = "-"
+			}
+			fmt.Fprintf(&b, "%s %s %q", symbol, change.Kind, change.Name)
+			if len(change.Fields) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(change.Fields, ", "))
+			}
+			if change.Note != "" {
+				fmt.Fprintf(&b, ": %s", change.Note)
+			}
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "\nPlan: %s.\n", p.Summary())
+	}
+	for _, note := range p.Notes {
+		fmt.Fprintf(&b, "Note: %s\n", note)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Plan) add(change Change) {
+	p.Changes = append(p.Changes, change)
+}
@@ -0,0 +1,838 @@
+package declarative
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"gpt-load/internal/centralizedmgmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/dig"
+	"gorm.io/gorm"
+)
+
+const (
+	kindSettings     = "settings"
+	kindGroup        = "group"
+	kindKeys         = "keys"
+	kindSubGroup     = "sub_group"
+	kindHubPriority  = "hub_priority"
+	kindHubAccessKey = "hub_access_key"
+
+	// keyHashQueryChunk bounds the number of hashes per IN query.
+	keyHashQueryChunk = 500
+)
+
+// ReconcilerParams defines the dependencies of the Reconciler.
+type ReconcilerParams struct {
+	dig.In
+	DB                    *gorm.DB
+	SettingsManager       *config.SystemSettingsManager
+	GroupService          *services.GroupService
+	AggregateGroupService *services.AggregateGroupService
+	KeyService            *services.KeyService
+	HubService            *centralizedmgmt.HubService
+	AccessKeyService      *centralizedmgmt.HubAccessKeyService
+	EncryptionSvc         encryption.Service
+}
+
+// Reconciler compares a Spec with the database and applies the difference
+// through the same services the management API uses, so every change goes
+// through the usual validation and cache invalidation.
+type Reconciler struct {
+	db                    *gorm.DB
+	settingsManager       *config.SystemSettingsManager
+	groupService          *services.GroupService
+	aggregateGroupService *services.AggregateGroupService
+	keyService            *services.KeyService
+	hubService            *centralizedmgmt.HubService
+	accessKeyService      *centralizedmgmt.HubAccessKeyService
+	encryptionSvc         encryption.Service
+}
+
+// NewReconciler creates a Reconciler.
+func NewReconciler(params ReconcilerParams) *Reconciler {
+	return &Reconciler{
+		db:                    params.DB,
+		settingsManager:       params.SettingsManager,
+		groupService:          params.GroupService,
+		aggregateGroupService: params.AggregateGroupService,
+		keyService:            params.KeyService,
+		hubService:            params.HubService,
+		accessKeyService:      params.AccessKeyService,
+		encryptionSvc:         params.EncryptionSvc,
+	}
+}
+
+// Plan computes the changes needed to make the database match the spec.
+// It only reads from the database.
+func (r *Reconciler) Plan(ctx context.Context, spec *Spec) (*Plan, error) {
+	var groups []models.Group
+	if err := r.db.WithContext(ctx).Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	state := &dbState{
+		groups:     make(map[string]*models.Group, len(groups)),
+		groupNames: make(map[uint]string, len(groups)),
+	}
+	for i := range groups {
+		state.groups[groups[i].Name] = &groups[i]
+		state.groupNames[groups[i].ID] = groups[i].Name
+	}
+
+	plan := &Plan{}
+	steps := []func(context.Context, *Spec, *dbState, *Plan) error{
+		r.planSettings,
+		r.planGroups,
+		r.planKeys,
+		r.planSubGroups,
+		r.planHubPriorities,
+		r.planHubAccessKeys,
+	}
+	for _, step := range steps {
+		if err := step(ctx, spec, state, plan); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// Apply executes the changes of a plan in order and stops at the first error.
+// Changes applied before the error are kept; running apply again resumes from
+// where it stopped because the plan is recomputed from the database.
+func (r *Reconciler) Apply(ctx context.Context, plan *Plan) error {
+	hubChanged := false
+	for _, change := range plan.Changes {
+		if err := change.apply(ctx); err != nil {
+			return fmt.Errorf("failed to %s %s %q: %s", change.Action, change.Kind, change.Name, describeError(err))
+		}
+		logrus.WithFields(logrus.Fields{
+			"action": change.Action,
+			"kind":   change.Kind,
+			"name":   change.Name,
+		}).Info("Declarative configuration change applied")
+		if change.Kind == kindHubPriority || change.Kind == kindHubAccessKey || change.Kind == kindGroup || change.Kind == kindSubGroup {
+			hubChanged = true
+		}
+	}
+	if hubChanged && r.hubService != nil {
+		r.hubService.InvalidateModelPoolCache()
+	}
+	return nil
+}
+
+// Reconcile plans and applies in one step, returning the applied plan.
+func (r *Reconciler) Reconcile(ctx context.Context, spec *Spec) (*Plan, error) {
+	plan, err := r.Plan(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Empty() {
+		return plan, nil
+	}
+	return plan, r.Apply(ctx, plan)
+}
+
+// dbState holds the rows read once at the start of planning.
+type dbState struct {
+	groups     map[string]*models.Group
+	groupNames map[uint]string
+}
+
+// describeError includes the message ID of service errors, whose Error()
+// only carries the generic API error text.
+func describeError(err error) string {
+	var i18nErr *services.I18nError
+	if errors.As(err, &i18nErr) && i18nErr.MessageID != "" {
+		if len(i18nErr.Template) > 0 {
+			return fmt.Sprintf("%s (%s %v)", err.Error(), i18nErr.MessageID, i18nErr.Template)
+		}
+		return fmt.Sprintf("%s (%s)", err.Error(), i18nErr.MessageID)
+	}
+	return err.Error()
+}
+
+// groupID resolves a group name at apply time, when it may have been created
+// by an earlier change of the same plan.
+func (r *Reconciler) groupID(ctx context.Context, name string) (uint, error) {
+	var group models.Group
+	if err := r.db.WithContext(ctx).Select("id").Where("name = ?", name).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("group %s not found", name)
+		}
+		return 0, err
+	}
+	return group.ID, nil
+}
+
+func (r *Reconciler) planSettings(ctx context.Context, spec *Spec, _ *dbState, plan *Plan) error {
+	if len(spec.Settings) == 0 {
+		return nil
+	}
+
+	var rows []models.SystemSetting
+	if err := r.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load system settings: %w", err)
+	}
+	current := make(map[string]string, len(rows))
+	for _, row := range rows {
+		current[row.SettingKey] = row.SettingValue
+	}
+
+	// Values are compared the way UpdateSettings stores them.
+	changed := make(map[string]any)
+	for key, value := range spec.Settings {
+		if stored, ok := current[key]; !ok || stored != fmt.Sprintf("%v", value) {
+			changed[key] = value
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	plan.add(Change{
+		Action: ActionUpdate,
+		Kind:   kindSettings,
+		Name:   "system",
+		Fields: sortedKeys(changed),
+		apply: func(context.Context) error {
+			return r.settingsManager.UpdateSettings(changed)
+		},
+	})
+	return nil
+}
+
+func (r *Reconciler) planGroups(_ context.Context, spec *Spec, state *dbState, plan *Plan) error {
+	for i := range spec.Groups {
+		gs := &spec.Groups[i]
+		existing, ok := state.groups[gs.Name]
+		if !ok {
+			r.planGroupCreate(gs, plan)
+			continue
+		}
+		if existing.GroupType != gs.GroupType {
+			return fmt.Errorf("group %s is %s in the database and %s in the configuration; group_type cannot be changed", gs.Name, existing.GroupType, gs.GroupType)
+		}
+
+		fields, params, err := diffGroup(existing, gs)
+		if err != nil {
+			return fmt.Errorf("group %s: %w", gs.Name, err)
+		}
+		toggle := gs.Enabled != nil && *gs.Enabled != existing.Enabled
+		if toggle {
+			fields = append(fields, "enabled")
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		id := existing.ID
+		updateFields := len(fields) > 1 || !toggle
+		plan.add(Change{
+			Action: ActionUpdate,
+			Kind:   kindGroup,
+			Name:   gs.Name,
+			Fields: fields,
+			apply: func(ctx context.Context) error {
+				if updateFields {
+					if _, err := r.groupService.UpdateGroup(ctx, id, params); err != nil {
+						return err
+					}
+				}
+				if toggle {
+					return r.groupService.ToggleGroupEnabled(ctx, id, *gs.Enabled)
+				}
+				return nil
+			},
+		})
+	}
+	return nil
+}
+
+func (r *Reconciler) planGroupCreate(gs *GroupSpec, plan *Plan) {
+	params := services.GroupCreateParams{
+		Name:                 gs.Name,
+		DisplayName:          deref(gs.DisplayName),
+		Description:          deref(gs.Description),
+		GroupType:            gs.GroupType,
+		Upstreams:            gs.Upstreams,
+		ChannelType:          gs.ChannelType,
+		TestModel:            deref(gs.TestModel),
+		ValidationEndpoint:   deref(gs.ValidationEndpoint),
+		ParamOverrides:       gs.ParamOverrides,
+		Config:               gs.Config,
+		ModelRedirectRulesV2: gs.ModelRedirectRulesV2,
+		ProxyKeys:            deref(gs.ProxyKeys),
+	}
+	if gs.Sort != nil {
+		params.Sort = *gs.Sort
+	}
+	if gs.HeaderRules != nil {
+		params.HeaderRules = *gs.HeaderRules
+	}
+	if gs.ModelRedirectStrict != nil {
+		params.ModelRedirectStrict = *gs.ModelRedirectStrict
+	}
+	if gs.PathRedirects != nil {
+		params.PathRedirects = *gs.PathRedirects
+	}
+
+	plan.add(Change{
+		Action: ActionCreate,
+		Kind:   kindGroup,
+		Name:   gs.Name,
+		apply: func(ctx context.Context) error {
+			group, err := r.groupService.CreateGroup(ctx, params)
+			if err != nil {
+				return err
+			}
+			if gs.Enabled != nil && !*gs.Enabled {
+				return r.groupService.ToggleGroupEnabled(ctx, group.ID, false)
+			}
+			return nil
+		},
+	})
+}
+
+// diffGroup returns the declared fields that differ from the stored group and
+// the update parameters that change only those fields. JSON fields are
+// normalized the way GroupService stores them before comparing.
+func diffGroup(g *models.Group, gs *GroupSpec) ([]string, services.GroupUpdateParams, error) {
+	var fields []string
+	var params services.GroupUpdateParams
+
+	if gs.DisplayName != nil && *gs.DisplayName != g.DisplayName {
+		fields = append(fields, "display_name")
+		params.DisplayName = gs.DisplayName
+	}
+	if gs.Description != nil && *gs.Description != g.Description {
+		fields = append(fields, "description")
+		params.Description = gs.Description
+	}
+	// The channel type of aggregate groups follows their sub-groups.
+	if gs.GroupType == "standard" && gs.ChannelType != "" && strings.TrimSpace(gs.ChannelType) != g.ChannelType {
+		fields = append(fields, "channel_type")
+		params.ChannelType = &gs.ChannelType
+	}
+	if gs.Sort != nil && *gs.Sort != g.Sort {
+		fields = append(fields, "sort")
+		params.Sort = gs.Sort
+	}
+	if gs.TestModel != nil && strings.TrimSpace(*gs.TestModel) != g.TestModel {
+		fields = append(fields, "test_model")
+		params.TestModel = *gs.TestModel
+		params.HasTestModel = true
+	}
+	if gs.ValidationEndpoint != nil && strings.TrimSpace(*gs.ValidationEndpoint) != g.ValidationEndpoint {
+		fields = append(fields, "validation_endpoint")
+		params.ValidationEndpoint = gs.ValidationEndpoint
+	}
+	if gs.ProxyKeys != nil && strings.TrimSpace(*gs.ProxyKeys) != g.ProxyKeys {
+		fields = append(fields, "proxy_keys")
+		params.ProxyKeys = gs.ProxyKeys
+	}
+	if gs.ModelRedirectStrict != nil && *gs.ModelRedirectStrict != g.ModelRedirectStrict {
+		fields = append(fields, "model_redirect_strict")
+		params.ModelRedirectStrict = gs.ModelRedirectStrict
+	}
+
+	if len(gs.Upstreams) > 0 {
+		declared, err := normalizeUpstreams(gs.Upstreams)
+		if err != nil {
+			return nil, params, fmt.Errorf("invalid upstreams: %w", err)
+		}
+		if declared != canonicalJSON(g.Upstreams) {
+			fields = append(fields, "upstreams")
+			params.Upstreams = gs.Upstreams
+			params.HasUpstreams = true
+		}
+	}
+	if gs.Config != nil && !sameMap(gs.Config, g.Config) {
+		fields = append(fields, "config")
+		params.Config = gs.Config
+	}
+	if gs.ParamOverrides != nil && !sameMap(gs.ParamOverrides, g.ParamOverrides) {
+		fields = append(fields, "param_overrides")
+		params.ParamOverrides = gs.ParamOverrides
+	}
+	if gs.HeaderRules != nil && normalizeHeaderRules(*gs.HeaderRules) != canonicalJSON(g.HeaderRules) {
+		fields = append(fields, "header_rules")
+		params.HeaderRules = gs.HeaderRules
+	}
+	if len(gs.ModelRedirectRulesV2) > 0 {
+		declared, err := normalizeModelRedirectRules(gs.ModelRedirectRulesV2)
+		if err != nil {
+			return nil, params, fmt.Errorf("invalid model_redirect_rules_v2: %w", err)
+		}
+		stored, _ := normalizeModelRedirectRules(json.RawMessage(g.ModelRedirectRulesV2))
+		if declared != stored {
+			fields = append(fields, "model_redirect_rules_v2")
+			params.ModelRedirectRulesV2 = gs.ModelRedirectRulesV2
+		}
+	}
+	if gs.PathRedirects != nil && normalizePathRedirects(*gs.PathRedirects) != canonicalJSON(g.PathRedirects) {
+		fields = append(fields, "path_redirects")
+		params.PathRedirects = append([]models.PathRedirectRule{}, *gs.PathRedirects...)
+	}
+	return fields, params, nil
+}
+
+func (r *Reconciler) planKeys(ctx context.Context, spec *Spec, state *dbState, plan *Plan) error {
+	for i := range spec.Groups {
+		gs := &spec.Groups[i]
+		if len(gs.Keys) == 0 {
+			continue
+		}
+
+		hashes := make(map[string]string)
+		for _, ref := range gs.Keys {
+			secret, err := ref.Resolve(spec.baseDir)
+			if err != nil {
+				return fmt.Errorf("group %s: key %s: %w", gs.Name, ref, err)
+			}
+			for _, key := range utils.DelimitersPattern.Split(secret, -1) {
+				if key = strings.TrimSpace(key); key != "" {
+					hashes[r.encryptionSvc.Hash(key)] = key
+				}
+			}
+		}
+		if len(hashes) == 0 {
+			return fmt.Errorf("group %s: the referenced keys are empty", gs.Name)
+		}
+
+		var total int64
+		if existing, ok := state.groups[gs.Name]; ok {
+			present, err := r.existingKeyHashes(ctx, existing.ID, sortedKeys(hashes))
+			if err != nil {
+				return fmt.Errorf("failed to load keys of group %s: %w", gs.Name, err)
+			}
+			for _, hash := range present {
+				delete(hashes, hash)
+			}
+			if err := r.db.WithContext(ctx).Model(&models.APIKey{}).Where("group_id = ?", existing.ID).Count(&total).Error; err != nil {
+				return fmt.Errorf("failed to count keys of group %s: %w", gs.Name, err)
+			}
+			if undeclared := int(total) - len(present); undeclared > 0 {
+				plan.Notes = append(plan.Notes, fmt.Sprintf("group %q has %d keys that are not in the configuration; they are kept", gs.Name, undeclared))
+			}
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+
+		keys := make([]string, 0, len(hashes))
+		for _, hash := range sortedKeys(hashes) {
+			keys = append(keys, hashes[hash])
+		}
+		name := gs.Name
+		plan.add(Change{
+			Action: ActionCreate,
+			Kind:   kindKeys,
+			Name:   name,
+			Note:   fmt.Sprintf("%d keys", len(keys)),
+			apply: func(ctx context.Context) error {
+				id, err := r.groupID(ctx, name)
+				if err != nil {
+					return err
+				}
+				for start := 0; start < len(keys); start += services.BulkSyncThreshold {
+					end := min(start+services.BulkSyncThreshold, len(keys))
+					if _, err := r.keyService.AddMultipleKeys(id, strings.Join(keys[start:end], "\n")); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+	return nil
+}
+
+func (r *Reconciler) existingKeyHashes(ctx context.Context, groupID uint, hashes []string) ([]string, error) {
+	var present []string
+	for start := 0; start < len(hashes); start += keyHashQueryChunk {
+		end := min(start+keyHashQueryChunk, len(hashes))
+		var chunk []string
+		if err := r.db.WithContext(ctx).Model(&models.APIKey{}).
+			Where("group_id = ? AND key_hash IN ?", groupID, hashes[start:end]).
+			Distinct().Pluck("key_hash", &chunk).Error; err != nil {
+			return nil, err
+		}
+		present = append(present, chunk...)
+	}
+	return present, nil
+}
+
+// planSubGroups makes the declared sub-group list authoritative: missing
+// sub-groups are added, weights are updated and undeclared ones are removed.
+func (r *Reconciler) planSubGroups(ctx context.Context, spec *Spec, state *dbState, plan *Plan) error {
+	declaredGroups := make(map[string]bool, len(spec.Groups))
+	for _, gs := range spec.Groups {
+		declaredGroups[gs.Name] = true
+	}
+
+	for i := range spec.Groups {
+		gs := &spec.Groups[i]
+		if gs.SubGroups == nil {
+			continue
+		}
+
+		current := make(map[string]models.GroupSubGroup)
+		if existing, ok := state.groups[gs.Name]; ok {
+			var rows []models.GroupSubGroup
+			if err := r.db.WithContext(ctx).Where("group_id = ?", existing.ID).Find(&rows).Error; err != nil {
+				return fmt.Errorf("failed to load sub-groups of %s: %w", gs.Name, err)
+			}
+			for _, row := range rows {
+				current[state.groupNames[row.SubGroupID]] = row
+			}
+		}
+
+		aggregate := gs.Name
+		for _, sub := range *gs.SubGroups {
+			if _, ok := state.groups[sub.Group]; !ok && !declaredGroups[sub.Group] {
+				return fmt.Errorf("group %s: sub-group %s does not exist", aggregate, sub.Group)
+			}
+			name := aggregate + "/" + sub.Group
+			row, ok := current[sub.Group]
+			delete(current, sub.Group)
+			switch {
+			case !ok:
+				plan.add(Change{
+					Action: ActionCreate,
+					Kind:   kindSubGroup,
+					Name:   name,
+					apply: func(ctx context.Context) error {
+						groupID, subID, err := r.subGroupIDs(ctx, aggregate, sub.Group)
+						if err != nil {
+							return err
+						}
+						return r.aggregateGroupService.AddSubGroups(ctx, groupID, []services.SubGroupInput{{GroupID: subID, Weight: sub.Weight}})
+					},
+				})
+			case row.Weight != sub.Weight:
+				plan.add(Change{
+					Action: ActionUpdate,
+					Kind:   kindSubGroup,
+					Name:   name,
+					Fields: []string{"weight"},
+					apply: func(ctx context.Context) error {
+						return r.aggregateGroupService.UpdateSubGroupWeight(ctx, row.GroupID, row.SubGroupID, services.UpdateSubGroupSettingsInput{Weight: sub.Weight})
+					},
+				})
+			}
+		}
+
+		for _, subName := range sortedKeys(current) {
+			row := current[subName]
+			plan.add(Change{
+				Action: ActionDelete,
+				Kind:   kindSubGroup,
+				Name:   aggregate + "/" + subName,
+				apply: func(ctx context.Context) error {
+					return r.aggregateGroupService.DeleteSubGroup(ctx, row.GroupID, row.SubGroupID)
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) subGroupIDs(ctx context.Context, aggregate, sub string) (uint, uint, error) {
+	groupID, err := r.groupID(ctx, aggregate)
+	if err != nil {
+		return 0, 0, err
+	}
+	subID, err := r.groupID(ctx, sub)
+	if err != nil {
+		return 0, 0, err
+	}
+	return groupID, subID, nil
+}
+
+func (r *Reconciler) planHubPriorities(ctx context.Context, spec *Spec, state *dbState, plan *Plan) error {
+	if len(spec.Hub.Priorities) == 0 {
+		return nil
+	}
+
+	declaredGroups := make(map[string]bool, len(spec.Groups))
+	for _, gs := range spec.Groups {
+		declaredGroups[gs.Name] = true
+	}
+	modelNames := make([]string, 0, len(spec.Hub.Priorities))
+	for _, priority := range spec.Hub.Priorities {
+		modelNames = append(modelNames, priority.Model)
+	}
+	var rows []centralizedmgmt.HubModelGroupPriority
+	if err := r.db.WithContext(ctx).Where("model_name IN ?", modelNames).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load hub priorities: %w", err)
+	}
+	current := make(map[string]int, len(rows))
+	for _, row := range rows {
+		current[row.ModelName+"\x00"+state.groupNames[row.GroupID]] = row.Priority
+	}
+
+	for _, priority := range spec.Hub.Priorities {
+		if _, ok := state.groups[priority.Group]; !ok && !declaredGroups[priority.Group] {
+			return fmt.Errorf("hub priority %s/%s: group %s does not exist", priority.Model, priority.Group, priority.Group)
+		}
+		stored, ok := current[priority.Model+"\x00"+priority.Group]
+		if ok && stored == priority.Priority {
+			continue
+		}
+		action := ActionCreate
+		var fields []string
+		if ok {
+			action, fields = ActionUpdate, []string{"priority"}
+		}
+		plan.add(Change{
+			Action: action,
+			Kind:   kindHubPriority,
+			Name:   priority.Model + "/" + priority.Group,
+			Fields: fields,
+			apply: func(ctx context.Context) error {
+				id, err := r.groupID(ctx, priority.Group)
+				if err != nil {
+					return err
+				}
+				return r.hubService.UpdateModelGroupPriority(ctx, priority.Model, id, priority.Priority)
+			},
+		})
+	}
+	return nil
+}
+
+func (r *Reconciler) planHubAccessKeys(ctx context.Context, spec *Spec, _ *dbState, plan *Plan) error {
+	if len(spec.Hub.AccessKeys) == 0 {
+		return nil
+	}
+
+	var rows []centralizedmgmt.HubAccessKey
+	if err := r.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load hub access keys: %w", err)
+	}
+	current := make(map[string]centralizedmgmt.HubAccessKey, len(rows))
+	for _, row := range rows {
+		current[row.Name] = row
+	}
+
+	for _, key := range spec.Hub.AccessKeys {
+		value, err := key.Key.Resolve(spec.baseDir)
+		if err != nil {
+			return fmt.Errorf("hub access key %s: %w", key.Name, err)
+		}
+		if value == "" {
+			return fmt.Errorf("hub access key %s: the referenced key is empty", key.Name)
+		}
+		r.planHubAccessKey(key, value, current, plan)
+	}
+	return nil
+}
+
+func (r *Reconciler) planHubAccessKey(key HubAccessKeySpec, value string, current map[string]centralizedmgmt.HubAccessKey, plan *Plan) {
+	enabled := key.Enabled == nil || *key.Enabled
+	create := func(ctx context.Context) error {
+		_, _, err := r.accessKeyService.CreateAccessKey(ctx, centralizedmgmt.CreateAccessKeyParams{
+			Name:          key.Name,
+			KeyValue:      value,
+			AllowedModels: key.AllowedModels,
+			Enabled:       enabled,
+		})
+		return err
+	}
+
+	existing, ok := current[key.Name]
+	if !ok {
+		plan.add(Change{Action: ActionCreate, Kind: kindHubAccessKey, Name: key.Name, apply: create})
+		return
+	}
+
+	// A different key value cannot be updated in place; the key is replaced.
+	if existing.KeyHash != r.encryptionSvc.Hash(value) {
+		id := existing.ID
+		plan.add(Change{
+			Action: ActionUpdate,
+			Kind:   kindHubAccessKey,
+			Name:   key.Name,
+			Fields: []string{"key"},
+			Note:   "replaced",
+			apply: func(ctx context.Context) error {
+				if err := r.accessKeyService.DeleteAccessKey(ctx, id); err != nil {
+					return err
+				}
+				return create(ctx)
+			},
+		})
+		return
+	}
+
+	var fields []string
+	params := centralizedmgmt.UpdateAccessKeyParams{}
+	var storedModels []string
+	_ = json.Unmarshal(existing.AllowedModels, &storedModels)
+	if !sameStrings(storedModels, key.AllowedModels) {
+		fields = append(fields, "allowed_models")
+	}
+	// UpdateAccessKey treats nil allowed models as unchanged, so they are
+	// always sent and an empty list clears the restriction.
+	params.AllowedModels = append([]string{}, key.AllowedModels...)
+	if key.Enabled != nil && *key.Enabled != existing.Enabled {
+		fields = append(fields, "enabled")
+		params.Enabled = key.Enabled
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	id := existing.ID
+	plan.add(Change{
+		Action: ActionUpdate,
+		Kind:   kindHubAccessKey,
+		Name:   key.Name,
+		Fields: fields,
+		apply: func(ctx context.Context) error {
+			_, err := r.accessKeyService.UpdateAccessKey(ctx, id, params)
+			return err
+		},
+	})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sameStrings(a, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
+
+// canonicalJSON re-encodes a JSON document with sorted object keys. Null and
+// empty arrays or objects all map to the empty string.
+func canonicalJSON(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []any:
+		if len(v) == 0 {
+			return ""
+		}
+	case map[string]any:
+		if len(v) == 0 {
+			return ""
+		}
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func canonicalValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return canonicalJSON(data)
+}
+
+func sameMap(declared map[string]any, stored map[string]any) bool {
+	return canonicalValue(declared) == canonicalValue(stored)
+}
+
+// normalizeUpstreams mirrors the cleaning done by GroupService so that a
+// declared list compares equal to what was stored from it.
+func normalizeUpstreams(raw json.RawMessage) (string, error) {
+	var defs []struct {
+		URL          string  `json:"url"`
+		Weight       int     `json:"weight"`
+		ProxyURL     *string `json:"proxy_url,omitempty"`
+		GatewayProxy string  `json:"gateway_proxy,omitempty"`
+	}
+	if err := json.Unmarshal(raw, &defs); err != nil {
+		return "", err
+	}
+	for i := range defs {
+		defs[i].URL = strings.TrimSpace(defs[i].URL)
+		defs[i].GatewayProxy = strings.ToLower(strings.TrimSpace(defs[i].GatewayProxy))
+		if defs[i].ProxyURL == nil {
+			continue
+		}
+		proxyValue := strings.TrimSpace(*defs[i].ProxyURL)
+		switch {
+		case proxyValue == "":
+			defs[i].ProxyURL = nil
+		case utils.IsProxyPoolRef(proxyValue):
+			defs[i].ProxyURL = &proxyValue
+		default:
+			if normalized, err := utils.NormalizeProxyURL(proxyValue); err == nil {
+				proxyValue = normalized
+			}
+			defs[i].ProxyURL = &proxyValue
+		}
+	}
+	return canonicalValue(defs), nil
+}
+
+func normalizeHeaderRules(rules []models.HeaderRule) string {
+	normalized := make([]models.HeaderRule, 0, len(rules))
+	for _, rule := range rules {
+		key := strings.TrimSpace(rule.Key)
+		if key == "" {
+			continue
+		}
+		normalized = append(normalized, models.HeaderRule{Key: http.CanonicalHeaderKey(key), Value: rule.Value, Action: rule.Action})
+	}
+	return canonicalValue(normalized)
+}
+
+func normalizePathRedirects(rules []models.PathRedirectRule) string {
+	normalized := make([]models.PathRedirectRule, 0, len(rules))
+	for _, rule := range rules {
+		from, to := strings.TrimSpace(rule.From), strings.TrimSpace(rule.To)
+		if from != "" && to != "" {
+			normalized = append(normalized, models.PathRedirectRule{From: from, To: to})
+		}
+	}
+	return canonicalValue(normalized)
+}
+
+func normalizeModelRedirectRules(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var rules map[string]*models.ModelRedirectRuleV2
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return "", err
+	}
+	return canonicalValue(rules), nil
+}
@@ -0,0 +1,210 @@
+package declarative
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gpt-load/internal/centralizedmgmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newPlanOnlyReconciler returns a Reconciler that can plan against an
+// in-memory database. Applying needs the full set of services.
+func newPlanOnlyReconciler(t *testing.T) (*Reconciler, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(
+		&models.SystemSetting{},
+		&models.Group{},
+		&models.GroupSubGroup{},
+		&models.APIKey{},
+		&models.GroupRevision{},
+		&centralizedmgmt.HubModelGroupPriority{},
+		&centralizedmgmt.HubAccessKey{},
+	))
+
+	encryptionSvc, err := encryption.NewService("")
+	require.NoError(t, err)
+	return NewReconciler(ReconcilerParams{DB: db, EncryptionSvc: encryptionSvc}), db
+}
+
+func seedGroup(t *testing.T, db *gorm.DB, group models.Group) models.Group {
+	t.Helper()
+	if group.Upstreams == nil {
+		group.Upstreams = datatypes.JSON(`[{"url":"https://api.openai.com","weight":1}]`)
+	}
+	if group.GroupType == "" {
+		group.GroupType = "standard"
+	}
+	if group.ChannelType == "" {
+		group.ChannelType = "openai"
+	}
+	if group.TestModel == "" {
+		group.TestModel = "gpt-4o-mini"
+	}
+	group.Enabled = true
+	require.NoError(t, db.Create(&group).Error)
+	return group
+}
+
+func changeNames(plan *Plan) []string {
+	names := make([]string, 0, len(plan.Changes))
+	for _, change := range plan.Changes {
+		names = append(names, change.Action+" "+change.Kind+" "+change.Name)
+	}
+	return names
+}
+
+func TestPlan_CreatesMissingResources(t *testing.T) {
+	r, _ := newPlanOnlyReconciler(t)
+	t.Setenv("GPT_LOAD_SECRET_DECLARATIVE_KEYS", "sk-a,sk-b")
+	t.Setenv("GPT_LOAD_SECRET_DECLARATIVE_HUB_KEY", "hk-secret")
+
+	spec := &Spec{
+		Settings: map[string]any{"request_timeout": float64(600)},
+		Groups: []GroupSpec{
+			{Name: "openai", GroupType: "standard", ChannelType: "openai", Keys: []SecretRef{{Env: "GPT_LOAD_SECRET_DECLARATIVE_KEYS"}}},
+			{Name: "all", GroupType: "aggregate", ChannelType: "openai", SubGroups: &[]SubGroupSpec{{Group: "openai", Weight: 10}}},
+		},
+		Hub: HubSpec{
+			Priorities: []HubPrioritySpec{{Model: "gpt-4o", Group: "openai", Priority: 5}},
+			AccessKeys: []HubAccessKeySpec{{Name: "ci", Key: SecretRef{Env: "GPT_LOAD_SECRET_DECLARATIVE_HUB_KEY"}}},
+		},
+	}
+
+	plan, err := r.Plan(context.Background(), spec)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"update settings system",
+		"create group openai",
+		"create group all",
+		"create keys openai",
+		"create sub_group all/openai",
+		"create hub_priority gpt-4o/openai",
+		"create hub_access_key ci",
+	}, changeNames(plan))
+	assert.Equal(t, "2 keys", plan.Changes[3].Note)
+
+	var out bytes.Buffer
+	require.NoError(t, plan.Write(&out))
+	assert.NotContains(t, out.String(), "sk-a")
+	assert.NotContains(t, out.String(), "hk-secret")
+	assert.Contains(t, out.String(), "Plan: 6 to create, 1 to update, 0 to delete.")
+}
+
+func TestPlan_EmptyWhenDatabaseMatches(t *testing.T) {
+	r, db := newPlanOnlyReconciler(t)
+	t.Setenv("GPT_LOAD_SECRET_DECLARATIVE_KEYS", "sk-a")
+	t.Setenv("GPT_LOAD_SECRET_DECLARATIVE_HUB_KEY", "hk-secret")
+
+	require.NoError(t, db.Create(&models.SystemSetting{SettingKey: "request_timeout", SettingValue: "600"}).Error)
+	openai := seedGroup(t, db, models.Group{
+		Name:        "openai",
+		Config:      datatypes.JSONMap{"max_retries": float64(3)},
+		HeaderRules: datatypes.JSON(`[{"key":"X-Team","value":"a","action":"set"}]`),
+		Sort:        7,
+	})
+	all := seedGroup(t, db, models.Group{Name: "all", GroupType: "aggregate", Upstreams: datatypes.JSON("[]"), TestModel: "-"})
+	require.NoError(t, db.Create(&models.GroupSubGroup{GroupID: all.ID, SubGroupID: openai.ID, Weight: 10}).Error)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: openai.ID, KeyValue: "sk-a", KeyHash: r.encryptionSvc.Hash("sk-a")}).Error)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: openai.ID, KeyValue: "sk-ui", KeyHash: r.encryptionSvc.Hash("sk-ui")}).Error)
+	require.NoError(t, db.Create(&centralizedmgmt.HubModelGroupPriority{ModelName: "gpt-4o", GroupID: openai.ID, Priority: 5}).Error)
+	allowed, _ := json.Marshal([]string{"gpt-4o"})
+	require.NoError(t, db.Create(&centralizedmgmt.HubAccessKey{Name: "ci", KeyHash: r.encryptionSvc.Hash("hk-secret"), KeyValue: "x", AllowedModels: allowed, Enabled: true}).Error)
+
+	sort := 7
+	headerRules := []models.HeaderRule{{Key: "x-team", Value: "a", Action: "set"}}
+	spec := &Spec{
+		Settings: map[string]any{"request_timeout": float64(600)},
+		Groups: []GroupSpec{
+			{
+				Name:        "openai",
+				GroupType:   "standard",
+				ChannelType: "openai",
+				Sort:        &sort,
+				Upstreams:   json.RawMessage(`[{"url": " https://api.openai.com ", "weight": 1}]`),
+				Config:      map[string]any{"max_retries": float64(3)},
+				HeaderRules: &headerRules,
+				Keys:        []SecretRef{{Env: "GPT_LOAD_SECRET_DECLARATIVE_KEYS"}},
+			},
+			{Name: "all", GroupType: "aggregate", SubGroups: &[]SubGroupSpec{{Group: "openai", Weight: 10}}},
+		},
+		Hub: HubSpec{
+			Priorities: []HubPrioritySpec{{Model: "gpt-4o", Group: "openai", Priority: 5}},
+			AccessKeys: []HubAccessKeySpec{{Name: "ci", Key: SecretRef{Env: "GPT_LOAD_SECRET_DECLARATIVE_HUB_KEY"}, AllowedModels: []string{"gpt-4o"}}},
+		},
+	}
+
+	plan, err := r.Plan(context.Background(), spec)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), "unexpected changes: %v", changeNames(plan))
+	assert.Equal(t, []string{`group "openai" has 1 keys that are not in the configuration; they are kept`}, plan.Notes)
+}
+
+func TestPlan_DetectsUpdatesAndRemovals(t *testing.T) {
+	r, db := newPlanOnlyReconciler(t)
+	t.Setenv("GPT_LOAD_SECRET_DECLARATIVE_HUB_KEY", "hk-rotated")
+
+	openai := seedGroup(t, db, models.Group{Name: "openai", DisplayName: "OpenAI"})
+	backup := seedGroup(t, db, models.Group{Name: "backup"})
+	all := seedGroup(t, db, models.Group{Name: "all", GroupType: "aggregate", Upstreams: datatypes.JSON("[]"), TestModel: "-"})
+	require.NoError(t, db.Create(&models.GroupSubGroup{GroupID: all.ID, SubGroupID: openai.ID, Weight: 10}).Error)
+	require.NoError(t, db.Create(&models.GroupSubGroup{GroupID: all.ID, SubGroupID: backup.ID, Weight: 1}).Error)
+	require.NoError(t, db.Create(&centralizedmgmt.HubModelGroupPriority{ModelName: "gpt-4o", GroupID: openai.ID, Priority: 5}).Error)
+	require.NoError(t, db.Create(&centralizedmgmt.HubAccessKey{Name: "ci", KeyHash: r.encryptionSvc.Hash("hk-old"), KeyValue: "x", AllowedModels: datatypes.JSON("[]"), Enabled: true}).Error)
+
+	displayName := "OpenAI Production"
+	disabled := false
+	spec := &Spec{
+		Groups: []GroupSpec{
+			{Name: "openai", GroupType: "standard", DisplayName: &displayName, Enabled: &disabled, Upstreams: json.RawMessage(`[{"url":"https://proxy.example.com","weight":1}]`)},
+			{Name: "all", GroupType: "aggregate", SubGroups: &[]SubGroupSpec{{Group: "openai", Weight: 20}}},
+		},
+		Hub: HubSpec{
+			Priorities: []HubPrioritySpec{{Model: "gpt-4o", Group: "openai", Priority: 1}},
+			AccessKeys: []HubAccessKeySpec{{Name: "ci", Key: SecretRef{Env: "GPT_LOAD_SECRET_DECLARATIVE_HUB_KEY"}}},
+		},
+	}
+
+	plan, err := r.Plan(context.Background(), spec)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"update group openai",
+		"update sub_group all/openai",
+		"delete sub_group all/backup",
+		"update hub_priority gpt-4o/openai",
+		"update hub_access_key ci",
+	}, changeNames(plan))
+	assert.Equal(t, []string{"display_name", "upstreams", "enabled"}, plan.Changes[0].Fields)
+	assert.Equal(t, []string{"key"}, plan.Changes[4].Fields)
+}
+
+func TestPlan_RejectsGroupTypeChangeAndUnknownSubGroup(t *testing.T) {
+	r, db := newPlanOnlyReconciler(t)
+	seedGroup(t, db, models.Group{Name: "openai"})
+
+	_, err := r.Plan(context.Background(), &Spec{Groups: []GroupSpec{{Name: "openai", GroupType: "aggregate"}}})
+	assert.ErrorContains(t, err, "group_type cannot be changed")
+
+	_, err = r.Plan(context.Background(), &Spec{Groups: []GroupSpec{
+		{Name: "all", GroupType: "aggregate", SubGroups: &[]SubGroupSpec{{Group: "missing", Weight: 1}}},
+	}})
+	assert.ErrorContains(t, err, "sub-group missing does not exist")
+}
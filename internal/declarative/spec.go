@@ -0,0 +1,289 @@
+// Package declarative reconciles the database with configuration files kept
+// in version control. A configuration directory describes system settings,
+// groups with their keys and sub-groups, and Hub priorities and access keys.
+package declarative
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/secrets"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Spec is the desired state read from a configuration directory.
+// Resources that are not mentioned are left untouched, and so are omitted
+// fields of a declared group.
+type Spec struct {
+	Settings map[string]any `json:"settings,omitempty"`
+	Groups   []GroupSpec    `json:"groups,omitempty"`
+	Hub      HubSpec        `json:"hub"`
+
+	baseDir string
+}
+
+// GroupSpec declares a group. Pointer and nil fields are only enforced when set.
+type GroupSpec struct {
+	Name                 string                     `json:"name"`
+	DisplayName          *string                    `json:"display_name,omitempty"`
+	Description          *string                    `json:"description,omitempty"`
+	GroupType            string                     `json:"group_type,omitempty"` // standard (default) or aggregate
+	ChannelType          string                     `json:"channel_type"`
+	Enabled              *bool                      `json:"enabled,omitempty"`
+	Sort                 *int                       `json:"sort,omitempty"`
+	TestModel            *string                    `json:"test_model,omitempty"`
+	ValidationEndpoint   *string                    `json:"validation_endpoint,omitempty"`
+	Upstreams            json.RawMessage            `json:"upstreams,omitempty"`
+	ProxyKeys            *string                    `json:"proxy_keys,omitempty"`
+	Config               map[string]any             `json:"config,omitempty"`
+	ParamOverrides       map[string]any             `json:"param_overrides,omitempty"`
+	HeaderRules          *[]models.HeaderRule       `json:"header_rules,omitempty"`
+	ModelRedirectRulesV2 json.RawMessage            `json:"model_redirect_rules_v2,omitempty"`
+	ModelRedirectStrict  *bool                      `json:"model_redirect_strict,omitempty"`
+	PathRedirects        *[]models.PathRedirectRule `json:"path_redirects,omitempty"`
+	// Keys are added when missing; keys added through the UI are kept.
+	Keys []SecretRef `json:"keys,omitempty"`
+	// SubGroups, when set on an aggregate group, is the exact list of sub-groups.
+	SubGroups *[]SubGroupSpec `json:"sub_groups,omitempty"`
+}
+
+// SubGroupSpec declares one sub-group of an aggregate group by name.
+type SubGroupSpec struct {
+	Group  string `json:"group"`
+	Weight int    `json:"weight"`
+}
+
+// HubSpec declares Hub routing priorities and access keys.
+type HubSpec struct {
+	Priorities []HubPrioritySpec  `json:"priorities,omitempty"`
+	AccessKeys []HubAccessKeySpec `json:"access_keys,omitempty"`
+}
+
+// HubPrioritySpec sets the priority of a group for a model (1-999, lower wins).
+type HubPrioritySpec struct {
+	Model    string `json:"model"`
+	Group    string `json:"group"`
+	Priority int    `json:"priority"`
+}
+
+// HubAccessKeySpec declares a Hub access key by name.
+type HubAccessKeySpec struct {
+	Name          string    `json:"name"`
+	Key           SecretRef `json:"key"`
+	AllowedModels []string  `json:"allowed_models,omitempty"` // Empty means all models
+	Enabled       *bool     `json:"enabled,omitempty"`
+}
+
+// SecretRef points at a secret outside the configuration files, so key
+// values never need to be committed.
+type SecretRef struct {
+	Env  string `json:"env,omitempty"`
+	File string `json:"file,omitempty"` // Relative paths are resolved against the configuration directory
+}
+
+// Resolve returns the referenced secret with surrounding whitespace removed.
+// It reads through the same providers as env: and file: key references, so
+// the same allow-lists apply; files below baseDir are allowed as well.
+func (r SecretRef) Resolve(baseDir string) (string, error) {
+	ctx := context.Background()
+	switch {
+	case r.Env != "" && r.File != "":
+		return "", fmt.Errorf("secret reference must set only one of env and file")
+	case r.Env != "":
+		return secrets.NewEnvProviderFromEnv().Fetch(ctx, r.Env)
+	case r.File != "":
+		path := r.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		value, err := secrets.NewFileProviderFromEnv(baseDir).Fetch(ctx, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("secret reference must set env or file")
+	}
+}
+
+// String describes the reference without revealing the secret.
+func (r SecretRef) String() string {
+	if r.Env != "" {
+		return "env:" + r.Env
+	}
+	return "file:" + r.File
+}
+
+// Load reads a configuration file, or every .yaml, .yml and .json file of a
+// directory in name order, and merges them into one Spec.
+func Load(path string) (*Spec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+
+	files := []string{path}
+	baseDir := filepath.Dir(path)
+	if info.IsDir() {
+		baseDir = path
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configuration directory: %w", err)
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() && isConfigFile(entry.Name()) {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		slices.Sort(files)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no .yaml, .yml or .json files found in %s", path)
+		}
+	}
+
+	spec := &Spec{Settings: map[string]any{}, baseDir: baseDir}
+	for _, file := range files {
+		part, err := loadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if err := spec.merge(part); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func isConfigFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// loadFile decodes one file. YAML is converted to JSON first so both formats
+// share the JSON field names and unknown fields are rejected the same way.
+func loadFile(file string) (*Spec, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(filepath.Ext(file)) != ".json" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+	if len(bytes.TrimSpace(data)) == 0 || bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return &Spec{}, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var spec Spec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &spec, nil
+}
+
+func (s *Spec) merge(part *Spec) error {
+	for key, value := range part.Settings {
+		if _, exists := s.Settings[key]; exists {
+			return fmt.Errorf("setting %s is declared more than once", key)
+		}
+		s.Settings[key] = value
+	}
+	s.Groups = append(s.Groups, part.Groups...)
+	s.Hub.Priorities = append(s.Hub.Priorities, part.Hub.Priorities...)
+	s.Hub.AccessKeys = append(s.Hub.AccessKeys, part.Hub.AccessKeys...)
+	return nil
+}
+
+// validate checks what can be checked without the database. Field values are
+// validated by the services when the plan is applied.
+func (s *Spec) validate() error {
+	groups := make(map[string]*GroupSpec, len(s.Groups))
+	for i := range s.Groups {
+		group := &s.Groups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		if group.Name == "" {
+			return fmt.Errorf("group #%d has no name", i+1)
+		}
+		if _, exists := groups[group.Name]; exists {
+			return fmt.Errorf("group %s is declared more than once", group.Name)
+		}
+		switch group.GroupType {
+		case "":
+			group.GroupType = "standard"
+		case "standard", "aggregate":
+		default:
+			return fmt.Errorf("group %s: group_type must be standard or aggregate", group.Name)
+		}
+		if group.GroupType == "aggregate" && len(group.Keys) > 0 {
+			return fmt.Errorf("group %s: aggregate groups cannot have keys", group.Name)
+		}
+		if group.GroupType == "aggregate" && (len(group.Upstreams) > 0 || group.TestModel != nil || group.ValidationEndpoint != nil) {
+			return fmt.Errorf("group %s: aggregate groups cannot set upstreams, test_model or validation_endpoint", group.Name)
+		}
+		if group.GroupType == "standard" && group.SubGroups != nil {
+			return fmt.Errorf("group %s: only aggregate groups can have sub_groups", group.Name)
+		}
+		groups[group.Name] = group
+	}
+
+	for _, group := range s.Groups {
+		if group.SubGroups == nil {
+			continue
+		}
+		seen := make(map[string]bool, len(*group.SubGroups))
+		for _, sub := range *group.SubGroups {
+			if seen[sub.Group] {
+				return fmt.Errorf("group %s: sub-group %s is listed more than once", group.Name, sub.Group)
+			}
+			seen[sub.Group] = true
+			if declared, ok := groups[sub.Group]; ok && declared.GroupType == "aggregate" {
+				return fmt.Errorf("group %s: sub-group %s is an aggregate group", group.Name, sub.Group)
+			}
+		}
+	}
+
+	priorities := make(map[string]bool, len(s.Hub.Priorities))
+	for _, priority := range s.Hub.Priorities {
+		if priority.Model == "" || priority.Group == "" {
+			return fmt.Errorf("hub priority needs a model and a group")
+		}
+		if priority.Priority < 1 || priority.Priority > 999 {
+			return fmt.Errorf("hub priority %s/%s must be between 1 and 999", priority.Model, priority.Group)
+		}
+		id := priority.Model + "\x00" + priority.Group
+		if priorities[id] {
+			return fmt.Errorf("hub priority %s/%s is declared more than once", priority.Model, priority.Group)
+		}
+		priorities[id] = true
+	}
+
+	accessKeys := make(map[string]bool, len(s.Hub.AccessKeys))
+	for _, key := range s.Hub.AccessKeys {
+		if strings.TrimSpace(key.Name) == "" {
+			return fmt.Errorf("hub access key has no name")
+		}
+		if accessKeys[key.Name] {
+			return fmt.Errorf("hub access key %s is declared more than once", key.Name)
+		}
+		accessKeys[key.Name] = true
+	}
+	return nil
+}
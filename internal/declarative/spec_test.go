@@ -0,0 +1,158 @@
+package declarative
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestLoad_MergesFilesInNameOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "10-settings.yaml", `
+settings:
+  request_timeout: 600
+  enable_request_body_logging: true
+`)
+	writeConfigFile(t, dir, "20-groups.yml", `
+groups:
+  - name: openai
+    channel_type: openai
+    test_model: gpt-4o-mini
+    upstreams:
+      - url: https://api.openai.com
+        weight: 1
+    keys:
+      - env: OPENAI_KEYS
+  - name: all
+    group_type: aggregate
+    channel_type: openai
+    sub_groups:
+      - group: openai
+        weight: 10
+`)
+	writeConfigFile(t, dir, "30-hub.json", `{"hub": {"priorities": [{"model": "gpt-4o", "group": "openai", "priority": 5}]}}`)
+	writeConfigFile(t, dir, "README.md", "ignored")
+
+	spec, err := Load(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(600), spec.Settings["request_timeout"])
+	assert.Equal(t, true, spec.Settings["enable_request_body_logging"])
+	require.Len(t, spec.Groups, 2)
+	assert.Equal(t, "standard", spec.Groups[0].GroupType, "group_type defaults to standard")
+	assert.JSONEq(t, `[{"url":"https://api.openai.com","weight":1}]`, string(spec.Groups[0].Upstreams))
+	assert.Equal(t, []SecretRef{{Env: "OPENAI_KEYS"}}, spec.Groups[0].Keys)
+	require.NotNil(t, spec.Groups[1].SubGroups)
+	assert.Equal(t, []SubGroupSpec{{Group: "openai", Weight: 10}}, *spec.Groups[1].SubGroups)
+	assert.Equal(t, []HubPrioritySpec{{Model: "gpt-4o", Group: "openai", Priority: 5}}, spec.Hub.Priorities)
+	assert.Equal(t, dir, spec.baseDir)
+}
+
+func TestLoad_SingleFile(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "gpt-load.yaml", "groups:\n  - name: a\n    channel_type: openai\n")
+
+	spec, err := Load(filepath.Join(dir, "gpt-load.yaml"))
+	require.NoError(t, err)
+	require.Len(t, spec.Groups, 1)
+	assert.Equal(t, dir, spec.baseDir)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			files:   map[string]string{"a.yaml": "groups:\n  - name: a\n    chanel_type: openai\n"},
+			wantErr: "unknown field",
+		},
+		{
+			name: "duplicate group across files",
+			files: map[string]string{
+				"a.yaml": "groups:\n  - name: a\n",
+				"b.yaml": "groups:\n  - name: a\n",
+			},
+			wantErr: "group a is declared more than once",
+		},
+		{
+			name: "duplicate setting across files",
+			files: map[string]string{
+				"a.yaml": "settings:\n  request_timeout: 1\n",
+				"b.json": `{"settings": {"request_timeout": 2}}`,
+			},
+			wantErr: "setting request_timeout is declared more than once",
+		},
+		{
+			name:    "aggregate with keys",
+			files:   map[string]string{"a.yaml": "groups:\n  - name: a\n    group_type: aggregate\n    keys:\n      - env: X\n"},
+			wantErr: "aggregate groups cannot have keys",
+		},
+		{
+			name:    "standard with sub-groups",
+			files:   map[string]string{"a.yaml": "groups:\n  - name: a\n    sub_groups: []\n"},
+			wantErr: "only aggregate groups can have sub_groups",
+		},
+		{
+			name:    "priority out of range",
+			files:   map[string]string{"a.yaml": "hub:\n  priorities:\n    - model: m\n      group: g\n      priority: 1000\n"},
+			wantErr: "must be between 1 and 999",
+		},
+		{
+			name:    "no configuration files",
+			files:   map[string]string{"notes.txt": "x"},
+			wantErr: "no .yaml, .yml or .json files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeConfigFile(t, dir, name, content)
+			}
+			_, err := Load(dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSecretRefResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "keys.txt", "sk-one\nsk-two\n")
+	t.Setenv("GPT_LOAD_SECRET_DECLARATIVE_KEY", " sk-env ")
+
+	value, err := SecretRef{Env: "GPT_LOAD_SECRET_DECLARATIVE_KEY"}.Resolve(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-env", value)
+
+	value, err = SecretRef{File: "keys.txt"}.Resolve(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-one\nsk-two", value)
+
+	_, err = SecretRef{Env: "GPT_LOAD_SECRET_DECLARATIVE_MISSING"}.Resolve(dir)
+	assert.ErrorContains(t, err, "GPT_LOAD_SECRET_DECLARATIVE_MISSING is not set")
+
+	t.Setenv("AUTH_KEY", "sk-admin")
+	_, err = SecretRef{Env: "AUTH_KEY"}.Resolve(dir)
+	assert.ErrorContains(t, err, "not allowed")
+	_, err = SecretRef{File: "../keys.txt"}.Resolve(dir)
+	assert.ErrorContains(t, err, "outside the allowed secret directories")
+
+	_, err = SecretRef{Env: "A", File: "b"}.Resolve(dir)
+	assert.Error(t, err)
+
+	_, err = SecretRef{}.Resolve(dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,456 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Data key statuses.
+const (
+	DataKeyActive  = "active"
+	DataKeyRetired = "retired"
+)
+
+// keyringReloadInterval is how often a node picks up data keys rotated by
+// another node.
+const keyringReloadInterval = time.Minute
+
+// keyringMissReloadInterval limits reloads triggered by ciphertext of an
+// unknown data key version.
+const keyringMissReloadInterval = 5 * time.Second
+
+// DataKey is a data encryption key stored wrapped by the key-encryption key
+// (ENCRYPTION_KEY). Version 0 is the key used before the keyring existed: it
+// decrypts unprefixed ciphertext and keys the lookup hashes, so neither
+// changes when data keys rotate.
+type DataKey struct {
+	Version    uint      `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	WrappedKey string    `gorm:"type:text;not null" json:"-"`
+	Status     string    `gorm:"type:varchar(16);not null" json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for DataKey
+func (DataKey) TableName() string {
+	return "encryption_keys"
+}
+
+// ErrKeyringMismatch is returned when the key-encryption key cannot unwrap
+// the stored data keys.
+var ErrKeyringMismatch = errors.New("ENCRYPTION_KEY cannot unwrap the data keys in encryption_keys")
+
+// Keyring implements Service with envelope encryption. Ciphertext is prefixed
+// with the version of the data key that produced it ("v2:<hex>"), so data
+// encrypted with older keys stays readable while it is re-encrypted.
+type Keyring struct {
+	db   *gorm.DB
+	wrap cipher.AEAD
+	// root is the version 0 key until the keyring is loaded.
+	root []byte
+
+	mu     sync.RWMutex
+	keys   map[uint]cipher.AEAD
+	hash   []byte
+	active uint
+
+	loadedAt  atomic.Int64
+	reloading atomic.Bool
+}
+
+// NewKeyring returns a keyring for kek and loads the data keys from db. A
+// database without the encryption_keys table is read with the version 0 key
+// only, until Initialize creates the keyring.
+func NewKeyring(db *gorm.DB, kek string) (*Keyring, error) {
+	if kek == "" {
+		return nil, errors.New("key-encryption key is empty")
+	}
+	utils.ValidatePasswordStrength(kek, "ENCRYPTION_KEY")
+
+	root := utils.DeriveAESKey(kek)
+	wrap, err := newGCM(wrappingKey(root))
+	if err != nil {
+		return nil, err
+	}
+	rootGCM, err := newGCM(root)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{
+		db:   db,
+		wrap: wrap,
+		root: root,
+		keys: map[uint]cipher.AEAD{0: rootGCM},
+		hash: root,
+	}
+	if err := k.Load(context.Background()); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Open returns the service for encryptionKey, reading the data keys from db
+// when encryption is enabled.
+func Open(db *gorm.DB, encryptionKey string) (Service, error) {
+	if encryptionKey == "" {
+		return &noopService{}, nil
+	}
+	keyring, err := NewKeyring(db, encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// HasKeyring reports whether db holds data keys, i.e. whether stored data
+// uses envelope encryption.
+func HasKeyring(db *gorm.DB) (bool, error) {
+	if !db.Migrator().HasTable(&DataKey{}) {
+		return false, nil
+	}
+	var count int64
+	if err := db.Model(&DataKey{}).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// wrappingKey derives the key that wraps data keys, so the version 0 key is
+// never used to wrap itself.
+func wrappingKey(root []byte) []byte {
+	mac := hmac.New(sha256.New, root)
+	mac.Write([]byte("gpt-load key-encryption key"))
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+func versionLabel(version uint) []byte {
+	return []byte("gpt-load data key v" + strconv.FormatUint(uint64(version), 10))
+}
+
+func seal(gcm cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(gcm cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, encrypted := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, encrypted, additional)
+}
+
+// wrapKey encrypts a data key with the key-encryption key.
+func wrapKey(wrap cipher.AEAD, version uint, key []byte) (string, error) {
+	sealed, err := seal(wrap, key, versionLabel(version))
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sealed), nil
+}
+
+func unwrapKey(wrap cipher.AEAD, dk DataKey) ([]byte, error) {
+	data, err := hex.DecodeString(dk.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped data key v%d: %w", dk.Version, err)
+	}
+	key, err := open(wrap, data, versionLabel(dk.Version))
+	if err != nil {
+		return nil, fmt.Errorf("%w (data key v%d)", ErrKeyringMismatch, dk.Version)
+	}
+	return key, nil
+}
+
+// Load reads the data keys from the database.
+func (k *Keyring) Load(ctx context.Context) error {
+	db := k.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&DataKey{}) {
+		k.loadedAt.Store(time.Now().UnixNano())
+		return nil
+	}
+
+	var rows []DataKey
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load data keys: %w", err)
+	}
+
+	keys := make(map[uint]cipher.AEAD, len(rows)+1)
+	hash := k.root
+	var active uint
+	for _, row := range rows {
+		key, err := unwrapKey(k.wrap, row)
+		if err != nil {
+			return err
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return err
+		}
+		keys[row.Version] = gcm
+		if row.Version == 0 {
+			hash = key
+		}
+		if row.Status == DataKeyActive {
+			active = row.Version
+		}
+	}
+	if _, ok := keys[0]; !ok {
+		keys[0] = k.keys[0]
+	}
+
+	k.mu.Lock()
+	k.keys, k.hash, k.active = keys, hash, active
+	k.mu.Unlock()
+	k.loadedAt.Store(time.Now().UnixNano())
+	return nil
+}
+
+// Initialize creates the keyring on first use with version 0, the key that
+// existing data was encrypted with, as the active data key. Ciphertext keeps
+// the unprefixed legacy format until the first Rotate, so nodes running an
+// older release can still read it during a rolling upgrade. The caller must
+// have created the encryption_keys table.
+func (k *Keyring) Initialize(ctx context.Context) error {
+	err := k.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&DataKey{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		root, err := wrapKey(k.wrap, 0, k.root)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&DataKey{Version: 0, WrappedKey: root, Status: DataKeyActive}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize keyring: %w", err)
+	}
+	return k.Load(ctx)
+}
+
+// createDataKey stores a new random data key as the active version.
+func createDataKey(tx *gorm.DB, wrap cipher.AEAD, version uint) error {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	wrapped, err := wrapKey(wrap, version, key)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&DataKey{Version: version, WrappedKey: wrapped, Status: DataKeyActive}).Error
+}
+
+// Rotate creates a new data key and makes it the active one. Data encrypted
+// with older versions stays readable until it is re-encrypted.
+func (k *Keyring) Rotate(ctx context.Context) (uint, error) {
+	var version uint
+	err := k.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest DataKey
+		if err := tx.Order("version DESC").First(&latest).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("keyring is not initialized")
+			}
+			return err
+		}
+		version = latest.Version + 1
+		if err := tx.Model(&DataKey{}).Where("status = ?", DataKeyActive).
+			Update("status", DataKeyRetired).Error; err != nil {
+			return err
+		}
+		return createDataKey(tx, k.wrap, version)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rotate data key: %w", err)
+	}
+	return version, k.Load(ctx)
+}
+
+// Rewrap wraps all data keys with a new key-encryption key. Stored data and
+// lookup hashes do not change; nodes must be restarted with the new key.
+func (k *Keyring) Rewrap(ctx context.Context, newKEK string) error {
+	wrap, err := newGCM(wrappingKey(utils.DeriveAESKey(newKEK)))
+	if err != nil {
+		return err
+	}
+	return k.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []DataKey
+		if err := tx.Order("version").Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return errors.New("keyring is not initialized")
+		}
+		for _, row := range rows {
+			key, err := unwrapKey(k.wrap, row)
+			if err != nil {
+				return err
+			}
+			wrapped, err := wrapKey(wrap, row.Version, key)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&DataKey{}).Where("version = ?", row.Version).
+				Update("wrapped_key", wrapped).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ActiveVersion returns the version new data is encrypted with; 0 means the
+// keyring is not initialized and ciphertext has no version prefix.
+func (k *Keyring) ActiveVersion() uint {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// VersionPrefix returns the ciphertext prefix of a data key version.
+func VersionPrefix(version uint) string {
+	if version == 0 {
+		return ""
+	}
+	return "v" + strconv.FormatUint(uint64(version), 10) + ":"
+}
+
+// CiphertextVersion returns the data key version a ciphertext was produced
+// with. Ciphertext without a prefix is version 0.
+func CiphertextVersion(ciphertext string) (uint, string, bool) {
+	if !strings.HasPrefix(ciphertext, "v") {
+		return 0, ciphertext, true
+	}
+	prefix, data, found := strings.Cut(ciphertext[1:], ":")
+	if !found {
+		return 0, ciphertext, false
+	}
+	version, err := strconv.ParseUint(prefix, 10, 32)
+	if err != nil {
+		return 0, ciphertext, false
+	}
+	return uint(version), data, true
+}
+
+// maybeReload refreshes the data keys in the background once they are older
+// than keyringReloadInterval.
+func (k *Keyring) maybeReload() {
+	if time.Since(time.Unix(0, k.loadedAt.Load())) < keyringReloadInterval {
+		return
+	}
+	if !k.reloading.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer k.reloading.Store(false)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := k.Load(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to reload encryption keyring")
+			// Retry after the next interval rather than on every call.
+			k.loadedAt.Store(time.Now().UnixNano())
+		}
+	}()
+}
+
+func (k *Keyring) key(version uint) (cipher.AEAD, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	gcm, ok := k.keys[version]
+	return gcm, ok
+}
+
+// Encrypt encrypts plaintext with the active data key.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	k.maybeReload()
+	k.mu.RLock()
+	version := k.active
+	gcm := k.keys[version]
+	k.mu.RUnlock()
+
+	sealed, err := seal(gcm, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return VersionPrefix(version) + hex.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts ciphertext produced with any data key version.
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	version, encoded, ok := CiphertextVersion(ciphertext)
+	if !ok {
+		return "", fmt.Errorf("invalid ciphertext version prefix")
+	}
+	gcm, ok := k.key(version)
+	if !ok && time.Since(time.Unix(0, k.loadedAt.Load())) > keyringMissReloadInterval {
+		// Another node may have rotated the keyring since it was loaded.
+		if err := k.Load(context.Background()); err != nil {
+			return "", err
+		}
+		gcm, ok = k.key(version)
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown data key version %d", version)
+	}
+
+	data, err := hex.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex data: %w", err)
+	}
+	plaintext, err := open(gcm, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("decryption failed: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Hash generates a hash of the plaintext using HMAC-SHA256 with the version 0
+// key, so hashes stay stable across rotations.
+func (k *Keyring) Hash(plaintext string) string {
+	if plaintext == "" {
+		return ""
+	}
+	k.mu.RLock()
+	key := k.hash
+	k.mu.RUnlock()
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plaintext))
+	return hex.EncodeToString(mac.Sum(nil))
+}
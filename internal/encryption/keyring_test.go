@@ -0,0 +1,150 @@
+package encryption
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const testKEK = "test-encryption-key-32-bytes!!"
+
+func setupKeyringDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return db
+}
+
+func TestKeyringReadsLegacyCiphertext(t *testing.T) {
+	db := setupKeyringDB(t)
+	legacy, err := NewService(testKEK)
+	require.NoError(t, err)
+	ciphertext, err := legacy.Encrypt("sk-legacy")
+	require.NoError(t, err)
+
+	// Without the table the keyring behaves like the legacy service.
+	keyring, err := NewKeyring(db, testKEK)
+	require.NoError(t, err)
+	assert.Zero(t, keyring.ActiveVersion())
+	plaintext, err := keyring.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy", plaintext)
+
+	require.NoError(t, db.AutoMigrate(&DataKey{}))
+	require.NoError(t, keyring.Initialize(context.Background()))
+	assert.Zero(t, keyring.ActiveVersion(), "the legacy key stays active until the first rotation")
+
+	plaintext, err = keyring.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy", plaintext)
+	assert.Equal(t, legacy.Hash("sk-legacy"), keyring.Hash("sk-legacy"))
+
+	// New values keep the legacy format, so older nodes can still read them.
+	encrypted, err := keyring.Encrypt("sk-new")
+	require.NoError(t, err)
+	plaintext, err = legacy.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-new", plaintext)
+
+	// Initialize is a no-op once the keyring exists.
+	require.NoError(t, keyring.Initialize(context.Background()))
+	var count int64
+	require.NoError(t, db.Model(&DataKey{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestKeyringRotate(t *testing.T) {
+	db := setupKeyringDB(t)
+	require.NoError(t, db.AutoMigrate(&DataKey{}))
+	keyring, err := NewKeyring(db, testKEK)
+	require.NoError(t, err)
+	require.NoError(t, keyring.Initialize(context.Background()))
+
+	before, err := keyring.Encrypt("sk-1")
+	require.NoError(t, err)
+	hash := keyring.Hash("sk-1")
+
+	version, err := keyring.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+	assert.Equal(t, uint(1), keyring.ActiveVersion())
+
+	after, err := keyring.Encrypt("sk-1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(after, "v1:"))
+	for _, ciphertext := range []string{before, after} {
+		plaintext, err := keyring.Decrypt(ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "sk-1", plaintext)
+	}
+	assert.Equal(t, hash, keyring.Hash("sk-1"))
+
+	var active []DataKey
+	require.NoError(t, db.Where("status = ?", DataKeyActive).Find(&active).Error)
+	require.Len(t, active, 1)
+	assert.Equal(t, uint(1), active[0].Version)
+
+	// Another node picks up the new key when it sees its ciphertext.
+	other, err := NewKeyring(db, testKEK)
+	require.NoError(t, err)
+	plaintext, err := other.Decrypt(after)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-1", plaintext)
+}
+
+func TestKeyringRewrap(t *testing.T) {
+	db := setupKeyringDB(t)
+	require.NoError(t, db.AutoMigrate(&DataKey{}))
+	keyring, err := NewKeyring(db, testKEK)
+	require.NoError(t, err)
+	require.NoError(t, keyring.Initialize(context.Background()))
+	ciphertext, err := keyring.Encrypt("sk-1")
+	require.NoError(t, err)
+	hash := keyring.Hash("sk-1")
+
+	const newKEK = "another-encryption-key-32-bytes"
+	require.NoError(t, keyring.Rewrap(context.Background(), newKEK))
+
+	_, err = NewKeyring(db, testKEK)
+	require.ErrorIs(t, err, ErrKeyringMismatch)
+
+	rewrapped, err := NewKeyring(db, newKEK)
+	require.NoError(t, err)
+	plaintext, err := rewrapped.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-1", plaintext)
+	assert.Equal(t, hash, rewrapped.Hash("sk-1"))
+}
+
+func TestCiphertextVersion(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		ciphertext string
+		version    uint
+		data       string
+		ok         bool
+	}{
+		{"abcdef", 0, "abcdef", true},
+		{"v3:abcdef", 3, "abcdef", true},
+		{"v12:", 12, "", true},
+		{"vx:abcdef", 0, "vx:abcdef", false},
+		{"v3abcdef", 0, "v3abcdef", false},
+	}
+	for _, tc := range testCases {
+		version, data, ok := CiphertextVersion(tc.ciphertext)
+		assert.Equal(t, tc.ok, ok, tc.ciphertext)
+		assert.Equal(t, tc.version, version, tc.ciphertext)
+		assert.Equal(t, tc.data, data, tc.ciphertext)
+	}
+	assert.Empty(t, VersionPrefix(0))
+	assert.Equal(t, "v7:", VersionPrefix(7))
+}
@@ -0,0 +1,90 @@
+package encryption
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Encryption mismatch scenarios between ENCRYPTION_KEY and the stored keys.
+const (
+	ScenarioNone             = ""
+	ScenarioDataNotEncrypted = "data_not_encrypted"
+	ScenarioKeyNotConfigured = "key_not_configured"
+	ScenarioKeyMismatch      = "key_mismatch"
+)
+
+// DetectScenario samples stored API keys and reports which
+// encryption mismatch scenario, if any, applies to encryptionKey.
+// It returns ScenarioNone when the configuration matches the data.
+func DetectScenario(ctx context.Context, db *gorm.DB, encryptionKey string) (string, error) {
+	// Sample check API keys
+	var sampleKeys []struct {
+		KeyValue string
+		KeyHash  string
+	}
+	if err := db.WithContext(ctx).Table("api_keys").Select("key_value, key_hash").Limit(20).
+		Where("key_hash IS NOT NULL AND key_hash != ''").Find(&sampleKeys).Error; err != nil {
+		return ScenarioNone, err
+	}
+
+	if len(sampleKeys) == 0 {
+		// No keys in database, no mismatch
+		return ScenarioNone, nil
+	}
+
+	// Check hash consistency with unencrypted data
+	noopService, err := NewService("")
+	if err != nil {
+		return ScenarioNone, err
+	}
+
+	unencryptedHashMatchCount := 0
+	for _, key := range sampleKeys {
+		// For unencrypted data: key_hash should match SHA256(key_value)
+		expectedHash := noopService.Hash(key.KeyValue)
+		if expectedHash == key.KeyHash {
+			unencryptedHashMatchCount++
+		}
+	}
+
+	unencryptedConsistencyRate := float64(unencryptedHashMatchCount) / float64(len(sampleKeys))
+
+	// If ENCRYPTION_KEY is configured, also check if current key can decrypt the data
+	var currentKeyHashMatchCount int
+	if encryptionKey != "" {
+		// A key that cannot unwrap the keyring counts as a mismatch.
+		currentService, err := Open(db.WithContext(ctx), encryptionKey)
+		if err == nil {
+			for _, key := range sampleKeys {
+				// Try to decrypt and re-hash to check if current key matches
+				decrypted, err := currentService.Decrypt(key.KeyValue)
+				if err == nil {
+					// Successfully decrypted, check if hash matches
+					expectedHash := currentService.Hash(decrypted)
+					if expectedHash == key.KeyHash {
+						currentKeyHashMatchCount++
+					}
+				}
+			}
+		}
+	}
+	currentKeyConsistencyRate := float64(currentKeyHashMatchCount) / float64(len(sampleKeys))
+
+	// Scenario A: ENCRYPTION_KEY configured but data not encrypted
+	if encryptionKey != "" && unencryptedConsistencyRate > 0.8 {
+		return ScenarioDataNotEncrypted, nil
+	}
+
+	// Scenario B: ENCRYPTION_KEY not configured but data is encrypted
+	if encryptionKey == "" && unencryptedConsistencyRate < 0.2 {
+		return ScenarioKeyNotConfigured, nil
+	}
+
+	// Scenario C: ENCRYPTION_KEY configured but doesn't match encrypted data
+	if encryptionKey != "" && unencryptedConsistencyRate < 0.2 && currentKeyConsistencyRate < 0.2 {
+		return ScenarioKeyMismatch, nil
+	}
+
+	return ScenarioNone, nil
+}
@@ -0,0 +1,45 @@
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectScenario(t *testing.T) {
+	db := setupKeyringDB(t)
+	require.NoError(t, db.Exec("CREATE TABLE api_keys (id INTEGER PRIMARY KEY, key_value TEXT, key_hash TEXT)").Error)
+
+	ctx := context.Background()
+	scenario, err := DetectScenario(ctx, db, testKEK)
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioNone, scenario, "no keys, no mismatch")
+
+	plain, err := NewService("")
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("INSERT INTO api_keys (key_value, key_hash) VALUES (?, ?)", "sk-plain", plain.Hash("sk-plain")).Error)
+	scenario, err = DetectScenario(ctx, db, testKEK)
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioDataNotEncrypted, scenario)
+	scenario, err = DetectScenario(ctx, db, "")
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioNone, scenario)
+
+	encrypted, err := NewService(testKEK)
+	require.NoError(t, err)
+	ciphertext, err := encrypted.Encrypt("sk-secret")
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("DELETE FROM api_keys").Error)
+	require.NoError(t, db.Exec("INSERT INTO api_keys (key_value, key_hash) VALUES (?, ?)", ciphertext, encrypted.Hash("sk-secret")).Error)
+	scenario, err = DetectScenario(ctx, db, "")
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioKeyNotConfigured, scenario)
+	scenario, err = DetectScenario(ctx, db, "another-encryption-key-32-bytes")
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioKeyMismatch, scenario)
+	scenario, err = DetectScenario(ctx, db, testKEK)
+	require.NoError(t, err)
+	assert.Equal(t, ScenarioNone, scenario)
+}
@@ -0,0 +1,181 @@
+package guardrail
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// requestInfo is the format-independent view of a request used by the checks.
+type requestInfo struct {
+	text         string
+	messageCount int
+	imageSizes   []int64 // decoded bytes for inline images, 0 for remote URLs
+	tools        []string
+}
+
+// inspectRequest extracts text, message count, images and tool names from
+// OpenAI chat/completions, Responses, Claude messages and Gemini bodies.
+func inspectRequest(body []byte) requestInfo {
+	var info requestInfo
+	if !gjson.ValidBytes(body) {
+		return info
+	}
+	root := gjson.ParseBytes(body)
+
+	var text []string
+	for _, field := range []string{"system", "prompt", "input", "messages", "contents", "systemInstruction", "system_instruction"} {
+		value := root.Get(field)
+		if !value.Exists() {
+			continue
+		}
+		collectText(value, &text)
+		walkImages(value, &info.imageSizes)
+	}
+	info.text = strings.Join(text, "\n")
+
+	switch {
+	case root.Get("messages").IsArray():
+		info.messageCount = len(root.Get("messages").Array())
+	case root.Get("contents").IsArray():
+		info.messageCount = len(root.Get("contents").Array())
+	case root.Get("input").IsArray():
+		info.messageCount = len(root.Get("input").Array())
+	}
+
+	root.Get("tools").ForEach(func(_, tool gjson.Result) bool {
+		for _, path := range []string{"function.name", "name"} {
+			if name := tool.Get(path).String(); name != "" {
+				info.tools = append(info.tools, name)
+			}
+		}
+		for _, path := range []string{"functionDeclarations", "function_declarations"} {
+			tool.Get(path).ForEach(func(_, decl gjson.Result) bool {
+				if name := decl.Get("name").String(); name != "" {
+					info.tools = append(info.tools, name)
+				}
+				return true
+			})
+		}
+		return true
+	})
+	return info
+}
+
+// RequestText returns the message text of a request body, as checked against
+// the blocklist and sent to moderation.
+func RequestText(body []byte) string {
+	return inspectRequest(body).text
+}
+
+// collectText gathers user-visible text, descending only into text-bearing
+// fields so image payloads and tool schemas are not scanned.
+func collectText(value gjson.Result, out *[]string) {
+	switch {
+	case value.Type == gjson.String:
+		if s := value.String(); s != "" {
+			*out = append(*out, s)
+		}
+	case value.IsArray():
+		value.ForEach(func(_, item gjson.Result) bool {
+			collectText(item, out)
+			return true
+		})
+	case value.IsObject():
+		for _, field := range []string{"text", "content", "parts"} {
+			if child := value.Get(field); child.Exists() {
+				collectText(child, out)
+			}
+		}
+	}
+}
+
+// walkImages records every image part found under value.
+func walkImages(value gjson.Result, sizes *[]int64) {
+	if value.IsArray() {
+		value.ForEach(func(_, item gjson.Result) bool {
+			walkImages(item, sizes)
+			return true
+		})
+		return
+	}
+	if !value.IsObject() {
+		return
+	}
+
+	switch {
+	case value.Get("image_url").Exists():
+		// OpenAI chat {"image_url":{"url":...}} and Responses {"type":"input_image","image_url":"..."}.
+		url := value.Get("image_url.url").String()
+		if url == "" {
+			url = value.Get("image_url").String()
+		}
+		*sizes = append(*sizes, dataURLSize(url))
+		return
+	case value.Get("type").String() == "image":
+		// Claude {"type":"image","source":{"type":"base64","data":...}}.
+		*sizes = append(*sizes, base64Size(value.Get("source.data").String()))
+		return
+	}
+	for _, field := range []string{"inlineData", "inline_data"} {
+		if inline := value.Get(field); inline.Exists() && isImageMIME(inline) {
+			*sizes = append(*sizes, base64Size(inline.Get("data").String()))
+			return
+		}
+	}
+	for _, field := range []string{"fileData", "file_data"} {
+		if file := value.Get(field); file.Exists() && isImageMIME(file) {
+			*sizes = append(*sizes, 0)
+			return
+		}
+	}
+	for _, field := range []string{"content", "parts"} {
+		if child := value.Get(field); child.IsArray() {
+			walkImages(child, sizes)
+		}
+	}
+}
+
+func isImageMIME(value gjson.Result) bool {
+	mime := value.Get("mimeType").String()
+	if mime == "" {
+		mime = value.Get("mime_type").String()
+	}
+	return strings.HasPrefix(mime, "image/")
+}
+
+// dataURLSize returns the decoded size of a base64 data URL, or 0 for remote URLs.
+func dataURLSize(url string) int64 {
+	if !strings.HasPrefix(url, "data:") {
+		return 0
+	}
+	_, data, found := strings.Cut(url, ";base64,")
+	if !found {
+		return 0
+	}
+	return base64Size(data)
+}
+
+func base64Size(data string) int64 {
+	data = strings.TrimRight(strings.TrimSpace(data), "=")
+	return int64(len(data)) * 3 / 4
+}
+
+// ResponseText extracts generated text from a non-streaming OpenAI chat or
+// completions, Responses, Claude or Gemini response body.
+func ResponseText(body []byte) string {
+	if !gjson.ValidBytes(body) {
+		return ""
+	}
+	var text []string
+	for _, path := range []string{
+		"choices.#.message.content",
+		"choices.#.text",
+		"content.#.text",
+		"output.#.content.#.text",
+		"candidates.#.content.parts.#.text",
+	} {
+		collectText(gjson.GetBytes(body, path), &text)
+	}
+	return strings.Join(text, "\n")
+}
@@ -0,0 +1,243 @@
+// Package guardrail implements gateway-level content policy checks that run
+// before a request is forwarded and after a non-streaming response completes.
+package guardrail
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Violation reasons, recorded in request logs as "guardrail <reason>: ...".
+const (
+	ReasonBlocklist             = "blocklist"
+	ReasonMaxMessages           = "max_messages"
+	ReasonMaxImages             = "max_images"
+	ReasonImageSize             = "image_size"
+	ReasonForbiddenTool         = "forbidden_tool"
+	ReasonModeration            = "moderation"
+	ReasonModerationUnavailable = "moderation_unavailable"
+)
+
+// DefaultModerationModel is used when the moderation config omits a model.
+const DefaultModerationModel = "omni-moderation-latest"
+
+// HubPolicyContextKey holds the raw policy of the Hub access key that
+// authenticated the request, set by the Hub handler before proxying.
+const HubPolicyContextKey = "hub_guardrail_policy"
+
+const (
+	maxBlocklistEntries = 500
+	maxForbiddenTools   = 200
+)
+
+// Config is the JSON representation of a guardrail policy, stored in the
+// group config under "guardrail_policy" and on Hub access keys.
+type Config struct {
+	// Blocklist entries are matched as case-insensitive substrings.
+	Blocklist []string `json:"blocklist,omitempty"`
+	// BlocklistPatterns are regular expressions matched against message text.
+	BlocklistPatterns []string `json:"blocklist_patterns,omitempty"`
+	MaxMessages       int      `json:"max_messages,omitempty"`
+	MaxImages         int      `json:"max_images,omitempty"`
+	// MaxImageBytes limits the decoded size of each inline (base64) image.
+	MaxImageBytes  int64    `json:"max_image_bytes,omitempty"`
+	ForbiddenTools []string `json:"forbidden_tools,omitempty"`
+	// CheckResponse applies the blocklist and moderation to non-streaming
+	// responses. Streamed responses are forwarded as they arrive and are not checked.
+	CheckResponse bool              `json:"check_response,omitempty"`
+	Moderation    *ModerationConfig `json:"moderation,omitempty"`
+}
+
+// ModerationConfig routes text to an OpenAI-compatible /v1/moderations group
+// on this gateway.
+type ModerationConfig struct {
+	Group string `json:"group"`
+	Model string `json:"model,omitempty"`
+	// FailClosed rejects requests when the moderation call itself fails.
+	FailClosed bool `json:"fail_closed,omitempty"`
+}
+
+// Violation describes why a request or response was rejected.
+type Violation struct {
+	Reason  string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("guardrail %s: %s", v.Reason, v.Message)
+}
+
+// Policy is a compiled Config.
+type Policy struct {
+	blocklist      []string
+	patterns       []*regexp.Regexp
+	maxMessages    int
+	maxImages      int
+	maxImageBytes  int64
+	forbiddenTools map[string]bool
+	checkResponse  bool
+	moderation     *ModerationConfig
+}
+
+var cache sync.Map // raw JSON string -> *Policy
+
+// Compile is Parse with results cached by the raw JSON, since policies are
+// read on every proxied request.
+func Compile(raw []byte) (*Policy, error) {
+	raw = bytes.TrimSpace(raw)
+	key := string(raw)
+	if cached, ok := cache.Load(key); ok {
+		return cached.(*Policy), nil
+	}
+	p, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	cache.Store(key, p)
+	return p, nil
+}
+
+// Parse decodes and compiles a policy. Empty input, "null" and "{}" yield a
+// nil Policy, which allows everything.
+func Parse(raw []byte) (*Policy, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var cfg Config
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid guardrail policy: %w", err)
+	}
+	return New(cfg)
+}
+
+// New compiles cfg into a Policy.
+func New(cfg Config) (*Policy, error) {
+	if len(cfg.Blocklist)+len(cfg.BlocklistPatterns) > maxBlocklistEntries {
+		return nil, fmt.Errorf("too many blocklist entries: at most %d allowed", maxBlocklistEntries)
+	}
+	if len(cfg.ForbiddenTools) > maxForbiddenTools {
+		return nil, fmt.Errorf("too many forbidden tools: at most %d allowed", maxForbiddenTools)
+	}
+	if cfg.MaxMessages < 0 || cfg.MaxImages < 0 || cfg.MaxImageBytes < 0 {
+		return nil, errors.New("guardrail limits must not be negative")
+	}
+
+	p := &Policy{
+		maxMessages:   cfg.MaxMessages,
+		maxImages:     cfg.MaxImages,
+		maxImageBytes: cfg.MaxImageBytes,
+		checkResponse: cfg.CheckResponse,
+	}
+	for _, term := range cfg.Blocklist {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			p.blocklist = append(p.blocklist, term)
+		}
+	}
+	for _, pattern := range cfg.BlocklistPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocklist pattern %q: %w", pattern, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+	for _, name := range cfg.ForbiddenTools {
+		if name = strings.TrimSpace(name); name != "" {
+			if p.forbiddenTools == nil {
+				p.forbiddenTools = make(map[string]bool, len(cfg.ForbiddenTools))
+			}
+			p.forbiddenTools[name] = true
+		}
+	}
+	if cfg.Moderation != nil {
+		moderation := *cfg.Moderation
+		moderation.Group = strings.TrimSpace(moderation.Group)
+		moderation.Model = strings.TrimSpace(moderation.Model)
+		if moderation.Group == "" {
+			return nil, errors.New("moderation.group is required")
+		}
+		if moderation.Model == "" {
+			moderation.Model = DefaultModerationModel
+		}
+		p.moderation = &moderation
+	}
+	if p.isEmpty() {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (p *Policy) isEmpty() bool {
+	return len(p.blocklist) == 0 && len(p.patterns) == 0 && p.maxMessages == 0 && p.maxImages == 0 &&
+		p.maxImageBytes == 0 && len(p.forbiddenTools) == 0 && p.moderation == nil
+}
+
+// Moderation returns the moderation callout config, or nil when disabled.
+func (p *Policy) Moderation() *ModerationConfig {
+	if p == nil {
+		return nil
+	}
+	return p.moderation
+}
+
+// ChecksResponse reports whether completed responses should be checked.
+func (p *Policy) ChecksResponse() bool {
+	return p != nil && p.checkResponse && (len(p.blocklist) > 0 || len(p.patterns) > 0 || p.moderation != nil)
+}
+
+// CheckRequest runs the local (non-moderation) checks against a request body
+// in OpenAI, Claude, Gemini or Responses format.
+func (p *Policy) CheckRequest(body []byte) *Violation {
+	if p == nil || len(body) == 0 {
+		return nil
+	}
+	req := inspectRequest(body)
+
+	if p.maxMessages > 0 && req.messageCount > p.maxMessages {
+		return &Violation{ReasonMaxMessages, fmt.Sprintf("request has %d messages, limit is %d", req.messageCount, p.maxMessages)}
+	}
+	if p.maxImages > 0 && len(req.imageSizes) > p.maxImages {
+		return &Violation{ReasonMaxImages, fmt.Sprintf("request has %d images, limit is %d", len(req.imageSizes), p.maxImages)}
+	}
+	if p.maxImageBytes > 0 {
+		for _, size := range req.imageSizes {
+			if size > p.maxImageBytes {
+				return &Violation{ReasonImageSize, fmt.Sprintf("image of %d bytes exceeds limit of %d bytes", size, p.maxImageBytes)}
+			}
+		}
+	}
+	for _, name := range req.tools {
+		if p.forbiddenTools[name] {
+			return &Violation{ReasonForbiddenTool, fmt.Sprintf("tool %q is not allowed", name)}
+		}
+	}
+	return p.CheckText(req.text)
+}
+
+// CheckText matches text against the blocklist.
+func (p *Policy) CheckText(text string) *Violation {
+	if p == nil || text == "" {
+		return nil
+	}
+	if len(p.blocklist) > 0 {
+		lower := strings.ToLower(text)
+		for _, term := range p.blocklist {
+			if strings.Contains(lower, term) {
+				return &Violation{ReasonBlocklist, "content matched a blocked term"}
+			}
+		}
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(text) {
+			return &Violation{ReasonBlocklist, "content matched a blocked pattern"}
+		}
+	}
+	return nil
+}
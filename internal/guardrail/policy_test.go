@@ -0,0 +1,111 @@
+package guardrail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRequest(t *testing.T) {
+	t.Parallel()
+
+	policy, err := Parse([]byte(`{
+		"blocklist":["Forbidden Phrase"],
+		"blocklist_patterns":["\\bssn:\\s*\\d{3}-\\d{2}-\\d{4}"],
+		"max_messages":3,
+		"max_images":1,
+		"max_image_bytes":30,
+		"forbidden_tools":["shell","delete_repo"]
+	}`))
+	require.NoError(t, err)
+	require.NotNil(t, policy)
+
+	smallImage := strings.Repeat("A", 20)
+	largeImage := strings.Repeat("A", 80)
+
+	tests := []struct {
+		name   string
+		body   string
+		reason string
+	}{
+		{"allowed openai", `{"messages":[{"role":"user","content":"hello"}]}`, ""},
+		{"blocklist openai parts", `{"messages":[{"role":"user","content":[{"type":"text","text":"a forbidden phrase here"}]}]}`, ReasonBlocklist},
+		{"blocklist pattern claude system", `{"system":"ssn: 123-45-6789","messages":[{"role":"user","content":"hi"}]}`, ReasonBlocklist},
+		{"blocklist gemini", `{"contents":[{"role":"user","parts":[{"text":"FORBIDDEN PHRASE"}]}]}`, ReasonBlocklist},
+		{"blocklist responses string input", `{"input":"forbidden phrase"}`, ReasonBlocklist},
+		{"too many messages", `{"messages":[{"content":"a"},{"content":"b"},{"content":"c"},{"content":"d"}]}`, ReasonMaxMessages},
+		{"too many images", `{"messages":[{"content":[{"type":"image_url","image_url":{"url":"https://x/a.png"}},{"type":"image_url","image_url":{"url":"https://x/b.png"}}]}]}`, ReasonMaxImages},
+		{"small inline image", `{"messages":[{"content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + smallImage + `"}}]}]}`, ""},
+		{"large openai image", `{"messages":[{"content":[{"type":"image_url","image_url":{"url":"data:image/png;base64,` + largeImage + `"}}]}]}`, ReasonImageSize},
+		{"large claude image", `{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","data":"` + largeImage + `"}}]}]}`, ReasonImageSize},
+		{"large gemini image", `{"contents":[{"parts":[{"inlineData":{"mimeType":"image/png","data":"` + largeImage + `"}}]}]}`, ReasonImageSize},
+		{"openai forbidden tool", `{"messages":[],"tools":[{"type":"function","function":{"name":"shell"}}]}`, ReasonForbiddenTool},
+		{"claude forbidden tool", `{"messages":[],"tools":[{"name":"delete_repo","input_schema":{}}]}`, ReasonForbiddenTool},
+		{"gemini forbidden tool", `{"contents":[],"tools":[{"functionDeclarations":[{"name":"shell"}]}]}`, ReasonForbiddenTool},
+		{"tool schema is not scanned", `{"messages":[],"tools":[{"name":"ok","description":"forbidden phrase"}]}`, ""},
+		{"non json", `not json`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := policy.CheckRequest([]byte(tt.body))
+			if tt.reason == "" {
+				assert.Nil(t, violation)
+				return
+			}
+			require.NotNil(t, violation)
+			assert.Equal(t, tt.reason, violation.Reason)
+			assert.Contains(t, violation.Error(), "guardrail "+tt.reason)
+		})
+	}
+}
+
+func TestResponseText(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "hi", ResponseText([]byte(`{"choices":[{"message":{"content":"hi"}}]}`)))
+	assert.Equal(t, "a\nb", ResponseText([]byte(`{"content":[{"type":"text","text":"a"},{"type":"text","text":"b"}]}`)))
+	assert.Equal(t, "g", ResponseText([]byte(`{"candidates":[{"content":{"parts":[{"text":"g"}]}}]}`)))
+	assert.Equal(t, "r", ResponseText([]byte(`{"output":[{"type":"message","content":[{"type":"output_text","text":"r"}]}]}`)))
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{"", "null", "{}", `{"blocklist":["  "]}`} {
+		policy, err := Parse([]byte(raw))
+		require.NoError(t, err, raw)
+		assert.Nil(t, policy, raw)
+	}
+
+	policy, err := Parse([]byte(`{"moderation":{"group":" mod "},"check_response":true}`))
+	require.NoError(t, err)
+	assert.Equal(t, &ModerationConfig{Group: "mod", Model: DefaultModerationModel}, policy.Moderation())
+	assert.True(t, policy.ChecksResponse())
+
+	for _, raw := range []string{
+		`{"blocklist_patterns":["("]}`,
+		`{"max_messages":-1}`,
+		`{"moderation":{"model":"m"}}`,
+		`{"unknown":1}`,
+		`[]`,
+	} {
+		_, err := Parse([]byte(raw))
+		assert.Error(t, err, raw)
+	}
+
+	var nilPolicy *Policy
+	assert.Nil(t, nilPolicy.CheckRequest([]byte(`{"messages":[]}`)))
+	assert.False(t, nilPolicy.ChecksResponse())
+}
+
+func TestCompileCaches(t *testing.T) {
+	t.Parallel()
+
+	first, err := Compile([]byte(`{"blocklist":["x"]}`))
+	require.NoError(t, err)
+	second, err := Compile([]byte(` {"blocklist":["x"]} `))
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+}
@@ -733,10 +733,10 @@ func hasGoodComplexity(password string) bool {
 
 // Encryption scenario types
 const (
-	ScenarioNone             = ""
-	ScenarioDataNotEncrypted = "data_not_encrypted"
-	ScenarioKeyNotConfigured = "key_not_configured"
-	ScenarioKeyMismatch      = "key_mismatch"
+	ScenarioNone             = encryption.ScenarioNone
+	ScenarioDataNotEncrypted = encryption.ScenarioDataNotEncrypted
+	ScenarioKeyNotConfigured = encryption.ScenarioKeyNotConfigured
+	ScenarioKeyMismatch      = encryption.ScenarioKeyMismatch
 )
 
 // EncryptionStatus checks if ENCRYPTION_KEY is configured but keys are not encrypted
@@ -753,83 +753,30 @@ func (s *Server) EncryptionStatus(c *gin.Context) {
 
 // checkEncryptionMismatch detects encryption configuration mismatches
 func (s *Server) checkEncryptionMismatch(c *gin.Context) (bool, string, string, string) {
-	encryptionKey := s.config.GetEncryptionKey()
-
-	// Sample check API keys
-	var sampleKeys []models.APIKey
 	ctxTimeout, cancel := context.WithTimeout(c.Request.Context(), 300*time.Millisecond)
 	defer cancel()
-	if err := s.readOnlyDB().WithContext(ctxTimeout).Limit(20).Where("key_hash IS NOT NULL AND key_hash != ''").Find(&sampleKeys).Error; err != nil {
-		logrus.WithError(err).Warn("Encryption check sample query failed/timeout; skipping")
-		return false, ScenarioNone, "", ""
-	}
-
-	if len(sampleKeys) == 0 {
-		// No keys in database, no mismatch
-		return false, ScenarioNone, "", ""
-	}
-
-	// Check hash consistency with unencrypted data
-	noopService, err := encryption.NewService("")
+	scenario, err := encryption.DetectScenario(ctxTimeout, s.readOnlyDB(), s.config.GetEncryptionKey())
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create noop encryption service")
+		logrus.WithError(err).Warn("Encryption check sample query failed/timeout; skipping")
 		return false, ScenarioNone, "", ""
 	}
 
-	unencryptedHashMatchCount := 0
-	for _, key := range sampleKeys {
-		// For unencrypted data: key_hash should match SHA256(key_value)
-		expectedHash := noopService.Hash(key.KeyValue)
-		if expectedHash == key.KeyHash {
-			unencryptedHashMatchCount++
-		}
-	}
-
-	unencryptedConsistencyRate := float64(unencryptedHashMatchCount) / float64(len(sampleKeys))
-
-	// If ENCRYPTION_KEY is configured, also check if current key can decrypt the data
-	var currentKeyHashMatchCount int
-	if encryptionKey != "" {
-		currentService, err := encryption.NewService(encryptionKey)
-		if err == nil {
-			for _, key := range sampleKeys {
-				// Try to decrypt and re-hash to check if current key matches
-				decrypted, err := currentService.Decrypt(key.KeyValue)
-				if err == nil {
-					// Successfully decrypted, check if hash matches
-					expectedHash := currentService.Hash(decrypted)
-					if expectedHash == key.KeyHash {
-						currentKeyHashMatchCount++
-					}
-				}
-			}
-		}
-	}
-	currentKeyConsistencyRate := float64(currentKeyHashMatchCount) / float64(len(sampleKeys))
-
-	// Scenario A: ENCRYPTION_KEY configured but data not encrypted
-	if encryptionKey != "" && unencryptedConsistencyRate > 0.8 {
+	switch scenario {
+	case ScenarioDataNotEncrypted:
 		return true,
 			ScenarioDataNotEncrypted,
 			i18n.Message(c, "dashboard.encryption_key_configured_but_data_not_encrypted"),
 			i18n.Message(c, "dashboard.encryption_key_migration_required")
-	}
-
-	// Scenario B: ENCRYPTION_KEY not configured but data is encrypted
-	if encryptionKey == "" && unencryptedConsistencyRate < 0.2 {
+	case ScenarioKeyNotConfigured:
 		return true,
 			ScenarioKeyNotConfigured,
 			i18n.Message(c, "dashboard.data_encrypted_but_key_not_configured"),
 			i18n.Message(c, "dashboard.configure_same_encryption_key")
-	}
-
-	// Scenario C: ENCRYPTION_KEY configured but doesn't match encrypted data
-	if encryptionKey != "" && unencryptedConsistencyRate < 0.2 && currentKeyConsistencyRate < 0.2 {
+	case ScenarioKeyMismatch:
 		return true,
 			ScenarioKeyMismatch,
 			i18n.Message(c, "dashboard.encryption_key_mismatch"),
 			i18n.Message(c, "dashboard.use_correct_encryption_key")
 	}
-
 	return false, ScenarioNone, "", ""
 }
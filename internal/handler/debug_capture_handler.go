@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DebugTokenRequest defines the payload for issuing a debug token.
+type DebugTokenRequest struct {
+	// TTLMinutes defaults to one hour and is capped at one day.
+	TTLMinutes int `json:"ttl_minutes"`
+}
+
+// DebugTokenResponse is an issued debug token. The token is only shown once.
+type DebugTokenResponse struct {
+	Token     string    `json:"token"`
+	Header    string    `json:"header"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DebugTokenRevokeRequest defines the payload for revoking a debug token.
+type DebugTokenRevokeRequest struct {
+	Token string `json:"token"`
+}
+
+// parseDebugCaptureID reads the :id parameter, writing an error response when
+// it is not a valid ID.
+func parseDebugCaptureID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_debug_capture_id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondDebugCaptureError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrDebugCaptureNotFound) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.debug_capture_not_found")
+		return
+	}
+	response.Error(c, app_errors.ParseDBError(err))
+}
+
+// IssueDebugToken handles POST /api/debug/tokens.
+func (s *Server) IssueDebugToken(c *gin.Context) {
+	var req DebugTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+			return
+		}
+	}
+	if req.TTLMinutes < 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.debug_token_ttl_negative")
+		return
+	}
+
+	token, expiresAt, err := s.DebugCaptureService.IssueToken(time.Duration(req.TTLMinutes) * time.Minute)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrInternalServer, "error.issue_debug_token")
+		return
+	}
+	response.Success(c, DebugTokenResponse{Token: token, Header: proxy.DebugCaptureHeader, ExpiresAt: expiresAt})
+}
+
+// RevokeDebugToken handles DELETE /api/debug/tokens.
+func (s *Server) RevokeDebugToken(c *gin.Context) {
+	var req DebugTokenRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.debug_token_required")
+		return
+	}
+	if err := s.DebugCaptureService.RevokeToken(token); err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrInternalServer, "error.revoke_debug_token")
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ListDebugCaptures handles GET /api/debug/captures.
+func (s *Server) ListDebugCaptures(c *gin.Context) {
+	captures, err := s.DebugCaptureService.List(c.Query("group_name"))
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	if captures == nil {
+		captures = []models.DebugCapture{}
+	}
+	response.Success(c, captures)
+}
+
+// GetDebugCapture handles GET /api/debug/captures/:id.
+func (s *Server) GetDebugCapture(c *gin.Context) {
+	id, ok := parseDebugCaptureID(c)
+	if !ok {
+		return
+	}
+	capture, err := s.DebugCaptureService.Get(id)
+	if err != nil {
+		respondDebugCaptureError(c, err)
+		return
+	}
+	response.Success(c, capture)
+}
+
+// DeleteDebugCapture handles DELETE /api/debug/captures/:id.
+func (s *Server) DeleteDebugCapture(c *gin.Context) {
+	id, ok := parseDebugCaptureID(c)
+	if !ok {
+		return
+	}
+	if err := s.DebugCaptureService.Delete(id); err != nil {
+		respondDebugCaptureError(c, err)
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeDebugToken(t *testing.T) {
+	t.Parallel()
+
+	debugSvc := services.NewDebugCaptureService(setupTestDB(t), store.NewMemoryStore())
+	server := &Server{DebugCaptureService: debugSvc}
+	token, _, err := debugSvc.IssueToken(0)
+	require.NoError(t, err)
+	require.True(t, debugSvc.ValidateToken(token))
+
+	revoke := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/api/debug/tokens", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		server.RevokeDebugToken(c)
+		return w
+	}
+
+	missing := revoke(`{"token":" "}`)
+	assert.Equal(t, http.StatusBadRequest, missing.Code)
+	assert.NotContains(t, missing.Body.String(), "validation.debug_token_required", "the message ID resolves to a translation")
+	assert.True(t, debugSvc.ValidateToken(token))
+
+	require.Equal(t, http.StatusOK, revoke(`{"token":"`+token+`"}`).Code)
+	assert.False(t, debugSvc.ValidateToken(token))
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEncryptionStatus handles GET /api/encryption.
+func (s *Server) GetEncryptionStatus(c *gin.Context) {
+	status, err := s.EncryptionRotation.Status(c.Request.Context())
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, status)
+}
+
+// RotateEncryptionKey handles POST /api/encryption/rotate. Stored secrets are
+// re-encrypted with the new data key in the background.
+func (s *Server) RotateEncryptionKey(c *gin.Context) {
+	status, err := s.EncryptionRotation.Rotate(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrEncryptionDisabled) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "ENCRYPTION_KEY is not set"))
+			return
+		}
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, err.Error()))
+		return
+	}
+	response.Success(c, status)
+}
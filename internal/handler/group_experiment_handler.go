@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+func parseExperimentID(c *gin.Context, name string) (uint, *app_errors.APIError) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		return 0, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid experiment ID")
+	}
+	return uint(id), nil
+}
+
+// parseGroupExperimentParams reads the group and experiment IDs of
+// /api/groups/:id/experiments/:experimentId routes.
+func parseGroupExperimentParams(c *gin.Context) (uint, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return 0, 0, false
+	}
+	experimentID, apiErr := parseExperimentID(c, "experimentId")
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return 0, 0, false
+	}
+	return uint(id), experimentID, true
+}
+
+// ListGroupExperiments handles GET /api/groups/:id/experiments.
+func (s *Server) ListGroupExperiments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	experiments, err := s.GroupExperimentService.ListExperiments(c.Request.Context(), uint(id))
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, experiments)
+}
+
+// CreateGroupExperiment handles POST /api/groups/:id/experiments.
+func (s *Server) CreateGroupExperiment(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var req services.GroupExperimentParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	experiment, err := s.GroupExperimentService.CreateExperiment(c.Request.Context(), uint(id), req)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, experiment)
+}
+
+// GetGroupExperiment handles GET /api/groups/:id/experiments/:experimentId.
+func (s *Server) GetGroupExperiment(c *gin.Context) {
+	id, experimentID, ok := parseGroupExperimentParams(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := s.GroupExperimentService.GetExperiment(c.Request.Context(), id, experimentID)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, experiment)
+}
+
+// UpdateGroupExperimentTraffic handles PUT /api/groups/:id/experiments/:experimentId/traffic.
+func (s *Server) UpdateGroupExperimentTraffic(c *gin.Context) {
+	id, experimentID, ok := parseGroupExperimentParams(c)
+	if !ok {
+		return
+	}
+
+	var req services.GroupExperimentTraffic
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	experiment, err := s.GroupExperimentService.UpdateTraffic(c.Request.Context(), id, experimentID, req)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, experiment)
+}
+
+// PromoteGroupExperiment handles POST /api/groups/:id/experiments/:experimentId/promote.
+func (s *Server) PromoteGroupExperiment(c *gin.Context) {
+	id, experimentID, ok := parseGroupExperimentParams(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := s.GroupExperimentService.Promote(c.Request.Context(), id, experimentID)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, experiment)
+}
+
+// AbortGroupExperiment handles POST /api/groups/:id/experiments/:experimentId/abort.
+func (s *Server) AbortGroupExperiment(c *gin.Context) {
+	id, experimentID, ok := parseGroupExperimentParams(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := s.GroupExperimentService.Abort(c.Request.Context(), id, experimentID)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, experiment)
+}
+
+// ExperimentComparison handles GET /api/dashboard/experiments/:id and compares
+// success rate, latency and token usage of the experiment variants.
+func (s *Server) ExperimentComparison(c *gin.Context) {
+	experimentID, apiErr := parseExperimentID(c, "id")
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
+	comparison, err := s.GroupExperimentService.Compare(c.Request.Context(), experimentID)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, comparison)
+}
@@ -3,6 +3,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/url"
 	"sort"
@@ -13,6 +14,7 @@ import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
 
@@ -1028,3 +1030,40 @@ func (s *Server) ResetModelRedirectHealth(c *gin.Context) {
 
 	response.SuccessI18n(c, "success.health_reset", nil)
 }
+
+// ExplainGroupRequest shows how the group's proxy pipeline transforms a client
+// request, stage by stage, without contacting upstream.
+func (s *Server) ExplainGroupRequest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var opts proxy.ExplainOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	group, ok := s.findGroupByID(c, uint(id))
+	if !ok {
+		return
+	}
+
+	result, err := s.ProxyServer.ExplainRequest(c.Request.Context(), group.Name, opts)
+	switch {
+	case err == nil:
+		response.Success(c, result)
+	case errors.Is(err, proxy.ErrReplayInvalidPath):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.explain_invalid_path", nil)
+	case errors.Is(err, proxy.ErrReplayInvalidBody):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.explain_invalid_body", nil)
+	case errors.Is(err, proxy.ErrExplainInvalidMethod):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.explain_invalid_method", nil)
+	case errors.Is(err, proxy.ErrReplayGroupNotFound):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found", nil)
+	default:
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleGroupChangeRequest defines the payload for scheduling a group change.
+type ScheduleGroupChangeRequest struct {
+	ApplyAt time.Time             `json:"apply_at"`
+	Note    string                `json:"note"`
+	Changes services.GroupChanges `json:"changes"`
+}
+
+// GroupRevisionResponse is a rolled back group together with the revision
+// recorded for the rollback.
+type GroupRevisionResponse struct {
+	Group    GroupResponse `json:"group"`
+	Revision int           `json:"revision"`
+}
+
+func parseGroupRevision(c *gin.Context, name string) (int, *app_errors.APIError) {
+	revision, err := strconv.Atoi(c.Param(name))
+	if err != nil || revision <= 0 {
+		return 0, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid revision")
+	}
+	return revision, nil
+}
+
+// ListGroupRevisions handles GET /api/groups/:id/revisions.
+func (s *Server) ListGroupRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	revisions, err := s.GroupRevisionService.ListRevisions(c.Request.Context(), uint(id))
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, revisions)
+}
+
+// GetGroupRevision handles GET /api/groups/:id/revisions/:revision.
+func (s *Server) GetGroupRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	revision, apiErr := parseGroupRevision(c, "revision")
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
+	rev, err := s.GroupRevisionService.GetRevision(c.Request.Context(), uint(id), revision)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, rev)
+}
+
+// DiffGroupRevision handles GET /api/groups/:id/revisions/:revision/diff.
+// The revision is compared with the revision given by ?to= or, by default,
+// with the current configuration.
+func (s *Server) DiffGroupRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	revision, apiErr := parseGroupRevision(c, "revision")
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+	to := 0
+	if raw := c.Query("to"); raw != "" {
+		to, err = strconv.Atoi(raw)
+		if err != nil || to <= 0 {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid to revision"))
+			return
+		}
+	}
+
+	diff, err := s.GroupRevisionService.Diff(c.Request.Context(), uint(id), revision, to)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, diff)
+}
+
+// RollbackGroupRevision handles POST /api/groups/:id/revisions/:revision/rollback.
+func (s *Server) RollbackGroupRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	revision, apiErr := parseGroupRevision(c, "revision")
+	if apiErr != nil {
+		response.Error(c, apiErr)
+		return
+	}
+
+	group, err := s.GroupRevisionService.Rollback(c.Request.Context(), uint(id), revision)
+	if HandleServiceError(c, err) {
+		return
+	}
+	latest, err := s.GroupRevisionService.LatestRevision(c.Request.Context(), group.ID)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, GroupRevisionResponse{Group: *s.newGroupResponse(group), Revision: latest})
+}
+
+// ListScheduledGroupChanges handles GET /api/groups/:id/scheduled-changes.
+func (s *Server) ListScheduledGroupChanges(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	changes, err := s.GroupScheduleService.ListScheduledChanges(c.Request.Context(), uint(id))
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, changes)
+}
+
+// ScheduleGroupChange handles POST /api/groups/:id/scheduled-changes.
+func (s *Server) ScheduleGroupChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var req ScheduleGroupChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if req.ApplyAt.IsZero() {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "apply_at is required"))
+		return
+	}
+
+	change, err := s.GroupScheduleService.ScheduleChange(c.Request.Context(), uint(id), req.ApplyAt, req.Note, req.Changes)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, change)
+}
+
+// CancelScheduledGroupChange handles DELETE /api/groups/:id/scheduled-changes/:changeId.
+func (s *Server) CancelScheduledGroupChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	changeID, err := strconv.Atoi(c.Param("changeId"))
+	if err != nil || changeID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid scheduled change ID"))
+		return
+	}
+
+	if err := s.GroupScheduleService.CancelScheduledChange(c.Request.Context(), uint(id), uint(changeID)); HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, nil)
+}
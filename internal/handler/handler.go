@@ -10,8 +10,11 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/leader"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/sitemanagement"
+	"gpt-load/internal/store"
 	"gpt-load/internal/types"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +32,10 @@ type Server struct {
 	GroupService               *services.GroupService
 	AggregateGroupService      *services.AggregateGroupService
 	ChildGroupService          *services.ChildGroupService
+	GroupRevisionService       *services.GroupRevisionService
+	GroupScheduleService       *services.GroupScheduleService
+	GroupExperimentService     *services.GroupExperimentService
+	ShadowReportService        *services.ShadowReportService
 	ProxyPoolService           *services.ProxyPoolService
 	KeyManualValidationService *services.KeyManualValidationService
 	TaskService                *services.TaskService
@@ -36,8 +43,11 @@ type Server struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	RequestLogService          *services.RequestLogService
+	DebugCaptureService        *services.DebugCaptureService
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
+	EncryptionRotation         *services.EncryptionRotationService
 	BulkImportService          *services.BulkImportService   // Added for optimized bulk imports
 	ImportExportService        *services.ImportExportService // Added for unified import/export
 	SiteService                *sitemanagement.SiteService
@@ -45,6 +55,9 @@ type Server struct {
 	BindingService             *sitemanagement.BindingService
 	BalanceService             *sitemanagement.BalanceService // Balance fetching service
 	DynamicWeightManager       *services.DynamicWeightManager // Dynamic weight manager for adaptive load balancing
+	ProxyServer                *proxy.ProxyServer             // Used to replay logged requests
+	Elector                    *leader.Elector                // Reports which node runs the master-only services
+	Store                      store.Store                    // Checked by the readiness endpoint
 }
 
 // NewServerParams defines the dependencies for the NewServer constructor.
@@ -58,6 +71,10 @@ type NewServerParams struct {
 	GroupService               *services.GroupService
 	AggregateGroupService      *services.AggregateGroupService
 	ChildGroupService          *services.ChildGroupService
+	GroupRevisionService       *services.GroupRevisionService
+	GroupScheduleService       *services.GroupScheduleService
+	GroupExperimentService     *services.GroupExperimentService
+	ShadowReportService        *services.ShadowReportService
 	ProxyPoolService           *services.ProxyPoolService
 	KeyManualValidationService *services.KeyManualValidationService
 	TaskService                *services.TaskService
@@ -65,8 +82,11 @@ type NewServerParams struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	RequestLogService          *services.RequestLogService
+	DebugCaptureService        *services.DebugCaptureService
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
+	EncryptionRotation         *services.EncryptionRotationService
 	BulkImportService          *services.BulkImportService   // Added for optimized bulk imports
 	ImportExportService        *services.ImportExportService // Added for unified import/export
 	SiteService                *sitemanagement.SiteService
@@ -74,6 +94,9 @@ type NewServerParams struct {
 	BindingService             *sitemanagement.BindingService
 	BalanceService             *sitemanagement.BalanceService // Balance fetching service
 	DynamicWeightManager       *services.DynamicWeightManager // Dynamic weight manager for adaptive load balancing
+	ProxyServer                *proxy.ProxyServer             // Used to replay logged requests
+	Elector                    *leader.Elector
+	Store                      store.Store
 }
 
 // NewServer creates a new handler instance with dependencies injected by dig.
@@ -91,6 +114,10 @@ func NewServer(params NewServerParams) *Server {
 		GroupService:               params.GroupService,
 		AggregateGroupService:      params.AggregateGroupService,
 		ChildGroupService:          params.ChildGroupService,
+		GroupRevisionService:       params.GroupRevisionService,
+		GroupScheduleService:       params.GroupScheduleService,
+		GroupExperimentService:     params.GroupExperimentService,
+		ShadowReportService:        params.ShadowReportService,
 		ProxyPoolService:           params.ProxyPoolService,
 		KeyManualValidationService: params.KeyManualValidationService,
 		TaskService:                params.TaskService,
@@ -98,8 +125,11 @@ func NewServer(params NewServerParams) *Server {
 		KeyImportService:           params.KeyImportService,
 		KeyDeleteService:           params.KeyDeleteService,
 		LogService:                 params.LogService,
+		RequestLogService:          params.RequestLogService,
+		DebugCaptureService:        params.DebugCaptureService,
 		CommonHandler:              params.CommonHandler,
 		EncryptionSvc:              params.EncryptionSvc,
+		EncryptionRotation:         params.EncryptionRotation,
 		BulkImportService:          params.BulkImportService,
 		ImportExportService:        params.ImportExportService,
 		SiteService:                params.SiteService,
@@ -107,6 +137,9 @@ func NewServer(params NewServerParams) *Server {
 		BindingService:             params.BindingService,
 		BalanceService:             params.BalanceService,
 		DynamicWeightManager:       params.DynamicWeightManager,
+		ProxyServer:                params.ProxyServer,
+		Elector:                    params.Elector,
+		Store:                      params.Store,
 	}
 
 	// Set binding callbacks to avoid circular dependency between services and sitemanagement packages
@@ -193,12 +226,7 @@ func (s *Server) Login(c *gin.Context) {
 // Returns HTTP 200 with status information if the service is healthy
 // Returns HTTP 503 if critical components (database) are not available
 func (s *Server) Health(c *gin.Context) {
-	uptime := "unknown"
-	if startTime, exists := c.Get("serverStartTime"); exists {
-		if st, ok := startTime.(time.Time); ok {
-			uptime = time.Since(st).String()
-		}
-	}
+	uptime := serverUptime(c)
 
 	// Check database connectivity
 	// Use a short timeout to avoid blocking health checks
@@ -229,10 +257,14 @@ func (s *Server) Health(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	result := gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"uptime":    uptime,
 		"database":  "ok",
-	})
+	}
+	if s.Elector != nil {
+		result["leader"] = s.Elector.Status()
+	}
+	c.JSON(http.StatusOK, result)
 }
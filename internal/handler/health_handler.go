@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// readinessTimeout bounds the whole readiness probe; checks still running
+	// when it expires are reported as timed out.
+	readinessTimeout = 3 * time.Second
+
+	healthCheckOK      = "ok"
+	healthCheckFailed  = "failed"
+	healthCheckTimeout = "timeout"
+)
+
+// ReadinessCheck is the result of one dependency check.
+type ReadinessCheck struct {
+	Status    string         `json:"status"`
+	LatencyMs int64          `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+type readinessProbe struct {
+	name string
+	run  func(ctx context.Context) ReadinessCheck
+}
+
+type readinessResult struct {
+	name  string
+	check ReadinessCheck
+}
+
+// serverUptime returns the uptime recorded by the router middleware.
+func serverUptime(c *gin.Context) string {
+	if startTime, exists := c.Get("serverStartTime"); exists {
+		if st, ok := startTime.(time.Time); ok {
+			return time.Since(st).String()
+		}
+	}
+	return "unknown"
+}
+
+// Live handles GET /health/live.
+// It only reports that the process is serving requests. Dependencies are left
+// to the readiness probe because restarting the process does not fix them.
+func (s *Server) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "alive",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"uptime":    serverUptime(c),
+	})
+}
+
+// Ready handles GET /health/ready.
+// Returns HTTP 200 when every dependency check passes and HTTP 503 otherwise,
+// with the result of each check in the body.
+func (s *Server) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	probes := s.readinessProbes()
+	results := make(chan readinessResult, len(probes))
+	for _, probe := range probes {
+		go func(p readinessProbe) {
+			start := time.Now()
+			check := p.run(ctx)
+			check.LatencyMs = time.Since(start).Milliseconds()
+			results <- readinessResult{name: p.name, check: check}
+		}(probe)
+	}
+
+	checks := make(map[string]ReadinessCheck, len(probes))
+collect:
+	for range probes {
+		select {
+		case result := <-results:
+			checks[result.name] = result.check
+		case <-ctx.Done():
+			break collect
+		}
+	}
+	for _, probe := range probes {
+		if _, ok := checks[probe.name]; !ok {
+			checks[probe.name] = ReadinessCheck{Status: healthCheckTimeout, LatencyMs: readinessTimeout.Milliseconds()}
+		}
+	}
+
+	ready := true
+	for name, check := range checks {
+		if check.Status != healthCheckOK {
+			ready = false
+			logrus.WithFields(logrus.Fields{"check": name, "status": check.Status}).Warn("Readiness check failed")
+		}
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	result := gin.H{
+		"status":    status,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"uptime":    serverUptime(c),
+		"checks":    checks,
+	}
+	if s.Elector != nil {
+		result["leader"] = s.Elector.Status()
+	}
+	c.JSON(code, result)
+}
+
+// readinessProbes returns the checks for the dependencies this server has.
+func (s *Server) readinessProbes() []readinessProbe {
+	var probes []readinessProbe
+	if s.DB != nil {
+		probes = append(probes, readinessProbe{"database", func(ctx context.Context) ReadinessCheck {
+			return pingDatabase(ctx, s.DB)
+		}})
+		if s.readDB != nil && s.readDB != s.DB {
+			probes = append(probes, readinessProbe{"read_database", func(ctx context.Context) ReadinessCheck {
+				return pingDatabase(ctx, s.readDB)
+			}})
+		}
+	}
+	if s.Store != nil {
+		probes = append(probes, readinessProbe{"store", s.checkStoreRoundTrip})
+	}
+	if s.GroupManager != nil {
+		probes = append(probes,
+			readinessProbe{"group_cache", s.checkGroupCache},
+			readinessProbe{"pubsub", s.checkSubscriptions},
+		)
+	}
+	if s.RequestLogService != nil {
+		probes = append(probes, readinessProbe{"request_log_buffer", s.checkRequestLogBacklog})
+	}
+	return probes
+}
+
+func pingDatabase(ctx context.Context, gormDB *gorm.DB) ReadinessCheck {
+	sqlDB, err := gormDB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		logrus.WithError(err).Debug("Readiness database ping failed")
+		return ReadinessCheck{Status: healthCheckFailed, Error: "database ping failed"}
+	}
+	return ReadinessCheck{Status: healthCheckOK}
+}
+
+// checkStoreRoundTrip writes, reads back and deletes a short-lived key.
+func (s *Server) checkStoreRoundTrip(context.Context) ReadinessCheck {
+	key := "health:ready:" + utils.GenerateRandomSuffix()
+	value := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+
+	if err := s.Store.Set(key, value, 10*time.Second); err != nil {
+		logrus.WithError(err).Debug("Readiness store write failed")
+		return ReadinessCheck{Status: healthCheckFailed, Error: "store write failed"}
+	}
+	got, err := s.Store.Get(key)
+	if err != nil || string(got) != string(value) {
+		logrus.WithError(err).Debug("Readiness store read failed")
+		return ReadinessCheck{Status: healthCheckFailed, Error: "store read failed"}
+	}
+	if err := s.Store.Delete(key); err != nil {
+		logrus.WithError(err).Debug("Readiness store delete failed")
+		return ReadinessCheck{Status: healthCheckFailed, Error: "store delete failed"}
+	}
+	return ReadinessCheck{Status: healthCheckOK}
+}
+
+func (s *Server) checkGroupCache(context.Context) ReadinessCheck {
+	if !s.GroupManager.Initialized() {
+		return ReadinessCheck{Status: healthCheckFailed, Error: "group cache not initialized"}
+	}
+	return ReadinessCheck{Status: healthCheckOK}
+}
+
+// checkSubscriptions verifies the cache invalidation subscriptions, without
+// which this node would serve stale groups and settings.
+func (s *Server) checkSubscriptions(context.Context) ReadinessCheck {
+	details := map[string]any{"groups": s.GroupManager.Subscribed()}
+	if s.SettingsManager != nil {
+		details["system_settings"] = s.SettingsManager.Subscribed()
+	}
+	for _, subscribed := range details {
+		if subscribed != true {
+			return ReadinessCheck{Status: healthCheckFailed, Error: "cache invalidation subscription inactive", Details: details}
+		}
+	}
+	return ReadinessCheck{Status: healthCheckOK, Details: details}
+}
+
+// checkRequestLogBacklog fails when request logs pile up faster than they are flushed.
+func (s *Server) checkRequestLogBacklog(context.Context) ReadinessCheck {
+	pending, err := s.RequestLogService.PendingLogs()
+	if err != nil {
+		logrus.WithError(err).Debug("Readiness request log backlog check failed")
+		return ReadinessCheck{Status: healthCheckFailed, Error: "failed to read request log backlog"}
+	}
+	details := map[string]any{
+		"pending":         pending,
+		"high_water_mark": services.PendingLogsHighWaterMark,
+	}
+	if pending >= services.PendingLogsHighWaterMark {
+		return ReadinessCheck{Status: healthCheckFailed, Error: "request log backlog above high-water mark", Details: details}
+	}
+	return ReadinessCheck{Status: healthCheckOK, Details: details}
+}
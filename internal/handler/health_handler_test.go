@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newReadinessTestServer(t *testing.T) (*Server, store.Store) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	memStore := store.NewMemoryStore()
+	t.Cleanup(func() { _ = memStore.Close() })
+	settingsManager := config.NewSystemSettingsManager()
+
+	return &Server{
+		DB:                db,
+		Store:             memStore,
+		SettingsManager:   settingsManager,
+		RequestLogService: services.NewRequestLogService(db, memStore, settingsManager),
+	}, memStore
+}
+
+func serveReady(t *testing.T, server *Server) (int, map[string]any) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+	server.Ready(c)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func readinessCheck(t *testing.T, body map[string]any, name string) map[string]any {
+	t.Helper()
+	checks, ok := body["checks"].(map[string]any)
+	require.True(t, ok, "checks missing from body")
+	check, ok := checks[name].(map[string]any)
+	require.True(t, ok, "check %q missing", name)
+	return check
+}
+
+func TestLive(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	(&Server{}).Live(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"alive"`)
+}
+
+func TestReady_AllChecksPass(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newReadinessTestServer(t)
+	code, body := serveReady(t, server)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", body["status"])
+	for _, name := range []string{"database", "store", "request_log_buffer"} {
+		assert.Equal(t, "ok", readinessCheck(t, body, name)["status"], name)
+	}
+	assert.NotContains(t, body["checks"], "read_database", "a shared read pool is not checked twice")
+}
+
+func TestReady_RequestLogBacklogAboveHighWaterMark(t *testing.T) {
+	t.Parallel()
+
+	server, memStore := newReadinessTestServer(t)
+	members := make([]any, services.PendingLogsHighWaterMark)
+	for i := range members {
+		members[i] = fmt.Sprintf("request_log:%d", i)
+	}
+	require.NoError(t, memStore.SAdd(services.PendingLogKeysSet, members...))
+
+	code, body := serveReady(t, server)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", body["status"])
+	check := readinessCheck(t, body, "request_log_buffer")
+	assert.Equal(t, "failed", check["status"])
+	assert.EqualValues(t, services.PendingLogsHighWaterMark, check["details"].(map[string]any)["pending"])
+}
+
+func TestReady_GroupCacheNotInitialized(t *testing.T) {
+	t.Parallel()
+
+	server, memStore := newReadinessTestServer(t)
+	server.GroupManager = services.NewGroupManager(server.DB, memStore, server.SettingsManager, nil)
+
+	code, body := serveReady(t, server)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "failed", readinessCheck(t, body, "group_cache")["status"])
+	assert.Equal(t, "failed", readinessCheck(t, body, "pubsub")["status"])
+	assert.Equal(t, "ok", readinessCheck(t, body, "database")["status"])
+}
+
+func TestReady_DatabaseUnavailable(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newReadinessTestServer(t)
+	sqlDB, err := server.DB.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	code, body := serveReady(t, server)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	check := readinessCheck(t, body, "database")
+	assert.Equal(t, "failed", check["status"])
+	assert.Equal(t, "database ping failed", check["error"])
+}
@@ -14,6 +14,7 @@ import (
 
 	"gpt-load/internal/centralizedmgmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/guardrail"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
@@ -80,6 +81,11 @@ func (h *HubHandler) HandleHubProxy(c *gin.Context) {
 		return
 	}
 
+	// The proxy enforces the key's guardrail policy alongside the group policy.
+	if len(accessKey.GuardrailPolicy) > 0 {
+		c.Set(guardrail.HubPolicyContextKey, []byte(accessKey.GuardrailPolicy))
+	}
+
 	// Step 1: Detect relay format from request path
 	relayFormat := h.detectRelayFormat(c.Request.URL.Path, c.Request.Method)
 	c.Set("relay_format", relayFormat)
@@ -670,29 +676,7 @@ func (h *HubHandler) rewriteHubPath(path, groupName string) string {
 
 // returnHubError returns a Hub-specific error response.
 func (h *HubHandler) returnHubError(c *gin.Context, status int, code, message string) {
-	c.JSON(status, map[string]any{
-		"error": map[string]any{
-			"code":    code,
-			"message": message,
-			"type":    h.getErrorType(status),
-		},
-	})
-}
-
-// getErrorType returns the error type based on HTTP status code.
-func (h *HubHandler) getErrorType(status int) string {
-	switch {
-	case status == http.StatusUnauthorized || status == http.StatusForbidden:
-		return "authentication_error"
-	case status == http.StatusNotFound:
-		return "not_found_error"
-	case status == http.StatusBadRequest:
-		return "invalid_request_error"
-	case status >= 500:
-		return "server_error"
-	default:
-		return "api_error"
-	}
+	proxy.ReturnOpenAIError(c, status, code, message)
 }
 
 // bodyReader wraps a byte slice to implement io.ReadCloser.
@@ -1,12 +1,21 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
+	"gpt-load/internal/redaction"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"io"
 	"log"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +25,7 @@ import (
 // LogResponse defines the structure for log entries in the API response
 type LogResponse struct {
 	models.RequestLog
+	SearchSnippets []services.LogSearchSnippet `json:"search_snippets,omitempty"`
 }
 
 // GetLogs handles fetching request logs with filtering and pagination.
@@ -32,21 +42,94 @@ func (s *Server) GetLogs(c *gin.Context) {
 
 	// Decrypt all keys in logs for frontend display
 	for i := range logs {
-		if logs[i].KeyValue != "" {
-			decryptedValue, err := s.EncryptionSvc.Decrypt(logs[i].KeyValue)
-			if err != nil {
-				logrus.WithError(err).WithField("log_id", logs[i].ID).Error("Failed to decrypt log key value")
-				logs[i].KeyValue = "failed-to-decrypt"
-			} else {
-				logs[i].KeyValue = decryptedValue
+		s.decryptLogKey(&logs[i])
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		items := make([]LogResponse, len(logs))
+		for i := range logs {
+			items[i] = LogResponse{
+				RequestLog:     logs[i],
+				SearchSnippets: services.LogSearchSnippets(&logs[i], q),
 			}
 		}
+		pagination.Items = items
+	} else {
+		pagination.Items = logs
 	}
-
-	pagination.Items = logs
 	response.Success(c, pagination)
 }
 
+// decryptLogKey replaces the stored encrypted key of a log with its plain value.
+func (s *Server) decryptLogKey(entry *models.RequestLog) {
+	if entry.KeyValue == "" {
+		return
+	}
+	decryptedValue, err := s.EncryptionSvc.Decrypt(entry.KeyValue)
+	if err != nil {
+		logrus.WithError(err).WithField("log_id", entry.ID).Error("Failed to decrypt log key value")
+		entry.KeyValue = "failed-to-decrypt"
+	} else {
+		entry.KeyValue = decryptedValue
+	}
+}
+
+// StreamLogs pushes request logs matching the GET /api/logs filters as
+// server-sent events as soon as they are recorded, before they are flushed to
+// the database. Logs are dropped rather than buffered for slow clients.
+func (s *Server) StreamLogs(c *gin.Context) {
+	filter := s.LogService.NewLogStreamFilter(c)
+	sub, err := s.RequestLogService.SubscribeStream()
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	defer sub.Close()
+
+	// The stream outlives the server write timeout.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(services.RequestLogStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			var entry models.RequestLog
+			if err := json.Unmarshal(msg.Payload, &entry); err != nil || !filter.Match(&entry) {
+				continue
+			}
+			s.decryptLogKey(&entry)
+			payload, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: log\ndata: %s\n\n", payload); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			s.RequestLogService.KeepStreamAlive()
+			if _, err := io.WriteString(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
 // ExportLogs handles exporting filtered log keys to a CSV file.
 func (s *Server) ExportLogs(c *gin.Context) {
 	filename := fmt.Sprintf("log_keys_export_%s.csv", time.Now().Format("20060102150405"))
@@ -61,3 +144,121 @@ func (s *Server) ExportLogs(c *gin.Context) {
 		return
 	}
 }
+
+// ExportFullLogs streams every request log column matching the current filters.
+// Query parameters: format (csv, jsonl or columnar), include_bodies (default false)
+// and mask_keys (default true).
+func (s *Server) ExportFullLogs(c *gin.Context) {
+	opts := services.LogExportOptions{
+		Format:   strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", services.LogExportFormatCSV))),
+		MaskKeys: true,
+	}
+	if !services.IsValidLogExportFormat(opts.Format) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_export_format")
+		return
+	}
+	if includeBodies, err := strconv.ParseBool(c.Query("include_bodies")); err == nil {
+		opts.IncludeBodies = includeBodies
+	}
+	if maskKeys, err := strconv.ParseBool(c.Query("mask_keys")); err == nil {
+		opts.MaskKeys = maskKeys
+	}
+
+	extension, contentType := "csv", "text/csv; charset=utf-8"
+	switch opts.Format {
+	case services.LogExportFormatJSONL:
+		extension, contentType = "jsonl", "application/x-ndjson; charset=utf-8"
+	case services.LogExportFormatColumnar:
+		extension, contentType = "columnar.jsonl", "application/x-ndjson; charset=utf-8"
+	}
+	filename := fmt.Sprintf("logs_export_%s.%s", time.Now().Format("20060102150405"), extension)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", contentType)
+
+	// Headers are already sent once the first batch is written, so later errors
+	// can only be logged.
+	if err := s.LogService.StreamLogs(c, c.Writer, opts); err != nil {
+		logrus.WithError(err).Error("Failed to stream full log export")
+		if !c.Writer.Written() {
+			c.JSON(500, gin.H{"error": i18n.Message(c, "error.export_logs")})
+		}
+	}
+}
+
+// RedactionPreviewRequest is the payload for previewing log redaction rules.
+// When Rules is empty the group's effective rules (or the global setting when
+// no group is given) are used.
+type RedactionPreviewRequest struct {
+	Rules   string `json:"rules"`
+	GroupID uint   `json:"group_id"`
+	Sample  string `json:"sample"`
+}
+
+// PreviewLogRedaction applies redaction rules to a sample body without storing anything.
+func (s *Server) PreviewLogRedaction(c *gin.Context) {
+	var req RedactionPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	rules := req.Rules
+	if strings.TrimSpace(rules) == "" {
+		if req.GroupID != 0 {
+			group, ok := s.findGroupByID(c, req.GroupID)
+			if !ok {
+				return
+			}
+			rules = s.SettingsManager.GetEffectiveConfig(group.Config).LogRedactionRules
+		} else {
+			rules = s.SettingsManager.GetSettings().LogRedactionRules
+		}
+	}
+
+	// Parse rather than Compile so ad-hoc previews do not grow the rules cache.
+	redactor, err := redaction.Parse(rules)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_redaction_rules", map[string]any{"error": err.Error()})
+		return
+	}
+
+	response.Success(c, gin.H{
+		"rules":    rules,
+		"redacted": redactor.Redact(req.Sample),
+	})
+}
+
+// ReplayLog resends a logged request through the proxy pipeline, optionally to
+// another group or model, or as a dry run that only shows the upstream request.
+func (s *Server) ReplayLog(c *gin.Context) {
+	// All options are optional; an empty body replays the log as stored.
+	var opts proxy.ReplayOptions
+	if err := c.ShouldBindJSON(&opts); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	var entry models.RequestLog
+	if err := s.DB.Where("id = ?", c.Param("id")).First(&entry).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	result, err := s.ProxyServer.ReplayRequestLog(c.Request.Context(), &entry, opts)
+	switch {
+	case err == nil:
+		response.Success(c, result)
+	case errors.Is(err, proxy.ErrReplayNoRequestBody):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.replay_no_request_body", nil)
+	case errors.Is(err, proxy.ErrReplayInvalidBody):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.replay_invalid_body", nil)
+	case errors.Is(err, proxy.ErrReplayInvalidPath):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.replay_invalid_path", nil)
+	case errors.Is(err, proxy.ErrReplayModelUnsettable):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.replay_model_unsettable", nil)
+	case errors.Is(err, proxy.ErrReplayGroupNotFound):
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "validation.group_not_found", nil)
+	default:
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+	}
+}
@@ -3,10 +3,14 @@ package handler
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"gpt-load/internal/config"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
 )
 
 func TestGetLogs_NilService(t *testing.T) {
@@ -40,3 +44,32 @@ func TestExportLogs_NilService(t *testing.T) {
 		router.ServeHTTP(w, req)
 	})
 }
+
+func TestPreviewLogRedaction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := &Server{SettingsManager: config.NewSystemSettingsManager()}
+	router := gin.New()
+	router.POST("/logs/redaction/preview", server.PreviewLogRedaction)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/logs/redaction/preview", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"rules":"{\"detectors\":[\"email\"],\"drop_paths\":[\"messages[*].content\"]}","sample":"{\"messages\":[{\"content\":\"hi\"}],\"user\":\"a@b.io\"}"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	redacted := gjson.Get(w.Body.String(), "data.redacted").String()
+	assert.JSONEq(t, `{"messages":[{"content":"[DROPPED]"}],"user":"[REDACTED_EMAIL]"}`, redacted)
+
+	// No rules configured globally leaves the sample untouched.
+	w = post(`{"sample":"a@b.io"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "a@b.io", gjson.Get(w.Body.String(), "data.redacted").String())
+
+	w = post(`{"rules":"{\"detectors\":[\"ssn\"]}","sample":"x"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
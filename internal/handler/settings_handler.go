@@ -69,10 +69,15 @@ func (s *Server) GetProxyPoolSettings(c *gin.Context) {
 }
 
 // GetEnvironmentInfo returns environment-specific information like DEBUG_MODE status
+// and the leadership of this node.
 func (s *Server) GetEnvironmentInfo(c *gin.Context) {
-	response.Success(c, gin.H{
+	info := gin.H{
 		"debug_mode": s.config.IsDebugMode(),
-	})
+	}
+	if s.Elector != nil {
+		info["leader"] = s.Elector.Status()
+	}
+	response.Success(c, info)
 }
 
 // UpdateSettings handles the PUT /api/settings request.
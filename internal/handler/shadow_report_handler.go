@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShadowReport handles GET /api/dashboard/shadow?groupId=&range= and compares
+// the live requests of a group with the requests mirrored to its shadow group.
+func (s *Server) ShadowReport(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Query("groupId"), 10, 32)
+	if err != nil || groupID == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	start, end, err := dashboardChartTimeRange(time.Now(), c.Query("range"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "invalid_param")
+		return
+	}
+
+	report, err := s.ShadowReportService.Report(c.Request.Context(), uint(groupID), start, end)
+	if HandleServiceError(c, err) {
+		return
+	}
+	response.Success(c, report)
+}
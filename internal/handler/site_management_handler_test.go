@@ -28,12 +28,13 @@ import (
 )
 
 type handlerHubAccessKeyTestModel struct {
-	ID            uint   `gorm:"primaryKey"`
-	Name          string `gorm:"column:name"`
-	KeyHash       string `gorm:"column:key_hash"`
-	KeyValue      string `gorm:"column:key_value"`
-	AllowedModels []byte `gorm:"column:allowed_models"`
-	Enabled       bool   `gorm:"column:enabled"`
+	ID              uint   `gorm:"primaryKey"`
+	Name            string `gorm:"column:name"`
+	KeyHash         string `gorm:"column:key_hash"`
+	KeyValue        string `gorm:"column:key_value"`
+	AllowedModels   []byte `gorm:"column:allowed_models"`
+	Enabled         bool   `gorm:"column:enabled"`
+	GuardrailPolicy []byte `gorm:"column:guardrail_policy"`
 }
 
 func (handlerHubAccessKeyTestModel) TableName() string {
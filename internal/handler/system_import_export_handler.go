@@ -204,10 +204,11 @@ func (s *Server) ExportAll(c *gin.Context) {
 				return
 			}
 			decryptedKeys = append(decryptedKeys, services.HubAccessKeyExportInfo{
-				Name:          key.Name,
-				KeyValue:      kv,
-				AllowedModels: key.AllowedModels,
-				Enabled:       key.Enabled,
+				Name:            key.Name,
+				KeyValue:        kv,
+				AllowedModels:   key.AllowedModels,
+				Enabled:         key.Enabled,
+				GuardrailPolicy: key.GuardrailPolicy,
 			})
 		}
 		hubAccessKeys = decryptedKeys
@@ -416,10 +417,11 @@ func (s *Server) ImportAll(c *gin.Context) {
 				continue
 			}
 			encryptedKeys = append(encryptedKeys, services.HubAccessKeyExportInfo{
-				Name:          key.Name,
-				KeyValue:      kv,
-				AllowedModels: key.AllowedModels,
-				Enabled:       key.Enabled,
+				Name:            key.Name,
+				KeyValue:        kv,
+				AllowedModels:   key.AllowedModels,
+				Enabled:         key.Enabled,
+				GuardrailPolicy: key.GuardrailPolicy,
 			})
 		}
 		hubAccessKeys = encryptedKeys
@@ -57,6 +57,19 @@ var MessagesEnUS = map[string]string{
 	"validation.group_not_found":                               "Group not found",
 	"validation.group_disabled":                                "Group is disabled, cannot perform this operation",
 	"validation.invalid_status_filter":                         "Invalid status filter",
+	"validation.invalid_export_format":                         "Invalid export format. Must be 'csv', 'jsonl', or 'columnar'",
+	"validation.invalid_redaction_rules":                       "Invalid redaction rules: {{.error}}",
+	"validation.replay_no_request_body":                        "This log has no stored request body. Enable request body logging or provide a body to replay",
+	"validation.replay_invalid_body":                           "The request body is not valid JSON; stored bodies longer than the log capture limit are truncated",
+	"validation.replay_invalid_path":                           "Invalid replay path. It must be the path below /proxy/<group>, starting with /",
+	"validation.replay_model_unsettable":                       "The model cannot be overridden for this request",
+	"validation.explain_invalid_path":                          "Invalid path. It must be the client path below /proxy/<group>, starting with /",
+	"validation.explain_invalid_body":                          "The request body is not valid JSON",
+	"validation.explain_invalid_method":                        "Unsupported request method. Use GET, POST, PUT, PATCH or DELETE",
+	"validation.invalid_debug_capture_id":                      "Invalid debug capture ID",
+	"validation.debug_capture_not_found":                       "Debug capture not found",
+	"validation.debug_token_ttl_negative":                      "ttl_minutes must not be negative",
+	"validation.debug_token_required":                          "Debug token is required",
 	"validation.invalid_group_id":                              "Invalid group ID format",
 	"validation.test_model_required":                           "Test model is required",
 	"validation.invalid_copy_keys_value":                       "Invalid copy_keys value. Must be 'none', 'valid_only', or 'all'",
@@ -198,6 +211,8 @@ var MessagesEnUS = map[string]string{
 	"config.log_write_interval_desc":          "Interval (in minutes) for writing request logs from cache to database, 0 for real-time writes.",
 	"config.enable_request_body_logging":      "Enable Request Body Logging",
 	"config.enable_request_body_logging_desc": "Whether to log complete request body content. Enabling this will increase memory and storage usage.",
+	"config.log_redaction_rules":              "Log Redaction Rules",
+	"config.log_redaction_rules_desc":         "JSON rules applied to logged request/response bodies before storage. Supports built-in detectors (email, phone, credit_card, api_key), custom regex patterns and JSON drop paths such as messages[*].content. Leave empty to disable.",
 
 	// Request settings related
 	"config.request_timeout":                               "Request Timeout (seconds)",
@@ -273,6 +288,8 @@ var MessagesEnUS = map[string]string{
 	"error.start_import_task":             "failed to start async key import task for group copy",
 	"error.export_logs":                   "failed to export logs",
 	"error.debug_mode_required":           "this operation requires DEBUG_MODE to be enabled",
+	"error.issue_debug_token":             "failed to issue debug token",
+	"error.revoke_debug_token":            "failed to revoke debug token",
 	"error.dynamic_weight_not_configured": "dynamic weight manager is not configured",
 	"error.health_reset_failed":           "failed to reset health metrics",
 
@@ -57,6 +57,19 @@ var MessagesJaJP = map[string]string{
 	"validation.group_not_found":                               "グループが見つかりません",
 	"validation.group_disabled":                                "グループが無効化されているため、この操作を実行できません",
 	"validation.invalid_status_filter":                         "無効なステータスフィルター",
+	"validation.invalid_export_format":                         "無効なエクスポート形式です。'csv'、'jsonl'、または 'columnar' を指定してください",
+	"validation.invalid_redaction_rules":                       "無効な秘匿化ルールです: {{.error}}",
+	"validation.replay_no_request_body":                        "このログにはリクエスト本文が保存されていません。リクエスト本文のログ記録を有効にするか、再送する本文を指定してください",
+	"validation.replay_invalid_body":                           "リクエスト本文が有効なJSONではありません。ログの保存上限を超える本文は切り詰められています",
+	"validation.replay_invalid_path":                           "無効な再送パスです。/proxy/<group> 以下の / で始まるパスを指定してください",
+	"validation.replay_model_unsettable":                       "このリクエストではモデルを上書きできません",
+	"validation.explain_invalid_path":                          "無効なパスです。/proxy/<group> 以下の / で始まるクライアントパスを指定してください",
+	"validation.explain_invalid_body":                          "リクエスト本文が有効なJSONではありません",
+	"validation.explain_invalid_method":                        "サポートされていないリクエストメソッドです。GET、POST、PUT、PATCH、DELETE を使用してください",
+	"validation.invalid_debug_capture_id":                      "無効なデバッグキャプチャIDです",
+	"validation.debug_capture_not_found":                       "デバッグキャプチャが見つかりません",
+	"validation.debug_token_ttl_negative":                      "ttl_minutes は負の値にできません",
+	"validation.debug_token_required":                          "デバッグトークンが必要です",
 	"validation.invalid_group_id":                              "無効なグループID形式",
 	"validation.test_model_required":                           "テストモデルが必要です",
 	"validation.invalid_copy_keys_value":                       "無効なcopy_keys値。'none'、'valid_only'、'all'のいずれかである必要があります",
@@ -176,6 +189,8 @@ var MessagesJaJP = map[string]string{
 	"config.log_write_interval_desc":          "リクエストログをキャッシュからデータベースに書き込む間隔（分）、0でリアルタイム書き込み。",
 	"config.enable_request_body_logging":      "リクエストボディログを有効化",
 	"config.enable_request_body_logging_desc": "完全なリクエストボディの内容をログに記録するかどうか。有効にするとメモリとストレージの使用量が増加します。",
+	"config.log_redaction_rules":              "ログ秘匿化ルール",
+	"config.log_redaction_rules_desc":         "保存前にログ記録されるリクエスト/レスポンスボディへ適用する JSON ルール。組み込み検出器（email、phone、credit_card、api_key）、カスタム正規表現、messages[*].content などの JSON 削除パスをサポートします。空の場合は無効です。",
 
 	// Request settings related
 	"config.request_timeout":                               "リクエストタイムアウト（秒）",
@@ -250,6 +265,8 @@ var MessagesJaJP = map[string]string{
 	"error.decrypt_key_copy":              "グループコピー中のキー復号化に失敗、スキップします",
 	"error.start_import_task":             "グループコピー用の非同期キーインポートタスクの開始に失敗しました",
 	"error.debug_mode_required":           "この操作にはDEBUG_MODEを有効にする必要があります",
+	"error.issue_debug_token":             "デバッグトークンの発行に失敗しました",
+	"error.revoke_debug_token":            "デバッグトークンの取り消しに失敗しました",
 	"error.dynamic_weight_not_configured": "動的重み管理が設定されていません",
 	"error.health_reset_failed":           "ヘルスメトリクスのリセットに失敗しました",
 	"error.export_logs":                   "ログのエクスポートに失敗しました",
@@ -57,6 +57,19 @@ var MessagesZhCN = map[string]string{
 	"validation.group_not_found":                               "分组不存在",
 	"validation.group_disabled":                                "分组已禁用，无法执行此操作",
 	"validation.invalid_status_filter":                         "无效的状态过滤器",
+	"validation.invalid_export_format":                         "无效的导出格式，必须为 'csv'、'jsonl' 或 'columnar'",
+	"validation.invalid_redaction_rules":                       "无效的脱敏规则：{{.error}}",
+	"validation.replay_no_request_body":                        "该日志没有保存请求体。请启用请求体日志记录，或提供要重放的请求体",
+	"validation.replay_invalid_body":                           "请求体不是有效的 JSON；超过日志保存上限的请求体会被截断",
+	"validation.replay_invalid_path":                           "无效的重放路径，必须是 /proxy/<group> 之后以 / 开头的路径",
+	"validation.replay_model_unsettable":                       "该请求无法覆盖模型",
+	"validation.explain_invalid_path":                          "无效的路径，必须是 /proxy/<group> 之后以 / 开头的客户端路径",
+	"validation.explain_invalid_body":                          "请求体不是有效的 JSON",
+	"validation.explain_invalid_method":                        "不支持的请求方法，请使用 GET、POST、PUT、PATCH 或 DELETE",
+	"validation.invalid_debug_capture_id":                      "无效的调试捕获ID",
+	"validation.debug_capture_not_found":                       "调试捕获不存在",
+	"validation.debug_token_ttl_negative":                      "ttl_minutes 不能为负数",
+	"validation.debug_token_required":                          "调试令牌是必需的",
 	"validation.invalid_group_id":                              "无效的分组ID格式",
 	"validation.test_model_required":                           "测试模型是必需的",
 	"validation.invalid_copy_keys_value":                       "无效的copy_keys值。必须是'none'、'valid_only'或'all'",
@@ -198,6 +211,8 @@ var MessagesZhCN = map[string]string{
 	"config.log_write_interval_desc":          "请求日志从缓存写入数据库的周期（分钟），0为实时写入数据。",
 	"config.enable_request_body_logging":      "启用日志详情",
 	"config.enable_request_body_logging_desc": "是否在请求日志中记录完整的请求体内容。启用此功能会增加内存以及存储空间的占用。",
+	"config.log_redaction_rules":              "日志脱敏规则",
+	"config.log_redaction_rules_desc":         "在存储前应用于请求/响应日志内容的 JSON 规则。支持内置检测器（email、phone、credit_card、api_key）、自定义正则以及 messages[*].content 等 JSON 删除路径。留空表示不启用。",
 
 	// Request settings related
 	"config.request_timeout":                               "请求超时（秒）",
@@ -272,6 +287,8 @@ var MessagesZhCN = map[string]string{
 	"error.decrypt_key_copy":              "解密密钥时失败，跳过该密钥",
 	"error.start_import_task":             "启动异步密钥导入任务失败",
 	"error.debug_mode_required":           "此操作需要启用 DEBUG_MODE",
+	"error.issue_debug_token":             "签发调试令牌失败",
+	"error.revoke_debug_token":            "撤销调试令牌失败",
 	"error.dynamic_weight_not_configured": "动态权重管理器未配置",
 	"error.health_reset_failed":           "重置健康度指标失败",
 	"error.export_logs":                   "导出日志失败",
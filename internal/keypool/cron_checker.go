@@ -57,8 +57,16 @@ func NewCronChecker(
 // Start begins the cron job execution.
 func (s *CronChecker) Start() {
 	logrus.Debug("Starting CronChecker...")
+	// Stop closes the channel, so a restart after regaining leadership needs a
+	// new one. The loop gets its own channel so that a loop left over from a
+	// timed-out Stop never reads the new one.
+	select {
+	case <-s.stopChan:
+		s.stopChan = make(chan struct{})
+	default:
+	}
 	s.wg.Add(1)
-	go s.runLoop()
+	go s.runLoop(s.stopChan)
 }
 
 // Stop stops the cron job, respecting the context for shutdown timeout.
@@ -80,7 +88,7 @@ func (s *CronChecker) Stop(ctx context.Context) {
 	}
 }
 
-func (s *CronChecker) runLoop() {
+func (s *CronChecker) runLoop(stopCh <-chan struct{}) {
 	defer s.wg.Done()
 
 	// Initial delay to allow database initialization to complete
@@ -89,11 +97,11 @@ func (s *CronChecker) runLoop() {
 	// 10 seconds is sufficient for most systems to complete DB initialization
 	select {
 	case <-time.After(10 * time.Second):
-	case <-s.stopChan:
+	case <-stopCh:
 		return
 	}
 
-	s.submitValidationJobs()
+	s.submitValidationJobs(stopCh)
 
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -102,15 +110,15 @@ func (s *CronChecker) runLoop() {
 		select {
 		case <-ticker.C:
 			logrus.Debug("CronChecker: Running as Master, submitting validation jobs.")
-			s.submitValidationJobs()
-		case <-s.stopChan:
+			s.submitValidationJobs(stopCh)
+		case <-stopCh:
 			return
 		}
 	}
 }
 
 // submitValidationJobs finds groups whose keys need validation and validates them concurrently.
-func (s *CronChecker) submitValidationJobs() {
+func (s *CronChecker) submitValidationJobs(stopCh <-chan struct{}) {
 	// Skip when a heavy task (import/delete) is running to avoid DB contention
 	if s.isBusy() {
 		logrus.Debug("CronChecker: busy mode detected (import/delete running), skipping this cycle")
@@ -153,7 +161,7 @@ func (s *CronChecker) submitValidationJobs() {
 			g := group
 			go func() {
 				defer wg.Done()
-				s.validateGroupKeys(g, &groupsToUpdateMu, groupsToUpdate)
+				s.validateGroupKeys(stopCh, g, &groupsToUpdateMu, groupsToUpdate)
 			}()
 		}
 	}
@@ -169,7 +177,7 @@ func (s *CronChecker) submitValidationJobs() {
 // validateGroupKeys validates all invalid keys for a single group concurrently.
 // It adds the group ID to groupsToUpdate map after validation completes.
 // Uses streaming batch processing for large result sets to improve performance and reduce memory usage.
-func (s *CronChecker) validateGroupKeys(group *models.Group, groupsToUpdateMu *sync.Mutex, groupsToUpdate map[uint]struct{}) {
+func (s *CronChecker) validateGroupKeys(stopCh <-chan struct{}, group *models.Group, groupsToUpdateMu *sync.Mutex, groupsToUpdate map[uint]struct{}) {
 	groupProcessStart := time.Now()
 
 	// First, check if there are any invalid keys (quick count query using index)
@@ -235,7 +243,7 @@ func (s *CronChecker) validateGroupKeys(group *models.Group, groupsToUpdateMu *s
 					if isValid {
 						atomic.AddInt32(&becameValidCount, 1)
 					}
-				case <-s.stopChan:
+				case <-stopCh:
 					return
 				}
 			}
@@ -283,7 +291,7 @@ func (s *CronChecker) validateGroupKeys(group *models.Group, groupsToUpdateMu *s
 			for i := range batchKeys {
 				select {
 				case jobs <- &batchKeys[i]:
-				case <-s.stopChan:
+				case <-stopCh:
 					return
 				}
 			}
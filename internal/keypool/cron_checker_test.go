@@ -155,7 +155,7 @@ func TestValidateGroupKeys_NoInvalidKeys(t *testing.T) {
 	groupsToUpdate := make(map[uint]struct{})
 	var mu sync.Mutex
 
-	cronChecker.validateGroupKeys(group, &mu, groupsToUpdate)
+	cronChecker.validateGroupKeys(cronChecker.stopChan, group, &mu, groupsToUpdate)
 
 	// Group should be marked for update
 	assert.Contains(t, groupsToUpdate, group.ID)
@@ -190,7 +190,7 @@ func TestValidateGroupKeys_DisabledGroup(t *testing.T) {
 	groupsToUpdate := make(map[uint]struct{})
 	var mu sync.Mutex
 
-	cronChecker.validateGroupKeys(group, &mu, groupsToUpdate)
+	cronChecker.validateGroupKeys(cronChecker.stopChan, group, &mu, groupsToUpdate)
 
 	// Group will be marked for update even if disabled (when called directly)
 	// In production, disabled groups are filtered out before calling validateGroupKeys
@@ -382,7 +382,7 @@ func BenchmarkValidateGroupKeys(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		cronChecker.validateGroupKeys(group, &mu, groupsToUpdate)
+		cronChecker.validateGroupKeys(cronChecker.stopChan, group, &mu, groupsToUpdate)
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
+	"gpt-load/internal/secrets"
 	"gpt-load/internal/store"
 	"gpt-load/internal/utils"
 	"math/rand"
@@ -34,6 +35,8 @@ type KeyProvider struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+	// resolver resolves keys stored as secret references; nil leaves them as is.
+	resolver *secrets.Resolver
 	// CacheInvalidationCallback is an optional callback for cache invalidation.
 	// Note: This callback will be invoked from a goroutine (spawned in UpdateStatus),
 	// so implementers must handle concurrent access if the callback accesses shared state.
@@ -76,6 +79,33 @@ func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemS
 	return p
 }
 
+// SetSecretResolver sets the resolver for keys stored as secret references.
+func (p *KeyProvider) SetSecretResolver(resolver *secrets.Resolver) {
+	p.resolver = resolver
+}
+
+// ResolveKeyValue returns the upstream key for a decrypted key value,
+// resolving secret references.
+func (p *KeyProvider) ResolveKeyValue(value string) (string, error) {
+	if p.resolver == nil {
+		return value, nil
+	}
+	return p.resolver.Resolve(value)
+}
+
+// resolveKey replaces a secret reference in apiKey with the secret value.
+func (p *KeyProvider) resolveKey(apiKey *models.APIKey) error {
+	if p.resolver == nil || !secrets.IsReference(apiKey.KeyValue) {
+		return nil
+	}
+	value, err := p.resolver.Resolve(apiKey.KeyValue)
+	if err != nil {
+		return fmt.Errorf("failed to resolve key %d: %w", apiKey.ID, err)
+	}
+	apiKey.KeyRef, apiKey.KeyValue = apiKey.KeyValue, value
+	return nil
+}
+
 // statusUpdateWorker processes status update tasks from the channel.
 func (p *KeyProvider) statusUpdateWorker() {
 	defer p.workerWg.Done()
@@ -148,8 +178,30 @@ func (p *KeyProvider) Stop() {
 	})
 }
 
+// secretResolveAttempts is how many keys SelectKey tries when secret
+// references cannot be resolved.
+const secretResolveAttempts = 3
+
 // SelectKey atomically selects and rotates an available APIKey for the specified group.
+// Keys whose secret reference cannot be resolved are skipped; they are not
+// counted as upstream failures.
 func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
+	var err error
+	for range secretResolveAttempts {
+		var apiKey *models.APIKey
+		apiKey, err = p.selectKey(groupID)
+		if err != nil {
+			return nil, err
+		}
+		if err = p.resolveKey(apiKey); err == nil {
+			return apiKey, nil
+		}
+		logrus.WithError(err).WithField("group_id", groupID).Warn("Skipping key with unresolvable secret reference")
+	}
+	return nil, err
+}
+
+func (p *KeyProvider) selectKey(groupID uint) (*models.APIKey, error) {
 	// Use strconv instead of fmt.Sprintf for better performance in hot path
 	activeKeysListKey := "group:" + strconv.FormatUint(uint64(groupID), 10) + ":active_keys"
 
@@ -169,12 +221,36 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 
 	// 2. Get key details from HASH
 	// Use strconv instead of fmt.Sprintf for better performance in hot path
-	keyHashKey := "key:" + keyIDStr
-	keyDetails, err := p.store.HGetAll(keyHashKey)
+	keyDetails, err := p.store.HGetAll("key:" + keyIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
+	}
+
+	return p.apiKeyFromDetails(groupID, uint(keyID), keyDetails), nil
+}
+
+// SelectKeyByID returns the given key when it is still active in the group.
+// Session affinity uses it to reuse a previously bound key without rotating the list;
+// callers fall back to SelectKey when it returns an error.
+func (p *KeyProvider) SelectKeyByID(groupID, keyID uint) (*models.APIKey, error) {
+	keyDetails, err := p.store.HGetAll("key:" + strconv.FormatUint(uint64(keyID), 10))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get key details for key ID %d: %w", keyID, err)
 	}
+	// A binding must never reuse a key that was removed, disabled or moved to another group.
+	if keyDetails["status"] != models.KeyStatusActive ||
+		keyDetails["group_id"] != strconv.FormatUint(uint64(groupID), 10) {
+		return nil, app_errors.ErrNoActiveKeys
+	}
+	apiKey := p.apiKeyFromDetails(groupID, keyID, keyDetails)
+	if err := p.resolveKey(apiKey); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
 
+// apiKeyFromDetails converts a key hash from the store into an APIKey with a decrypted value.
+func (p *KeyProvider) apiKeyFromDetails(groupID, keyID uint, keyDetails map[string]string) *models.APIKey {
 	// 3. Manually unmarshal the map into an APIKey struct
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
 	createdAt, _ := strconv.ParseInt(keyDetails["created_at"], 10, 64)
@@ -191,16 +267,14 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 		decryptedKeyValue = encryptedKeyValue
 	}
 
-	apiKey := &models.APIKey{
-		ID:           uint(keyID),
+	return &models.APIKey{
+		ID:           keyID,
 		KeyValue:     decryptedKeyValue,
 		Status:       keyDetails["status"],
 		FailureCount: failureCount,
 		GroupID:      groupID,
 		CreatedAt:    time.Unix(createdAt, 0),
 	}
-
-	return apiKey, nil
 }
 
 // UpdateStatus submits a key status update task to the worker pool.
@@ -6,6 +6,7 @@ import (
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
+	"gpt-load/internal/secrets"
 	"gpt-load/internal/store"
 	"testing"
 	"time"
@@ -141,6 +142,75 @@ func TestSelectKey_Success(t *testing.T) {
 	assert.Equal(t, models.KeyStatusActive, selectedKey.Status)
 }
 
+func TestSelectKeyByID(t *testing.T) {
+	provider, db, memStore := setupTestProvider(t)
+	defer provider.Stop()
+
+	group := createTestGroup(t, db, "affinity-group")
+	encSvc, _ := encryption.NewService("test-key-32-bytes-long-enough!!")
+	encryptedKey, err := encSvc.Encrypt("sk-bound")
+	require.NoError(t, err)
+
+	require.NoError(t, memStore.HSet("key:42", map[string]any{
+		"key_string":    encryptedKey,
+		"status":        models.KeyStatusActive,
+		"failure_count": "0",
+		"group_id":      group.ID,
+		"created_at":    time.Now().Unix(),
+	}))
+
+	selectedKey, err := provider.SelectKeyByID(group.ID, 42)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), selectedKey.ID)
+	assert.Equal(t, "sk-bound", selectedKey.KeyValue)
+
+	_, err = provider.SelectKeyByID(group.ID+1, 42)
+	assert.Error(t, err, "key from another group must not be reused")
+
+	require.NoError(t, memStore.HSet("key:42", map[string]any{"status": models.KeyStatusInvalid}))
+	_, err = provider.SelectKeyByID(group.ID, 42)
+	assert.Error(t, err, "invalid key must not be reused")
+
+	_, err = provider.SelectKeyByID(group.ID, 404)
+	assert.Error(t, err, "missing key must not be reused")
+}
+
+func TestSelectKey_ResolvesSecretReferences(t *testing.T) {
+	provider, db, memStore := setupTestProvider(t)
+	defer provider.Stop()
+	t.Setenv("GPT_LOAD_SECRET_TEST_UPSTREAM_KEY", "sk-from-env")
+	resolver, err := secrets.NewResolverFromEnv()
+	require.NoError(t, err)
+	provider.SetSecretResolver(resolver)
+
+	group := createTestGroup(t, db, "secret-group")
+	encSvc, _ := encryption.NewService("test-key-32-bytes-long-enough!!")
+	for id, value := range map[uint]string{1: "env:GPT_LOAD_SECRET_TEST_UPSTREAM_KEY", 2: "env:GPT_LOAD_SECRET_TEST_MISSING_KEY"} {
+		encryptedKey, err := encSvc.Encrypt(value)
+		require.NoError(t, err)
+		require.NoError(t, memStore.HSet(fmt.Sprintf("key:%d", id), map[string]any{
+			"key_string":    encryptedKey,
+			"status":        models.KeyStatusActive,
+			"failure_count": "0",
+			"group_id":      group.ID,
+			"created_at":    time.Now().Unix(),
+		}))
+		require.NoError(t, memStore.LPush(fmt.Sprintf("group:%d:active_keys", group.ID), id))
+	}
+
+	// The key with the missing variable is skipped on every rotation.
+	for range 4 {
+		selectedKey, err := provider.SelectKey(group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), selectedKey.ID)
+		assert.Equal(t, "sk-from-env", selectedKey.KeyValue)
+		assert.Equal(t, "env:GPT_LOAD_SECRET_TEST_UPSTREAM_KEY", selectedKey.StoredValue())
+	}
+
+	_, err = provider.SelectKeyByID(group.ID, 2)
+	assert.ErrorContains(t, err, "GPT_LOAD_SECRET_TEST_MISSING_KEY")
+}
+
 func TestUpdateStatus_Success(t *testing.T) {
 	provider, db, memStore := setupTestProvider(t)
 	defer provider.Stop()
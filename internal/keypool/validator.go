@@ -71,6 +71,18 @@ func (s *KeyValidator) validateSingleKey(key *models.APIKey, group *models.Group
 		return false, nil, fmt.Errorf("failed to get channel for group %s: %w", group.Name, err)
 	}
 
+	// An unavailable secret provider says nothing about the key itself, so
+	// the key status is left unchanged.
+	keyValue, err := s.keypoolProvider.ResolveKeyValue(key.KeyValue)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to resolve key %d: %w", key.ID, err)
+	}
+	if keyValue != key.KeyValue {
+		resolved := *key
+		resolved.KeyRef, resolved.KeyValue = key.KeyValue, keyValue
+		key = &resolved
+	}
+
 	var trace *channel.ValidationTrace
 	var isValid bool
 	var validationErr error
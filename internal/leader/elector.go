@@ -0,0 +1,383 @@
+// Package leader decides which node runs the master-only background services.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLeaseTTL is how long a lease stays valid without renewal.
+	DefaultLeaseTTL = 15 * time.Second
+
+	leaseKey         = "leader:lease"
+	fencingTokenKey  = "leader:fencing_token"
+	migrationLockKey = "leader:migration_lock"
+
+	// ModeStatic uses IS_SLAVE to decide the master.
+	ModeStatic = "static"
+	// ModeElection elects the master through the shared store.
+	ModeElection = "election"
+)
+
+// Status describes the current leadership as seen by this node.
+type Status struct {
+	Mode         string `json:"mode"`
+	IsLeader     bool   `json:"is_leader"`
+	NodeID       string `json:"node_id"`
+	LeaderID     string `json:"leader_id,omitempty"`
+	FencingToken int64  `json:"fencing_token,omitempty"`
+}
+
+// Elector holds a lease in the shared store while this node is the leader.
+// The lease value is "<node id>:<fencing token>"; the token increases with
+// every acquisition, so a node only ever renews the lease it acquired itself.
+// Writes of the master-only services are not fenced: a node that lost its
+// lease keeps running them until its next renewal attempt notices.
+// In static mode the elector only reports the IS_SLAVE configuration.
+type Elector struct {
+	store    store.Store
+	enabled  bool
+	static   bool
+	nodeID   string
+	ttl      time.Duration
+	interval time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	leader      atomic.Bool
+	lease       []byte
+	token       int64
+	lastRenewed time.Time
+
+	onChange func(isLeader bool)
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	started  bool
+}
+
+// NewElector creates an elector from the server configuration.
+func NewElector(s store.Store, configManager types.ConfigManager) *Elector {
+	serverConfig := configManager.GetEffectiveServerConfig()
+	e := newElector(s, serverConfig.LeaderElection, DefaultLeaseTTL)
+	e.static = serverConfig.IsMaster
+	return e
+}
+
+func newElector(s store.Store, enabled bool, ttl time.Duration) *Elector {
+	return &Elector{
+		store:    s,
+		enabled:  enabled,
+		nodeID:   newNodeID(),
+		ttl:      ttl,
+		interval: ttl / 3,
+		now:      time.Now,
+	}
+}
+
+func newNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "node"
+	}
+	return hostname + "-" + utils.GenerateRandomSuffix()
+}
+
+// Enabled reports whether leadership is elected rather than configured.
+func (e *Elector) Enabled() bool {
+	return e.enabled
+}
+
+// IsLeader reports whether this node should run the master-only services.
+func (e *Elector) IsLeader() bool {
+	if !e.enabled {
+		return e.static
+	}
+	return e.leader.Load()
+}
+
+// NodeID returns the identifier this node uses in the lease.
+func (e *Elector) NodeID() string {
+	return e.nodeID
+}
+
+// Status returns the current leadership for health and environment reporting.
+func (e *Elector) Status() Status {
+	if !e.enabled {
+		return Status{Mode: ModeStatic, IsLeader: e.static, NodeID: e.nodeID}
+	}
+
+	status := Status{Mode: ModeElection, IsLeader: e.leader.Load(), NodeID: e.nodeID}
+	e.mu.Lock()
+	if status.IsLeader {
+		status.FencingToken = e.token
+	}
+	e.mu.Unlock()
+
+	if value, err := e.store.Get(leaseKey); err == nil {
+		status.LeaderID, status.FencingToken = parseLease(value)
+	}
+	return status
+}
+
+// FencingToken returns the token of the lease held by this node, or 0. It is
+// reported for diagnostics; no store or database write checks it.
+func (e *Elector) FencingToken() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leader.Load() {
+		return 0
+	}
+	return e.token
+}
+
+// TryAcquire makes one attempt to become the leader without notifying the
+// change callback. It is used during startup to decide the initial role.
+func (e *Elector) TryAcquire() bool {
+	if !e.enabled {
+		return e.static
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.acquireLocked(); err != nil {
+		logrus.WithError(err).Warn("Leader election attempt failed")
+	}
+	return e.leader.Load()
+}
+
+// WithMigrationLock runs fn while holding the migration lock in the shared
+// store, so nodes that start together run the database migrations one at a
+// time. The lock is renewed while fn runs. In static mode fn runs directly.
+func (e *Elector) WithMigrationLock(ctx context.Context, fn func() error) error {
+	if !e.enabled {
+		return fn()
+	}
+
+	value := []byte(e.nodeID)
+	for {
+		ok, err := e.store.SetNX(migrationLockKey, value, e.ttl)
+		if err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if ok {
+			break
+		}
+		logrus.Debug("Waiting for another node to finish migrations")
+		select {
+		case <-time.After(e.interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	stopRenew := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := e.store.CompareAndSwap(migrationLockKey, value, value, e.ttl); err != nil {
+					logrus.WithError(err).Warn("Failed to renew migration lock")
+				}
+			case <-stopRenew:
+				return
+			}
+		}
+	}()
+
+	err := fn()
+	close(stopRenew)
+	wg.Wait()
+	if _, releaseErr := e.store.CompareAndDelete(migrationLockKey, value); releaseErr != nil {
+		logrus.WithError(releaseErr).Warn("Failed to release migration lock")
+	}
+	return err
+}
+
+// Start runs the election loop. onChange is called from the loop whenever this
+// node gains or loses leadership. It is a no-op in static mode.
+func (e *Elector) Start(onChange func(isLeader bool)) {
+	if !e.enabled {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.started {
+		return
+	}
+	e.started = true
+	e.onChange = onChange
+	e.stopCh = make(chan struct{})
+	e.wg.Add(1)
+	go e.run()
+	logrus.WithFields(logrus.Fields{"node_id": e.nodeID, "lease_ttl": e.ttl}).Info("Leader election started")
+}
+
+// Stop ends the election loop and releases the lease so another node can take
+// over without waiting for it to expire.
+func (e *Elector) Stop(ctx context.Context) {
+	if !e.enabled {
+		return
+	}
+	e.mu.Lock()
+	started := e.started
+	if started {
+		e.started = false
+		close(e.stopCh)
+	}
+	e.mu.Unlock()
+
+	if started {
+		done := make(chan struct{})
+		go func() {
+			e.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			logrus.Warn("Leader election loop stop timed out")
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leader.Load() {
+		return
+	}
+	if err := e.releaseLocked(); err != nil {
+		logrus.WithError(err).Warn("Failed to release leader lease")
+		return
+	}
+	logrus.WithField("node_id", e.nodeID).Info("Released leader lease")
+}
+
+func (e *Elector) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.tick()
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// tick renews the lease when leading and tries to acquire it otherwise.
+func (e *Elector) tick() {
+	e.mu.Lock()
+	wasLeader := e.leader.Load()
+	var err error
+	if wasLeader {
+		err = e.renewLocked()
+	} else {
+		err = e.acquireLocked()
+	}
+	isLeader := e.leader.Load()
+	onChange := e.onChange
+	e.mu.Unlock()
+
+	if err != nil {
+		logrus.WithError(err).Warn("Leader election tick failed")
+	}
+	if isLeader != wasLeader {
+		if isLeader {
+			logrus.WithField("node_id", e.nodeID).Info("Acquired leadership")
+		} else {
+			logrus.WithField("node_id", e.nodeID).Warn("Lost leadership")
+		}
+		if onChange != nil {
+			onChange(isLeader)
+		}
+	}
+}
+
+// acquireLocked takes the lease if nobody holds it. A fencing token is only
+// drawn when the lease looks free, so tokens are not burnt by every follower tick.
+func (e *Elector) acquireLocked() error {
+	exists, err := e.store.Exists(leaseKey)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	token, err := e.store.IncrByWithTTL(fencingTokenKey, 1, 0)
+	if err != nil {
+		return fmt.Errorf("failed to draw fencing token: %w", err)
+	}
+	value := []byte(e.nodeID + ":" + strconv.FormatInt(token, 10))
+	ok, err := e.store.SetNX(leaseKey, value, e.ttl)
+	if err != nil || !ok {
+		return err
+	}
+
+	e.lease = value
+	e.token = token
+	e.lastRenewed = e.now()
+	e.leader.Store(true)
+	return nil
+}
+
+// renewLocked extends the lease while it still carries this node's value.
+// Store errors are tolerated until the lease may have expired elsewhere.
+func (e *Elector) renewLocked() error {
+	ok, err := e.store.CompareAndSwap(leaseKey, e.lease, e.lease, e.ttl)
+	if err != nil {
+		if e.now().Sub(e.lastRenewed) >= e.ttl*2/3 {
+			e.demoteLocked()
+		}
+		return fmt.Errorf("failed to renew leader lease: %w", err)
+	}
+	if !ok {
+		e.demoteLocked()
+		return nil
+	}
+	e.lastRenewed = e.now()
+	return nil
+}
+
+// releaseLocked deletes the lease only while it still carries this node's
+// value, so a lease that expired and was taken over is left alone.
+func (e *Elector) releaseLocked() error {
+	defer e.demoteLocked()
+	_, err := e.store.CompareAndDelete(leaseKey, e.lease)
+	return err
+}
+
+func (e *Elector) demoteLocked() {
+	e.leader.Store(false)
+	e.lease = nil
+	e.token = 0
+}
+
+func parseLease(value []byte) (string, int64) {
+	s := string(value)
+	idx := strings.LastIndex(s, ":")
+	if idx < 0 {
+		return s, 0
+	}
+	token, _ := strconv.ParseInt(s[idx+1:], 10, 64)
+	return s[:idx], token
+}
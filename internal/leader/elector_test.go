@@ -0,0 +1,162 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestElector(s store.Store, ttl time.Duration) *Elector {
+	return newElector(s, true, ttl)
+}
+
+func TestElectorStaticMode(t *testing.T) {
+	e := newElector(store.NewMemoryStore(), false, time.Second)
+	e.static = true
+
+	assert.True(t, e.TryAcquire())
+	assert.True(t, e.IsLeader())
+	assert.Equal(t, ModeStatic, e.Status().Mode)
+
+	e.static = false
+	assert.False(t, e.IsLeader())
+	e.Start(func(bool) { t.Fatal("static mode must not call onChange") })
+	e.Stop(context.Background())
+}
+
+func TestElectorOnlyOneNodeAcquires(t *testing.T) {
+	s := store.NewMemoryStore()
+	first := newTestElector(s, time.Minute)
+	second := newTestElector(s, time.Minute)
+
+	require.True(t, first.TryAcquire())
+	assert.False(t, second.TryAcquire())
+
+	status := second.Status()
+	assert.Equal(t, ModeElection, status.Mode)
+	assert.False(t, status.IsLeader)
+	assert.Equal(t, first.NodeID(), status.LeaderID)
+	assert.Equal(t, first.FencingToken(), status.FencingToken)
+	assert.Zero(t, second.FencingToken())
+}
+
+func TestElectorReleaseHandsOver(t *testing.T) {
+	s := store.NewMemoryStore()
+	first := newTestElector(s, time.Minute)
+	second := newTestElector(s, time.Minute)
+
+	require.True(t, first.TryAcquire())
+	firstToken := first.FencingToken()
+	first.Stop(context.Background())
+	assert.False(t, first.IsLeader())
+
+	require.True(t, second.TryAcquire())
+	assert.Greater(t, second.FencingToken(), firstToken, "fencing tokens increase with every acquisition")
+
+	// Releasing must not delete a lease held by another node.
+	first.Stop(context.Background())
+	exists, err := s.Exists(leaseKey)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestElectorRenewsLease(t *testing.T) {
+	s := store.NewMemoryStore()
+	leader := newTestElector(s, 150*time.Millisecond)
+	follower := newTestElector(s, 150*time.Millisecond)
+
+	require.True(t, leader.TryAcquire())
+	leader.Start(nil)
+	defer leader.Stop(context.Background())
+	follower.Start(nil)
+	defer follower.Stop(context.Background())
+
+	time.Sleep(500 * time.Millisecond)
+	assert.True(t, leader.IsLeader(), "renewal keeps the lease beyond its TTL")
+	assert.False(t, follower.IsLeader())
+}
+
+func TestElectorTakesOverExpiredLease(t *testing.T) {
+	s := store.NewMemoryStore()
+	crashed := newTestElector(s, 100*time.Millisecond)
+	require.True(t, crashed.TryAcquire())
+
+	var changes atomic.Int32
+	follower := newTestElector(s, 100*time.Millisecond)
+	follower.Start(func(isLeader bool) {
+		if isLeader {
+			changes.Add(1)
+		}
+	})
+	defer follower.Stop(context.Background())
+
+	require.Eventually(t, follower.IsLeader, 2*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 1, changes.Load())
+}
+
+func TestElectorDemotesWhenLeaseIsLost(t *testing.T) {
+	s := store.NewMemoryStore()
+	e := newTestElector(s, 150*time.Millisecond)
+	require.True(t, e.TryAcquire())
+
+	lost := make(chan struct{}, 1)
+	e.Start(func(isLeader bool) {
+		if !isLeader {
+			lost <- struct{}{}
+		}
+	})
+	defer e.Stop(context.Background())
+
+	// Another node overwrote the lease, e.g. after a long pause of this one.
+	require.NoError(t, s.Set(leaseKey, []byte("other:99"), time.Minute))
+
+	select {
+	case <-lost:
+	case <-time.After(2 * time.Second):
+		t.Fatal("elector did not notice the lost lease")
+	}
+	assert.False(t, e.IsLeader())
+}
+
+func TestElectorMigrationLockRunsNodesOneAtATime(t *testing.T) {
+	s := store.NewMemoryStore()
+	first := newTestElector(s, 300*time.Millisecond)
+	second := newTestElector(s, 300*time.Millisecond)
+
+	var running, overlaps, runs atomic.Int32
+	migrate := func() error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		// Longer than the lock TTL, so the lock must be renewed.
+		time.Sleep(500 * time.Millisecond)
+		running.Add(-1)
+		runs.Add(1)
+		return nil
+	}
+
+	done := make(chan error, 2)
+	for _, e := range []*Elector{first, second} {
+		go func() { done <- e.WithMigrationLock(context.Background(), migrate) }()
+	}
+	for range 2 {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("migrations did not finish")
+		}
+	}
+	assert.EqualValues(t, 2, runs.Load(), "every node runs the migrations")
+	assert.Zero(t, overlaps.Load())
+
+	exists, err := s.Exists(migrationLockKey)
+	require.NoError(t, err)
+	assert.False(t, exists, "the lock is released")
+}
@@ -0,0 +1,191 @@
+package logsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sink types.
+const (
+	TypeHTTP   = "http"
+	TypeFile   = "file"
+	TypeSyslog = "syslog"
+)
+
+// HTTP payload formats.
+const (
+	FormatJSON          = "json"          // JSON array of records
+	FormatNDJSON        = "ndjson"        // One JSON record per line
+	FormatElasticsearch = "elasticsearch" // Elasticsearch _bulk API body
+	FormatLoki          = "loki"          // Loki push API body
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	defaultBufferSize    = 10000
+	defaultMaxRetries    = 3
+	defaultHTTPTimeout   = 10 * time.Second
+	defaultFileMaxSizeMB = 100
+	defaultFileBackups   = 5
+	defaultSyslogTag     = "gpt-load"
+)
+
+// defaultExcludedFields are left out when a sink has no explicit field allow-list.
+// Bodies can be large and sensitive; key values are ciphertext and useless downstream.
+var defaultExcludedFields = map[string]bool{
+	"request_body":  true,
+	"response_body": true,
+	"key_value":     true,
+}
+
+// Config describes one log sink. It is read from the LOG_SINKS environment
+// variable as a JSON array.
+type Config struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+
+	// Fields is an allow-list of RequestLog JSON field names. When empty, every
+	// field except the request/response bodies and key value is shipped.
+	Fields []string `json:"fields"`
+
+	BatchSize            int `json:"batch_size"`
+	FlushIntervalSeconds int `json:"flush_interval_seconds"`
+	// BufferSize bounds the records held for the sink, including failed batches
+	// waiting for a retry. The oldest records are dropped when it is full.
+	BufferSize int `json:"buffer_size"`
+	MaxRetries int `json:"max_retries"`
+
+	// HTTP sink
+	URL            string            `json:"url"`
+	Format         string            `json:"format"`
+	Headers        map[string]string `json:"headers"`
+	Index          string            `json:"index"`  // Elasticsearch index name
+	Labels         map[string]string `json:"labels"` // Loki stream labels
+	TimeoutSeconds int               `json:"timeout_seconds"`
+
+	// File sink
+	Path       string `json:"path"`
+	MaxSizeMB  int    `json:"max_size_mb"`
+	MaxBackups int    `json:"max_backups"`
+
+	// Syslog sink
+	Network string `json:"network"` // "udp" or "tcp"
+	Address string `json:"address"`
+	Tag     string `json:"tag"`
+}
+
+// ParseConfigs parses and validates a JSON array of sink configs.
+func ParseConfigs(raw string) ([]Config, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var configs []Config
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, fmt.Errorf("invalid LOG_SINKS: %w", err)
+	}
+	for i := range configs {
+		if err := configs[i].normalize(i); err != nil {
+			return nil, err
+		}
+	}
+	return configs, nil
+}
+
+func (c *Config) normalize(index int) error {
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	if c.Name == "" {
+		c.Name = fmt.Sprintf("%s-%d", c.Type, index)
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.FlushIntervalSeconds <= 0 {
+		c.FlushIntervalSeconds = int(defaultFlushInterval / time.Second)
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = defaultBufferSize
+	}
+	if c.BufferSize < c.BatchSize {
+		c.BufferSize = c.BatchSize
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	for _, field := range c.Fields {
+		if !knownFields[field] {
+			return fmt.Errorf("log sink %q: unknown field %q", c.Name, field)
+		}
+	}
+
+	switch c.Type {
+	case TypeHTTP:
+		if c.URL == "" {
+			return fmt.Errorf("log sink %q: url is required", c.Name)
+		}
+		c.Format = strings.ToLower(strings.TrimSpace(c.Format))
+		switch c.Format {
+		case "":
+			c.Format = FormatJSON
+		case FormatJSON, FormatNDJSON, FormatLoki:
+		case FormatElasticsearch:
+			if c.Index == "" {
+				c.Index = "gpt-load-request-logs"
+			}
+		default:
+			return fmt.Errorf("log sink %q: unsupported format %q", c.Name, c.Format)
+		}
+		if c.TimeoutSeconds <= 0 {
+			c.TimeoutSeconds = int(defaultHTTPTimeout / time.Second)
+		}
+	case TypeFile:
+		if c.Path == "" {
+			return fmt.Errorf("log sink %q: path is required", c.Name)
+		}
+		if c.MaxSizeMB <= 0 {
+			c.MaxSizeMB = defaultFileMaxSizeMB
+		}
+		if c.MaxBackups <= 0 {
+			c.MaxBackups = defaultFileBackups
+		}
+	case TypeSyslog:
+		if c.Address == "" {
+			return fmt.Errorf("log sink %q: address is required", c.Name)
+		}
+		c.Network = strings.ToLower(strings.TrimSpace(c.Network))
+		if c.Network == "" {
+			c.Network = "udp"
+		}
+		if c.Network != "udp" && c.Network != "tcp" {
+			return fmt.Errorf("log sink %q: network must be 'udp' or 'tcp'", c.Name)
+		}
+		if c.Tag == "" {
+			c.Tag = defaultSyslogTag
+		}
+	default:
+		return fmt.Errorf("log sink %q: unsupported type %q", c.Name, c.Type)
+	}
+	return nil
+}
+
+// fieldFilter returns the set of fields shipped by the sink.
+func (c *Config) fieldFilter() map[string]bool {
+	allowed := make(map[string]bool, len(knownFields))
+	if len(c.Fields) > 0 {
+		for _, field := range c.Fields {
+			allowed[field] = true
+		}
+		return allowed
+	}
+	for field := range knownFields {
+		if !defaultExcludedFields[field] {
+			allowed[field] = true
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,38 @@
+package logsink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkRotates(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "logs", "requests.ndjson")
+	sink, err := newFileSink(Config{Name: "file", Path: path, MaxSizeMB: 1, MaxBackups: 2})
+	require.NoError(t, err)
+	// Rotate after every write to exercise the backup chain.
+	sink.maxBytes = 10
+
+	for _, model := range []string{"first", "second", "third", "fourth"} {
+		require.NoError(t, sink.Write(context.Background(), []Record{{Fields: map[string]any{"model": model}}}))
+	}
+	require.NoError(t, sink.Close())
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return strings.TrimSpace(string(data))
+	}
+	assert.Equal(t, `{"model":"fourth"}`, read(path))
+	assert.Equal(t, `{"model":"third"}`, read(path+".1"))
+	assert.Equal(t, `{"model":"second"}`, read(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only max_backups files are kept")
+}
@@ -0,0 +1,120 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpSink POSTs each batch to a collector as a JSON array, NDJSON, an
+// Elasticsearch _bulk body or a Loki push request.
+type httpSink struct {
+	cfg    Config
+	client *http.Client
+}
+
+func newHTTPSink(cfg Config) *httpSink {
+	return &httpSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+	}
+}
+
+func (s *httpSink) Write(ctx context.Context, records []Record) error {
+	body, contentType, err := s.encode(records)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range s.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	// The bulk API reports per-item failures with a 200 status.
+	if s.cfg.Format == FormatElasticsearch {
+		var bulk struct {
+			Errors bool `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &bulk) == nil && bulk.Errors {
+			return fmt.Errorf("elasticsearch bulk request reported item errors")
+		}
+	}
+	return nil
+}
+
+func (s *httpSink) encode(records []Record) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch s.cfg.Format {
+	case FormatNDJSON:
+		encoder := json.NewEncoder(&buf)
+		for _, record := range records {
+			if err := encoder.Encode(record.Fields); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+
+	case FormatElasticsearch:
+		encoder := json.NewEncoder(&buf)
+		action := map[string]any{"index": map[string]any{"_index": s.cfg.Index}}
+		for _, record := range records {
+			if err := encoder.Encode(action); err != nil {
+				return nil, "", err
+			}
+			if err := encoder.Encode(record.Fields); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+
+	case FormatLoki:
+		labels := map[string]string{"job": "gpt-load"}
+		for key, value := range s.cfg.Labels {
+			labels[key] = value
+		}
+		values := make([][2]string, 0, len(records))
+		for _, record := range records {
+			line, err := json.Marshal(record.Fields)
+			if err != nil {
+				return nil, "", err
+			}
+			values = append(values, [2]string{strconv.FormatInt(record.Timestamp.UnixNano(), 10), string(line)})
+		}
+		payload := map[string]any{
+			"streams": []any{map[string]any{"stream": labels, "values": values}},
+		}
+		data, err := json.Marshal(payload)
+		return data, "application/json", err
+
+	default:
+		documents := make([]map[string]any, len(records))
+		for i, record := range records {
+			documents[i] = record.Fields
+		}
+		data, err := json.Marshal(documents)
+		return data, "application/json", err
+	}
+}
+
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,83 @@
+package logsink
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestHTTPSinkFormats(t *testing.T) {
+	t.Parallel()
+
+	var gotBody, gotContentType, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotContentType, gotAuth = string(body), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		_, _ = io.WriteString(w, `{"errors":false}`)
+	}))
+	t.Cleanup(server.Close)
+
+	ts := time.Unix(1700000000, 5)
+	records := []Record{{Timestamp: ts, Fields: map[string]any{"model": "m"}}}
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T)
+	}{
+		{FormatJSON, func(t *testing.T) {
+			assert.Equal(t, "application/json", gotContentType)
+			assert.Equal(t, "m", gjson.Get(gotBody, "0.model").String())
+		}},
+		{FormatNDJSON, func(t *testing.T) {
+			assert.Equal(t, "{\"model\":\"m\"}\n", gotBody)
+		}},
+		{FormatElasticsearch, func(t *testing.T) {
+			lines := strings.Split(strings.TrimSpace(gotBody), "\n")
+			require.Len(t, lines, 2)
+			assert.Equal(t, "logs", gjson.Get(lines[0], "index._index").String())
+			assert.Equal(t, "application/x-ndjson", gotContentType)
+		}},
+		{FormatLoki, func(t *testing.T) {
+			assert.Equal(t, "prod", gjson.Get(gotBody, "streams.0.stream.env").String())
+			assert.Equal(t, "1700000000000000005", gjson.Get(gotBody, "streams.0.values.0.0").String())
+			assert.Equal(t, `{"model":"m"}`, gjson.Get(gotBody, "streams.0.values.0.1").String())
+		}},
+	}
+
+	for _, tt := range tests {
+		sink := newHTTPSink(Config{
+			URL: server.URL, Format: tt.format, Index: "logs", TimeoutSeconds: 5,
+			Labels: map[string]string{"env": "prod"}, Headers: map[string]string{"Authorization": "Bearer t"},
+		})
+		require.NoError(t, sink.Write(context.Background(), records), tt.format)
+		assert.Equal(t, "Bearer t", gotAuth)
+		tt.check(t)
+	}
+}
+
+func TestHTTPSinkReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	status := http.StatusServiceUnavailable
+	body := "down"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	sink := newHTTPSink(Config{URL: server.URL, Format: FormatJSON, TimeoutSeconds: 5})
+	assert.ErrorContains(t, sink.Write(context.Background(), []Record{{Fields: map[string]any{}}}), "status 503")
+
+	status, body = http.StatusOK, `{"errors":true}`
+	sink = newHTTPSink(Config{URL: server.URL, Format: FormatElasticsearch, Index: "logs", TimeoutSeconds: 5})
+	assert.ErrorContains(t, sink.Write(context.Background(), []Record{{Fields: map[string]any{}}}), "item errors")
+}
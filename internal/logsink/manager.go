@@ -0,0 +1,325 @@
+// Package logsink ships request logs to external systems alongside the database.
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// knownFields holds the JSON field names of models.RequestLog.
+var knownFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(models.RequestLog{})
+	for i := range t.NumField() {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// Record is one request log projected onto a sink's field allow-list.
+type Record struct {
+	Timestamp time.Time
+	Fields    map[string]any
+}
+
+// Sink delivers batches of records to one destination. Write is only called from
+// the sink's own worker goroutine.
+type Sink interface {
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
+
+// partialWriteError is returned by a sink that delivered the first written
+// records of a batch before failing, so only the remainder is sent again.
+type partialWriteError struct {
+	written int
+	err     error
+}
+
+func (e *partialWriteError) Error() string { return e.err.Error() }
+
+func (e *partialWriteError) Unwrap() error { return e.err }
+
+// Manager fans request logs out to the configured sinks. Each sink has its own
+// bounded buffer and worker, so a slow or unavailable destination never blocks
+// the database flush or other sinks. A nil Manager is valid and does nothing.
+type Manager struct {
+	workers []*worker
+}
+
+// NewManagerFromEnv creates a manager from the LOG_SINKS environment variable.
+// It returns nil when no sinks are configured.
+func NewManagerFromEnv() (*Manager, error) {
+	configs, err := ParseConfigs(os.Getenv("LOG_SINKS"))
+	if err != nil {
+		return nil, err
+	}
+	return NewManager(configs)
+}
+
+// NewManager creates the sinks and starts their workers.
+func NewManager(configs []Config) (*Manager, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	m := &Manager{}
+	for _, cfg := range configs {
+		sink, err := newSink(cfg)
+		if err != nil {
+			m.Stop(context.Background())
+			return nil, err
+		}
+		m.workers = append(m.workers, newWorker(cfg, sink))
+		logrus.WithFields(logrus.Fields{"sink": cfg.Name, "type": cfg.Type}).Info("Request log sink enabled")
+	}
+	return m, nil
+}
+
+func newSink(cfg Config) (Sink, error) {
+	switch cfg.Type {
+	case TypeHTTP:
+		return newHTTPSink(cfg), nil
+	case TypeFile:
+		return newFileSink(cfg)
+	default:
+		return newSyslogSink(cfg), nil
+	}
+}
+
+// Publish queues logs for every sink. It never blocks on sink delivery.
+func (m *Manager) Publish(logs []*models.RequestLog) {
+	if m == nil || len(logs) == 0 {
+		return
+	}
+	documents := make([]map[string]any, 0, len(logs))
+	timestamps := make([]time.Time, 0, len(logs))
+	for _, log := range logs {
+		data, err := json.Marshal(log)
+		if err != nil {
+			continue
+		}
+		var document map[string]any
+		if err := json.Unmarshal(data, &document); err != nil {
+			continue
+		}
+		documents = append(documents, document)
+		timestamps = append(timestamps, log.Timestamp)
+	}
+	for _, w := range m.workers {
+		records := make([]Record, len(documents))
+		for i, document := range documents {
+			records[i] = Record{Timestamp: timestamps[i], Fields: project(document, w.fields)}
+		}
+		w.enqueue(records)
+	}
+}
+
+// Stop flushes the buffered records and closes every sink.
+func (m *Manager) Stop(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, w := range m.workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.stop(ctx)
+		}(w)
+	}
+	wg.Wait()
+}
+
+func project(document map[string]any, fields map[string]bool) map[string]any {
+	projected := make(map[string]any, len(fields))
+	for key, value := range document {
+		if fields[key] {
+			projected[key] = value
+		}
+	}
+	return projected
+}
+
+// worker batches records for one sink and retries failed batches.
+type worker struct {
+	name          string
+	sink          Sink
+	fields        map[string]bool
+	batchSize     int
+	bufferSize    int
+	maxRetries    int
+	flushInterval time.Duration
+
+	mu     sync.Mutex
+	buffer []Record
+
+	wake     chan struct{}
+	stopChan chan struct{}
+	done     chan struct{}
+	// stopCtx bounds the final drain; set before stopChan is closed.
+	stopCtx context.Context
+	// ctx is passed to interval flushes and cancelled when stop gives up
+	// waiting, so a slow write does not outlive the worker.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// stopGrace is how long stop waits for the worker after cancelling its
+// writes; var for tests.
+var stopGrace = time.Second
+
+// retryBackoff is the delay before retry n (1-based); var for tests.
+var retryBackoff = func(n int) time.Duration {
+	return time.Duration(1<<min(n-1, 5)) * time.Second
+}
+
+func newWorker(cfg Config, sink Sink) *worker {
+	w := &worker{
+		name:          cfg.Name,
+		sink:          sink,
+		fields:        cfg.fieldFilter(),
+		batchSize:     cfg.BatchSize,
+		bufferSize:    cfg.BufferSize,
+		maxRetries:    cfg.MaxRetries,
+		flushInterval: time.Duration(cfg.FlushIntervalSeconds) * time.Second,
+		wake:          make(chan struct{}, 1),
+		stopChan:      make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	go w.run()
+	return w
+}
+
+func (w *worker) enqueue(records []Record) {
+	w.mu.Lock()
+	w.buffer = append(w.buffer, records...)
+	overflow := w.trimLocked()
+	full := len(w.buffer) >= w.batchSize
+	w.mu.Unlock()
+
+	if overflow > 0 {
+		logrus.WithFields(logrus.Fields{"sink": w.name, "dropped": overflow}).Warn("Log sink buffer full, dropping oldest records")
+	}
+	if full {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// trimLocked drops the oldest records beyond the buffer bound. Caller holds w.mu.
+func (w *worker) trimLocked() int {
+	overflow := len(w.buffer) - w.bufferSize
+	if overflow <= 0 {
+		return 0
+	}
+	w.buffer = append(w.buffer[:0:0], w.buffer[overflow:]...)
+	return overflow
+}
+
+func (w *worker) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush(w.ctx, true, false)
+		case <-w.wake:
+			w.flush(w.ctx, false, false)
+		case <-w.stopChan:
+			w.flush(w.stopCtx, true, true)
+			return
+		}
+	}
+}
+
+// flush sends the buffered records in batches. Partial batches are only sent when
+// partial is set (interval ticks and shutdown). A batch that still fails after its
+// retries is put back at the head of the buffer, except during the final drain.
+func (w *worker) flush(ctx context.Context, partial, final bool) {
+	for {
+		w.mu.Lock()
+		n := min(len(w.buffer), w.batchSize)
+		if n == 0 || (!partial && n < w.batchSize) {
+			w.mu.Unlock()
+			return
+		}
+		batch := append([]Record(nil), w.buffer[:n]...)
+		w.buffer = w.buffer[n:]
+		w.mu.Unlock()
+
+		if unsent, err := w.send(ctx, batch); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{"sink": w.name, "records": len(unsent)}).Warn("Failed to ship request logs")
+			if final {
+				return
+			}
+			w.mu.Lock()
+			w.buffer = append(unsent, w.buffer...)
+			w.trimLocked()
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+// send writes batch with retries and returns the records that were not
+// delivered when it gives up.
+func (w *worker) send(ctx context.Context, batch []Record) ([]Record, error) {
+	var err error
+	for attempt := 0; attempt <= w.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(retryBackoff(attempt)):
+			case <-ctx.Done():
+				return batch, ctx.Err()
+			}
+		}
+		if err = w.sink.Write(ctx, batch); err == nil {
+			return nil, nil
+		}
+		var partial *partialWriteError
+		if errors.As(err, &partial) {
+			batch = batch[partial.written:]
+		}
+	}
+	return batch, err
+}
+
+func (w *worker) stop(ctx context.Context) {
+	w.stopCtx = ctx
+	close(w.stopChan)
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		logrus.WithField("sink", w.name).Warn("Log sink stop timed out, remaining records are dropped")
+		w.cancel()
+		select {
+		case <-w.done:
+		case <-time.After(stopGrace):
+			// Closing the sink under a write that is still running is unsafe.
+			logrus.WithField("sink", w.name).Warn("Log sink did not stop, leaving it open")
+			return
+		}
+	}
+	w.cancel()
+	if err := w.sink.Close(); err != nil {
+		logrus.WithError(err).WithField("sink", w.name).Warn("Failed to close log sink")
+	}
+}
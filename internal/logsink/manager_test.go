@@ -0,0 +1,224 @@
+package logsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	retryBackoff = func(int) time.Duration { return time.Millisecond }
+	stopGrace = 50 * time.Millisecond
+}
+
+type recordingSink struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][]Record
+}
+
+func (s *recordingSink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("collector unavailable")
+	}
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func (s *recordingSink) records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var all []Record
+	for _, batch := range s.batches {
+		all = append(all, batch...)
+	}
+	return all
+}
+
+func TestParseConfigs(t *testing.T) {
+	t.Parallel()
+
+	configs, err := ParseConfigs(`[{"type":"http","url":"http://collector","format":"elasticsearch"},{"type":"FILE","path":"/tmp/x.ndjson"}]`)
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	assert.Equal(t, "gpt-load-request-logs", configs[0].Index)
+	assert.Equal(t, defaultBatchSize, configs[0].BatchSize)
+	assert.Equal(t, TypeFile, configs[1].Type)
+	assert.Equal(t, "file-1", configs[1].Name)
+
+	configs, err = ParseConfigs("")
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+
+	for _, raw := range []string{
+		`[{"type":"http"}]`,
+		`[{"type":"http","url":"http://x","format":"xml"}]`,
+		`[{"type":"syslog","address":"x:514","network":"unix"}]`,
+		`[{"type":"kafka"}]`,
+		`[{"type":"file","path":"x","fields":["no_such_field"]}]`,
+		`not json`,
+	} {
+		_, err := ParseConfigs(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestFieldFilter(t *testing.T) {
+	t.Parallel()
+
+	defaults := (&Config{}).fieldFilter()
+	assert.True(t, defaults["model"])
+	assert.False(t, defaults["request_body"])
+	assert.False(t, defaults["key_value"])
+
+	explicit := (&Config{Fields: []string{"model", "request_body"}}).fieldFilter()
+	assert.Equal(t, map[string]bool{"model": true, "request_body": true}, explicit)
+}
+
+func TestWorkerRetriesAndDrainsOnStop(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{failures: 2}
+	cfg := Config{Name: "test", BatchSize: 2, BufferSize: 10, MaxRetries: 3, FlushIntervalSeconds: 3600, Fields: []string{"model"}}
+	m := &Manager{workers: []*worker{newWorker(cfg, sink)}}
+
+	m.Publish([]*models.RequestLog{
+		{Model: "a", RequestBody: "secret"},
+		{Model: "b"},
+		{Model: "c"},
+	})
+	m.Stop(context.Background())
+
+	records := sink.records()
+	require.Len(t, records, 3)
+	assert.Equal(t, map[string]any{"model": "a"}, records[0].Fields)
+	assert.Equal(t, "c", records[2].Fields["model"])
+}
+
+// blockingSink blocks in Write until released, or until the write context is
+// cancelled when honorContext is set.
+type blockingSink struct {
+	honorContext bool
+	writing      chan struct{}
+	release      chan struct{}
+
+	mu                sync.Mutex
+	inWrite           bool
+	closed            bool
+	closedDuringWrite bool
+}
+
+func (s *blockingSink) Write(ctx context.Context, _ []Record) error {
+	s.mu.Lock()
+	s.inWrite = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inWrite = false
+		s.mu.Unlock()
+	}()
+	s.writing <- struct{}{}
+	if s.honorContext {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.release:
+			return nil
+		}
+	}
+	<-s.release
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.closedDuringWrite = s.inWrite
+	return nil
+}
+
+func TestWorkerStopDoesNotCloseSinkDuringWrite(t *testing.T) {
+	t.Parallel()
+
+	for _, honorContext := range []bool{true, false} {
+		sink := &blockingSink{honorContext: honorContext, writing: make(chan struct{}, 1), release: make(chan struct{})}
+		cfg := Config{Name: "blocking", BatchSize: 1, BufferSize: 10, FlushIntervalSeconds: 3600}
+		w := newWorker(cfg, sink)
+		w.enqueue([]Record{{Fields: map[string]any{"n": 1}}})
+		<-sink.writing
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		w.stop(ctx)
+		cancel()
+
+		sink.mu.Lock()
+		assert.False(t, sink.closedDuringWrite, "honorContext=%v", honorContext)
+		assert.Equal(t, honorContext, sink.closed, "the sink is closed only after the worker exits")
+		sink.mu.Unlock()
+		close(sink.release)
+	}
+}
+
+func TestWorkerBufferIsBounded(t *testing.T) {
+	t.Parallel()
+
+	w := &worker{name: "bounded", batchSize: 10, bufferSize: 3, wake: make(chan struct{}, 1)}
+	w.enqueue([]Record{{Fields: map[string]any{"n": 1}}, {Fields: map[string]any{"n": 2}}})
+	w.enqueue([]Record{{Fields: map[string]any{"n": 3}}, {Fields: map[string]any{"n": 4}}})
+
+	require.Len(t, w.buffer, 3)
+	assert.Equal(t, 2, w.buffer[0].Fields["n"], "oldest record is dropped")
+}
+
+func TestNilManagerIsNoop(t *testing.T) {
+	t.Parallel()
+
+	var m *Manager
+	m.Publish([]*models.RequestLog{{Model: "a"}})
+	m.Stop(context.Background())
+}
+
+// partialSink delivers only the first record of its first batch and then fails.
+type partialSink struct {
+	recordingSink
+	failed bool
+}
+
+func (s *partialSink) Write(ctx context.Context, records []Record) error {
+	if !s.failed {
+		s.failed = true
+		_ = s.recordingSink.Write(ctx, records[:1])
+		return &partialWriteError{written: 1, err: errors.New("connection reset")}
+	}
+	return s.recordingSink.Write(ctx, records)
+}
+
+func TestWorkerResendsOnlyUnsentRecords(t *testing.T) {
+	t.Parallel()
+
+	sink := &partialSink{}
+	cfg := Config{Name: "partial", BatchSize: 3, BufferSize: 10, MaxRetries: 1, FlushIntervalSeconds: 3600, Fields: []string{"model"}}
+	m := &Manager{workers: []*worker{newWorker(cfg, sink)}}
+
+	m.Publish([]*models.RequestLog{{Model: "a"}, {Model: "b"}, {Model: "c"}})
+	m.Stop(context.Background())
+
+	var values []any
+	for _, record := range sink.records() {
+		values = append(values, record.Fields["model"])
+	}
+	assert.Equal(t, []any{"a", "b", "c"}, values, "records are delivered once")
+}
@@ -0,0 +1,104 @@
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// syslogFacilityLocal0 with severity info (local0.info) or warning for failed requests.
+	syslogPriorityInfo    = 16*8 + 6
+	syslogPriorityWarning = 16*8 + 4
+	syslogDialTimeout     = 5 * time.Second
+)
+
+// syslogSink sends one RFC 5424 message per record with the record JSON as the
+// message body. TCP uses octet-counting framing (RFC 6587).
+type syslogSink struct {
+	cfg      Config
+	hostname string
+	conn     net.Conn
+}
+
+func newSyslogSink(cfg Config) *syslogSink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &syslogSink{cfg: cfg, hostname: hostname}
+}
+
+func (s *syslogSink) Write(ctx context.Context, records []Record) error {
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: syslogDialTimeout}
+		conn, err := dialer.DialContext(ctx, s.cfg.Network, s.cfg.Address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	for i, record := range records {
+		message, err := s.format(record)
+		if err != nil {
+			return syslogPartial(i, err)
+		}
+		if s.cfg.Network == "tcp" {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = s.conn.SetWriteDeadline(deadline)
+		} else {
+			_ = s.conn.SetWriteDeadline(time.Now().Add(syslogDialTimeout))
+		}
+		if _, err := s.conn.Write([]byte(message)); err != nil {
+			// Reconnect on the next attempt. A message cut off by the failure is
+			// dropped by the receiver with the connection, so it is sent again.
+			s.conn.Close()
+			s.conn = nil
+			return syslogPartial(i, err)
+		}
+	}
+	return nil
+}
+
+// syslogPartial reports the records written before a failure, if any.
+func syslogPartial(written int, err error) error {
+	if written == 0 {
+		return err
+	}
+	return &partialWriteError{written: written, err: err}
+}
+
+func (s *syslogSink) format(record Record) (string, error) {
+	body, err := json.Marshal(record.Fields)
+	if err != nil {
+		return "", err
+	}
+	priority := syslogPriorityInfo
+	if success, ok := record.Fields["is_success"].(bool); ok && !success {
+		priority = syslogPriorityWarning
+	}
+	timestamp := record.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	return fmt.Sprintf("<%d>1 %s %s %s %d request_log - %s\n",
+		priority, timestamp.UTC().Format(time.RFC3339Nano), s.hostname,
+		strings.ReplaceAll(s.cfg.Tag, " ", "_"), os.Getpid(), body), nil
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
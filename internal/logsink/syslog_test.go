@@ -0,0 +1,47 @@
+package logsink
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingConn accepts a fixed number of writes and then fails.
+type failingConn struct {
+	net.Conn
+	writes int
+	sent   [][]byte
+}
+
+func (c *failingConn) Write(b []byte) (int, error) {
+	if len(c.sent) == c.writes {
+		return 0, errors.New("broken pipe")
+	}
+	c.sent = append(c.sent, b)
+	return len(b), nil
+}
+
+func (c *failingConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (c *failingConn) Close() error { return nil }
+
+func TestSyslogSinkReportsWrittenRecords(t *testing.T) {
+	t.Parallel()
+
+	conn := &failingConn{writes: 2}
+	sink := newSyslogSink(Config{Network: "tcp", Tag: "gpt-load"})
+	sink.conn = conn
+
+	records := []Record{{Fields: map[string]any{"n": 1}}, {Fields: map[string]any{"n": 2}}, {Fields: map[string]any{"n": 3}}}
+	err := sink.Write(context.Background(), records)
+	var partial *partialWriteError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, 2, partial.written)
+	assert.Len(t, conn.sent, 2)
+	assert.Nil(t, sink.conn, "the connection is dropped after a failed write")
+}
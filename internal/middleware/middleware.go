@@ -235,6 +235,10 @@ func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
 	}
 }
 
+// ProxyKeyContextKey is the gin context key under which ProxyAuth stores the
+// authenticated proxy key.
+const ProxyKeyContextKey = "proxy_key"
+
 // ProxyAuth validates proxy authentication and logs failed attempts
 func ProxyAuth(gm *services.GroupManager, requestLogService *services.RequestLogService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -273,6 +277,8 @@ func ProxyAuth(gm *services.GroupManager, requestLogService *services.RequestLog
 		_, existsInGroup := group.ProxyKeysMap[key]
 
 		if existsInEffective || existsInGroup {
+			// Session affinity can bind by proxy key, so expose the authenticated key to handlers.
+			c.Set(ProxyKeyContextKey, key)
 			c.Next()
 			return
 		}
@@ -309,12 +315,21 @@ func Recovery() gin.HandlerFunc {
 	})
 }
 
-// RateLimiter creates a simple rate limiting middleware
-func RateLimiter(config types.PerformanceConfig) gin.HandlerFunc {
+// RateLimiter creates a simple rate limiting middleware.
+// Requests to exemptPaths, such as long-lived SSE streams, do not take a slot.
+func RateLimiter(config types.PerformanceConfig, exemptPaths ...string) gin.HandlerFunc {
 	// Simple semaphore-based rate limiting
 	semaphore := make(chan struct{}, config.MaxConcurrentRequests)
+	exempt := make(map[string]struct{}, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = struct{}{}
+	}
 
 	return func(c *gin.Context) {
+		if _, ok := exempt[c.Request.URL.Path]; ok {
+			c.Next()
+			return
+		}
 		select {
 		case semaphore <- struct{}{}:
 			defer func() { <-semaphore }()
@@ -350,7 +365,7 @@ func ErrorHandler() gin.HandlerFunc {
 
 // isMonitoringEndpoint checks if the path is a monitoring endpoint
 func isMonitoringEndpoint(path string) bool {
-	monitoringPaths := []string{"/health"}
+	monitoringPaths := []string{"/health", "/health/live", "/health/ready"}
 	for _, monitoringPath := range monitoringPaths {
 		if path == monitoringPath {
 			return true
@@ -212,6 +212,43 @@ func TestRateLimiter(t *testing.T) {
 	}
 }
 
+// TestRateLimiterExemptPaths tests that exempt paths do not take a slot
+func TestRateLimiterExemptPaths(t *testing.T) {
+	router := gin.New()
+	router.Use(RateLimiter(types.PerformanceConfig{MaxConcurrentRequests: 1}, "/api/logs/stream"))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "OK")
+	})
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+	router.GET("/api/logs/stream", func(c *gin.Context) {
+		c.String(http.StatusOK, "OK")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	limited := httptest.NewRecorder()
+	router.ServeHTTP(limited, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.NotEqual(t, http.StatusOK, limited.Code)
+
+	stream := httptest.NewRecorder()
+	router.ServeHTTP(stream, httptest.NewRequest(http.MethodGet, "/api/logs/stream", nil))
+	assert.Equal(t, http.StatusOK, stream.Code)
+
+	close(release)
+	<-done
+}
+
 // TestIsMonitoringEndpoint tests monitoring endpoint detection
 func TestIsMonitoringEndpoint(t *testing.T) {
 	tests := []struct {
@@ -219,6 +256,8 @@ func TestIsMonitoringEndpoint(t *testing.T) {
 		expected bool
 	}{
 		{"/health", true},
+		{"/health/live", true},
+		{"/health/ready", true},
 		{"/api/test", false},
 		{"/", false},
 	}
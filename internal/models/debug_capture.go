@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// DebugCapture stores the full trace of one proxied request sent with a valid
+// debug token: the client request, every upstream attempt and the intermediate
+// conversion states. Captures expire shortly after they are taken.
+type DebugCapture struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	GroupName  string `gorm:"type:varchar(255);index" json:"group_name"`
+	Method     string `gorm:"type:varchar(16)" json:"method"`
+	Path       string `gorm:"type:varchar(500)" json:"path"`
+	StatusCode int    `json:"status_code"`
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"duration_ms"`
+	// Data holds the capture itself as JSON; it is omitted from list results.
+	Data      datatypes.JSON `gorm:"type:json" json:"data,omitempty"`
+	ExpiresAt time.Time      `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// TableName returns the table name for GORM.
+func (DebugCapture) TableName() string {
+	return "debug_captures"
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Group experiment states. A group has at most one running experiment.
+const (
+	ExperimentRunning  = "running"
+	ExperimentPromoted = "promoted"
+	ExperimentAborted  = "aborted"
+)
+
+// Experiment variants recorded on request logs.
+const (
+	ExperimentVariantControl   = "control"
+	ExperimentVariantCandidate = "candidate"
+)
+
+// GroupExperiment sends part of the traffic of a group to a candidate variant
+// of its configuration. The candidate is the group configuration with the
+// non-empty override fields applied; Config keys are merged into the group
+// config while the other fields replace the group value.
+type GroupExperiment struct {
+	ID      uint   `gorm:"primaryKey" json:"id"`
+	GroupID uint   `gorm:"not null;index" json:"group_id"`
+	Name    string `gorm:"type:varchar(255)" json:"name"`
+	Status  string `gorm:"type:varchar(16);not null;index" json:"status"`
+	// Percentage is the share of requests, 0-100, routed to the candidate.
+	Percentage int `gorm:"not null;default:0" json:"percentage"`
+	// ProxyKeys is a comma separated list of proxy keys whose requests always
+	// use the candidate.
+	ProxyKeys string `gorm:"type:text" json:"proxy_keys"`
+	// SessionAffinity keeps each client session on one variant. It is also
+	// implied when session affinity is enabled on the group.
+	SessionAffinity      bool              `gorm:"not null;default:false" json:"session_affinity"`
+	ParamOverrides       datatypes.JSONMap `gorm:"type:json" json:"param_overrides,omitempty"`
+	ModelRedirectRulesV2 datatypes.JSON    `gorm:"type:json" json:"model_redirect_rules_v2,omitempty"`
+	ModelRedirectStrict  *bool             `json:"model_redirect_strict,omitempty"`
+	Config               datatypes.JSONMap `gorm:"type:json" json:"config,omitempty"`
+	EndedAt              *time.Time        `json:"ended_at,omitempty"`
+	// PromotedRevision is the group revision created by promoting the candidate.
+	PromotedRevision int       `json:"promoted_revision,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// For cache
+	ProxyKeysMap map[string]struct{} `gorm:"-" json:"-"`
+}
+
+// TableName returns the table name for GORM.
+func (GroupExperiment) TableName() string {
+	return "group_experiments"
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// GroupRevision is a snapshot of the routing configuration of a group taken
+// whenever the group is changed, numbered per group starting at 1.
+type GroupRevision struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	GroupID  uint `gorm:"not null;uniqueIndex:idx_group_revision" json:"group_id"`
+	Revision int  `gorm:"not null;uniqueIndex:idx_group_revision" json:"revision"`
+	// Source tells what produced the revision: baseline, update, sub_group,
+	// rollback or schedule.
+	Source    string         `gorm:"type:varchar(32)" json:"source"`
+	Note      string         `gorm:"type:varchar(500)" json:"note"`
+	Snapshot  datatypes.JSON `gorm:"type:json" json:"snapshot,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// TableName returns the table name for GORM.
+func (GroupRevision) TableName() string {
+	return "group_revisions"
+}
+
+// Scheduled group change states. A change is applying while the node that
+// claimed it applies it; one left in that state by a crash is not retried.
+const (
+	ScheduledChangePending   = "pending"
+	ScheduledChangeApplying  = "applying"
+	ScheduledChangeApplied   = "applied"
+	ScheduledChangeFailed    = "failed"
+	ScheduledChangeCancelled = "cancelled"
+)
+
+// GroupScheduledChange is a group update that is applied at ApplyAt by the
+// master node.
+type GroupScheduledChange struct {
+	ID      uint      `gorm:"primaryKey" json:"id"`
+	GroupID uint      `gorm:"not null;index" json:"group_id"`
+	ApplyAt time.Time `gorm:"not null;index:idx_scheduled_change_due" json:"apply_at"`
+	Status  string    `gorm:"type:varchar(16);not null;index:idx_scheduled_change_due" json:"status"`
+	Note    string    `gorm:"type:varchar(500)" json:"note"`
+	// Changes holds the update payload in the format of the group update API.
+	Changes   datatypes.JSON `gorm:"type:json" json:"changes"`
+	Error     string         `gorm:"type:text" json:"error,omitempty"`
+	AppliedAt *time.Time     `json:"applied_at,omitempty"`
+	// Revision is the group revision created by applying the change.
+	Revision  int       `json:"revision,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for GORM.
+func (GroupScheduledChange) TableName() string {
+	return "group_scheduled_changes"
+}
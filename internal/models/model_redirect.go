@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"strings"
 )
 
 // ModelRedirectTarget represents a single redirect target with weight configuration.
@@ -16,7 +17,30 @@ type ModelRedirectTarget struct {
 // one-to-many mapping with weighted random selection.
 type ModelRedirectRuleV2 struct {
 	Targets  []ModelRedirectTarget `json:"targets"`            // List of target models
-	Fallback []string              `json:"fallback,omitempty"` // Fallback models (P1 extension)
+	Fallback []string              `json:"fallback,omitempty"` // Ordered models tried after the selected target fails
+}
+
+// FallbackModels returns the fallback chain in configured order.
+// Blank entries and duplicates are skipped so each fallback model is tried at most once.
+func (r *ModelRedirectRuleV2) FallbackModels() []string {
+	if r == nil || len(r.Fallback) == 0 {
+		return nil
+	}
+
+	result := make([]string, 0, len(r.Fallback))
+	seen := make(map[string]struct{}, len(r.Fallback))
+	for _, model := range r.Fallback {
+		model = strings.TrimSpace(model)
+		if model == "" {
+			continue
+		}
+		if _, exists := seen[model]; exists {
+			continue
+		}
+		seen[model] = struct{}{}
+		result = append(result, model)
+	}
+	return result
 }
 
 // IsEnabled returns whether the target is enabled.
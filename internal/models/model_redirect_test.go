@@ -92,6 +92,42 @@ func TestModelRedirectTarget_GetWeight(t *testing.T) {
 	}
 }
 
+// TestModelRedirectRuleV2_FallbackModels tests fallback chain normalization
+func TestModelRedirectRuleV2_FallbackModels(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     *ModelRedirectRuleV2
+		expected []string
+	}{
+		{
+			name:     "nil rule",
+			rule:     nil,
+			expected: nil,
+		},
+		{
+			name:     "no fallback",
+			rule:     &ModelRedirectRuleV2{Targets: []ModelRedirectTarget{{Model: "gpt-4o"}}},
+			expected: nil,
+		},
+		{
+			name:     "keeps configured order",
+			rule:     &ModelRedirectRuleV2{Fallback: []string{"gpt-4o-mini", "gpt-4.1"}},
+			expected: []string{"gpt-4o-mini", "gpt-4.1"},
+		},
+		{
+			name:     "skips blank and duplicate entries",
+			rule:     &ModelRedirectRuleV2{Fallback: []string{" gpt-4o-mini ", "", "gpt-4o-mini", "gpt-4.1"}},
+			expected: []string{"gpt-4o-mini", "gpt-4.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.FallbackModels())
+		})
+	}
+}
+
 // TestNewModelRedirectSelector tests selector creation
 func TestNewModelRedirectSelector(t *testing.T) {
 	t.Run("valid function", func(t *testing.T) {
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// SemanticCacheEntry stores a cached chat completion together with the embedding
+// of the prompt that produced it. Entries are matched by cosine similarity within
+// the same group and request parameters.
+type SemanticCacheEntry struct {
+	ID      uint `gorm:"primaryKey" json:"id"`
+	GroupID uint `gorm:"not null;index:idx_sce_group_expires" json:"group_id"`
+	// ParamsHash identifies everything in the request except the messages
+	// (model, tools, response format, sampling parameters); only entries with
+	// the same hash are compared.
+	ParamsHash string `gorm:"type:varchar(64);not null" json:"params_hash"`
+	// Embedding holds the normalized prompt embedding as little-endian float32 values.
+	Embedding []byte    `gorm:"not null" json:"-"`
+	Response  string    `gorm:"type:text;not null" json:"response"` // Upstream chat completion JSON
+	ExpiresAt time.Time `gorm:"not null;index:idx_sce_group_expires" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for GORM.
+func (SemanticCacheEntry) TableName() string {
+	return "semantic_cache_entries"
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/failover"
+	"gpt-load/internal/guardrail"
 	"gpt-load/internal/types"
 	"net/url"
 	"strings"
@@ -90,6 +91,7 @@ type GroupConfig struct {
 	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
 	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
 	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
+	LogRedactionRules            *string `json:"log_redaction_rules,omitempty"`
 	// ForceFunctionCall enables experimental function call middleware for this group.
 	// This flag is stored in the group-level config JSON and is optional.
 	ForceFunctionCall *bool `json:"force_function_call,omitempty"`
@@ -110,6 +112,53 @@ type GroupConfig struct {
 	CodexAffinityEnabled *bool `json:"codex_affinity_enabled,omitempty"`
 	// CodexAffinityMaxRetries is the total attempt limit, including the first request, for the affinity sub-group.
 	CodexAffinityMaxRetries *int `json:"codex_affinity_max_retries,omitempty"`
+	// SessionAffinityEnabled keeps requests from the same client session on a stable
+	// sub-group and/or key of an aggregate group for prompt-cache locality.
+	SessionAffinityEnabled *bool `json:"session_affinity_enabled,omitempty"`
+	// SessionAffinitySource selects where the session identifier is read from.
+	// Values: "auto" (built-in client fields), "header", "json_path", "proxy_key".
+	SessionAffinitySource *string `json:"session_affinity_source,omitempty"`
+	// SessionAffinityKey is the header name or JSON path used by the "header" and "json_path" sources.
+	SessionAffinityKey *string `json:"session_affinity_key,omitempty"`
+	// SessionAffinityScope selects what is pinned. Values: "sub_group" (default), "key", "both".
+	SessionAffinityScope *string `json:"session_affinity_scope,omitempty"`
+	// SessionAffinityTTLSeconds is how long a binding lives after its last successful request.
+	SessionAffinityTTLSeconds *int `json:"session_affinity_ttl_seconds,omitempty"`
+	// SessionAffinityMaxEntries caps the number of live bindings kept for the group.
+	SessionAffinityMaxEntries *int `json:"session_affinity_max_entries,omitempty"`
+	// ResponseCacheEnabled serves repeated non-streaming deterministic requests from the response cache.
+	// Text generation is only cached with temperature 0, a single choice and no nucleus sampling.
+	ResponseCacheEnabled *bool `json:"response_cache_enabled,omitempty"`
+	// ResponseCacheTTLSeconds is how long a cached response is served.
+	ResponseCacheTTLSeconds *int `json:"response_cache_ttl_seconds,omitempty"`
+	// ResponseCacheMaxEntries caps the number of cached responses kept for the group.
+	ResponseCacheMaxEntries *int `json:"response_cache_max_entries,omitempty"`
+	// SemanticCacheEnabled serves chat completions whose prompt embedding is similar
+	// to a cached one. Only applies to standard OpenAI channel groups.
+	SemanticCacheEnabled *bool `json:"semantic_cache_enabled,omitempty"`
+	// SemanticCacheEmbeddingGroup is the standard OpenAI group on this gateway used to embed prompts.
+	SemanticCacheEmbeddingGroup *string `json:"semantic_cache_embedding_group,omitempty"`
+	// SemanticCacheEmbeddingModel is the embedding model requested from the embedding group.
+	SemanticCacheEmbeddingModel *string `json:"semantic_cache_embedding_model,omitempty"`
+	// SemanticCacheThreshold is the minimum cosine similarity for a cache hit (0, 1].
+	SemanticCacheThreshold *float64 `json:"semantic_cache_threshold,omitempty"`
+	// SemanticCacheTTLSeconds is how long a cached completion is served.
+	SemanticCacheTTLSeconds *int `json:"semantic_cache_ttl_seconds,omitempty"`
+	// GuardrailPolicy holds content policy checks applied before forwarding and,
+	// when check_response is set, to completed non-streaming responses.
+	GuardrailPolicy *guardrail.Config `json:"guardrail_policy,omitempty"`
+	// HedgeAfterMs fires a second non-streaming attempt on another key of the same group
+	// when no response headers arrived within this delay. For aggregate groups the hedge
+	// stays in the selected sub-group; sub-groups are not raced against each other.
+	// Zero or nil disables request hedging.
+	HedgeAfterMs *int `json:"hedge_after_ms,omitempty"`
+	// ShadowGroup is a standard group with the same channel type that receives a copy
+	// of sampled requests. Shadow responses are logged and discarded.
+	ShadowGroup *string `json:"shadow_group,omitempty"`
+	// ShadowSamplePercent is the share of requests mirrored to the shadow group (1-100, default 100).
+	ShadowSamplePercent *int `json:"shadow_sample_percent,omitempty"`
+	// ShadowLogResponseBody stores the shadow response body on the shadow request log.
+	ShadowLogResponseBody *bool `json:"shadow_log_response_body,omitempty"`
 	// CodexDegradationMitigationEnabled folds truncated Codex Responses reasoning rounds into one SSE response.
 	// Only applies to OpenAI Responses (openai-response) channel groups.
 	CodexDegradationMitigationEnabled *bool `json:"codex_degradation_mitigation_enabled,omitempty"`
@@ -269,6 +318,8 @@ type Group struct {
 	ModelRedirectMapV2        map[string]*ModelRedirectRuleV2 `gorm:"-" json:"-"` // Parsed V2 rules (one-to-many)
 	PathRedirectRuleList      []PathRedirectRule              `gorm:"-" json:"-"` // Parsed path redirect rules (OpenAI)
 	FailoverStatusCodeMatcher failover.StatusCodeMatcher      `gorm:"-" json:"-"`
+	Experiment                *GroupExperiment                `gorm:"-" json:"-"` // Running experiment of the group
+	ExperimentCandidate       *Group                          `gorm:"-" json:"-"` // Group with the experiment overrides applied
 }
 
 // APIKey corresponds to the api_keys table.
@@ -284,6 +335,19 @@ type APIKey struct {
 	LastUsedAt   *time.Time `json:"last_used_at"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// KeyRef is the secret reference KeyValue was resolved from, such as
+	// env:OPENAI_KEY. Logs record the reference, never the resolved value.
+	KeyRef string `gorm:"-" json:"-"`
+}
+
+// StoredValue returns the key as stored in the database: the secret
+// reference for resolved keys, otherwise the key value.
+func (k *APIKey) StoredValue() string {
+	if k.KeyRef != "" {
+		return k.KeyRef
+	}
+	return k.KeyValue
 }
 
 // RequestType request type constants
@@ -291,6 +355,10 @@ const (
 	RequestTypeRetry      = "retry"
 	RequestTypeFinal      = "final"
 	RequestTypeValidation = "validation"
+	RequestTypeHedge      = "hedge"
+	RequestTypeCacheHit   = "cache_hit"
+	RequestTypeGuardrail  = "guardrail"
+	RequestTypeShadow     = "shadow"
 )
 
 // Token usage source constants.
@@ -300,6 +368,15 @@ const (
 	TokenUsageSourceEstimated = "estimated"
 )
 
+// Full-text search objects created by migration v1.30.0 when LOG_SEARCH_INDEX is enabled.
+const (
+	RequestLogSearchTable = "request_logs_fts"
+	RequestLogSearchIndex = "idx_request_logs_search"
+	// RequestLogSearchVector is both indexed and queried; PostgreSQL only uses
+	// the GIN index when the two expressions are identical.
+	RequestLogSearchVector = "to_tsvector('simple', coalesce(request_body, '') || ' ' || coalesce(response_body, '') || ' ' || coalesce(error_message, ''))"
+)
+
 // RequestLog corresponds to the request_logs table.
 type RequestLog struct {
 	ID                     string    `gorm:"type:varchar(36);primaryKey" json:"id"`
@@ -316,6 +393,7 @@ type RequestLog struct {
 	SourceIP               string    `gorm:"type:varchar(64)" json:"source_ip"`
 	StatusCode             int       `gorm:"not null" json:"status_code"`
 	RequestPath            string    `gorm:"type:varchar(500)" json:"request_path"`
+	RequestMethod          string    `gorm:"type:varchar(16);not null;default:''" json:"request_method"` // empty for logs written before it was recorded
 	Duration               int64     `gorm:"not null" json:"duration_ms"`
 	ErrorMessage           string    `gorm:"type:text" json:"error_message"`
 	UserAgent              string    `gorm:"type:varchar(512)" json:"user_agent"`
@@ -333,6 +411,8 @@ type RequestLog struct {
 	CacheWriteTokens       int64     `gorm:"not null;default:0" json:"cache_write_tokens"`
 	ThinkingTokens         int64     `gorm:"not null;default:0" json:"thinking_tokens"`
 	TokenUsageSource       string    `gorm:"type:varchar(20);not null;default:''" json:"token_usage_source"` // zero value is TokenUsageSourceUnknown
+	ExperimentID           uint      `gorm:"not null;default:0;index" json:"experiment_id,omitempty"`
+	ExperimentVariant      string    `gorm:"type:varchar(16);not null;default:''" json:"experiment_variant,omitempty"` // control or candidate
 }
 
 // StatCard represents a single statistics card data for the dashboard.
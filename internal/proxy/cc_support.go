@@ -2511,6 +2511,10 @@ func parseFunctionCallsFromContentForCC(c *gin.Context, content string) (string,
 
 // handleCCNormalResponse handles non-streaming response conversion for CC support.
 func (ps *ProxyServer) handleCCNormalResponse(c *gin.Context, resp *http.Response) {
+	if ps.blockGuardrailResponse(c, resp) {
+		return
+	}
+
 	bodyBytes, err := readAllWithLimit(resp.Body, maxUpstreamResponseBodySize)
 	if err != nil {
 		if errors.Is(err, ErrBodyTooLarge) {
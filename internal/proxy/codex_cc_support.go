@@ -1652,6 +1652,10 @@ func (s *codexStreamState) processCodexStreamEvent(event *CodexStreamEvent) []Cl
 
 // handleCodexCCNormalResponse handles non-streaming Codex response conversion to Claude format.
 func (ps *ProxyServer) handleCodexCCNormalResponse(c *gin.Context, resp *http.Response) {
+	if ps.blockGuardrailResponse(c, resp) {
+		return
+	}
+
 	bodyBytes, err := readAllWithLimit(resp.Body, maxUpstreamResponseBodySize)
 	if err != nil {
 		if errors.Is(err, ErrBodyTooLarge) {
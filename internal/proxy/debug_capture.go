@@ -0,0 +1,291 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DebugCaptureHeader carries a server-issued debug token. Requests with a
+	// valid token are captured in full; the header is never sent upstream.
+	DebugCaptureHeader = "X-GPTLoad-Debug"
+
+	// maxDebugCaptureBodyBytes caps every captured body after decompression.
+	maxDebugCaptureBodyBytes = 64 * 1024
+)
+
+// debugCaptureKey marks a request that is being captured.
+type debugCaptureKey struct{}
+
+// DebugCaptureData is the stored trace of a captured request. Offsets and
+// durations are in milliseconds since the proxy received the request.
+type DebugCaptureData struct {
+	Request  DebugCaptureRequest    `json:"request"`
+	Stages   []DebugCaptureStage    `json:"stages"`
+	Attempts []*DebugCaptureAttempt `json:"attempts"`
+	Response DebugCaptureResponse   `json:"response"`
+}
+
+// DebugCaptureRequest is the client request as received.
+type DebugCaptureRequest struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// DebugCaptureStage is the request after one conversion stage, as reported by
+// the explain endpoint.
+type DebugCaptureStage struct {
+	Name      string `json:"name"`
+	Group     string `json:"group"`
+	Path      string `json:"path"`
+	Body      string `json:"body,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// DebugCaptureAttempt is one request sent upstream and what came back.
+// DurationMs measures the time until response headers arrived.
+type DebugCaptureAttempt struct {
+	Group             string            `json:"group"`
+	Method            string            `json:"method"`
+	URL               string            `json:"url"`
+	RequestHeaders    map[string]string `json:"request_headers"`
+	RequestBody       string            `json:"request_body,omitempty"`
+	RequestTruncated  bool              `json:"request_truncated,omitempty"`
+	StartedMs         int64             `json:"started_ms"`
+	DurationMs        int64             `json:"duration_ms"`
+	StatusCode        int               `json:"status_code,omitempty"`
+	ResponseHeaders   map[string]string `json:"response_headers,omitempty"`
+	ResponseBody      string            `json:"response_body,omitempty"`
+	ResponseTruncated bool              `json:"response_truncated,omitempty"`
+	Error             string            `json:"error,omitempty"`
+
+	body *debugBodyRecorder
+}
+
+// DebugCaptureResponse is the response returned to the client.
+type DebugCaptureResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+// debugCapture collects the trace of one request.
+type debugCapture struct {
+	mu    sync.Mutex
+	start time.Time
+	data  DebugCaptureData
+}
+
+// SetDebugCaptureService enables per-request debug capture through DebugCaptureHeader.
+func (ps *ProxyServer) SetDebugCaptureService(svc *services.DebugCaptureService) {
+	ps.debugCapture = svc
+}
+
+// startDebugCapture begins capturing the request when it carries a valid
+// debug token. The debug header is always stripped so it never reaches upstream.
+func (ps *ProxyServer) startDebugCapture(c *gin.Context, body []byte, startTime time.Time) *debugCapture {
+	token := strings.TrimSpace(c.GetHeader(DebugCaptureHeader))
+	if token == "" {
+		return nil
+	}
+	c.Request.Header.Del(DebugCaptureHeader)
+	if ps.debugCapture == nil || !ps.debugCapture.ValidateToken(token) {
+		logrus.WithField("group", c.Param("group_name")).Debug("Ignoring request with invalid debug token")
+		return nil
+	}
+
+	requestBody, truncated := truncateDebugBody(body)
+	capture := &debugCapture{
+		start: startTime,
+		data: DebugCaptureData{
+			Request: DebugCaptureRequest{
+				Method:    c.Request.Method,
+				URL:       utils.SanitizeURLForLog(c.Request.URL),
+				Headers:   maskExplainHeaders(c.Request.Header, ""),
+				Body:      requestBody,
+				Truncated: truncated,
+			},
+		},
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), debugCaptureKey{}, capture))
+	return capture
+}
+
+func debugCaptureFrom(c *gin.Context) *debugCapture {
+	capture, _ := c.Request.Context().Value(debugCaptureKey{}).(*debugCapture)
+	return capture
+}
+
+func (d *debugCapture) elapsedMs() int64 {
+	return time.Since(d.start).Milliseconds()
+}
+
+func (d *debugCapture) recordStage(name, group, path string, body []byte) {
+	stageBody, truncated := truncateDebugBody(body)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.data.Stages = append(d.data.Stages, DebugCaptureStage{
+		Name:      name,
+		Group:     group,
+		Path:      path,
+		Body:      stageBody,
+		Truncated: truncated,
+		ElapsedMs: d.elapsedMs(),
+	})
+}
+
+// recordDebugAttempt records an upstream attempt of a captured request. The
+// response body is captured as the proxy reads it. It is a no-op for requests
+// that are not captured.
+func recordDebugAttempt(c *gin.Context, group *models.Group, req *http.Request, body []byte, apiKey *models.APIKey, resp *http.Response, err error, started time.Time) {
+	capture := debugCaptureFrom(c)
+	if capture == nil {
+		return
+	}
+	var keyValue string
+	if apiKey != nil {
+		keyValue = apiKey.KeyValue
+	}
+	upstreamURL := utils.SanitizeURLForLog(req.URL)
+	if keyValue != "" {
+		upstreamURL = strings.ReplaceAll(upstreamURL, keyValue, maskExplainSecret(keyValue))
+	}
+	requestBody, truncated := truncateDebugBody(body)
+	attempt := &DebugCaptureAttempt{
+		Group:            group.Name,
+		Method:           req.Method,
+		URL:              upstreamURL,
+		RequestHeaders:   maskExplainHeaders(req.Header, keyValue),
+		RequestBody:      requestBody,
+		RequestTruncated: truncated,
+		StartedMs:        started.Sub(capture.start).Milliseconds(),
+		DurationMs:       time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		attempt.Error = sanitizeInternalErrorMessage(err.Error())
+	}
+	if resp != nil {
+		attempt.StatusCode = resp.StatusCode
+		attempt.ResponseHeaders = maskExplainHeaders(resp.Header, keyValue)
+		if resp.Body != nil {
+			attempt.body = &debugBodyRecorder{ReadCloser: resp.Body}
+			resp.Body = attempt.body
+		}
+	}
+
+	capture.mu.Lock()
+	capture.data.Attempts = append(capture.data.Attempts, attempt)
+	capture.mu.Unlock()
+}
+
+// saveDebugCapture stores the capture once the response has been written.
+// Bodies are redacted with the group's log redaction rules like request logs.
+func (ps *ProxyServer) saveDebugCapture(c *gin.Context, group *models.Group, capture *debugCapture) {
+	capture.mu.Lock()
+	data := capture.data
+	capture.mu.Unlock()
+
+	redactor := logRedactor(group)
+	data.Request.Body = redactor.Redact(data.Request.Body)
+	for i := range data.Stages {
+		data.Stages[i].Body = redactor.Redact(data.Stages[i].Body)
+	}
+	for _, attempt := range data.Attempts {
+		attempt.RequestBody = redactor.Redact(attempt.RequestBody)
+		if attempt.body == nil {
+			continue
+		}
+		body, truncated := attempt.body.snapshot()
+		body = decompressDebugBody(attempt.ResponseHeaders["Content-Encoding"], body)
+		attempt.ResponseBody, attempt.ResponseTruncated = truncateDebugBody(body)
+		attempt.ResponseBody = redactor.Redact(attempt.ResponseBody)
+		attempt.ResponseTruncated = attempt.ResponseTruncated || truncated
+	}
+	data.Response = DebugCaptureResponse{
+		StatusCode: c.Writer.Status(),
+		Headers:    maskExplainHeaders(c.Writer.Header(), ""),
+		DurationMs: capture.elapsedMs(),
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to encode debug capture")
+		return
+	}
+	record := &models.DebugCapture{
+		GroupName:  group.Name,
+		Method:     data.Request.Method,
+		Path:       utils.TruncateString(data.Request.URL, 500),
+		StatusCode: data.Response.StatusCode,
+		Attempts:   len(data.Attempts),
+		DurationMs: data.Response.DurationMs,
+		Data:       payload,
+	}
+	if err := ps.debugCapture.Save(record); err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to save debug capture")
+		return
+	}
+	logrus.WithFields(logrus.Fields{"group": group.Name, "capture_id": record.ID}).Info("Saved debug capture")
+}
+
+// debugBodyRecorder keeps the first bytes read from an upstream response body.
+type debugBodyRecorder struct {
+	io.ReadCloser
+	mu        sync.Mutex
+	buf       []byte
+	truncated bool
+}
+
+func (r *debugBodyRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.mu.Lock()
+		if room := maxDebugCaptureBodyBytes - len(r.buf); room >= n {
+			r.buf = append(r.buf, p[:n]...)
+		} else {
+			r.buf = append(r.buf, p[:max(room, 0)]...)
+			r.truncated = true
+		}
+		r.mu.Unlock()
+	}
+	return n, err
+}
+
+func (r *debugBodyRecorder) snapshot() ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.buf...), r.truncated
+}
+
+// decompressDebugBody decodes a captured body; a body truncated mid-stream may
+// not decode, in which case the raw bytes are kept.
+func decompressDebugBody(contentEncoding string, body []byte) []byte {
+	decoded, err := utils.DecompressResponseWithLimit(contentEncoding, body, maxDebugCaptureBodyBytes*4)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+func truncateDebugBody(body []byte) (string, bool) {
+	if len(body) <= maxDebugCaptureBodyBytes {
+		return strings.ToValidUTF8(string(body), "�"), false
+	}
+	return strings.ToValidUTF8(string(body[:maxDebugCaptureBodyBytes]), "�"), true
+}
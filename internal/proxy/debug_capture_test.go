@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugRecorderCapsBody(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("x", maxDebugCaptureBodyBytes+10)
+	recorder := &debugBodyRecorder{ReadCloser: io.NopCloser(strings.NewReader(body))}
+	var out bytes.Buffer
+	_, err := out.ReadFrom(recorder)
+	require.NoError(t, err)
+
+	captured, truncated := recorder.snapshot()
+	assert.Equal(t, body, out.String(), "the proxy still reads the whole body")
+	assert.Len(t, captured, maxDebugCaptureBodyBytes)
+	assert.True(t, truncated)
+}
+
+func TestDebugCaptureRecordsRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.DebugCapture{}))
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+	debugSvc := services.NewDebugCaptureService(db, memStore)
+	ps.SetDebugCaptureService(debugSvc)
+
+	var upstreamCalls atomic.Int32
+	var forwardedDebugHeader atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(DebugCaptureHeader) != "" {
+			forwardedDebugHeader.Store(true)
+		}
+		if upstreamCalls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"message":"boom"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(`{"choices":[{"message":{"content":"hello"}}]}`))
+		_ = gz.Close()
+	}))
+	t.Cleanup(upstream.Close)
+
+	group := createTestGroup(t, db, "debug", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.ParamOverrides = map[string]any{"temperature": 0.2}
+	group.Config = map[string]any{"log_redaction_rules": `{"detectors":["email"]}`}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-debug-1111111111", ps.encryptionSvc)
+	createTestKey(t, db, group.ID, "sk-debug-2222222222", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	send := func(token string) *replayResponseWriter {
+		req := httptest.NewRequest(http.MethodPost, "/proxy/debug/v1/chat/completions",
+			strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi from jane@example.com"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set(DebugCaptureHeader, token)
+		return ps.serveProxyRequest(req)
+	}
+
+	t.Run("invalid tokens are ignored", func(t *testing.T) {
+		upstreamCalls.Store(1)
+		send("gld-invalid")
+		assert.False(t, forwardedDebugHeader.Load())
+
+		captures, err := debugSvc.List("")
+		require.NoError(t, err)
+		assert.Empty(t, captures)
+	})
+
+	t.Run("valid tokens capture every attempt", func(t *testing.T) {
+		upstreamCalls.Store(0)
+		token, _, err := debugSvc.IssueToken(0)
+		require.NoError(t, err)
+		send(token)
+		assert.False(t, forwardedDebugHeader.Load())
+
+		captures, err := debugSvc.List("debug")
+		require.NoError(t, err)
+		require.Len(t, captures, 1)
+		assert.Equal(t, 2, captures[0].Attempts)
+		assert.Equal(t, http.StatusOK, captures[0].StatusCode)
+
+		capture, err := debugSvc.Get(captures[0].ID)
+		require.NoError(t, err)
+		var data DebugCaptureData
+		require.NoError(t, json.Unmarshal(capture.Data, &data))
+
+		assert.Contains(t, data.Request.Body, `"content":"hi from `)
+		assert.NotContains(t, string(capture.Data), "jane@example.com", "bodies are redacted like request logs")
+		assert.NotContains(t, data.Request.Headers, DebugCaptureHeader)
+
+		var stageNames []string
+		for _, stage := range data.Stages {
+			stageNames = append(stageNames, stage.Name)
+		}
+		assert.Contains(t, stageNames, ExplainStageParamOverrides)
+
+		require.Len(t, data.Attempts, 2)
+		assert.Equal(t, http.StatusInternalServerError, data.Attempts[0].StatusCode)
+		assert.Contains(t, data.Attempts[0].ResponseBody, "boom")
+		assert.Contains(t, data.Attempts[0].RequestBody, `"temperature":0.2`)
+		assert.Contains(t, data.Attempts[0].RequestHeaders["Authorization"], "****")
+		assert.Equal(t, http.StatusOK, data.Attempts[1].StatusCode)
+		assert.Equal(t, `{"choices":[{"message":{"content":"hello"}}]}`, data.Attempts[1].ResponseBody)
+		assert.Equal(t, http.StatusOK, data.Response.StatusCode)
+	})
+}
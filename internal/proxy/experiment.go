@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ctxKeyExperimentAssignment stores the experiment variant chosen for the request.
+const ctxKeyExperimentAssignment = "experiment_assignment"
+
+// experimentAssignment is the experiment variant serving a request.
+type experimentAssignment struct {
+	experimentID uint
+	variant      string
+}
+
+// selectExperimentVariant returns the variant of the group that serves the
+// request and records the assignment for the request log. Groups without a
+// running experiment are returned unchanged.
+func selectExperimentVariant(c *gin.Context, group *models.Group, bodyBytes []byte) *models.Group {
+	experiment := group.Experiment
+	if experiment == nil || group.ExperimentCandidate == nil {
+		return group
+	}
+
+	assignment := experimentAssignment{experimentID: experiment.ID, variant: models.ExperimentVariantControl}
+	selected := group
+	if experimentUsesCandidate(c, group, experiment, bodyBytes) {
+		assignment.variant = models.ExperimentVariantCandidate
+		selected = group.ExperimentCandidate
+	}
+	c.Set(ctxKeyExperimentAssignment, assignment)
+	return selected
+}
+
+// experimentUsesCandidate reports whether the request goes to the candidate.
+// Listed proxy keys always do; other requests are split by percentage, by a
+// hash of the session identifier when session affinity applies so that a
+// session stays on one variant.
+func experimentUsesCandidate(c *gin.Context, group *models.Group, experiment *models.GroupExperiment, bodyBytes []byte) bool {
+	if proxyKey := c.GetString(middleware.ProxyKeyContextKey); proxyKey != "" {
+		if _, ok := experiment.ProxyKeysMap[proxyKey]; ok {
+			return true
+		}
+	}
+	if experiment.Percentage <= 0 {
+		return false
+	}
+	if experiment.Percentage >= 100 {
+		return true
+	}
+	if identifier := experimentSessionIdentifier(c, group, experiment, bodyBytes); identifier != "" {
+		return experimentBucket(experiment.ID, identifier) < experiment.Percentage
+	}
+	return rand.IntN(100) < experiment.Percentage
+}
+
+// experimentSessionIdentifier returns the client session identifier when the
+// group or the experiment enables session affinity. The group session affinity
+// settings select the identifier source; otherwise the built-in client fields
+// are used.
+func experimentSessionIdentifier(c *gin.Context, group *models.Group, experiment *models.GroupExperiment, bodyBytes []byte) string {
+	cfg, ok := parseSessionAffinityConfig(group)
+	if !ok {
+		if !experiment.SessionAffinity {
+			return ""
+		}
+		cfg = &sessionAffinityConfig{source: sessionAffinitySourceAuto}
+	}
+	return sessionAffinityIdentifier(c, cfg, bodyBytes)
+}
+
+// experimentBucket maps a session to a stable bucket in [0, 100). The
+// experiment ID is part of the hash so sessions are reshuffled per experiment.
+func experimentBucket(experimentID uint, identifier string) int {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.FormatUint(uint64(experimentID), 10)))
+	h.Write([]byte{':'})
+	h.Write([]byte(identifier))
+	return int(h.Sum32() % 100)
+}
+
+// applyExperimentAssignment copies the experiment variant of the request onto
+// its log entry.
+func applyExperimentAssignment(c *gin.Context, logEntry *models.RequestLog) {
+	value, exists := c.Get(ctxKeyExperimentAssignment)
+	if !exists {
+		return
+	}
+	if assignment, ok := value.(experimentAssignment); ok {
+		logEntry.ExperimentID = assignment.experimentID
+		logEntry.ExperimentVariant = assignment.variant
+	}
+}
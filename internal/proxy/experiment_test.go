@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newExperimentTestGroup(experiment *models.GroupExperiment) *models.Group {
+	group := &models.Group{ID: 1, Name: "canary", GroupType: "standard"}
+	candidate := *group
+	candidate.ParamOverrides = map[string]any{"temperature": 0.2}
+	group.Experiment = experiment
+	group.ExperimentCandidate = &candidate
+	return group
+}
+
+func newExperimentTestContext(proxyKey string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/canary/v1/chat/completions", nil)
+	if proxyKey != "" {
+		c.Set(middleware.ProxyKeyContextKey, proxyKey)
+	}
+	return c
+}
+
+func TestSelectExperimentVariant(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no experiment", func(t *testing.T) {
+		t.Parallel()
+		group := &models.Group{ID: 1, Name: "plain"}
+		c := newExperimentTestContext("")
+		assert.Same(t, group, selectExperimentVariant(c, group, nil))
+
+		logEntry := &models.RequestLog{}
+		applyExperimentAssignment(c, logEntry)
+		assert.Zero(t, logEntry.ExperimentID)
+		assert.Empty(t, logEntry.ExperimentVariant)
+	})
+
+	t.Run("proxy keys always use the candidate", func(t *testing.T) {
+		t.Parallel()
+		group := newExperimentTestGroup(&models.GroupExperiment{
+			ID:           7,
+			ProxyKeysMap: map[string]struct{}{"sk-canary": {}},
+		})
+
+		c := newExperimentTestContext("sk-canary")
+		assert.Same(t, group.ExperimentCandidate, selectExperimentVariant(c, group, nil))
+		logEntry := &models.RequestLog{}
+		applyExperimentAssignment(c, logEntry)
+		assert.Equal(t, uint(7), logEntry.ExperimentID)
+		assert.Equal(t, models.ExperimentVariantCandidate, logEntry.ExperimentVariant)
+
+		c = newExperimentTestContext("sk-other")
+		assert.Same(t, group, selectExperimentVariant(c, group, nil))
+		logEntry = &models.RequestLog{}
+		applyExperimentAssignment(c, logEntry)
+		assert.Equal(t, models.ExperimentVariantControl, logEntry.ExperimentVariant)
+	})
+
+	t.Run("session affinity is deterministic", func(t *testing.T) {
+		t.Parallel()
+		group := newExperimentTestGroup(&models.GroupExperiment{ID: 3, Percentage: 50, SessionAffinity: true})
+
+		candidates := 0
+		for i := range 200 {
+			body := []byte(fmt.Sprintf(`{"prompt_cache_key":"session-%d"}`, i))
+			first := selectExperimentVariant(newExperimentTestContext(""), group, body)
+			for range 3 {
+				assert.Same(t, first, selectExperimentVariant(newExperimentTestContext(""), group, body))
+			}
+			if first == group.ExperimentCandidate {
+				candidates++
+			}
+		}
+		assert.InDelta(t, 100, candidates, 40, "about half of the sessions use the candidate")
+	})
+
+	t.Run("group session affinity settings select the identifier", func(t *testing.T) {
+		t.Parallel()
+		group := newExperimentTestGroup(&models.GroupExperiment{ID: 4, Percentage: 50})
+		group.GroupType = "aggregate"
+		group.Config = map[string]any{
+			"session_affinity_enabled": true,
+			"session_affinity_source":  "header",
+			"session_affinity_key":     "X-Session",
+		}
+
+		for i := range 20 {
+			session := fmt.Sprintf("s-%d", i)
+			c := newExperimentTestContext("")
+			c.Request.Header.Set("X-Session", session)
+			expected := experimentBucket(4, session) < 50
+			selected := selectExperimentVariant(c, group, nil)
+			assert.Equal(t, expected, selected == group.ExperimentCandidate, session)
+		}
+	})
+
+	t.Run("percentage bounds", func(t *testing.T) {
+		t.Parallel()
+		all := newExperimentTestGroup(&models.GroupExperiment{ID: 5, Percentage: 100})
+		none := newExperimentTestGroup(&models.GroupExperiment{ID: 6, Percentage: 0})
+		for range 20 {
+			assert.Same(t, all.ExperimentCandidate, selectExperimentVariant(newExperimentTestContext(""), all, nil))
+			assert.Same(t, none, selectExperimentVariant(newExperimentTestContext(""), none, nil))
+		}
+	})
+}
+
+func TestExperimentBucket(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, experimentBucket(1, "session"), experimentBucket(1, "session"))
+	differs := false
+	for i := range 20 {
+		id := fmt.Sprintf("session-%d", i)
+		bucket := experimentBucket(1, id)
+		assert.True(t, bucket >= 0 && bucket < 100)
+		if bucket != experimentBucket(2, id) {
+			differs = true
+		}
+	}
+	assert.True(t, differs, "buckets are reshuffled per experiment")
+}
@@ -0,0 +1,245 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrExplainInvalidMethod is returned for methods the proxy does not forward.
+var ErrExplainInvalidMethod = errors.New("unsupported request method")
+
+// Pipeline stage names reported by the explain endpoint, in pipeline order.
+const (
+	ExplainStageClientRequest       = "client_request"
+	ExplainStagePathRewrite         = "path_rewrite"
+	ExplainStageModelMapping        = "model_mapping"
+	ExplainStageParamOverrides      = "param_overrides"
+	ExplainStageCCConversion        = "cc_conversion"
+	ExplainStageCodexConversion     = "codex_conversion"
+	ExplainStageParallelToolCalls   = "parallel_tool_calls"
+	ExplainStageFunctionCallRewrite = "function_call_rewrite"
+	ExplainStageStreamOverride      = "stream_override"
+	ExplainStageResponsesInclude    = "responses_include"
+	ExplainStageStreamMode          = "stream_mode"
+	ExplainStageModelRedirect       = "model_redirect"
+)
+
+// explainTraceKey marks a request whose pipeline stages are recorded and
+// whose upstream request is captured instead of sent.
+type explainTraceKey struct{}
+
+// explainTrace collects the stages HandleProxy passes through.
+type explainTrace struct {
+	stages   []ExplainStage
+	upstream *ExplainUpstream
+}
+
+// ExplainOptions describes the client request to explain.
+type ExplainOptions struct {
+	// Method defaults to POST.
+	Method string `json:"method"`
+	// Path is the client path below /proxy/{group}, e.g. /v1/chat/completions.
+	Path string `json:"path"`
+	// Headers are sent as client request headers, e.g. anthropic-beta.
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// ExplainStage is the request as it looks after one pipeline stage. Group is
+// the sub-group for stages applied per aggregate attempt.
+type ExplainStage struct {
+	Name    string `json:"name"`
+	Group   string `json:"group"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Body    string `json:"body,omitempty"`
+	Changed bool   `json:"changed"`
+}
+
+// ExplainUpstream is the final request that would be sent upstream, with
+// credentials masked.
+type ExplainUpstream struct {
+	Group    string            `json:"group"`
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body,omitempty"`
+	IsStream bool              `json:"is_stream"`
+}
+
+// ExplainResult describes how the proxy pipeline transforms a client request.
+type ExplainResult struct {
+	GroupName string           `json:"group_name"`
+	Stages    []ExplainStage   `json:"stages"`
+	Upstream  *ExplainUpstream `json:"upstream,omitempty"`
+	// Response is set when the pipeline answered locally instead of reaching
+	// upstream, e.g. a guardrail block or a local count_tokens reply.
+	Response *ReplayResponse `json:"response,omitempty"`
+}
+
+// ExplainRequest runs a client request through the proxy pipeline of a group
+// and reports the request after each stage, stopping before anything is sent
+// upstream. Caches are bypassed so the trace always reaches the upstream stage.
+func (ps *ProxyServer) ExplainRequest(ctx context.Context, groupName string, opts ExplainOptions) (*ExplainResult, error) {
+	if _, err := ps.groupManager.GetGroupByName(groupName); err != nil {
+		return nil, ErrReplayGroupNotFound
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(opts.Method))
+	switch method {
+	case "":
+		method = http.MethodPost
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil, ErrExplainInvalidMethod
+	}
+
+	if strings.TrimSpace(opts.Path) == "" {
+		return nil, ErrReplayInvalidPath
+	}
+	subPath, rawQuery, err := replaySubPath("", opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.TrimSpace(opts.Body)
+	if len(body) > 0 && !json.Valid(body) {
+		return nil, ErrReplayInvalidBody
+	}
+
+	target := &url.URL{Path: "/proxy/" + groupName + subPath, RawQuery: rawQuery}
+	trace := &explainTrace{}
+	trace.record(ExplainStageClientRequest, groupName, method, utils.SanitizeURLForLog(target), body)
+
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, explainTraceKey{}, trace), method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "gpt-load-explain")
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+
+	writer := ps.serveProxyRequest(req)
+
+	result := &ExplainResult{GroupName: groupName, Stages: trace.stages, Upstream: trace.upstream}
+	if trace.upstream == nil {
+		result.Response = &ReplayResponse{StatusCode: writer.status, Body: writer.body.String(), Truncated: writer.truncated}
+	}
+	return result, nil
+}
+
+func (t *explainTrace) record(name, group, method, path string, body []byte) {
+	stage := ExplainStage{Name: name, Group: group, Method: method, Path: path, Body: string(body)}
+	if n := len(t.stages); n > 0 {
+		prev := t.stages[n-1]
+		stage.Changed = prev.Path != stage.Path || prev.Body != stage.Body
+	}
+	t.stages = append(t.stages, stage)
+}
+
+func explainTraceFrom(c *gin.Context) *explainTrace {
+	trace, _ := c.Request.Context().Value(explainTraceKey{}).(*explainTrace)
+	return trace
+}
+
+// isExplainRequest reports whether the request only traces the pipeline.
+func isExplainRequest(c *gin.Context) bool {
+	return explainTraceFrom(c) != nil
+}
+
+// traceExplainStage records the request path and body after a pipeline stage
+// for explain requests and debug captures. It is a no-op for other requests.
+func traceExplainStage(c *gin.Context, name string, group *models.Group, body []byte) {
+	if capture := debugCaptureFrom(c); capture != nil {
+		capture.recordStage(name, group.Name, utils.SanitizeURLForLog(c.Request.URL), body)
+	}
+	trace := explainTraceFrom(c)
+	if trace == nil {
+		return
+	}
+	trace.record(name, group.Name, c.Request.Method, utils.SanitizeURLForLog(c.Request.URL), body)
+}
+
+// captureExplainUpstream records the fully built upstream request of an
+// explain request. Returns true when the request must not be sent.
+func captureExplainUpstream(c *gin.Context, group *models.Group, req *http.Request, body []byte, isStream bool, apiKey *models.APIKey) bool {
+	trace := explainTraceFrom(c)
+	if trace == nil {
+		return false
+	}
+	var keyValue string
+	if apiKey != nil {
+		keyValue = apiKey.KeyValue
+	}
+	upstreamURL := utils.SanitizeURLForLog(req.URL)
+	if keyValue != "" {
+		upstreamURL = strings.ReplaceAll(upstreamURL, keyValue, maskExplainSecret(keyValue))
+	}
+	trace.upstream = &ExplainUpstream{
+		Group:    group.Name,
+		Method:   req.Method,
+		URL:      upstreamURL,
+		Headers:  maskExplainHeaders(req.Header, keyValue),
+		Body:     string(body),
+		IsStream: isStream,
+	}
+	c.Status(http.StatusNoContent)
+	return true
+}
+
+// maskExplainHeaders flattens headers for display, masking credentials and
+// any value that embeds the selected key (e.g. through header rules).
+func maskExplainHeaders(header http.Header, keyValue string) map[string]string {
+	masked := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		switch {
+		case isSensitiveExplainHeader(name):
+			value = maskExplainSecret(value)
+		case keyValue != "" && strings.Contains(value, keyValue):
+			value = strings.ReplaceAll(value, keyValue, maskExplainSecret(keyValue))
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+func isSensitiveExplainHeader(name string) bool {
+	lower := strings.ToLower(name)
+	for _, marker := range []string{"authorization", "api-key", "apikey", "token", "secret", "cookie"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskExplainSecret masks a secret, keeping an auth scheme such as "Bearer".
+func maskExplainSecret(value string) string {
+	scheme, secret, ok := strings.Cut(value, " ")
+	if !ok {
+		scheme, secret = "", value
+	} else {
+		scheme += " "
+	}
+	if len(secret) <= 8 {
+		return scheme + "****"
+	}
+	return scheme + utils.MaskAPIKey(secret)
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestMaskExplainHeaders(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer sk-1234567890abcdef")
+	header.Set("X-Goog-Api-Key", "short")
+	header.Set("X-Upstream-Key", "prefix-sk-1234567890abcdef")
+	header.Set("Anthropic-Version", "2023-06-01")
+
+	masked := maskExplainHeaders(header, "sk-1234567890abcdef")
+	assert.Equal(t, "Bearer sk-1****cdef", masked["Authorization"])
+	assert.Equal(t, "****", masked["X-Goog-Api-Key"])
+	assert.Equal(t, "prefix-sk-1****cdef", masked["X-Upstream-Key"])
+	assert.Equal(t, "2023-06-01", masked["Anthropic-Version"])
+}
+
+func TestExplainRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, _ := setupTestProxyServerWithStore(t, db)
+
+	var upstreamCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls.Add(1)
+	}))
+	t.Cleanup(upstream.Close)
+
+	group := createTestGroup(t, db, "explain", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.ParamOverrides = map[string]any{"temperature": 0.2}
+	group.Config = map[string]any{"cc_support": true}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-explain-1234567890", ps.encryptionSvc)
+	disabled := createTestGroup(t, db, "explain-off", "openai")
+	require.NoError(t, db.Model(disabled).Update("enabled", false).Error)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	stageNames := func(result *ExplainResult) []string {
+		names := make([]string, len(result.Stages))
+		for i, stage := range result.Stages {
+			names[i] = stage.Name
+		}
+		return names
+	}
+
+	t.Run("reports each stage and the upstream request", func(t *testing.T) {
+		result, err := ps.ExplainRequest(context.Background(), group.Name, ExplainOptions{
+			Path: "/v1/chat/completions",
+			Body: []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), upstreamCalls.Load())
+
+		names := stageNames(result)
+		assert.Equal(t, ExplainStageClientRequest, names[0])
+		assert.Contains(t, names, ExplainStageParamOverrides)
+		assert.Equal(t, ExplainStageModelRedirect, names[len(names)-1])
+		for _, stage := range result.Stages {
+			if stage.Name == ExplainStageParamOverrides {
+				assert.True(t, stage.Changed)
+				assert.Equal(t, 0.2, gjson.Get(stage.Body, "temperature").Float())
+			}
+		}
+
+		require.NotNil(t, result.Upstream)
+		assert.Nil(t, result.Response)
+		assert.Equal(t, http.MethodPost, result.Upstream.Method)
+		assert.Equal(t, upstream.URL+"/v1/chat/completions", result.Upstream.URL)
+		assert.Equal(t, "Bearer sk-e****7890", result.Upstream.Headers["Authorization"])
+		assert.Equal(t, 0.2, gjson.Get(result.Upstream.Body, "temperature").Float())
+	})
+
+	t.Run("shows CC conversion for Claude requests", func(t *testing.T) {
+		result, err := ps.ExplainRequest(context.Background(), group.Name, ExplainOptions{
+			Path:    "/claude/v1/messages?beta=true",
+			Headers: map[string]string{"anthropic-version": "2023-06-01"},
+			Body:    []byte(`{"model":"gpt-4o","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), upstreamCalls.Load())
+		assert.Contains(t, stageNames(result), ExplainStageCCConversion)
+
+		require.NotNil(t, result.Upstream)
+		assert.Equal(t, upstream.URL+"/v1/chat/completions", result.Upstream.URL)
+		assert.Equal(t, "hi", gjson.Get(result.Upstream.Body, "messages.0.content").String())
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		_, err := ps.ExplainRequest(context.Background(), "missing", ExplainOptions{Path: "/v1/models"})
+		assert.ErrorIs(t, err, ErrReplayGroupNotFound)
+
+		_, err = ps.ExplainRequest(context.Background(), group.Name, ExplainOptions{Path: ""})
+		assert.ErrorIs(t, err, ErrReplayInvalidPath)
+
+		_, err = ps.ExplainRequest(context.Background(), group.Name, ExplainOptions{Path: "/v1/chat/completions", Body: []byte(`{"model":`)})
+		assert.ErrorIs(t, err, ErrReplayInvalidBody)
+
+		_, err = ps.ExplainRequest(context.Background(), group.Name, ExplainOptions{Method: "TRACE", Path: "/v1/models"})
+		assert.ErrorIs(t, err, ErrExplainInvalidMethod)
+	})
+
+	t.Run("disabled groups answer locally", func(t *testing.T) {
+		result, err := ps.ExplainRequest(context.Background(), disabled.Name, ExplainOptions{Path: "/v1/models", Method: "GET"})
+		require.NoError(t, err)
+		assert.Nil(t, result.Upstream)
+		require.NotNil(t, result.Response)
+		assert.Equal(t, http.StatusBadRequest, result.Response.StatusCode)
+		assert.Equal(t, []string{ExplainStageClientRequest}, stageNames(result))
+	})
+}
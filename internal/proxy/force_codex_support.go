@@ -1037,6 +1037,10 @@ func (ps *ProxyServer) applyForceCodexRequestConversion(c *gin.Context, group *m
 }
 
 func (ps *ProxyServer) handleForceCodexNormalResponse(c *gin.Context, resp *http.Response) {
+	if ps.blockGuardrailResponse(c, resp) {
+		return
+	}
+
 	format := getCodexUpstreamFormat(c)
 	if format == codexUpstreamResponses {
 		if isFunctionCallEnabled(c) {
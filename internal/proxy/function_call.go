@@ -1718,6 +1718,10 @@ func (ps *ProxyServer) handleFunctionCallNormalResponse(c *gin.Context, resp *ht
 }
 
 func (ps *ProxyServer) handleFunctionCallNormalResponseByChannel(c *gin.Context, resp *http.Response, group *models.Group) {
+	if ps.blockGuardrailResponse(c, resp) {
+		return
+	}
+
 	if group == nil {
 		ps.handleFunctionCallNormalResponse(c, resp)
 		return
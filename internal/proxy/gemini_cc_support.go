@@ -713,6 +713,10 @@ func getGeminiToolNameReverseMap(c *gin.Context) map[string]string {
 
 // handleGeminiCCNormalResponse handles non-streaming Gemini response conversion to Claude format
 func (ps *ProxyServer) handleGeminiCCNormalResponse(c *gin.Context, resp *http.Response) {
+	if ps.blockGuardrailResponse(c, resp) {
+		return
+	}
+
 	bodyBytes, err := readAllWithLimit(resp.Body, maxUpstreamResponseBodySize)
 	if err != nil {
 		if errors.Is(err, ErrBodyTooLarge) {
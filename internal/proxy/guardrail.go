@@ -0,0 +1,315 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/guardrail"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	guardrailModerationTimeout = 10 * time.Second
+
+	ctxKeyGuardrailResponse        = "guardrail_response"
+	ctxKeyGuardrailParentResponse  = "guardrail_parent_response"
+	ctxKeyGuardrailViolation       = "guardrail_violation"
+	ctxKeyGuardrailResponseBlocked = "guardrail_response_blocked"
+)
+
+// Client-facing error formats for guardrail violations.
+const (
+	guardrailFormatOpenAI = "openai"
+	guardrailFormatClaude = "claude"
+	guardrailFormatGemini = "gemini"
+)
+
+// guardrailResponseCheck carries the policies that also apply to the completed
+// response, along with the error format detected from the original client path.
+type guardrailResponseCheck struct {
+	policies []*guardrail.Policy
+	format   string
+}
+
+// guardrailPolicies returns the group policy followed by the Hub access key
+// policy. Both must pass. Invalid policies are rejected when saved, so a
+// compile error here only disables that policy.
+func guardrailPolicies(c *gin.Context, group *models.Group) []*guardrail.Policy {
+	var policies []*guardrail.Policy
+	if policy := groupGuardrailPolicy(group); policy != nil {
+		policies = append(policies, policy)
+	}
+	if raw, ok := c.Get(guardrail.HubPolicyContextKey); ok {
+		if data, isBytes := raw.([]byte); isBytes {
+			if policy := compileGuardrailPolicy(group, data); policy != nil {
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies
+}
+
+// groupGuardrailPolicy returns the policy configured on the group itself, or
+// nil when it has none.
+func groupGuardrailPolicy(group *models.Group) *guardrail.Policy {
+	raw, ok := group.Config["guardrail_policy"]
+	if !ok || raw == nil {
+		return nil
+	}
+	if s, isString := raw.(string); isString {
+		return compileGuardrailPolicy(group, []byte(s))
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	return compileGuardrailPolicy(group, data)
+}
+
+func compileGuardrailPolicy(group *models.Group, source []byte) *guardrail.Policy {
+	policy, err := guardrail.Compile(source)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Ignoring invalid guardrail policy")
+		return nil
+	}
+	return policy
+}
+
+// guardrailErrorFormat picks the error shape from the client-facing path, which
+// must be read before CC and Codex path rewriting.
+func guardrailErrorFormat(path, groupName string) string {
+	switch {
+	case isClaudePath(path, groupName) || strings.HasSuffix(path, "/v1/messages"):
+		return guardrailFormatClaude
+	case strings.Contains(path, ":generateContent") || strings.Contains(path, ":streamGenerateContent") ||
+		(strings.Contains(path, "/v1beta/") && !strings.Contains(path, "/v1beta/openai/")):
+		return guardrailFormatGemini
+	default:
+		return guardrailFormatOpenAI
+	}
+}
+
+// enforceRequestGuardrails runs the group and Hub key policies against the
+// client request. It writes a format-correct error and logs the violation when
+// a policy rejects the request, returning true in that case. Aggregate groups
+// pass a nil channelHandler; their response checks are armed per sub-group
+// attempt by enforceSubGroupGuardrails.
+func (ps *ProxyServer) enforceRequestGuardrails(c *gin.Context, group *models.Group, channelHandler channel.ChannelProxy, bodyBytes []byte, startTime time.Time) bool {
+	if c.Request.Method == http.MethodGet || len(bodyBytes) == 0 {
+		return false
+	}
+	policies := guardrailPolicies(c, group)
+	if len(policies) == 0 {
+		return false
+	}
+
+	violation, responsePolicies := ps.checkRequestGuardrails(c, group, policies, bodyBytes)
+	if violation == nil {
+		if channelHandler != nil {
+			armGuardrailResponseCheck(c, group, channelHandler, bodyBytes, responsePolicies)
+		} else {
+			c.Set(ctxKeyGuardrailParentResponse, responsePolicies)
+		}
+		return false
+	}
+	ps.rejectGuardrailRequest(c, group, group, channelHandler, bodyBytes, startTime, violation)
+	return true
+}
+
+// enforceSubGroupGuardrails runs the policy of the sub-group an aggregate
+// request was routed to. The aggregate and Hub key policies have already
+// passed in enforceRequestGuardrails. Response checks of both levels are armed
+// again for every attempt, since a failed attempt consumes them.
+func (ps *ProxyServer) enforceSubGroupGuardrails(c *gin.Context, aggregateGroup, subGroup *models.Group, channelHandler channel.ChannelProxy, bodyBytes []byte, startTime time.Time) bool {
+	c.Set(ctxKeyGuardrailResponse, nil)
+	if c.Request.Method == http.MethodGet || len(bodyBytes) == 0 {
+		return false
+	}
+	var responsePolicies []*guardrail.Policy
+	if value, exists := c.Get(ctxKeyGuardrailParentResponse); exists {
+		if parent, ok := value.([]*guardrail.Policy); ok {
+			responsePolicies = append(responsePolicies, parent...)
+		}
+	}
+	if policy := groupGuardrailPolicy(subGroup); policy != nil {
+		violation, subResponse := ps.checkRequestGuardrails(c, subGroup, []*guardrail.Policy{policy}, bodyBytes)
+		if violation != nil {
+			ps.rejectGuardrailRequest(c, aggregateGroup, subGroup, channelHandler, bodyBytes, startTime, violation)
+			return true
+		}
+		responsePolicies = append(responsePolicies, subResponse...)
+	}
+	armGuardrailResponseCheck(c, aggregateGroup, channelHandler, bodyBytes, responsePolicies)
+	return false
+}
+
+// checkRequestGuardrails returns the first violation of the request, or the
+// policies that also want the response checked when all of them pass.
+func (ps *ProxyServer) checkRequestGuardrails(c *gin.Context, group *models.Group, policies []*guardrail.Policy, bodyBytes []byte) (*guardrail.Violation, []*guardrail.Policy) {
+	var responsePolicies []*guardrail.Policy
+	for _, policy := range policies {
+		violation := policy.CheckRequest(bodyBytes)
+		if violation == nil {
+			violation = ps.moderateGuardrailText(c.Request.Context(), group, policy, guardrail.RequestText(bodyBytes))
+		}
+		if violation != nil {
+			return violation, nil
+		}
+		if policy.ChecksResponse() {
+			responsePolicies = append(responsePolicies, policy)
+		}
+	}
+	return nil, responsePolicies
+}
+
+// armGuardrailResponseCheck stores the response policies for
+// blockGuardrailResponse. Streamed responses reach the client as they arrive,
+// so response checks only apply to non-streaming requests.
+func armGuardrailResponseCheck(c *gin.Context, group *models.Group, channelHandler channel.ChannelProxy, bodyBytes []byte, policies []*guardrail.Policy) {
+	if len(policies) == 0 || channelHandler.IsStreamRequest(c, bodyBytes) {
+		return
+	}
+	format := guardrailErrorFormat(c.Request.URL.Path, group.Name)
+	c.Set(ctxKeyGuardrailResponse, &guardrailResponseCheck{policies: policies, format: format})
+}
+
+func (ps *ProxyServer) rejectGuardrailRequest(c *gin.Context, originalGroup, group *models.Group, channelHandler channel.ChannelProxy, bodyBytes []byte, startTime time.Time, violation *guardrail.Violation) {
+	logrus.WithFields(logrus.Fields{
+		"group":  group.Name,
+		"reason": violation.Reason,
+	}).Info("Request blocked by guardrail policy")
+	writeGuardrailError(c, guardrailErrorFormat(c.Request.URL.Path, originalGroup.Name), "Request blocked by content policy: "+violation.Message)
+	c.Set(ctxKeyGuardrailViolation, violation)
+	ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusBadRequest, violation, false, "", nil, "", channelHandler, bodyBytes, models.RequestTypeGuardrail)
+}
+
+// blockGuardrailResponse checks a completed non-streaming response against the
+// policies that opted into response checks. On a violation the client receives
+// an error instead of the upstream body. Otherwise resp.Body is restored so the
+// caller can forward it unchanged. Each response is checked once, also when a
+// handler hands it on to another one.
+func (ps *ProxyServer) blockGuardrailResponse(c *gin.Context, resp *http.Response) bool {
+	value, exists := c.Get(ctxKeyGuardrailResponse)
+	check, ok := value.(*guardrailResponseCheck)
+	if !exists || !ok {
+		return false
+	}
+	c.Set(ctxKeyGuardrailResponse, nil)
+	if resp.StatusCode >= http.StatusBadRequest || strings.TrimSpace(resp.Header.Get("Content-Encoding")) != "" {
+		return false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamResponseBodySize+1))
+	if err != nil {
+		logUpstreamError("reading response body for guardrail check", err)
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), resp.Body))
+		return false
+	}
+	if int64(len(body)) > maxUpstreamResponseBodySize {
+		// Too large to inspect; stream it through unchecked.
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), resp.Body))
+		return false
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var group *models.Group
+	if groupVal, exists := c.Get("group"); exists {
+		group, _ = groupVal.(*models.Group)
+	}
+	text := guardrail.ResponseText(body)
+	for _, policy := range check.policies {
+		violation := policy.CheckText(text)
+		if violation == nil {
+			violation = ps.moderateGuardrailText(c.Request.Context(), group, policy, text)
+		}
+		if violation == nil {
+			continue
+		}
+		logrus.WithField("reason", violation.Reason).Info("Response blocked by guardrail policy")
+		writeGuardrailError(c, check.format, "Response blocked by content policy: "+violation.Message)
+		c.Set(ctxKeyGuardrailViolation, violation)
+		// The upstream already produced the response, so its usage still counts.
+		c.Set(ctxKeyGuardrailResponseBlocked, true)
+		setTokenUsageOrEstimateFromFullBodyIf(c, body, true)
+		setLogicalFailureContext(c, http.StatusBadRequest, "guardrail_"+violation.Reason, violation.Message)
+		return true
+	}
+	return false
+}
+
+// moderateGuardrailText sends text to the policy's moderation group on this
+// gateway. Moderation failures are fail-open unless the policy says otherwise.
+func (ps *ProxyServer) moderateGuardrailText(ctx context.Context, group *models.Group, policy *guardrail.Policy, text string) *guardrail.Violation {
+	moderation := policy.Moderation()
+	if moderation == nil || strings.TrimSpace(text) == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, guardrailModerationTimeout)
+	defer cancel()
+	body, err := ps.callOpenAIGroup(ctx, moderation.Group, "/v1/moderations", map[string]any{"model": moderation.Model, "input": text})
+	if err != nil {
+		fields := logrus.Fields{"moderation_group": moderation.Group}
+		if group != nil {
+			fields["group"] = group.Name
+		}
+		logrus.WithError(err).WithFields(fields).Warn("Guardrail moderation call failed")
+		if moderation.FailClosed {
+			return &guardrail.Violation{Reason: guardrail.ReasonModerationUnavailable, Message: "moderation check is unavailable"}
+		}
+		return nil
+	}
+
+	var categories []string
+	flagged := false
+	gjson.GetBytes(body, "results").ForEach(func(_, result gjson.Result) bool {
+		if !result.Get("flagged").Bool() {
+			return true
+		}
+		flagged = true
+		result.Get("categories").ForEach(func(name, value gjson.Result) bool {
+			if value.Bool() {
+				categories = append(categories, name.String())
+			}
+			return true
+		})
+		return true
+	})
+	if !flagged {
+		return nil
+	}
+	message := "content flagged by moderation"
+	if len(categories) > 0 {
+		sort.Strings(categories)
+		message += " (" + strings.Join(categories, ", ") + ")"
+	}
+	return &guardrail.Violation{Reason: guardrail.ReasonModeration, Message: message}
+}
+
+// writeGuardrailError writes a 400 error in the client's API format.
+func writeGuardrailError(c *gin.Context, format, message string) {
+	switch format {
+	case guardrailFormatClaude:
+		returnClaudeError(c, http.StatusBadRequest, message)
+	case guardrailFormatGemini:
+		clearUpstreamEncodingHeaders(c)
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    http.StatusBadRequest,
+			"message": message,
+			"status":  "INVALID_ARGUMENT",
+		}})
+	default:
+		clearUpstreamEncodingHeaders(c)
+		ReturnOpenAIError(c, http.StatusBadRequest, "content_policy_violation", message)
+	}
+}
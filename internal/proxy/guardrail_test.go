@@ -0,0 +1,260 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/guardrail"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestGuardrailErrorFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/proxy/g/v1/chat/completions", guardrailFormatOpenAI},
+		{"/proxy/g/v1/messages", guardrailFormatClaude},
+		{"/proxy/g/claude/v1/messages", guardrailFormatClaude},
+		{"/proxy/g/v1beta/models/gemini-pro:generateContent", guardrailFormatGemini},
+		{"/proxy/g/v1beta/openai/chat/completions", guardrailFormatOpenAI},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, guardrailErrorFormat(tt.path, "g"), tt.path)
+	}
+}
+
+func TestWriteGuardrailErrorShapes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	write := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		writeGuardrailError(c, format, "blocked")
+		return w
+	}
+
+	openai := write(guardrailFormatOpenAI)
+	assert.Equal(t, http.StatusBadRequest, openai.Code)
+	assert.Equal(t, "content_policy_violation", gjson.Get(openai.Body.String(), "error.code").String())
+
+	claude := write(guardrailFormatClaude)
+	assert.Equal(t, "error", gjson.Get(claude.Body.String(), "type").String())
+	assert.Equal(t, "blocked", gjson.Get(claude.Body.String(), "error.message").String())
+
+	gemini := write(guardrailFormatGemini)
+	assert.Equal(t, "INVALID_ARGUMENT", gjson.Get(gemini.Body.String(), "error.status").String())
+}
+
+func TestHandleProxyEnforcesGuardrails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, _ := setupTestProxyServerWithStore(t, db)
+
+	var chatCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/moderations") {
+			flagged := strings.Contains(gjson.GetBytes(body, "input").String(), "attack")
+			if flagged {
+				_, _ = io.WriteString(w, `{"results":[{"flagged":true,"categories":{"violence":true,"hate":false}}]}`)
+			} else {
+				_, _ = io.WriteString(w, `{"results":[{"flagged":false,"categories":{}}]}`)
+			}
+			return
+		}
+		chatCalls.Add(1)
+		content := "all good"
+		if strings.Contains(string(body), "leak") {
+			content = "the secret plan is ready"
+		}
+		_, _ = io.WriteString(w, `{"id":"c","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"`+content+`"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	moderation := createTestGroup(t, db, "moderation", "openai")
+	moderation.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	require.NoError(t, db.Save(moderation).Error)
+	createTestKey(t, db, moderation.ID, "sk-moderation", ps.encryptionSvc)
+
+	group := createTestGroup(t, db, "guarded", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.Config = map[string]any{
+		"guardrail_policy": map[string]any{
+			"blocklist":      []any{"Secret Plan"},
+			"check_response": true,
+			"moderation":     map[string]any{"group": moderation.Name},
+		},
+		"cc_support":    true,
+		"codex_support": true,
+	}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-guarded", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	sendTo := func(path, body, hubPolicy string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/guarded"+path, bytes.NewReader([]byte(body)))
+		c.Params = gin.Params{{Key: "group_name", Value: group.Name}}
+		if hubPolicy != "" {
+			c.Set(guardrail.HubPolicyContextKey, []byte(hubPolicy))
+		}
+		ps.HandleProxy(c)
+		return w
+	}
+	send := func(body string, hubPolicy string) *httptest.ResponseRecorder {
+		return sendTo("/v1/chat/completions", body, hubPolicy)
+	}
+
+	allowed := send(`{"model":"m","messages":[{"role":"user","content":"hello"}]}`, "")
+	require.Equal(t, http.StatusOK, allowed.Code)
+	assert.Equal(t, "all good", gjson.Get(allowed.Body.String(), "choices.0.message.content").String())
+
+	blocked := send(`{"model":"m","messages":[{"role":"user","content":"share the secret plan"}]}`, "")
+	assert.Equal(t, http.StatusBadRequest, blocked.Code)
+	assert.Equal(t, "content_policy_violation", gjson.Get(blocked.Body.String(), "error.code").String())
+
+	moderated := send(`{"model":"m","messages":[{"role":"user","content":"plan an attack"}]}`, "")
+	assert.Equal(t, http.StatusBadRequest, moderated.Code)
+	assert.Contains(t, gjson.Get(moderated.Body.String(), "error.message").String(), "violence")
+	assert.Equal(t, int32(1), chatCalls.Load(), "blocked requests never reach the upstream")
+
+	hubBlocked := send(`{"model":"m","messages":[{"role":"user","content":"a"},{"role":"user","content":"b"}]}`, `{"max_messages":1}`)
+	assert.Equal(t, http.StatusBadRequest, hubBlocked.Code)
+	assert.Contains(t, gjson.Get(hubBlocked.Body.String(), "error.message").String(), "2 messages")
+
+	responseBlocked := send(`{"model":"m","messages":[{"role":"user","content":"leak it"}]}`, "")
+	assert.Equal(t, http.StatusBadRequest, responseBlocked.Code)
+	assert.Contains(t, gjson.Get(responseBlocked.Body.String(), "error.message").String(), "Response blocked")
+	assert.NotContains(t, responseBlocked.Body.String(), "secret plan is ready")
+
+	ccBlocked := sendTo("/claude/v1/messages", `{"model":"m","max_tokens":16,"messages":[{"role":"user","content":"leak it"}]}`, "")
+	assert.Equal(t, http.StatusBadRequest, ccBlocked.Code)
+	assert.Equal(t, "error", gjson.Get(ccBlocked.Body.String(), "type").String(), "CC clients get a Claude error")
+	assert.Contains(t, gjson.Get(ccBlocked.Body.String(), "error.message").String(), "Response blocked")
+
+	codexBlocked := sendTo("/codex/v1/responses", `{"model":"m","input":"leak it"}`, "")
+	assert.Equal(t, http.StatusBadRequest, codexBlocked.Code)
+	assert.Contains(t, gjson.Get(codexBlocked.Body.String(), "error.message").String(), "Response blocked")
+
+	// Response checks do not apply to streamed responses.
+	streamed := send(`{"model":"m","stream":true,"messages":[{"role":"user","content":"leak it"}]}`, "")
+	assert.Equal(t, http.StatusOK, streamed.Code)
+	assert.Contains(t, streamed.Body.String(), "secret plan is ready")
+}
+
+func TestHandleProxyEnforcesSubGroupGuardrails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, _ := setupTestProxyServerWithStore(t, db)
+
+	var chatCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		chatCalls.Add(1)
+		content := "all good"
+		if strings.Contains(string(body), "leak") {
+			content = "the secret plan is ready"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"c","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"`+content+`"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	aggregateGroup := &models.Group{
+		Name:        "agg-guarded",
+		ChannelType: "openai",
+		GroupType:   "aggregate",
+		Enabled:     true,
+		Upstreams:   []byte(`[{"url":"https://unused.example","weight":100}]`),
+		Config:      map[string]any{"max_retries": 0},
+	}
+	require.NoError(t, db.Create(aggregateGroup).Error)
+	subGroup := createTestGroup(t, db, "agg-guarded-sub", "openai")
+	subGroup.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	subGroup.Config = map[string]any{
+		"guardrail_policy": map[string]any{
+			"blocklist":      []any{"Secret Plan"},
+			"check_response": true,
+		},
+	}
+	require.NoError(t, db.Save(subGroup).Error)
+	require.NoError(t, db.Create(&models.GroupSubGroup{
+		GroupID:         aggregateGroup.ID,
+		SubGroupID:      subGroup.ID,
+		SubGroupName:    subGroup.Name,
+		SubGroupEnabled: true,
+		Weight:          100,
+	}).Error)
+	createTestKey(t, db, subGroup.ID, "sk-agg-guarded", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/agg-guarded/v1/chat/completions", bytes.NewReader([]byte(body)))
+		c.Params = gin.Params{{Key: "group_name", Value: aggregateGroup.Name}}
+		ps.HandleProxy(c)
+		return w
+	}
+
+	allowed := send(`{"model":"m","messages":[{"role":"user","content":"hello"}]}`)
+	require.Equal(t, http.StatusOK, allowed.Code)
+
+	blocked := send(`{"model":"m","messages":[{"role":"user","content":"share the secret plan"}]}`)
+	assert.Equal(t, http.StatusBadRequest, blocked.Code)
+	assert.Contains(t, gjson.Get(blocked.Body.String(), "error.message").String(), "Request blocked")
+	assert.Equal(t, int32(1), chatCalls.Load(), "the sub-group policy blocks before the upstream call")
+
+	responseBlocked := send(`{"model":"m","messages":[{"role":"user","content":"leak it"}]}`)
+	assert.Equal(t, http.StatusBadRequest, responseBlocked.Code)
+	assert.Contains(t, gjson.Get(responseBlocked.Body.String(), "error.message").String(), "Response blocked")
+}
+
+func TestBlockGuardrailResponseKeepsUsage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy, err := guardrail.Compile([]byte(`{"blocklist":["secret"],"check_response":true}`))
+	require.NoError(t, err)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set(ctxKeyGuardrailResponse, &guardrailResponseCheck{policies: []*guardrail.Policy{policy}, format: guardrailFormatOpenAI})
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body: io.NopCloser(strings.NewReader(`{"choices":[{"message":{"content":"the secret"}}],` +
+			`"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`)),
+	}
+
+	require.True(t, (&ProxyServer{}).blockGuardrailResponse(c, resp))
+	assert.True(t, c.GetBool(ctxKeyGuardrailResponseBlocked))
+	usage, _, ok := getTokenUsage(c)
+	require.True(t, ok, "usage of the blocked response is kept for stats")
+	assert.EqualValues(t, 10, usage.TotalTokens)
+}
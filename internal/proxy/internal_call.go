@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// callOpenAIGroup sends a JSON POST to an endpoint of a standard OpenAI group
+// on this gateway, bypassing the proxy pipeline. It is used for auxiliary
+// calls such as semantic cache embeddings and guardrail moderation.
+func (ps *ProxyServer) callOpenAIGroup(ctx context.Context, groupName, endpoint string, payload any) ([]byte, error) {
+	group, err := ps.groupManager.GetGroupByName(groupName)
+	if err != nil {
+		return nil, err
+	}
+	if !group.Enabled || group.GroupType == "aggregate" || group.ChannelType != "openai" {
+		return nil, errors.New("group must be an enabled standard OpenAI group")
+	}
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := channelHandler.SelectUpstreamWithClients(&url.URL{Path: "/proxy/" + group.Name + endpoint}, group.Name)
+	if err != nil {
+		return nil, err
+	}
+	if upstream == nil || upstream.URL == "" || upstream.HTTPClient == nil {
+		return nil, errors.New("no upstream available")
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upstream.URL, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	channelHandler.ModifyRequest(req, apiKey, group)
+
+	resp, err := upstream.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamErrorBodySize*16))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s request failed with status %d", endpoint, resp.StatusCode)
+	}
+	return body, nil
+}
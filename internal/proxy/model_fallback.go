@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ctxKeyModelFallbackStep counts how many fallback models the current group has
+	// switched to. Zero means the redirect target selected by weight is still active.
+	ctxKeyModelFallbackStep = "model_fallback_step"
+	// ctxKeyModelRedirectTargetModel records the fallback model actually sent upstream,
+	// because fallback models have no index in the rule's weighted target list.
+	ctxKeyModelRedirectTargetModel = "model_redirect_target_model"
+)
+
+// modelUnavailableMarkers are lowercase fragments that upstreams use to report a
+// missing or unavailable model. They are only matched together with "model".
+var modelUnavailableMarkers = []string{
+	"not found",
+	"not_found",
+	"does not exist",
+	"not exist",
+	"unavailable",
+	"not available",
+	"not supported",
+	"unsupported",
+}
+
+// modelFallbackChain returns the fallback models configured for the redirect source
+// model of the current request in the given group.
+func modelFallbackChain(c *gin.Context, group *models.Group) []string {
+	if group == nil || len(group.ModelRedirectMapV2) == 0 {
+		return nil
+	}
+	sourceModel := c.GetString(ctxKeyModelRedirectSourceModel)
+	if sourceModel == "" {
+		return nil
+	}
+	return group.ModelRedirectMapV2[sourceModel].FallbackModels()
+}
+
+// modelFallbackStep returns the number of fallback models already switched to.
+func modelFallbackStep(c *gin.Context) int {
+	step, _ := c.Get(ctxKeyModelFallbackStep)
+	if n, ok := step.(int); ok && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// hasNextModelFallback reports whether the request can still switch to another fallback model.
+func hasNextModelFallback(c *gin.Context, group *models.Group) bool {
+	return modelFallbackStep(c) < len(modelFallbackChain(c, group))
+}
+
+// advanceModelFallback switches the request to the next fallback model.
+// Returns false when the chain is exhausted or no fallback is configured.
+func advanceModelFallback(c *gin.Context, group *models.Group, statusCode int) bool {
+	chain := modelFallbackChain(c, group)
+	step := modelFallbackStep(c)
+	if step >= len(chain) {
+		return false
+	}
+
+	c.Set(ctxKeyModelFallbackStep, step+1)
+	logrus.WithFields(logrus.Fields{
+		"group":          group.Name,
+		"source_model":   c.GetString(ctxKeyModelRedirectSourceModel),
+		"fallback_model": chain[step],
+		"fallback_step":  step + 1,
+		"status_code":    statusCode,
+	}).Info("Switching to fallback model after upstream failure")
+	return true
+}
+
+// applyModelFallback rewrites the upstream model to the active fallback model.
+// The model is replaced in the JSON body when present; Gemini native requests
+// carry the model in the URL path instead. The original client model is kept in
+// context so logs and CC responses still report what the client requested.
+func applyModelFallback(c *gin.Context, req *http.Request, bodyBytes []byte, group *models.Group) []byte {
+	step := modelFallbackStep(c)
+	if step == 0 {
+		return bodyBytes
+	}
+	chain := modelFallbackChain(c, group)
+	if step > len(chain) {
+		return bodyBytes
+	}
+	fallbackModel := chain[step-1]
+
+	c.Set(ctxKeyModelRedirectTargetIndex, -1)
+	c.Set(ctxKeyModelRedirectTargetModel, fallbackModel)
+
+	var requestData map[string]any
+	if len(bodyBytes) > 0 && json.Unmarshal(bodyBytes, &requestData) == nil {
+		if _, ok := requestData["model"].(string); ok {
+			requestData["model"] = fallbackModel
+			modifiedBytes, err := json.Marshal(requestData)
+			if err != nil {
+				logrus.WithError(err).Warn("Failed to marshal request body for fallback model, using current model")
+				return bodyBytes
+			}
+			req.Body = io.NopCloser(bytes.NewReader(modifiedBytes))
+			req.ContentLength = int64(len(modifiedBytes))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(modifiedBytes)), nil
+			}
+			return modifiedBytes
+		}
+	}
+
+	if group.ChannelType == "gemini" {
+		parts := strings.Split(req.URL.Path, "/")
+		for i, part := range parts {
+			if part == "models" && i+1 < len(parts) {
+				suffix := ""
+				if colonIndex := strings.Index(parts[i+1], ":"); colonIndex != -1 {
+					suffix = parts[i+1][colonIndex:]
+				}
+				parts[i+1] = fallbackModel + suffix
+				req.URL.Path = strings.Join(parts, "/")
+				req.URL.RawPath = ""
+				break
+			}
+		}
+	}
+	return bodyBytes
+}
+
+// isModelUnavailableError reports whether an upstream error says the requested
+// model is missing, unavailable or overloaded rather than the key being bad.
+func isModelUnavailableError(statusCode int, errorBody string) bool {
+	// 529 is Anthropic's dedicated "overloaded" status.
+	if statusCode == 529 {
+		return true
+	}
+	lower := strings.ToLower(errorBody)
+	if strings.Contains(lower, "overloaded") {
+		return true
+	}
+	if !strings.Contains(lower, "model") {
+		return false
+	}
+	for _, marker := range modelUnavailableMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldFallbackOnResponse checks error responses that are not configured as
+// failover statuses (for example 404 model_not_found) and reports whether they
+// should switch to a fallback model. The response body is restored for callers.
+func shouldFallbackOnResponse(c *gin.Context, group *models.Group, resp *http.Response) bool {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest || shouldFailoverOnStatusCode(resp.StatusCode, group) {
+		return false
+	}
+	if !hasNextModelFallback(c, group) {
+		return false
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamErrorBodySize))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+	return isModelUnavailableError(resp.StatusCode, string(decompressUpstreamErrorBody(resp, peeked)))
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsModelUnavailableError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   bool
+	}{
+		{name: "anthropic overloaded status", statusCode: 529, body: "", expected: true},
+		{name: "overloaded error type", statusCode: 503, body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, expected: true},
+		{name: "openai model not found", statusCode: 404, body: `{"error":{"code":"model_not_found","message":"The model gpt-x does not exist"}}`, expected: true},
+		{name: "model unavailable", statusCode: 400, body: `{"error":{"message":"model is currently unavailable"}}`, expected: true},
+		{name: "plain rate limit", statusCode: 429, body: `{"error":{"message":"Rate limit exceeded"}}`, expected: false},
+		{name: "invalid key", statusCode: 401, body: `{"error":{"message":"Incorrect API key provided"}}`, expected: false},
+		{name: "not found without model", statusCode: 404, body: `{"error":{"message":"File not found"}}`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, isModelUnavailableError(tt.statusCode, tt.body))
+		})
+	}
+}
+
+func TestApplyModelFallbackRewritesModel(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	group := &models.Group{
+		ChannelType: "gemini",
+		ModelRedirectMapV2: map[string]*models.ModelRedirectRuleV2{
+			"alias": {Targets: []models.ModelRedirectTarget{{Model: "primary"}}, Fallback: []string{"backup"}},
+		},
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	setModelRedirectContext(c, "alias", 0, true)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	body := []byte(`{"model":"primary"}`)
+	assert.Equal(t, body, applyModelFallback(c, req, body, group), "inactive fallback keeps the body")
+
+	require.True(t, advanceModelFallback(c, group, http.StatusNotFound))
+	assert.False(t, hasNextModelFallback(c, group))
+	assert.JSONEq(t, `{"model":"backup"}`, string(applyModelFallback(c, req, body, group)))
+	assert.Equal(t, "backup", c.GetString(ctxKeyModelRedirectTargetModel))
+	assert.Equal(t, "alias", c.GetString("original_model"))
+
+	nativeReq := httptest.NewRequest(http.MethodPost, "/v1beta/models/primary:generateContent", nil)
+	applyModelFallback(c, nativeReq, []byte(`{"contents":[]}`), group)
+	assert.Equal(t, "/v1beta/models/backup:generateContent", nativeReq.URL.Path)
+
+	assert.False(t, advanceModelFallback(c, group, http.StatusNotFound), "chain is exhausted")
+}
+
+func TestExecuteRequestWithRetryFallsBackToNextModel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+	dwm := services.NewDynamicWeightManager(store.NewMemoryStore())
+	ps.SetDynamicWeightManager(dwm)
+
+	group := createTestGroup(t, db, "fallback-standard", "openai")
+	group.EffectiveConfig = systemSettingsWithRetryTimeout(1, 5)
+	group.ModelRedirectMapV2 = map[string]*models.ModelRedirectRuleV2{
+		"smart": {
+			Targets:  []models.ModelRedirectTarget{{Model: "model-a"}},
+			Fallback: []string{"model-b", "model-c"},
+		},
+	}
+	createTestKey(t, db, group.ID, "sk-fallback-1", ps.encryptionSvc)
+	createTestKey(t, db, group.ID, "sk-fallback-2", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+
+	var mu sync.Mutex
+	var seenModels []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		seenModels = append(seenModels, payload.Model)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch payload.Model {
+		case "model-a":
+			// 404 is not a failover status, so this checks the model-unavailable path.
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"error":{"code":"model_not_found","message":"The model model-a does not exist"}}`)
+		case "model-b":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = io.WriteString(w, `{"error":{"message":"internal error"}}`)
+		default:
+			_, _ = io.WriteString(w, `{"model":"`+payload.Model+`","choices":[]}`)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := []byte(`{"model":"smart","messages":[]}`)
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/fallback-standard/v1/chat/completions", bytes.NewReader(body))
+
+	handler := &fallbackTestChannelProxy{testChannelProxy: testChannelProxy{client: upstream.Client(), url: upstream.URL}}
+	ps.executeRequestWithRetry(c, handler, group, group, body, false, time.Now(), 0)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"model-a", "model-b", "model-b", "model-c"}, seenModels,
+		"model-a switches immediately, model-b uses its retry budget first")
+	assert.Equal(t, "smart", c.GetString("original_model"))
+
+	keys, err := memStore.SPopN(services.PendingLogKeysSet, 100)
+	require.NoError(t, err)
+	loggedModels := map[string]int{}
+	var finalLogs int
+	for _, key := range keys {
+		logBytes, err := memStore.Get(key)
+		require.NoError(t, err)
+		var entry models.RequestLog
+		require.NoError(t, json.Unmarshal(logBytes, &entry))
+		assert.Equal(t, "smart", entry.MappedModel)
+		loggedModels[entry.Model]++
+		if entry.RequestType == models.RequestTypeFinal {
+			finalLogs++
+			assert.Equal(t, "model-c", entry.Model)
+			assert.True(t, entry.IsSuccess)
+		}
+	}
+	assert.Equal(t, map[string]int{"model-a": 1, "model-b": 2, "model-c": 1}, loggedModels)
+	assert.Equal(t, 1, finalLogs)
+
+	metrics, err := dwm.GetModelRedirectMetrics(group.ID, "smart", "model-c")
+	require.NoError(t, err)
+	require.NotNil(t, metrics)
+	assert.Equal(t, int64(1), metrics.Successes7d)
+	failed, err := dwm.GetModelRedirectMetrics(group.ID, "smart", "model-b")
+	require.NoError(t, err)
+	require.NotNil(t, failed)
+	assert.Equal(t, int64(2), failed.ConsecutiveFailures)
+}
+
+// fallbackTestChannelProxy applies V2 redirect rules and reports the upstream model for logs.
+type fallbackTestChannelProxy struct {
+	testChannelProxy
+}
+
+func (p *fallbackTestChannelProxy) ApplyModelRedirectWithIndex(_ *http.Request, bodyBytes []byte, group *models.Group) ([]byte, string, int, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		return bodyBytes, "", -1, nil
+	}
+	source, _ := payload["model"].(string)
+	target, _, _, idx, err := models.ResolveTargetModelWithIndex(source, nil, group.ModelRedirectMapV2, models.NewModelRedirectSelector(func([]int) int { return 0 }))
+	if err != nil || target == "" {
+		return bodyBytes, "", -1, err
+	}
+	payload["model"] = target
+	modified, err := json.Marshal(payload)
+	return modified, source, idx, err
+}
+
+func (p *fallbackTestChannelProxy) ExtractModel(_ *gin.Context, bodyBytes []byte) string {
+	var payload struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(bodyBytes, &payload)
+	return payload.Model
+}
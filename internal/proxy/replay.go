@@ -0,0 +1,406 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	// maxReplayResponseBytes bounds the replayed response kept for display and diffing.
+	maxReplayResponseBytes = 1 << 20
+	// maxReplayDiffLines skips the diff when either side is longer, since the
+	// line diff is quadratic.
+	maxReplayDiffLines = 2000
+)
+
+// Replay request validation errors, mapped to i18n messages by the handler.
+var (
+	ErrReplayNoRequestBody   = errors.New("log has no stored request body")
+	ErrReplayInvalidBody     = errors.New("request body is not valid JSON")
+	ErrReplayInvalidPath     = errors.New("request path is not a proxy path")
+	ErrReplayGroupNotFound   = errors.New("replay target group not found")
+	ErrReplayModelUnsettable = errors.New("model cannot be overridden for this request")
+)
+
+// replayDryRunKey marks a replay request whose transformed upstream request
+// should be captured instead of sent.
+type replayDryRunKey struct{}
+
+// replayDryRunCapture receives the upstream request built for the first attempt,
+// after model redirects and header rules.
+type replayDryRunCapture struct {
+	captured  bool
+	path      string
+	body      []byte
+	isStream  bool
+	aggregate bool
+}
+
+// ReplayOptions controls how a logged request is replayed.
+type ReplayOptions struct {
+	// GroupName targets another group. Defaults to the aggregate group the
+	// request entered through, or the logged group.
+	GroupName string `json:"group_name"`
+	// Model overrides the request model.
+	Model string `json:"model"`
+	// Path overrides the client path below /proxy/{group}, e.g. /claude/v1/messages.
+	Path string `json:"path"`
+	// Body replaces the stored request body, e.g. with the original client
+	// request when the log holds the converted upstream body.
+	Body json.RawMessage `json:"body"`
+	// DryRun returns the transformed upstream request without sending it.
+	DryRun bool `json:"dry_run"`
+}
+
+// ReplayResponse is a captured response of the original or replayed request.
+type ReplayResponse struct {
+	StatusCode int    `json:"status_code"`
+	Body       string `json:"body"`
+	Truncated  bool   `json:"truncated,omitempty"`
+}
+
+// ReplayDiffRow is one row of a side-by-side line diff. Op is "equal",
+// "change", "delete" (left only) or "insert" (right only).
+type ReplayDiffRow struct {
+	Op    string `json:"op"`
+	Left  string `json:"left,omitempty"`
+	Right string `json:"right,omitempty"`
+}
+
+// ReplayResult describes a replayed request.
+type ReplayResult struct {
+	GroupName   string `json:"group_name"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	RequestBody string `json:"request_body"`
+	DryRun      bool   `json:"dry_run"`
+
+	// Dry-run output: the request HandleProxy would send upstream. For
+	// aggregate groups, sub-group transformations happen per attempt and
+	// are not included.
+	UpstreamPath string `json:"upstream_path,omitempty"`
+	UpstreamBody string `json:"upstream_body,omitempty"`
+	IsStream     bool   `json:"is_stream"`
+	Aggregate    bool   `json:"aggregate,omitempty"`
+
+	Original   *ReplayResponse `json:"original,omitempty"`
+	Replayed   *ReplayResponse `json:"replayed,omitempty"`
+	Diff       []ReplayDiffRow `json:"diff,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// replayResponseWriter collects the response written by HandleProxy.
+type replayResponseWriter struct {
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *replayResponseWriter) Header() http.Header { return w.header }
+
+func (w *replayResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *replayResponseWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if remaining := maxReplayResponseBytes - w.body.Len(); remaining < len(data) {
+		w.truncated = true
+		if remaining > 0 {
+			w.body.Write(data[:remaining])
+		}
+	} else {
+		w.body.Write(data)
+	}
+	return len(data), nil
+}
+
+func (w *replayResponseWriter) Flush() {}
+
+// ReplayRequestLog resends a logged request through the proxy pipeline, or
+// with DryRun only shows the upstream request it would produce. Replayed
+// requests are logged like any other proxied request.
+func (ps *ProxyServer) ReplayRequestLog(ctx context.Context, log *models.RequestLog, opts ReplayOptions) (*ReplayResult, error) {
+	// Logs written before the method was recorded were all proxied as POST.
+	method := log.RequestMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+	body := []byte(log.RequestBody)
+	if len(opts.Body) > 0 {
+		body = opts.Body
+	}
+	hasBody := len(bytes.TrimSpace(body)) > 0
+	if !hasBody && method == http.MethodPost {
+		return nil, ErrReplayNoRequestBody
+	}
+	// Stored bodies are truncated at the log capture limit; those cannot be replayed.
+	if hasBody && !json.Valid(body) {
+		return nil, ErrReplayInvalidBody
+	}
+
+	groupName := strings.TrimSpace(opts.GroupName)
+	if groupName == "" {
+		groupName = log.GroupName
+		if log.ParentGroupName != "" {
+			groupName = log.ParentGroupName
+		}
+	}
+	if _, err := ps.groupManager.GetGroupByName(groupName); err != nil {
+		return nil, ErrReplayGroupNotFound
+	}
+
+	subPath, rawQuery, err := replaySubPath(log.RequestPath, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send the model alias the client asked for, so redirect rules apply again.
+	model := strings.TrimSpace(opts.Model)
+	if model == "" && log.MappedModel != "" && gjson.GetBytes(body, "model").String() == log.Model {
+		model = log.MappedModel
+	}
+	if model != "" {
+		switch {
+		case gjson.GetBytes(body, "model").Exists():
+			if body, err = sjson.SetBytes(body, "model", model); err != nil {
+				return nil, err
+			}
+		case strings.Contains(subPath, "/models/"):
+			subPath = replaceGeminiPathModel(subPath, model)
+		case opts.Model != "":
+			return nil, ErrReplayModelUnsettable
+		}
+	}
+
+	target := &url.URL{Path: "/proxy/" + groupName + subPath, RawQuery: rawQuery}
+	result := &ReplayResult{
+		GroupName:   groupName,
+		Method:      method,
+		Path:        target.Path,
+		RequestBody: string(body),
+		DryRun:      opts.DryRun,
+	}
+
+	var capture *replayDryRunCapture
+	if opts.DryRun {
+		capture = &replayDryRunCapture{}
+		ctx = context.WithValue(ctx, replayDryRunKey{}, capture)
+	}
+	// HandleProxy reads the body like a server request, which is never nil.
+	var reqBody io.Reader = http.NoBody
+	if hasBody {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if hasBody {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "gpt-load-replay")
+	req.RemoteAddr = "127.0.0.1:0"
+
+	started := time.Now()
+	writer := ps.serveProxyRequest(req)
+	result.DurationMs = time.Since(started).Milliseconds()
+
+	if capture != nil && capture.captured {
+		result.UpstreamPath = capture.path
+		result.UpstreamBody = string(capture.body)
+		result.IsStream = capture.isStream
+		result.Aggregate = capture.aggregate
+		return result, nil
+	}
+
+	replayed := &ReplayResponse{StatusCode: writer.status, Truncated: writer.truncated}
+	responseBody := writer.body.Bytes()
+	if encoding := strings.TrimSpace(writer.header.Get("Content-Encoding")); encoding != "" && !writer.truncated {
+		if decoded, decodeErr := utils.DecompressResponseWithLimit(encoding, responseBody, maxReplayResponseBytes); decodeErr == nil {
+			responseBody = decoded
+		}
+	}
+	replayed.Body = string(responseBody)
+	result.Replayed = replayed
+	result.IsStream = strings.HasPrefix(writer.header.Get("Content-Type"), "text/event-stream")
+
+	if !opts.DryRun {
+		result.Original = &ReplayResponse{StatusCode: log.StatusCode, Body: log.ResponseBody}
+		result.Diff = replayLineDiff(result.Original.Body, replayed.Body)
+	}
+	return result, nil
+}
+
+// serveProxyRequest runs req through HandleProxy outside the proxy router,
+// so proxy authentication does not apply, and collects the response.
+func (ps *ProxyServer) serveProxyRequest(req *http.Request) *replayResponseWriter {
+	engine := gin.New()
+	engine.Any("/proxy/:group_name/*path", ps.HandleProxy)
+	writer := &replayResponseWriter{header: make(http.Header)}
+	engine.ServeHTTP(writer, req)
+	return writer
+}
+
+// replaySubPath returns the client path below /proxy/{group} and the query of
+// the logged path, or the override path when given.
+func replaySubPath(loggedPath, override string) (string, string, error) {
+	if override = strings.TrimSpace(override); override != "" {
+		u, err := url.Parse(override)
+		if err != nil || !strings.HasPrefix(u.Path, "/") {
+			return "", "", ErrReplayInvalidPath
+		}
+		return u.Path, u.RawQuery, nil
+	}
+
+	u, err := url.Parse(loggedPath)
+	if err != nil {
+		return "", "", ErrReplayInvalidPath
+	}
+	rest, ok := strings.CutPrefix(u.Path, "/proxy/")
+	if !ok {
+		return "", "", ErrReplayInvalidPath
+	}
+	slash := strings.Index(rest, "/")
+	if slash < 0 {
+		return "", "", ErrReplayInvalidPath
+	}
+	// Drop parameters redacted for logging; the group's own credentials are used.
+	query := u.Query()
+	for key, values := range query {
+		for _, value := range values {
+			if value == "[REDACTED]" {
+				query.Del(key)
+				break
+			}
+		}
+	}
+	return rest[slash:], query.Encode(), nil
+}
+
+// replaceGeminiPathModel swaps the model in a native Gemini path such as
+// /v1beta/models/{model}:generateContent.
+func replaceGeminiPathModel(path, model string) string {
+	idx := strings.Index(path, "/models/")
+	if idx < 0 {
+		return path
+	}
+	start := idx + len("/models/")
+	end := len(path)
+	if colon := strings.Index(path[start:], ":"); colon >= 0 {
+		end = start + colon
+	} else if slash := strings.Index(path[start:], "/"); slash >= 0 {
+		end = start + slash
+	}
+	return path[:start] + model + path[end:]
+}
+
+// isReplayDryRun reports whether the request is a dry-run replay.
+func isReplayDryRun(c *gin.Context) bool {
+	_, ok := c.Request.Context().Value(replayDryRunKey{}).(*replayDryRunCapture)
+	return ok
+}
+
+// captureReplayDryRun records the upstream request for a dry-run replay.
+// Returns true when the request must not be sent.
+func captureReplayDryRun(c *gin.Context, bodyBytes []byte, isStream, aggregate bool) bool {
+	capture, ok := c.Request.Context().Value(replayDryRunKey{}).(*replayDryRunCapture)
+	if !ok {
+		return false
+	}
+	capture.captured = true
+	capture.path = c.Request.URL.Path
+	capture.body = append([]byte(nil), bodyBytes...)
+	capture.isStream = isStream
+	capture.aggregate = aggregate
+	c.Status(http.StatusNoContent)
+	return true
+}
+
+// replayLineDiff compares two bodies line by line, pretty-printing JSON first
+// so field-level changes line up.
+func replayLineDiff(left, right string) []ReplayDiffRow {
+	leftLines := strings.Split(prettyReplayBody(left), "\n")
+	rightLines := strings.Split(prettyReplayBody(right), "\n")
+	if len(leftLines) > maxReplayDiffLines || len(rightLines) > maxReplayDiffLines {
+		return nil
+	}
+
+	// Longest common subsequence table, filled from the end.
+	n, m := len(leftLines), len(rightLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if leftLines[i] == rightLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var rows []ReplayDiffRow
+	var deleted, inserted []string
+	flush := func() {
+		// Pair removed and added lines into "change" rows for side-by-side display.
+		for k := 0; k < len(deleted) || k < len(inserted); k++ {
+			switch {
+			case k < len(deleted) && k < len(inserted):
+				rows = append(rows, ReplayDiffRow{Op: "change", Left: deleted[k], Right: inserted[k]})
+			case k < len(deleted):
+				rows = append(rows, ReplayDiffRow{Op: "delete", Left: deleted[k]})
+			default:
+				rows = append(rows, ReplayDiffRow{Op: "insert", Right: inserted[k]})
+			}
+		}
+		deleted, inserted = deleted[:0], inserted[:0]
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && leftLines[i] == rightLines[j]:
+			flush()
+			rows = append(rows, ReplayDiffRow{Op: "equal", Left: leftLines[i], Right: rightLines[j]})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+			inserted = append(inserted, rightLines[j])
+			j++
+		default:
+			deleted = append(deleted, leftLines[i])
+			i++
+		}
+	}
+	flush()
+	return rows
+}
+
+func prettyReplayBody(body string) string {
+	var out bytes.Buffer
+	if json.Indent(&out, []byte(body), "", "  ") == nil {
+		return out.String()
+	}
+	return body
+}
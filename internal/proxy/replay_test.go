@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestReplaySubPath(t *testing.T) {
+	t.Parallel()
+
+	path, query, err := replaySubPath("/proxy/old/v1/chat/completions?key=%5BREDACTED%5D&alt=sse", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/chat/completions", path)
+	assert.Equal(t, "alt=sse", query)
+
+	path, query, err = replaySubPath("/proxy/old/v1/chat/completions", "/claude/v1/messages?beta=true")
+	require.NoError(t, err)
+	assert.Equal(t, "/claude/v1/messages", path)
+	assert.Equal(t, "beta=true", query)
+
+	_, _, err = replaySubPath("/hub/v1/chat/completions", "")
+	assert.ErrorIs(t, err, ErrReplayInvalidPath)
+	_, _, err = replaySubPath("", "v1/chat")
+	assert.ErrorIs(t, err, ErrReplayInvalidPath)
+}
+
+func TestReplaceGeminiPathModel(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/v1beta/models/gemini-2.5-pro:generateContent",
+		replaceGeminiPathModel("/v1beta/models/gemini-pro:generateContent", "gemini-2.5-pro"))
+	assert.Equal(t, "/v1beta/models/b", replaceGeminiPathModel("/v1beta/models/a", "b"))
+	assert.Equal(t, "/v1/chat/completions", replaceGeminiPathModel("/v1/chat/completions", "b"))
+}
+
+func TestReplayLineDiff(t *testing.T) {
+	t.Parallel()
+
+	rows := replayLineDiff(`{"a":1,"b":2,"c":3}`, `{"a":1,"b":5,"c":3,"d":4}`)
+	var ops []string
+	for _, row := range rows {
+		ops = append(ops, row.Op)
+	}
+	assert.Equal(t, []string{"equal", "equal", "change", "change", "insert", "equal"}, ops)
+	assert.Equal(t, `  "b": 2,`, rows[2].Left)
+	assert.Equal(t, `  "b": 5,`, rows[2].Right)
+
+	rows = replayLineDiff("same", "same")
+	require.Len(t, rows, 1)
+	assert.Equal(t, "equal", rows[0].Op)
+}
+
+func TestReplayRequestLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, _ := setupTestProxyServerWithStore(t, db)
+
+	var upstreamCalls atomic.Int32
+	var lastUpstreamBody, lastUpstreamMethod atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls.Add(1)
+		lastUpstreamMethod.Store(r.Method)
+		body, _ := io.ReadAll(r.Body)
+		lastUpstreamBody.Store(string(body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"new","model":"`+gjson.GetBytes(body, "model").String()+`","choices":[]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	group := createTestGroup(t, db, "replay", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.ParamOverrides = map[string]any{"temperature": 0.2}
+	group.Config = map[string]any{"cc_support": true}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-replay", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	entry := &models.RequestLog{
+		GroupName:    group.Name,
+		RequestPath:  "/proxy/replay/v1/chat/completions",
+		RequestBody:  `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`,
+		StatusCode:   http.StatusOK,
+		ResponseBody: `{"id":"old","model":"gpt-4o","choices":[]}`,
+	}
+
+	t.Run("dry run shows the transformed body without sending", func(t *testing.T) {
+		result, err := ps.ReplayRequestLog(context.Background(), entry, ReplayOptions{DryRun: true, Model: "gpt-4.1"})
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), upstreamCalls.Load())
+		assert.Equal(t, "/proxy/replay/v1/chat/completions", result.UpstreamPath)
+		assert.Equal(t, "gpt-4.1", gjson.Get(result.UpstreamBody, "model").String())
+		assert.Equal(t, 0.2, gjson.Get(result.UpstreamBody, "temperature").Float())
+		assert.Nil(t, result.Replayed)
+	})
+
+	t.Run("dry run shows CC conversion for a Claude body", func(t *testing.T) {
+		result, err := ps.ReplayRequestLog(context.Background(), entry, ReplayOptions{
+			DryRun: true,
+			Path:   "/claude/v1/messages",
+			Body:   []byte(`{"model":"gpt-4o","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "/proxy/replay/v1/chat/completions", result.UpstreamPath)
+		assert.Equal(t, "hi", gjson.Get(result.UpstreamBody, "messages.0.content").String())
+		assert.Equal(t, int32(0), upstreamCalls.Load())
+	})
+
+	t.Run("replay sends the request and diffs the responses", func(t *testing.T) {
+		result, err := ps.ReplayRequestLog(context.Background(), entry, ReplayOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), upstreamCalls.Load())
+		assert.Equal(t, 0.2, gjson.Get(lastUpstreamBody.Load().(string), "temperature").Float())
+		require.NotNil(t, result.Replayed)
+		assert.Equal(t, http.StatusOK, result.Replayed.StatusCode)
+		assert.Equal(t, "new", gjson.Get(result.Replayed.Body, "id").String())
+		require.NotNil(t, result.Original)
+		assert.Contains(t, result.Diff, ReplayDiffRow{Op: "change", Left: `  "id": "old",`, Right: `  "id": "new",`})
+	})
+
+	t.Run("replay keeps the logged method", func(t *testing.T) {
+		before := upstreamCalls.Load()
+		result, err := ps.ReplayRequestLog(context.Background(), &models.RequestLog{
+			GroupName:     group.Name,
+			RequestMethod: http.MethodGet,
+			RequestPath:   "/proxy/replay/v1/models",
+		}, ReplayOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, before+1, upstreamCalls.Load())
+		assert.Equal(t, http.MethodGet, lastUpstreamMethod.Load())
+		assert.Equal(t, http.MethodGet, result.Method)
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		_, err := ps.ReplayRequestLog(context.Background(), &models.RequestLog{GroupName: group.Name}, ReplayOptions{})
+		assert.ErrorIs(t, err, ErrReplayNoRequestBody)
+
+		truncated := *entry
+		truncated.RequestBody = `{"model":"gpt-4o","messages":[`
+		_, err = ps.ReplayRequestLog(context.Background(), &truncated, ReplayOptions{})
+		assert.ErrorIs(t, err, ErrReplayInvalidBody)
+
+		_, err = ps.ReplayRequestLog(context.Background(), entry, ReplayOptions{GroupName: "missing"})
+		assert.ErrorIs(t, err, ErrReplayGroupNotFound)
+	})
+}
+
+func TestReplayDryRunShowsRedirectedUpstreamBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, _ := setupTestProxyServerWithStore(t, db)
+
+	var upstreamCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"new","choices":[]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	group := createTestGroup(t, db, "redirected", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.ModelRedirectRulesV2 = []byte(`{"gpt-4o":{"targets":[{"model":"gpt-4.1-mini","weight":100}]}}`)
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-redirected", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	result, err := ps.ReplayRequestLog(context.Background(), &models.RequestLog{
+		GroupName:   group.Name,
+		RequestPath: "/proxy/redirected/v1/chat/completions",
+		RequestBody: `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`,
+	}, ReplayOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), upstreamCalls.Load())
+	assert.Equal(t, "gpt-4.1-mini", gjson.Get(result.UpstreamBody, "model").String(), "the dry run shows the body after model redirect")
+	assert.Nil(t, result.Replayed)
+}
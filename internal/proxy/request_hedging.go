@@ -0,0 +1,346 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxHedgeKeySelectAttempts bounds how many rotations are spent looking for a
+// key different from the primary attempt before hedging is skipped.
+const maxHedgeKeySelectAttempts = 3
+
+// errHedgeAttemptLost is logged for the attempt cancelled because the other
+// attempt in the hedged race produced a usable response first.
+var errHedgeAttemptLost = errors.New("hedged attempt cancelled: other attempt won")
+
+// hedgeNonIdempotentPathMarkers lists endpoints where a duplicate upstream call
+// can create billable artifacts or side effects, so they are never hedged.
+var hedgeNonIdempotentPathMarkers = []string{
+	"/images/",
+	"/audio/",
+	"/videos",
+	"/files",
+	"/uploads",
+	"/batches",
+	"/fine_tuning",
+	"/fine-tunes",
+	"/assistants",
+	"/threads",
+	"/vector_stores",
+	"/realtime",
+}
+
+// hedgeAttempt describes the shared inputs of one hedged upstream race.
+type hedgeAttempt struct {
+	channelHandler    channel.ChannelProxy
+	originalGroup     *models.Group
+	group             *models.Group
+	apiKey            *models.APIKey
+	upstreamSelection *channel.UpstreamSelection
+	bodyBytes         []byte
+	startTime         time.Time
+}
+
+type hedgeAttemptResult struct {
+	resp    *http.Response
+	err     error
+	apiKey  *models.APIKey
+	isHedge bool
+	cancel  context.CancelFunc
+}
+
+// cancelOnCloseBody releases the per-attempt context once the caller finishes
+// reading the winning response body.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// parseHedgeAfterMs returns the configured hedge delay in milliseconds.
+// Invalid persisted values fail closed to zero so hedging never amplifies load by accident.
+func parseHedgeAfterMs(cfg map[string]any) int {
+	if cfg == nil {
+		return 0
+	}
+	val, ok := cfg["hedge_after_ms"]
+	if !ok || val == nil {
+		return 0
+	}
+
+	var delay int64
+	switch v := val.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) || math.Trunc(v) != v {
+			return 0
+		}
+		if v > float64(config.MaxHedgeAfterMs) {
+			return config.MaxHedgeAfterMs
+		}
+		delay = int64(v)
+	case int:
+		delay = int64(v)
+	case int64:
+		delay = v
+	case json.Number:
+		parsed, err := v.Int64()
+		if err != nil {
+			return 0
+		}
+		delay = parsed
+	case string:
+		parsed, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0
+		}
+		delay = int64(parsed)
+	default:
+		return 0
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+	if delay > config.MaxHedgeAfterMs {
+		return config.MaxHedgeAfterMs
+	}
+	return int(delay)
+}
+
+// isHedgeEligiblePath reports whether duplicating the request is safe for the endpoint.
+func isHedgeEligiblePath(path, method string) bool {
+	if method != http.MethodPost && method != http.MethodGet {
+		return false
+	}
+	lowerPath := strings.ToLower(path)
+	for _, marker := range hedgeNonIdempotentPathMarkers {
+		if strings.Contains(lowerPath, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// hedgeDelayForRequest returns the hedge delay for the current attempt, or zero
+// when hedging must stay off. A sub-group setting wins over its aggregate parent.
+// Streaming requests are never hedged because the first response headers arrive
+// before the useful payload does.
+func hedgeDelayForRequest(c *gin.Context, originalGroup, group *models.Group, isStream bool) time.Duration {
+	if isStream || group == nil || c == nil || c.Request == nil {
+		return 0
+	}
+	delayMs := parseHedgeAfterMs(group.Config)
+	if delayMs <= 0 && originalGroup != nil && originalGroup.ID != group.ID {
+		delayMs = parseHedgeAfterMs(originalGroup.Config)
+	}
+	if delayMs <= 0 {
+		return 0
+	}
+	if !isHedgeEligiblePath(c.Request.URL.Path, c.Request.Method) {
+		return 0
+	}
+	// Forced-stream Responses and Codex mitigation keep a stream open upstream.
+	if isOpenAIResponseForcedStream(c) || codexDegradationMitigationEnabled(c) {
+		return 0
+	}
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+// selectHedgeKey picks an active key different from the primary attempt.
+func (ps *ProxyServer) selectHedgeKey(group *models.Group, primary *models.APIKey) *models.APIKey {
+	for range maxHedgeKeySelectAttempts {
+		candidate, err := ps.keyProvider.SelectKey(group.ID)
+		if err != nil {
+			return nil
+		}
+		if primary == nil || candidate.ID != primary.ID {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// newHedgeRequest clones the fully prepared primary request and swaps only the
+// key-dependent parts so both attempts send the same upstream payload.
+func (ps *ProxyServer) newHedgeRequest(c *gin.Context, ctx context.Context, primaryReq *http.Request, attempt hedgeAttempt, hedgeKey *models.APIKey) *http.Request {
+	hedgeReq := primaryReq.Clone(ctx)
+	body := attempt.bodyBytes
+	hedgeReq.Body = io.NopCloser(bytes.NewReader(body))
+	hedgeReq.ContentLength = int64(len(body))
+	hedgeReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	attempt.channelHandler.ModifyRequest(hedgeReq, hedgeKey, attempt.group)
+	if len(attempt.group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContextFromGin(c, attempt.group, hedgeKey)
+		utils.ApplyHeaderRules(hedgeReq, attempt.group.HeaderRuleList, headerCtx)
+	}
+	return hedgeReq
+}
+
+func isHedgeResultUsable(result hedgeAttemptResult, group *models.Group) bool {
+	return result.err == nil && result.resp != nil && !shouldFailoverOnStatusCode(result.resp.StatusCode, group)
+}
+
+// doHedgedRequest sends req and, if no response headers arrived within delay,
+// races a second attempt on another key of the same group. The first usable
+// response wins and the other attempt is cancelled. Only the discarded attempt
+// is logged as a hedge entry; the returned one is logged once by the normal
+// final/retry handling.
+func (ps *ProxyServer) doHedgedRequest(c *gin.Context, client *http.Client, req *http.Request, delay time.Duration, attempt hedgeAttempt) (*http.Response, *models.APIKey, error) {
+	results := make(chan hedgeAttemptResult, 2)
+	launch := func(attemptReq *http.Request, key *models.APIKey, isHedge bool, cancel context.CancelFunc) {
+		go func() {
+			resp, err := client.Do(attemptReq)
+			results <- hedgeAttemptResult{resp: resp, err: err, apiKey: key, isHedge: isHedge, cancel: cancel}
+		}()
+	}
+
+	primaryCtx, primaryCancel := context.WithCancel(req.Context())
+	launch(req.WithContext(primaryCtx), attempt.apiKey, false, primaryCancel)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var first hedgeAttemptResult
+	select {
+	case first = <-results:
+		// The primary answered before the hedge delay; no race is needed.
+		return finishHedgeAttempt(first)
+	case <-timer.C:
+	}
+
+	hedgeKey := ps.selectHedgeKey(attempt.group, attempt.apiKey)
+	if hedgeKey == nil {
+		logrus.WithField("group", attempt.group.Name).Debug("Request hedging skipped: no alternative key available")
+		return finishHedgeAttempt(<-results)
+	}
+
+	hedgeCtx, hedgeCancel := context.WithCancel(req.Context())
+	launch(ps.newHedgeRequest(c, hedgeCtx, req, attempt, hedgeKey), hedgeKey, true, hedgeCancel)
+	logrus.WithFields(logrus.Fields{
+		"group":    attempt.group.Name,
+		"delay_ms": delay.Milliseconds(),
+		"primary":  utils.MaskAPIKey(attempt.apiKey.KeyValue),
+		"hedge":    utils.MaskAPIKey(hedgeKey.KeyValue),
+	}).Debug("Request hedging: launched second attempt")
+
+	first = <-results
+	if isHedgeResultUsable(first, attempt.group) {
+		// Cancel the loser and wait for it so its connection is released and the
+		// log write happens on the request goroutine.
+		if first.isHedge {
+			primaryCancel()
+		} else {
+			hedgeCancel()
+		}
+		loser := <-results
+		ps.logHedgeAttempt(c, attempt, loser)
+		ps.releaseHedgeLoser(attempt, loser)
+		return finishHedgeAttempt(first)
+	}
+
+	second := <-results
+	if isHedgeResultUsable(second, attempt.group) {
+		ps.logHedgeAttempt(c, attempt, first)
+		ps.releaseHedgeLoser(attempt, first)
+		return finishHedgeAttempt(second)
+	}
+
+	// Both attempts failed: keep the primary failure for the regular retry path.
+	primary, hedge := first, second
+	if primary.isHedge {
+		primary, hedge = second, first
+	}
+	ps.logHedgeAttempt(c, attempt, hedge)
+	ps.releaseHedgeLoser(attempt, hedge)
+	return finishHedgeAttempt(primary)
+}
+
+// finishHedgeAttempt ties the attempt context to the response body lifetime.
+func finishHedgeAttempt(result hedgeAttemptResult) (*http.Response, *models.APIKey, error) {
+	if result.resp == nil {
+		result.cancel()
+		return nil, result.apiKey, result.err
+	}
+	result.resp.Body = &cancelOnCloseBody{ReadCloser: result.resp.Body, cancel: result.cancel}
+	return result.resp, result.apiKey, result.err
+}
+
+// releaseHedgeLoser closes a discarded attempt. Real upstream failures still
+// reach the blacklist logic; attempts cancelled by the race do not.
+func (ps *ProxyServer) releaseHedgeLoser(attempt hedgeAttempt, result hedgeAttemptResult) {
+	failed := !isHedgeResultUsable(result, attempt.group) && !isHedgeAttemptCancelled(result)
+	message := hedgeAttemptErrorMessage(result)
+	if result.resp != nil {
+		_ = result.resp.Body.Close()
+	}
+	result.cancel()
+	if failed && result.apiKey != nil {
+		ps.keyProvider.UpdateStatus(result.apiKey, attempt.group, false, message)
+	}
+}
+
+func isHedgeAttemptCancelled(result hedgeAttemptResult) bool {
+	return result.resp == nil && errors.Is(result.err, context.Canceled)
+}
+
+func hedgeAttemptStatus(result hedgeAttemptResult) int {
+	switch {
+	case isHedgeAttemptCancelled(result):
+		return statusClientClosedRequest
+	case result.err != nil:
+		return http.StatusInternalServerError
+	case result.resp != nil:
+		return result.resp.StatusCode
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func hedgeAttemptErrorMessage(result hedgeAttemptResult) string {
+	if isHedgeAttemptCancelled(result) {
+		return errHedgeAttemptLost.Error()
+	}
+	if result.err != nil {
+		return sanitizeInternalErrorMessage(result.err.Error())
+	}
+	if result.resp != nil {
+		return fmt.Sprintf("upstream returned status %d", result.resp.StatusCode)
+	}
+	return ""
+}
+
+// logHedgeAttempt records the discarded side of a hedged race with RequestTypeHedge.
+func (ps *ProxyServer) logHedgeAttempt(c *gin.Context, attempt hedgeAttempt, result hedgeAttemptResult) {
+	statusCode := hedgeAttemptStatus(result)
+	var finalErr error
+	if statusCode >= http.StatusBadRequest {
+		finalErr = errors.New(hedgeAttemptErrorMessage(result))
+	}
+	ps.logRequest(c, attempt.originalGroup, attempt.group, result.apiKey, attempt.startTime, statusCode, finalErr, false,
+		attempt.upstreamSelection.URL, attempt.upstreamSelection.ProxyURL, attempt.upstreamSelection.GatewayProxy,
+		attempt.channelHandler, attempt.bodyBytes, models.RequestTypeHedge)
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHedgeAfterMs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		cfg      map[string]any
+		expected int
+	}{
+		{name: "nil config", cfg: nil, expected: 0},
+		{name: "missing key", cfg: map[string]any{}, expected: 0},
+		{name: "float value", cfg: map[string]any{"hedge_after_ms": float64(250)}, expected: 250},
+		{name: "int value", cfg: map[string]any{"hedge_after_ms": 100}, expected: 100},
+		{name: "json number", cfg: map[string]any{"hedge_after_ms": json.Number("300")}, expected: 300},
+		{name: "string value", cfg: map[string]any{"hedge_after_ms": " 400 "}, expected: 400},
+		{name: "negative disables", cfg: map[string]any{"hedge_after_ms": -5}, expected: 0},
+		{name: "fractional fails closed", cfg: map[string]any{"hedge_after_ms": 1.5}, expected: 0},
+		{name: "invalid string fails closed", cfg: map[string]any{"hedge_after_ms": "soon"}, expected: 0},
+		{name: "clamped to maximum", cfg: map[string]any{"hedge_after_ms": float64(config.MaxHedgeAfterMs + 1)}, expected: config.MaxHedgeAfterMs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, parseHedgeAfterMs(tt.cfg))
+		})
+	}
+}
+
+func TestHedgeDelayForRequestEligibility(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	group := &models.Group{ID: 2, Config: map[string]any{"hedge_after_ms": float64(150)}}
+	parent := &models.Group{ID: 1, Config: map[string]any{"hedge_after_ms": float64(90)}}
+	plainSubGroup := &models.Group{ID: 3}
+
+	newContext := func(method, path string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(method, path, nil)
+		return c
+	}
+
+	assert.Equal(t, 150*time.Millisecond, hedgeDelayForRequest(newContext(http.MethodPost, "/proxy/g/v1/embeddings"), group, group, false))
+	assert.Zero(t, hedgeDelayForRequest(newContext(http.MethodPost, "/proxy/g/v1/chat/completions"), group, group, true), "streaming is never hedged")
+	assert.Zero(t, hedgeDelayForRequest(newContext(http.MethodPost, "/proxy/g/v1/images/generations"), group, group, false), "media endpoints are not idempotent")
+	assert.Zero(t, hedgeDelayForRequest(newContext(http.MethodPost, "/proxy/g/v1/audio/transcriptions"), group, group, false), "media endpoints are not idempotent")
+	assert.Zero(t, hedgeDelayForRequest(newContext(http.MethodDelete, "/proxy/g/v1/models/x"), group, group, false), "only GET and POST are hedged")
+	assert.Equal(t, 150*time.Millisecond, hedgeDelayForRequest(newContext(http.MethodPost, "/proxy/g/v1/moderations"), parent, group, false), "sub-group setting wins")
+	assert.Equal(t, 90*time.Millisecond, hedgeDelayForRequest(newContext(http.MethodPost, "/proxy/g/v1/moderations"), parent, plainSubGroup, false), "aggregate parent applies as fallback")
+
+	forced := newContext(http.MethodPost, "/proxy/g/v1/responses")
+	forced.Set(ctxKeyOpenAIResponseForcedStream, true)
+	assert.Zero(t, hedgeDelayForRequest(forced, group, group, false), "forced upstream streaming is never hedged")
+}
+
+func TestExecuteRequestWithRetryHedgesSlowPrimaryOnAnotherKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+
+	group := createTestGroup(t, db, "hedge-standard", "openai")
+	group.Config = map[string]any{"hedge_after_ms": float64(50)}
+	group.EffectiveConfig = systemSettingsWithRetryTimeout(0, 5)
+	createTestKey(t, db, group.ID, "sk-hedge-slow", ps.encryptionSvc)
+	createTestKey(t, db, group.ID, "sk-hedge-fast", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+
+	var slowCancelled atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drain the body so the server watches the connection for client cancellation.
+		_, _ = io.Copy(io.Discard, r.Body)
+		if r.Header.Get("Authorization") == "Bearer sk-hedge-slow" {
+			select {
+			case <-r.Context().Done():
+				slowCancelled.Store(true)
+			case <-time.After(3 * time.Second):
+				_, _ = io.WriteString(w, `{"winner":"slow"}`)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"winner":"fast"}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	handler := &hedgeTestChannelProxy{testChannelProxy: testChannelProxy{client: upstream.Client(), url: upstream.URL}}
+	body := []byte(`{"model":"text-embedding-3-small","input":"hello"}`)
+
+	// Key rotation sends one of the two requests to the slow key first, which must be hedged.
+	for range 2 {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/hedge-standard/v1/embeddings", bytes.NewReader(body))
+
+		start := time.Now()
+		ps.executeRequestWithRetry(c, handler, group, group, body, false, start, 0)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"winner":"fast"}`, w.Body.String())
+		assert.Less(t, time.Since(start), 2*time.Second)
+	}
+
+	assert.Eventually(t, slowCancelled.Load, time.Second, 10*time.Millisecond, "losing attempt must be cancelled")
+
+	var hedgeLogs, finalLogs int
+	keys, err := memStore.SPopN(services.PendingLogKeysSet, 100)
+	require.NoError(t, err)
+	for _, key := range keys {
+		logBytes, err := memStore.Get(key)
+		require.NoError(t, err)
+		var entry models.RequestLog
+		require.NoError(t, json.Unmarshal(logBytes, &entry))
+		switch entry.RequestType {
+		case models.RequestTypeHedge:
+			hedgeLogs++
+		case models.RequestTypeFinal:
+			finalLogs++
+			assert.True(t, entry.IsSuccess)
+		}
+	}
+	assert.Equal(t, 2, finalLogs)
+	assert.Equal(t, 1, hedgeLogs, "only the cancelled attempt is logged as a hedge; the winner is the final log")
+}
+
+func TestExecuteRequestWithRetrySkipsHedgeWhenPrimaryIsFast(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps := setupTestProxyServer(t, db)
+
+	group := createTestGroup(t, db, "hedge-fast-primary", "openai")
+	group.Config = map[string]any{"hedge_after_ms": float64(500)}
+	group.EffectiveConfig = systemSettingsWithRetryTimeout(0, 5)
+	createTestKey(t, db, group.ID, "sk-hedge-primary-1", ps.encryptionSvc)
+	createTestKey(t, db, group.ID, "sk-hedge-primary-2", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+
+	var attempts atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := []byte(`{"model":"omni-moderation-latest","input":"hi"}`)
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/hedge-fast-primary/v1/moderations", bytes.NewReader(body))
+
+	ps.executeRequestWithRetry(c, &hedgeTestChannelProxy{testChannelProxy: testChannelProxy{client: upstream.Client(), url: upstream.URL}}, group, group, body, false, time.Now(), 0)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(1), attempts.Load())
+	assert.True(t, strings.Contains(w.Body.String(), "ok"))
+}
+
+// hedgeTestChannelProxy sets the key header so the upstream stub can tell attempts apart.
+type hedgeTestChannelProxy struct {
+	testChannelProxy
+}
+
+func (p *hedgeTestChannelProxy) ModifyRequest(req *http.Request, apiKey *models.APIKey, _ *models.Group) {
+	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+}
@@ -37,6 +37,7 @@ func setModelRedirectContext(c *gin.Context, originalModel string, targetIdx int
 	// Keep redirect metrics independent from original_model, which is also used
 	// for model-mapping log output and may contain a user-facing alias.
 	c.Set(ctxKeyModelRedirectSourceModel, originalModel)
+	delete(c.Keys, ctxKeyModelRedirectTargetModel)
 	if targetIdx >= 0 {
 		c.Set(ctxKeyModelRedirectTargetIndex, targetIdx)
 	} else {
@@ -84,6 +85,7 @@ func clearModelRedirectContext(c *gin.Context) {
 	delete(c.Keys, "original_model")
 	delete(c.Keys, ctxKeyModelRedirectSourceModel)
 	delete(c.Keys, ctxKeyModelRedirectTargetIndex)
+	delete(c.Keys, ctxKeyModelRedirectTargetModel)
 }
 
 // modelListRedirectLogModels returns display-only model labels for /models logs.
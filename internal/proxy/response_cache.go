@@ -0,0 +1,298 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	defaultResponseCacheTTL        = 5 * time.Minute
+	defaultResponseCacheMaxEntries = 1000
+	// maxResponseCacheBodyBytes skips caching responses larger than this.
+	maxResponseCacheBodyBytes = 1 << 20
+
+	responseCacheKeyPrefix   = "response_cache:"
+	responseCacheIndexPrefix = "response_cache_index:"
+
+	responseCacheHeader = "X-Cache"
+)
+
+// responseCacheConfig is the parsed response cache config of a group.
+type responseCacheConfig struct {
+	ttl        time.Duration
+	maxEntries int
+}
+
+// cachedResponse is the value stored for a cached upstream response.
+type cachedResponse struct {
+	StatusCode      int    `json:"status_code"`
+	ContentType     string `json:"content_type,omitempty"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+	Body            []byte `json:"body"`
+}
+
+// responseCacheWriter tees the client response so it can be cached after the
+// request completes. Capture stops once the body exceeds the cache size limit.
+type responseCacheWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *responseCacheWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseCacheWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *responseCacheWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(data) > maxResponseCacheBodyBytes {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(data)
+}
+
+// parseResponseCacheConfig reads the response cache config of a group.
+// Returns false when caching is disabled.
+func parseResponseCacheConfig(group *models.Group) (*responseCacheConfig, bool) {
+	if !getGroupConfigBool(group, "response_cache_enabled") {
+		return nil, false
+	}
+	cfg := &responseCacheConfig{
+		ttl:        defaultResponseCacheTTL,
+		maxEntries: defaultResponseCacheMaxEntries,
+	}
+	if seconds, ok := positiveConfigInt(group.Config, "response_cache_ttl_seconds"); ok {
+		cfg.ttl = time.Duration(seconds) * time.Second
+	}
+	if entries, ok := positiveConfigInt(group.Config, "response_cache_max_entries"); ok {
+		cfg.maxEntries = entries
+	}
+	return cfg, true
+}
+
+// responseCacheGenerationMarkers identify endpoints that sample text from a model.
+var responseCacheGenerationMarkers = []string{
+	"/chat/completions",
+	"/completions",
+	"/responses",
+	"/messages",
+	":generatecontent",
+}
+
+// isResponseCacheable reports whether the request is eligible for caching.
+// Only non-streaming GET and POST requests are cached. Text generation is only
+// cached when it is greedy: temperature explicitly 0 (the provider default
+// samples), no nucleus sampling and a single choice. A seed alone does not make
+// sampling deterministic, so it does not replace temperature 0. Other requests,
+// such as embeddings and moderations, are deterministic.
+func isResponseCacheable(c *gin.Context, bodyBytes []byte, isStream bool) bool {
+	if isStream {
+		return false
+	}
+	switch c.Request.Method {
+	case http.MethodGet:
+		return true
+	case http.MethodPost:
+	default:
+		return false
+	}
+	if len(bodyBytes) == 0 || !gjson.ValidBytes(bodyBytes) {
+		return false
+	}
+	if !isGenerationPath(c.Request.URL.Path) {
+		return true
+	}
+	return isGreedySampling(bodyBytes)
+}
+
+func isGenerationPath(path string) bool {
+	lowerPath := strings.ToLower(path)
+	for _, marker := range responseCacheGenerationMarkers {
+		if strings.Contains(lowerPath, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGreedySampling reports whether an OpenAI, Anthropic or Gemini body asks
+// for a single greedy completion.
+func isGreedySampling(bodyBytes []byte) bool {
+	fields := gjson.GetManyBytes(bodyBytes,
+		"temperature", "generationConfig.temperature",
+		"top_p", "generationConfig.topP",
+		"n", "generationConfig.candidateCount")
+	temperature := fields[0]
+	if !temperature.Exists() {
+		temperature = fields[1]
+	}
+	if temperature.Type != gjson.Number || temperature.Float() != 0 {
+		return false
+	}
+	for _, topP := range fields[2:4] {
+		if topP.Exists() && (topP.Type != gjson.Number || topP.Float() != 1) {
+			return false
+		}
+	}
+	for _, choices := range fields[4:6] {
+		if choices.Exists() && (choices.Type != gjson.Number || choices.Int() != 1) {
+			return false
+		}
+	}
+	return true
+}
+
+// responseCacheDirectives reads the client Cache-Control header. no-cache skips
+// the lookup but still refreshes the cache; no-store bypasses the cache entirely.
+func responseCacheDirectives(c *gin.Context) (noCache, noStore bool) {
+	for _, directive := range strings.Split(c.GetHeader("Cache-Control"), ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache":
+			noCache = true
+		case "no-store":
+			noCache = true
+			noStore = true
+		}
+	}
+	return noCache, noStore
+}
+
+// responseCacheKey hashes the group, endpoint, model and a normalized request body.
+// JSON bodies are re-encoded so key order and whitespace do not split cache entries.
+func responseCacheKey(c *gin.Context, group *models.Group, bodyBytes []byte) string {
+	h := sha256.New()
+	writeField := func(value string) {
+		_, _ = io.WriteString(h, strconv.Itoa(len(value)))
+		_, _ = io.WriteString(h, ":")
+		_, _ = io.WriteString(h, value)
+	}
+	writeField(strconv.FormatUint(uint64(group.ID), 10))
+	writeField(c.Request.Method)
+	writeField(c.Request.URL.Path)
+	writeField(c.Request.URL.Query().Encode())
+	writeField(modelFromRequestBody(bodyBytes))
+	writeField(string(normalizeResponseCacheBody(bodyBytes)))
+	return responseCacheKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+func normalizeResponseCacheBody(bodyBytes []byte) []byte {
+	if len(bodyBytes) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+	var payload any
+	if err := decoder.Decode(&payload); err != nil {
+		return bodyBytes
+	}
+	normalized, err := json.Marshal(payload)
+	if err != nil {
+		return bodyBytes
+	}
+	return normalized
+}
+
+// serveCachedResponse writes a cached response when one exists.
+func (ps *ProxyServer) serveCachedResponse(c *gin.Context, group *models.Group, cacheKey string) bool {
+	raw, err := ps.store.Get(cacheKey)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logrus.WithError(err).WithField("group", group.Name).Warn("Failed to load cached response")
+		}
+		return false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(raw, &cached); err != nil || cached.StatusCode == 0 {
+		return false
+	}
+
+	if cached.ContentType != "" {
+		c.Header("Content-Type", cached.ContentType)
+	}
+	if cached.ContentEncoding != "" {
+		c.Header("Content-Encoding", cached.ContentEncoding)
+	}
+	c.Header(responseCacheHeader, "HIT")
+	c.Status(cached.StatusCode)
+	if _, err := c.Writer.Write(cached.Body); err != nil {
+		logUpstreamError("writing cached response body", err)
+	}
+	if group.EffectiveConfig.EnableRequestBodyLogging && cached.ContentEncoding == "" {
+		c.Set("response_body", sanitizeAndTruncateBytesForLog(cached.Body, maxResponseCaptureBytes))
+	}
+	return true
+}
+
+// storeCachedResponse caches a successful response captured by writer.
+func (ps *ProxyServer) storeCachedResponse(c *gin.Context, group *models.Group, cfg *responseCacheConfig, cacheKey string, writer *responseCacheWriter) {
+	if writer.overflow || writer.Status() != http.StatusOK || writer.body.Len() == 0 {
+		return
+	}
+	if strings.Contains(writer.Header().Get("Content-Type"), "text/event-stream") {
+		return
+	}
+	_, _, logicalFailure := logicalStatusFromContext(c)
+	_, statusUnverified := c.Get(ctxKeyResponsesStatusUnverified)
+	_, processingFailed := c.Get(ctxKeyResponseProcessingFailed)
+	if logicalFailure || statusUnverified || processingFailed {
+		return
+	}
+
+	exists, err := ps.store.Exists(cacheKey)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to check response cache entry")
+		return
+	}
+	if !exists {
+		admitted, err := ps.admitStoreEntry(responseCacheIndexPrefix, group.ID, cfg.ttl, cfg.maxEntries, cacheKey)
+		if err != nil {
+			logrus.WithError(err).WithField("group", group.Name).Warn("Failed to check response cache capacity")
+			return
+		}
+		if !admitted {
+			logrus.WithFields(logrus.Fields{
+				"group":       group.Name,
+				"max_entries": cfg.maxEntries,
+			}).Debug("Response cache entry limit reached, not caching response")
+			return
+		}
+	}
+
+	value, err := json.Marshal(cachedResponse{
+		StatusCode:      writer.Status(),
+		ContentType:     writer.Header().Get("Content-Type"),
+		ContentEncoding: writer.Header().Get("Content-Encoding"),
+		Body:            writer.body.Bytes(),
+	})
+	if err != nil {
+		return
+	}
+	if err := ps.store.Set(cacheKey, value, cfg.ttl); err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to save cached response")
+	}
+}
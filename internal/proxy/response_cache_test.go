@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsResponseCacheable(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		isStream bool
+		expected bool
+	}{
+		{name: "model list", method: http.MethodGet, path: "/proxy/g/v1/models", expected: true},
+		{name: "zero temperature", method: http.MethodPost, body: `{"model":"m","temperature":0}`, expected: true},
+		{name: "zero temperature with top_p 1 and one choice", method: http.MethodPost, body: `{"model":"m","temperature":0,"top_p":1,"n":1}`, expected: true},
+		{name: "embeddings", method: http.MethodPost, path: "/proxy/g/v1/embeddings", body: `{"model":"e","input":"text"}`, expected: true},
+		{name: "default temperature", method: http.MethodPost, body: `{"model":"m","messages":[]}`, expected: false},
+		{name: "sampling temperature", method: http.MethodPost, body: `{"model":"m","temperature":0.7}`, expected: false},
+		{name: "seed without zero temperature", method: http.MethodPost, body: `{"model":"m","temperature":0.7,"seed":1}`, expected: false},
+		{name: "nucleus sampling", method: http.MethodPost, body: `{"model":"m","temperature":0,"top_p":0.9}`, expected: false},
+		{name: "several choices", method: http.MethodPost, body: `{"model":"m","temperature":0,"n":2}`, expected: false},
+		{name: "gemini zero temperature", method: http.MethodPost, path: "/proxy/g/v1beta/models/gemini:generateContent", body: `{"generationConfig":{"temperature":0}}`, expected: true},
+		{name: "gemini sampling temperature", method: http.MethodPost, path: "/proxy/g/v1beta/models/gemini:generateContent", body: `{"generationConfig":{"temperature":1}}`, expected: false},
+		{name: "gemini several candidates", method: http.MethodPost, path: "/proxy/g/v1beta/models/gemini:generateContent", body: `{"generationConfig":{"temperature":0,"candidateCount":2}}`, expected: false},
+		{name: "anthropic default temperature", method: http.MethodPost, path: "/proxy/g/v1/messages", body: `{"model":"claude","messages":[]}`, expected: false},
+		{name: "stream", method: http.MethodPost, body: `{"model":"m","temperature":0}`, isStream: true, expected: false},
+		{name: "invalid json", method: http.MethodPost, body: `not json`, expected: false},
+		{name: "delete", method: http.MethodDelete, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			path := tt.path
+			if path == "" {
+				path = "/proxy/g/v1/chat/completions"
+			}
+			c.Request = httptest.NewRequest(tt.method, path, nil)
+			assert.Equal(t, tt.expected, isResponseCacheable(c, []byte(tt.body), tt.isStream))
+		})
+	}
+}
+
+func TestResponseCacheKeyNormalizesBody(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	keyFor := func(groupID uint, body string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/g/v1/embeddings", nil)
+		return responseCacheKey(c, &models.Group{ID: groupID}, []byte(body))
+	}
+
+	base := keyFor(1, `{"model":"e","input":"text"}`)
+	assert.Equal(t, base, keyFor(1, "{\n  \"input\": \"text\",\n  \"model\": \"e\"\n}"), "key order and whitespace are ignored")
+	assert.NotEqual(t, base, keyFor(1, `{"model":"e2","input":"text"}`))
+	assert.NotEqual(t, base, keyFor(2, `{"model":"e","input":"text"}`))
+}
+
+func TestHandleProxyServesCachedResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+
+	var upstreamCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		n := upstreamCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"resp-`+strconv.Itoa(int(n))+`","usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	group := createTestGroup(t, db, "response-cache", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.Config = map[string]any{"response_cache_enabled": true}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-response-cache", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	send := func(body, cacheControl string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/response-cache/v1/chat/completions", bytes.NewReader([]byte(body)))
+		if cacheControl != "" {
+			c.Request.Header.Set("Cache-Control", cacheControl)
+		}
+		c.Params = gin.Params{{Key: "group_name", Value: group.Name}}
+		ps.HandleProxy(c)
+		return w
+	}
+
+	first := send(`{"model":"m","temperature":0,"messages":[]}`, "")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+
+	second := send(`{"messages":[],"temperature":0,"model":"m"}`, "")
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+
+	third := send(`{"model":"m","temperature":0,"messages":[]}`, "no-cache")
+	assert.Equal(t, "MISS", third.Header().Get("X-Cache"))
+	assert.Equal(t, int32(2), upstreamCalls.Load())
+
+	refreshed := send(`{"model":"m","temperature":0,"messages":[]}`, "")
+	assert.Equal(t, "HIT", refreshed.Header().Get("X-Cache"))
+	assert.Equal(t, third.Body.String(), refreshed.Body.String(), "no-cache refreshes the cached entry")
+
+	keys, err := memStore.SPopN(services.PendingLogKeysSet, 100)
+	require.NoError(t, err)
+	var cacheHits, finals int
+	for _, key := range keys {
+		logBytes, err := memStore.Get(key)
+		require.NoError(t, err)
+		var entry models.RequestLog
+		require.NoError(t, json.Unmarshal(logBytes, &entry))
+		switch entry.RequestType {
+		case models.RequestTypeCacheHit:
+			cacheHits++
+			assert.True(t, entry.IsSuccess)
+			assert.Zero(t, entry.TotalTokens)
+			assert.Empty(t, entry.KeyHash)
+		case models.RequestTypeFinal:
+			finals++
+			assert.Equal(t, int64(4), entry.TotalTokens)
+		}
+	}
+	assert.Equal(t, 2, cacheHits)
+	assert.Equal(t, 2, finals)
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"gpt-load/internal/models"
+	"gpt-load/internal/redaction"
 	"gpt-load/internal/tokenusage"
 	"gpt-load/internal/utils"
 
@@ -353,6 +354,17 @@ func sanitizeAndTruncateBytesForLog(value []byte, limit int) string {
 	return sanitizeAndTruncateStringForLog(string(value), limit)
 }
 
+// logRedactor returns the compiled redaction rules for the group's logged bodies.
+// Rules are validated when saved, so a compile error only disables redaction.
+func logRedactor(group *models.Group) *redaction.Redactor {
+	redactor, err := redaction.Compile(group.EffectiveConfig.LogRedactionRules)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Ignoring invalid log redaction rules")
+		return nil
+	}
+	return redactor
+}
+
 func decodeResponseBodyForLog(resp *http.Response, body []byte) ([]byte, bool) {
 	if resp == nil || len(body) == 0 {
 		return body, true
@@ -594,6 +606,10 @@ func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Respon
 }
 
 func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response) {
+	if ps.blockGuardrailResponse(c, resp) {
+		return
+	}
+
 	// Check if response body capturing is enabled
 	shouldCapture := shouldCaptureResponse(c)
 	contentEncoding := strings.TrimSpace(resp.Header.Get("Content-Encoding"))
@@ -1044,3 +1060,29 @@ readLoop:
 
 	return finalResp, nil
 }
+
+// ReturnOpenAIError writes an error in the OpenAI API shape, with the error
+// type derived from the status code.
+func ReturnOpenAIError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{"error": gin.H{
+		"code":    code,
+		"message": message,
+		"type":    openAIErrorType(status),
+	}})
+}
+
+// openAIErrorType maps a status code to an OpenAI error type.
+func openAIErrorType(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "authentication_error"
+	case status == http.StatusNotFound:
+		return "not_found_error"
+	case status == http.StatusBadRequest:
+		return "invalid_request_error"
+	case status >= 500:
+		return "server_error"
+	default:
+		return "api_error"
+	}
+}
@@ -0,0 +1,478 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	defaultSemanticCacheThreshold      = 0.95
+	defaultSemanticCacheTTL            = time.Hour
+	defaultSemanticCacheEmbeddingModel = "text-embedding-3-small"
+	semanticCacheEmbeddingTimeout      = 10 * time.Second
+	// maxSemanticCacheResponseBytes keeps stored completions within a TEXT column on every database.
+	maxSemanticCacheResponseBytes = 60000
+	// maxSemanticCacheCaptureBytes bounds the upstream body captured for a cache miss.
+	maxSemanticCacheCaptureBytes = 1 << 20
+
+	semanticCacheHeader = "X-Semantic-Cache"
+	// semanticCacheBypassHeader skips the semantic cache lookup and store when set to a truthy value.
+	semanticCacheBypassHeader = "X-Semantic-Cache-Bypass"
+
+	ctxKeySemanticCacheMiss = "semantic_cache_miss"
+)
+
+// semanticCacheIgnoredParams are request fields that do not change the completion
+// and are left out of the params hash.
+var semanticCacheIgnoredParams = []string{"stream", "stream_options", "user", "metadata"}
+
+// semanticCacheConfig is the parsed semantic cache config of a group.
+type semanticCacheConfig struct {
+	embeddingGroup string
+	embeddingModel string
+	threshold      float64
+	ttl            time.Duration
+}
+
+// semanticCacheMiss is kept in the request context after a miss so a successful
+// upstream completion can be stored under the already computed embedding.
+type semanticCacheMiss struct {
+	group      *models.Group
+	cfg        *semanticCacheConfig
+	paramsHash string
+	vector     []float32
+}
+
+// semanticCacheCapture tees the upstream response body of a cache miss.
+type semanticCacheCapture struct {
+	miss     *semanticCacheMiss
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (capture *semanticCacheCapture) Write(data []byte) (int, error) {
+	if !capture.overflow {
+		if capture.body.Len()+len(data) > maxSemanticCacheCaptureBytes {
+			capture.overflow = true
+			capture.body = bytes.Buffer{}
+		} else {
+			capture.body.Write(data)
+		}
+	}
+	return len(data), nil
+}
+
+// parseSemanticCacheConfig reads the semantic cache config of a standard OpenAI group.
+// Returns false when the cache is disabled or no embedding group is configured.
+func parseSemanticCacheConfig(group *models.Group) (*semanticCacheConfig, bool) {
+	if group == nil || group.GroupType == "aggregate" || group.ChannelType != "openai" ||
+		!getGroupConfigBool(group, "semantic_cache_enabled") {
+		return nil, false
+	}
+	cfg := &semanticCacheConfig{
+		embeddingGroup: getGroupConfigString(group, "semantic_cache_embedding_group"),
+		embeddingModel: getGroupConfigString(group, "semantic_cache_embedding_model"),
+		threshold:      defaultSemanticCacheThreshold,
+		ttl:            defaultSemanticCacheTTL,
+	}
+	if cfg.embeddingGroup == "" {
+		return nil, false
+	}
+	if cfg.embeddingModel == "" {
+		cfg.embeddingModel = defaultSemanticCacheEmbeddingModel
+	}
+	if threshold, ok := group.Config["semantic_cache_threshold"].(float64); ok &&
+		threshold > 0 && threshold <= 1 && !math.IsNaN(threshold) {
+		cfg.threshold = threshold
+	}
+	if seconds, ok := positiveConfigInt(group.Config, "semantic_cache_ttl_seconds"); ok {
+		cfg.ttl = time.Duration(seconds) * time.Second
+	}
+	return cfg, true
+}
+
+// semanticCachePrompt returns the text that is embedded for a chat completion
+// request: the system prompt followed by the last user message. The rest of the
+// conversation must match exactly through the params hash.
+func semanticCachePrompt(bodyBytes []byte) string {
+	var system []string
+	var lastUser string
+	gjson.GetBytes(bodyBytes, "messages").ForEach(func(_, message gjson.Result) bool {
+		text := chatMessageText(message.Get("content"))
+		switch message.Get("role").String() {
+		case "system", "developer":
+			if text != "" {
+				system = append(system, text)
+			}
+		case "user":
+			lastUser = text
+		}
+		return true
+	})
+	if lastUser == "" {
+		return ""
+	}
+	if len(system) == 0 {
+		return lastUser
+	}
+	return strings.Join(system, "\n") + "\n\n" + lastUser
+}
+
+// chatMessageText flattens string content and text content parts.
+func chatMessageText(content gjson.Result) string {
+	if content.Type == gjson.String {
+		return strings.TrimSpace(content.Str)
+	}
+	var parts []string
+	content.ForEach(func(_, part gjson.Result) bool {
+		if part.Get("type").String() == "text" {
+			if text := strings.TrimSpace(part.Get("text").String()); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return true
+	})
+	return strings.Join(parts, "\n")
+}
+
+// semanticCacheParamsHash hashes every request field except the last user
+// message, which is matched by its embedding instead. A cached completion is
+// therefore only reused for the same model, tools, sampling settings and
+// conversation history, so short replies such as "continue" in different
+// conversations do not share an answer.
+func semanticCacheParamsHash(bodyBytes []byte) string {
+	params := bodyBytes
+	for _, field := range semanticCacheIgnoredParams {
+		if updated, err := sjson.DeleteBytes(params, field); err == nil {
+			params = updated
+		}
+	}
+	if updated, err := sjson.SetRawBytes(params, "messages", semanticCacheHistory(bodyBytes)); err == nil {
+		params = updated
+	}
+	sum := sha256.Sum256(normalizeResponseCacheBody(params))
+	return hex.EncodeToString(sum[:])
+}
+
+// semanticCacheHistory returns the messages of a chat completion request
+// without the last user message, as a JSON array.
+func semanticCacheHistory(bodyBytes []byte) []byte {
+	messages := gjson.GetBytes(bodyBytes, "messages").Array()
+	lastUser := -1
+	for i, message := range messages {
+		if message.Get("role").String() == "user" {
+			lastUser = i
+		}
+	}
+	var history bytes.Buffer
+	history.WriteByte('[')
+	for i, message := range messages {
+		if i == lastUser {
+			continue
+		}
+		if history.Len() > 1 {
+			history.WriteByte(',')
+		}
+		history.WriteString(message.Raw)
+	}
+	history.WriteByte(']')
+	return history.Bytes()
+}
+
+// isSemanticCacheBypassed reports whether the client asked to skip the semantic cache.
+func isSemanticCacheBypassed(c *gin.Context) bool {
+	value := strings.ToLower(strings.TrimSpace(c.GetHeader(semanticCacheBypassHeader)))
+	return value == "1" || value == "true" || value == "yes"
+}
+
+// serveSemanticCache looks up a semantically similar cached completion and replays
+// it in the client's format. On a miss the embedding is kept in the context so the
+// upstream completion can be stored once it succeeds.
+func (ps *ProxyServer) serveSemanticCache(c *gin.Context, group *models.Group, channelHandler channel.ChannelProxy, bodyBytes []byte, isStream bool, startTime time.Time) bool {
+	cfg, ok := parseSemanticCacheConfig(group)
+	if !ok || ps.semanticCache == nil || !isChatCompletionsEndpoint(c.Request.URL.Path, c.Request.Method) ||
+		isFunctionCallEnabled(c) || isSemanticCacheBypassed(c) {
+		return false
+	}
+	prompt := semanticCachePrompt(bodyBytes)
+	if prompt == "" {
+		return false
+	}
+
+	vector, err := ps.embedSemanticCachePrompt(c.Request.Context(), cfg, prompt)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"group":           group.Name,
+			"embedding_group": cfg.embeddingGroup,
+		}).Warn("Semantic cache embedding failed, skipping cache")
+		return false
+	}
+
+	paramsHash := semanticCacheParamsHash(bodyBytes)
+	entry, score, err := ps.semanticCache.Lookup(group.ID, paramsHash, vector, cfg.threshold)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Semantic cache lookup failed")
+		return false
+	}
+	if entry == nil {
+		c.Header(semanticCacheHeader, "MISS")
+		c.Set(ctxKeySemanticCacheMiss, &semanticCacheMiss{group: group, cfg: cfg, paramsHash: paramsHash, vector: vector})
+		return false
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"group":      group.Name,
+		"similarity": score,
+	}).Debug("Serving chat completion from semantic cache")
+	c.Header(semanticCacheHeader, "HIT")
+	c.Set("group", group)
+	ps.replaySemanticCacheEntry(c, []byte(entry.Response), isStream)
+	ps.logRequest(c, group, group, nil, startTime, c.Writer.Status(), nil, isStream, "", nil, "", channelHandler, bodyBytes, models.RequestTypeCacheHit)
+	return true
+}
+
+// replaySemanticCacheEntry feeds a cached OpenAI chat completion through the same
+// response handlers as a live upstream response, so CC and Codex clients receive
+// their own format. Streaming requests get the completion as synthetic SSE chunks.
+func (ps *ProxyServer) replaySemanticCacheEntry(c *gin.Context, completion []byte, isStream bool) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(completion)),
+		Request:    c.Request,
+	}
+	resp.Header.Set("Content-Type", "application/json")
+	if isStream {
+		resp.Header.Set("Content-Type", "text/event-stream")
+		resp.Body = io.NopCloser(bytes.NewReader(chatCompletionToSSE(completion)))
+	}
+
+	if !isCCEnabled(c) && !isCodexEnabled(c) {
+		c.Header("Content-Type", resp.Header.Get("Content-Type"))
+	}
+	c.Status(http.StatusOK)
+
+	switch {
+	case isStream && isCCEnabled(c):
+		ps.handleCCStreamingResponse(c, resp)
+	case isStream && isCodexEnabled(c):
+		ps.handleForceCodexStreamingResponse(c, resp)
+	case isStream:
+		ps.handleStreamingResponse(c, resp)
+	case isCCEnabled(c):
+		ps.handleCCNormalResponse(c, resp)
+	case isCodexEnabled(c):
+		ps.handleForceCodexNormalResponse(c, resp)
+	default:
+		ps.handleNormalResponse(c, resp)
+	}
+}
+
+// chatCompletionToSSE converts a chat completion into chat.completion.chunk events:
+// one chunk with the full message, one with the finish reason and usage, and [DONE].
+func chatCompletionToSSE(completion []byte) []byte {
+	parsed := gjson.ParseBytes(completion)
+	base := map[string]any{
+		"id":      parsed.Get("id").String(),
+		"object":  "chat.completion.chunk",
+		"created": parsed.Get("created").Int(),
+		"model":   parsed.Get("model").String(),
+	}
+	if base["created"] == int64(0) {
+		base["created"] = time.Now().Unix()
+	}
+
+	var buf bytes.Buffer
+	writeChunk := func(choices []any, usage any) {
+		chunk := make(map[string]any, len(base)+2)
+		for k, v := range base {
+			chunk[k] = v
+		}
+		chunk["choices"] = choices
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return
+		}
+		buf.WriteString("data: ")
+		buf.Write(data)
+		buf.WriteString("\n\n")
+	}
+
+	var deltas, finishes []any
+	parsed.Get("choices").ForEach(func(_, choice gjson.Result) bool {
+		index := choice.Get("index").Int()
+		delta := map[string]any{"role": "assistant"}
+		if content := choice.Get("message.content"); content.Exists() {
+			delta["content"] = content.Value()
+		}
+		if toolCalls := choice.Get("message.tool_calls"); toolCalls.IsArray() {
+			calls := make([]any, 0, len(toolCalls.Array()))
+			for i, call := range toolCalls.Array() {
+				value, ok := call.Value().(map[string]any)
+				if !ok {
+					continue
+				}
+				value["index"] = i
+				calls = append(calls, value)
+			}
+			delta["tool_calls"] = calls
+		}
+		deltas = append(deltas, map[string]any{"index": index, "delta": delta, "finish_reason": nil})
+		finishes = append(finishes, map[string]any{"index": index, "delta": map[string]any{}, "finish_reason": choice.Get("finish_reason").Value()})
+		return true
+	})
+	writeChunk(deltas, nil)
+	writeChunk(finishes, parsed.Get("usage").Value())
+	buf.WriteString("data: [DONE]\n\n")
+	return buf.Bytes()
+}
+
+// sseToChatCompletion assembles a streamed text completion into a chat completion.
+// Streams with tool calls or multiple choices are not assembled.
+func sseToChatCompletion(stream []byte) ([]byte, bool) {
+	var id, model, finishReason string
+	var created int64
+	var usage any
+	var content strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSemanticCacheCaptureBytes)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		chunk := gjson.Parse(data)
+		if id == "" {
+			id, model, created = chunk.Get("id").String(), chunk.Get("model").String(), chunk.Get("created").Int()
+		}
+		if u := chunk.Get("usage"); u.IsObject() {
+			usage = u.Value()
+		}
+		for _, choice := range chunk.Get("choices").Array() {
+			if choice.Get("index").Int() != 0 || choice.Get("delta.tool_calls").Exists() {
+				return nil, false
+			}
+			content.WriteString(choice.Get("delta.content").String())
+			if reason := choice.Get("finish_reason").String(); reason != "" {
+				finishReason = reason
+			}
+		}
+	}
+	if scanner.Err() != nil || finishReason == "" {
+		return nil, false
+	}
+
+	completion := map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": created,
+		"model":   model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": content.String()},
+			"finish_reason": finishReason,
+		}},
+	}
+	if usage != nil {
+		completion["usage"] = usage
+	}
+	data, err := json.Marshal(completion)
+	return data, err == nil
+}
+
+// captureSemanticCacheResponse tees the upstream body of a semantic cache miss.
+// Returns nil when the request did not miss the semantic cache.
+func captureSemanticCacheResponse(c *gin.Context, resp *http.Response) *semanticCacheCapture {
+	value, ok := c.Get(ctxKeySemanticCacheMiss)
+	if !ok {
+		return nil
+	}
+	miss, ok := value.(*semanticCacheMiss)
+	if !ok || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" ||
+		codexDegradationMitigationEnabled(c) {
+		return nil
+	}
+	capture := &semanticCacheCapture{miss: miss}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, capture), resp.Body}
+	return capture
+}
+
+// storeSemanticCacheResponse stores a captured completion after the response was
+// delivered successfully.
+func (ps *ProxyServer) storeSemanticCacheResponse(c *gin.Context, capture *semanticCacheCapture, isStream bool) {
+	if capture == nil || capture.overflow || ps.semanticCache == nil {
+		return
+	}
+	_, _, logicalFailure := logicalStatusFromContext(c)
+	_, processingFailed := c.Get(ctxKeyResponseProcessingFailed)
+	if logicalFailure || processingFailed {
+		return
+	}
+
+	completion := capture.body.Bytes()
+	if isStream {
+		assembled, ok := sseToChatCompletion(completion)
+		if !ok {
+			return
+		}
+		completion = assembled
+	}
+	if len(completion) > maxSemanticCacheResponseBytes || !gjson.GetBytes(completion, "choices.0").Exists() {
+		return
+	}
+
+	miss := capture.miss
+	if err := ps.semanticCache.Store(miss.group.ID, miss.paramsHash, miss.vector, string(completion), miss.cfg.ttl); err != nil {
+		logrus.WithError(err).WithField("group", miss.group.Name).Warn("Failed to store semantic cache entry")
+	}
+}
+
+// embedSemanticCachePrompt embeds the prompt through the configured embedding
+// group on this gateway, using that group's keys and upstreams.
+func (ps *ProxyServer) embedSemanticCachePrompt(ctx context.Context, cfg *semanticCacheConfig, prompt string) ([]float32, error) {
+	ctx, cancel := context.WithTimeout(ctx, semanticCacheEmbeddingTimeout)
+	defer cancel()
+	body, err := ps.callOpenAIGroup(ctx, cfg.embeddingGroup, "/v1/embeddings", map[string]any{"model": cfg.embeddingModel, "input": prompt})
+	if err != nil {
+		return nil, fmt.Errorf("embedding group %q: %w", cfg.embeddingGroup, err)
+	}
+
+	values := gjson.GetBytes(body, "data.0.embedding").Array()
+	if len(values) == 0 {
+		return nil, errors.New("embedding response has no vector")
+	}
+	vector := make([]float32, len(values))
+	for i, v := range values {
+		vector[i] = float32(v.Float())
+	}
+	return vector, nil
+}
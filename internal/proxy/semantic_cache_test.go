@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestSemanticCachePrompt(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "system and last user message",
+			body:     `{"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"old"},{"role":"assistant","content":"a"},{"role":"user","content":" new question "}]}`,
+			expected: "Be brief.\n\nnew question",
+		},
+		{
+			name:     "text content parts",
+			body:     `{"messages":[{"role":"user","content":[{"type":"text","text":"describe"},{"type":"image_url","image_url":{"url":"x"}},{"type":"text","text":"this"}]}]}`,
+			expected: "describe\nthis",
+		},
+		{
+			name:     "no user message",
+			body:     `{"messages":[{"role":"system","content":"Be brief."}]}`,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, semanticCachePrompt([]byte(tt.body)))
+		})
+	}
+}
+
+func TestSemanticCacheParamsHashIgnoresLastUserMessage(t *testing.T) {
+	t.Parallel()
+
+	base := semanticCacheParamsHash([]byte(`{"model":"m","messages":[{"role":"user","content":"a"}],"temperature":0.2}`))
+	assert.Equal(t, base, semanticCacheParamsHash([]byte(`{"temperature":0.2,"stream":true,"model":"m","messages":[{"role":"user","content":"b"}]}`)))
+	assert.NotEqual(t, base, semanticCacheParamsHash([]byte(`{"model":"m2","messages":[{"role":"user","content":"a"}],"temperature":0.2}`)))
+	assert.NotEqual(t, base, semanticCacheParamsHash([]byte(`{"model":"m","messages":[{"role":"user","content":"a"}],"temperature":0.9}`)))
+
+	conversation := func(earlier, last string) string {
+		return semanticCacheParamsHash([]byte(`{"model":"m","messages":[` +
+			`{"role":"system","content":"be brief"},` +
+			`{"role":"user","content":"` + earlier + `"},` +
+			`{"role":"assistant","content":"ok"},` +
+			`{"role":"user","content":"` + last + `"}]}`))
+	}
+	assert.Equal(t, conversation("write a poem", "continue"), conversation("write a poem", "go on"))
+	assert.NotEqual(t, conversation("write a poem", "continue"), conversation("summarize the report", "continue"),
+		"the same last turn after a different history must not match")
+}
+
+func TestChatCompletionSSERoundTrip(t *testing.T) {
+	t.Parallel()
+
+	completion := []byte(`{"id":"c1","object":"chat.completion","created":1700000000,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":2,"completion_tokens":1,"total_tokens":3}}`)
+	stream := chatCompletionToSSE(completion)
+	assert.True(t, strings.HasSuffix(string(stream), "data: [DONE]\n\n"))
+
+	assembled, ok := sseToChatCompletion(stream)
+	require.True(t, ok)
+	assert.Equal(t, "hello", gjson.GetBytes(assembled, "choices.0.message.content").String())
+	assert.Equal(t, "stop", gjson.GetBytes(assembled, "choices.0.finish_reason").String())
+	assert.Equal(t, int64(3), gjson.GetBytes(assembled, "usage.total_tokens").Int())
+	assert.Equal(t, "c1", gjson.GetBytes(assembled, "id").String())
+
+	_, ok = sseToChatCompletion([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0}]}}]}\n\n"))
+	assert.False(t, ok, "tool call streams are not cached")
+}
+
+func TestHandleProxyServesSemanticCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.SemanticCacheEntry{}))
+	ps, _ := setupTestProxyServerWithStore(t, db)
+	ps.SetSemanticCacheService(services.NewSemanticCacheService(db))
+
+	var chatCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/embeddings") {
+			vector := "[0,1,0]"
+			if strings.Contains(strings.ToLower(gjson.GetBytes(body, "input").String()), "weather") {
+				vector = "[1,0.05,0]"
+			}
+			_, _ = io.WriteString(w, `{"data":[{"embedding":`+vector+`}]}`)
+			return
+		}
+		n := chatCalls.Add(1)
+		_, _ = io.WriteString(w, `{"id":"chat-`+strconv.Itoa(int(n))+`","object":"chat.completion","created":1700000000,"model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"sunny"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	embeddings := createTestGroup(t, db, "embeddings", "openai")
+	embeddings.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	require.NoError(t, db.Save(embeddings).Error)
+	createTestKey(t, db, embeddings.ID, "sk-embeddings", ps.encryptionSvc)
+
+	group := createTestGroup(t, db, "semantic-cache", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.Config = map[string]any{
+		"semantic_cache_enabled":         true,
+		"semantic_cache_embedding_group": embeddings.Name,
+		"semantic_cache_threshold":       0.9,
+	}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-semantic-cache", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	send := func(body string, bypass bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/semantic-cache/v1/chat/completions", bytes.NewReader([]byte(body)))
+		if bypass {
+			c.Request.Header.Set(semanticCacheBypassHeader, "true")
+		}
+		c.Params = gin.Params{{Key: "group_name", Value: group.Name}}
+		ps.HandleProxy(c)
+		return w
+	}
+
+	first := send(`{"model":"m","messages":[{"role":"user","content":"What is the weather today?"}]}`, false)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get(semanticCacheHeader))
+
+	second := send(`{"model":"m","messages":[{"role":"user","content":"How is the weather today"}]}`, false)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "HIT", second.Header().Get(semanticCacheHeader))
+	assert.Equal(t, "chat-1", gjson.Get(second.Body.String(), "id").String())
+
+	streamed := send(`{"model":"m","stream":true,"messages":[{"role":"user","content":"weather please"}]}`, false)
+	require.Equal(t, http.StatusOK, streamed.Code)
+	assert.Equal(t, "HIT", streamed.Header().Get(semanticCacheHeader))
+	assert.Contains(t, streamed.Body.String(), `"content":"sunny"`)
+	assert.Contains(t, streamed.Body.String(), "data: [DONE]")
+
+	bypassed := send(`{"model":"m","messages":[{"role":"user","content":"What is the weather today?"}]}`, true)
+	assert.Empty(t, bypassed.Header().Get(semanticCacheHeader))
+
+	other := send(`{"model":"m","messages":[{"role":"user","content":"Tell me a joke"}]}`, false)
+	assert.Equal(t, "MISS", other.Header().Get(semanticCacheHeader))
+	assert.Equal(t, int32(3), chatCalls.Load())
+}
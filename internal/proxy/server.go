@@ -25,10 +25,12 @@ import (
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/failover"
+	"gpt-load/internal/guardrail"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/store"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
@@ -639,6 +641,10 @@ type ProxyServer struct {
 	encryptionSvc        encryption.Service
 	dynamicWeightManager *services.DynamicWeightManager // Optional dynamic weight manager for adaptive load balancing
 	codexAffinityCache   *codexAggregateAffinityCache
+	store                store.Store                    // Shared store for cross-node state such as session affinity bindings
+	semanticCache        *services.SemanticCacheService // Optional semantic cache for chat completions
+	debugCapture         *services.DebugCaptureService  // Optional per-request debug capture
+	shadowMirror         *shadowMirror                  // Sends sampled requests to shadow groups
 }
 
 // retryContext holds the retry state for a single request
@@ -650,6 +656,7 @@ type retryContext struct {
 	originalPath                   string        // Original request path (for CC support restoration)
 	subGroupKeyRetryMap            map[uint]int  // Tracks key retry count for each sub-group (sub-group ID -> retry count)
 	forcedSubGroupID               uint          // Keeps key-level retries on the selected sub-group until its retry budget is exhausted
+	modelFallbackSteps             map[uint]int  // Fallback models already used per sub-group (sub-group ID -> fallback step)
 	codexAffinityKey               string        // Stable Codex affinity key for this aggregate request
 	codexAffinityCacheKey          string        // Precomputed bounded cache key reused across retries and binding
 	codexAffinityPrimarySubGroupID uint          // Stable primary sub-group for Codex affinity requests
@@ -659,6 +666,11 @@ type retryContext struct {
 	codexParsedPayloadSet          bool
 	codexParsedModel               string
 	codexParsedModelSet            bool
+	sessionAffinity                *sessionAffinityConfig  // Parsed session affinity config, nil when disabled or no identifier
+	sessionAffinityStoreKey        string                  // Store key of the session binding
+	sessionAffinityBinding         *sessionAffinityBinding // Binding loaded at the start of the request
+	sessionAffinityResolved        bool
+	sessionAffinityKeyTried        bool
 	lifecycleCtx                   context.Context
 	lifecycleCancel                context.CancelFunc
 	lifecycleConfig                types.SystemSettings
@@ -1338,8 +1350,9 @@ func NewProxyServer(
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
 	encryptionSvc encryption.Service,
+	store store.Store,
 ) (*ProxyServer, error) {
-	return &ProxyServer{
+	ps := &ProxyServer{
 		keyProvider:          keyProvider,
 		groupManager:         groupManager,
 		subGroupManager:      subGroupManager,
@@ -1349,7 +1362,10 @@ func NewProxyServer(
 		encryptionSvc:        encryptionSvc,
 		dynamicWeightManager: nil, // Set via SetDynamicWeightManager if needed
 		codexAffinityCache:   newCodexAggregateAffinityCache(codexAggregateAffinityTTL, codexAggregateAffinityMaxEntries),
-	}, nil
+		store:                store,
+	}
+	ps.shadowMirror = newShadowMirror(shadowQueueSize, ps.sendShadowRequest)
+	return ps, nil
 }
 
 // SetDynamicWeightManager sets the dynamic weight manager for adaptive load balancing.
@@ -1362,6 +1378,11 @@ func (ps *ProxyServer) SetDynamicWeightManager(dwm *services.DynamicWeightManage
 	}
 }
 
+// SetSemanticCacheService sets the semantic cache used by groups that enable it.
+func (ps *ProxyServer) SetSemanticCacheService(svc *services.SemanticCacheService) {
+	ps.semanticCache = svc
+}
+
 // GetDynamicWeightManager returns the dynamic weight manager if set.
 func (ps *ProxyServer) GetDynamicWeightManager() *services.DynamicWeightManager {
 	return ps.dynamicWeightManager
@@ -1437,6 +1458,18 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 	// 4. No downstream handlers store the bodyBytes slice beyond the request scope
 	bodyBytes := buf.Bytes()
 
+	// Route the request to its experiment variant. Variants only differ in how
+	// requests are shaped, so the channel of the group is kept.
+	if variant := selectExperimentVariant(c, originalGroup, bodyBytes); variant != originalGroup {
+		originalGroup = variant
+		group = variant
+	}
+
+	// Capture the full request when it carries a valid debug token.
+	if capture := ps.startDebugCapture(c, bodyBytes, startTime); capture != nil {
+		defer ps.saveDebugCapture(c, originalGroup, capture)
+	}
+
 	// Check preconditions for aggregate groups
 	// Preconditions must be met before the request can enter the aggregate group
 	if originalGroup.GroupType == "aggregate" {
@@ -1480,6 +1513,15 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
+	// Enforce group and Hub access key guardrail policies on the client request,
+	// before any format conversion.
+	if ps.enforceRequestGuardrails(c, originalGroup, channelHandler, bodyBytes, startTime) {
+		return
+	}
+
+	// Mirror a sample of the client request to the shadow group, if any.
+	ps.mirrorToShadowGroup(c, originalGroup, bodyBytes)
+
 	// For GET requests (like /v1/models), skip body processing
 	var finalBodyBytes []byte
 	var isStream bool
@@ -1526,6 +1568,8 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		}).Debug("Force Codex: rewritten Codex path for channel type")
 	}
 
+	traceExplainStage(c, ExplainStagePathRewrite, group, bodyBytes)
+
 	if c.Request.Method == "GET" || len(bodyBytes) == 0 {
 		finalBodyBytes = bodyBytes
 		isStream = false
@@ -1539,6 +1583,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		} else {
 			// Apply model mapping first (before param overrides to allow overriding the mapped model if needed)
 			bodyBytesAfterMapping, originalModel := ps.applyModelMapping(bodyBytes, group)
+			traceExplainStage(c, ExplainStageModelMapping, group, bodyBytesAfterMapping)
 
 			// Store original model only if mapping changed the payload
 			if originalModel != "" && !bytes.Equal(bodyBytesAfterMapping, bodyBytes) {
@@ -1550,6 +1595,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 				response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to apply parameter overrides: %v", err)))
 				return
 			}
+			traceExplainStage(c, ExplainStageParamOverrides, group, finalBodyBytes)
 
 			// Handle Claude count_tokens endpoint (CC only).
 			if ps.handleTokenCount(c, group, finalBodyBytes) {
@@ -1645,6 +1691,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 						}).Debug("CC support: converted Claude request to OpenAI format")
 					}
 				}
+				traceExplainStage(c, ExplainStageCCConversion, group, finalBodyBytes)
 			}
 
 			if group.ChannelType == "openai-response" && wasCodexPath && isOpenAIResponsesCodexEndpoint(c.Request.URL.Path) {
@@ -1679,6 +1726,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 						"new_path":     c.Request.URL.Path,
 					}).Debug("Force Codex: converted Responses request to upstream format")
 				}
+				traceExplainStage(c, ExplainStageCodexConversion, group, finalBodyBytes)
 			}
 
 			// Apply parallel_tool_calls config for OpenAI channel when force_function_call is NOT enabled.
@@ -1691,6 +1739,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 				if err != nil {
 					logrus.WithError(err).Warn("Failed to apply parallel_tool_calls config")
 				}
+				traceExplainStage(c, ExplainStageParallelToolCalls, group, finalBodyBytes)
 			}
 
 			// Apply function call request rewrite for eligible channel endpoints.
@@ -1711,6 +1760,7 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 						"trigger_signal": triggerSignal,
 					}).Debug("Function call request rewrite applied")
 				}
+				traceExplainStage(c, ExplainStageFunctionCallRewrite, group, finalBodyBytes)
 			}
 
 			if group.ChannelType == "gemini" {
@@ -1727,11 +1777,13 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 					logrus.WithError(err).Warn("Failed to apply stream override config")
 				}
 			}
+			traceExplainStage(c, ExplainStageStreamOverride, group, finalBodyBytes)
 			if group.ChannelType == "openai-response" && isOpenAIResponsesEndpoint(c.Request.URL.Path) {
 				finalBodyBytes, err = ps.applyResponsesIncludeConfig(finalBodyBytes, group)
 				if err != nil {
 					logrus.WithError(err).Warn("Failed to apply Responses include config")
 				}
+				traceExplainStage(c, ExplainStageResponsesInclude, group, finalBodyBytes)
 			}
 
 			isStream = channelHandler.IsStreamRequest(c, finalBodyBytes)
@@ -1764,9 +1816,32 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 					// Keep isStream as false so response handler knows to collect and convert
 				}
 			}
+			traceExplainStage(c, ExplainStageStreamMode, group, finalBodyBytes)
+		}
+	}
+
+	// Serve deterministic requests from the response cache when the group opts in.
+	// Explain and dry-run replay requests bypass both caches so they reach the upstream request.
+	if cacheCfg, ok := parseResponseCacheConfig(originalGroup); ok && ps.store != nil && !isExplainRequest(c) && !isReplayDryRun(c) && isResponseCacheable(c, finalBodyBytes, isStream) {
+		noCache, noStore := responseCacheDirectives(c)
+		cacheKey := responseCacheKey(c, originalGroup, finalBodyBytes)
+		if !noCache && ps.serveCachedResponse(c, originalGroup, cacheKey) {
+			ps.logRequest(c, originalGroup, originalGroup, nil, startTime, c.Writer.Status(), nil, false, "", nil, "", channelHandler, finalBodyBytes, models.RequestTypeCacheHit)
+			return
+		}
+		c.Header(responseCacheHeader, "MISS")
+		if !noStore {
+			cacheWriter := &responseCacheWriter{ResponseWriter: c.Writer}
+			c.Writer = cacheWriter
+			defer ps.storeCachedResponse(c, originalGroup, cacheCfg, cacheKey, cacheWriter)
 		}
 	}
 
+	// Serve similar chat completions from the semantic cache when the group opts in.
+	if !isExplainRequest(c) && !isReplayDryRun(c) && ps.serveSemanticCache(c, originalGroup, channelHandler, finalBodyBytes, isStream, startTime) {
+		return
+	}
+
 	// Use new retry logic for aggregate groups, old logic for standard groups
 	if originalGroup.GroupType == "aggregate" && retryCtx != nil {
 		ps.executeRequestWithAggregateRetry(c, channelHandler, originalGroup, finalBodyBytes, isStream, startTime, retryCtx)
@@ -1967,10 +2042,11 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 	// Apply model redirection with index tracking for dynamic weight metrics
 	// Skip for CC mode as redirection is already handled in CC conversion
 	// This prevents strict mode errors when using Claude model names with CC
+	// Skip once a fallback model is active because the body already carries it.
 	finalBodyBytes := bodyBytes
 	var originalModel string
 	var targetIdx int = -1
-	if !isCCEnabled(c) {
+	if !isCCEnabled(c) && modelFallbackStep(c) == 0 {
 		var err error
 		finalBodyBytes, originalModel, targetIdx, err = channelHandler.ApplyModelRedirectWithIndex(req, bodyBytes, group)
 		if err != nil {
@@ -1994,6 +2070,8 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 			bodyBytes = finalBodyBytes
 		}
 	}
+	bodyBytes = applyModelFallback(c, req, bodyBytes, group)
+	traceExplainStage(c, ExplainStageModelRedirect, group, bodyBytes)
 
 	// Log request
 	channelHandler.ModifyRequest(req, apiKey, group)
@@ -2052,14 +2130,39 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 		}).Debug("Using HTTP client for request")
 	}
 
-	resp, err := client.Do(req)
+	// Dry-run replays stop with the fully built upstream request.
+	if captureReplayDryRun(c, bodyBytes, isStream, originalGroup.GroupType == "aggregate") {
+		return
+	}
+
+	// Explain requests stop with the fully built upstream request.
+	if captureExplainUpstream(c, group, req, bodyBytes, isStream, apiKey) {
+		return
+	}
+
+	attemptStart := time.Now()
+	var resp *http.Response
+	if hedgeDelay := hedgeDelayForRequest(c, originalGroup, group, isStream); hedgeDelay > 0 {
+		resp, apiKey, err = ps.doHedgedRequest(c, client, req, hedgeDelay, hedgeAttempt{
+			channelHandler:    channelHandler,
+			originalGroup:     originalGroup,
+			group:             group,
+			apiKey:            apiKey,
+			upstreamSelection: upstreamSelection,
+			bodyBytes:         bodyBytes,
+			startTime:         startTime,
+		})
+	} else {
+		resp, err = client.Do(req)
+	}
+	recordDebugAttempt(c, group, req, bodyBytes, apiKey, resp, err, attemptStart)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	setRateLimitPressureContextForAttempt(c, resp, time.Now())
 
 	// Unified error handling for retries.
-	if err != nil || (resp != nil && shouldFailoverOnStatusCode(resp.StatusCode, group)) {
+	if err != nil || (resp != nil && (shouldFailoverOnStatusCode(resp.StatusCode, group) || shouldFallbackOnResponse(c, group, resp))) {
 		if ps.shouldAbortOnIgnorableError(c, err) {
 			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
 			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, sanitizeInternalError(err), isStream, upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, channelHandler, bodyBytes, models.RequestTypeFinal)
@@ -2069,6 +2172,7 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 		var statusCode int
 		var parsedError string
 		var internalError string
+		var modelUnavailable bool
 
 		if err != nil {
 			statusCode = 500
@@ -2100,23 +2204,30 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 
 			parsedError = app_errors.ParseUpstreamError(errorBody)
 			internalError = sanitizeInternalErrorMessage(parsedError)
+			modelUnavailable = hasNextModelFallback(c, group) && isModelUnavailableError(statusCode, string(errorBody))
 			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), internalError)
 		}
 
-		// Update key status with parsed error information
-		ps.keyProvider.UpdateStatus(apiKey, group, false, internalError)
+		// Update key status with parsed error information.
+		// Model-level failures say nothing about the key, so they do not count against it.
+		if !modelUnavailable {
+			ps.keyProvider.UpdateStatus(apiKey, group, false, internalError)
+		}
 
-		// Check if this is the last retry attempt
+		// Check if this is the last retry attempt. Instead of failing, switch to the next
+		// fallback model once the current model has used its retry budget, or right away
+		// when the upstream reports that the model itself is unavailable.
 		isLastAttempt := retryCount >= cfg.MaxRetries
+		switchToFallback := (isLastAttempt || modelUnavailable) && advanceModelFallback(c, group, statusCode)
 		requestType := models.RequestTypeRetry
-		if isLastAttempt {
+		if isLastAttempt && !switchToFallback {
 			requestType = models.RequestTypeFinal
 		}
 
 		ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, errors.New(internalError), isStream, upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, channelHandler, bodyBytes, requestType)
 
 		// If this is the last attempt, return error directly without recursion
-		if isLastAttempt {
+		if isLastAttempt && !switchToFallback {
 			// For CC mode (Claude Code), return Claude-formatted error response
 			// to ensure the client can properly parse and display the error message.
 			if isCCEnabled(c) {
@@ -2134,7 +2245,12 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 			return
 		}
 
-		ps.executeRequestWithRetryLifecycle(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1, lifecycleCtx)
+		// Each fallback model starts with a fresh key retry budget.
+		nextRetryCount := retryCount + 1
+		if switchToFallback {
+			nextRetryCount = 0
+		}
+		ps.executeRequestWithRetryLifecycle(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, nextRetryCount, lifecycleCtx)
 		return
 	}
 
@@ -2142,9 +2258,11 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
 
 	// Check if this is a model list request (needs special handling)
+	var semanticCapture *semanticCacheCapture
 	if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
 		ps.handleModelListResponse(c, resp, group, channelHandler)
 	} else {
+		semanticCapture = captureSemanticCacheResponse(c, resp)
 		for key, values := range resp.Header {
 			for _, value := range values {
 				c.Header(key, value)
@@ -2229,6 +2347,7 @@ func (ps *ProxyServer) executeRequestWithRetryLifecycle(
 	}
 
 	ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, channelHandler, bodyBytes, models.RequestTypeFinal)
+	ps.storeSemanticCacheResponse(c, semanticCapture, isStream)
 }
 
 // executeRequestWithAggregateRetry handles requests for aggregate groups with intelligent retry logic
@@ -2363,7 +2482,20 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 				subGroupName, subGroupID, err = ps.subGroupManager.SelectSubGroupWithRetry(originalGroup, retryCtx.excludedSubGroups)
 			}
 		} else {
-			subGroupName, subGroupID, err = ps.subGroupManager.SelectSubGroupWithRetry(originalGroup, retryCtx.excludedSubGroups)
+			var bound bool
+			if !codexAffinityEnabled {
+				ps.resolveSessionAffinity(c, originalGroup, retryCtx, bodyBytes)
+				subGroupName, subGroupID, bound = ps.sessionAffinitySubGroup(originalGroup, retryCtx)
+			}
+			if bound {
+				logrus.WithFields(logrus.Fields{
+					"aggregate_group": originalGroup.Name,
+					"selected_group":  subGroupName,
+					"selected_id":     subGroupID,
+				}).Debug("Selected aggregate sub-group from session affinity")
+			} else {
+				subGroupName, subGroupID, err = ps.subGroupManager.SelectSubGroupWithRetry(originalGroup, retryCtx.excludedSubGroups)
+			}
 		}
 		if err != nil {
 			// All sub-groups are unavailable (runtime error)
@@ -2406,16 +2538,24 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 		return
 	}
 
+	// The selected sub-group's own guardrail policy applies on top of the
+	// aggregate and Hub key policies checked in HandleProxy.
+	if ps.enforceSubGroupGuardrails(c, originalGroup, group, subGroupChannelHandler, bodyBytes, startTime) {
+		return
+	}
+
 	// Store current sub-group ID for failure handling
 	c.Set("current_sub_group_id", subGroupID)
 	codexAffinityFallback := retryCtx.codexAffinityDegraded
 	clearModelRedirectContext(c)
+	c.Set(ctxKeyModelFallbackStep, retryCtx.modelFallbackSteps[subGroupID])
 
 	// Apply model mapping for the selected sub-group
 	finalBodyBytes, originalModel := ps.applyModelMapping(bodyBytes, group)
 	if originalModel != "" && !bytes.Equal(finalBodyBytes, bodyBytes) {
 		c.Set("original_model", originalModel)
 	}
+	traceExplainStage(c, ExplainStageModelMapping, group, finalBodyBytes)
 
 	// Apply parameter overrides for the selected sub-group
 	finalBodyBytes, err = ps.applyParamOverrides(finalBodyBytes, group)
@@ -2426,6 +2566,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 		}).Warn("Failed to apply parameter overrides for sub-group, using original body")
 		finalBodyBytes = bodyBytes
 	}
+	traceExplainStage(c, ExplainStageParamOverrides, group, finalBodyBytes)
 
 	// Handle Claude count_tokens endpoint for aggregate sub-group (CC only).
 	if ps.handleTokenCount(c, group, finalBodyBytes) {
@@ -2490,6 +2631,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 			"new_path":        c.Request.URL.Path,
 		}).Debug("Force Codex: rewritten Codex path for sub-group channel type")
 	}
+	traceExplainStage(c, ExplainStagePathRewrite, group, finalBodyBytes)
 
 	// Convert Claude messages request to target format (OpenAI, OpenAI Responses, or Gemini)
 	// Note: Path has already been rewritten from /claude/v1/messages to /v1/messages (or /v1beta/messages for Gemini)
@@ -2627,6 +2769,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 				}).Debug("CC support: converted Claude request for sub-group")
 			}
 		}
+		traceExplainStage(c, ExplainStageCCConversion, group, finalBodyBytes)
 	}
 
 	shouldUseCodexEndpointForSubGroup := isCodexEndpointSupported(group) &&
@@ -2665,6 +2808,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 				"new_path":        c.Request.URL.Path,
 			}).Debug("Force Codex: converted Responses request for sub-group")
 		}
+		traceExplainStage(c, ExplainStageCodexConversion, group, finalBodyBytes)
 	}
 
 	// Apply parallel_tool_calls config for OpenAI sub-groups when force_function_call is NOT enabled.
@@ -2679,6 +2823,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 				"sub_group":       group.Name,
 			}).Warn("Failed to apply parallel_tool_calls config for sub-group")
 		}
+		traceExplainStage(c, ExplainStageParallelToolCalls, group, finalBodyBytes)
 	}
 
 	// Apply function call request rewrite for eligible sub-group endpoints.
@@ -2705,6 +2850,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 				"trigger_signal":  triggerSignal,
 			}).Debug("Function call request rewrite applied for sub-group")
 		}
+		traceExplainStage(c, ExplainStageFunctionCallRewrite, group, finalBodyBytes)
 	}
 
 	if group.ChannelType == "gemini" {
@@ -2724,6 +2870,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 			}).Warn("Failed to apply stream override config for sub-group")
 		}
 	}
+	traceExplainStage(c, ExplainStageStreamOverride, group, finalBodyBytes)
 	if group.ChannelType == "openai-response" && isOpenAIResponsesEndpoint(c.Request.URL.Path) {
 		finalBodyBytes, err = ps.applyResponsesIncludeConfig(finalBodyBytes, group)
 		if err != nil {
@@ -2732,6 +2879,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 				"sub_group":       group.Name,
 			}).Warn("Failed to apply Responses include config for sub-group")
 		}
+		traceExplainStage(c, ExplainStageResponsesInclude, group, finalBodyBytes)
 	}
 	isStream = subGroupChannelHandler.IsStreamRequest(c, finalBodyBytes)
 	if codexDegradationMitigationShouldEnable(c, group, originalGroup, finalBodyBytes, isStream) {
@@ -2790,6 +2938,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 			// Keep isStream as false so response handler knows to collect and convert
 		}
 	}
+	traceExplainStage(c, ExplainStageStreamMode, group, finalBodyBytes)
 
 	if lifecycleCancel := retryCtx.ensureLifecycleContext(c.Request.Context(), isStream); lifecycleCancel != nil {
 		defer lifecycleCancel()
@@ -2801,7 +2950,19 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 		delete(c.Keys, ctxKeyUpstreamUserAgent)
 	}
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	var apiKey *models.APIKey
+	if boundKeyID := retryCtx.sessionAffinityKeyID(subGroupID); boundKeyID != 0 {
+		apiKey, err = ps.keyProvider.SelectKeyByID(group.ID, boundKeyID)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"sub_group": group.Name,
+				"key_id":    boundKeyID,
+			}).Debug("Session affinity key is no longer active, using key rotation")
+		}
+	}
+	if apiKey == nil {
+		apiKey, err = ps.keyProvider.SelectKey(group.ID)
+	}
 	if err != nil {
 		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCtx.attemptCount+1, err)
 		if retryCtx.isCodexAffinityPrimary(codexAffinityEnabled, subGroupID) {
@@ -2888,6 +3049,8 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 			}
 		}
 	}
+	finalBodyBytes = applyModelFallback(c, req, finalBodyBytes, group)
+	traceExplainStage(c, ExplainStageModelRedirect, group, finalBodyBytes)
 
 	subGroupChannelHandler.ModifyRequest(req, apiKey, group)
 
@@ -2943,19 +3106,44 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 		"is_stream": isStream,
 	}).Debug("Using HTTP client for aggregate sub-group request")
 
+	// Dry-run replays stop with the fully built upstream request.
+	if captureReplayDryRun(c, finalBodyBytes, isStream, originalGroup.GroupType == "aggregate") {
+		return
+	}
+
+	// Explain requests stop with the fully built upstream request.
+	if captureExplainUpstream(c, group, req, finalBodyBytes, isStream, apiKey) {
+		return
+	}
+
 	isCodexAffinityPrimaryAttempt := retryCtx.isCodexAffinityPrimary(codexAffinityEnabled, subGroupID)
 	if isCodexAffinityPrimaryAttempt {
 		// Count only attempts that reach the actual upstream client call.
 		retryCtx.codexAffinityAttemptCount++
 	}
-	resp, err := client.Do(req)
+	attemptStart := time.Now()
+	var resp *http.Response
+	if hedgeDelay := hedgeDelayForRequest(c, originalGroup, group, isStream); hedgeDelay > 0 {
+		resp, apiKey, err = ps.doHedgedRequest(c, client, req, hedgeDelay, hedgeAttempt{
+			channelHandler:    subGroupChannelHandler,
+			originalGroup:     originalGroup,
+			group:             group,
+			apiKey:            apiKey,
+			upstreamSelection: upstreamSelection,
+			bodyBytes:         finalBodyBytes,
+			startTime:         startTime,
+		})
+	} else {
+		resp, err = client.Do(req)
+	}
+	recordDebugAttempt(c, group, req, finalBodyBytes, apiKey, resp, err, attemptStart)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	setRateLimitPressureContextForAttempt(c, resp, time.Now())
 
 	// Unified error handling for retries.
-	if err != nil || (resp != nil && shouldFailoverOnStatusCode(resp.StatusCode, group)) {
+	if err != nil || (resp != nil && (shouldFailoverOnStatusCode(resp.StatusCode, group) || shouldFallbackOnResponse(c, group, resp))) {
 		if ps.shouldAbortOnIgnorableError(c, err) {
 			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
 			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, sanitizeInternalError(err), isStream, upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, subGroupChannelHandler, finalBodyBytes, models.RequestTypeFinal)
@@ -2965,6 +3153,7 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 		var statusCode int
 		var parsedError string
 		var internalError string
+		var modelUnavailable bool
 
 		if err != nil {
 			statusCode = 500
@@ -2995,11 +3184,14 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 
 			parsedError = app_errors.ParseUpstreamError(errorBody)
 			internalError = sanitizeInternalErrorMessage(parsedError)
+			modelUnavailable = hasNextModelFallback(c, group) && isModelUnavailableError(statusCode, string(errorBody))
 			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCtx.attemptCount+1, maxRetries, utils.MaskAPIKey(apiKey.KeyValue), internalError)
 		}
 
-		// Update key status
-		ps.keyProvider.UpdateStatus(apiKey, group, false, internalError)
+		// Update key status. Model-level failures say nothing about the key.
+		if !modelUnavailable {
+			ps.keyProvider.UpdateStatus(apiKey, group, false, internalError)
+		}
 
 		if isCodexAffinityPrimaryAttempt {
 			if retryCtx.codexAffinityAttemptCount < codexAffinityMaxAttempts {
@@ -3048,6 +3240,33 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 		// Determine if sub-group has exhausted its key retries
 		isSubGroupKeyRetryExhausted := subGroupKeyRetryCount >= subGroupMaxRetries
 
+		// Try the sub-group's fallback models before leaving it. Each fallback model
+		// gets a fresh key retry budget inside the same sub-group.
+		if (isSubGroupKeyRetryExhausted || modelUnavailable) && advanceModelFallback(c, group, statusCode) {
+			if retryCtx.modelFallbackSteps == nil {
+				retryCtx.modelFallbackSteps = make(map[uint]int, 1)
+			}
+			retryCtx.modelFallbackSteps[subGroupID] = modelFallbackStep(c)
+			retryCtx.subGroupKeyRetryMap[subGroupID] = 0
+
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, errors.New(internalError), isStream,
+				upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, subGroupChannelHandler, finalBodyBytes, models.RequestTypeRetry)
+
+			restoreOriginalPath(c, retryCtx)
+
+			if !waitBeforeRetry(retryCtx.lifecycleCtx, retryDelayForAttempt(subGroupCfg, subGroupKeyRetryCount)) {
+				statusCode, ctxErr := retryLifecycleErrorStatus(retryCtx.lifecycleCtx)
+				ps.logRequest(c, originalGroup, group, apiKey, startTime, statusCode, sanitizeInternalError(ctxErr), isStream,
+					upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, subGroupChannelHandler, finalBodyBytes, models.RequestTypeFinal)
+				writeRetryLifecycleError(c, statusCode, ctxErr)
+				return
+			}
+
+			retryCtx.forcedSubGroupID = subGroupID
+			ps.executeRequestWithAggregateRetry(c, channelHandler, originalGroup, retryCtx.originalBodyBytes, isStream, startTime, retryCtx)
+			return
+		}
+
 		// Log detailed retry status
 		logrus.WithFields(logrus.Fields{
 			"aggregate_group":       originalGroup.Name,
@@ -3210,14 +3429,17 @@ func (ps *ProxyServer) executeRequestWithAggregateRetry(
 	}
 
 	ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamSelection.URL, upstreamSelection.ProxyURL, upstreamSelection.GatewayProxy, subGroupChannelHandler, finalBodyBytes, models.RequestTypeFinal)
-	if retryCtx.codexAffinityCacheKey != "" &&
+	if (retryCtx.codexAffinityCacheKey != "" || retryCtx.sessionAffinityStoreKey != "") &&
 		resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices &&
 		c.Writer.Status() >= http.StatusOK && c.Writer.Status() < http.StatusMultipleChoices {
 		_, _, logicalFailure := logicalStatusFromContext(c)
 		_, statusUnverified := c.Get(ctxKeyResponsesStatusUnverified)
 		_, processingFailed := c.Get(ctxKeyResponseProcessingFailed)
 		if !logicalFailure && !statusUnverified && !processingFailed {
-			ps.codexAffinityCache.set(retryCtx.codexAffinityCacheKey, subGroupID, time.Now())
+			if retryCtx.codexAffinityCacheKey != "" {
+				ps.codexAffinityCache.set(retryCtx.codexAffinityCacheKey, subGroupID, time.Now())
+			}
+			ps.bindSessionAffinity(originalGroup, retryCtx, subGroupID, apiKey.ID)
 		}
 	}
 }
@@ -3427,7 +3649,8 @@ func (ps *ProxyServer) logRequest(
 	var requestBodyToLog, responseBodyToLog, userAgent, upstreamUserAgent string
 
 	if group.EffectiveConfig.EnableRequestBodyLogging {
-		requestBodyToLog = sanitizeAndTruncateBytesForLog(bodyBytes, maxResponseCaptureBytes)
+		redactor := logRedactor(group)
+		requestBodyToLog = sanitizeAndTruncateStringForLog(redactor.Redact(string(bodyBytes)), maxResponseCaptureBytes)
 		userAgent = sanitizeAndTruncateStringForLog(c.Request.UserAgent(), requestLogUserAgentMaxRunes)
 		if ua, exists := c.Get(ctxKeyUpstreamUserAgent); exists {
 			if uaStr, ok := ua.(string); ok {
@@ -3438,7 +3661,7 @@ func (ps *ProxyServer) logRequest(
 		// Get captured response body from context (if available)
 		if responseBody, exists := c.Get("response_body"); exists {
 			if responseBodyStr, ok := responseBody.(string); ok {
-				responseBodyToLog = sanitizeAndTruncateStringForLog(responseBodyStr, maxResponseCaptureBytes)
+				responseBodyToLog = sanitizeAndTruncateStringForLog(redactor.Redact(responseBodyStr), maxResponseCaptureBytes)
 			}
 		}
 	}
@@ -3454,6 +3677,7 @@ func (ps *ProxyServer) logRequest(
 		SourceIP:               c.ClientIP(),
 		StatusCode:             statusCode,
 		RequestPath:            utils.TruncateString(utils.SanitizeURLForLog(c.Request.URL), 500), // Sanitize to prevent auth token leakage
+		RequestMethod:          c.Request.Method,
 		Duration:               duration,
 		UserAgent:              userAgent,
 		UpstreamUserAgent:      upstreamUserAgent,
@@ -3474,6 +3698,18 @@ func (ps *ProxyServer) logRequest(
 			logEntry.ErrorMessage = finalError.Error()
 		}
 	}
+	// Guardrail violations keep their reason in the error message and are
+	// logged with their own request type.
+	if value, exists := c.Get(ctxKeyGuardrailViolation); exists {
+		if violation, ok := value.(*guardrail.Violation); ok {
+			logEntry.IsSuccess = false
+			logEntry.StatusCode = http.StatusBadRequest
+			logEntry.ErrorMessage = violation.Error()
+			logEntry.RequestType = models.RequestTypeGuardrail
+		}
+	}
+
+	applyExperimentAssignment(c, logEntry)
 
 	// Set parent group
 	if originalGroup != nil && originalGroup.GroupType == "aggregate" && originalGroup.ID != group.ID {
@@ -3508,7 +3744,8 @@ func (ps *ProxyServer) logRequest(
 
 	if apiKey != nil {
 		// Encrypt key value for log storage
-		encryptedKeyValue, err := ps.encryptionSvc.Encrypt(apiKey.KeyValue)
+		// Secret references are logged instead of the resolved value
+		encryptedKeyValue, err := ps.encryptionSvc.Encrypt(apiKey.StoredValue())
 		if err != nil {
 			logrus.WithError(err).Error("Failed to encrypt key value for logging")
 			logEntry.KeyValue = "failed-to-encryption"
@@ -3516,7 +3753,7 @@ func (ps *ProxyServer) logRequest(
 			logEntry.KeyValue = encryptedKeyValue
 		}
 		// Add KeyHash for reverse lookup
-		logEntry.KeyHash = ps.encryptionSvc.Hash(apiKey.KeyValue)
+		logEntry.KeyHash = ps.encryptionSvc.Hash(apiKey.StoredValue())
 	}
 
 	if finalError != nil && logEntry.ErrorMessage == "" {
@@ -3524,7 +3761,9 @@ func (ps *ProxyServer) logRequest(
 	}
 
 	// Only successful final requests enter token stats; failed upstream 4xx/5xx responses are excluded.
-	if logEntry.RequestType == models.RequestTypeFinal && logEntry.IsSuccess {
+	// Responses blocked by a guardrail were still generated upstream, so they keep their usage.
+	if (logEntry.RequestType == models.RequestTypeFinal && logEntry.IsSuccess) ||
+		(logEntry.RequestType == models.RequestTypeGuardrail && c.GetBool(ctxKeyGuardrailResponseBlocked)) {
 		if usage, source, ok := getTokenUsage(c); ok {
 			logEntry.InputTokens = usage.InputTokens
 			logEntry.OutputTokens = usage.OutputTokens
@@ -3582,7 +3821,10 @@ func (ps *ProxyServer) logRequest(
 	// Record both final and retry requests to accurately track sub-group health.
 	// This ensures that failed sub-group attempts are reflected in health scores,
 	// even when the overall aggregate request succeeds via retry to another sub-group.
-	if ps.dynamicWeightManager != nil {
+	// Hedge entries are discarded racing attempts; the attempt that was used is recorded by the regular path.
+	// Cache hits and guardrail blocks say nothing about sub-group health.
+	if ps.dynamicWeightManager != nil && logEntry.RequestType != models.RequestTypeHedge &&
+		logEntry.RequestType != models.RequestTypeCacheHit && logEntry.RequestType != models.RequestTypeGuardrail {
 		ps.recordDynamicWeightMetrics(c, originalGroup, group, logEntry.IsSuccess, logEntry.StatusCode, logEntry.RequestType)
 	}
 }
@@ -3658,12 +3900,14 @@ func (ps *ProxyServer) recordDynamicWeightMetrics(c *gin.Context, originalGroup,
 	// user-facing model-mapping alias used only for request logs.
 	if redirectSourceModel, exists := c.Get(ctxKeyModelRedirectSourceModel); exists {
 		if originalModelStr, ok := redirectSourceModel.(string); ok && originalModelStr != "" {
-			// Get the selected target index from context if available
+			// Get the selected target index from context if available.
+			// Fallback models have no target index and are recorded by name instead.
+			fallbackModel := c.GetString(ctxKeyModelRedirectTargetModel)
 			if targetIdx, exists := c.Get(ctxKeyModelRedirectTargetIndex); exists {
-				if targetIdxInt, ok := targetIdx.(int); ok && targetIdxInt >= 0 {
+				if targetIdxInt, ok := targetIdx.(int); ok && (targetIdxInt >= 0 || fallbackModel != "") {
 					// Get target model name from the redirect rule
-					var targetModel string
-					if rule, found := group.ModelRedirectMapV2[originalModelStr]; found {
+					targetModel := fallbackModel
+					if rule, found := group.ModelRedirectMapV2[originalModelStr]; found && targetModel == "" {
 						if targetIdxInt < len(rule.Targets) {
 							targetModel = rule.Targets[targetIdxInt].Model
 						}
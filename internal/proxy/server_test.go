@@ -159,6 +159,7 @@ func setupTestProxyServerWithStore(t *testing.T, db *gorm.DB) (*ProxyServer, sto
 		channelFactory,
 		requestLogService,
 		encSvc,
+		memStore,
 	)
 	require.NoError(t, err)
 
@@ -6182,7 +6183,7 @@ func BenchmarkCountAvailableSubGroups(b *testing.B) {
 	clientManager := httpclient.NewHTTPClientManager()
 	channelFactory := channel.NewFactory(settingsManager, clientManager)
 	requestLogService := services.NewRequestLogService(db, memStore, settingsManager)
-	ps, err := NewProxyServer(keyProvider, groupManager, subGroupManager, settingsManager, channelFactory, requestLogService, encSvc)
+	ps, err := NewProxyServer(keyProvider, groupManager, subGroupManager, settingsManager, channelFactory, requestLogService, encSvc, memStore)
 	if err != nil {
 		b.Fatal(err)
 	}
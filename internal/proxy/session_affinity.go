@@ -0,0 +1,271 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	sessionAffinitySourceAuto     = "auto"
+	sessionAffinitySourceHeader   = "header"
+	sessionAffinitySourceJSONPath = "json_path"
+	sessionAffinitySourceProxyKey = "proxy_key"
+
+	sessionAffinityScopeSubGroup = "sub_group"
+	sessionAffinityScopeKey      = "key"
+	sessionAffinityScopeBoth     = "both"
+
+	defaultSessionAffinityTTL        = time.Hour
+	defaultSessionAffinityMaxEntries = 10240
+
+	sessionAffinityKeyPrefix   = "session_affinity:"
+	sessionAffinityIndexPrefix = "session_affinity_index:"
+)
+
+// sessionAffinityAutoPaths are the request fields clients already use to identify
+// a cacheable session, in priority order: OpenAI prompt_cache_key, Claude Code
+// metadata.user_id, Gemini cached content and the OpenAI end-user id.
+var sessionAffinityAutoPaths = []string{
+	"prompt_cache_key",
+	"metadata.user_id",
+	"cachedContent",
+	"cached_content",
+	"user",
+}
+
+// sessionAffinityConfig is the parsed session affinity config of an aggregate group.
+type sessionAffinityConfig struct {
+	source     string
+	key        string
+	scope      string
+	ttl        time.Duration
+	maxEntries int
+}
+
+// sessionAffinityBinding is the value stored for a bound session.
+type sessionAffinityBinding struct {
+	SubGroupID uint `json:"sub_group_id"`
+	KeyID      uint `json:"key_id,omitempty"`
+}
+
+// pinsSubGroup reports whether the binding should steer sub-group selection.
+func (cfg *sessionAffinityConfig) pinsSubGroup() bool {
+	return cfg.scope != sessionAffinityScopeKey
+}
+
+// pinsKey reports whether the binding should steer key selection inside the bound sub-group.
+func (cfg *sessionAffinityConfig) pinsKey() bool {
+	return cfg.scope != sessionAffinityScopeSubGroup
+}
+
+// parseSessionAffinityConfig reads the session affinity config of an aggregate group.
+// Returns false when affinity is disabled or the group is not an aggregate group.
+func parseSessionAffinityConfig(group *models.Group) (*sessionAffinityConfig, bool) {
+	if group == nil || group.GroupType != "aggregate" || !getGroupConfigBool(group, "session_affinity_enabled") {
+		return nil, false
+	}
+
+	cfg := &sessionAffinityConfig{
+		source:     strings.ToLower(getGroupConfigString(group, "session_affinity_source")),
+		key:        getGroupConfigString(group, "session_affinity_key"),
+		scope:      strings.ToLower(getGroupConfigString(group, "session_affinity_scope")),
+		ttl:        defaultSessionAffinityTTL,
+		maxEntries: defaultSessionAffinityMaxEntries,
+	}
+	switch cfg.source {
+	case sessionAffinitySourceHeader, sessionAffinitySourceJSONPath:
+		if cfg.key == "" {
+			return nil, false
+		}
+	case sessionAffinitySourceProxyKey:
+	default:
+		cfg.source = sessionAffinitySourceAuto
+	}
+	switch cfg.scope {
+	case sessionAffinityScopeKey, sessionAffinityScopeBoth:
+	default:
+		cfg.scope = sessionAffinityScopeSubGroup
+	}
+	if seconds, ok := positiveConfigInt(group.Config, "session_affinity_ttl_seconds"); ok {
+		cfg.ttl = time.Duration(seconds) * time.Second
+	}
+	if entries, ok := positiveConfigInt(group.Config, "session_affinity_max_entries"); ok {
+		cfg.maxEntries = entries
+	}
+	return cfg, true
+}
+
+// positiveConfigInt reads a positive integer from group config.
+// Values are validated on save, so malformed values simply fall back to defaults.
+func positiveConfigInt(cfg map[string]any, key string) (int, bool) {
+	var n int64
+	switch v := cfg[key].(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) || math.Trunc(v) != v || v > math.MaxInt32 {
+			return 0, false
+		}
+		n = int64(v)
+	case int:
+		n = int64(v)
+	case int64:
+		n = v
+	case json.Number:
+		parsed, err := v.Int64()
+		if err != nil {
+			return 0, false
+		}
+		n = parsed
+	case string:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		n = parsed
+	default:
+		return 0, false
+	}
+	if n < 1 || n > math.MaxInt32 {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// sessionAffinityIdentifier extracts the client session identifier from the request.
+func sessionAffinityIdentifier(c *gin.Context, cfg *sessionAffinityConfig, bodyBytes []byte) string {
+	switch cfg.source {
+	case sessionAffinitySourceHeader:
+		return strings.TrimSpace(c.GetHeader(cfg.key))
+	case sessionAffinitySourceJSONPath:
+		if len(bodyBytes) == 0 {
+			return ""
+		}
+		return strings.TrimSpace(gjson.GetBytes(bodyBytes, cfg.key).String())
+	case sessionAffinitySourceProxyKey:
+		return c.GetString(middleware.ProxyKeyContextKey)
+	}
+
+	if len(bodyBytes) == 0 || !gjson.ValidBytes(bodyBytes) {
+		return ""
+	}
+	for _, result := range gjson.GetManyBytes(bodyBytes, sessionAffinityAutoPaths...) {
+		if result.Type == gjson.String {
+			if value := strings.TrimSpace(result.Str); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// resolveSessionAffinity parses the group config, derives the store key and loads
+// the current binding once per request. The result is cached on the retry context.
+func (ps *ProxyServer) resolveSessionAffinity(c *gin.Context, group *models.Group, retryCtx *retryContext, bodyBytes []byte) {
+	if retryCtx.sessionAffinityResolved {
+		return
+	}
+	retryCtx.sessionAffinityResolved = true
+	if ps.store == nil {
+		return
+	}
+
+	cfg, ok := parseSessionAffinityConfig(group)
+	if !ok {
+		return
+	}
+	identifier := sessionAffinityIdentifier(c, cfg, bodyBytes)
+	cacheKey := codexAggregateAffinityCacheKey(group.ID, identifier, retryCtx.codexRequestModel(bodyBytes))
+	if cacheKey == "" {
+		return
+	}
+	retryCtx.sessionAffinity = cfg
+	retryCtx.sessionAffinityStoreKey = sessionAffinityKeyPrefix + cacheKey
+
+	raw, err := ps.store.Get(retryCtx.sessionAffinityStoreKey)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logrus.WithError(err).WithField("aggregate_group", group.Name).Warn("Failed to load session affinity binding")
+		}
+		return
+	}
+	var binding sessionAffinityBinding
+	if err := json.Unmarshal(raw, &binding); err != nil || binding.SubGroupID == 0 {
+		return
+	}
+	retryCtx.sessionAffinityBinding = &binding
+}
+
+// sessionAffinitySubGroup returns the bound sub-group when the binding pins the
+// sub-group and it is still enabled, not excluded and has active keys.
+func (ps *ProxyServer) sessionAffinitySubGroup(group *models.Group, retryCtx *retryContext) (string, uint, bool) {
+	binding := retryCtx.sessionAffinityBinding
+	if binding == nil || !retryCtx.sessionAffinity.pinsSubGroup() {
+		return "", 0, false
+	}
+	name, id, ok := forcedAggregateSubGroup(group, binding.SubGroupID, retryCtx.excludedSubGroups)
+	if !ok || !ps.subGroupManager.HasActiveKeys(id) {
+		return "", 0, false
+	}
+	return name, id, true
+}
+
+// sessionAffinityKeyID returns the bound key for the first key attempt in the
+// bound sub-group. Later attempts use normal rotation so a failing key is left.
+func (retryCtx *retryContext) sessionAffinityKeyID(subGroupID uint) uint {
+	binding := retryCtx.sessionAffinityBinding
+	if binding == nil || retryCtx.sessionAffinityKeyTried || !retryCtx.sessionAffinity.pinsKey() ||
+		binding.SubGroupID != subGroupID {
+		return 0
+	}
+	retryCtx.sessionAffinityKeyTried = true
+	return binding.KeyID
+}
+
+// bindSessionAffinity records the sub-group and key that served a successful request.
+// Existing bindings are refreshed; new bindings are admitted only while the group is
+// below its entry limit.
+func (ps *ProxyServer) bindSessionAffinity(group *models.Group, retryCtx *retryContext, subGroupID, keyID uint) {
+	if ps.store == nil || retryCtx.sessionAffinityStoreKey == "" {
+		return
+	}
+	cfg := retryCtx.sessionAffinity
+	binding := sessionAffinityBinding{SubGroupID: subGroupID}
+	if cfg.pinsKey() {
+		binding.KeyID = keyID
+	}
+
+	if retryCtx.sessionAffinityBinding == nil {
+		admitted, err := ps.admitStoreEntry(sessionAffinityIndexPrefix, group.ID, cfg.ttl, cfg.maxEntries, retryCtx.sessionAffinityStoreKey)
+		if err != nil {
+			logrus.WithError(err).WithField("aggregate_group", group.Name).Warn("Failed to check session affinity capacity")
+			return
+		}
+		if !admitted {
+			logrus.WithFields(logrus.Fields{
+				"aggregate_group": group.Name,
+				"max_entries":     cfg.maxEntries,
+			}).Debug("Session affinity entry limit reached, not binding new session")
+			return
+		}
+	}
+
+	// Existing bindings are rewritten even when unchanged so the TTL slides with activity.
+	value, err := json.Marshal(binding)
+	if err != nil {
+		return
+	}
+	if err := ps.store.Set(retryCtx.sessionAffinityStoreKey, value, cfg.ttl); err != nil {
+		logrus.WithError(err).WithField("aggregate_group", group.Name).Warn("Failed to save session affinity binding")
+	}
+}
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSessionAffinityConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		group    *models.Group
+		expected *sessionAffinityConfig
+	}{
+		{
+			name:  "disabled",
+			group: &models.Group{GroupType: "aggregate", Config: map[string]any{"session_affinity_enabled": false}},
+		},
+		{
+			name:  "standard group",
+			group: &models.Group{GroupType: "standard", Config: map[string]any{"session_affinity_enabled": true}},
+		},
+		{
+			name:  "header source without header name",
+			group: &models.Group{GroupType: "aggregate", Config: map[string]any{"session_affinity_enabled": true, "session_affinity_source": "header"}},
+		},
+		{
+			name:  "defaults",
+			group: &models.Group{GroupType: "aggregate", Config: map[string]any{"session_affinity_enabled": true}},
+			expected: &sessionAffinityConfig{
+				source:     sessionAffinitySourceAuto,
+				scope:      sessionAffinityScopeSubGroup,
+				ttl:        defaultSessionAffinityTTL,
+				maxEntries: defaultSessionAffinityMaxEntries,
+			},
+		},
+		{
+			name: "custom",
+			group: &models.Group{GroupType: "aggregate", Config: map[string]any{
+				"session_affinity_enabled":     true,
+				"session_affinity_source":      "json_path",
+				"session_affinity_key":         "metadata.session",
+				"session_affinity_scope":       "both",
+				"session_affinity_ttl_seconds": float64(120),
+				"session_affinity_max_entries": 5,
+			}},
+			expected: &sessionAffinityConfig{
+				source:     sessionAffinitySourceJSONPath,
+				key:        "metadata.session",
+				scope:      sessionAffinityScopeBoth,
+				ttl:        2 * time.Minute,
+				maxEntries: 5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, ok := parseSessionAffinityConfig(tt.group)
+			if tt.expected == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, cfg)
+		})
+	}
+}
+
+func TestSessionAffinityIdentifier(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		cfg      sessionAffinityConfig
+		body     string
+		expected string
+	}{
+		{name: "openai prompt cache key", cfg: sessionAffinityConfig{source: sessionAffinitySourceAuto}, body: `{"user":"u1","prompt_cache_key":"pck"}`, expected: "pck"},
+		{name: "claude code user id", cfg: sessionAffinityConfig{source: sessionAffinitySourceAuto}, body: `{"metadata":{"user_id":"user_abc_session_1"}}`, expected: "user_abc_session_1"},
+		{name: "gemini cached content", cfg: sessionAffinityConfig{source: sessionAffinitySourceAuto}, body: `{"cachedContent":"cachedContents/xyz"}`, expected: "cachedContents/xyz"},
+		{name: "openai user", cfg: sessionAffinityConfig{source: sessionAffinitySourceAuto}, body: `{"user":"u1"}`, expected: "u1"},
+		{name: "auto without identifier", cfg: sessionAffinityConfig{source: sessionAffinitySourceAuto}, body: `{"model":"m"}`, expected: ""},
+		{name: "header", cfg: sessionAffinityConfig{source: sessionAffinitySourceHeader, key: "X-Session"}, body: `{"user":"u1"}`, expected: "header-session"},
+		{name: "json path", cfg: sessionAffinityConfig{source: sessionAffinitySourceJSONPath, key: "extra.session"}, body: `{"extra":{"session":"s-9"}}`, expected: "s-9"},
+		{name: "proxy key", cfg: sessionAffinityConfig{source: sessionAffinitySourceProxyKey}, body: `{}`, expected: "sk-proxy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/proxy/agg/v1/chat/completions", nil)
+			c.Request.Header.Set("X-Session", "header-session")
+			c.Set(middleware.ProxyKeyContextKey, "sk-proxy")
+			assert.Equal(t, tt.expected, sessionAffinityIdentifier(c, &tt.cfg, []byte(tt.body)))
+		})
+	}
+}
+
+func TestBindSessionAffinityRespectsEntryLimit(t *testing.T) {
+	t.Parallel()
+
+	db := setupTestDB(t)
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+	group := &models.Group{ID: 7, Name: "agg"}
+	cfg := &sessionAffinityConfig{scope: sessionAffinityScopeBoth, ttl: time.Hour, maxEntries: 1}
+
+	first := &retryContext{sessionAffinity: cfg, sessionAffinityStoreKey: sessionAffinityKeyPrefix + "first"}
+	ps.bindSessionAffinity(group, first, 3, 11)
+	raw, err := memStore.Get(first.sessionAffinityStoreKey)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sub_group_id":3,"key_id":11}`, string(raw))
+
+	second := &retryContext{sessionAffinity: cfg, sessionAffinityStoreKey: sessionAffinityKeyPrefix + "second"}
+	ps.bindSessionAffinity(group, second, 4, 12)
+	exists, err := memStore.Exists(second.sessionAffinityStoreKey)
+	require.NoError(t, err)
+	assert.False(t, exists, "new session must not be bound once the limit is reached")
+
+	// Existing bindings are refreshed even when the group is full.
+	first.sessionAffinityBinding = &sessionAffinityBinding{SubGroupID: 3, KeyID: 11}
+	ps.bindSessionAffinity(group, first, 5, 13)
+	raw, err = memStore.Get(first.sessionAffinityStoreKey)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sub_group_id":5,"key_id":13}`, string(raw))
+}
+
+func TestExecuteRequestWithAggregateRetryUsesSessionAffinityBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+
+	var mu sync.Mutex
+	var seen []string
+	newUpstream := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			mu.Lock()
+			seen = append(seen, name+" "+r.Header.Get("Authorization"))
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"ok":true}`)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	aggregateGroup := &models.Group{
+		Name:        "agg-session-affinity",
+		ChannelType: "openai",
+		GroupType:   "aggregate",
+		Enabled:     true,
+		Upstreams:   []byte(`[{"url":"https://unused.example","weight":100}]`),
+		Config: map[string]any{
+			"max_retries":              0,
+			"session_affinity_enabled": true,
+			"session_affinity_scope":   "both",
+		},
+	}
+	require.NoError(t, db.Create(aggregateGroup).Error)
+
+	subGroupIDs := map[string]uint{}
+	for _, sub := range []struct {
+		name   string
+		weight int
+	}{{name: "agg-session-a", weight: 1000}, {name: "agg-session-b", weight: 1}} {
+		upstream := newUpstream(sub.name)
+		subGroup := createTestGroup(t, db, sub.name, "openai")
+		subGroup.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+		require.NoError(t, db.Save(subGroup).Error)
+		require.NoError(t, db.Create(&models.GroupSubGroup{
+			GroupID:         aggregateGroup.ID,
+			SubGroupID:      subGroup.ID,
+			SubGroupName:    subGroup.Name,
+			SubGroupEnabled: true,
+			Weight:          sub.weight,
+		}).Error)
+		subGroupIDs[sub.name] = subGroup.ID
+	}
+	createTestKey(t, db, subGroupIDs["agg-session-b"], "sk-session-b1", ps.encryptionSvc)
+	boundKey := createTestKey(t, db, subGroupIDs["agg-session-b"], "sk-session-b2", ps.encryptionSvc)
+	createTestKey(t, db, subGroupIDs["agg-session-a"], "sk-session-a1", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	cachedAggregate, err := ps.groupManager.GetGroupByName(aggregateGroup.Name)
+	require.NoError(t, err)
+
+	body := []byte(`{"model":"gpt-test","metadata":{"user_id":"user_1_session_42"}}`)
+	storeKey := sessionAffinityKeyPrefix + codexAggregateAffinityCacheKey(cachedAggregate.ID, "user_1_session_42", "gpt-test")
+	binding, err := json.Marshal(sessionAffinityBinding{SubGroupID: subGroupIDs["agg-session-b"], KeyID: boundKey.ID})
+	require.NoError(t, err)
+	require.NoError(t, memStore.Set(storeKey, binding, time.Hour))
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/"+aggregateGroup.Name+"/v1/chat/completions", bytes.NewReader(body))
+		retryCtx := &retryContext{
+			excludedSubGroups:   make(map[uint]bool, len(cachedAggregate.SubGroups)),
+			originalBodyBytes:   body,
+			originalPath:        c.Request.URL.Path,
+			subGroupKeyRetryMap: make(map[uint]int, len(cachedAggregate.SubGroups)),
+		}
+		ps.executeRequestWithAggregateRetry(c, nil, cachedAggregate, body, false, time.Now(), retryCtx)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	assert.Equal(t, []string{
+		"agg-session-b Bearer sk-session-b2",
+		"agg-session-b Bearer sk-session-b2",
+		"agg-session-b Bearer sk-session-b2",
+	}, seen, "the bound sub-group and key win over weights and key rotation")
+
+	raw, err := memStore.Get(storeKey)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(binding), string(raw))
+}
@@ -0,0 +1,385 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/tokenusage"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// shadowQueueSize bounds the mirrored requests waiting for a worker.
+	// Requests are dropped when the queue is full.
+	shadowQueueSize = 256
+	// shadowWorkers is the number of mirrored requests sent concurrently.
+	shadowWorkers = 4
+	// shadowRequestTimeout bounds a single mirrored request.
+	shadowRequestTimeout = 2 * time.Minute
+	// shadowDropLogInterval logs one warning per this many dropped requests.
+	shadowDropLogInterval = 100
+)
+
+// shadowRequest is a copy of a client request mirrored to a shadow group.
+type shadowRequest struct {
+	sourceGroup *models.Group
+	shadowGroup string
+	method      string
+	path        string // Path after /proxy/<group>
+	rawQuery    string
+	header      http.Header
+	body        []byte
+	sourceIP    string
+}
+
+// shadowMirror sends mirrored requests from a bounded queue. Workers are
+// started on the first mirrored request so servers without shadow groups do
+// not run them.
+type shadowMirror struct {
+	queue     chan *shadowRequest
+	send      func(ctx context.Context, req *shadowRequest)
+	ctx       context.Context
+	cancel    context.CancelFunc
+	startOnce sync.Once
+	stopOnce  sync.Once
+	mu        sync.RWMutex
+	stopped   bool
+	wg        sync.WaitGroup
+	dropped   atomic.Int64 // Mirrored requests dropped since start
+}
+
+func newShadowMirror(queueSize int, send func(ctx context.Context, req *shadowRequest)) *shadowMirror {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &shadowMirror{
+		queue:  make(chan *shadowRequest, queueSize),
+		send:   send,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// enqueue adds a request to the queue without blocking. It reports false when
+// the mirror is stopped or the queue is full.
+func (m *shadowMirror) enqueue(req *shadowRequest) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.stopped {
+		return false
+	}
+	m.startOnce.Do(func() {
+		for range shadowWorkers {
+			m.wg.Add(1)
+			go m.worker()
+		}
+	})
+	select {
+	case m.queue <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *shadowMirror) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case req := <-m.queue:
+			if m.ctx.Err() != nil {
+				return
+			}
+			m.send(m.ctx, req)
+		}
+	}
+}
+
+// Stop discards queued requests, cancels the ones in flight and waits for the
+// workers to exit.
+func (m *shadowMirror) Stop(ctx context.Context) {
+	m.stopOnce.Do(func() {
+		m.mu.Lock()
+		m.stopped = true
+		m.mu.Unlock()
+		m.cancel()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("Shadow traffic mirror stopped.")
+	case <-ctx.Done():
+		logrus.Warn("Shadow traffic mirror stop timed out.")
+	}
+}
+
+// StopShadowMirror stops mirroring requests to shadow groups.
+func (ps *ProxyServer) StopShadowMirror(ctx context.Context) {
+	if ps.shadowMirror != nil {
+		ps.shadowMirror.Stop(ctx)
+	}
+}
+
+// mirrorToShadowGroup queues a copy of a sampled client request for the
+// group's shadow group. It never blocks or fails the client request.
+func (ps *ProxyServer) mirrorToShadowGroup(c *gin.Context, group *models.Group, bodyBytes []byte) {
+	if ps.shadowMirror == nil {
+		return
+	}
+	shadowGroup := getGroupConfigString(group, "shadow_group")
+	if shadowGroup == "" || shadowGroup == group.Name {
+		return
+	}
+	if c.Request.Method == http.MethodGet || len(bodyBytes) == 0 {
+		return
+	}
+	// Explain and dry-run replay requests never reach an upstream.
+	if isExplainRequest(c) || isReplayDryRun(c) {
+		return
+	}
+	// Claude and Codex compatible paths are converted per group and are not mirrored.
+	if isClaudePath(c.Request.URL.Path, group.Name) || isCodexPath(c.Request.URL.Path, group.Name) {
+		return
+	}
+	if percent, ok := positiveConfigInt(group.Config, "shadow_sample_percent"); ok && percent < 100 && rand.IntN(100) >= percent {
+		return
+	}
+
+	req := &shadowRequest{
+		sourceGroup: group,
+		shadowGroup: shadowGroup,
+		method:      c.Request.Method,
+		path:        strings.TrimPrefix(c.Request.URL.Path, "/proxy/"+group.Name),
+		rawQuery:    c.Request.URL.RawQuery,
+		header:      c.Request.Header.Clone(),
+		body:        bytes.Clone(bodyBytes),
+		sourceIP:    c.ClientIP(),
+	}
+	if !ps.shadowMirror.enqueue(req) {
+		// Under sustained load every request is dropped, so only every
+		// shadowDropLogInterval-th drop is logged.
+		if dropped := ps.shadowMirror.dropped.Add(1); dropped%shadowDropLogInterval == 1 {
+			logrus.WithFields(logrus.Fields{
+				"group":         group.Name,
+				"shadow_group":  shadowGroup,
+				"dropped_total": dropped,
+			}).Warn("Shadow queue is full, dropping mirrored requests")
+		}
+	}
+}
+
+// resolveShadowGroup returns the shadow group of a mirrored request. The shadow
+// group must be an enabled standard group with the channel type of the source
+// group so the client request can be sent unchanged.
+func (ps *ProxyServer) resolveShadowGroup(req *shadowRequest) (*models.Group, error) {
+	group, err := ps.groupManager.GetGroupByName(req.shadowGroup)
+	if err != nil {
+		return nil, err
+	}
+	if !group.Enabled || group.GroupType == "aggregate" {
+		return nil, errors.New("shadow group must be an enabled standard group")
+	}
+	if group.ChannelType != req.sourceGroup.ChannelType {
+		return nil, fmt.Errorf("shadow group channel type %s does not match %s", group.ChannelType, req.sourceGroup.ChannelType)
+	}
+	return group, nil
+}
+
+// sendShadowRequest sends a mirrored request to its shadow group as a
+// non-streaming request and records the result. The response is discarded
+// and the shadow group keys are not penalized on failure.
+func (ps *ProxyServer) sendShadowRequest(ctx context.Context, req *shadowRequest) {
+	startTime := time.Now()
+	group, err := ps.resolveShadowGroup(req)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"group":        req.sourceGroup.Name,
+			"shadow_group": req.shadowGroup,
+		}).Warn("Skipping mirrored request")
+		return
+	}
+
+	logEntry := &models.RequestLog{
+		GroupID:         group.ID,
+		GroupName:       group.Name,
+		ParentGroupID:   req.sourceGroup.ID,
+		ParentGroupName: req.sourceGroup.Name,
+		SourceIP:        req.sourceIP,
+		RequestType:     models.RequestTypeShadow,
+	}
+	defer ps.recordShadowLog(logEntry, startTime)
+
+	channelHandler, err := ps.channelFactory.GetChannel(group)
+	if err != nil {
+		logEntry.StatusCode = http.StatusInternalServerError
+		logEntry.ErrorMessage = fmt.Sprintf("failed to get channel: %v", err)
+		return
+	}
+	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	if err != nil {
+		logEntry.StatusCode = http.StatusServiceUnavailable
+		logEntry.ErrorMessage = err.Error()
+		return
+	}
+	ps.applyShadowKey(logEntry, apiKey)
+
+	path, rawQuery, body := shadowNonStreamRequest(req.path, req.rawQuery, req.body)
+	body, originalModel := ps.applyModelMapping(body, group)
+	body, err = ps.applyParamOverrides(body, group)
+	if err != nil {
+		logEntry.StatusCode = http.StatusInternalServerError
+		logEntry.ErrorMessage = fmt.Sprintf("failed to apply parameter overrides: %v", err)
+		return
+	}
+
+	proxyURL := &url.URL{Path: "/proxy/" + group.Name + path, RawQuery: rawQuery}
+	logEntry.RequestPath = utils.TruncateString(utils.SanitizeURLForLog(proxyURL), 500)
+	upstream, err := channelHandler.SelectUpstreamWithClients(proxyURL, group.Name)
+	if err == nil && (upstream == nil || upstream.URL == "" || upstream.HTTPClient == nil) {
+		err = errors.New("empty result")
+	}
+	if err != nil {
+		logEntry.StatusCode = http.StatusInternalServerError
+		logEntry.ErrorMessage = fmt.Sprintf("failed to select upstream: %v", err)
+		return
+	}
+	logEntry.UpstreamAddr = utils.TruncateString(formatUpstreamAddrForLog(upstream.URL, upstream.ProxyURL, upstream.GatewayProxy), 500)
+
+	ctx, cancel := context.WithTimeout(ctx, shadowRequestTimeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, upstream.URL, bytes.NewReader(body))
+	if err != nil {
+		logEntry.StatusCode = http.StatusInternalServerError
+		logEntry.ErrorMessage = fmt.Sprintf("failed to create request: %v", err)
+		return
+	}
+	httpReq.Header = req.header.Clone()
+	utils.CleanClientAuthHeaders(httpReq)
+	utils.CleanAnonymizationHeaders(httpReq)
+	// Let the transport negotiate compression so the body can be parsed.
+	httpReq.Header.Del("Accept-Encoding")
+
+	body, redirectedModel, _, err := channelHandler.ApplyModelRedirectWithIndex(httpReq, body, group)
+	if err != nil {
+		logEntry.StatusCode = http.StatusBadRequest
+		logEntry.ErrorMessage = err.Error()
+		return
+	}
+	if originalModel == "" {
+		originalModel = redirectedModel
+	}
+	httpReq.Body = io.NopCloser(bytes.NewReader(body))
+	httpReq.ContentLength = int64(len(body))
+	channelHandler.ModifyRequest(httpReq, apiKey, group)
+	if len(group.HeaderRuleList) > 0 {
+		utils.ApplyHeaderRules(httpReq, group.HeaderRuleList, utils.NewHeaderVariableContext(group, apiKey))
+	}
+
+	logEntry.Model = channelHandler.ExtractModel(&gin.Context{Request: httpReq}, body)
+	if originalModel != "" && originalModel != logEntry.Model {
+		logEntry.MappedModel = originalModel
+	}
+
+	resp, err := upstream.HTTPClient.Do(httpReq)
+	if err != nil {
+		logEntry.StatusCode = http.StatusBadGateway
+		logEntry.ErrorMessage = utils.TruncateString(err.Error(), 500)
+		return
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamErrorBodySize*16))
+	logEntry.StatusCode = resp.StatusCode
+	if err != nil {
+		logEntry.ErrorMessage = fmt.Sprintf("failed to read response: %v", err)
+		return
+	}
+
+	if resp.StatusCode >= 400 {
+		logEntry.ErrorMessage = sanitizeAndTruncateBytesForLog(responseBody, 500)
+	} else {
+		logEntry.IsSuccess = true
+		if usage, ok := tokenusage.FromResponseBody(responseBody); ok {
+			logEntry.InputTokens = usage.InputTokens
+			logEntry.OutputTokens = usage.OutputTokens
+			logEntry.TotalTokens = usage.TotalTokens
+			logEntry.CacheReadTokens = usage.CacheReadTokens
+			logEntry.CacheWriteTokens = usage.CacheWriteTokens
+			logEntry.ThinkingTokens = usage.ThinkingTokens
+			logEntry.TokenUsageSource = models.TokenUsageSourceUpstream
+		}
+	}
+	if getGroupConfigBool(req.sourceGroup, "shadow_log_response_body") {
+		redactor := logRedactor(group)
+		logEntry.ResponseBody = sanitizeAndTruncateStringForLog(redactor.Redact(string(responseBody)), maxResponseCaptureBytes)
+	}
+}
+
+// applyShadowKey stores the encrypted key and its hash on a shadow log entry.
+func (ps *ProxyServer) applyShadowKey(logEntry *models.RequestLog, apiKey *models.APIKey) {
+	encryptedKeyValue, err := ps.encryptionSvc.Encrypt(apiKey.StoredValue())
+	if err != nil {
+		logrus.WithError(err).Error("Failed to encrypt key value for logging")
+		logEntry.KeyValue = "failed-to-encryption"
+	} else {
+		logEntry.KeyValue = encryptedKeyValue
+	}
+	logEntry.KeyHash = ps.encryptionSvc.Hash(apiKey.StoredValue())
+}
+
+func (ps *ProxyServer) recordShadowLog(logEntry *models.RequestLog, startTime time.Time) {
+	if ps.requestLogService == nil {
+		return
+	}
+	logEntry.Duration = time.Since(startTime).Milliseconds()
+	if err := ps.requestLogService.Record(logEntry); err != nil {
+		logrus.Errorf("Failed to record shadow request log: %v", err)
+	}
+}
+
+// shadowNonStreamRequest rewrites a mirrored request so that it is served
+// without streaming: the OpenAI and Anthropic stream flag is cleared and the
+// Gemini native stream endpoint is replaced by its non-streaming variant.
+func shadowNonStreamRequest(path, rawQuery string, body []byte) (string, string, []byte) {
+	if isGeminiNativeGenerateContentPath(path) {
+		path = applyGeminiNativeStreamPathOverride(path, false, true)
+		if query, err := url.ParseQuery(rawQuery); err == nil && query.Has("alt") {
+			query.Del("alt")
+			rawQuery = query.Encode()
+		}
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return path, rawQuery, body
+	}
+	if _, ok := payload["stream"]; !ok {
+		return path, rawQuery, body
+	}
+	payload["stream"] = false
+	delete(payload, "stream_options")
+	if rewritten, err := json.Marshal(payload); err == nil {
+		body = rewritten
+	}
+	return path, rawQuery, body
+}
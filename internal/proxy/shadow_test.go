@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// pendingRequestLogs returns the request logs recorded in the store and not yet flushed.
+func pendingRequestLogs(t *testing.T, memStore store.Store) []models.RequestLog {
+	t.Helper()
+	keys, err := memStore.SPopN(services.PendingLogKeysSet, 100)
+	require.NoError(t, err)
+	logs := make([]models.RequestLog, 0, len(keys))
+	for _, key := range keys {
+		data, err := memStore.Get(key)
+		require.NoError(t, err)
+		var entry models.RequestLog
+		require.NoError(t, json.Unmarshal(data, &entry))
+		logs = append(logs, entry)
+	}
+	return logs
+}
+
+func TestHandleProxyMirrorsToShadowGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, memStore := setupTestProxyServerWithStore(t, db)
+
+	var mu sync.Mutex
+	shadowBodies := [][]byte{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Authorization") == "Bearer sk-shadow" {
+			mu.Lock()
+			shadowBodies = append(shadowBodies, body)
+			mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"id":"s","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"from shadow"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"id\":\"p\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"from live\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(upstream.Close)
+
+	candidate := createTestGroup(t, db, "candidate", "openai")
+	candidate.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	candidate.ParamOverrides = map[string]any{"temperature": 0.1}
+	require.NoError(t, db.Save(candidate).Error)
+	createTestKey(t, db, candidate.ID, "sk-shadow", ps.encryptionSvc)
+
+	group := createTestGroup(t, db, "live", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.Config = map[string]any{
+		"shadow_group":             candidate.Name,
+		"shadow_log_response_body": true,
+	}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-live", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	sent := make(chan struct{}, 1)
+	ps.shadowMirror = newShadowMirror(shadowQueueSize, func(ctx context.Context, req *shadowRequest) {
+		ps.sendShadowRequest(ctx, req)
+		sent <- struct{}{}
+	})
+	t.Cleanup(func() {
+		ps.StopShadowMirror(context.Background())
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"model":"m","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hello"}]}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/proxy/live/v1/chat/completions", bytes.NewReader([]byte(body)))
+	c.Params = gin.Params{{Key: "group_name", Value: group.Name}}
+	ps.HandleProxy(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "from live")
+
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("mirrored request was not sent")
+	}
+
+	mu.Lock()
+	require.Len(t, shadowBodies, 1)
+	shadowBody := shadowBodies[0]
+	mu.Unlock()
+	assert.False(t, gjson.GetBytes(shadowBody, "stream").Bool(), "streaming requests are mirrored as non-streaming")
+	assert.False(t, gjson.GetBytes(shadowBody, "stream_options").Exists())
+	assert.Equal(t, 0.1, gjson.GetBytes(shadowBody, "temperature").Float())
+
+	var shadowLog *models.RequestLog
+	logs := pendingRequestLogs(t, memStore)
+	for i := range logs {
+		if logs[i].RequestType == models.RequestTypeShadow {
+			shadowLog = &logs[i]
+		}
+	}
+	require.NotNil(t, shadowLog)
+	assert.Equal(t, candidate.ID, shadowLog.GroupID)
+	assert.Equal(t, group.ID, shadowLog.ParentGroupID)
+	assert.True(t, shadowLog.IsSuccess)
+	assert.Equal(t, http.StatusOK, shadowLog.StatusCode)
+	assert.Equal(t, "m", shadowLog.Model)
+	assert.Equal(t, int64(7), shadowLog.TotalTokens)
+	assert.Contains(t, shadowLog.ResponseBody, "from shadow")
+	assert.False(t, shadowLog.IsStream)
+}
+
+func TestShadowMirrorSkipsExplainAndDryRun(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := setupTestDB(t)
+	ps, _ := setupTestProxyServerWithStore(t, db)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"p","object":"chat.completion","choices":[]}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	candidate := createTestGroup(t, db, "candidate", "openai")
+	createTestKey(t, db, candidate.ID, "sk-shadow", ps.encryptionSvc)
+	group := createTestGroup(t, db, "live", "openai")
+	group.Upstreams = []byte(`[{"url":"` + upstream.URL + `","weight":100}]`)
+	group.Config = map[string]any{"shadow_group": candidate.Name}
+	require.NoError(t, db.Save(group).Error)
+	createTestKey(t, db, group.ID, "sk-live", ps.encryptionSvc)
+	require.NoError(t, ps.keyProvider.LoadKeysFromDB())
+	require.NoError(t, ps.groupManager.Initialize())
+	t.Cleanup(func() {
+		ps.groupManager.Stop(context.Background())
+	})
+
+	// Keep the workers from starting so mirrored requests stay queued.
+	mirror := newShadowMirror(shadowQueueSize, func(context.Context, *shadowRequest) {})
+	mirror.startOnce.Do(func() {})
+	ps.shadowMirror = mirror
+
+	body := []byte(`{"model":"m","messages":[{"role":"user","content":"hello"}]}`)
+	_, err := ps.ExplainRequest(context.Background(), group.Name, ExplainOptions{Path: "/v1/chat/completions", Body: body})
+	require.NoError(t, err)
+	_, err = ps.ReplayRequestLog(context.Background(), &models.RequestLog{
+		GroupName:   group.Name,
+		RequestPath: "/proxy/live/v1/chat/completions",
+		RequestBody: string(body),
+	}, ReplayOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Empty(t, mirror.queue, "explain and dry-run requests are not mirrored")
+
+	_, err = ps.ReplayRequestLog(context.Background(), &models.RequestLog{
+		GroupName:   group.Name,
+		RequestPath: "/proxy/live/v1/chat/completions",
+		RequestBody: string(body),
+	}, ReplayOptions{})
+	require.NoError(t, err)
+	assert.Len(t, mirror.queue, 1, "replays that are sent are mirrored")
+}
+
+func TestShadowMirrorQueueIsBounded(t *testing.T) {
+	t.Parallel()
+
+	mirror := newShadowMirror(1, func(ctx context.Context, _ *shadowRequest) {
+		<-ctx.Done()
+	})
+
+	accepted := 0
+	for range shadowWorkers + 10 {
+		if mirror.enqueue(&shadowRequest{}) {
+			accepted++
+		}
+	}
+	assert.GreaterOrEqual(t, accepted, 1)
+	assert.LessOrEqual(t, accepted, shadowWorkers+1, "busy workers plus one queued request")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	mirror.Stop(ctx)
+	assert.NoError(t, ctx.Err(), "stop cancels in-flight requests")
+	assert.False(t, mirror.enqueue(&shadowRequest{}))
+}
+
+func TestShadowMirrorCountsDroppedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Without workers the single queue slot stays taken.
+	mirror := newShadowMirror(1, func(context.Context, *shadowRequest) {})
+	mirror.startOnce.Do(func() {})
+	ps := &ProxyServer{shadowMirror: mirror}
+	group := &models.Group{Name: "live", Config: map[string]any{"shadow_group": "candidate"}}
+
+	for range 3 {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/proxy/live/v1/chat/completions", nil)
+		ps.mirrorToShadowGroup(c, group, []byte(`{"model":"m"}`))
+	}
+	assert.Len(t, mirror.queue, 1)
+	assert.Equal(t, int64(2), mirror.dropped.Load())
+}
+
+func TestShadowNonStreamRequest(t *testing.T) {
+	t.Parallel()
+
+	path, query, body := shadowNonStreamRequest("/v1beta/models/gemini-pro:streamGenerateContent", "alt=sse&key=x", []byte(`{"contents":[]}`))
+	assert.Equal(t, "/v1beta/models/gemini-pro:generateContent", path)
+	assert.Equal(t, "key=x", query)
+	assert.JSONEq(t, `{"contents":[]}`, string(body))
+
+	path, query, body = shadowNonStreamRequest("/v1/messages", "", []byte(`{"model":"claude","stream":true}`))
+	assert.Equal(t, "/v1/messages", path)
+	assert.Empty(t, query)
+	assert.JSONEq(t, `{"model":"claude","stream":false}`, string(body))
+
+	_, _, body = shadowNonStreamRequest("/v1/chat/completions", "", []byte(`not json`))
+	assert.Equal(t, "not json", string(body))
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+)
+
+// admitStoreEntry counts a new TTL-bound store entry against a per-group limit.
+// Set members cannot expire individually, so members are indexed in TTL-sized
+// epochs and an entry counts while its epoch or the previous one is current.
+// The limit is therefore approximate, but it is shared by every node using the
+// same store. Each epoch set expires once it can no longer be counted.
+func (ps *ProxyServer) admitStoreEntry(indexPrefix string, groupID uint, ttl time.Duration, maxEntries int, member string) (bool, error) {
+	epoch := time.Now().UnixNano() / int64(ttl)
+	current := storeEntryIndexKey(indexPrefix, groupID, epoch)
+	previous := storeEntryIndexKey(indexPrefix, groupID, epoch-1)
+
+	currentCount, err := ps.store.SCard(current)
+	if err != nil {
+		return false, err
+	}
+	previousCount, err := ps.store.SCard(previous)
+	if err != nil {
+		return false, err
+	}
+	if currentCount+previousCount >= int64(maxEntries) {
+		return false, nil
+	}
+	if err := ps.store.SAdd(current, member); err != nil {
+		return false, err
+	}
+	// The set is counted until the next epoch ends, at most 2*ttl from now.
+	if err := ps.store.Expire(current, 2*ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func storeEntryIndexKey(indexPrefix string, groupID uint, epoch int64) string {
+	return fmt.Sprintf("%s%d:%d", indexPrefix, groupID, epoch)
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmitStoreEntryExpiresEpochIndex(t *testing.T) {
+	t.Parallel()
+
+	memStore := store.NewMemoryStore()
+	t.Cleanup(func() { _ = memStore.Close() })
+	ps := &ProxyServer{store: memStore}
+	ttl := 100 * time.Millisecond
+
+	admitted, err := ps.admitStoreEntry("admission_test:", 1, ttl, 1, "a")
+	require.NoError(t, err)
+	assert.True(t, admitted)
+	admitted, err = ps.admitStoreEntry("admission_test:", 1, ttl, 1, "b")
+	require.NoError(t, err)
+	assert.False(t, admitted, "the limit counts the current epoch")
+
+	// With no further writes, the epoch sets are removed by their own TTL.
+	time.Sleep(2*ttl + 50*time.Millisecond)
+	epoch := time.Now().UnixNano() / int64(ttl)
+	for back := range int64(4) {
+		count, err := memStore.SCard(storeEntryIndexKey("admission_test:", 1, epoch-back))
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	}
+	admitted, err = ps.admitStoreEntry("admission_test:", 1, ttl, 1, "c")
+	require.NoError(t, err)
+	assert.True(t, admitted)
+}
@@ -0,0 +1,269 @@
+// Package redaction applies configurable redaction rules to request and
+// response bodies before they are written to request logs.
+package redaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gpt-load/internal/utils"
+)
+
+// Built-in detector names.
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+	DetectorAPIKey     = "api_key"
+)
+
+const (
+	// DefaultReplacement is used for custom patterns without a replacement.
+	DefaultReplacement = "[REDACTED]"
+	// DroppedValue replaces values selected by drop paths.
+	DroppedValue = "[DROPPED]"
+
+	maxPatterns  = 50
+	maxDropPaths = 50
+)
+
+// Pattern is a custom regular expression redaction rule.
+type Pattern struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Rules is the JSON representation of a redaction configuration, e.g.
+//
+//	{"detectors":["email","credit_card"],"patterns":[{"pattern":"user-\\d+"}],"drop_paths":["messages[*].content"]}
+type Rules struct {
+	Detectors []string  `json:"detectors,omitempty"`
+	Patterns  []Pattern `json:"patterns,omitempty"`
+	DropPaths []string  `json:"drop_paths,omitempty"`
+}
+
+type detector struct {
+	pattern     *regexp.Regexp
+	replacement string
+	validate    func(string) bool
+}
+
+var detectors = map[string][]detector{
+	DetectorEmail:      {{pattern: utils.EmailPattern, replacement: "[REDACTED_EMAIL]"}},
+	DetectorPhone:      {{pattern: utils.PhonePattern, replacement: "[REDACTED_PHONE]"}},
+	DetectorCreditCard: {{pattern: utils.CreditCardPattern, replacement: "[REDACTED_CARD]", validate: utils.IsLuhnValid}},
+	DetectorAPIKey: {
+		{pattern: utils.APIKeyPattern, replacement: "[REDACTED_API_KEY]"},
+		{pattern: utils.APIKeyLikePattern, replacement: "[REDACTED_API_KEY]"},
+		{pattern: utils.AWSAccessKeyPattern, replacement: "[REDACTED_AWS_KEY]"},
+	},
+}
+
+// Redactor is a compiled set of rules. A nil Redactor leaves bodies unchanged.
+type Redactor struct {
+	dropPaths [][]string
+	matchers  []detector
+}
+
+var cache sync.Map // raw rules string -> *Redactor
+
+// Compile is Parse with results cached by the raw string, for configured
+// rules that are read on every logged request.
+func Compile(raw string) (*Redactor, error) {
+	raw = strings.TrimSpace(raw)
+	if cached, ok := cache.Load(raw); ok {
+		return cached.(*Redactor), nil
+	}
+	r, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	cache.Store(raw, r)
+	return r, nil
+}
+
+// Parse parses and compiles a rules JSON string. Empty input yields a nil
+// Redactor.
+func Parse(raw string) (*Redactor, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var rules Rules
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("invalid redaction rules: %w", err)
+	}
+	return New(rules)
+}
+
+// Validate reports whether raw is a valid rules JSON string.
+func Validate(raw string) error {
+	_, err := Parse(raw)
+	return err
+}
+
+// New compiles rules into a Redactor.
+func New(rules Rules) (*Redactor, error) {
+	if len(rules.Patterns) > maxPatterns {
+		return nil, fmt.Errorf("too many redaction patterns: at most %d allowed", maxPatterns)
+	}
+	if len(rules.DropPaths) > maxDropPaths {
+		return nil, fmt.Errorf("too many drop paths: at most %d allowed", maxDropPaths)
+	}
+
+	r := &Redactor{}
+	for _, path := range rules.DropPaths {
+		segments, err := parsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		r.dropPaths = append(r.dropPaths, segments)
+	}
+	for _, name := range rules.Detectors {
+		matchers, ok := detectors[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction detector %q", name)
+		}
+		r.matchers = append(r.matchers, matchers...)
+	}
+	for _, p := range rules.Patterns {
+		if strings.TrimSpace(p.Pattern) == "" {
+			return nil, fmt.Errorf("redaction pattern must not be empty")
+		}
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p.Pattern, err)
+		}
+		replacement := p.Replacement
+		if replacement == "" {
+			replacement = DefaultReplacement
+		}
+		r.matchers = append(r.matchers, detector{pattern: re, replacement: replacement})
+	}
+	return r, nil
+}
+
+// Redact applies drop paths (when body is JSON) followed by detectors and
+// custom patterns.
+func (r *Redactor) Redact(body string) string {
+	if r == nil || body == "" {
+		return body
+	}
+	if len(r.dropPaths) > 0 {
+		body = r.dropJSONPaths(body)
+	}
+	for _, m := range r.matchers {
+		if m.validate == nil {
+			body = m.pattern.ReplaceAllLiteralString(body, m.replacement)
+			continue
+		}
+		body = m.pattern.ReplaceAllStringFunc(body, func(match string) string {
+			if m.validate(match) {
+				return m.replacement
+			}
+			return match
+		})
+	}
+	return body
+}
+
+func (r *Redactor) dropJSONPaths(body string) string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil || decoder.More() {
+		return body
+	}
+	changed := false
+	for _, segments := range r.dropPaths {
+		doc = dropPath(doc, segments, &changed)
+	}
+	if !changed {
+		return body
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return body
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// dropPath replaces every value matched by segments with DroppedValue.
+func dropPath(node any, segments []string, changed *bool) any {
+	if len(segments) == 0 {
+		*changed = true
+		return DroppedValue
+	}
+	segment, rest := segments[0], segments[1:]
+	switch v := node.(type) {
+	case map[string]any:
+		if segment == "*" {
+			for key, child := range v {
+				v[key] = dropPath(child, rest, changed)
+			}
+		} else if child, ok := v[segment]; ok {
+			v[segment] = dropPath(child, rest, changed)
+		}
+	case []any:
+		if segment == "*" {
+			for i, child := range v {
+				v[i] = dropPath(child, rest, changed)
+			}
+		} else if idx, err := strconv.Atoi(segment); err == nil && idx >= 0 && idx < len(v) {
+			v[idx] = dropPath(v[idx], rest, changed)
+		}
+	}
+	return node
+}
+
+// parsePath splits paths like "messages[*].content" or "input.0.text" into
+// segments. "*" matches every object field or array element.
+func parsePath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("drop path must not be empty")
+	}
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		name, indexes, found := strings.Cut(part, "[")
+		if name != "" {
+			segments = append(segments, name)
+		} else if !found {
+			return nil, fmt.Errorf("invalid drop path %q", path)
+		}
+		for found {
+			var index string
+			index, indexes, found = strings.Cut(indexes, "]")
+			if !found || !isIndex(index) {
+				return nil, fmt.Errorf("invalid drop path %q", path)
+			}
+			segments = append(segments, index)
+			if indexes == "" {
+				break
+			}
+			if !strings.HasPrefix(indexes, "[") {
+				return nil, fmt.Errorf("invalid drop path %q", path)
+			}
+			indexes = indexes[1:]
+		}
+	}
+	return segments, nil
+}
+
+func isIndex(s string) bool {
+	if s == "*" {
+		return true
+	}
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -61,7 +61,8 @@ func NewRouter(
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.Logger(configManager.GetLogConfig()))
 	router.Use(middleware.CORS(configManager.GetCORSConfig()))
-	router.Use(middleware.RateLimiter(configManager.GetPerformanceConfig()))
+	// Live log viewers hold their connection open and must not use up request slots.
+	router.Use(middleware.RateLimiter(configManager.GetPerformanceConfig(), "/api/logs/stream"))
 	router.Use(middleware.SecurityHeaders())
 	// Request body size limit protects against memory exhaustion from large requests
 	// Default: 150MB to support large bulk key imports (500k+ keys)
@@ -99,6 +100,8 @@ func NewRouter(
 // registerSystemRoutes registers system-level routes
 func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
 	router.GET("/health", serverHandler.Health)
+	router.GET("/health/live", serverHandler.Live)
+	router.GET("/health/ready", serverHandler.Ready)
 }
 
 // registerAPIRoutes registers API routes
@@ -147,9 +150,23 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		groups.PUT("/:id", serverHandler.UpdateGroup)
 		groups.DELETE("/:id", serverHandler.DeleteGroup)
 		groups.GET("/:id/stats", serverHandler.GetGroupStats)
+		groups.POST("/:id/explain", serverHandler.ExplainGroupRequest)
 		groups.POST("/:id/copy", serverHandler.CopyGroup)
 		groups.PUT("/:id/toggle-enabled", serverHandler.ToggleGroupEnabled)
 		groups.GET("/:id/export", serverHandler.ExportGroup)
+		groups.GET("/:id/revisions", serverHandler.ListGroupRevisions)
+		groups.GET("/:id/revisions/:revision", serverHandler.GetGroupRevision)
+		groups.GET("/:id/revisions/:revision/diff", serverHandler.DiffGroupRevision)
+		groups.POST("/:id/revisions/:revision/rollback", serverHandler.RollbackGroupRevision)
+		groups.GET("/:id/scheduled-changes", serverHandler.ListScheduledGroupChanges)
+		groups.POST("/:id/scheduled-changes", serverHandler.ScheduleGroupChange)
+		groups.DELETE("/:id/scheduled-changes/:changeId", serverHandler.CancelScheduledGroupChange)
+		groups.GET("/:id/experiments", serverHandler.ListGroupExperiments)
+		groups.POST("/:id/experiments", serverHandler.CreateGroupExperiment)
+		groups.GET("/:id/experiments/:experimentId", serverHandler.GetGroupExperiment)
+		groups.PUT("/:id/experiments/:experimentId/traffic", serverHandler.UpdateGroupExperimentTraffic)
+		groups.POST("/:id/experiments/:experimentId/promote", serverHandler.PromoteGroupExperiment)
+		groups.POST("/:id/experiments/:experimentId/abort", serverHandler.AbortGroupExperiment)
 		groups.POST("/import", serverHandler.ImportGroup)
 
 		groups.GET("/:id/sub-groups", serverHandler.GetSubGroups)
@@ -211,6 +228,8 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		dashboard.GET("/stats", serverHandler.Stats)
 		dashboard.GET("/chart", serverHandler.Chart)
 		dashboard.GET("/token-usage", serverHandler.TokenUsage)
+		dashboard.GET("/experiments/:id", serverHandler.ExperimentComparison)
+		dashboard.GET("/shadow", serverHandler.ShadowReport)
 		dashboard.GET("/encryption-status", serverHandler.EncryptionStatus)
 	}
 
@@ -218,7 +237,11 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	logs := api.Group("/logs")
 	{
 		logs.GET("", serverHandler.GetLogs)
+		logs.GET("/stream", serverHandler.StreamLogs)
 		logs.GET("/export", serverHandler.ExportLogs)
+		logs.GET("/export/full", serverHandler.ExportFullLogs)
+		logs.POST("/redaction/preview", serverHandler.PreviewLogRedaction)
+		logs.POST("/:id/replay", serverHandler.ReplayLog)
 	}
 
 	// Settings
@@ -229,6 +252,23 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		settings.PUT("", serverHandler.UpdateSettings)
 	}
 
+	// Encryption keyring
+	encryption := api.Group("/encryption")
+	{
+		encryption.GET("", serverHandler.GetEncryptionStatus)
+		encryption.POST("/rotate", serverHandler.RotateEncryptionKey)
+	}
+
+	// Debug capture
+	debug := api.Group("/debug")
+	{
+		debug.POST("/tokens", serverHandler.IssueDebugToken)
+		debug.DELETE("/tokens", serverHandler.RevokeDebugToken)
+		debug.GET("/captures", serverHandler.ListDebugCaptures)
+		debug.GET("/captures/:id", serverHandler.GetDebugCapture)
+		debug.DELETE("/captures/:id", serverHandler.DeleteDebugCapture)
+	}
+
 	// Proxy pool
 	proxyPool := api.Group("/proxy-pool")
 	{
@@ -348,16 +348,16 @@ func TestRegisterSystemRoutes_HealthEndpoint(t *testing.T) {
 	mockHandler := &handler.Server{}
 	registerSystemRoutes(router, mockHandler)
 
-	// Verify health endpoint is registered
-	routes := router.Routes()
-	found := false
-	for _, route := range routes {
-		if route.Path == "/health" && route.Method == "GET" {
-			found = true
-			break
+	// Verify health endpoints are registered
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		if route.Method == "GET" {
+			registered[route.Path] = true
 		}
 	}
-	assert.True(t, found, "Health endpoint should be registered")
+	for _, path := range []string{"/health", "/health/live", "/health/ready"} {
+		assert.True(t, registered[path], "%s should be registered", path)
+	}
 }
 
 func BenchmarkEmbedFolderExists(b *testing.B) {
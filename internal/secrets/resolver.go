@@ -0,0 +1,303 @@
+// Package secrets resolves upstream key references such as env:NAME,
+// file:/run/secrets/openai or vault:secret/openai#api_key, so provider keys
+// can live outside the application database.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultRefreshInterval is how long a resolved value is used before it
+	// is fetched again.
+	DefaultRefreshInterval = 5 * time.Minute
+	// retryInterval is how long a failed fetch is cached before the next
+	// attempt, so an unavailable provider is not queried on every request.
+	retryInterval = 30 * time.Second
+	fetchTimeout  = 10 * time.Second
+
+	// DefaultEnvPrefix is the prefix env: references must use unless
+	// SECRET_ENV_PREFIX sets another one.
+	DefaultEnvPrefix = "GPT_LOAD_SECRET_"
+	// DefaultFileDir is the directory file: references must point into
+	// unless SECRET_FILE_DIR sets another one.
+	DefaultFileDir = "/run/secrets"
+)
+
+// Provider fetches the secret a reference points to. The reference is passed
+// without its scheme prefix.
+type Provider interface {
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// entry is the cached state of one reference.
+type entry struct {
+	value     string
+	fetchedAt time.Time
+	err       error
+	failedAt  time.Time
+	// loading is closed when a fetch for an entry without a value finishes.
+	loading    chan struct{}
+	refreshing bool
+}
+
+// Resolver resolves secret references and caches the values. A value older
+// than the refresh interval is still returned while it is fetched again in
+// the background; when that fetch fails, the last value stays in use.
+type Resolver struct {
+	providers       map[string]Provider
+	refreshInterval time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewResolver creates a resolver for the given scheme providers.
+func NewResolver(providers map[string]Provider, refreshInterval time.Duration) *Resolver {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	return &Resolver{
+		providers:       providers,
+		refreshInterval: refreshInterval,
+		entries:         make(map[string]*entry),
+	}
+}
+
+// NewResolverFromEnv creates a resolver for env:, file: and, when VAULT_ADDR
+// is set, vault: references.
+func NewResolverFromEnv() (*Resolver, error) {
+	providers := map[string]Provider{
+		"env":  NewEnvProviderFromEnv(),
+		"file": NewFileProviderFromEnv(),
+	}
+	vault, err := newVaultProviderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if vault != nil {
+		providers["vault"] = vault
+	}
+	refresh := time.Duration(utils.ParseInteger(os.Getenv("SECRET_REFRESH_INTERVAL"), int(DefaultRefreshInterval/time.Second))) * time.Second
+	return NewResolver(providers, refresh), nil
+}
+
+// IsReference reports whether value uses one of the reference schemes.
+func IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	if !ok {
+		return false
+	}
+	switch scheme {
+	case "env", "file", "vault":
+		return true
+	}
+	return false
+}
+
+// Resolve returns the secret a reference points to. Values that are not
+// references are returned unchanged.
+func (r *Resolver) Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	e, ok := r.entries[value]
+	if !ok {
+		e = &entry{}
+		r.entries[value] = e
+	}
+	if e.value != "" {
+		now := time.Now()
+		if now.Sub(e.fetchedAt) >= r.refreshInterval && !e.refreshing && now.Sub(e.failedAt) >= retryInterval {
+			e.refreshing = true
+			go r.refresh(value, e)
+		}
+		resolved := e.value
+		r.mu.Unlock()
+		return resolved, nil
+	}
+
+	// No value yet: wait for the fetch in flight, or start one unless the
+	// last attempt failed recently.
+	loading := e.loading
+	if loading == nil {
+		if !e.failedAt.IsZero() && time.Since(e.failedAt) < retryInterval {
+			err := e.err
+			r.mu.Unlock()
+			return "", err
+		}
+		e.loading = make(chan struct{})
+		r.mu.Unlock()
+		r.fetch(value, e)
+	} else {
+		r.mu.Unlock()
+		<-loading
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.value == "" {
+		return "", e.err
+	}
+	return e.value, nil
+}
+
+// fetch loads a reference that has no value yet and wakes up the waiters.
+func (r *Resolver) fetch(ref string, e *entry) {
+	value, err := r.load(ref)
+	r.mu.Lock()
+	r.store(e, value, err)
+	close(e.loading)
+	e.loading = nil
+	r.mu.Unlock()
+	if err != nil {
+		logrus.WithError(err).WithField("ref", ref).Warn("Failed to resolve secret reference")
+	}
+}
+
+// refresh fetches a reference again, keeping the old value on failure.
+func (r *Resolver) refresh(ref string, e *entry) {
+	value, err := r.load(ref)
+	r.mu.Lock()
+	r.store(e, value, err)
+	e.refreshing = false
+	r.mu.Unlock()
+	if err != nil {
+		logrus.WithError(err).WithField("ref", ref).Warn("Failed to refresh secret reference, keeping the previous value")
+	}
+}
+
+// store records the outcome of a fetch. The caller must hold r.mu.
+func (r *Resolver) store(e *entry, value string, err error) {
+	if err != nil {
+		e.err, e.failedAt = err, time.Now()
+		return
+	}
+	e.value, e.fetchedAt, e.err, e.failedAt = value, time.Now(), nil, time.Time{}
+}
+
+func (r *Resolver) load(ref string) (string, error) {
+	scheme, path, _ := strings.Cut(ref, ":")
+	provider, ok := r.providers[scheme]
+	if !ok {
+		return "", fmt.Errorf("secret provider %q is not configured", scheme)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	value, err := provider.Fetch(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", scheme, err)
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s: secret %s is empty", scheme, path)
+	}
+	return value, nil
+}
+
+// Invalidate drops cached values so they are fetched on next use.
+func (r *Resolver) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ref, e := range r.entries {
+		// Fetches in flight still report to their waiters.
+		if e.loading == nil {
+			delete(r.entries, ref)
+		}
+	}
+}
+
+// EnvProvider reads env:NAME references from the process environment. Only
+// names that start with Prefix can be read, so a reference cannot expose the
+// gateway's own settings such as AUTH_KEY.
+type EnvProvider struct {
+	Prefix string
+}
+
+// NewEnvProviderFromEnv creates an EnvProvider for SECRET_ENV_PREFIX.
+func NewEnvProviderFromEnv() EnvProvider {
+	return EnvProvider{Prefix: utils.GetEnvOrDefault("SECRET_ENV_PREFIX", DefaultEnvPrefix)}
+}
+
+// Fetch returns the trimmed value of the environment variable name.
+func (p EnvProvider) Fetch(_ context.Context, name string) (string, error) {
+	if p.Prefix == "" || !strings.HasPrefix(name, p.Prefix) {
+		return "", fmt.Errorf("environment variable %s is not allowed, names must start with %s", name, p.Prefix)
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// FileProvider reads file:/path references, e.g. mounted Docker or
+// Kubernetes secrets. Only files inside one of Dirs can be read, also after
+// following symlinks. Surrounding whitespace is trimmed.
+type FileProvider struct {
+	Dirs []string
+}
+
+// NewFileProviderFromEnv creates a FileProvider for SECRET_FILE_DIR and any
+// further allowed directories.
+func NewFileProviderFromEnv(dirs ...string) FileProvider {
+	return FileProvider{Dirs: append([]string{utils.GetEnvOrDefault("SECRET_FILE_DIR", DefaultFileDir)}, dirs...)}
+}
+
+// Fetch returns the trimmed content of the file at path.
+func (p FileProvider) Fetch(_ context.Context, path string) (string, error) {
+	if !p.allows(path) {
+		return "", fmt.Errorf("file %s is outside the allowed secret directories", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if !p.allows(resolved) {
+		return "", fmt.Errorf("file %s links outside the allowed secret directories", path)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// allows reports whether path lies inside one of the allowed directories.
+func (p FileProvider) allows(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range p.Dirs {
+		if dir == "" {
+			continue
+		}
+		candidates := []string{dir}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			candidates = append(candidates, resolved)
+		}
+		for _, candidate := range candidates {
+			candidate, err := filepath.Abs(candidate)
+			if err != nil {
+				continue
+			}
+			rel, err := filepath.Rel(candidate, path)
+			if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider returns the values set by the test and counts fetches.
+type fakeProvider struct {
+	mu      sync.Mutex
+	value   string
+	err     error
+	fetches int
+}
+
+func (p *fakeProvider) Fetch(_ context.Context, _ string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetches++
+	return p.value, p.err
+}
+
+func (p *fakeProvider) set(value string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.value, p.err = value, err
+}
+
+func (p *fakeProvider) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fetches
+}
+
+func TestIsReference(t *testing.T) {
+	t.Parallel()
+	assert.True(t, IsReference("env:OPENAI_KEY"))
+	assert.True(t, IsReference("file:/run/secrets/openai"))
+	assert.True(t, IsReference("vault:secret/openai#key"))
+	assert.False(t, IsReference("sk-1234"))
+	assert.False(t, IsReference("https://example.com"))
+	assert.False(t, IsReference("AIzaSy-env"))
+}
+
+func TestResolverEnvAndFile(t *testing.T) {
+	t.Setenv("GPT_LOAD_SECRET_TEST_SECRET", "sk-env")
+	dir := t.TempDir()
+	t.Setenv("SECRET_FILE_DIR", dir)
+	path := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(path, []byte("sk-file\n"), 0o600))
+
+	resolver, err := NewResolverFromEnv()
+	require.NoError(t, err)
+
+	value, err := resolver.Resolve("env:GPT_LOAD_SECRET_TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-env", value)
+	value, err = resolver.Resolve("file:" + path)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-file", value)
+	value, err = resolver.Resolve("sk-plain")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-plain", value)
+
+	_, err = resolver.Resolve("env:GPT_LOAD_SECRET_TEST_UNSET")
+	assert.ErrorContains(t, err, "GPT_LOAD_SECRET_TEST_UNSET is not set")
+	_, err = resolver.Resolve("vault:secret/openai#key")
+	assert.ErrorContains(t, err, "not configured")
+}
+
+func TestResolverRejectsReferencesOutsideAllowList(t *testing.T) {
+	t.Setenv("AUTH_KEY", "sk-admin")
+	dir := t.TempDir()
+	t.Setenv("SECRET_FILE_DIR", dir)
+	outside := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(outside, []byte("sk-outside"), 0o600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link")))
+
+	resolver, err := NewResolverFromEnv()
+	require.NoError(t, err)
+
+	_, err = resolver.Resolve("env:AUTH_KEY")
+	assert.ErrorContains(t, err, "must start with GPT_LOAD_SECRET_")
+	_, err = resolver.Resolve("file:" + outside)
+	assert.ErrorContains(t, err, "outside the allowed secret directories")
+	_, err = resolver.Resolve("file:" + filepath.Join(dir, "..", filepath.Base(filepath.Dir(outside)), "key"))
+	assert.ErrorContains(t, err, "outside the allowed secret directories")
+	_, err = resolver.Resolve("file:" + filepath.Join(dir, "link"))
+	assert.ErrorContains(t, err, "links outside the allowed secret directories")
+
+	t.Setenv("SECRET_ENV_PREFIX", "AUTH_")
+	value, err := NewEnvProviderFromEnv().Fetch(context.Background(), "AUTH_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-admin", value)
+}
+
+func TestResolverCachesAndRefreshes(t *testing.T) {
+	t.Parallel()
+	provider := &fakeProvider{value: "sk-1"}
+	resolver := NewResolver(map[string]Provider{"env": provider}, time.Hour)
+
+	for range 3 {
+		value, err := resolver.Resolve("env:KEY")
+		require.NoError(t, err)
+		assert.Equal(t, "sk-1", value)
+	}
+	assert.Equal(t, 1, provider.count())
+
+	// A stale value is served while it is refreshed in the background.
+	provider.set("sk-2", nil)
+	resolver.mu.Lock()
+	resolver.entries["env:KEY"].fetchedAt = time.Now().Add(-2 * time.Hour)
+	resolver.mu.Unlock()
+	value, err := resolver.Resolve("env:KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-1", value)
+	assert.Eventually(t, func() bool {
+		value, _ := resolver.Resolve("env:KEY")
+		return value == "sk-2"
+	}, time.Second, 10*time.Millisecond)
+
+	// A failed refresh keeps the previous value.
+	provider.set("", errors.New("provider down"))
+	resolver.mu.Lock()
+	resolver.entries["env:KEY"].fetchedAt = time.Now().Add(-2 * time.Hour)
+	resolver.mu.Unlock()
+	_, err = resolver.Resolve("env:KEY")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		resolver.mu.Lock()
+		defer resolver.mu.Unlock()
+		return !resolver.entries["env:KEY"].refreshing
+	}, time.Second, 10*time.Millisecond)
+	value, err = resolver.Resolve("env:KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-2", value)
+}
+
+func TestResolverBacksOffAfterFailure(t *testing.T) {
+	t.Parallel()
+	provider := &fakeProvider{err: errors.New("provider down")}
+	resolver := NewResolver(map[string]Provider{"env": provider}, time.Hour)
+
+	for range 3 {
+		_, err := resolver.Resolve("env:KEY")
+		assert.ErrorContains(t, err, "provider down")
+	}
+	assert.Equal(t, 1, provider.count())
+
+	provider.set("sk-1", nil)
+	resolver.Invalidate()
+	value, err := resolver.Resolve("env:KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-1", value)
+	assert.Equal(t, 2, provider.count())
+}
+
+func TestVaultProvider(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/team/openai" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"sk-vault","count":1},"metadata":{"version":3}}}`))
+	}))
+	defer server.Close()
+
+	provider := newVaultProvider(server.URL+"/", "test-token", "")
+	ctx := context.Background()
+	value, err := provider.Fetch(ctx, "secret/team/openai#api_key")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-vault", value)
+
+	_, err = provider.Fetch(ctx, "secret/team/openai#missing")
+	assert.ErrorContains(t, err, "no field missing")
+	_, err = provider.Fetch(ctx, "secret/team/openai#count")
+	assert.ErrorContains(t, err, "not a string")
+	_, err = provider.Fetch(ctx, "secret/team/other#api_key")
+	assert.ErrorContains(t, err, "status 404")
+	_, err = provider.Fetch(ctx, "secret/team/openai")
+	assert.ErrorContains(t, err, "no #field")
+	_, err = provider.Fetch(ctx, "openai#api_key")
+	assert.ErrorContains(t, err, "<mount>/<path>#<field>")
+
+	_, err = newVaultProvider(server.URL, "wrong", "").Fetch(ctx, "secret/team/openai#api_key")
+	assert.ErrorContains(t, err, "status 403")
+}
+
+func TestNewVaultProviderFromEnv(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	provider, err := newVaultProviderFromEnv()
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_TOKEN", "")
+	_, err = newVaultProviderFromEnv()
+	assert.ErrorContains(t, err, "VAULT_TOKEN is empty")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0o600))
+	t.Setenv("VAULT_TOKEN_FILE", tokenFile)
+	provider, err = newVaultProviderFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", provider.token)
+
+	t.Setenv("VAULT_TOKEN", "env-token")
+	_, err = newVaultProviderFromEnv()
+	assert.ErrorContains(t, err, "only one of")
+}
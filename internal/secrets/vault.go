@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// vaultProvider reads vault:<mount>/<path>#<field> references from a
+// HashiCorp Vault compatible KV version 2 HTTP API.
+type vaultProvider struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// newVaultProviderFromEnv configures the provider from VAULT_ADDR, VAULT_TOKEN
+// or VAULT_TOKEN_FILE and VAULT_NAMESPACE. It returns nil when VAULT_ADDR is
+// not set.
+func newVaultProviderFromEnv() (*vaultProvider, error) {
+	addr := strings.TrimSpace(os.Getenv("VAULT_ADDR"))
+	if addr == "" {
+		return nil, nil
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if file := strings.TrimSpace(os.Getenv("VAULT_TOKEN_FILE")); file != "" {
+		if token != "" {
+			return nil, errors.New("set only one of VAULT_TOKEN and VAULT_TOKEN_FILE")
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read VAULT_TOKEN_FILE: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return nil, errors.New("VAULT_ADDR is set but VAULT_TOKEN is empty")
+	}
+	return newVaultProvider(addr, token, os.Getenv("VAULT_NAMESPACE")), nil
+}
+
+func newVaultProvider(addr, token, namespace string) *vaultProvider {
+	return &vaultProvider{
+		addr:      strings.TrimRight(addr, "/"),
+		token:     token,
+		namespace: namespace,
+		client:    &http.Client{Timeout: fetchTimeout + time.Second},
+	}
+}
+
+// parseVaultRef splits secret/openai/prod#api_key into the mount, the secret
+// path and the field.
+func parseVaultRef(ref string) (mount, path, field string, err error) {
+	secretPath, field, ok := strings.Cut(ref, "#")
+	if !ok || field == "" {
+		return "", "", "", fmt.Errorf("reference %s has no #field", ref)
+	}
+	mount, path, ok = strings.Cut(strings.Trim(secretPath, "/"), "/")
+	if !ok || mount == "" || path == "" {
+		return "", "", "", fmt.Errorf("reference %s must be <mount>/<path>#<field>", ref)
+	}
+	return mount, path, field, nil
+}
+
+func (p *vaultProvider) Fetch(ctx context.Context, ref string) (string, error) {
+	mount, path, field, err := parseVaultRef(ref)
+	if err != nil {
+		return "", err
+	}
+	endpoint := p.addr + "/v1/" + escapePath(mount) + "/data/" + escapePath(path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// The error body never contains secret data.
+		return "", fmt.Errorf("vault returned status %d for %s/%s: %s", resp.StatusCode, mount, path, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	value, ok := secret.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no field %s", mount, path, field)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %s of secret %s/%s is not a string", field, mount, path)
+	}
+	return str, nil
+}
+
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -373,6 +373,8 @@ func (s *AggregateGroupService) AddSubGroups(ctx context.Context, groupID uint,
 		}
 	}
 
+	s.ensureBaselineRevision(ctx, groupID)
+
 	// Add new sub groups using batch insert for better performance
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Set groupID for all sub groups
@@ -391,6 +393,8 @@ func (s *AggregateGroupService) AddSubGroups(ctx context.Context, groupID uint,
 		return err
 	}
 
+	s.recordRevision(ctx, groupID)
+
 	// Restore soft-deleted health metrics for re-added sub-groups
 	if s.OnSubGroupAdded != nil {
 		for _, sg := range result.SubGroups {
@@ -413,23 +417,47 @@ func (s *AggregateGroupService) UpdateSubGroupWeight(ctx context.Context, groupI
 		return err
 	}
 
-	if err := validateSubGroupWeight(input.Weight); err != nil {
+	if err := validateSubGroupSettings(input.Weight, input.MinEffectiveWeight, input.HealthResetIntervalSeconds); err != nil {
 		return err
 	}
-	if input.MinEffectiveWeight != nil {
-		if err := validateMinEffectiveWeight(input.Weight, *input.MinEffectiveWeight); err != nil {
+
+	s.ensureBaselineRevision(ctx, groupID)
+	if err := updateSubGroupSettings(ctx, s.db, groupID, subGroupID, input); err != nil {
+		return err
+	}
+	s.recordRevision(ctx, groupID)
+
+	// Trigger cache update
+	if err := s.groupManager.Invalidate(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after updating sub group weight")
+	}
+
+	return nil
+}
+
+// validateSubGroupSettings validates the relationship-level settings of a sub group.
+func validateSubGroupSettings(weight int, minEffectiveWeight *int, healthResetIntervalSeconds *int64) error {
+	if err := validateSubGroupWeight(weight); err != nil {
+		return err
+	}
+	if minEffectiveWeight != nil {
+		if err := validateMinEffectiveWeight(weight, *minEffectiveWeight); err != nil {
 			return err
 		}
 	}
-	if input.HealthResetIntervalSeconds != nil {
-		if err := validateHealthResetIntervalSeconds(*input.HealthResetIntervalSeconds); err != nil {
+	if healthResetIntervalSeconds != nil {
+		if err := validateHealthResetIntervalSeconds(*healthResetIntervalSeconds); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+// updateSubGroupSettings writes validated settings of an existing sub group relation.
+func updateSubGroupSettings(ctx context.Context, db *gorm.DB, groupID, subGroupID uint, input UpdateSubGroupSettingsInput) error {
 	// Check if sub-group relationship exists
 	var existingRecord models.GroupSubGroup
-	if err := s.db.WithContext(ctx).Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).Limit(1).Find(&existingRecord).Error; err != nil {
+	if err := db.WithContext(ctx).Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).Limit(1).Find(&existingRecord).Error; err != nil {
 		return err
 	}
 	if existingRecord.GroupID == 0 {
@@ -448,7 +476,7 @@ func (s *AggregateGroupService) UpdateSubGroupWeight(ctx context.Context, groupI
 		updates["health_reset_interval_seconds"] = *input.HealthResetIntervalSeconds
 	}
 
-	result := s.db.WithContext(ctx).
+	result := db.WithContext(ctx).
 		Model(&models.GroupSubGroup{}).
 		Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).
 		Updates(updates)
@@ -460,13 +488,22 @@ func (s *AggregateGroupService) UpdateSubGroupWeight(ctx context.Context, groupI
 	if result.RowsAffected == 0 {
 		return NewI18nError(app_errors.ErrResourceNotFound, "group.sub_group_not_found", nil)
 	}
+	return nil
+}
 
-	// Trigger cache update
-	if err := s.groupManager.Invalidate(); err != nil {
-		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after updating sub group weight")
+// ensureBaselineRevision records the state of an aggregate group before its
+// sub groups change. Failures are logged; they never block the change.
+func (s *AggregateGroupService) ensureBaselineRevision(ctx context.Context, groupID uint) {
+	if err := ensureBaselineRevision(ctx, s.db, groupID); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("group_id", groupID).Warn("Failed to record baseline group revision")
 	}
+}
 
-	return nil
+// recordRevision records the state of an aggregate group after its sub groups changed.
+func (s *AggregateGroupService) recordRevision(ctx context.Context, groupID uint) {
+	if _, err := recordGroupRevision(ctx, s.db, groupID, RevisionSourceSubGroup, ""); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("group_id", groupID).Warn("Failed to record group revision")
+	}
 }
 
 // ResetSubGroupHealth clears runtime and persisted health metrics for a sub-group relation.
@@ -580,6 +617,8 @@ func (s *AggregateGroupService) DeleteSubGroup(ctx context.Context, groupID, sub
 		return err
 	}
 
+	s.ensureBaselineRevision(ctx, groupID)
+
 	result := s.db.WithContext(ctx).
 		Where("group_id = ? AND sub_group_id = ?", groupID, subGroupID).
 		Delete(&models.GroupSubGroup{})
@@ -592,6 +631,8 @@ func (s *AggregateGroupService) DeleteSubGroup(ctx context.Context, groupID, sub
 		return NewI18nError(app_errors.ErrResourceNotFound, "group.sub_group_not_found", nil)
 	}
 
+	s.recordRevision(ctx, groupID)
+
 	// Soft-delete health metrics for this sub-group (preserves data for potential restoration)
 	if s.OnSubGroupRemoved != nil {
 		s.OnSubGroupRemoved(groupID, subGroupID)
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// DebugTokenDefaultTTL is how long an issued debug token stays valid by default.
+	DebugTokenDefaultTTL = time.Hour
+	// DebugTokenMaxTTL bounds the lifetime of a debug token.
+	DebugTokenMaxTTL = 24 * time.Hour
+	// DebugCaptureTTL is how long a debug capture is kept.
+	DebugCaptureTTL = time.Hour
+
+	debugTokenPrefix    = "gld-"
+	debugTokenKeyPrefix = "debug_token:"
+	debugCaptureListMax = 200
+)
+
+// ErrDebugCaptureNotFound is returned when a capture does not exist or has expired.
+var ErrDebugCaptureNotFound = errors.New("debug capture not found")
+
+// DebugCaptureService issues debug tokens and stores the captures taken for
+// requests that present one. Tokens live in the shared store so every node
+// accepts them; captures live in the database until they expire.
+type DebugCaptureService struct {
+	db    *gorm.DB
+	store store.Store
+	now   func() time.Time
+}
+
+// NewDebugCaptureService creates a new debug capture service.
+func NewDebugCaptureService(db *gorm.DB, store store.Store) *DebugCaptureService {
+	return &DebugCaptureService{
+		db:    db,
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// IssueToken creates a debug token valid for ttl, clamped to DebugTokenMaxTTL.
+// Only a hash of the token is stored.
+func (s *DebugCaptureService) IssueToken(ttl time.Duration) (string, time.Time, error) {
+	if ttl <= 0 {
+		ttl = DebugTokenDefaultTTL
+	}
+	ttl = min(ttl, DebugTokenMaxTTL)
+
+	token := debugTokenPrefix + utils.GenerateSecureRandomString(40)
+	if err := s.store.Set(debugTokenStoreKey(token), []byte("1"), ttl); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, s.now().Add(ttl), nil
+}
+
+// ValidateToken reports whether token is an unexpired debug token.
+func (s *DebugCaptureService) ValidateToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	exists, err := s.store.Exists(debugTokenStoreKey(token))
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to look up debug token")
+		return false
+	}
+	return exists
+}
+
+// RevokeToken invalidates a debug token before it expires.
+func (s *DebugCaptureService) RevokeToken(token string) error {
+	return s.store.Delete(debugTokenStoreKey(token))
+}
+
+// Save stores a capture for DebugCaptureTTL and removes expired captures.
+func (s *DebugCaptureService) Save(capture *models.DebugCapture) error {
+	now := s.now()
+	capture.ExpiresAt = now.Add(DebugCaptureTTL)
+	if err := s.db.Where("expires_at <= ?", now).Delete(&models.DebugCapture{}).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to delete expired debug captures")
+	}
+	return s.db.Create(capture).Error
+}
+
+// List returns unexpired captures newest first, without their data. An empty
+// groupName lists captures of every group.
+func (s *DebugCaptureService) List(groupName string) ([]models.DebugCapture, error) {
+	query := s.db.Omit("data").Where("expires_at > ?", s.now())
+	if groupName != "" {
+		query = query.Where("group_name = ?", groupName)
+	}
+	var captures []models.DebugCapture
+	if err := query.Order("id DESC").Limit(debugCaptureListMax).Find(&captures).Error; err != nil {
+		return nil, err
+	}
+	return captures, nil
+}
+
+// Get returns an unexpired capture with its data.
+func (s *DebugCaptureService) Get(id uint) (*models.DebugCapture, error) {
+	var capture models.DebugCapture
+	if err := s.db.Where("id = ? AND expires_at > ?", id, s.now()).First(&capture).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDebugCaptureNotFound
+		}
+		return nil, err
+	}
+	return &capture, nil
+}
+
+// Delete removes a capture.
+func (s *DebugCaptureService) Delete(id uint) error {
+	result := s.db.Delete(&models.DebugCapture{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDebugCaptureNotFound
+	}
+	return nil
+}
+
+func debugTokenStoreKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return debugTokenKeyPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugCaptureServiceTokens(t *testing.T) {
+	t.Parallel()
+
+	service := NewDebugCaptureService(nil, store.NewMemoryStore())
+
+	token, expiresAt, err := service.IssueToken(0)
+	require.NoError(t, err)
+	assert.True(t, service.ValidateToken(token))
+	assert.WithinDuration(t, time.Now().Add(DebugTokenDefaultTTL), expiresAt, time.Minute)
+
+	_, expiresAt, err = service.IssueToken(7 * 24 * time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(DebugTokenMaxTTL), expiresAt, time.Minute)
+
+	assert.False(t, service.ValidateToken(""))
+	assert.False(t, service.ValidateToken(token+"x"))
+
+	require.NoError(t, service.RevokeToken(token))
+	assert.False(t, service.ValidateToken(token))
+}
+
+func TestDebugCaptureServiceStoresCapturesUntilExpiry(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.DebugCapture{})
+	service := NewDebugCaptureService(db, store.NewMemoryStore())
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	first := &models.DebugCapture{GroupName: "a", Data: []byte(`{"attempts":[]}`)}
+	require.NoError(t, service.Save(first))
+	require.NoError(t, service.Save(&models.DebugCapture{GroupName: "b"}))
+
+	captures, err := service.List("")
+	require.NoError(t, err)
+	require.Len(t, captures, 2)
+	assert.Equal(t, "b", captures[0].GroupName)
+	assert.Empty(t, captures[1].Data, "list omits capture data")
+
+	captures, err = service.List("a")
+	require.NoError(t, err)
+	require.Len(t, captures, 1)
+
+	got, err := service.Get(first.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"attempts":[]}`, string(got.Data))
+
+	// Expired captures are hidden and removed by the next save.
+	now = now.Add(DebugCaptureTTL + time.Second)
+	_, err = service.Get(first.ID)
+	assert.ErrorIs(t, err, ErrDebugCaptureNotFound)
+	require.NoError(t, service.Save(&models.DebugCapture{GroupName: "c"}))
+	var count int64
+	require.NoError(t, db.Model(&models.DebugCapture{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	assert.ErrorIs(t, service.Delete(first.ID), ErrDebugCaptureNotFound)
+}
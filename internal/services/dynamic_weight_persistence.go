@@ -72,8 +72,15 @@ func NewDynamicWeightPersistence(db *gorm.DB, manager *DynamicWeightManager) *Dy
 
 // Start begins the periodic persistence routine.
 func (p *DynamicWeightPersistence) Start() {
+	// Stop closes the channel, so restarting after regaining leadership needs a
+	// new one. The loop is handed its own channel and never reads the field.
+	select {
+	case <-p.stopChan:
+		p.stopChan = make(chan struct{})
+	default:
+	}
 	p.wg.Add(1)
-	go p.runLoop()
+	go p.runLoop(p.stopChan)
 	logrus.Info("Dynamic weight persistence service started")
 }
 
@@ -101,7 +108,7 @@ func (p *DynamicWeightPersistence) Stop(ctx context.Context) {
 }
 
 // runLoop runs the periodic persistence routine.
-func (p *DynamicWeightPersistence) runLoop() {
+func (p *DynamicWeightPersistence) runLoop(stopCh <-chan struct{}) {
 	defer p.wg.Done()
 
 	ticker := time.NewTicker(p.interval)
@@ -112,7 +119,7 @@ func (p *DynamicWeightPersistence) runLoop() {
 		case <-ticker.C:
 			p.syncDirtyKeys()
 			p.checkAndRunMaintenance()
-		case <-p.stopChan:
+		case <-stopCh:
 			return
 		}
 	}
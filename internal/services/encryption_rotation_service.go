@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gpt-load/internal/encryption"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	reencryptBatchSize = 500
+	// reencryptFollowUpDelay is how long after a re-encryption the column
+	// values are checked once more, so values written by nodes that had not
+	// yet reloaded the keyring are re-encrypted too.
+	reencryptFollowUpDelay = 2 * time.Minute
+)
+
+// ErrEncryptionDisabled is returned when a keyring operation is requested
+// without ENCRYPTION_KEY.
+var ErrEncryptionDisabled = errors.New("encryption is not enabled")
+
+// EncryptedColumn is a column whose values are encrypted with the keyring.
+type EncryptedColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// EncryptedColumns lists the columns that are re-encrypted after a rotation.
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "api_keys", Column: "key_value"},
+	{Table: "hub_access_keys", Column: "key_value"},
+	{Table: "managed_sites", Column: "auth_value"},
+	{Table: "managed_sites", Column: "user_id"},
+}
+
+// ColumnStatus reports how many values of a column are not encrypted with the
+// active data key yet.
+type ColumnStatus struct {
+	EncryptedColumn
+	Pending int64 `json:"pending"`
+}
+
+// ReencryptionRun is the outcome of a background re-encryption.
+type ReencryptionRun struct {
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Reencrypted int64      `json:"reencrypted"`
+	Failed      int64      `json:"failed"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// EncryptionStatus describes the keyring and the progress of re-encryption.
+type EncryptionStatus struct {
+	Enabled       bool                 `json:"enabled"`
+	ActiveVersion uint                 `json:"active_version"`
+	Keys          []encryption.DataKey `json:"keys"`
+	Columns       []ColumnStatus       `json:"columns"`
+	Running       bool                 `json:"running"`
+	LastRun       *ReencryptionRun     `json:"last_run,omitempty"`
+}
+
+// EncryptionRotationService rotates data keys and re-encrypts stored secrets
+// with the active key in the background while the service keeps running.
+type EncryptionRotationService struct {
+	db            *gorm.DB
+	keyring       *encryption.Keyring
+	followUpDelay time.Duration
+
+	mu      sync.Mutex
+	running bool
+	rerun   bool
+	lastRun *ReencryptionRun
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewEncryptionRotationService creates the service; keyring is nil when
+// encryption is disabled.
+func NewEncryptionRotationService(db *gorm.DB, keyring *encryption.Keyring) *EncryptionRotationService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EncryptionRotationService{
+		db:            db,
+		keyring:       keyring,
+		followUpDelay: reencryptFollowUpDelay,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Initialize creates the keyring on the first start with encryption enabled.
+// It must run after the encryption_keys table was migrated.
+func (s *EncryptionRotationService) Initialize(ctx context.Context) error {
+	if s.keyring == nil {
+		return nil
+	}
+	return s.keyring.Initialize(ctx)
+}
+
+// Start resumes re-encryption on the master node when values are still
+// encrypted with an older data key after an interrupted run. A keyring that
+// was never rotated has nothing to re-encrypt.
+func (s *EncryptionRotationService) Start() {
+	if s.keyring == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		// Restarting after Stop, e.g. after regaining leadership.
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+	s.mu.Unlock()
+
+	columns, err := s.columnStatus(s.ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check encrypted columns")
+		return
+	}
+	for _, column := range columns {
+		if column.Pending > 0 {
+			logrus.Infof("Values encrypted with older data keys found, re-encrypting with v%d", s.keyring.ActiveVersion())
+			s.startReencryption()
+			return
+		}
+	}
+}
+
+// Stop cancels a running re-encryption and waits for it to return.
+func (s *EncryptionRotationService) Stop(ctx context.Context) {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		logrus.Info("EncryptionRotationService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("EncryptionRotationService stop timed out.")
+	}
+}
+
+// Rotate creates a new active data key and re-encrypts all stored secrets
+// with it in the background.
+func (s *EncryptionRotationService) Rotate(ctx context.Context) (*EncryptionStatus, error) {
+	if s.keyring == nil {
+		return nil, ErrEncryptionDisabled
+	}
+	version, err := s.keyring.Rotate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Rotated data key, new values are encrypted with v%d", version)
+	s.startReencryption()
+	return s.Status(ctx)
+}
+
+// Status reports the data keys and the values still to re-encrypt.
+func (s *EncryptionRotationService) Status(ctx context.Context) (*EncryptionStatus, error) {
+	status := &EncryptionStatus{Keys: []encryption.DataKey{}, Columns: []ColumnStatus{}}
+	if s.keyring == nil {
+		return status, nil
+	}
+	status.Enabled = true
+	status.ActiveVersion = s.keyring.ActiveVersion()
+
+	if s.db.Migrator().HasTable(&encryption.DataKey{}) {
+		if err := s.db.WithContext(ctx).Order("version").Find(&status.Keys).Error; err != nil {
+			return nil, err
+		}
+	}
+	columns, err := s.columnStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Columns = columns
+
+	s.mu.Lock()
+	status.Running = s.running
+	if s.lastRun != nil {
+		run := *s.lastRun
+		status.LastRun = &run
+	}
+	s.mu.Unlock()
+	return status, nil
+}
+
+// pendingQuery selects the values of a column that are not encrypted with
+// the active data key.
+func (s *EncryptionRotationService) pendingQuery(ctx context.Context, column EncryptedColumn) *gorm.DB {
+	query := s.db.WithContext(ctx).Table(column.Table).Where(column.Column + " <> ''")
+	if prefix := encryption.VersionPrefix(s.keyring.ActiveVersion()); prefix != "" {
+		query = query.Where(column.Column+" NOT LIKE ?", prefix+"%")
+	}
+	return query
+}
+
+func (s *EncryptionRotationService) columnStatus(ctx context.Context) ([]ColumnStatus, error) {
+	columns := make([]ColumnStatus, 0, len(EncryptedColumns))
+	for _, column := range EncryptedColumns {
+		if !s.db.Migrator().HasTable(column.Table) {
+			continue
+		}
+		status := ColumnStatus{EncryptedColumn: column}
+		if s.keyring.ActiveVersion() > 0 {
+			if err := s.pendingQuery(ctx, column).Count(&status.Pending).Error; err != nil {
+				return nil, fmt.Errorf("failed to count %s.%s: %w", column.Table, column.Column, err)
+			}
+		}
+		columns = append(columns, status)
+	}
+	return columns, nil
+}
+
+// startReencryption runs a sweep in the background, or schedules another one
+// when a sweep is already running. Each started run checks for new values to
+// re-encrypt once after reencryptFollowUpDelay.
+func (s *EncryptionRotationService) startReencryption() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.rerun = true
+		return
+	}
+	s.running = true
+	s.lastRun = &ReencryptionRun{StartedAt: time.Now()}
+	s.wg.Add(1)
+	go s.run(s.ctx, s.lastRun)
+}
+
+func (s *EncryptionRotationService) run(ctx context.Context, run *ReencryptionRun) {
+	defer s.wg.Done()
+	failed, ok := s.reencrypt(ctx, run)
+	if !ok {
+		return
+	}
+
+	// Pick up values written meanwhile by nodes with a stale keyring. Values
+	// that did not decrypt stay pending and do not count.
+	select {
+	case <-time.After(s.followUpDelay):
+	case <-ctx.Done():
+		return
+	}
+	columns, err := s.columnStatus(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to check encrypted columns")
+		return
+	}
+	var pending int64
+	for _, column := range columns {
+		pending += column.Pending
+	}
+	if pending <= failed {
+		return
+	}
+
+	s.mu.Lock()
+	if s.running || s.lastRun != run {
+		// A newer rotation runs its own sweep and follow-up.
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	run.FinishedAt = nil
+	s.mu.Unlock()
+	s.reencrypt(ctx, run)
+}
+
+// reencrypt sweeps until no further sweep was requested and adds the outcome
+// to run. It returns the values of the last sweep that did not decrypt and
+// whether the sweeps completed.
+func (s *EncryptionRotationService) reencrypt(ctx context.Context, run *ReencryptionRun) (int64, bool) {
+	var lastFailed, reencryptedTotal int64
+	for {
+		reencrypted, failed, err := s.sweep(ctx)
+		lastFailed = failed
+		reencryptedTotal += reencrypted
+
+		s.mu.Lock()
+		run.Reencrypted += reencrypted
+		run.Failed += failed
+		if err != nil {
+			run.Error = err.Error()
+		}
+		if s.rerun && err == nil {
+			s.rerun = false
+			s.mu.Unlock()
+			continue
+		}
+		now := time.Now()
+		run.FinishedAt = &now
+		s.running = false
+		s.mu.Unlock()
+
+		if err != nil {
+			logrus.Errorf("Re-encryption stopped: %s", err)
+			return lastFailed, false
+		}
+		break
+	}
+
+	logrus.Infof("Re-encrypted %d values with data key v%d (%d could not be decrypted)",
+		reencryptedTotal, s.keyring.ActiveVersion(), lastFailed)
+	return lastFailed, true
+}
+
+// sweep re-encrypts every value that is not encrypted with the active data
+// key. Values that do not decrypt are counted and left unchanged.
+func (s *EncryptionRotationService) sweep(ctx context.Context) (reencrypted, failed int64, err error) {
+	for _, column := range EncryptedColumns {
+		if !s.db.Migrator().HasTable(column.Table) {
+			continue
+		}
+		lastID := uint(0)
+		for {
+			if err := ctx.Err(); err != nil {
+				return reencrypted, failed, err
+			}
+			var rows []struct {
+				ID    uint
+				Value string
+			}
+			if err := s.pendingQuery(ctx, column).
+				Select("id, "+column.Column+" AS value").
+				Where("id > ?", lastID).
+				Order("id").Limit(reencryptBatchSize).
+				Scan(&rows).Error; err != nil {
+				return reencrypted, failed, fmt.Errorf("failed to read %s: %w", column.Table, err)
+			}
+			if len(rows) == 0 {
+				break
+			}
+			for _, row := range rows {
+				plaintext, err := s.keyring.Decrypt(row.Value)
+				if err != nil {
+					failed++
+					continue
+				}
+				ciphertext, err := s.keyring.Encrypt(plaintext)
+				if err != nil {
+					return reencrypted, failed, err
+				}
+				// Only replace the value that was read, never a concurrent update.
+				result := s.db.WithContext(ctx).Table(column.Table).
+					Where("id = ? AND "+column.Column+" = ?", row.ID, row.Value).
+					Update(column.Column, ciphertext)
+				if result.Error != nil {
+					return reencrypted, failed, fmt.Errorf("failed to update %s: %w", column.Table, result.Error)
+				}
+				reencrypted += result.RowsAffected
+			}
+			lastID = rows[len(rows)-1].ID
+		}
+	}
+	return reencrypted, failed, nil
+}
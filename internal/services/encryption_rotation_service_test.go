@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestEncryptionRotationReencryptsColumns(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(&models.APIKey{}, &encryption.DataKey{}))
+	require.NoError(t, db.Exec("CREATE TABLE hub_access_keys (id integer PRIMARY KEY, key_value text)").Error)
+	// managed_sites is left out: missing tables are skipped.
+
+	const kek = "test-encryption-key-32-bytes!!"
+	legacy, err := encryption.NewService(kek)
+	require.NoError(t, err)
+	legacyKey, err := legacy.Encrypt("sk-legacy")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: 1, KeyValue: legacyKey, KeyHash: "a"}).Error)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: 1, KeyValue: "not-ciphertext", KeyHash: "b"}).Error)
+	legacyAccessKey, err := legacy.Encrypt("sk-hub")
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("INSERT INTO hub_access_keys (id, key_value) VALUES (1, ?), (2, '')", legacyAccessKey).Error)
+
+	keyring, err := encryption.NewKeyring(db, kek)
+	require.NoError(t, err)
+	service := NewEncryptionRotationService(db, keyring)
+	ctx := context.Background()
+	require.NoError(t, service.Initialize(ctx))
+
+	// Creating the keyring leaves the legacy values alone.
+	status, err := service.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.Zero(t, status.ActiveVersion)
+	require.Len(t, status.Columns, 2)
+	assert.Zero(t, status.Columns[0].Pending)
+	assert.Zero(t, status.Columns[1].Pending)
+
+	_, err = keyring.Rotate(ctx)
+	require.NoError(t, err)
+	reencrypted, failed, err := service.sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), reencrypted)
+	assert.Equal(t, int64(1), failed)
+
+	_, err = keyring.Rotate(ctx)
+	require.NoError(t, err)
+	reencrypted, failed, err = service.sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), reencrypted)
+	assert.Equal(t, int64(1), failed)
+
+	var keys []models.APIKey
+	require.NoError(t, db.Order("id").Find(&keys).Error)
+	assert.True(t, strings.HasPrefix(keys[0].KeyValue, "v2:"))
+	plaintext, err := keyring.Decrypt(keys[0].KeyValue)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy", plaintext)
+	assert.Equal(t, "not-ciphertext", keys[1].KeyValue)
+
+	var accessKey string
+	require.NoError(t, db.Table("hub_access_keys").Where("id = 1").Pluck("key_value", &accessKey).Error)
+	plaintext, err = keyring.Decrypt(accessKey)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-hub", plaintext)
+
+	status, err = service.Status(ctx)
+	require.NoError(t, err)
+	assert.Len(t, status.Keys, 3)
+	assert.Equal(t, int64(1), status.Columns[0].Pending)
+	assert.Zero(t, status.Columns[1].Pending)
+}
+
+func TestEncryptionRotationFollowsUpOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&models.APIKey{}, &encryption.DataKey{}))
+
+	const kek = "test-encryption-key-32-bytes!!"
+	keyring, err := encryption.NewKeyring(db, kek)
+	require.NoError(t, err)
+	service := NewEncryptionRotationService(db, keyring)
+	service.followUpDelay = 300 * time.Millisecond
+	t.Cleanup(func() { service.Stop(context.Background()) })
+	ctx := context.Background()
+	require.NoError(t, service.Initialize(ctx))
+
+	// Values that do not decrypt stay pending but do not trigger the follow-up.
+	require.NoError(t, db.Create(&models.APIKey{GroupID: 1, KeyValue: "not-ciphertext", KeyHash: "a"}).Error)
+	stale, err := keyring.Encrypt("sk-stale")
+	require.NoError(t, err)
+	_, err = service.Rotate(ctx)
+	require.NoError(t, err)
+
+	// A node that has not reloaded the keyring yet writes with the old key.
+	require.Eventually(t, func() bool {
+		status, err := service.Status(ctx)
+		return err == nil && !status.Running
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, db.Create(&models.APIKey{GroupID: 1, KeyValue: stale, KeyHash: "b"}).Error)
+
+	done := make(chan struct{})
+	go func() {
+		service.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("re-encryption kept following up")
+	}
+
+	status, err := service.Status(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, status.LastRun)
+	assert.NotNil(t, status.LastRun.FinishedAt)
+	assert.Equal(t, int64(2), status.LastRun.Failed, "the rotation and the follow-up each skip the undecryptable value")
+	assert.Equal(t, int64(1), status.LastRun.Reencrypted, "the follow-up adds to the run of the rotation")
+	assert.Equal(t, int64(1), status.Columns[0].Pending)
+}
+
+func TestEncryptionRotationDisabled(t *testing.T) {
+	service := NewEncryptionRotationService(nil, nil)
+	status, err := service.Status(context.Background())
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+
+	_, err = service.Rotate(context.Background())
+	require.ErrorIs(t, err, ErrEncryptionDisabled)
+	service.Start()
+	service.Stop(context.Background())
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ErrGroupExperimentNotFound is returned for an unknown group experiment.
+var ErrGroupExperimentNotFound = app_errors.NewAPIError(app_errors.ErrResourceNotFound, "experiment not found")
+
+// GroupExperimentParams defines the candidate variant and traffic split of a
+// new experiment. Override fields left empty keep the group value.
+type GroupExperimentParams struct {
+	Name                 string          `json:"name"`
+	Percentage           int             `json:"percentage"`
+	ProxyKeys            string          `json:"proxy_keys"`
+	SessionAffinity      bool            `json:"session_affinity"`
+	ParamOverrides       map[string]any  `json:"param_overrides"`
+	ModelRedirectRulesV2 json.RawMessage `json:"model_redirect_rules_v2"`
+	ModelRedirectStrict  *bool           `json:"model_redirect_strict"`
+	Config               map[string]any  `json:"config"`
+}
+
+// GroupExperimentTraffic changes the traffic split of a running experiment.
+type GroupExperimentTraffic struct {
+	Percentage *int    `json:"percentage"`
+	ProxyKeys  *string `json:"proxy_keys"`
+}
+
+// ExperimentVariantStats summarizes the final requests served by one variant.
+type ExperimentVariantStats struct {
+	Variant string `json:"variant"`
+	RequestLogStats
+}
+
+// ExperimentComparison compares the control and candidate variants of an
+// experiment.
+type ExperimentComparison struct {
+	Experiment models.GroupExperiment   `json:"experiment"`
+	Variants   []ExperimentVariantStats `json:"variants"`
+}
+
+// GroupExperimentService manages canary experiments on group configuration.
+// The proxy reads running experiments from the group cache.
+type GroupExperimentService struct {
+	db           *gorm.DB
+	readDB       *gorm.DB
+	groupService *GroupService
+}
+
+// NewGroupExperimentService creates a GroupExperimentService.
+func NewGroupExperimentService(db *gorm.DB, readDB ReadOnlyDB, groupService *GroupService) *GroupExperimentService {
+	reader := readDB.DB
+	if reader == nil {
+		reader = db
+	}
+	return &GroupExperimentService{db: db, readDB: reader, groupService: groupService}
+}
+
+// ListExperiments returns the experiments of a group, newest first.
+func (s *GroupExperimentService) ListExperiments(ctx context.Context, groupID uint) ([]models.GroupExperiment, error) {
+	if _, err := FindGroupByID(ctx, s.db, groupID); err != nil {
+		return nil, err
+	}
+	experiments := []models.GroupExperiment{}
+	if err := s.db.WithContext(ctx).Where("group_id = ?", groupID).Order("id DESC").Find(&experiments).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return experiments, nil
+}
+
+// GetExperiment returns one experiment of a group.
+func (s *GroupExperimentService) GetExperiment(ctx context.Context, groupID, experimentID uint) (*models.GroupExperiment, error) {
+	var experiment models.GroupExperiment
+	err := s.db.WithContext(ctx).Where("id = ? AND group_id = ?", experimentID, groupID).First(&experiment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupExperimentNotFound
+	}
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &experiment, nil
+}
+
+// CreateExperiment starts an experiment on a group. A group can only run one
+// experiment at a time.
+func (s *GroupExperimentService) CreateExperiment(ctx context.Context, groupID uint, params GroupExperimentParams) (*models.GroupExperiment, error) {
+	group, err := FindGroupByID(ctx, s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	proxyKeys := normalizeExperimentProxyKeys(params.ProxyKeys)
+	if err := validateExperimentTraffic(params.Percentage, proxyKeys); err != nil {
+		return nil, err
+	}
+	if params.ParamOverrides == nil && len(params.ModelRedirectRulesV2) == 0 && params.ModelRedirectStrict == nil && params.Config == nil {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "the candidate must override at least one field")
+	}
+	if err := validateParamOverrides(params.ParamOverrides); err != nil {
+		return nil, err
+	}
+	if len(params.ModelRedirectRulesV2) > 0 {
+		var rules map[string]*models.ModelRedirectRuleV2
+		if err := json.Unmarshal(params.ModelRedirectRulesV2, &rules); err != nil {
+			return nil, app_errors.NewAPIError(app_errors.ErrValidation, "invalid model_redirect_rules_v2: "+err.Error())
+		}
+	}
+	if params.Config != nil {
+		if _, err := s.groupService.validateAndCleanConfig(mergeExperimentConfig(group.Config, params.Config), group.ChannelType); err != nil {
+			return nil, err
+		}
+	}
+
+	running, err := s.runningExperimentCount(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if running > 0 {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "the group already has a running experiment")
+	}
+
+	experiment := &models.GroupExperiment{
+		GroupID:             groupID,
+		Name:                strings.TrimSpace(params.Name),
+		Status:              models.ExperimentRunning,
+		Percentage:          params.Percentage,
+		ProxyKeys:           proxyKeys,
+		SessionAffinity:     params.SessionAffinity,
+		ParamOverrides:      params.ParamOverrides,
+		ModelRedirectStrict: params.ModelRedirectStrict,
+		Config:              params.Config,
+	}
+	if len(params.ModelRedirectRulesV2) > 0 {
+		experiment.ModelRedirectRulesV2 = datatypes.JSON(params.ModelRedirectRulesV2)
+	}
+	if err := s.db.WithContext(ctx).Create(experiment).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	s.invalidateGroups(ctx)
+	return experiment, nil
+}
+
+// UpdateTraffic changes the traffic split of a running experiment.
+func (s *GroupExperimentService) UpdateTraffic(ctx context.Context, groupID, experimentID uint, traffic GroupExperimentTraffic) (*models.GroupExperiment, error) {
+	experiment, err := s.runningExperiment(ctx, groupID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	if traffic.Percentage != nil {
+		experiment.Percentage = *traffic.Percentage
+	}
+	if traffic.ProxyKeys != nil {
+		experiment.ProxyKeys = normalizeExperimentProxyKeys(*traffic.ProxyKeys)
+	}
+	if err := validateExperimentTraffic(experiment.Percentage, experiment.ProxyKeys); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(experiment).Updates(map[string]any{
+		"percentage": experiment.Percentage,
+		"proxy_keys": experiment.ProxyKeys,
+	}).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	s.invalidateGroups(ctx)
+	return experiment, nil
+}
+
+// Promote applies the candidate configuration to the group and ends the
+// experiment. The group update is recorded as a revision.
+func (s *GroupExperimentService) Promote(ctx context.Context, groupID, experimentID uint) (*models.GroupExperiment, error) {
+	experiment, err := s.runningExperiment(ctx, groupID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	group, err := FindGroupByID(ctx, s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stored maps decode numbers as json.Number; the update API expects plain
+	// JSON values.
+	paramOverrides, err := plainJSONMap(experiment.ParamOverrides)
+	if err != nil {
+		return nil, err
+	}
+	params := GroupUpdateParams{
+		ParamOverrides:      paramOverrides,
+		ModelRedirectStrict: experiment.ModelRedirectStrict,
+		RevisionSource:      RevisionSourceExperiment,
+		RevisionNote:        fmt.Sprintf("promote experiment %d", experiment.ID),
+	}
+	if experiment.Name != "" {
+		params.RevisionNote += ": " + experiment.Name
+	}
+	if len(experiment.ModelRedirectRulesV2) > 0 {
+		params.ModelRedirectRulesV2 = json.RawMessage(experiment.ModelRedirectRulesV2)
+	}
+	if experiment.Config != nil {
+		if params.Config, err = plainJSONMap(mergeExperimentConfig(group.Config, experiment.Config)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Claim the experiment before touching the group, so a concurrent promote
+	// or abort cannot apply its settings as well.
+	now := time.Now().UTC()
+	if err := s.endExperiment(ctx, experiment, models.ExperimentPromoted, now); err != nil {
+		return nil, err
+	}
+	if _, err := s.groupService.UpdateGroup(ctx, groupID, params); err != nil {
+		if reopenErr := s.db.WithContext(ctx).Model(&models.GroupExperiment{}).
+			Where("id = ? AND status = ?", experiment.ID, models.ExperimentPromoted).
+			Updates(map[string]any{"status": models.ExperimentRunning, "ended_at": nil}).Error; reopenErr != nil {
+			logrus.WithContext(ctx).WithError(reopenErr).WithField("experiment_id", experiment.ID).Error("Failed to reopen experiment after a failed promotion")
+		}
+		return nil, err
+	}
+
+	if revision, err := latestGroupRevision(ctx, s.db, groupID); err == nil {
+		experiment.PromotedRevision = revision
+		if err := s.db.WithContext(ctx).Model(&models.GroupExperiment{}).
+			Where("id = ?", experiment.ID).
+			Update("promoted_revision", revision).Error; err != nil {
+			logrus.WithContext(ctx).WithError(err).WithField("experiment_id", experiment.ID).Warn("Failed to store promoted revision")
+		}
+	}
+	experiment.Status = models.ExperimentPromoted
+	experiment.EndedAt = &now
+	s.invalidateGroups(ctx)
+	return experiment, nil
+}
+
+// Abort ends the experiment and sends all traffic back to the group
+// configuration.
+func (s *GroupExperimentService) Abort(ctx context.Context, groupID, experimentID uint) (*models.GroupExperiment, error) {
+	experiment, err := s.runningExperiment(ctx, groupID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := s.endExperiment(ctx, experiment, models.ExperimentAborted, now); err != nil {
+		return nil, err
+	}
+	experiment.Status = models.ExperimentAborted
+	experiment.EndedAt = &now
+	s.invalidateGroups(ctx)
+	return experiment, nil
+}
+
+// Compare summarizes the final requests of each variant of an experiment.
+func (s *GroupExperimentService) Compare(ctx context.Context, experimentID uint) (*ExperimentComparison, error) {
+	var experiment models.GroupExperiment
+	err := s.db.WithContext(ctx).First(&experiment, experimentID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupExperimentNotFound
+	}
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	var rows []requestLogStatsRow
+	err = s.readDB.WithContext(ctx).Model(&models.RequestLog{}).
+		Select("experiment_variant AS stats_key, "+requestLogStatsColumns).
+		Where("experiment_id = ? AND request_type = ?", experimentID, models.RequestTypeFinal).
+		Group("experiment_variant").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	byVariant := make(map[string]ExperimentVariantStats, len(rows))
+	for _, row := range rows {
+		byVariant[row.StatsKey] = ExperimentVariantStats{Variant: row.StatsKey, RequestLogStats: row.stats()}
+	}
+
+	comparison := &ExperimentComparison{Experiment: experiment}
+	for _, variant := range []string{models.ExperimentVariantControl, models.ExperimentVariantCandidate} {
+		stats, ok := byVariant[variant]
+		if !ok {
+			stats = ExperimentVariantStats{Variant: variant}
+		}
+		comparison.Variants = append(comparison.Variants, stats)
+	}
+	return comparison, nil
+}
+
+func (s *GroupExperimentService) runningExperiment(ctx context.Context, groupID, experimentID uint) (*models.GroupExperiment, error) {
+	experiment, err := s.GetExperiment(ctx, groupID, experimentID)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status != models.ExperimentRunning {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("experiment is already %s", experiment.Status))
+	}
+	return experiment, nil
+}
+
+// endExperiment moves a running experiment to status. It fails when the
+// experiment was ended meanwhile by another request.
+func (s *GroupExperimentService) endExperiment(ctx context.Context, experiment *models.GroupExperiment, status string, endedAt time.Time) error {
+	result := s.db.WithContext(ctx).Model(&models.GroupExperiment{}).
+		Where("id = ? AND status = ?", experiment.ID, models.ExperimentRunning).
+		Updates(map[string]any{"status": status, "ended_at": endedAt})
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.NewAPIError(app_errors.ErrValidation, "experiment is no longer running")
+	}
+	return nil
+}
+
+func (s *GroupExperimentService) runningExperimentCount(ctx context.Context, groupID uint) (int64, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.GroupExperiment{}).
+		Where("group_id = ? AND status = ?", groupID, models.ExperimentRunning).
+		Count(&count).Error; err != nil {
+		return 0, app_errors.ParseDBError(err)
+	}
+	return count, nil
+}
+
+func (s *GroupExperimentService) invalidateGroups(ctx context.Context) {
+	if s.groupService == nil || s.groupService.groupManager == nil {
+		return
+	}
+	if err := s.groupService.groupManager.Invalidate(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
+	}
+}
+
+func validateExperimentTraffic(percentage int, proxyKeys string) error {
+	if percentage < 0 || percentage > 100 {
+		return app_errors.NewAPIError(app_errors.ErrValidation, "percentage must be between 0 and 100")
+	}
+	if percentage == 0 && proxyKeys == "" {
+		return app_errors.NewAPIError(app_errors.ErrValidation, "set a percentage or proxy keys to route traffic to the candidate")
+	}
+	return nil
+}
+
+// normalizeExperimentProxyKeys trims the keys of a comma separated list and
+// drops empty entries.
+func normalizeExperimentProxyKeys(keys string) string {
+	seen := make(map[string]struct{})
+	list := make([]string, 0)
+	for _, key := range utils.SplitAndTrim(keys, ",") {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		list = append(list, key)
+	}
+	return strings.Join(list, ",")
+}
+
+// mergeExperimentConfig returns the group config with the experiment config
+// keys applied on top.
+func mergeExperimentConfig(base map[string]any, overrides map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(overrides))
+	maps.Copy(merged, base)
+	maps.Copy(merged, overrides)
+	return merged
+}
+
+// plainJSONMap re-decodes a map so that numbers become float64.
+func plainJSONMap(m map[string]any) (map[string]any, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var plain map[string]any
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return nil, err
+	}
+	return plain, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupExperimentService_CandidateAndPromote(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	experiments := NewGroupExperimentService(db, ReadOnlyDB{DB: db}, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	_, err := experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{Percentage: 10})
+	assert.Error(t, err, "the candidate must override a field")
+	_, err = experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{Percentage: 101, ParamOverrides: map[string]any{"temperature": 0.2}})
+	assert.Error(t, err)
+	_, err = experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{ProxyKeys: " , ", ParamOverrides: map[string]any{"temperature": 0.2}})
+	assert.Error(t, err, "no traffic reaches the candidate")
+
+	experiment, err := experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{
+		Name:                 "lower temperature",
+		Percentage:           20,
+		ProxyKeys:            "sk-a, sk-b,sk-a",
+		ParamOverrides:       map[string]any{"temperature": 0.2},
+		ModelRedirectRulesV2: json.RawMessage(`{"gpt-4":{"targets":[{"model":"gpt-4.1","weight":100}]}}`),
+		Config:               map[string]any{"max_retries": 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ExperimentRunning, experiment.Status)
+	assert.Equal(t, "sk-a,sk-b", experiment.ProxyKeys)
+
+	_, err = experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{Percentage: 5, ParamOverrides: map[string]any{"top_p": 0.5}})
+	assert.Error(t, err, "only one experiment runs per group")
+
+	require.NoError(t, svc.groupManager.Reload())
+	cached, err := svc.groupManager.GetGroupByID(group.ID)
+	require.NoError(t, err)
+	require.NotNil(t, cached.Experiment)
+	require.NotNil(t, cached.ExperimentCandidate)
+	assert.Contains(t, cached.Experiment.ProxyKeysMap, "sk-b")
+	assert.Empty(t, cached.ParamOverrides)
+	assert.Equal(t, 3, cached.EffectiveConfig.MaxRetries)
+	candidate := cached.ExperimentCandidate
+	assert.EqualValues(t, "0.2", candidate.ParamOverrides["temperature"])
+	assert.Equal(t, 5, candidate.EffectiveConfig.MaxRetries)
+	assert.Contains(t, candidate.ModelRedirectMapV2, "gpt-4")
+	assert.Nil(t, candidate.ExperimentCandidate)
+
+	percentage := 50
+	updated, err := experiments.UpdateTraffic(ctx, group.ID, experiment.ID, GroupExperimentTraffic{Percentage: &percentage})
+	require.NoError(t, err)
+	assert.Equal(t, 50, updated.Percentage)
+	assert.Equal(t, "sk-a,sk-b", updated.ProxyKeys)
+
+	promoted, err := experiments.Promote(ctx, group.ID, experiment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExperimentPromoted, promoted.Status)
+	assert.NotNil(t, promoted.EndedAt)
+	assert.Equal(t, 2, promoted.PromotedRevision)
+
+	var stored models.Group
+	require.NoError(t, db.First(&stored, group.ID).Error)
+	assert.EqualValues(t, "0.2", stored.ParamOverrides["temperature"])
+	assert.EqualValues(t, "5", stored.Config["max_retries"])
+	assert.Contains(t, string(stored.ModelRedirectRulesV2), "gpt-4.1")
+
+	revisions := NewGroupRevisionService(db, svc)
+	list, err := revisions.ListRevisions(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RevisionSourceExperiment, list[0].Source)
+	assert.Contains(t, list[0].Note, "lower temperature")
+
+	_, err = experiments.Abort(ctx, group.ID, experiment.ID)
+	assert.Error(t, err, "ended experiments cannot be aborted")
+
+	require.NoError(t, svc.groupManager.Reload())
+	cached, err = svc.groupManager.GetGroupByID(group.ID)
+	require.NoError(t, err)
+	assert.Nil(t, cached.Experiment)
+	assert.Nil(t, cached.ExperimentCandidate)
+}
+
+func TestGroupExperimentService_AbortAndCompare(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	experiments := NewGroupExperimentService(db, ReadOnlyDB{DB: db}, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	experiment, err := experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{
+		Percentage:     30,
+		ParamOverrides: map[string]any{"temperature": 0.2},
+	})
+	require.NoError(t, err)
+
+	logs := []struct {
+		variant     string
+		success     bool
+		duration    int64
+		tokens      int64
+		requestType string
+	}{
+		{models.ExperimentVariantControl, true, 100, 10, models.RequestTypeFinal},
+		{models.ExperimentVariantControl, false, 300, 0, models.RequestTypeFinal},
+		{models.ExperimentVariantControl, false, 900, 0, models.RequestTypeRetry},
+		{models.ExperimentVariantCandidate, true, 50, 30, models.RequestTypeFinal},
+	}
+	for _, entry := range logs {
+		require.NoError(t, db.Create(&models.RequestLog{
+			ID:                uuid.NewString(),
+			Timestamp:         time.Now(),
+			GroupID:           group.ID,
+			IsSuccess:         entry.success,
+			Duration:          entry.duration,
+			TotalTokens:       entry.tokens,
+			RequestType:       entry.requestType,
+			ExperimentID:      experiment.ID,
+			ExperimentVariant: entry.variant,
+		}).Error)
+	}
+
+	comparison, err := experiments.Compare(ctx, experiment.ID)
+	require.NoError(t, err)
+	require.Len(t, comparison.Variants, 2)
+	control, candidate := comparison.Variants[0], comparison.Variants[1]
+	assert.Equal(t, models.ExperimentVariantControl, control.Variant)
+	assert.Equal(t, int64(2), control.Requests)
+	assert.Equal(t, 0.5, control.SuccessRate)
+	assert.Equal(t, float64(200), control.AvgDurationMs)
+	assert.Equal(t, int64(10), control.TotalTokens)
+	assert.Equal(t, models.ExperimentVariantCandidate, candidate.Variant)
+	assert.Equal(t, int64(1), candidate.Requests)
+	assert.Equal(t, 1.0, candidate.SuccessRate)
+	assert.Equal(t, float64(30), candidate.AvgTokens)
+
+	aborted, err := experiments.Abort(ctx, group.ID, experiment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExperimentAborted, aborted.Status)
+
+	var stored models.Group
+	require.NoError(t, db.First(&stored, group.ID).Error)
+	assert.Empty(t, stored.ParamOverrides)
+
+	list, err := experiments.ListExperiments(ctx, group.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, models.ExperimentAborted, list[0].Status)
+
+	_, err = experiments.Compare(ctx, experiment.ID+1)
+	assert.ErrorIs(t, err, ErrGroupExperimentNotFound)
+}
+
+func TestGroupExperimentService_EndsExperimentOnce(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	experiments := NewGroupExperimentService(db, ReadOnlyDB{DB: db}, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	experiment, err := experiments.CreateExperiment(ctx, group.ID, GroupExperimentParams{
+		Percentage:     30,
+		ParamOverrides: map[string]any{"temperature": 0.2},
+	})
+	require.NoError(t, err)
+
+	// A promotion and an abort both saw the experiment running.
+	now := time.Now().UTC()
+	require.NoError(t, experiments.endExperiment(ctx, experiment, models.ExperimentAborted, now))
+	assert.Error(t, experiments.endExperiment(ctx, experiment, models.ExperimentPromoted, now))
+
+	_, err = experiments.Promote(ctx, group.ID, experiment.ID)
+	assert.Error(t, err)
+	stored, err := experiments.GetExperiment(ctx, group.ID, experiment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExperimentAborted, stored.Status)
+	var current models.Group
+	require.NoError(t, db.First(&current, group.ID).Error)
+	assert.NotContains(t, current.ParamOverrides, "temperature", "an aborted experiment is never applied")
+}
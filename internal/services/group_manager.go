@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -158,6 +159,9 @@ func (gm *GroupManager) Initialize() error {
 			subGroupsByAggregateID[sg.GroupID] = append(subGroupsByAggregateID[sg.GroupID], sg)
 		}
 
+		// Load running experiments. Failures only disable the candidate variants.
+		experimentsByGroupID := gm.loadRunningExperiments(timeout)
+
 		// Create group ID to group object mapping for sub-group lookups
 		groupByID := make(map[uint]*models.Group)
 		for _, group := range groups {
@@ -253,6 +257,11 @@ func (gm *GroupManager) Initialize() error {
 				}
 			}
 
+			if experiment, ok := experimentsByGroupID[g.ID]; ok {
+				g.Experiment = experiment
+				g.ExperimentCandidate = gm.experimentCandidate(&g, experiment)
+			}
+
 			cache.ByName[g.Name] = &g
 			cache.ByID[g.ID] = &g
 		}
@@ -278,6 +287,51 @@ func (gm *GroupManager) Initialize() error {
 	return nil
 }
 
+// loadRunningExperiments returns the running experiment of each group.
+func (gm *GroupManager) loadRunningExperiments(timeout time.Duration) map[uint]*models.GroupExperiment {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var experiments []*models.GroupExperiment
+	if err := gm.db.WithContext(ctx).Where("status = ?", models.ExperimentRunning).Find(&experiments).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to load running group experiments")
+		return nil
+	}
+	byGroupID := make(map[uint]*models.GroupExperiment, len(experiments))
+	for _, experiment := range experiments {
+		experiment.ProxyKeysMap = utils.StringToSet(experiment.ProxyKeys, ",")
+		byGroupID[experiment.GroupID] = experiment
+	}
+	return byGroupID
+}
+
+// experimentCandidate returns a copy of the cached group with the candidate
+// overrides of the experiment applied and the derived fields rebuilt.
+func (gm *GroupManager) experimentCandidate(group *models.Group, experiment *models.GroupExperiment) *models.Group {
+	candidate := *group
+	candidate.ExperimentCandidate = nil
+	if experiment.ParamOverrides != nil {
+		candidate.ParamOverrides = experiment.ParamOverrides
+	}
+	if len(experiment.ModelRedirectRulesV2) > 0 {
+		candidate.ModelRedirectRulesV2 = experiment.ModelRedirectRulesV2
+		candidate.ModelRedirectMapV2 = parseModelRedirectRulesV2(experiment.ModelRedirectRulesV2, group.Name)
+	}
+	if experiment.ModelRedirectStrict != nil {
+		candidate.ModelRedirectStrict = *experiment.ModelRedirectStrict
+	}
+	if experiment.Config != nil {
+		candidate.Config = datatypes.JSONMap(mergeExperimentConfig(group.Config, experiment.Config))
+		candidate.EffectiveConfig = gm.settingsManager.GetEffectiveConfig(candidate.Config)
+		statusMatcher, err := failover.ParseStatusCodeMatcher(candidate.EffectiveConfig.FailoverStatusCodes)
+		if err != nil {
+			statusMatcher = failover.DefaultStatusCodeMatcher()
+		}
+		candidate.FailoverStatusCodeMatcher = statusMatcher
+	}
+	return &candidate
+}
+
 // GetGroupByName retrieves a single group by its name from the cache.
 func (gm *GroupManager) GetGroupByName(name string) (*models.Group, error) {
 	if gm.syncer == nil {
@@ -330,6 +384,16 @@ func (gm *GroupManager) Reload() error {
 	return gm.syncer.Reload()
 }
 
+// Initialized reports whether the group cache has been loaded.
+func (gm *GroupManager) Initialized() bool {
+	return gm.syncer != nil
+}
+
+// Subscribed reports whether the group cache is listening for invalidations.
+func (gm *GroupManager) Subscribed() bool {
+	return gm.syncer != nil && gm.syncer.Subscribed()
+}
+
 // Stop gracefully stops the GroupManager's background syncer.
 func (gm *GroupManager) Stop(ctx context.Context) {
 	if gm.syncer != nil {
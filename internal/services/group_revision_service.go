@@ -0,0 +1,552 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxGroupRevisions is how many revisions are kept per group; older ones are
+// pruned when a new revision is recorded.
+const maxGroupRevisions = 100
+
+// maxRevisionNoteLength matches the size of the note columns.
+const maxRevisionNoteLength = 500
+
+// Revision sources.
+const (
+	RevisionSourceBaseline   = "baseline"
+	RevisionSourceUpdate     = "update"
+	RevisionSourceSubGroup   = "sub_group"
+	RevisionSourceRollback   = "rollback"
+	RevisionSourceSchedule   = "schedule"
+	RevisionSourceExperiment = "experiment"
+)
+
+// ErrGroupRevisionNotFound is returned for an unknown group revision.
+var ErrGroupRevisionNotFound = app_errors.NewAPIError(app_errors.ErrResourceNotFound, "group revision not found")
+
+// GroupSnapshot is the routing configuration of a group stored with each
+// revision.
+type GroupSnapshot struct {
+	Upstreams            json.RawMessage    `json:"upstreams"`
+	TestModel            string             `json:"test_model"`
+	ValidationEndpoint   string             `json:"validation_endpoint"`
+	Config               map[string]any     `json:"config"`
+	ParamOverrides       map[string]any     `json:"param_overrides"`
+	HeaderRules          json.RawMessage    `json:"header_rules"`
+	ModelRedirectRules   map[string]any     `json:"model_redirect_rules"`
+	ModelRedirectRulesV2 json.RawMessage    `json:"model_redirect_rules_v2"`
+	ModelRedirectStrict  bool               `json:"model_redirect_strict"`
+	PathRedirects        json.RawMessage    `json:"path_redirects"`
+	Preconditions        map[string]any     `json:"preconditions"`
+	SubGroups            []SubGroupSnapshot `json:"sub_groups"`
+}
+
+// SubGroupSnapshot is the weight settings of one sub-group of an aggregate
+// group.
+type SubGroupSnapshot struct {
+	SubGroupID                 uint   `json:"sub_group_id"`
+	Name                       string `json:"name"`
+	Weight                     int    `json:"weight"`
+	MinEffectiveWeight         int    `json:"min_effective_weight"`
+	HealthResetIntervalSeconds int64  `json:"health_reset_interval_seconds"`
+}
+
+// GroupRevisionChange is one field that differs between two snapshots. Map
+// fields are compared per key, e.g. "config.max_retries", and sub-groups per
+// name, e.g. "sub_groups.openai".
+type GroupRevisionChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// GroupRevisionDiff is the difference between two states of a group.
+// ToRevision is 0 when compared against the current configuration.
+type GroupRevisionDiff struct {
+	GroupID      uint                  `json:"group_id"`
+	FromRevision int                   `json:"from_revision"`
+	ToRevision   int                   `json:"to_revision"`
+	Changes      []GroupRevisionChange `json:"changes"`
+}
+
+// GroupRevisionService lists, compares and restores group revisions.
+type GroupRevisionService struct {
+	db           *gorm.DB
+	groupService *GroupService
+}
+
+// NewGroupRevisionService creates a GroupRevisionService.
+func NewGroupRevisionService(db *gorm.DB, groupService *GroupService) *GroupRevisionService {
+	return &GroupRevisionService{db: db, groupService: groupService}
+}
+
+// ListRevisions returns the revisions of a group, newest first, without
+// their snapshots.
+func (s *GroupRevisionService) ListRevisions(ctx context.Context, groupID uint) ([]models.GroupRevision, error) {
+	if _, err := FindGroupByID(ctx, s.db, groupID); err != nil {
+		return nil, err
+	}
+	revisions := []models.GroupRevision{}
+	err := s.db.WithContext(ctx).
+		Select("id", "group_id", "revision", "source", "note", "created_at").
+		Where("group_id = ?", groupID).
+		Order("revision DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return revisions, nil
+}
+
+// GetRevision returns one revision including its snapshot.
+func (s *GroupRevisionService) GetRevision(ctx context.Context, groupID uint, revision int) (*models.GroupRevision, error) {
+	var rev models.GroupRevision
+	err := s.db.WithContext(ctx).Where("group_id = ? AND revision = ?", groupID, revision).First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupRevisionNotFound
+	}
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &rev, nil
+}
+
+// LatestRevision returns the number of the newest revision of a group, or 0
+// when none was recorded.
+func (s *GroupRevisionService) LatestRevision(ctx context.Context, groupID uint) (int, error) {
+	return latestGroupRevision(ctx, s.db, groupID)
+}
+
+func latestGroupRevision(ctx context.Context, db *gorm.DB, groupID uint) (int, error) {
+	var latest models.GroupRevision
+	err := db.WithContext(ctx).Select("revision").Where("group_id = ?", groupID).
+		Order("revision DESC").Limit(1).Find(&latest).Error
+	return latest.Revision, err
+}
+
+// Diff compares revision from with revision to, or with the current
+// configuration of the group when to is 0.
+func (s *GroupRevisionService) Diff(ctx context.Context, groupID uint, from, to int) (*GroupRevisionDiff, error) {
+	fromRev, err := s.GetRevision(ctx, groupID, from)
+	if err != nil {
+		return nil, err
+	}
+	var fromSnapshot, toSnapshot GroupSnapshot
+	if err := json.Unmarshal(fromRev.Snapshot, &fromSnapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot of revision %d: %w", from, err)
+	}
+
+	if to > 0 {
+		toRev, err := s.GetRevision(ctx, groupID, to)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(toRev.Snapshot, &toSnapshot); err != nil {
+			return nil, fmt.Errorf("invalid snapshot of revision %d: %w", to, err)
+		}
+	} else {
+		current, err := snapshotGroup(ctx, s.db, groupID)
+		if err != nil {
+			return nil, err
+		}
+		toSnapshot = *current
+	}
+
+	changes, err := diffSnapshots(&fromSnapshot, &toSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupRevisionDiff{GroupID: groupID, FromRevision: from, ToRevision: to, Changes: changes}, nil
+}
+
+// Rollback restores the configuration of a revision. The rollback itself is
+// recorded as a new revision. Sub-group weights are restored for sub-groups
+// that still belong to the group; membership is not changed.
+func (s *GroupRevisionService) Rollback(ctx context.Context, groupID uint, revision int) (*models.Group, error) {
+	rev, err := s.GetRevision(ctx, groupID, revision)
+	if err != nil {
+		return nil, err
+	}
+	var target GroupSnapshot
+	if err := json.Unmarshal(rev.Snapshot, &target); err != nil {
+		return nil, fmt.Errorf("invalid snapshot of revision %d: %w", revision, err)
+	}
+
+	group, err := FindGroupByID(ctx, s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := snapshotGroup(ctx, s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffSnapshots(current, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	params := rollbackParams(group, current, &target, changes)
+	params.RevisionSource = RevisionSourceRollback
+	params.RevisionNote = fmt.Sprintf("rollback to revision %d", revision)
+	return s.groupService.UpdateGroup(ctx, groupID, params)
+}
+
+// rollbackParams builds the update that turns current into target, setting
+// only the fields that changed.
+func rollbackParams(group *models.Group, current, target *GroupSnapshot, changes []GroupRevisionChange) GroupUpdateParams {
+	changed := make(map[string]bool, len(changes))
+	for _, change := range changes {
+		field, _, _ := strings.Cut(change.Field, ".")
+		changed[field] = true
+	}
+
+	var params GroupUpdateParams
+	// Child group upstreams follow the parent group and cannot be changed.
+	if changed["upstreams"] && group.ParentGroupID == nil {
+		params.Upstreams = target.Upstreams
+		params.HasUpstreams = true
+	}
+	if changed["test_model"] && target.TestModel != "" {
+		params.TestModel = target.TestModel
+		params.HasTestModel = true
+	}
+	if changed["validation_endpoint"] {
+		params.ValidationEndpoint = &target.ValidationEndpoint
+	}
+	if changed["config"] {
+		params.Config = nonNilMap(target.Config)
+	}
+	if changed["param_overrides"] {
+		params.ParamOverrides = nonNilMap(target.ParamOverrides)
+	}
+	if changed["header_rules"] {
+		var rules []models.HeaderRule
+		if len(target.HeaderRules) > 0 {
+			_ = json.Unmarshal(target.HeaderRules, &rules)
+		}
+		if rules == nil {
+			rules = []models.HeaderRule{}
+		}
+		params.HeaderRules = &rules
+	}
+	if changed["model_redirect_rules"] || changed["model_redirect_rules_v2"] {
+		v1 := make(map[string]string, len(target.ModelRedirectRules))
+		for source, value := range target.ModelRedirectRules {
+			if str, ok := value.(string); ok {
+				v1[source] = str
+			}
+		}
+		params.ModelRedirectRules = v1
+		params.ModelRedirectRulesV2 = target.ModelRedirectRulesV2
+		if len(params.ModelRedirectRulesV2) == 0 {
+			params.ModelRedirectRulesV2 = json.RawMessage("{}")
+		}
+	}
+	if changed["model_redirect_strict"] {
+		params.ModelRedirectStrict = &target.ModelRedirectStrict
+	}
+	if changed["path_redirects"] {
+		rules := []models.PathRedirectRule{}
+		if len(target.PathRedirects) > 0 {
+			_ = json.Unmarshal(target.PathRedirects, &rules)
+		}
+		params.PathRedirects = rules
+	}
+	if changed["preconditions"] {
+		params.Preconditions = nonNilMap(target.Preconditions)
+	}
+	if changed["sub_groups"] {
+		members := make(map[uint]bool, len(current.SubGroups))
+		for _, sub := range current.SubGroups {
+			members[sub.SubGroupID] = true
+		}
+		for _, sub := range target.SubGroups {
+			if !members[sub.SubGroupID] {
+				continue
+			}
+			minEffectiveWeight := sub.MinEffectiveWeight
+			healthResetIntervalSeconds := sub.HealthResetIntervalSeconds
+			params.SubGroupSettings = append(params.SubGroupSettings, SubGroupInput{
+				GroupID:                    sub.SubGroupID,
+				Weight:                     sub.Weight,
+				MinEffectiveWeight:         &minEffectiveWeight,
+				HealthResetIntervalSeconds: &healthResetIntervalSeconds,
+			})
+		}
+	}
+	return params
+}
+
+func nonNilMap(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
+
+// deleteGroupHistory removes the revisions and scheduled changes of deleted
+// groups. Like the hourly stats cleanup it is best-effort.
+func deleteGroupHistory(ctx context.Context, tx *gorm.DB, groupIDs []uint) {
+	if err := tx.Where("group_id IN ?", groupIDs).Delete(&models.GroupRevision{}).Error; err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("Failed to delete group revisions")
+	}
+	if err := tx.Where("group_id IN ?", groupIDs).Delete(&models.GroupScheduledChange{}).Error; err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("Failed to delete scheduled group changes")
+	}
+	if err := tx.Where("group_id IN ?", groupIDs).Delete(&models.GroupExperiment{}).Error; err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("Failed to delete group experiments")
+	}
+}
+
+// snapshotGroup captures the current routing configuration of a group.
+func snapshotGroup(ctx context.Context, db *gorm.DB, groupID uint) (*GroupSnapshot, error) {
+	var group models.Group
+	if err := db.WithContext(ctx).First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	snapshot := &GroupSnapshot{
+		Upstreams:            json.RawMessage(group.Upstreams),
+		TestModel:            group.TestModel,
+		ValidationEndpoint:   group.ValidationEndpoint,
+		Config:               group.Config,
+		ParamOverrides:       group.ParamOverrides,
+		HeaderRules:          json.RawMessage(group.HeaderRules),
+		ModelRedirectRules:   group.ModelRedirectRules,
+		ModelRedirectRulesV2: json.RawMessage(group.ModelRedirectRulesV2),
+		ModelRedirectStrict:  group.ModelRedirectStrict,
+		PathRedirects:        json.RawMessage(group.PathRedirects),
+		Preconditions:        group.Preconditions,
+		SubGroups:            []SubGroupSnapshot{},
+	}
+
+	if group.GroupType == "aggregate" {
+		err := db.WithContext(ctx).
+			Table("group_sub_groups AS gsg").
+			Select("gsg.sub_group_id, g.name, gsg.weight, gsg.min_effective_weight, gsg.health_reset_interval_seconds").
+			Joins("LEFT JOIN groups g ON g.id = gsg.sub_group_id").
+			Where("gsg.group_id = ?", groupID).
+			Order("gsg.sub_group_id").
+			Scan(&snapshot.SubGroups).Error
+		if err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+	}
+	return snapshot, nil
+}
+
+// ensureBaselineRevision records the current state of a group as its first
+// revision when the group has none yet, so the first change can be reverted.
+func ensureBaselineRevision(ctx context.Context, db *gorm.DB, groupID uint) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.GroupRevision{}).Where("group_id = ?", groupID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		_, err := recordGroupRevisionTx(ctx, tx, groupID, RevisionSourceBaseline, "")
+		return err
+	})
+}
+
+// recordGroupRevision stores the current state of a group as a new revision.
+// Nothing is recorded when the state equals the latest revision, e.g. after
+// a change of the display name; the latest revision is returned then.
+// Inside a caller's transaction it runs in a savepoint, so a failure leaves
+// that transaction usable.
+func recordGroupRevision(ctx context.Context, db *gorm.DB, groupID uint, source, note string) (*models.GroupRevision, error) {
+	var rev *models.GroupRevision
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		rev, err = recordGroupRevisionTx(ctx, tx, groupID, source, note)
+		return err
+	})
+	return rev, err
+}
+
+// lockForRevision adds FOR UPDATE on MySQL and PostgreSQL. Besides locking,
+// this makes MySQL read the latest committed rows instead of the snapshot of
+// a REPEATABLE READ transaction. SQLite serializes writers on its own.
+func lockForRevision(tx *gorm.DB) *gorm.DB {
+	switch tx.Dialector.Name() {
+	case "mysql", "postgres":
+		return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	return tx
+}
+
+func recordGroupRevisionTx(ctx context.Context, tx *gorm.DB, groupID uint, source, note string) (*models.GroupRevision, error) {
+	// Concurrent recorders of the same group wait here, so each one numbers
+	// its revision after the previous one committed.
+	var locked []uint
+	if err := lockForRevision(tx.Model(&models.Group{})).Where("id = ?", groupID).Pluck("id", &locked).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot, err := snapshotGroup(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest models.GroupRevision
+	err = lockForRevision(tx).Where("group_id = ?", groupID).Order("revision DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+	if latest.ID != 0 {
+		var previous GroupSnapshot
+		if err := json.Unmarshal(latest.Snapshot, &previous); err == nil {
+			changes, err := diffSnapshots(&previous, snapshot)
+			if err != nil {
+				return nil, err
+			}
+			if len(changes) == 0 {
+				return &latest, nil
+			}
+		}
+	}
+
+	if source == "" {
+		source = RevisionSourceUpdate
+	}
+	if runes := []rune(note); len(runes) > maxRevisionNoteLength {
+		note = string(runes[:maxRevisionNoteLength])
+	}
+	rev := &models.GroupRevision{
+		GroupID:  groupID,
+		Revision: latest.Revision + 1,
+		Source:   source,
+		Note:     note,
+		Snapshot: data,
+	}
+	if err := tx.Create(rev).Error; err != nil {
+		return nil, err
+	}
+
+	if rev.Revision > maxGroupRevisions {
+		err := tx.Where("group_id = ? AND revision <= ?", groupID, rev.Revision-maxGroupRevisions).
+			Delete(&models.GroupRevision{}).Error
+		if err != nil {
+			logrus.WithContext(ctx).WithError(err).WithField("group_id", groupID).Warn("Failed to prune old group revisions")
+		}
+	}
+	return rev, nil
+}
+
+// snapshotExpandedFields are compared per key instead of as a whole.
+var snapshotExpandedFields = map[string]bool{
+	"config":                  true,
+	"param_overrides":         true,
+	"model_redirect_rules":    true,
+	"model_redirect_rules_v2": true,
+	"preconditions":           true,
+}
+
+// diffSnapshots lists the fields that differ between two snapshots, sorted
+// by field name.
+func diffSnapshots(from, to *GroupSnapshot) ([]GroupRevisionChange, error) {
+	fromFields, err := flattenSnapshot(from)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := flattenSnapshot(to)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fromFields)+len(toFields))
+	for name := range fromFields {
+		names = append(names, name)
+	}
+	for name := range toFields {
+		if _, ok := fromFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []GroupRevisionChange{}
+	for _, name := range names {
+		fromValue, toValue := fromFields[name], toFields[name]
+		if reflect.DeepEqual(fromValue, toValue) {
+			continue
+		}
+		changes = append(changes, GroupRevisionChange{Field: name, From: fromValue, To: toValue})
+	}
+	return changes, nil
+}
+
+// flattenSnapshot decodes a snapshot into comparable values keyed by field.
+// Empty values are left out so that null, {} and [] compare equal.
+func flattenSnapshot(snapshot *GroupSnapshot) (map[string]any, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]any, len(decoded))
+	for name, value := range decoded {
+		switch {
+		case name == "sub_groups":
+			for _, sub := range snapshot.SubGroups {
+				key := sub.Name
+				if key == "" {
+					key = strconv.FormatUint(uint64(sub.SubGroupID), 10)
+				}
+				fields["sub_groups."+key] = map[string]any{
+					"weight":                        float64(sub.Weight),
+					"min_effective_weight":          float64(sub.MinEffectiveWeight),
+					"health_reset_interval_seconds": float64(sub.HealthResetIntervalSeconds),
+				}
+			}
+		case snapshotExpandedFields[name]:
+			if entries, ok := value.(map[string]any); ok {
+				for key, entry := range entries {
+					fields[name+"."+key] = entry
+				}
+			}
+		default:
+			if isEmptySnapshotValue(value) {
+				continue
+			}
+			fields[name] = value
+		}
+	}
+	return fields, nil
+}
+
+func isEmptySnapshotValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
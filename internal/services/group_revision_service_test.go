@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/datatypes"
+)
+
+func createRevisionTestGroup(t *testing.T, svc *GroupService) *models.Group {
+	t.Helper()
+	group, err := svc.CreateGroup(context.Background(), GroupCreateParams{
+		Name:               "revision-group",
+		GroupType:          "standard",
+		Upstreams:          json.RawMessage(`[{"url":"https://api.openai.com","weight":100}]`),
+		ChannelType:        "openai",
+		TestModel:          "gpt-4o-mini",
+		ValidationEndpoint: "/v1/chat/completions",
+		Config:             map[string]any{"max_retries": 3},
+	})
+	require.NoError(t, err)
+	return group
+}
+
+func TestUpdateGroup_RecordsRevisions(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	revisions := NewGroupRevisionService(db, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	_, err := svc.UpdateGroup(ctx, group.ID, GroupUpdateParams{
+		TestModel:          "gpt-4.1-mini",
+		HasTestModel:       true,
+		Config:             map[string]any{"max_retries": 5},
+		ModelRedirectRules: map[string]string{"gpt-4": "gpt-4.1"},
+	})
+	require.NoError(t, err)
+
+	list, err := revisions.ListRevisions(ctx, group.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, 2, list[0].Revision)
+	assert.Equal(t, RevisionSourceUpdate, list[0].Source)
+	assert.Equal(t, RevisionSourceBaseline, list[1].Source)
+	assert.Empty(t, list[0].Snapshot, "list results omit snapshots")
+
+	// Changes outside the routing configuration do not create a revision.
+	displayName := "Renamed"
+	_, err = svc.UpdateGroup(ctx, group.ID, GroupUpdateParams{DisplayName: &displayName})
+	require.NoError(t, err)
+	latest, err := revisions.LatestRevision(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, latest)
+
+	diff, err := revisions.Diff(ctx, group.ID, 1, 2)
+	require.NoError(t, err)
+	fields := make(map[string]GroupRevisionChange)
+	for _, change := range diff.Changes {
+		fields[change.Field] = change
+	}
+	assert.Equal(t, "gpt-4o-mini", fields["test_model"].From)
+	assert.Equal(t, "gpt-4.1-mini", fields["test_model"].To)
+	assert.Equal(t, float64(3), fields["config.max_retries"].From)
+	assert.Equal(t, float64(5), fields["config.max_retries"].To)
+	assert.Contains(t, fields, "model_redirect_rules_v2.gpt-4")
+
+	current, err := revisions.Diff(ctx, group.ID, 2, 0)
+	require.NoError(t, err)
+	assert.Empty(t, current.Changes)
+}
+
+func TestGroupRevisionService_Rollback(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	revisions := NewGroupRevisionService(db, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	rules := []models.HeaderRule{{Key: "X-Env", Value: "prod", Action: "set"}}
+	_, err := svc.UpdateGroup(ctx, group.ID, GroupUpdateParams{
+		TestModel:            "gpt-4.1-mini",
+		HasTestModel:         true,
+		Config:               map[string]any{},
+		HeaderRules:          &rules,
+		ModelRedirectRulesV2: json.RawMessage(`{"gpt-4":{"targets":[{"model":"gpt-4.1","weight":100}]}}`),
+	})
+	require.NoError(t, err)
+
+	restored, err := revisions.Rollback(ctx, group.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", restored.TestModel)
+	assert.EqualValues(t, 3, restored.Config["max_retries"])
+
+	list, err := revisions.ListRevisions(ctx, group.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, RevisionSourceRollback, list[0].Source)
+	assert.Equal(t, "rollback to revision 1", list[0].Note)
+
+	diff, err := revisions.Diff(ctx, group.ID, 1, 0)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changes)
+
+	_, err = revisions.Rollback(ctx, group.ID, 9)
+	assert.ErrorIs(t, err, ErrGroupRevisionNotFound)
+}
+
+func TestGroupRevisionService_SubGroupWeights(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	aggregateSvc := NewAggregateGroupService(db, ReadOnlyDB{DB: db}, svc.groupManager, nil)
+	svc.aggregateGroupService = aggregateSvc
+	revisions := NewGroupRevisionService(db, svc)
+	ctx := context.Background()
+
+	sub := models.Group{
+		Name:               "weights-sub",
+		GroupType:          "standard",
+		Enabled:            true,
+		ChannelType:        "openai",
+		Upstreams:          datatypes.JSON(`[{"url":"https://api.openai.com","weight":100}]`),
+		ValidationEndpoint: "/v1/chat/completions",
+		TestModel:          "gpt-4o-mini",
+	}
+	require.NoError(t, db.Create(&sub).Error)
+	aggregate := models.Group{
+		Name:        "weights-aggregate",
+		GroupType:   "aggregate",
+		Enabled:     true,
+		ChannelType: "openai",
+		Upstreams:   datatypes.JSON(`[]`),
+		TestModel:   "-",
+	}
+	require.NoError(t, db.Create(&aggregate).Error)
+	require.NoError(t, db.Create(&models.GroupSubGroup{GroupID: aggregate.ID, SubGroupID: sub.ID, Weight: 10, MinEffectiveWeight: 1}).Error)
+
+	require.NoError(t, aggregateSvc.UpdateSubGroupWeight(ctx, aggregate.ID, sub.ID, UpdateSubGroupSettingsInput{Weight: 40}))
+
+	list, err := revisions.ListRevisions(ctx, aggregate.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, RevisionSourceSubGroup, list[0].Source)
+
+	diff, err := revisions.Diff(ctx, aggregate.ID, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "sub_groups.weights-sub", diff.Changes[0].Field)
+
+	_, err = revisions.Rollback(ctx, aggregate.ID, 1)
+	require.NoError(t, err)
+	var relation models.GroupSubGroup
+	require.NoError(t, db.Where("group_id = ? AND sub_group_id = ?", aggregate.ID, sub.ID).First(&relation).Error)
+	assert.Equal(t, 10, relation.Weight)
+}
+
+func TestGroupScheduleService_ApplyDue(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	schedule := NewGroupScheduleService(db, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	testModel := "gpt-4.1-nano"
+	applyAt := time.Now().Add(time.Hour)
+	change, err := schedule.ScheduleChange(ctx, group.ID, applyAt, "off-peak", GroupChanges{TestModel: &testModel})
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduledChangePending, change.Status)
+
+	empty := ""
+	failing, err := schedule.ScheduleChange(ctx, group.ID, applyAt, "", GroupChanges{TestModel: &empty})
+	require.NoError(t, err)
+
+	cancelled, err := schedule.ScheduleChange(ctx, group.ID, applyAt, "", GroupChanges{TestModel: &testModel})
+	require.NoError(t, err)
+	require.NoError(t, schedule.CancelScheduledChange(ctx, group.ID, cancelled.ID))
+	assert.Error(t, schedule.CancelScheduledChange(ctx, group.ID, cancelled.ID))
+
+	_, err = schedule.ScheduleChange(ctx, group.ID, time.Now().Add(-time.Minute), "", GroupChanges{TestModel: &testModel})
+	assert.Error(t, err)
+	_, err = schedule.ScheduleChange(ctx, group.ID, applyAt, "", GroupChanges{})
+	assert.Error(t, err)
+
+	// Nothing is due yet.
+	schedule.applyDue(ctx, time.Now())
+	var stored models.Group
+	require.NoError(t, db.First(&stored, group.ID).Error)
+	assert.Equal(t, "gpt-4o-mini", stored.TestModel)
+
+	schedule.applyDue(ctx, applyAt.Add(time.Second))
+	require.NoError(t, db.First(&stored, group.ID).Error)
+	assert.Equal(t, testModel, stored.TestModel)
+
+	changes, err := schedule.ListScheduledChanges(ctx, group.ID)
+	require.NoError(t, err)
+	status := make(map[uint]models.GroupScheduledChange)
+	for _, c := range changes {
+		status[c.ID] = c
+	}
+	assert.Equal(t, models.ScheduledChangeApplied, status[change.ID].Status)
+	assert.Equal(t, 2, status[change.ID].Revision)
+	assert.NotNil(t, status[change.ID].AppliedAt)
+	assert.Equal(t, models.ScheduledChangeFailed, status[failing.ID].Status)
+	assert.Contains(t, status[failing.ID].Error, "validation.test_model_empty")
+	assert.Equal(t, models.ScheduledChangeCancelled, status[cancelled.ID].Status)
+
+	revisions := NewGroupRevisionService(db, svc)
+	list, err := revisions.ListRevisions(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, RevisionSourceSchedule, list[0].Source)
+	assert.Contains(t, list[0].Note, "off-peak")
+}
+
+func TestGroupScheduleService_ApplySkipsUnclaimableChanges(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	schedule := NewGroupScheduleService(db, svc)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+
+	testModel := "gpt-4.1-nano"
+	change, err := schedule.ScheduleChange(ctx, group.ID, time.Now().Add(time.Hour), "", GroupChanges{TestModel: &testModel})
+	require.NoError(t, err)
+
+	// The change was loaded as due, then cancelled before it was applied.
+	due := *change
+	require.NoError(t, schedule.CancelScheduledChange(ctx, group.ID, change.ID))
+	schedule.apply(ctx, &due)
+
+	var stored models.Group
+	require.NoError(t, db.First(&stored, group.ID).Error)
+	assert.Equal(t, "gpt-4o-mini", stored.TestModel)
+	var reloaded models.GroupScheduledChange
+	require.NoError(t, db.First(&reloaded, change.ID).Error)
+	assert.Equal(t, models.ScheduledChangeCancelled, reloaded.Status)
+
+	// Applying twice, e.g. by a leader that lost its lease, changes nothing.
+	applied, err := schedule.ScheduleChange(ctx, group.ID, time.Now().Add(time.Hour), "", GroupChanges{TestModel: &testModel})
+	require.NoError(t, err)
+	due = *applied
+	schedule.apply(ctx, &due)
+	schedule.apply(ctx, &due)
+	list, err := NewGroupRevisionService(db, svc).ListRevisions(ctx, group.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, 2, "one revision for the creation and one for the change")
+	assert.Error(t, schedule.CancelScheduledChange(ctx, group.ID, applied.ID))
+}
+
+func TestUpdateGroup_SucceedsWhenRevisionFails(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	svc := setupTestGroupService(t, db)
+	ctx := context.Background()
+	group := createRevisionTestGroup(t, svc)
+	require.NoError(t, db.Migrator().DropTable(&models.GroupRevision{}))
+
+	_, err := svc.UpdateGroup(ctx, group.ID, GroupUpdateParams{TestModel: "gpt-4.1-mini", HasTestModel: true})
+	require.NoError(t, err)
+	var stored models.Group
+	require.NoError(t, db.First(&stored, group.ID).Error)
+	assert.Equal(t, "gpt-4.1-mini", stored.TestModel)
+}
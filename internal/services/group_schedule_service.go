@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// scheduledChangeCheckInterval is how often due scheduled changes are applied.
+const scheduledChangeCheckInterval = 30 * time.Second
+
+// ErrScheduledChangeNotFound is returned for an unknown scheduled change.
+var ErrScheduledChangeNotFound = app_errors.NewAPIError(app_errors.ErrResourceNotFound, "scheduled change not found")
+
+// GroupChanges is the payload of a scheduled group change. Fields have the
+// same meaning as in the group update API; sub_groups updates the weight
+// settings of existing sub-groups of an aggregate group.
+type GroupChanges struct {
+	Upstreams            json.RawMessage            `json:"upstreams,omitempty"`
+	TestModel            *string                    `json:"test_model,omitempty"`
+	ValidationEndpoint   *string                    `json:"validation_endpoint,omitempty"`
+	ParamOverrides       map[string]any             `json:"param_overrides,omitempty"`
+	Config               map[string]any             `json:"config,omitempty"`
+	HeaderRules          *[]models.HeaderRule       `json:"header_rules,omitempty"`
+	ModelRedirectRules   map[string]string          `json:"model_redirect_rules,omitempty"`
+	ModelRedirectRulesV2 json.RawMessage            `json:"model_redirect_rules_v2,omitempty"`
+	ModelRedirectStrict  *bool                      `json:"model_redirect_strict,omitempty"`
+	Preconditions        map[string]any             `json:"preconditions,omitempty"`
+	PathRedirects        *[]models.PathRedirectRule `json:"path_redirects,omitempty"`
+	SubGroups            []SubGroupInput            `json:"sub_groups,omitempty"`
+}
+
+// IsEmpty reports whether the changes do not set any field.
+func (c *GroupChanges) IsEmpty() bool {
+	return len(c.Upstreams) == 0 && c.TestModel == nil && c.ValidationEndpoint == nil &&
+		c.ParamOverrides == nil && c.Config == nil && c.HeaderRules == nil &&
+		c.ModelRedirectRules == nil && len(c.ModelRedirectRulesV2) == 0 && c.ModelRedirectStrict == nil &&
+		c.Preconditions == nil && c.PathRedirects == nil && len(c.SubGroups) == 0
+}
+
+// Params converts the changes into group update parameters.
+func (c *GroupChanges) Params() GroupUpdateParams {
+	params := GroupUpdateParams{
+		ValidationEndpoint:   c.ValidationEndpoint,
+		ParamOverrides:       c.ParamOverrides,
+		Config:               c.Config,
+		HeaderRules:          c.HeaderRules,
+		ModelRedirectRules:   c.ModelRedirectRules,
+		ModelRedirectRulesV2: c.ModelRedirectRulesV2,
+		ModelRedirectStrict:  c.ModelRedirectStrict,
+		Preconditions:        c.Preconditions,
+		SubGroupSettings:     c.SubGroups,
+	}
+	if len(c.Upstreams) > 0 {
+		params.Upstreams = c.Upstreams
+		params.HasUpstreams = true
+	}
+	if c.TestModel != nil {
+		params.TestModel = *c.TestModel
+		params.HasTestModel = true
+	}
+	if c.PathRedirects != nil {
+		params.PathRedirects = append([]models.PathRedirectRule{}, *c.PathRedirects...)
+	}
+	return params
+}
+
+// GroupScheduleService stores group changes for later and applies them when
+// they are due. Changes are applied on the master node only.
+type GroupScheduleService struct {
+	db           *gorm.DB
+	groupService *GroupService
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewGroupScheduleService creates a GroupScheduleService.
+func NewGroupScheduleService(db *gorm.DB, groupService *GroupService) *GroupScheduleService {
+	return &GroupScheduleService{
+		db:           db,
+		groupService: groupService,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start starts applying due changes.
+func (s *GroupScheduleService) Start() {
+	// Restarting after regaining leadership needs a new channel; run keeps the
+	// one it was started with.
+	select {
+	case <-s.stopCh:
+		s.stopCh = make(chan struct{})
+	default:
+	}
+	s.wg.Add(1)
+	go s.run(s.stopCh)
+	logrus.Debug("Group schedule service started")
+}
+
+// Stop stops the service gracefully.
+func (s *GroupScheduleService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("GroupScheduleService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("GroupScheduleService stop timed out.")
+	}
+}
+
+func (s *GroupScheduleService) run(stopCh <-chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(scheduledChangeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.applyDue(context.Background(), time.Now())
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// ListScheduledChanges returns the scheduled changes of a group, the latest
+// apply time first.
+func (s *GroupScheduleService) ListScheduledChanges(ctx context.Context, groupID uint) ([]models.GroupScheduledChange, error) {
+	if _, err := FindGroupByID(ctx, s.db, groupID); err != nil {
+		return nil, err
+	}
+	changes := []models.GroupScheduledChange{}
+	if err := s.db.WithContext(ctx).Where("group_id = ?", groupID).Order("apply_at DESC, id DESC").Find(&changes).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return changes, nil
+}
+
+// ScheduleChange stores changes to apply to a group at applyAt. The changes
+// are validated when they are applied; a change that fails is marked failed.
+func (s *GroupScheduleService) ScheduleChange(ctx context.Context, groupID uint, applyAt time.Time, note string, changes GroupChanges) (*models.GroupScheduledChange, error) {
+	group, err := FindGroupByID(ctx, s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if changes.IsEmpty() {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "changes must set at least one field")
+	}
+	if len([]rune(note)) > maxRevisionNoteLength {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("note must not exceed %d characters", maxRevisionNoteLength))
+	}
+	if !applyAt.After(time.Now()) {
+		return nil, app_errors.NewAPIError(app_errors.ErrValidation, "apply_at must be in the future")
+	}
+	if len(changes.SubGroups) > 0 {
+		if group.GroupType != "aggregate" {
+			return nil, NewI18nError(app_errors.ErrBadRequest, "group.not_aggregate", nil)
+		}
+		for _, sub := range changes.SubGroups {
+			if err := validateSubGroupSettings(sub.Weight, sub.MinEffectiveWeight, sub.HealthResetIntervalSeconds); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	change := &models.GroupScheduledChange{
+		GroupID: groupID,
+		ApplyAt: applyAt.UTC(),
+		Status:  models.ScheduledChangePending,
+		Note:    note,
+		Changes: data,
+	}
+	if err := s.db.WithContext(ctx).Create(change).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return change, nil
+}
+
+// CancelScheduledChange cancels a change that has not been applied yet.
+func (s *GroupScheduleService) CancelScheduledChange(ctx context.Context, groupID, changeID uint) error {
+	var change models.GroupScheduledChange
+	err := s.db.WithContext(ctx).Where("id = ? AND group_id = ?", changeID, groupID).First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrScheduledChangeNotFound
+	}
+	if err != nil {
+		return app_errors.ParseDBError(err)
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.GroupScheduledChange{}).
+		Where("id = ? AND status = ?", changeID, models.ScheduledChangePending).
+		Update("status", models.ScheduledChangeCancelled)
+	if result.Error != nil {
+		return app_errors.ParseDBError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("scheduled change is already %s", change.Status))
+	}
+	return nil
+}
+
+// applyDue applies the pending changes that are due at now, oldest first.
+func (s *GroupScheduleService) applyDue(ctx context.Context, now time.Time) {
+	var due []models.GroupScheduledChange
+	err := s.db.WithContext(ctx).
+		Where("status = ? AND apply_at <= ?", models.ScheduledChangePending, now.UTC()).
+		Order("apply_at, id").
+		Find(&due).Error
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load scheduled group changes")
+		return
+	}
+	for i := range due {
+		s.apply(ctx, &due[i])
+	}
+}
+
+// apply claims a pending change before applying it, so a change cancelled
+// meanwhile or claimed by another node is skipped and applied at most once.
+func (s *GroupScheduleService) apply(ctx context.Context, change *models.GroupScheduledChange) {
+	logger := logrus.WithFields(logrus.Fields{"group_id": change.GroupID, "scheduled_change_id": change.ID})
+
+	claim := s.db.WithContext(ctx).Model(&models.GroupScheduledChange{}).
+		Where("id = ? AND status = ?", change.ID, models.ScheduledChangePending).
+		Update("status", models.ScheduledChangeApplying)
+	if claim.Error != nil {
+		logger.WithError(claim.Error).Warn("Failed to claim scheduled group change")
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	updates := map[string]any{"applied_at": time.Now().UTC()}
+	var changes GroupChanges
+	err := json.Unmarshal(change.Changes, &changes)
+	if err == nil {
+		params := changes.Params()
+		params.RevisionSource = RevisionSourceSchedule
+		params.RevisionNote = fmt.Sprintf("scheduled change %d", change.ID)
+		if change.Note != "" {
+			params.RevisionNote += ": " + change.Note
+		}
+		_, err = s.groupService.UpdateGroup(ctx, change.GroupID, params)
+	}
+
+	if err != nil {
+		updates["status"] = models.ScheduledChangeFailed
+		updates["error"] = describeServiceError(err)
+		logger.WithError(err).Warn("Failed to apply scheduled group change")
+	} else {
+		updates["status"] = models.ScheduledChangeApplied
+		if revision, err := latestGroupRevision(ctx, s.db, change.GroupID); err == nil {
+			updates["revision"] = revision
+		}
+		logger.Info("Applied scheduled group change")
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.GroupScheduledChange{}).
+		Where("id = ? AND status = ?", change.ID, models.ScheduledChangeApplying).
+		Updates(updates).Error; err != nil {
+		logger.WithError(err).Error("Failed to update scheduled group change status")
+	}
+}
+
+// describeServiceError includes the message ID of service errors, whose
+// Error() only carries the generic API error text.
+func describeServiceError(err error) string {
+	var i18nErr *I18nError
+	if errors.As(err, &i18nErr) && i18nErr.MessageID != "" {
+		if len(i18nErr.Template) > 0 {
+			return fmt.Sprintf("%s (%s %v)", err.Error(), i18nErr.MessageID, i18nErr.Template)
+		}
+		return fmt.Sprintf("%s (%s)", err.Error(), i18nErr.MessageID)
+	}
+	return err.Error()
+}
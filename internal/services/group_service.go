@@ -212,6 +212,13 @@ type GroupUpdateParams struct {
 	PathRedirects        []models.PathRedirectRule
 	ProxyKeys            *string
 	SubGroups            *[]SubGroupInput
+	// SubGroupSettings updates the weight settings of existing sub-groups of
+	// an aggregate group; GroupID identifies the sub-group.
+	SubGroupSettings []SubGroupInput
+	// RevisionSource and RevisionNote are stored with the revision recorded
+	// for the update. RevisionSource defaults to "update".
+	RevisionSource string
+	RevisionNote   string
 }
 
 // GroupReorderItem captures one sort update in a group reorder operation.
@@ -1087,6 +1094,17 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.PathRedirects = pathRedirectsJSON
 	}
 
+	if len(params.SubGroupSettings) > 0 {
+		if group.GroupType != "aggregate" {
+			return nil, NewI18nError(app_errors.ErrBadRequest, "group.not_aggregate", nil)
+		}
+		for _, input := range params.SubGroupSettings {
+			if err := validateSubGroupSettings(input.Weight, input.MinEffectiveWeight, input.HealthResetIntervalSeconds); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Start transaction to ensure parent group update and child group sync are atomic
 	tx := s.db.WithContext(ctx).Begin()
 	if err := tx.Error; err != nil {
@@ -1098,6 +1116,12 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		}
 	}()
 
+	// Keep the state before the first recorded change so that it can be restored.
+	// History is best effort; it must not fail the update itself.
+	if err := ensureBaselineRevision(ctx, tx, group.ID); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("group_id", group.ID).Warn("Failed to record baseline group revision")
+	}
+
 	// Perform the actual database write within transaction
 	if err := tx.Save(&group).Error; err != nil {
 		// Check if it's a duplicate name error and return i18n error
@@ -1156,6 +1180,20 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		}
 	}
 
+	for _, input := range params.SubGroupSettings {
+		if err := updateSubGroupSettings(ctx, tx, group.ID, input.GroupID, UpdateSubGroupSettingsInput{
+			Weight:                     input.Weight,
+			MinEffectiveWeight:         input.MinEffectiveWeight,
+			HealthResetIntervalSeconds: input.HealthResetIntervalSeconds,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := recordGroupRevision(ctx, tx, group.ID, params.RevisionSource, params.RevisionNote); err != nil {
+		logrus.WithContext(ctx).WithError(err).WithField("group_id", group.ID).Warn("Failed to record group revision")
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
@@ -1461,6 +1499,10 @@ func (s *GroupService) DeleteGroup(ctx context.Context, id uint) (retErr error)
 			logrus.WithContext(ctx).WithError(err).Warn("Failed to delete group hourly stats")
 			// Continue anyway - stats cleanup is best-effort
 		}
+		deleteGroupHistory(ctx, tx, relatedGroupIDs)
+		if err := tx.Where("group_id IN ?", relatedGroupIDs).Delete(&models.SemanticCacheEntry{}).Error; err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("Failed to delete semantic cache entries")
+		}
 
 		if err := tx.Commit().Error; err != nil {
 			return app_errors.ErrDatabase
@@ -1528,6 +1570,10 @@ func (s *GroupService) DeleteGroup(ctx context.Context, id uint) (retErr error)
 			logrus.WithContext(ctx).WithError(err).Warn("Failed to delete group hourly stats")
 			// Continue anyway - stats cleanup is best-effort
 		}
+		deleteGroupHistory(ctx, tx, relatedGroupIDs)
+		if err := tx.Where("group_id IN ?", relatedGroupIDs).Delete(&models.SemanticCacheEntry{}).Error; err != nil {
+			logrus.WithContext(ctx).WithError(err).Warn("Failed to delete semantic cache entries")
+		}
 
 		// Use chunked deletion to avoid long-running single DELETE statement
 		// Note: Use subquery approach for PostgreSQL compatibility (DELETE...LIMIT not supported)
@@ -1761,6 +1807,10 @@ func (s *GroupService) runAsyncGroupDeletion(groupID uint, relatedGroupIDs []uin
 		logrus.WithContext(ctx).WithError(err).Warn("Failed to delete group hourly stats in async deletion")
 		// Continue anyway - stats cleanup is best-effort
 	}
+	deleteGroupHistory(ctx, tx, relatedGroupIDs)
+	if err := tx.Where("group_id IN ?", relatedGroupIDs).Delete(&models.SemanticCacheEntry{}).Error; err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("Failed to delete semantic cache entries")
+	}
 
 	// Delete child groups if any
 	if len(relatedGroupIDs) > 1 {
@@ -2841,6 +2891,13 @@ func (s *GroupService) validateAndCleanConfig(configMap map[string]any, channelT
 	if channelType != "openai" && channelType != "anthropic" {
 		delete(configMap, "codex_support")
 	}
+	if channelType != "openai" {
+		delete(configMap, "semantic_cache_enabled")
+		delete(configMap, "semantic_cache_embedding_group")
+		delete(configMap, "semantic_cache_embedding_model")
+		delete(configMap, "semantic_cache_threshold")
+		delete(configMap, "semantic_cache_ttl_seconds")
+	}
 	if channelType != "openai-response" {
 		delete(configMap, "codex_affinity_enabled")
 		delete(configMap, "codex_affinity_max_retries")
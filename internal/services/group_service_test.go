@@ -62,6 +62,9 @@ func setupTestDB(tb testing.TB) *gorm.DB {
 		&models.RequestLog{},
 		&models.GroupHourlyStat{},
 		&models.DynamicWeightMetric{},
+		&models.GroupRevision{},
+		&models.GroupScheduledChange{},
+		&models.GroupExperiment{},
 	)
 	require.NoError(tb, err)
 
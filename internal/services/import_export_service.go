@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"gpt-load/internal/encryption"
+	"gpt-load/internal/guardrail"
 	"gpt-load/internal/models"
 	"gpt-load/internal/utils"
 
@@ -895,10 +896,11 @@ type SystemExportData struct {
 // Note: This type is intentionally duplicated from centralizedmgmt package
 // to avoid circular dependency between services and centralizedmgmt packages.
 type HubAccessKeyExportInfo struct {
-	Name          string   `json:"name"`
-	KeyValue      string   `json:"key_value"`      // Encrypted value (same as storage)
-	AllowedModels []string `json:"allowed_models"` // Parsed from JSON for readability
-	Enabled       bool     `json:"enabled"`
+	Name            string          `json:"name"`
+	KeyValue        string          `json:"key_value"`      // Encrypted value (same as storage)
+	AllowedModels   []string        `json:"allowed_models"` // Parsed from JSON for readability
+	Enabled         bool            `json:"enabled"`
+	GuardrailPolicy json.RawMessage `json:"guardrail_policy,omitempty"`
 }
 
 // ManagedSitesExportData represents exported managed sites data
@@ -2134,12 +2136,13 @@ func (s *ImportExportService) generateUniqueSiteName(tx *gorm.DB, baseName strin
 // 1. Avoid circular dependency between services and centralizedmgmt packages
 // 2. Keep export/import logic self-contained with only the fields needed
 type hubAccessKeyModel struct {
-	ID            uint   `gorm:"primaryKey"`
-	Name          string `gorm:"column:name"`
-	KeyHash       string `gorm:"column:key_hash"`
-	KeyValue      string `gorm:"column:key_value"`
-	AllowedModels []byte `gorm:"column:allowed_models"`
-	Enabled       bool   `gorm:"column:enabled"`
+	ID              uint   `gorm:"primaryKey"`
+	Name            string `gorm:"column:name"`
+	KeyHash         string `gorm:"column:key_hash"`
+	KeyValue        string `gorm:"column:key_value"`
+	AllowedModels   []byte `gorm:"column:allowed_models"`
+	Enabled         bool   `gorm:"column:enabled"`
+	GuardrailPolicy []byte `gorm:"column:guardrail_policy"`
 }
 
 func (hubAccessKeyModel) TableName() string {
@@ -2173,10 +2176,11 @@ func (s *ImportExportService) exportHubAccessKeys() []HubAccessKeyExportInfo {
 		}
 
 		exports = append(exports, HubAccessKeyExportInfo{
-			Name:          key.Name,
-			KeyValue:      key.KeyValue, // Keep encrypted
-			AllowedModels: allowedModels,
-			Enabled:       key.Enabled,
+			Name:            key.Name,
+			KeyValue:        key.KeyValue, // Keep encrypted
+			AllowedModels:   allowedModels,
+			Enabled:         key.Enabled,
+			GuardrailPolicy: key.GuardrailPolicy,
 		})
 	}
 
@@ -2252,12 +2256,19 @@ func (s *ImportExportService) importHubAccessKeys(tx *gorm.DB, keys []HubAccessK
 		}
 
 		// Create the key with the encrypted value from export
+		// Invalid guardrail policies are dropped rather than failing the key.
+		var guardrailPolicy []byte
+		if policy, err := guardrail.Parse(keyInfo.GuardrailPolicy); err == nil && policy != nil {
+			guardrailPolicy = keyInfo.GuardrailPolicy
+		}
+
 		key := &hubAccessKeyModel{
-			Name:          uniqueName,
-			KeyHash:       keyHash,
-			KeyValue:      keyInfo.KeyValue, // Keep the encrypted value
-			AllowedModels: allowedModelsJSON,
-			Enabled:       keyInfo.Enabled,
+			Name:            uniqueName,
+			KeyHash:         keyHash,
+			KeyValue:        keyInfo.KeyValue, // Keep the encrypted value
+			AllowedModels:   allowedModelsJSON,
+			Enabled:         keyInfo.Enabled,
+			GuardrailPolicy: guardrailPolicy,
 		}
 
 		if err := tx.Create(key).Error; err != nil {
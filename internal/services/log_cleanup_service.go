@@ -31,8 +31,16 @@ func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsMan
 
 // Start starts the log cleanup service.
 func (s *LogCleanupService) Start() {
+	// A restart after regaining leadership needs a new channel. The loop keeps
+	// the one it was started with, so a loop left over from a timed-out Stop
+	// does not race with the new one.
+	select {
+	case <-s.stopCh:
+		s.stopCh = make(chan struct{})
+	default:
+	}
 	s.wg.Add(1)
-	go s.run()
+	go s.run(s.stopCh)
 	logrus.Debug("Log cleanup service started")
 }
 
@@ -55,14 +63,14 @@ func (s *LogCleanupService) Stop(ctx context.Context) {
 }
 
 // run executes the main cleanup loop.
-func (s *LogCleanupService) run() {
+func (s *LogCleanupService) run(stopCh <-chan struct{}) {
 	defer s.wg.Done()
 
 	// Initial delay to allow database initialization to complete
 	// This prevents slow SQL during startup when DB is busy with other tasks
 	select {
 	case <-time.After(30 * time.Second):
-	case <-s.stopCh:
+	case <-stopCh:
 		return
 	}
 
@@ -76,7 +84,7 @@ func (s *LogCleanupService) run() {
 		select {
 		case <-ticker.C:
 			s.cleanupExpiredLogs()
-		case <-s.stopCh:
+		case <-stopCh:
 			return
 		}
 	}
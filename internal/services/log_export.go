@@ -0,0 +1,326 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Log export formats.
+const (
+	LogExportFormatCSV   = "csv"
+	LogExportFormatJSONL = "jsonl"
+	// LogExportFormatColumnar writes one schema line followed by one line per row
+	// group, each holding a value array per column. It avoids repeating column
+	// names on every row and loads directly into columnar tools.
+	LogExportFormatColumnar = "columnar"
+)
+
+// logExportBatchSize is the number of rows fetched per cursor page. Memory use
+// stays bounded by one page regardless of the export size.
+const logExportBatchSize = 500
+
+// LogExportOptions controls a full request log export.
+type LogExportOptions struct {
+	Format        string
+	IncludeBodies bool // Include request_body and response_body columns
+	MaskKeys      bool // Mask key values instead of exporting them in plain text
+}
+
+// logExportColumn describes one exported RequestLog column.
+type logExportColumn struct {
+	name  string
+	kind  string // "string", "int", "bool" or "timestamp"; used by the columnar schema
+	value func(log *models.RequestLog) any
+}
+
+var logExportColumns = []logExportColumn{
+	{"id", "string", func(l *models.RequestLog) any { return l.ID }},
+	{"timestamp", "timestamp", func(l *models.RequestLog) any { return l.Timestamp.UTC().Format(time.RFC3339Nano) }},
+	{"group_id", "int", func(l *models.RequestLog) any { return l.GroupID }},
+	{"group_name", "string", func(l *models.RequestLog) any { return l.GroupName }},
+	{"parent_group_id", "int", func(l *models.RequestLog) any { return l.ParentGroupID }},
+	{"parent_group_name", "string", func(l *models.RequestLog) any { return l.ParentGroupName }},
+	{"key_value", "string", func(l *models.RequestLog) any { return l.KeyValue }},
+	{"key_hash", "string", func(l *models.RequestLog) any { return l.KeyHash }},
+	{"model", "string", func(l *models.RequestLog) any { return l.Model }},
+	{"mapped_model", "string", func(l *models.RequestLog) any { return l.MappedModel }},
+	{"is_success", "bool", func(l *models.RequestLog) any { return l.IsSuccess }},
+	{"source_ip", "string", func(l *models.RequestLog) any { return l.SourceIP }},
+	{"status_code", "int", func(l *models.RequestLog) any { return l.StatusCode }},
+	{"request_path", "string", func(l *models.RequestLog) any { return l.RequestPath }},
+	{"duration_ms", "int", func(l *models.RequestLog) any { return l.Duration }},
+	{"error_message", "string", func(l *models.RequestLog) any { return l.ErrorMessage }},
+	{"user_agent", "string", func(l *models.RequestLog) any { return l.UserAgent }},
+	{"upstream_user_agent", "string", func(l *models.RequestLog) any { return l.UpstreamUserAgent }},
+	{"simulated_client_enabled", "bool", func(l *models.RequestLog) any { return l.SimulatedClientEnabled }},
+	{"request_type", "string", func(l *models.RequestLog) any { return l.RequestType }},
+	{"upstream_addr", "string", func(l *models.RequestLog) any { return l.UpstreamAddr }},
+	{"is_stream", "bool", func(l *models.RequestLog) any { return l.IsStream }},
+	{"input_tokens", "int", func(l *models.RequestLog) any { return l.InputTokens }},
+	{"output_tokens", "int", func(l *models.RequestLog) any { return l.OutputTokens }},
+	{"total_tokens", "int", func(l *models.RequestLog) any { return l.TotalTokens }},
+	{"cache_read_tokens", "int", func(l *models.RequestLog) any { return l.CacheReadTokens }},
+	{"cache_write_tokens", "int", func(l *models.RequestLog) any { return l.CacheWriteTokens }},
+	{"thinking_tokens", "int", func(l *models.RequestLog) any { return l.ThinkingTokens }},
+	{"token_usage_source", "string", func(l *models.RequestLog) any { return l.TokenUsageSource }},
+	{"experiment_id", "int", func(l *models.RequestLog) any { return l.ExperimentID }},
+	{"experiment_variant", "string", func(l *models.RequestLog) any { return l.ExperimentVariant }},
+	{"request_body", "string", func(l *models.RequestLog) any { return l.RequestBody }},
+	{"response_body", "string", func(l *models.RequestLog) any { return l.ResponseBody }},
+}
+
+// IsValidLogExportFormat reports whether format is a supported export format.
+func IsValidLogExportFormat(format string) bool {
+	switch format {
+	case LogExportFormatCSV, LogExportFormatJSONL, LogExportFormatColumnar:
+		return true
+	}
+	return false
+}
+
+// selectedLogExportColumns returns the exported columns for opts.
+func selectedLogExportColumns(opts LogExportOptions) []logExportColumn {
+	if opts.IncludeBodies {
+		return logExportColumns
+	}
+	columns := make([]logExportColumn, 0, len(logExportColumns))
+	for _, column := range logExportColumns {
+		if column.name != "request_body" && column.name != "response_body" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// logExportEncoder writes exported rows in one format.
+type logExportEncoder interface {
+	writeBatch(logs []models.RequestLog) error
+	close() error
+}
+
+// StreamLogs streams every request log column matching the current filters.
+// Rows are read in (timestamp, id) order with keyset pagination, which works on
+// SQLite, MySQL and PostgreSQL and keeps memory constant for any export size.
+func (s *LogService) StreamLogs(c *gin.Context, writer io.Writer, opts LogExportOptions) error {
+	columns := selectedLogExportColumns(opts)
+	selectNames := make([]string, len(columns))
+	for i, column := range columns {
+		selectNames[i] = column.name
+		if column.name == "duration_ms" {
+			// The JSON name differs from the database column.
+			selectNames[i] = "duration"
+		}
+	}
+
+	var encoder logExportEncoder
+	switch opts.Format {
+	case LogExportFormatJSONL:
+		encoder = &jsonlLogExportEncoder{encoder: json.NewEncoder(writer), columns: columns}
+	case LogExportFormatColumnar:
+		enc, err := newColumnarLogExportEncoder(writer, columns)
+		if err != nil {
+			return err
+		}
+		encoder = enc
+	default:
+		enc, err := newCSVLogExportEncoder(writer, columns)
+		if err != nil {
+			return err
+		}
+		encoder = enc
+	}
+
+	var (
+		lastTimestamp time.Time
+		lastID        string
+		hasCursor     bool
+	)
+	for {
+		query := s.DB.Model(&models.RequestLog{}).
+			Select(selectNames).
+			Scopes(s.logFiltersScope(c))
+		if hasCursor {
+			query = query.Where("(timestamp > ? OR (timestamp = ? AND id > ?))", lastTimestamp, lastTimestamp, lastID)
+		}
+
+		var batch []models.RequestLog
+		if err := query.Order("timestamp ASC, id ASC").Limit(logExportBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to fetch request logs: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastTimestamp, lastID, hasCursor = batch[len(batch)-1].Timestamp, batch[len(batch)-1].ID, true
+
+		for i := range batch {
+			batch[i].KeyValue = s.exportKeyValue(&batch[i], opts.MaskKeys)
+		}
+		if err := encoder.writeBatch(batch); err != nil {
+			return err
+		}
+		if len(batch) < logExportBatchSize {
+			break
+		}
+		if err := c.Request.Context().Err(); err != nil {
+			return err
+		}
+	}
+	return encoder.close()
+}
+
+// exportKeyValue decrypts the stored key and masks it when requested.
+func (s *LogService) exportKeyValue(log *models.RequestLog, mask bool) string {
+	if log.KeyValue == "" {
+		return ""
+	}
+	decrypted, err := s.EncryptionSvc.Decrypt(log.KeyValue)
+	if err != nil {
+		logrus.WithError(err).WithField("log_id", log.ID).Error("Failed to decrypt key for log export")
+		return "failed-to-decrypt"
+	}
+	if mask {
+		return utils.MaskAPIKey(decrypted)
+	}
+	return decrypted
+}
+
+type csvLogExportEncoder struct {
+	writer  *csv.Writer
+	columns []logExportColumn
+	record  []string
+}
+
+func newCSVLogExportEncoder(w io.Writer, columns []logExportColumn) (*csvLogExportEncoder, error) {
+	enc := &csvLogExportEncoder{writer: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		enc.record[i] = column.name
+	}
+	if err := enc.writer.Write(enc.record); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return enc, nil
+}
+
+func (e *csvLogExportEncoder) writeBatch(logs []models.RequestLog) error {
+	for i := range logs {
+		for j, column := range e.columns {
+			e.record[j] = formatLogExportValue(column.value(&logs[i]))
+		}
+		if err := e.writer.Write(e.record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+	// Flush per batch so the client receives data progressively.
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvLogExportEncoder) close() error {
+	e.writer.Flush()
+	if err := e.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV data: %w", err)
+	}
+	return nil
+}
+
+type jsonlLogExportEncoder struct {
+	encoder *json.Encoder
+	columns []logExportColumn
+}
+
+func (e *jsonlLogExportEncoder) writeBatch(logs []models.RequestLog) error {
+	row := make(map[string]any, len(e.columns))
+	for i := range logs {
+		for _, column := range e.columns {
+			row[column.name] = column.value(&logs[i])
+		}
+		if err := e.encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to write JSONL record: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *jsonlLogExportEncoder) close() error {
+	return nil
+}
+
+// columnarLogExportSchema is the first line of a columnar export.
+type columnarLogExportSchema struct {
+	Format  string                    `json:"format"`
+	Columns []columnarLogExportColumn `json:"columns"`
+}
+
+type columnarLogExportColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// columnarLogExportRowGroup holds one cursor page of rows, one array per column.
+type columnarLogExportRowGroup struct {
+	Rows    int              `json:"rows"`
+	Columns map[string][]any `json:"columns"`
+}
+
+type columnarLogExportEncoder struct {
+	encoder *json.Encoder
+	columns []logExportColumn
+}
+
+func newColumnarLogExportEncoder(w io.Writer, columns []logExportColumn) (*columnarLogExportEncoder, error) {
+	enc := &columnarLogExportEncoder{encoder: json.NewEncoder(w), columns: columns}
+	schema := columnarLogExportSchema{Format: "gpt-load-columnar-v1", Columns: make([]columnarLogExportColumn, len(columns))}
+	for i, column := range columns {
+		schema.Columns[i] = columnarLogExportColumn{Name: column.name, Type: column.kind}
+	}
+	if err := enc.encoder.Encode(schema); err != nil {
+		return nil, fmt.Errorf("failed to write columnar schema: %w", err)
+	}
+	return enc, nil
+}
+
+func (e *columnarLogExportEncoder) writeBatch(logs []models.RequestLog) error {
+	group := columnarLogExportRowGroup{Rows: len(logs), Columns: make(map[string][]any, len(e.columns))}
+	for _, column := range e.columns {
+		values := make([]any, len(logs))
+		for i := range logs {
+			values[i] = column.value(&logs[i])
+		}
+		group.Columns[column.name] = values
+	}
+	if err := e.encoder.Encode(group); err != nil {
+		return fmt.Errorf("failed to write columnar row group: %w", err)
+	}
+	return nil
+}
+
+func (e *columnarLogExportEncoder) close() error {
+	return nil
+}
+
+func formatLogExportValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogExportContext(query string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/logs/export/full?"+query, nil)
+	return c
+}
+
+func TestStreamLogsCSV(t *testing.T) {
+	t.Parallel()
+	service, db := setupLogServiceTest(t)
+
+	encryptedKey, err := service.EncryptionSvc.Encrypt("sk-export-secret-value")
+	require.NoError(t, err)
+	now := time.Now().UTC()
+	require.NoError(t, db.Create(&[]models.RequestLog{
+		{ID: "a", Timestamp: now, GroupName: "g1", KeyValue: encryptedKey, StatusCode: 200, Duration: 12, RequestBody: "secret body"},
+		{ID: "b", Timestamp: now.Add(time.Second), GroupName: "g2", StatusCode: 500},
+	}).Error)
+
+	var buf bytes.Buffer
+	require.NoError(t, service.StreamLogs(newLogExportContext("group_name=g1"), &buf, LogExportOptions{Format: LogExportFormatCSV, MaskKeys: true}))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	header := records[0]
+	assert.NotContains(t, header, "request_body")
+	row := make(map[string]string, len(header))
+	for i, name := range header {
+		row[name] = records[1][i]
+	}
+	assert.Equal(t, "a", row["id"])
+	assert.Equal(t, "12", row["duration_ms"])
+	assert.NotEqual(t, "sk-export-secret-value", row["key_value"])
+	assert.NotEmpty(t, row["key_value"])
+}
+
+func TestStreamLogsJSONLWithBodies(t *testing.T) {
+	t.Parallel()
+	service, db := setupLogServiceTest(t)
+
+	encryptedKey, err := service.EncryptionSvc.Encrypt("sk-plain")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.RequestLog{
+		ID: "a", Timestamp: time.Now(), KeyValue: encryptedKey, RequestBody: `{"q":1}`, ResponseBody: "ok",
+	}).Error)
+
+	var buf bytes.Buffer
+	require.NoError(t, service.StreamLogs(newLogExportContext(""), &buf, LogExportOptions{Format: LogExportFormatJSONL, IncludeBodies: true}))
+
+	var row map[string]any
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &row))
+	assert.Equal(t, "sk-plain", row["key_value"])
+	assert.Equal(t, `{"q":1}`, row["request_body"])
+	assert.Equal(t, "ok", row["response_body"])
+}
+
+func TestStreamLogsPaginatesWithCursor(t *testing.T) {
+	t.Parallel()
+	service, db := setupLogServiceTest(t)
+
+	// Rows sharing a timestamp exercise the id tie-breaker across page boundaries.
+	base := time.Now().UTC().Truncate(time.Second)
+	total := logExportBatchSize*2 + 37
+	logs := make([]models.RequestLog, total)
+	for i := range logs {
+		logs[i] = models.RequestLog{ID: fmt.Sprintf("log-%05d", i), Timestamp: base.Add(time.Duration(i/100) * time.Second)}
+	}
+	require.NoError(t, db.CreateInBatches(logs, 200).Error)
+
+	var buf bytes.Buffer
+	require.NoError(t, service.StreamLogs(newLogExportContext(""), &buf, LogExportOptions{Format: LogExportFormatColumnar}))
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 16*1024*1024)
+	require.True(t, scanner.Scan())
+	var schema columnarLogExportSchema
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &schema))
+	assert.Equal(t, "id", schema.Columns[0].Name)
+
+	seen := make(map[string]bool, total)
+	groups := 0
+	for scanner.Scan() {
+		var group columnarLogExportRowGroup
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &group))
+		require.Len(t, group.Columns["id"], group.Rows)
+		for _, id := range group.Columns["id"] {
+			seen[id.(string)] = true
+		}
+		groups++
+	}
+	require.NoError(t, scanner.Err())
+	assert.Len(t, seen, total)
+	assert.Equal(t, 3, groups)
+}
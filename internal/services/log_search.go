@@ -0,0 +1,225 @@
+package services
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Search backends, selected once per LogService by probing for the index.
+const (
+	logSearchFTS5     = "fts5"
+	logSearchTSVector = "tsvector"
+	logSearchFulltext = "fulltext"
+	logSearchLike     = "like"
+)
+
+const (
+	maxLogSearchTerms     = 10
+	maxLogSearchQueryLen  = 500
+	logSearchSnippetWidth = 80 // runes of context on each side of the first match
+	maxLogSearchSnippets  = 3
+)
+
+// LogSearchSnippet is a highlighted excerpt of a logged field that matched the
+// search query. Text is HTML-escaped with matches wrapped in <mark>.
+type LogSearchSnippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// parseLogSearchTerms splits a free-text query into terms. Quotes and
+// operators are dropped so user input never reaches the engines' query syntax.
+func parseLogSearchTerms(q string) []string {
+	if len(q) > maxLogSearchQueryLen {
+		q = q[:maxLogSearchQueryLen]
+	}
+	q = strings.Map(func(r rune) rune {
+		switch r {
+		case '"', '\'', '`', '*', '+', '-', '(', ')', '<', '>', '~', '@', ':', '^':
+			return ' '
+		}
+		return r
+	}, q)
+	terms := strings.Fields(q)
+	if len(terms) > maxLogSearchTerms {
+		terms = terms[:maxLogSearchTerms]
+	}
+	return terms
+}
+
+// logSearchBackend reports which full-text index is available, falling back
+// to LIKE scans when the migration was not enabled.
+func (s *LogService) logSearchBackend() string {
+	s.searchOnce.Do(func() {
+		s.searchBackend = logSearchLike
+		var count int64
+		var err error
+		switch s.DB.Dialector.Name() {
+		case "sqlite":
+			err = s.DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", models.RequestLogSearchTable).Scan(&count).Error
+			if count > 0 {
+				s.searchBackend = logSearchFTS5
+			}
+		case "postgres":
+			err = s.DB.Raw("SELECT COUNT(*) FROM pg_indexes WHERE tablename = 'request_logs' AND indexname = ?", models.RequestLogSearchIndex).Scan(&count).Error
+			if count > 0 {
+				s.searchBackend = logSearchTSVector
+			}
+		case "mysql":
+			err = s.DB.Raw(`
+				SELECT COUNT(*) FROM information_schema.STATISTICS
+				WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'request_logs' AND INDEX_NAME = ?
+			`, models.RequestLogSearchIndex).Scan(&count).Error
+			if count > 0 {
+				s.searchBackend = logSearchFulltext
+			}
+		}
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to detect request log search index, using LIKE search")
+		}
+		logrus.Debugf("Request log search backend: %s", s.searchBackend)
+	})
+	return s.searchBackend
+}
+
+// applyLogSearch restricts db to logs whose request body, response body or
+// error message contain every term of q.
+func (s *LogService) applyLogSearch(db *gorm.DB, q string) *gorm.DB {
+	terms := parseLogSearchTerms(q)
+	if len(terms) == 0 {
+		return db
+	}
+
+	switch s.logSearchBackend() {
+	case logSearchFTS5:
+		phrases := make([]string, len(terms))
+		for i, term := range terms {
+			phrases[i] = `"` + term + `"`
+		}
+		return db.Where("request_logs.rowid IN (SELECT rowid FROM "+models.RequestLogSearchTable+" WHERE "+models.RequestLogSearchTable+" MATCH ?)", strings.Join(phrases, " "))
+	case logSearchTSVector:
+		return db.Where(models.RequestLogSearchVector+" @@ plainto_tsquery('simple', ?)", strings.Join(terms, " "))
+	case logSearchFulltext:
+		required := make([]string, len(terms))
+		for i, term := range terms {
+			required[i] = `+"` + term + `"`
+		}
+		return db.Where("MATCH(request_body, response_body, error_message) AGAINST (? IN BOOLEAN MODE)", strings.Join(required, " "))
+	default:
+		for _, term := range terms {
+			pattern := "%" + escapeLike(term) + "%"
+			db = db.Where("(request_body LIKE ? ESCAPE '!' OR response_body LIKE ? ESCAPE '!' OR error_message LIKE ? ESCAPE '!')", pattern, pattern, pattern)
+		}
+		return db
+	}
+}
+
+// LogSearchSnippets returns highlighted excerpts of the fields of log that
+// contain terms of q, in request body, response body, error message order.
+func LogSearchSnippets(log *models.RequestLog, q string) []LogSearchSnippet {
+	terms := parseLogSearchTerms(q)
+	if len(terms) == 0 {
+		return nil
+	}
+	fields := []struct{ name, value string }{
+		{"request_body", log.RequestBody},
+		{"response_body", log.ResponseBody},
+		{"error_message", log.ErrorMessage},
+	}
+	var snippets []LogSearchSnippet
+	for _, field := range fields {
+		if text, ok := highlightLogSearchSnippet(field.value, terms); ok {
+			snippets = append(snippets, LogSearchSnippet{Field: field.name, Text: text})
+			if len(snippets) == maxLogSearchSnippets {
+				break
+			}
+		}
+	}
+	return snippets
+}
+
+type matchRange struct{ start, end int }
+
+// highlightLogSearchSnippet cuts a window around the first term match and
+// wraps every match inside it in <mark>.
+func highlightLogSearchSnippet(value string, terms []string) (string, bool) {
+	if value == "" {
+		return "", false
+	}
+	// Match case-insensitively unless case folding changes byte offsets.
+	haystack := strings.ToLower(value)
+	folded := len(haystack) == len(value)
+	if !folded {
+		haystack = value
+	}
+
+	var matches []matchRange
+	for _, term := range terms {
+		needle := term
+		if folded {
+			needle = strings.ToLower(term)
+		}
+		for offset := 0; ; {
+			idx := strings.Index(haystack[offset:], needle)
+			if idx < 0 {
+				break
+			}
+			start := offset + idx
+			matches = append(matches, matchRange{start, start + len(needle)})
+			offset = start + len(needle)
+		}
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	windowStart := runeBoundaryBefore(value, matches[0].start, logSearchSnippetWidth)
+	windowEnd := runeBoundaryAfter(value, matches[0].end, logSearchSnippetWidth)
+
+	var b strings.Builder
+	if windowStart > 0 {
+		b.WriteString("…")
+	}
+	cursor := windowStart
+	for _, m := range matches {
+		if m.start < cursor || m.end > windowEnd {
+			continue
+		}
+		b.WriteString(html.EscapeString(value[cursor:m.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(value[m.start:m.end]))
+		b.WriteString("</mark>")
+		cursor = m.end
+	}
+	b.WriteString(html.EscapeString(value[cursor:windowEnd]))
+	if windowEnd < len(value) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// runeBoundaryBefore moves back up to n runes from pos.
+func runeBoundaryBefore(s string, pos, n int) int {
+	for ; n > 0 && pos > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:pos])
+		pos -= size
+	}
+	return pos
+}
+
+// runeBoundaryAfter moves forward up to n runes from pos.
+func runeBoundaryAfter(s string, pos, n int) int {
+	for ; n > 0 && pos < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		pos += size
+	}
+	return pos
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedSearchLogs(t *testing.T, service *LogService) {
+	t.Helper()
+	now := time.Now()
+	logs := []models.RequestLog{
+		{ID: "search-1", Timestamp: now, RequestBody: `{"messages":[{"content":"find the Purple elephant"}]}`},
+		{ID: "search-2", Timestamp: now.Add(-time.Minute), ResponseBody: `{"content":"elephant seen in the garden"}`},
+		{ID: "search-3", Timestamp: now.Add(-2 * time.Minute), ErrorMessage: "upstream timeout for purple request"},
+	}
+	require.NoError(t, service.DB.Create(&logs).Error)
+}
+
+func searchLogIDs(t *testing.T, service *LogService, q string) []string {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/logs?q="+q, nil)
+
+	var logs []models.RequestLog
+	require.NoError(t, service.GetLogsQuery(c).Order("timestamp desc").Find(&logs).Error)
+	ids := make([]string, len(logs))
+	for i, log := range logs {
+		ids[i] = log.ID
+	}
+	return ids
+}
+
+func TestLogSearchFallsBackToLike(t *testing.T) {
+	t.Parallel()
+	service, _ := setupLogServiceTest(t)
+	seedSearchLogs(t, service)
+
+	assert.Equal(t, logSearchLike, service.logSearchBackend())
+	assert.Equal(t, []string{"search-1", "search-2"}, searchLogIDs(t, service, "elephant"))
+	assert.Equal(t, []string{"search-1"}, searchLogIDs(t, service, "purple+elephant"))
+	assert.Equal(t, []string{"search-3"}, searchLogIDs(t, service, "timeout"))
+	assert.Empty(t, searchLogIDs(t, service, "giraffe"))
+}
+
+func TestLogSearchUsesFTS5Index(t *testing.T) {
+	t.Parallel()
+	service, db := setupLogServiceTest(t)
+	seedSearchLogs(t, service)
+
+	// Same external-content table as migration v1.30.0, which also covers the sync triggers.
+	require.NoError(t, db.Exec(`CREATE VIRTUAL TABLE request_logs_fts USING fts5(
+		request_body, response_body, error_message, content='request_logs', content_rowid='rowid')`).Error)
+	require.NoError(t, db.Exec("INSERT INTO request_logs_fts(request_logs_fts) VALUES ('rebuild')").Error)
+
+	assert.Equal(t, logSearchFTS5, service.logSearchBackend())
+	assert.Equal(t, []string{"search-1", "search-2"}, searchLogIDs(t, service, "elephant"))
+	assert.Equal(t, []string{"search-1"}, searchLogIDs(t, service, "purple+elephant"))
+	assert.Equal(t, []string{"search-3"}, searchLogIDs(t, service, `"timeout"`), "quotes are stripped before MATCH")
+	assert.Empty(t, searchLogIDs(t, service, "giraffe"))
+}
+
+func TestLogSearchSnippets(t *testing.T) {
+	t.Parallel()
+
+	log := &models.RequestLog{
+		RequestBody:  `{"prompt":"<b>Purple</b> elephant"}`,
+		ErrorMessage: "no match here",
+	}
+	snippets := LogSearchSnippets(log, "purple")
+	require.Len(t, snippets, 1)
+	assert.Equal(t, "request_body", snippets[0].Field)
+	assert.Equal(t, `{&#34;prompt&#34;:&#34;&lt;b&gt;<mark>Purple</mark>&lt;/b&gt; elephant&#34;}`, snippets[0].Text)
+
+	long := &models.RequestLog{ResponseBody: string(make([]byte, 200)) + "needle" + string(make([]byte, 200))}
+	snippets = LogSearchSnippets(long, "needle")
+	require.Len(t, snippets, 1)
+	assert.Contains(t, snippets[0].Text, "…")
+	assert.Contains(t, snippets[0].Text, "<mark>needle</mark>")
+	assert.Less(t, len(snippets[0].Text), 200)
+
+	assert.Nil(t, LogSearchSnippets(log, "  "))
+}
+
+func TestParseLogSearchTerms(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"foo", "bar"}, parseLogSearchTerms(`"foo" -bar*`))
+	assert.Empty(t, parseLogSearchTerms(`"" ()`))
+	assert.Len(t, parseLogSearchTerms("a b c d e f g h i j k l"), maxLogSearchTerms)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gpt-load/internal/encryption"
@@ -30,6 +31,9 @@ type ExportableLogKey struct {
 type LogService struct {
 	DB            *gorm.DB
 	EncryptionSvc encryption.Service
+
+	searchOnce    sync.Once
+	searchBackend string
 }
 
 // NewLogService creates a new LogService.
@@ -83,6 +87,9 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 		if errorContains := c.Query("error_contains"); errorContains != "" {
 			db = db.Where("error_message LIKE ? ESCAPE '!'", "%"+escapeLike(errorContains)+"%")
 		}
+		if q := strings.TrimSpace(c.Query("q")); q != "" {
+			db = s.applyLogSearch(db, q)
+		}
 		if startTimeStr := c.Query("start_time"); startTimeStr != "" {
 			if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
 				db = db.Where("timestamp >= ?", startTime)
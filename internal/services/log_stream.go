@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestLogStreamChannel carries every recorded request log as JSON to
+	// live log viewers on all nodes.
+	RequestLogStreamChannel = "request_log_stream"
+	// requestLogStreamViewersKey exists while any node has a live viewer, so
+	// nodes without viewers skip encoding and publishing logs.
+	requestLogStreamViewersKey = "request_log_stream:viewers"
+	requestLogStreamViewersTTL = 30 * time.Second
+	// RequestLogStreamKeepAlive is how often viewers must call KeepStreamAlive.
+	RequestLogStreamKeepAlive = 10 * time.Second
+
+	requestLogStreamQueueSize     = 256
+	requestLogStreamCheckInterval = 5 * time.Second
+)
+
+// SubscribeStream registers a live log viewer and subscribes to recorded logs.
+// Callers must close the subscription and call KeepStreamAlive while it is open.
+func (s *RequestLogService) SubscribeStream() (store.Subscription, error) {
+	sub, err := s.store.Subscribe(RequestLogStreamChannel)
+	if err != nil {
+		return nil, err
+	}
+	s.KeepStreamAlive()
+	return sub, nil
+}
+
+// KeepStreamAlive marks that a live log viewer is still connected.
+func (s *RequestLogService) KeepStreamAlive() {
+	if err := s.store.Set(requestLogStreamViewersKey, []byte("1"), requestLogStreamViewersTTL); err != nil {
+		logrus.WithError(err).Debug("Failed to mark request log stream viewer")
+	}
+	s.streamActive.Store(true)
+}
+
+// streamLog queues an accepted log for live viewers. It never blocks: logs are
+// dropped when the publisher falls behind.
+func (s *RequestLogService) streamLog(log *models.RequestLog) {
+	if !s.streamActive.Load() {
+		return
+	}
+	entry := *log
+	select {
+	case s.streamQueue <- &entry:
+	default:
+		dropped := atomic.AddInt64(&s.droppedStreamLogs, 1)
+		if dropped%100 == 1 { // Log every 100 drops to avoid log spam
+			logrus.Warnf("Dropping live request log due to backpressure (dropped total: %d)", dropped)
+		}
+	}
+}
+
+// StartStreamPublisher starts publishing recorded logs to live viewers. Every
+// node records logs, so the publisher runs on all nodes, independently of the
+// master-only flush loop started by Start.
+func (s *RequestLogService) StartStreamPublisher() {
+	s.streamWg.Add(1)
+	go s.runStreamPublisher(s.streamStopChan)
+}
+
+// StopStreamPublisher stops the live log publisher.
+func (s *RequestLogService) StopStreamPublisher(ctx context.Context) {
+	close(s.streamStopChan)
+
+	done := make(chan struct{})
+	go func() {
+		s.streamWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Debug("Request log stream publisher stopped.")
+	case <-ctx.Done():
+		logrus.Warn("Request log stream publisher stop timed out.")
+	}
+}
+
+// runStreamPublisher publishes queued logs to the stream channel, so a slow
+// store never delays the proxy path.
+func (s *RequestLogService) runStreamPublisher(stopCh <-chan struct{}) {
+	defer s.streamWg.Done()
+
+	s.refreshStreamActive()
+	ticker := time.NewTicker(requestLogStreamCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case log := <-s.streamQueue:
+			payload, err := json.Marshal(log)
+			if err != nil {
+				logrus.WithError(err).Warn("Failed to encode live request log")
+				continue
+			}
+			if err := s.store.Publish(RequestLogStreamChannel, payload); err != nil {
+				logrus.WithError(err).Debug("Failed to publish live request log")
+			}
+		case <-ticker.C:
+			s.refreshStreamActive()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (s *RequestLogService) refreshStreamActive() {
+	exists, err := s.store.Exists(requestLogStreamViewersKey)
+	s.streamActive.Store(err == nil && exists)
+}
+
+// LogStreamFilter matches live logs against the query filters of GET /api/logs.
+// It mirrors logFiltersScope, including its case-insensitive LIKE matching.
+type LogStreamFilter struct {
+	parentGroupName string
+	groupName       string
+	keyHash         string
+	model           string
+	isSuccess       *bool
+	requestType     string
+	statusCode      *int
+	sourceIP        string
+	errorContains   string
+	searchTerms     []string
+	startTime       *time.Time
+	endTime         *time.Time
+}
+
+// NewLogStreamFilter parses the log filters of the request.
+func (s *LogService) NewLogStreamFilter(c *gin.Context) *LogStreamFilter {
+	f := &LogStreamFilter{
+		parentGroupName: strings.ToLower(c.Query("parent_group_name")),
+		groupName:       strings.ToLower(c.Query("group_name")),
+		model:           strings.ToLower(c.Query("model")),
+		requestType:     c.Query("request_type"),
+		sourceIP:        c.Query("source_ip"),
+		errorContains:   strings.ToLower(c.Query("error_contains")),
+	}
+	if keyValue := c.Query("key_value"); keyValue != "" {
+		f.keyHash = s.EncryptionSvc.Hash(keyValue)
+	}
+	if isSuccess, err := strconv.ParseBool(c.Query("is_success")); err == nil {
+		f.isSuccess = &isSuccess
+	}
+	if statusCode, err := strconv.Atoi(c.Query("status_code")); err == nil {
+		f.statusCode = &statusCode
+	}
+	for _, term := range parseLogSearchTerms(strings.TrimSpace(c.Query("q"))) {
+		f.searchTerms = append(f.searchTerms, strings.ToLower(term))
+	}
+	if startTime, err := time.Parse(time.RFC3339, c.Query("start_time")); err == nil {
+		f.startTime = &startTime
+	}
+	if endTime, err := time.Parse(time.RFC3339, c.Query("end_time")); err == nil {
+		f.endTime = &endTime
+	}
+	return f
+}
+
+// Match reports whether log passes every filter.
+func (f *LogStreamFilter) Match(log *models.RequestLog) bool {
+	switch {
+	case f.parentGroupName != "" && !strings.Contains(strings.ToLower(log.ParentGroupName), f.parentGroupName):
+		return false
+	case f.groupName != "" && !strings.Contains(strings.ToLower(log.GroupName), f.groupName):
+		return false
+	case f.keyHash != "" && log.KeyHash != f.keyHash:
+		return false
+	case f.model != "" && !strings.Contains(strings.ToLower(log.Model), f.model):
+		return false
+	case f.isSuccess != nil && log.IsSuccess != *f.isSuccess:
+		return false
+	case f.requestType != "" && log.RequestType != f.requestType:
+		return false
+	case f.statusCode != nil && log.StatusCode != *f.statusCode:
+		return false
+	case f.sourceIP != "" && log.SourceIP != f.sourceIP:
+		return false
+	case f.errorContains != "" && !strings.Contains(strings.ToLower(log.ErrorMessage), f.errorContains):
+		return false
+	case f.startTime != nil && log.Timestamp.Before(*f.startTime):
+		return false
+	case f.endTime != nil && log.Timestamp.After(*f.endTime):
+		return false
+	}
+	for _, term := range f.searchTerms {
+		if !strings.Contains(strings.ToLower(log.RequestBody), term) &&
+			!strings.Contains(strings.ToLower(log.ResponseBody), term) &&
+			!strings.Contains(strings.ToLower(log.ErrorMessage), term) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogServiceStreamsRecordedLogs(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.APIKey{}, &models.RequestLog{}, &models.GroupHourlyStat{}, &models.ModelTokenHourlyStat{})
+	memStore := store.NewMemoryStore()
+	service := NewRequestLogService(db, memStore, config.NewSystemSettingsManager())
+	service.Start()
+	service.StartStreamPublisher()
+	t.Cleanup(func() {
+		service.StopStreamPublisher(context.Background())
+		service.Stop(context.Background())
+	})
+
+	// Without viewers nothing is queued.
+	require.NoError(t, service.Record(&models.RequestLog{GroupName: "quiet"}))
+	assert.Empty(t, service.streamQueue)
+
+	sub, err := service.SubscribeStream()
+	require.NoError(t, err)
+	defer sub.Close()
+
+	require.NoError(t, service.Record(&models.RequestLog{GroupName: "live", StatusCode: 200}))
+	select {
+	case msg := <-sub.Channel():
+		var entry models.RequestLog
+		require.NoError(t, json.Unmarshal(msg.Payload, &entry))
+		assert.Equal(t, "live", entry.GroupName)
+		assert.NotEmpty(t, entry.ID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("recorded log was not streamed")
+	}
+}
+
+func TestRequestLogServiceStreamsLogsFromNonMasterNode(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.APIKey{}, &models.RequestLog{}, &models.GroupHourlyStat{}, &models.ModelTokenHourlyStat{})
+	sharedStore := store.NewMemoryStore()
+	master := NewRequestLogService(db, sharedStore, config.NewSystemSettingsManager())
+	master.Start()
+	master.StartStreamPublisher()
+	t.Cleanup(func() {
+		master.StopStreamPublisher(context.Background())
+		master.Stop(context.Background())
+	})
+
+	sub, err := master.SubscribeStream()
+	require.NoError(t, err)
+	defer sub.Close()
+
+	// A slave node never starts the flush loop, only the publisher.
+	slave := NewRequestLogService(db, sharedStore, config.NewSystemSettingsManager())
+	slave.StartStreamPublisher()
+	t.Cleanup(func() { slave.StopStreamPublisher(context.Background()) })
+
+	require.Eventually(t, slave.streamActive.Load, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, slave.Record(&models.RequestLog{GroupName: "slave", StatusCode: 200}))
+	select {
+	case msg := <-sub.Channel():
+		var entry models.RequestLog
+		require.NoError(t, json.Unmarshal(msg.Payload, &entry))
+		assert.Equal(t, "slave", entry.GroupName)
+	case <-time.After(2 * time.Second):
+		t.Fatal("log recorded on the slave node was not streamed")
+	}
+}
+
+func TestRequestLogServiceStreamDropsUnderBackpressure(t *testing.T) {
+	t.Parallel()
+
+	// No publisher is running, so the queue fills up.
+	service := &RequestLogService{streamQueue: make(chan *models.RequestLog, 1)}
+	service.streamActive.Store(true)
+
+	service.streamLog(&models.RequestLog{ID: "1"})
+	service.streamLog(&models.RequestLog{ID: "2"})
+	assert.Len(t, service.streamQueue, 1)
+	assert.EqualValues(t, 1, service.droppedStreamLogs)
+}
+
+func TestLogStreamFilterMatchesLogFilters(t *testing.T) {
+	t.Parallel()
+	service, _ := setupLogServiceTest(t)
+
+	newFilter := func(query string) *LogStreamFilter {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/logs/stream?"+query, nil)
+		return service.NewLogStreamFilter(c)
+	}
+
+	now := time.Now()
+	log := &models.RequestLog{
+		Timestamp:    now,
+		GroupName:    "OpenAI-Main",
+		Model:        "gpt-4o",
+		KeyHash:      service.EncryptionSvc.Hash("sk-live"),
+		IsSuccess:    false,
+		StatusCode:   429,
+		RequestType:  models.RequestTypeFinal,
+		SourceIP:     "10.0.0.1",
+		ErrorMessage: "Rate limit exceeded",
+		RequestBody:  `{"messages":[{"content":"purple elephant"}]}`,
+	}
+
+	for query, want := range map[string]bool{
+		"":                                 true,
+		"group_name=openai":                true,
+		"group_name=claude":                false,
+		"model=GPT":                        true,
+		"key_value=sk-live":                true,
+		"key_value=sk-other":               false,
+		"is_success=false&status_code=429": true,
+		"is_success=true":                  false,
+		"request_type=retry":               false,
+		"source_ip=10.0.0.2":               false,
+		"error_contains=rate+limit":        true,
+		"q=purple+elephant":                true,
+		"q=purple+giraffe":                 false,
+		"start_time=" + now.Add(time.Minute).UTC().Format(time.RFC3339): false,
+		"end_time=" + now.Add(time.Minute).UTC().Format(time.RFC3339):   true,
+	} {
+		assert.Equal(t, want, newFilter(query).Match(log), query)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/config"
+	"gpt-load/internal/logsink"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
 	"gpt-load/internal/utils"
@@ -68,6 +69,9 @@ const (
 	// If this limit is reached, new logs will be dropped to prevent memory exhaustion
 	// Set to 10000 to handle ~10MB of log data (assuming ~1KB per log)
 	MaxPendingLogs = 10000
+	// PendingLogsHighWaterMark is the backlog above which the node reports itself
+	// not ready, before logs start being dropped at MaxPendingLogs.
+	PendingLogsHighWaterMark = MaxPendingLogs * 8 / 10
 )
 
 // RequestLogService is responsible for managing request logs.
@@ -77,9 +81,15 @@ type RequestLogService struct {
 	settingsManager *config.SystemSettingsManager
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
-	ticker          *time.Ticker
-	droppedLogs     int64 // Counter for dropped logs due to memory pressure
-	pendingCount    int64 // Approximate count of pending logs (updated on flush)
+	droppedLogs     int64            // Counter for dropped logs due to memory pressure
+	pendingCount    int64            // Approximate count of pending logs (updated on flush)
+	sinks           *logsink.Manager // Optional external log sinks fed after each DB write
+
+	streamQueue       chan *models.RequestLog // Logs waiting to be published to live viewers
+	streamStopChan    chan struct{}           // Stops the live log publisher, which runs on every node
+	streamWg          sync.WaitGroup
+	streamActive      atomic.Bool // Whether any node has a live log viewer
+	droppedStreamLogs int64       // Counter for live logs dropped under backpressure
 }
 
 // NewRequestLogService creates a new RequestLogService instance
@@ -89,9 +99,16 @@ func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSetti
 		store:           store,
 		settingsManager: sm,
 		stopChan:        make(chan struct{}),
+		streamQueue:     make(chan *models.RequestLog, requestLogStreamQueueSize),
+		streamStopChan:  make(chan struct{}),
 	}
 }
 
+// SetSinks sets the external log sinks that receive every log written to the database.
+func (s *RequestLogService) SetSinks(sinks *logsink.Manager) {
+	s.sinks = sinks
+}
+
 // Start initializes the service and starts the periodic flush routine
 func (s *RequestLogService) Start() {
 	// Initialize pendingCount from persistent store to maintain accuracy across restarts
@@ -105,11 +122,20 @@ func (s *RequestLogService) Start() {
 		}
 	}
 
+	// Restarting after regaining leadership needs a new stop channel. The loop
+	// only sees the channel it was started with, so a loop left over from a
+	// timed-out Stop keeps waiting on the old one.
+	select {
+	case <-s.stopChan:
+		s.stopChan = make(chan struct{})
+	default:
+	}
+
 	s.wg.Add(1)
-	go s.runLoop()
+	go s.runLoop(s.stopChan)
 }
 
-func (s *RequestLogService) runLoop() {
+func (s *RequestLogService) runLoop(stopCh <-chan struct{}) {
 	defer s.wg.Done()
 
 	// Initial flush on start
@@ -119,8 +145,8 @@ func (s *RequestLogService) runLoop() {
 	if interval <= 0 {
 		interval = time.Minute
 	}
-	s.ticker = time.NewTicker(interval)
-	defer s.ticker.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
 	// Emergency flush ticker - runs every 30 seconds to check for memory pressure
 	// This provides a safety net if regular flush is delayed or failing
@@ -129,13 +155,13 @@ func (s *RequestLogService) runLoop() {
 
 	for {
 		select {
-		case <-s.ticker.C:
+		case <-ticker.C:
 			newInterval := time.Duration(s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes) * time.Minute
 			if newInterval <= 0 {
 				newInterval = time.Minute
 			}
 			if newInterval != interval {
-				s.ticker.Reset(newInterval)
+				ticker.Reset(newInterval)
 				interval = newInterval
 				logrus.Debugf("Request log write interval updated to: %v", interval)
 			}
@@ -148,7 +174,7 @@ func (s *RequestLogService) runLoop() {
 				logrus.Warnf("Emergency flush triggered: %d pending logs (threshold: %d)", pendingCount, MaxPendingLogs/2)
 				s.flush()
 			}
-		case <-s.stopChan:
+		case <-stopCh:
 			return
 		}
 	}
@@ -182,7 +208,11 @@ func (s *RequestLogService) Record(log *models.RequestLog) error {
 
 	settings := s.settingsManager.GetSettings()
 	if settings.RequestLogWriteIntervalMinutes == 0 {
-		return s.writeLogsToDB([]*models.RequestLog{log})
+		if err := s.writeLogsToDB([]*models.RequestLog{log}); err != nil {
+			return err
+		}
+		s.streamLog(log)
+		return nil
 	}
 
 	// Fast path: check approximate pending count using atomic counter
@@ -223,9 +253,16 @@ func (s *RequestLogService) Record(log *models.RequestLog) error {
 
 	// Increment approximate counter
 	atomic.AddInt64(&s.pendingCount, 1)
+	s.streamLog(log)
 	return nil
 }
 
+// PendingLogs returns the number of logs waiting in the store to be flushed
+// to the database. The backlog is shared by all nodes and flushed by the master.
+func (s *RequestLogService) PendingLogs() (int64, error) {
+	return s.store.SCard(PendingLogKeysSet)
+}
+
 // RecordError is a convenience method to record error logs with minimal parameters.
 // It creates a RequestLog entry for failed requests (e.g., auth failures, early errors).
 // Note: groupID=0 indicates "no group context" (e.g., when group lookup fails before group is known).
@@ -395,7 +432,7 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 		return nil
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.CreateInBatches(logs, len(logs)).Error; err != nil {
 			return fmt.Errorf("failed to batch insert request logs: %w", err)
 		}
@@ -464,12 +501,21 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// Ship only what was committed so sinks never see logs that will be retried.
+	s.sinks.Publish(logs)
+	return nil
 }
 
 func aggregateModelTokenStats(logs []*models.RequestLog) map[modelTokenStatKey]modelTokenStatCounts {
 	stats := make(map[modelTokenStatKey]modelTokenStatCounts, len(logs)/4)
 	for _, log := range logs {
-		if log.RequestType != models.RequestTypeFinal || log.GroupID == 0 || log.TotalTokens <= 0 {
+		// Responses blocked by a guardrail carry the usage of the upstream call.
+		countsTokens := log.RequestType == models.RequestTypeFinal || log.RequestType == models.RequestTypeGuardrail
+		if !countsTokens || log.GroupID == 0 || log.TotalTokens <= 0 {
 			continue
 		}
 		hourlyTime := log.Timestamp.Truncate(time.Hour)
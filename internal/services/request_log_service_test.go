@@ -1,10 +1,13 @@
 package services
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"gpt-load/internal/config"
 	"gpt-load/internal/models"
+	"gpt-load/internal/store"
 
 	"github.com/glebarez/sqlite"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +28,26 @@ func setupRequestLogServiceTestDB(t *testing.T, tables ...interface{}) *gorm.DB
 	return db
 }
 
+func TestRequestLogServiceRestartsAfterTimedOutStop(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.APIKey{}, &models.RequestLog{}, &models.GroupHourlyStat{}, &models.ModelTokenHourlyStat{})
+	service := NewRequestLogService(db, store.NewMemoryStore(), config.NewSystemSettingsManager())
+	service.Start()
+
+	// A cancelled context makes Stop return without waiting, as after losing
+	// leadership with a slow flush. The restart must not touch the old loop.
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	service.Stop(expired)
+	service.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	service.Stop(ctx)
+	assert.NoError(t, ctx.Err(), "both loops exit on their own stop channel")
+}
+
 func TestRequestLogServiceWriteLogsToDBUpdatesKeyStatsByGroupAndHash(t *testing.T) {
 	t.Parallel()
 
@@ -167,6 +190,18 @@ func TestRequestLogServiceWriteLogsToDBAggregatesModelTokenStats(t *testing.T) {
 			OutputTokens: 100,
 			TotalTokens:  200,
 		},
+		{
+			ID:           "token-6",
+			Timestamp:    baseTime.Add(5 * time.Minute),
+			GroupID:      1,
+			Model:        "gpt-4o",
+			IsSuccess:    false,
+			StatusCode:   400,
+			RequestType:  models.RequestTypeGuardrail,
+			InputTokens:  4,
+			OutputTokens: 6,
+			TotalTokens:  10,
+		},
 	}
 
 	service := &RequestLogService{db: db}
@@ -174,10 +209,10 @@ func TestRequestLogServiceWriteLogsToDBAggregatesModelTokenStats(t *testing.T) {
 
 	var direct models.ModelTokenHourlyStat
 	require.NoError(t, db.Where("group_id = ? AND parent_group_id = ? AND model = ?", 1, 0, "gpt-4o").First(&direct).Error)
-	assert.EqualValues(t, 1, direct.RequestCount)
-	assert.EqualValues(t, 10, direct.InputTokens)
-	assert.EqualValues(t, 5, direct.OutputTokens)
-	assert.EqualValues(t, 15, direct.TotalTokens)
+	assert.EqualValues(t, 2, direct.RequestCount, "responses blocked by a guardrail keep their usage")
+	assert.EqualValues(t, 14, direct.InputTokens)
+	assert.EqualValues(t, 11, direct.OutputTokens)
+	assert.EqualValues(t, 25, direct.TotalTokens)
 
 	var aggregate models.ModelTokenHourlyStat
 	require.NoError(t, db.Where("group_id = ? AND parent_group_id = ? AND model = ?", 2, 9, "claude-sonnet-4").First(&aggregate).Error)
@@ -0,0 +1,59 @@
+package services
+
+// RequestLogStats summarizes a set of request logs.
+type RequestLogStats struct {
+	Requests      int64   `json:"requests"`
+	Successes     int64   `json:"successes"`
+	SuccessRate   float64 `json:"success_rate"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	TotalTokens   int64   `json:"total_tokens"`
+	AvgTokens     float64 `json:"avg_tokens"`
+}
+
+// requestLogStatsColumns aggregates request logs into requestLogStatsRow columns.
+const requestLogStatsColumns = `COUNT(*) AS requests,
+	COALESCE(SUM(CASE WHEN is_success THEN 1 ELSE 0 END), 0) AS successes,
+	COALESCE(SUM(duration), 0) AS duration_ms,
+	COALESCE(SUM(input_tokens), 0) AS input_tokens,
+	COALESCE(SUM(output_tokens), 0) AS output_tokens,
+	COALESCE(SUM(total_tokens), 0) AS total_tokens`
+
+// requestLogStatsRow is the scan target of requestLogStatsColumns. StatsKey
+// holds the grouping column, selected AS stats_key.
+type requestLogStatsRow struct {
+	StatsKey     string
+	Requests     int64
+	Successes    int64
+	DurationMs   int64
+	InputTokens  int64
+	OutputTokens int64
+	TotalTokens  int64
+}
+
+func (row requestLogStatsRow) add(other requestLogStatsRow) requestLogStatsRow {
+	row.Requests += other.Requests
+	row.Successes += other.Successes
+	row.DurationMs += other.DurationMs
+	row.InputTokens += other.InputTokens
+	row.OutputTokens += other.OutputTokens
+	row.TotalTokens += other.TotalTokens
+	return row
+}
+
+func (row requestLogStatsRow) stats() RequestLogStats {
+	stats := RequestLogStats{
+		Requests:     row.Requests,
+		Successes:    row.Successes,
+		InputTokens:  row.InputTokens,
+		OutputTokens: row.OutputTokens,
+		TotalTokens:  row.TotalTokens,
+	}
+	if row.Requests > 0 {
+		stats.SuccessRate = float64(row.Successes) / float64(row.Requests)
+		stats.AvgDurationMs = float64(row.DurationMs) / float64(row.Requests)
+		stats.AvgTokens = float64(row.TotalTokens) / float64(row.Requests)
+	}
+	return stats
+}
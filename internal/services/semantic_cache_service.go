@@ -0,0 +1,306 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// semanticCacheMaxEntriesPerGroup bounds the in-memory index of one group.
+	// The oldest entries are evicted first.
+	semanticCacheMaxEntriesPerGroup = 10000
+	// semanticCacheReloadInterval controls how often a group index is reloaded from
+	// the database so entries written by other nodes become visible.
+	semanticCacheReloadInterval = time.Minute
+)
+
+// semanticCacheVector is the in-memory part of a cached entry.
+type semanticCacheVector struct {
+	id         uint
+	paramsHash string
+	vector     []float32
+	expiresAt  time.Time
+}
+
+// semanticCacheIndex is a flat vector index for one group. mu guards entries
+// and loadedAt; reloadMu lets one request per group reload the index from the
+// database while others keep using the loaded entries.
+type semanticCacheIndex struct {
+	mu       sync.RWMutex
+	reloadMu sync.Mutex
+	entries  []semanticCacheVector
+	loadedAt time.Time
+}
+
+// SemanticCacheService keeps a flat in-memory vector index of cached chat
+// completions per group. Entries are persisted to the database and the index is
+// rebuilt from it lazily, so cached responses survive restarts. Each group has
+// its own lock, and database reads and writes happen outside of it.
+type SemanticCacheService struct {
+	db      *gorm.DB
+	mu      sync.Mutex // guards indexes and purgedAt
+	indexes map[uint]*semanticCacheIndex
+	// purgedAt is when expired rows of all groups were last deleted.
+	purgedAt time.Time
+	now      func() time.Time
+}
+
+// NewSemanticCacheService creates a new semantic cache service.
+func NewSemanticCacheService(db *gorm.DB) *SemanticCacheService {
+	return &SemanticCacheService{
+		db:      db,
+		indexes: make(map[uint]*semanticCacheIndex),
+		now:     time.Now,
+	}
+}
+
+// Lookup returns the most similar live entry with the same params hash whose
+// cosine similarity is at least threshold. It returns nil when nothing matches.
+func (s *SemanticCacheService) Lookup(groupID uint, paramsHash string, vector []float32, threshold float64) (*models.SemanticCacheEntry, float64, error) {
+	query := normalizeSemanticVector(vector)
+	if query == nil {
+		return nil, 0, nil
+	}
+
+	index, err := s.index(groupID)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := s.now()
+	var bestID uint
+	bestScore := -1.0
+	index.mu.RLock()
+	for _, entry := range index.entries {
+		if entry.paramsHash != paramsHash || !entry.expiresAt.After(now) || len(entry.vector) != len(query) {
+			continue
+		}
+		if score := dotProduct(query, entry.vector); score > bestScore {
+			bestScore = score
+			bestID = entry.id
+		}
+	}
+	index.mu.RUnlock()
+
+	if bestID == 0 || bestScore < threshold {
+		return nil, bestScore, nil
+	}
+	var entry models.SemanticCacheEntry
+	if err := s.db.Where("id = ? AND expires_at > ?", bestID, now).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, bestScore, nil
+		}
+		return nil, bestScore, err
+	}
+	return &entry, bestScore, nil
+}
+
+// Store persists a new entry and adds it to the group index.
+func (s *SemanticCacheService) Store(groupID uint, paramsHash string, vector []float32, response string, ttl time.Duration) error {
+	normalized := normalizeSemanticVector(vector)
+	if normalized == nil {
+		return errors.New("semantic cache: empty embedding")
+	}
+
+	now := s.now()
+	entry := &models.SemanticCacheEntry{
+		GroupID:    groupID,
+		ParamsHash: paramsHash,
+		Embedding:  encodeSemanticVector(normalized),
+		Response:   response,
+		ExpiresAt:  now.Add(ttl),
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		return err
+	}
+
+	index, err := s.index(groupID)
+	if err != nil {
+		return err
+	}
+	var evicted []uint
+	index.mu.Lock()
+	if !containsSemanticEntry(index.entries, entry.ID) {
+		index.entries = append(index.entries, semanticCacheVector{
+			id:         entry.ID,
+			paramsHash: paramsHash,
+			vector:     normalized,
+			expiresAt:  entry.ExpiresAt,
+		})
+	}
+	if overflow := len(index.entries) - semanticCacheMaxEntriesPerGroup; overflow > 0 {
+		evicted = make([]uint, 0, overflow)
+		for _, e := range index.entries[:overflow] {
+			evicted = append(evicted, e.id)
+		}
+		index.entries = append(index.entries[:0:0], index.entries[overflow:]...)
+	}
+	index.mu.Unlock()
+
+	if len(evicted) > 0 {
+		if err := s.db.Delete(&models.SemanticCacheEntry{}, evicted).Error; err != nil {
+			logrus.WithError(err).WithField("group_id", groupID).Warn("Failed to delete evicted semantic cache entries")
+		}
+	}
+	return nil
+}
+
+// index returns the group index, reloading it from the database when it is
+// missing or stale. While one request reloads a loaded index, other requests
+// of the group keep using the previous entries.
+func (s *SemanticCacheService) index(groupID uint) (*semanticCacheIndex, error) {
+	s.mu.Lock()
+	index, ok := s.indexes[groupID]
+	if !ok {
+		index = &semanticCacheIndex{}
+		s.indexes[groupID] = index
+	}
+	s.mu.Unlock()
+
+	index.mu.RLock()
+	loadedAt := index.loadedAt
+	index.mu.RUnlock()
+	if !loadedAt.IsZero() {
+		if s.now().Sub(loadedAt) < semanticCacheReloadInterval || !index.reloadMu.TryLock() {
+			return index, nil
+		}
+	} else {
+		index.reloadMu.Lock()
+	}
+	defer index.reloadMu.Unlock()
+
+	index.mu.RLock()
+	fresh := !index.loadedAt.IsZero() && s.now().Sub(index.loadedAt) < semanticCacheReloadInterval
+	index.mu.RUnlock()
+	if fresh {
+		return index, nil
+	}
+	if err := s.reload(groupID, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// reload replaces the entries of index with the live rows of the group.
+// Caller holds index.reloadMu.
+func (s *SemanticCacheService) reload(groupID uint, index *semanticCacheIndex) error {
+	now := s.now()
+	s.purgeExpired(now)
+
+	var rows []models.SemanticCacheEntry
+	err := s.db.Select("id", "params_hash", "embedding", "expires_at").
+		Where("group_id = ? AND expires_at > ?", groupID, now).
+		Order("id DESC").
+		Limit(semanticCacheMaxEntriesPerGroup).
+		Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	entries := make([]semanticCacheVector, 0, len(rows))
+	// Rows are loaded newest first to honor the limit; keep the index oldest first for eviction.
+	for i := len(rows) - 1; i >= 0; i-- {
+		vector := decodeSemanticVector(rows[i].Embedding)
+		if vector == nil {
+			continue
+		}
+		entries = append(entries, semanticCacheVector{
+			id:         rows[i].ID,
+			paramsHash: rows[i].ParamsHash,
+			vector:     vector,
+			expiresAt:  rows[i].ExpiresAt,
+		})
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	// Keep entries stored by this node while the rows were read.
+	var newestID uint
+	if len(entries) > 0 {
+		newestID = entries[len(entries)-1].id
+	}
+	for _, entry := range index.entries {
+		if entry.id > newestID && !containsSemanticEntry(entries, entry.id) {
+			entries = append(entries, entry)
+		}
+	}
+	index.entries = entries
+	index.loadedAt = now
+	return nil
+}
+
+// purgeExpired deletes expired rows of every group, including groups that no
+// longer exist, at most once per reload interval.
+func (s *SemanticCacheService) purgeExpired(now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.purgedAt) >= semanticCacheReloadInterval
+	if due {
+		s.purgedAt = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := s.db.Where("expires_at <= ?", now).Delete(&models.SemanticCacheEntry{}).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to delete expired semantic cache entries")
+	}
+}
+
+func containsSemanticEntry(entries []semanticCacheVector, id uint) bool {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeSemanticVector(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 || math.IsNaN(sum) || math.IsInf(sum, 0) {
+		return nil
+	}
+	norm := math.Sqrt(sum)
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+func dotProduct(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func encodeSemanticVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeSemanticVector(buf []byte) []float32 {
+	if len(buf) == 0 || len(buf)%4 != 0 {
+		return nil
+	}
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vector
+}
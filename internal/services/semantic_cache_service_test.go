@@ -0,0 +1,104 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemanticCacheServiceLookup(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.SemanticCacheEntry{})
+	svc := NewSemanticCacheService(db)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	require.NoError(t, svc.Store(1, "params-a", []float32{1, 0, 0}, `{"id":"x"}`, time.Hour))
+	require.NoError(t, svc.Store(1, "params-a", []float32{0, 1, 0}, `{"id":"y"}`, time.Hour))
+
+	entry, score, err := svc.Lookup(1, "params-a", []float32{0.99, 0.05, 0}, 0.95)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, `{"id":"x"}`, entry.Response)
+	assert.Greater(t, score, 0.95)
+
+	entry, _, err = svc.Lookup(1, "params-a", []float32{1, 1, 0}, 0.95)
+	require.NoError(t, err)
+	assert.Nil(t, entry, "below threshold")
+
+	entry, _, err = svc.Lookup(1, "params-b", []float32{1, 0, 0}, 0.95)
+	require.NoError(t, err)
+	assert.Nil(t, entry, "different request params")
+
+	entry, _, err = svc.Lookup(2, "params-a", []float32{1, 0, 0}, 0.95)
+	require.NoError(t, err)
+	assert.Nil(t, entry, "different group")
+
+	now = now.Add(2 * time.Hour)
+	entry, _, err = svc.Lookup(1, "params-a", []float32{1, 0, 0}, 0.95)
+	require.NoError(t, err)
+	assert.Nil(t, entry, "expired")
+	var count int64
+	require.NoError(t, db.Model(&models.SemanticCacheEntry{}).Count(&count).Error)
+	assert.Zero(t, count, "expired rows are removed on reload")
+}
+
+func TestSemanticCacheServiceReloadsFromDatabase(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.SemanticCacheEntry{})
+	require.NoError(t, NewSemanticCacheService(db).Store(3, "p", []float32{0.2, 0.4, 0.1}, `{"id":"persisted"}`, time.Hour))
+
+	entry, _, err := NewSemanticCacheService(db).Lookup(3, "p", []float32{0.2, 0.4, 0.1}, 0.99)
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, `{"id":"persisted"}`, entry.Response)
+}
+
+func TestSemanticCacheServicePurgesExpiredRowsOfEveryGroup(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.SemanticCacheEntry{})
+	svc := NewSemanticCacheService(db)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	// Group 9 stands in for a deleted group that is never looked up again.
+	require.NoError(t, svc.Store(9, "p", []float32{1, 0}, `{"id":"orphan"}`, time.Minute))
+	require.NoError(t, svc.Store(1, "p", []float32{1, 0}, `{"id":"live"}`, 3*time.Hour))
+
+	now = now.Add(2 * time.Hour)
+	_, _, err := svc.Lookup(1, "p", []float32{1, 0}, 0.9)
+	require.NoError(t, err)
+
+	var groups []uint
+	require.NoError(t, db.Model(&models.SemanticCacheEntry{}).Pluck("group_id", &groups).Error)
+	assert.Equal(t, []uint{1}, groups)
+}
+
+func TestSemanticCacheServiceConcurrentGroups(t *testing.T) {
+	t.Parallel()
+
+	db := setupRequestLogServiceTestDB(t, &models.SemanticCacheEntry{})
+	svc := NewSemanticCacheService(db)
+
+	var wg sync.WaitGroup
+	for group := range uint(4) {
+		wg.Go(func() {
+			for i := range 5 {
+				vector := []float32{float32(group + 1), float32(i + 1)}
+				assert.NoError(t, svc.Store(group, "p", vector, `{}`, time.Hour))
+				entry, _, err := svc.Lookup(group, "p", vector, 0.99)
+				assert.NoError(t, err)
+				assert.NotNil(t, entry)
+			}
+		})
+	}
+	wg.Wait()
+}
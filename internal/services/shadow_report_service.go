@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ShadowModelComparison compares live and shadow requests for one client model.
+type ShadowModelComparison struct {
+	Model   string          `json:"model"`
+	Primary RequestLogStats `json:"primary"`
+	Shadow  RequestLogStats `json:"shadow"`
+}
+
+// ShadowReport compares the live requests of a group with the requests
+// mirrored to its shadow group over a time range.
+type ShadowReport struct {
+	GroupID     uint                    `json:"group_id"`
+	GroupName   string                  `json:"group_name"`
+	ShadowGroup string                  `json:"shadow_group"`
+	Start       time.Time               `json:"start"`
+	End         time.Time               `json:"end"`
+	Primary     RequestLogStats         `json:"primary"`
+	Shadow      RequestLogStats         `json:"shadow"`
+	Models      []ShadowModelComparison `json:"models"`
+}
+
+// ShadowReportService builds shadow traffic reports from request logs.
+type ShadowReportService struct {
+	db     *gorm.DB
+	readDB *gorm.DB
+}
+
+// NewShadowReportService creates a ShadowReportService.
+func NewShadowReportService(db *gorm.DB, readDB ReadOnlyDB) *ShadowReportService {
+	reader := readDB.DB
+	if reader == nil {
+		reader = db
+	}
+	return &ShadowReportService{db: db, readDB: reader}
+}
+
+// shadowReportModelColumn is the model requested by the client. Logs keep the
+// client model in mapped_model when the upstream model differs.
+const shadowReportModelColumn = "COALESCE(NULLIF(mapped_model, ''), model)"
+
+// Report compares the final requests of a group, including those served by
+// its sub-groups, with the shadow requests mirrored from it in [start, end).
+func (s *ShadowReportService) Report(ctx context.Context, groupID uint, start, end time.Time) (*ShadowReport, error) {
+	group, err := FindGroupByID(ctx, s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ShadowReport{
+		GroupID:   group.ID,
+		GroupName: group.Name,
+		Start:     start,
+		End:       end,
+		Models:    []ShadowModelComparison{},
+	}
+	if name, ok := group.Config["shadow_group"].(string); ok {
+		report.ShadowGroup = strings.TrimSpace(name)
+	}
+
+	primary := s.readDB.WithContext(ctx).Model(&models.RequestLog{}).
+		Where("timestamp >= ? AND timestamp < ?", start, end).
+		Where("request_type = ?", models.RequestTypeFinal).
+		Where("group_id = ? OR parent_group_id = ?", groupID, groupID)
+	primaryRows, err := scanShadowReportRows(primary)
+	if err != nil {
+		return nil, err
+	}
+	shadow := s.readDB.WithContext(ctx).Model(&models.RequestLog{}).
+		Where("timestamp >= ? AND timestamp < ?", start, end).
+		Where("request_type = ? AND parent_group_id = ?", models.RequestTypeShadow, groupID)
+	shadowRows, err := scanShadowReportRows(shadow)
+	if err != nil {
+		return nil, err
+	}
+
+	byModel := make(map[string]*ShadowModelComparison)
+	comparison := func(model string) *ShadowModelComparison {
+		entry, ok := byModel[model]
+		if !ok {
+			entry = &ShadowModelComparison{Model: model}
+			byModel[model] = entry
+		}
+		return entry
+	}
+	var primaryTotal, shadowTotal requestLogStatsRow
+	for _, row := range primaryRows {
+		comparison(row.StatsKey).Primary = row.stats()
+		primaryTotal = primaryTotal.add(row)
+	}
+	for _, row := range shadowRows {
+		comparison(row.StatsKey).Shadow = row.stats()
+		shadowTotal = shadowTotal.add(row)
+	}
+	report.Primary = primaryTotal.stats()
+	report.Shadow = shadowTotal.stats()
+
+	for _, entry := range byModel {
+		report.Models = append(report.Models, *entry)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		if report.Models[i].Shadow.Requests != report.Models[j].Shadow.Requests {
+			return report.Models[i].Shadow.Requests > report.Models[j].Shadow.Requests
+		}
+		return report.Models[i].Model < report.Models[j].Model
+	})
+	return report, nil
+}
+
+func scanShadowReportRows(query *gorm.DB) ([]requestLogStatsRow, error) {
+	var rows []requestLogStatsRow
+	err := query.Select(shadowReportModelColumn + " AS stats_key, " + requestLogStatsColumns).
+		Group(shadowReportModelColumn).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return rows, nil
+}
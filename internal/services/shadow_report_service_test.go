@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadowReportService_Report(t *testing.T) {
+	t.Parallel()
+	db := setupTestDB(t)
+	group := &models.Group{
+		Name:        "live",
+		GroupType:   "standard",
+		ChannelType: "openai",
+		Upstreams:   []byte(`[{"url":"https://api.openai.com","weight":1}]`),
+		Config:      map[string]any{"shadow_group": "candidate"},
+	}
+	require.NoError(t, db.Create(group).Error)
+
+	now := time.Now()
+	logs := []models.RequestLog{
+		{GroupID: group.ID, Model: "gpt-4.1", MappedModel: "gpt-4", IsSuccess: true, Duration: 100, TotalTokens: 10, RequestType: models.RequestTypeFinal},
+		{GroupID: group.ID, Model: "gpt-4", IsSuccess: false, Duration: 300, RequestType: models.RequestTypeFinal},
+		{GroupID: group.ID, Model: "gpt-4", Duration: 900, RequestType: models.RequestTypeRetry},
+		{GroupID: 99, ParentGroupID: group.ID, Model: "gpt-4", IsSuccess: true, Duration: 50, TotalTokens: 20, RequestType: models.RequestTypeShadow},
+		{GroupID: 99, ParentGroupID: group.ID, Model: "claude", MappedModel: "gpt-4", IsSuccess: true, Duration: 150, TotalTokens: 40, RequestType: models.RequestTypeShadow},
+		{GroupID: 99, ParentGroupID: group.ID + 1, Model: "gpt-4", IsSuccess: true, RequestType: models.RequestTypeShadow},
+	}
+	for _, entry := range logs {
+		entry.ID = uuid.NewString()
+		entry.Timestamp = now
+		require.NoError(t, db.Create(&entry).Error)
+	}
+
+	reports := NewShadowReportService(db, ReadOnlyDB{DB: db})
+	report, err := reports.Report(context.Background(), group.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, "candidate", report.ShadowGroup)
+	assert.Equal(t, int64(2), report.Primary.Requests)
+	assert.Equal(t, 0.5, report.Primary.SuccessRate)
+	assert.Equal(t, float64(200), report.Primary.AvgDurationMs)
+	assert.Equal(t, int64(2), report.Shadow.Requests)
+	assert.Equal(t, 1.0, report.Shadow.SuccessRate)
+	assert.Equal(t, float64(30), report.Shadow.AvgTokens)
+
+	require.Len(t, report.Models, 1, "models are compared by the model requested by the client")
+	assert.Equal(t, "gpt-4", report.Models[0].Model)
+	assert.Equal(t, int64(2), report.Models[0].Primary.Requests)
+	assert.Equal(t, int64(2), report.Models[0].Shadow.Requests)
+
+	empty, err := reports.Report(context.Background(), group.ID, now.Add(time.Hour), now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, empty.Shadow.Requests)
+	assert.Empty(t, empty.Models)
+
+	_, err = reports.Report(context.Background(), group.ID+100, now.Add(-time.Hour), now)
+	assert.Error(t, err)
+}
@@ -137,23 +137,34 @@ func (s *AutoCheckinService) Start() {
 		return
 	}
 
+	// Restarting after regaining leadership needs new channels. Each goroutine
+	// is handed the channels it was started with, so goroutines left over from
+	// a timed-out Stop never read the new ones.
+	select {
+	case <-s.stopCh:
+		s.stopCh = make(chan struct{})
+		s.cleanupCh = make(chan struct{})
+		s.stopOnce = sync.Once{}
+	default:
+	}
+
 	s.wg.Add(1)
-	go s.runLoop()
+	go s.runLoop(s.stopCh)
 
 	// Start periodic cleanup goroutine for aggressive memory release
 	s.wg.Add(1)
-	go s.periodicCleanup()
+	go s.periodicCleanup(s.cleanupCh)
 
 	// Best-effort subscriptions for multi-node setups.
 	if sub, err := s.store.Subscribe(siteScheduleConfigUpdatedChannel); err == nil {
 		s.subConfig = sub
 		s.wg.Add(1)
-		go s.listenSubscription(sub, s.rescheduleCh)
+		go s.listenSubscription(s.stopCh, sub, s.rescheduleCh)
 	}
 	if sub, err := s.store.Subscribe(autoCheckinRunNowChannel); err == nil {
 		s.subRunNow = sub
 		s.wg.Add(1)
-		go s.listenSubscription(sub, s.runNowCh)
+		go s.listenSubscription(s.stopCh, sub, s.runNowCh)
 	}
 
 	// Initial schedule.
@@ -238,11 +249,11 @@ func (s *AutoCheckinService) RequestReschedule() {
 	}
 }
 
-func (s *AutoCheckinService) listenSubscription(sub store.Subscription, out chan<- struct{}) {
+func (s *AutoCheckinService) listenSubscription(stopCh <-chan struct{}, sub store.Subscription, out chan<- struct{}) {
 	defer s.wg.Done()
 	for {
 		select {
-		case <-s.stopCh:
+		case <-stopCh:
 			return
 		case _, ok := <-sub.Channel():
 			if !ok {
@@ -256,7 +267,7 @@ func (s *AutoCheckinService) listenSubscription(sub store.Subscription, out chan
 	}
 }
 
-func (s *AutoCheckinService) runLoop() {
+func (s *AutoCheckinService) runLoop(stopCh <-chan struct{}) {
 	defer s.wg.Done()
 
 	for {
@@ -277,7 +288,7 @@ func (s *AutoCheckinService) runLoop() {
 		s.resetTimer(time.Until(next))
 
 		select {
-		case <-s.stopCh:
+		case <-stopCh:
 			s.stopTimer()
 			return
 		case <-s.rescheduleCh:
@@ -1124,14 +1135,14 @@ func (s *AutoCheckinService) closeIdleConnections() {
 
 // periodicCleanup runs periodic cleanup of idle connections for aggressive memory release.
 // Site management is a non-critical feature, so we can be more aggressive with resource cleanup.
-func (s *AutoCheckinService) periodicCleanup() {
+func (s *AutoCheckinService) periodicCleanup(cleanupCh <-chan struct{}) {
 	defer s.wg.Done()
 	ticker := time.NewTicker(5 * time.Minute) // Cleanup every 5 minutes
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-s.cleanupCh:
+		case <-cleanupCh:
 			return
 		case <-ticker.C:
 			s.closeIdleConnections()
@@ -150,18 +150,30 @@ func (s *BalanceService) invalidateBalanceConsumers() {
 
 // Start begins the background balance refresh scheduler
 func (s *BalanceService) Start() {
+	// Restarting after regaining leadership needs new channels and a new
+	// lifecycle context. Goroutines are handed the ones they were started with,
+	// so goroutines left over from a timed-out Stop never read the new ones.
+	select {
+	case <-s.stopCh:
+		s.stopCh = make(chan struct{})
+		s.cleanupCh = make(chan struct{})
+		s.lifecycleCtx, s.cancelLifecycle = context.WithCancel(context.Background())
+		s.stopOnce = sync.Once{}
+	default:
+	}
+
 	s.wg.Add(1)
-	go s.runScheduler()
+	go s.runScheduler(s.lifecycleCtx, s.stopCh)
 
 	// Start periodic cleanup goroutine for aggressive memory release
 	s.wg.Add(1)
-	go s.periodicCleanup()
+	go s.periodicCleanup(s.cleanupCh)
 
 	if s.store != nil {
 		if sub, err := s.store.Subscribe(siteScheduleConfigUpdatedChannel); err == nil {
 			s.subConfig = sub
 			s.wg.Add(1)
-			go s.listenScheduleConfigUpdates(sub)
+			go s.listenScheduleConfigUpdates(s.stopCh, sub)
 		}
 	}
 	// Reconcile any configuration change that raced with scheduler startup.
@@ -230,11 +242,11 @@ func (s *BalanceService) RequestReschedule() {
 	}
 }
 
-func (s *BalanceService) listenScheduleConfigUpdates(sub store.Subscription) {
+func (s *BalanceService) listenScheduleConfigUpdates(stopCh <-chan struct{}, sub store.Subscription) {
 	defer s.wg.Done()
 	for {
 		select {
-		case <-s.stopCh:
+		case <-stopCh:
 			return
 		case _, ok := <-sub.Channel():
 			if !ok {
@@ -246,11 +258,11 @@ func (s *BalanceService) listenScheduleConfigUpdates(sub store.Subscription) {
 }
 
 // runScheduler runs balance refreshes at configured local-time slots.
-func (s *BalanceService) runScheduler() {
+func (s *BalanceService) runScheduler(ctx context.Context, stopCh <-chan struct{}) {
 	defer s.wg.Done()
 
 	for {
-		nextRefresh, enabled, err := s.nextRefreshTime(s.lifecycleCtx)
+		nextRefresh, enabled, err := s.nextRefreshTime(ctx)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				return
@@ -271,7 +283,7 @@ func (s *BalanceService) runScheduler() {
 		timer := time.NewTimer(waitDuration)
 
 		select {
-		case <-s.stopCh:
+		case <-stopCh:
 			timer.Stop()
 			return
 		case <-s.rescheduleCh:
@@ -284,7 +296,7 @@ func (s *BalanceService) runScheduler() {
 			continue
 		case <-timer.C:
 			if enabled {
-				s.refreshAllBalancesBackground(s.lifecycleCtx)
+				s.refreshAllBalancesBackground(ctx)
 			}
 		}
 	}
@@ -983,14 +995,14 @@ func (s *BalanceService) closeIdleConnections() {
 
 // periodicCleanup runs periodic cleanup of idle connections for aggressive memory release.
 // Site management is a non-critical feature, so we can be more aggressive with resource cleanup.
-func (s *BalanceService) periodicCleanup() {
+func (s *BalanceService) periodicCleanup(cleanupCh <-chan struct{}) {
 	defer s.wg.Done()
 	ticker := time.NewTicker(5 * time.Minute) // Cleanup every 5 minutes
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-s.cleanupCh:
+		case <-cleanupCh:
 			return
 		case <-ticker.C:
 			s.closeIdleConnections()
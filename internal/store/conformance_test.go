@@ -0,0 +1,332 @@
+package store
+
+import (
+	"context"
+	"math"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemoryStoreConformance runs the shared Store conformance suite against MemoryStore.
+func TestMemoryStoreConformance(t *testing.T) {
+	runStoreConformance(t, func(t *testing.T) Store {
+		s := NewMemoryStore()
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	})
+}
+
+// TestRedisStoreConformance runs the shared Store conformance suite against an
+// in-process miniredis server, which executes the same Lua scripts as Redis.
+func TestRedisStoreConformance(t *testing.T) {
+	runStoreConformance(t, func(t *testing.T) Store {
+		server := miniredis.RunT(t)
+		followWallClock(t, server)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		return NewRedisStore(client)
+	})
+}
+
+// followWallClock moves the miniredis clock forward with real time, since
+// miniredis only expires keys when its clock is advanced.
+func followWallClock(t *testing.T, server *miniredis.Miniredis) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		last := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				server.FastForward(now.Sub(last))
+				last = now
+			}
+		}
+	}()
+}
+
+// TestRealRedisStoreConformance runs the suite against a real Redis server.
+// Set TEST_REDIS_DSN (e.g. redis://localhost:6379/15) to run it.
+func TestRealRedisStoreConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_REDIS_DSN")
+	if dsn == "" {
+		t.Skip("TEST_REDIS_DSN not set")
+	}
+	opts, err := redis.ParseURL(dsn)
+	require.NoError(t, err)
+
+	runStoreConformance(t, func(t *testing.T) Store {
+		client := redis.NewClient(opts)
+		require.NoError(t, client.Ping(context.Background()).Err())
+		t.Cleanup(func() { _ = client.Close() })
+		return NewRedisStore(client)
+	})
+}
+
+// runStoreConformance checks the semantics every Store implementation must share.
+// Keys are scoped to the running test so the suite can share a Redis database.
+func runStoreConformance(t *testing.T, newStore func(t *testing.T) Store) {
+	key := func(t *testing.T, s Store, name string) string {
+		k := "conformance:" + t.Name() + ":" + name
+		require.NoError(t, s.Del(k))
+		t.Cleanup(func() { _ = s.Del(k) })
+		return k
+	}
+
+	t.Run("IncrByWithTTL", func(t *testing.T) {
+		s := newStore(t)
+		counter := key(t, s, "counter")
+
+		v, err := s.IncrByWithTTL(counter, 2, 300*time.Millisecond)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, v)
+		v, err = s.IncrByWithTTL(counter, -1, time.Hour)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, v)
+
+		raw, err := s.Get(counter)
+		require.NoError(t, err)
+		assert.Equal(t, "1", string(raw))
+
+		// The window starts at the first increment and is not extended.
+		require.Eventually(t, func() bool {
+			exists, err := s.Exists(counter)
+			return err == nil && !exists
+		}, 2*time.Second, 20*time.Millisecond)
+
+		v, err = s.IncrByWithTTL(counter, 5, 0)
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, v, "an expired counter starts over")
+	})
+
+	t.Run("IncrByWithTTL adds a TTL to counters without one", func(t *testing.T) {
+		s := newStore(t)
+		counter := key(t, s, "counter")
+
+		require.NoError(t, s.Set(counter, []byte("41"), 0))
+		v, err := s.IncrByWithTTL(counter, 1, 200*time.Millisecond)
+		require.NoError(t, err)
+		assert.EqualValues(t, 42, v)
+		require.Eventually(t, func() bool {
+			exists, err := s.Exists(counter)
+			return err == nil && !exists
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("IncrByWithTTL rejects non-integer values", func(t *testing.T) {
+		s := newStore(t)
+		counter := key(t, s, "counter")
+
+		require.NoError(t, s.Set(counter, []byte("abc"), 0))
+		_, err := s.IncrByWithTTL(counter, 1, time.Minute)
+		assert.Error(t, err)
+	})
+
+	t.Run("IncrByWithTTL is atomic", func(t *testing.T) {
+		s := newStore(t)
+		counter := key(t, s, "counter")
+
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.IncrByWithTTL(counter, 1, time.Minute)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		raw, err := s.Get(counter)
+		require.NoError(t, err)
+		assert.Equal(t, "50", string(raw))
+	})
+
+	t.Run("CompareAndSwap", func(t *testing.T) {
+		s := newStore(t)
+		k := key(t, s, "value")
+
+		swapped, err := s.CompareAndSwap(k, nil, []byte("v1"), 0)
+		require.NoError(t, err)
+		assert.True(t, swapped, "nil old value creates a missing key")
+
+		swapped, err = s.CompareAndSwap(k, nil, []byte("v2"), 0)
+		require.NoError(t, err)
+		assert.False(t, swapped, "nil old value does not overwrite")
+
+		swapped, err = s.CompareAndSwap(k, []byte("other"), []byte("v2"), 0)
+		require.NoError(t, err)
+		assert.False(t, swapped)
+
+		swapped, err = s.CompareAndSwap(k, []byte("v1"), []byte("v2"), 200*time.Millisecond)
+		require.NoError(t, err)
+		assert.True(t, swapped)
+		raw, err := s.Get(k)
+		require.NoError(t, err)
+		assert.Equal(t, "v2", string(raw))
+
+		require.Eventually(t, func() bool {
+			exists, err := s.Exists(k)
+			return err == nil && !exists
+		}, 2*time.Second, 20*time.Millisecond)
+
+		swapped, err = s.CompareAndSwap(k, []byte("v2"), []byte("v3"), 0)
+		require.NoError(t, err)
+		assert.False(t, swapped, "an expired key does not match")
+	})
+
+	t.Run("CompareAndDelete", func(t *testing.T) {
+		s := newStore(t)
+		k := key(t, s, "owned")
+		require.NoError(t, s.Set(k, []byte("owner"), 0))
+
+		deleted, err := s.CompareAndDelete(k, []byte("other"))
+		require.NoError(t, err)
+		assert.False(t, deleted)
+		exists, err := s.Exists(k)
+		require.NoError(t, err)
+		assert.True(t, exists, "a different value keeps the key")
+
+		deleted, err = s.CompareAndDelete(k, []byte("owner"))
+		require.NoError(t, err)
+		assert.True(t, deleted)
+		exists, err = s.Exists(k)
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		deleted, err = s.CompareAndDelete(k, []byte("owner"))
+		require.NoError(t, err)
+		assert.False(t, deleted, "a missing key is not deleted")
+	})
+
+	t.Run("CompareAndSwap lets one writer win", func(t *testing.T) {
+		s := newStore(t)
+		k := key(t, s, "lock")
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		wins := 0
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				swapped, err := s.CompareAndSwap(k, nil, []byte("owner"), time.Minute)
+				assert.NoError(t, err)
+				if swapped {
+					mu.Lock()
+					wins++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, wins)
+	})
+
+	t.Run("sorted sets", func(t *testing.T) {
+		s := newStore(t)
+		zset := key(t, s, "zset")
+
+		members, err := s.ZRangeByScore(zset, math.Inf(-1), math.Inf(1))
+		require.NoError(t, err)
+		assert.Empty(t, members)
+
+		require.NoError(t, s.ZAdd(zset,
+			ZMember{Score: 3, Member: "c"},
+			ZMember{Score: 1, Member: "b"},
+			ZMember{Score: 1, Member: "a"},
+			ZMember{Score: 2.5, Member: "d"},
+		))
+		require.NoError(t, s.ZAdd(zset, ZMember{Score: 10, Member: "c"}))
+
+		members, err = s.ZRangeByScore(zset, math.Inf(-1), math.Inf(1))
+		require.NoError(t, err)
+		assert.Equal(t, []ZMember{
+			{Score: 1, Member: "a"},
+			{Score: 1, Member: "b"},
+			{Score: 2.5, Member: "d"},
+			{Score: 10, Member: "c"},
+		}, members)
+
+		members, err = s.ZRangeByScore(zset, 1, 2.5)
+		require.NoError(t, err)
+		assert.Len(t, members, 3, "bounds are inclusive")
+
+		removed, err := s.ZRemRangeByScore(zset, math.Inf(-1), 2.5)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, removed)
+		members, err = s.ZRangeByScore(zset, math.Inf(-1), math.Inf(1))
+		require.NoError(t, err)
+		assert.Equal(t, []ZMember{{Score: 10, Member: "c"}}, members)
+
+		removed, err = s.ZRemRangeByScore(zset, 10, 10)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, removed)
+		exists, err := s.Exists(zset)
+		require.NoError(t, err)
+		assert.False(t, exists, "an emptied sorted set is removed")
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		s := newStore(t)
+		zset := key(t, s, "zset")
+		set := key(t, s, "set")
+		value := key(t, s, "value")
+		kept := key(t, s, "kept")
+
+		require.NoError(t, s.Expire(key(t, s, "missing"), time.Hour), "missing keys are ignored")
+		require.NoError(t, s.ZAdd(zset, ZMember{Score: 1, Member: "a"}))
+		require.NoError(t, s.SAdd(set, "a"))
+		require.NoError(t, s.Set(value, []byte("v"), 0))
+		require.NoError(t, s.SAdd(kept, "a"))
+		for _, k := range []string{zset, set, value, kept} {
+			require.NoError(t, s.Expire(k, 200*time.Millisecond))
+		}
+		require.NoError(t, s.Expire(kept, 0), "a zero TTL removes the expiry")
+
+		require.Eventually(t, func() bool {
+			for _, k := range []string{zset, set, value} {
+				if exists, err := s.Exists(k); err != nil || exists {
+					return false
+				}
+			}
+			return true
+		}, 2*time.Second, 20*time.Millisecond)
+		members, err := s.ZRangeByScore(zset, math.Inf(-1), math.Inf(1))
+		require.NoError(t, err)
+		assert.Empty(t, members)
+		count, err := s.SCard(set)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		count, err = s.SCard(kept)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+
+		require.NoError(t, s.ZAdd(zset, ZMember{Score: 2, Member: "b"}))
+		time.Sleep(300 * time.Millisecond)
+		members, err = s.ZRangeByScore(zset, math.Inf(-1), math.Inf(1))
+		require.NoError(t, err)
+		assert.Len(t, members, 1, "a recreated key starts without a TTL")
+	})
+
+	t.Run("sorted sets reject other types", func(t *testing.T) {
+		s := newStore(t)
+		k := key(t, s, "string")
+
+		require.NoError(t, s.Set(k, []byte("value"), 0))
+		assert.Error(t, s.ZAdd(k, ZMember{Score: 1, Member: "a"}))
+		_, err := s.ZRangeByScore(k, 0, 1)
+		assert.Error(t, err)
+	})
+}
@@ -1,8 +1,13 @@
 package store
 
 import (
+	"bytes"
+	"cmp"
 	"fmt"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,6 +25,7 @@ type memoryStoreItem struct {
 type MemoryStore struct {
 	mu              sync.RWMutex
 	data            map[string]any
+	expires         map[string]int64 // Unix-nano deadlines set by Expire on hash, list, set and sorted set keys.
 	muSubscribers   sync.RWMutex
 	subscribers     map[string]map[chan *Message]struct{}
 	droppedMessages atomic.Int64
@@ -35,6 +41,7 @@ type MemoryStore struct {
 func NewMemoryStore() *MemoryStore {
 	s := &MemoryStore{
 		data:        make(map[string]any),
+		expires:     make(map[string]int64),
 		subscribers: make(map[string]map[chan *Message]struct{}),
 		stopCleanup: make(chan struct{}),
 	}
@@ -80,6 +87,7 @@ func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
 		value:     value,
 		expiresAt: expiresAt,
 	}
+	delete(s.expires, key)
 	return nil
 }
 
@@ -113,6 +121,7 @@ func (s *MemoryStore) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.data, key)
+	delete(s.expires, key)
 	return nil
 }
 
@@ -122,6 +131,7 @@ func (s *MemoryStore) Del(keys ...string) error {
 	defer s.mu.Unlock()
 	for _, key := range keys {
 		delete(s.data, key)
+		delete(s.expires, key)
 	}
 	return nil
 }
@@ -130,9 +140,10 @@ func (s *MemoryStore) Del(keys ...string) error {
 func (s *MemoryStore) Exists(key string) (bool, error) {
 	s.mu.RLock()
 	rawItem, exists := s.data[key]
+	expired := s.expiredLocked(key)
 	s.mu.RUnlock()
 
-	if !exists {
+	if !exists || expired {
 		return false, nil
 	}
 
@@ -177,11 +188,146 @@ func (s *MemoryStore) SetNX(key string, value []byte, ttl time.Duration) (bool,
 	return true, nil
 }
 
+// CompareAndSwap replaces the value of key if it currently equals oldValue.
+func (s *MemoryStore) CompareAndSwap(key string, oldValue, newValue []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists, err := s.liveItemLocked(key)
+	if err != nil {
+		return false, err
+	}
+	if oldValue == nil {
+		if exists {
+			return false, nil
+		}
+	} else if !exists || !bytes.Equal(item.value, oldValue) {
+		return false, nil
+	}
+
+	s.data[key] = memoryStoreItem{
+		value:     newValue,
+		expiresAt: memoryExpiresAt(ttl),
+	}
+	return true, nil
+}
+
+// CompareAndDelete deletes key if its value currently equals value.
+func (s *MemoryStore) CompareAndDelete(key string, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists, err := s.liveItemLocked(key)
+	if err != nil {
+		return false, err
+	}
+	if !exists || !bytes.Equal(item.value, value) {
+		return false, nil
+	}
+	delete(s.data, key)
+	return true, nil
+}
+
+// IncrByWithTTL increments the counter at key, setting ttl when the counter has no expiry.
+func (s *MemoryStore) IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, exists, err := s.liveItemLocked(key)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	if exists {
+		current, err = strconv.ParseInt(string(item.value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value at key '%s' is not an integer", key)
+		}
+	}
+
+	current += incr
+	item.value = []byte(strconv.FormatInt(current, 10))
+	if item.expiresAt == 0 {
+		item.expiresAt = memoryExpiresAt(ttl)
+	}
+	s.data[key] = item
+	return current, nil
+}
+
+// liveItemLocked returns the unexpired K/V item at key, removing it when expired.
+// Caller must hold the write lock.
+func (s *MemoryStore) liveItemLocked(key string) (memoryStoreItem, bool, error) {
+	rawItem, exists := s.data[key]
+	if !exists {
+		return memoryStoreItem{}, false, nil
+	}
+	item, ok := rawItem.(memoryStoreItem)
+	if !ok {
+		return memoryStoreItem{}, false, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+	if item.expiresAt > 0 && time.Now().UnixNano() > item.expiresAt {
+		delete(s.data, key)
+		return memoryStoreItem{}, false, nil
+	}
+	return item, true, nil
+}
+
+func memoryExpiresAt(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().UnixNano() + ttl.Nanoseconds()
+}
+
+// Expire sets the TTL of an existing key of any type.
+func (s *MemoryStore) Expire(key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropExpiredLocked(key)
+	rawItem, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+	if item, ok := rawItem.(memoryStoreItem); ok {
+		if item.expiresAt > 0 && time.Now().UnixNano() > item.expiresAt {
+			delete(s.data, key)
+			return nil
+		}
+		item.expiresAt = memoryExpiresAt(ttl)
+		s.data[key] = item
+		return nil
+	}
+	if ttl <= 0 {
+		delete(s.expires, key)
+	} else {
+		s.expires[key] = memoryExpiresAt(ttl)
+	}
+	return nil
+}
+
+// expiredLocked reports whether the Expire deadline of a collection key has
+// passed. Caller holds at least the read lock.
+func (s *MemoryStore) expiredLocked(key string) bool {
+	expiresAt, ok := s.expires[key]
+	return ok && time.Now().UnixNano() > expiresAt
+}
+
+// dropExpiredLocked removes a collection key whose Expire deadline has passed.
+// Caller must hold the write lock.
+func (s *MemoryStore) dropExpiredLocked(key string) {
+	if s.expiredLocked(key) {
+		delete(s.data, key)
+		delete(s.expires, key)
+	}
+}
+
 // --- HASH operations ---
 
 func (s *MemoryStore) HSet(key string, values map[string]any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	var hash map[string]string
 	rawHash, exists := s.data[key]
@@ -207,7 +353,7 @@ func (s *MemoryStore) HGetAll(key string) (map[string]string, error) {
 	defer s.mu.RUnlock()
 
 	rawHash, exists := s.data[key]
-	if !exists {
+	if !exists || s.expiredLocked(key) {
 		return make(map[string]string), nil
 	}
 
@@ -227,6 +373,7 @@ func (s *MemoryStore) HGetAll(key string) (map[string]string, error) {
 func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	var hash map[string]string
 	rawHash, exists := s.data[key]
@@ -253,6 +400,7 @@ func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 func (s *MemoryStore) LPush(key string, values ...any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	var list []string
 	rawList, exists := s.data[key]
@@ -278,6 +426,7 @@ func (s *MemoryStore) LPush(key string, values ...any) error {
 func (s *MemoryStore) LRem(key string, count int64, value any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	rawList, exists := s.data[key]
 	if !exists {
@@ -308,6 +457,7 @@ func (s *MemoryStore) LRem(key string, count int64, value any) error {
 func (s *MemoryStore) Rotate(key string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	rawList, exists := s.data[key]
 	if !exists {
@@ -340,7 +490,7 @@ func (s *MemoryStore) LLen(key string) (int64, error) {
 	defer s.mu.RUnlock()
 
 	rawItem, exists := s.data[key]
-	if !exists {
+	if !exists || s.expiredLocked(key) {
 		return 0, nil
 	}
 
@@ -359,7 +509,7 @@ func (s *MemoryStore) SCard(key string) (int64, error) {
 	defer s.mu.RUnlock()
 
 	rawItem, exists := s.data[key]
-	if !exists {
+	if !exists || s.expiredLocked(key) {
 		return 0, nil
 	}
 
@@ -377,6 +527,7 @@ func (s *MemoryStore) SCard(key string) (int64, error) {
 func (s *MemoryStore) SAdd(key string, members ...any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	var set map[string]struct{}
 	rawSet, exists := s.data[key]
@@ -401,6 +552,7 @@ func (s *MemoryStore) SAdd(key string, members ...any) error {
 func (s *MemoryStore) SPopN(key string, count int64) ([]string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
 
 	rawSet, exists := s.data[key]
 	if !exists {
@@ -428,6 +580,98 @@ func (s *MemoryStore) SPopN(key string, count int64) ([]string, error) {
 	return popped, nil
 }
 
+// --- SORTED SET operations ---
+
+// memoryZSet maps sorted set members to their scores.
+type memoryZSet map[string]float64
+
+// ZAdd adds members to a sorted set or updates their scores.
+func (s *MemoryStore) ZAdd(key string, members ...ZMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
+
+	var zset memoryZSet
+	rawZSet, exists := s.data[key]
+	if !exists {
+		zset = make(memoryZSet, len(members))
+		s.data[key] = zset
+	} else {
+		var ok bool
+		zset, ok = rawZSet.(memoryZSet)
+		if !ok {
+			return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+		}
+	}
+
+	for _, member := range members {
+		if math.IsNaN(member.Score) {
+			return fmt.Errorf("score of member '%s' is not a number", member.Member)
+		}
+		zset[member.Member] = member.Score
+	}
+	return nil
+}
+
+// ZRangeByScore returns the members of a sorted set within the score range.
+func (s *MemoryStore) ZRangeByScore(key string, min, max float64) ([]ZMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rawZSet, exists := s.data[key]
+	if !exists || s.expiredLocked(key) {
+		return []ZMember{}, nil
+	}
+	zset, ok := rawZSet.(memoryZSet)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	result := make([]ZMember, 0, len(zset))
+	for member, score := range zset {
+		if score >= min && score <= max {
+			result = append(result, ZMember{Score: score, Member: member})
+		}
+	}
+	slices.SortFunc(result, func(a, b ZMember) int {
+		if c := cmp.Compare(a.Score, b.Score); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Member, b.Member)
+	})
+	return result, nil
+}
+
+// ZRemRangeByScore removes the members of a sorted set within the score range.
+// Like Redis, an emptied sorted set is deleted.
+func (s *MemoryStore) ZRemRangeByScore(key string, min, max float64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropExpiredLocked(key)
+
+	rawZSet, exists := s.data[key]
+	if !exists {
+		return 0, nil
+	}
+	zset, ok := rawZSet.(memoryZSet)
+	if !ok {
+		return 0, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	var removed int64
+	for member, score := range zset {
+		if score >= min && score <= max {
+			delete(zset, member)
+			removed++
+		}
+	}
+	if len(zset) == 0 {
+		delete(s.data, key)
+		delete(s.expires, key)
+	}
+	return removed, nil
+}
+
 // --- Pub/Sub operations ---
 
 // memorySubscription implements the Subscription interface for the in-memory store.
@@ -536,6 +780,7 @@ func (s *MemoryStore) Clear() error {
 
 	// Clear all data
 	s.data = make(map[string]any)
+	s.expires = make(map[string]int64)
 
 	return nil
 }
@@ -580,6 +825,11 @@ func (s *MemoryStore) performCleanup() {
 			}
 		}
 	}
+	for key, expiresAt := range s.expires {
+		if now > expiresAt {
+			expiredKeys = append(expiredKeys, key)
+		}
+	}
 	s.mu.RUnlock()
 
 	// Second pass: delete expired keys (write lock)
@@ -588,6 +838,12 @@ func (s *MemoryStore) performCleanup() {
 		s.mu.Lock()
 		for _, key := range expiredKeys {
 			// Double-check expiration under write lock to avoid race conditions
+			if expiresAt, ok := s.expires[key]; ok && now > expiresAt {
+				delete(s.data, key)
+				delete(s.expires, key)
+				deletedCount++
+				continue
+			}
 			if rawItem, exists := s.data[key]; exists {
 				if item, ok := rawItem.(memoryStoreItem); ok {
 					if item.expiresAt > 0 && now > item.expiresAt {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"sync"
 	"time"
 
@@ -76,11 +78,94 @@ func (s *RedisStore) Exists(key string) (bool, error) {
 	return val > 0, nil
 }
 
+// Expire sets the TTL of an existing key, or removes it when ttl is 0.
+func (s *RedisStore) Expire(key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return s.client.Persist(context.Background(), s.prefixKey(key)).Err()
+	}
+	return s.client.PExpire(context.Background(), s.prefixKey(key), ttl).Err()
+}
+
 // SetNX sets a key-value pair in Redis if the key does not already exist.
 func (s *RedisStore) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
 	return s.client.SetNX(context.Background(), s.prefixKey(key), value, ttl).Result()
 }
 
+// compareAndSwapScript sets KEYS[1] to ARGV[2] if its value equals ARGV[1], or
+// if it is missing when ARGV[3] is "1". ARGV[4] is the TTL in milliseconds.
+var compareAndSwapScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if ARGV[3] == '1' then
+	if current then return 0 end
+elseif current ~= ARGV[1] then
+	return 0
+end
+local ttl = tonumber(ARGV[4])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// compareAndDeleteScript deletes KEYS[1] if its value equals ARGV[1].
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// incrByWithTTLScript increments KEYS[1] by ARGV[1] and sets a TTL of ARGV[2]
+// milliseconds when the key has none.
+var incrByWithTTLScript = redis.NewScript(`
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return value
+`)
+
+// CompareAndSwap atomically replaces the value of key if it equals oldValue.
+func (s *RedisStore) CompareAndSwap(key string, oldValue, newValue []byte, ttl time.Duration) (bool, error) {
+	expectMissing := "0"
+	if oldValue == nil {
+		expectMissing = "1"
+	}
+	swapped, err := compareAndSwapScript.Run(context.Background(), s.client,
+		[]string{s.prefixKey(key)}, oldValue, newValue, expectMissing, ttlMillis(ttl)).Int()
+	if err != nil {
+		return false, err
+	}
+	return swapped == 1, nil
+}
+
+// CompareAndDelete atomically deletes key if its value equals value.
+func (s *RedisStore) CompareAndDelete(key string, value []byte) (bool, error) {
+	deleted, err := compareAndDeleteScript.Run(context.Background(), s.client,
+		[]string{s.prefixKey(key)}, value).Int()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
+
+// IncrByWithTTL atomically increments the counter at key, setting ttl when the counter has no expiry.
+func (s *RedisStore) IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error) {
+	return incrByWithTTLScript.Run(context.Background(), s.client,
+		[]string{s.prefixKey(key)}, incr, ttlMillis(ttl)).Int64()
+}
+
+// ttlMillis converts a TTL to milliseconds, rounding positive sub-millisecond TTLs up.
+func ttlMillis(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return max(ttl.Milliseconds(), 1)
+}
+
 // Close closes the Redis client connection.
 func (s *RedisStore) Close() error {
 	return s.client.Close()
@@ -142,6 +227,50 @@ func (s *RedisStore) SCard(key string) (int64, error) {
 	return s.client.SCard(context.Background(), s.prefixKey(key)).Result()
 }
 
+// --- SORTED SET operations ---
+
+func (s *RedisStore) ZAdd(key string, members ...ZMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+	zs := make([]redis.Z, len(members))
+	for i, member := range members {
+		zs[i] = redis.Z{Score: member.Score, Member: member.Member}
+	}
+	return s.client.ZAdd(context.Background(), s.prefixKey(key), zs...).Err()
+}
+
+func (s *RedisStore) ZRangeByScore(key string, min, max float64) ([]ZMember, error) {
+	zs, err := s.client.ZRangeByScoreWithScores(context.Background(), s.prefixKey(key), &redis.ZRangeBy{
+		Min: formatScore(min),
+		Max: formatScore(max),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	members := make([]ZMember, len(zs))
+	for i, z := range zs {
+		members[i] = ZMember{Score: z.Score, Member: fmt.Sprint(z.Member)}
+	}
+	return members, nil
+}
+
+func (s *RedisStore) ZRemRangeByScore(key string, min, max float64) (int64, error) {
+	return s.client.ZRemRangeByScore(context.Background(), s.prefixKey(key), formatScore(min), formatScore(max)).Result()
+}
+
+// formatScore formats a score as a Redis range bound.
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+}
+
 // --- Pipeliner implementation ---
 
 type redisPipeliner struct {
@@ -20,6 +20,12 @@ type Subscription interface {
 	Close() error
 }
 
+// ZMember is a member of a sorted set together with its score.
+type ZMember struct {
+	Score  float64
+	Member string
+}
+
 // Store is a generic key-value store interface.
 type Store interface {
 	// Set stores a key-value pair with an optional TTL.
@@ -37,9 +43,29 @@ type Store interface {
 	// Exists checks if a key exists in the store.
 	Exists(key string) (bool, error)
 
+	// Expire sets the TTL of an existing key of any type, replacing any previous
+	// one; a ttl of 0 removes the expiry. Missing keys are ignored. Sorted sets
+	// used as sliding windows should be given a TTL of about the window length.
+	Expire(key string, ttl time.Duration) error
+
 	// SetNX sets a key-value pair if the key does not already exist.
 	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
 
+	// CompareAndSwap replaces the value of key with newValue only if its current
+	// value equals oldValue, and reports whether it did. A nil oldValue means the
+	// key must not exist. The TTL of the key is replaced by ttl (0 for no expiry).
+	CompareAndSwap(key string, oldValue, newValue []byte, ttl time.Duration) (bool, error)
+
+	// CompareAndDelete deletes key only if its current value equals value, and
+	// reports whether it did.
+	CompareAndDelete(key string, value []byte) (bool, error)
+
+	// IncrByWithTTL atomically increments the integer counter at key by incr and
+	// returns the new value. A missing or expired key starts at 0. ttl is applied
+	// only when the counter has no expiry yet, so a counter created by this call
+	// expires ttl after its first increment (a fixed window).
+	IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error)
+
 	// HASH operations
 	HSet(key string, values map[string]any) error
 	HGetAll(key string) (map[string]string, error)
@@ -58,6 +84,16 @@ type Store interface {
 	// Returns 0 with nil error for missing keys to maintain consistency across implementations.
 	SCard(key string) (int64, error)
 
+	// SORTED SET operations
+	// ZAdd adds members or updates the scores of existing members.
+	ZAdd(key string, members ...ZMember) error
+	// ZRangeByScore returns members with min <= score <= max ordered by score,
+	// ties ordered by member. Use math.Inf for unbounded ranges.
+	ZRangeByScore(key string, min, max float64) ([]ZMember, error)
+	// ZRemRangeByScore removes members with min <= score <= max and returns how
+	// many were removed.
+	ZRemRangeByScore(key string, min, max float64) (int64, error)
+
 	// Close closes the store and releases any underlying resources.
 	Close() error
 
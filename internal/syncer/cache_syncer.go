@@ -3,6 +3,7 @@ package syncer
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"gpt-load/internal/store"
@@ -24,6 +25,7 @@ type CacheSyncer[T any] struct {
 	stopChan    chan struct{}
 	wg          sync.WaitGroup
 	afterReload func(newValue T)
+	subscribed  atomic.Bool // Whether the invalidation subscription is currently active
 }
 
 // NewCacheSyncer creates and initializes a new CacheSyncer.
@@ -72,6 +74,12 @@ func (s *CacheSyncer[T]) Reload() error {
 	return s.reload()
 }
 
+// Subscribed reports whether the syncer is currently listening for invalidations.
+// While it is not, changes made on other instances are not picked up.
+func (s *CacheSyncer[T]) Subscribed() bool {
+	return s.subscribed.Load()
+}
+
 // Stop gracefully shuts down the syncer's background goroutine.
 func (s *CacheSyncer[T]) Stop() {
 	close(s.stopChan)
@@ -130,6 +138,7 @@ func (s *CacheSyncer[T]) listenForUpdates() {
 		}
 
 		s.logger.Debugf("subscribed to channel: %s", s.channelName)
+		s.subscribed.Store(true)
 
 	subscriberLoop:
 		for {
@@ -144,12 +153,14 @@ func (s *CacheSyncer[T]) listenForUpdates() {
 					s.logger.Errorf("failed to reload cache after notification: %v", err)
 				}
 			case <-s.stopChan:
+				s.subscribed.Store(false)
 				if err := subscription.Close(); err != nil {
 					s.logger.Errorf("failed to close subscription: %v", err)
 				}
 				return
 			}
 		}
+		s.subscribed.Store(false)
 
 		// Before retrying, ensure the old subscription is closed.
 		if err := subscription.Close(); err != nil {
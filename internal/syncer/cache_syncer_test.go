@@ -70,10 +70,38 @@ func (m *mockStore) SCard(key string) (int64, error) {
 	return 0, nil
 }
 
+func (m *mockStore) CompareAndSwap(key string, oldValue, newValue []byte, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (m *mockStore) CompareAndDelete(key string, value []byte) (bool, error) {
+	return true, nil
+}
+
+func (m *mockStore) IncrByWithTTL(key string, incr int64, ttl time.Duration) (int64, error) {
+	return incr, nil
+}
+
+func (m *mockStore) ZAdd(key string, members ...store.ZMember) error {
+	return nil
+}
+
+func (m *mockStore) ZRangeByScore(key string, min, max float64) ([]store.ZMember, error) {
+	return nil, nil
+}
+
+func (m *mockStore) ZRemRangeByScore(key string, min, max float64) (int64, error) {
+	return 0, nil
+}
+
 func (m *mockStore) Exists(key string) (bool, error) {
 	return false, nil
 }
 
+func (m *mockStore) Expire(key string, ttl time.Duration) error {
+	return nil
+}
+
 func (m *mockStore) HSet(key string, values map[string]any) error {
 	return nil
 }
@@ -332,6 +360,23 @@ func TestStop(t *testing.T) {
 	assert.Equal(t, "test data", result)
 }
 
+// TestSubscribed tests that the subscription state follows the listener
+func TestSubscribed(t *testing.T) {
+	store := newMockStore()
+	loader := func() (string, error) {
+		return "test data", nil
+	}
+
+	logger := logrus.NewEntry(logrus.New())
+	syncer, err := NewCacheSyncer(loader, store, "test-channel", logger, nil)
+	require.NoError(t, err)
+
+	assert.Eventually(t, syncer.Subscribed, time.Second, 10*time.Millisecond)
+
+	syncer.Stop()
+	assert.False(t, syncer.Subscribed())
+}
+
 // TestConcurrentAccess tests concurrent access to cache
 func TestConcurrentAccess(t *testing.T) {
 	store := newMockStore()
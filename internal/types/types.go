@@ -25,6 +25,7 @@ type SystemSettings struct {
 	RequestLogRetentionDays        int    `json:"request_log_retention_days" default:"7" name:"config.log_retention_days" category:"config.category.basic" desc:"config.log_retention_days_desc" validate:"required,min=0"`
 	RequestLogWriteIntervalMinutes int    `json:"request_log_write_interval_minutes" default:"1" name:"config.log_write_interval" category:"config.category.basic" desc:"config.log_write_interval_desc" validate:"required,min=0"`
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"config.enable_request_body_logging" category:"config.category.basic" desc:"config.enable_request_body_logging_desc"`
+	LogRedactionRules              string `json:"log_redaction_rules" default:"" name:"config.log_redaction_rules" category:"config.category.basic" desc:"config.log_redaction_rules_desc"`
 
 	// Request settings
 	RequestTimeout          int    `json:"request_timeout" default:"1200" name:"config.request_timeout" category:"-" desc:"config.request_timeout_desc" validate:"required,min=1"`
@@ -65,6 +66,8 @@ type ServerConfig struct {
 	Port                    int    `json:"port"`
 	Host                    string `json:"host"`
 	IsMaster                bool   `json:"is_master"`
+	LeaderElection          bool   `json:"leader_election"` // Elect the master through Redis instead of IS_SLAVE
+	ConfigDir               string `json:"config_dir"`      // Declarative configuration applied by the master at startup
 	ReadTimeout             int    `json:"read_timeout"`
 	WriteTimeout            int    `json:"write_timeout"`
 	IdleTimeout             int    `json:"idle_timeout"`
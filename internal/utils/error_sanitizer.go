@@ -7,12 +7,9 @@ import (
 )
 
 // Compiled regex patterns for sensitive data detection.
-// Pre-compiled for performance in hot paths.
+// Pre-compiled for performance in hot paths. API key and email patterns are
+// shared and live in regex_patterns.go.
 var (
-	// API key patterns (OpenAI sk-..., Anthropic, etc.)
-	// Matches standalone API keys like sk-1234567890abcdefghij or sk-proj-abcdefghij...
-	// Per AI review: allow hyphens after sk- to cover formats like sk-proj-...
-	apiKeyStandalonePattern = regexp.MustCompile(`\bsk-[a-zA-Z0-9][a-zA-Z0-9-]{19,}\b`)
 	// Bearer token pattern (avoid spanning newlines)
 	bearerPattern = regexp.MustCompile(`(?i)\bBearer[ \t]+[a-zA-Z0-9\-._~+/]+=*`)
 	// Authorization header pattern (redact entire value on the line)
@@ -25,11 +22,6 @@ var (
 	secretQueryPattern = regexp.MustCompile(`(?i)([?&](?:key|api[_\-.]?key|x[_\-.]?api[_\-.]?key|x[_\-.]?goog[_\-.]?api[_\-.]?key|openai[_\-.]?api[_\-.]?key|subscription[_\-.]?key|access[_\-.]?token|refresh[_\-.]?token|client[_\-.]?secret|auth|authorization|secret|password|credential)[^=&\s"'<>]*=)[^&\s"'<>]+`)
 	// Sensitive key=value fragments sometimes appear without a URL prefix in upstream error messages.
 	secretAssignmentPattern = regexp.MustCompile(`(?i)(\b(?:api[_\-.]?key|x[_\-.]?api[_\-.]?key|x[_\-.]?goog[_\-.]?api[_\-.]?key|openai[_\-.]?api[_\-.]?key|subscription[_\-.]?key|access[_\-.]?token|refresh[_\-.]?token|client[_\-.]?secret|authorization|secret|password|credential)=)[^&\s"'<>]+`)
-	// Email pattern (basic PII)
-	emailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
-	// Per AI review: add cloud provider key patterns for broader coverage
-	// AWS Access Key ID pattern (starts with AKIA, ABIA, ACCA, ASIA)
-	awsKeyPattern = regexp.MustCompile(`\b(AKIA|ABIA|ACCA|ASIA)[A-Z0-9]{16}\b`)
 	// AWS Secret Access Key pattern (40 character base64-like string)
 	awsSecretPattern = regexp.MustCompile(`(?i)(aws_secret_access_key|aws_secret_key)\s*[=:]\s*[A-Za-z0-9/+=]{40}`)
 )
@@ -78,7 +70,7 @@ func SanitizeErrorBody(body string) string {
 	result = secretAssignmentPattern.ReplaceAllString(result, "${1}[REDACTED]")
 
 	// Redact standalone API keys (sk-...)
-	result = apiKeyStandalonePattern.ReplaceAllString(result, "[REDACTED_API_KEY]")
+	result = APIKeyPattern.ReplaceAllString(result, "[REDACTED_API_KEY]")
 
 	// Redact Bearer tokens
 	result = bearerPattern.ReplaceAllString(result, "Bearer [REDACTED]")
@@ -87,11 +79,11 @@ func SanitizeErrorBody(body string) string {
 	result = authHeaderPattern.ReplaceAllString(result, "Authorization: [REDACTED]")
 
 	// Redact email addresses
-	result = emailPattern.ReplaceAllString(result, "[REDACTED_EMAIL]")
+	result = EmailPattern.ReplaceAllString(result, "[REDACTED_EMAIL]")
 
 	// Per AI review: redact cloud provider keys for broader coverage
 	// Redact AWS Access Key IDs
-	result = awsKeyPattern.ReplaceAllString(result, "[REDACTED_AWS_KEY]")
+	result = AWSAccessKeyPattern.ReplaceAllString(result, "[REDACTED_AWS_KEY]")
 	// Redact AWS Secret Access Keys
 	result = awsSecretPattern.ReplaceAllString(result, "[REDACTED_AWS_SECRET]")
 
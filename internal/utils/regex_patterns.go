@@ -13,3 +13,54 @@ var (
 	// Allowed characters: alphanumeric, underscore, hyphen, dot, slash, plus, equals, and colon.
 	ValidKeyCharsPattern = regexp.MustCompile(`^[a-zA-Z0-9_\-./+=:]+$`)
 )
+
+// Pre-compiled patterns for sensitive data detection, shared by the error
+// sanitizer and the request log redaction rules.
+var (
+	// APIKeyPattern matches standalone OpenAI-style keys like sk-... or sk-proj-...
+	APIKeyPattern = regexp.MustCompile(`\bsk-[a-zA-Z0-9][a-zA-Z0-9-]{19,}\b`)
+
+	// AWSAccessKeyPattern matches AWS access key IDs (AKIA, ABIA, ACCA, ASIA prefixes).
+	AWSAccessKeyPattern = regexp.MustCompile(`\b(AKIA|ABIA|ACCA|ASIA)[A-Z0-9]{16}\b`)
+
+	// APIKeyLikePattern matches common provider token formats beyond sk-...:
+	// Google API keys, GitHub tokens, Slack tokens and generic pk-/rk- keys.
+	APIKeyLikePattern = regexp.MustCompile(`\b(?:AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9-]{10,}|(?:pk|rk)-[a-zA-Z0-9][a-zA-Z0-9_-]{19,})\b`)
+
+	// EmailPattern matches email addresses (basic PII).
+	EmailPattern = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+
+	// PhonePattern matches phone numbers written with an international prefix
+	// (e.g. +86 13812345678), NANP-style separators such as (555) 123-4567, or
+	// mainland China mobiles grouped as 138-1234-5678. Bare digit runs are
+	// intentionally not matched to avoid hitting IDs, timestamps and counts.
+	PhonePattern = regexp.MustCompile(`\+\d{1,3}[ .-]?\(?\d{1,4}\)?(?:[ .-]?\d{2,4}){2,4}\b|\(\d{3}\)[ .-]?\d{3}[ .-]\d{4}\b|\b\d{3}[.-]\d{3}[.-]\d{4}\b|\b1[3-9]\d[ -]\d{4}[ -]\d{4}\b`)
+
+	// CreditCardPattern matches 13-19 digit card number candidates, optionally
+	// grouped by spaces or hyphens. Use IsLuhnValid to filter false positives.
+	CreditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// IsLuhnValid reports whether the digits in s pass the Luhn checksum.
+// Non-digit characters such as spaces and hyphens are ignored.
+func IsLuhnValid(s string) bool {
+	sum, count := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		count++
+	}
+	return count >= 13 && sum%10 == 0
+}
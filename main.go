@@ -41,6 +41,30 @@ func runCommand() {
 	switch command {
 	case "migrate-keys":
 		commands.RunMigrateKeys(args)
+	case "plan":
+		commands.RunPlan(args)
+	case "apply":
+		commands.RunApply(args)
+	case "groups":
+		commands.RunGroups(args)
+	case "keys":
+		commands.RunKeys(args)
+	case "settings":
+		commands.RunSettings(args)
+	case "logs":
+		commands.RunLogs(args)
+	case "hub":
+		commands.RunHub(args)
+	case "export":
+		commands.RunExport(args)
+	case "import":
+		commands.RunImport(args)
+	case "doctor":
+		commands.RunDoctor(args)
+	case "db":
+		commands.RunDB(args)
+	case "encryption":
+		commands.RunEncryption(args)
 	case "help", "-h", "--help":
 		printHelp()
 	default:
@@ -60,9 +84,22 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("Available Commands:")
 	fmt.Println("  migrate-keys    Migrate encryption keys")
+	fmt.Println("  plan            Show changes needed to match a configuration directory")
+	fmt.Println("  apply           Apply a configuration directory to the database")
+	fmt.Println("  groups          List, inspect, enable or disable groups")
+	fmt.Println("  keys            Import, export, validate or restore the keys of a group")
+	fmt.Println("  settings        Get or set system settings")
+	fmt.Println("  logs            Tail request logs")
+	fmt.Println("  hub             Create or revoke Hub access keys")
+	fmt.Println("  export          Export a system snapshot")
+	fmt.Println("  import          Import a system snapshot")
+	fmt.Println("  doctor          Diagnose encryption, schema and store problems")
+	fmt.Println("  db              Back up, restore or migrate the database")
+	fmt.Println("  encryption      Show or rotate the encryption data keys")
 	fmt.Println("  help            Display this help message")
 	fmt.Println()
 	fmt.Println("Use 'gpt-load <command> --help' for more information about a command.")
+	fmt.Println("Admin commands work on the local database, or on a running node with --server.")
 }
 
 // runServer run App Server
@@ -2,6 +2,7 @@ import type {
   ChartData,
   DashboardStatsResponse,
   DashboardTokenUsageResponse,
+  ExperimentComparison,
   GroupListItem,
 } from "@/types/models";
 import http from "@/utils/http";
@@ -53,6 +54,14 @@ export const getDashboardTokenUsage = (
   });
 };
 
+/**
+ * Compare the control and candidate variants of a group experiment
+ * @param experimentId Experiment ID
+ */
+export const getExperimentComparison = (experimentId: number) => {
+  return http.get<ExperimentComparison>(`/dashboard/experiments/${experimentId}`);
+};
+
 /**
  * Get group list for filters
  */
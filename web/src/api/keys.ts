@@ -3,6 +3,9 @@ import type {
   APIKey,
   Group,
   GroupConfigOption,
+  GroupExplainOptions,
+  GroupExplainResult,
+  GroupExperiment,
   GroupListItem,
   GroupStatsResponse,
   KeyStatus,
@@ -82,6 +85,21 @@ export const keysApi = {
     return res.data;
   },
 
+  // Explain how the proxy pipeline transforms a client request, without contacting upstream
+  async explainGroupRequest(
+    groupId: number,
+    options: GroupExplainOptions
+  ): Promise<GroupExplainResult> {
+    const res = await http.post(`/groups/${groupId}/explain`, options);
+    return res.data;
+  },
+
+  // List the configuration experiments of a group, newest first
+  async listGroupExperiments(groupId: number): Promise<GroupExperiment[]> {
+    const res = await http.get(`/groups/${groupId}/experiments`);
+    return res.data || [];
+  },
+
   // Get group configurable options
   async getGroupConfigOptions(): Promise<GroupConfigOption[]> {
     const res = await http.get("/groups/config-options");
@@ -1,7 +1,20 @@
 import i18n from "@/locales";
-import type { ApiResponse, Group, LogFilter, LogsResponse } from "@/types/models";
+import type {
+  ApiResponse,
+  Group,
+  LogFilter,
+  LogReplayOptions,
+  LogReplayResult,
+  LogsResponse,
+} from "@/types/models";
 import http from "@/utils/http";
 
+export interface LogExportOptions {
+  format: "csv" | "jsonl" | "columnar";
+  include_bodies?: boolean;
+  mask_keys?: boolean;
+}
+
 export const logApi = {
   // Get logs list
   getLogs: (params: LogFilter): Promise<ApiResponse<LogsResponse>> => {
@@ -15,32 +28,67 @@ export const logApi = {
 
   // Export logs
   exportLogs: (params: Omit<LogFilter, "page" | "page_size">) => {
+    downloadExport("/logs/export", params, `logs-${Date.now()}.csv`);
+  },
+
+  // Export every log column as CSV, JSONL or columnar JSON
+  exportFullLogs: (params: Omit<LogFilter, "page" | "page_size">, options: LogExportOptions) => {
+    const extension = options.format === "columnar" ? "columnar.jsonl" : options.format;
+    downloadExport(
+      "/logs/export/full",
+      { ...params, ...options },
+      `logs-full-${Date.now()}.${extension}`
+    );
+  },
+
+  // Resend a logged request through the proxy, or dry-run it to see the upstream request
+  replayLog: (id: string, options: LogReplayOptions): Promise<ApiResponse<LogReplayResult>> => {
+    return http.post(`/logs/${id}/replay`, options);
+  },
+
+  // Follow newly recorded logs matching the filters over server-sent events
+  streamLogs: (params: Omit<LogFilter, "page" | "page_size">): EventSource | null => {
     const authKey = localStorage.getItem("authKey");
     if (!authKey) {
       window.$message.error(i18n.global.t("auth.noAuthKeyFound"));
-      return;
+      return null;
     }
-
-    const queryParams = new URLSearchParams(
-      Object.entries(params).reduce(
-        (acc, [key, value]) => {
-          if (value !== undefined && value !== null && value !== "") {
-            acc[key] = String(value);
-          }
-          return acc;
-        },
-        {} as Record<string, string>
-      )
-    );
+    const queryParams = buildQueryParams(params);
     queryParams.append("key", authKey);
-
-    const url = `${http.defaults.baseURL}/logs/export?${queryParams.toString()}`;
-
-    const link = document.createElement("a");
-    link.href = url;
-    link.setAttribute("download", `logs-${Date.now()}.csv`);
-    document.body.appendChild(link);
-    link.click();
-    document.body.removeChild(link);
+    return new EventSource(`${http.defaults.baseURL}/logs/stream?${queryParams.toString()}`);
   },
 };
+
+function buildQueryParams(params: Record<string, unknown>): URLSearchParams {
+  return new URLSearchParams(
+    Object.entries(params).reduce(
+      (acc, [key, value]) => {
+        if (value !== undefined && value !== null && value !== "") {
+          acc[key] = String(value);
+        }
+        return acc;
+      },
+      {} as Record<string, string>
+    )
+  );
+}
+
+function downloadExport(path: string, params: Record<string, unknown>, filename: string) {
+  const authKey = localStorage.getItem("authKey");
+  if (!authKey) {
+    window.$message.error(i18n.global.t("auth.noAuthKeyFound"));
+    return;
+  }
+
+  const queryParams = buildQueryParams(params);
+  queryParams.append("key", authKey);
+
+  const url = `${http.defaults.baseURL}${path}?${queryParams.toString()}`;
+
+  const link = document.createElement("a");
+  link.href = url;
+  link.setAttribute("download", filename);
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+}
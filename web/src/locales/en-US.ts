@@ -194,6 +194,50 @@ export default {
     toConfirmDeletion: " and all its keys. Please enter the group name to confirm:",
     confirmDelete: "Confirm Delete",
     copyGroup: "Copy Group",
+    explainGroup: "Explain Request Pipeline",
+    explainTitle: "Explain Request Pipeline - {name}",
+    explainHint:
+      "Runs a sample client request through this group's transforms and shows what would be sent upstream. Nothing is sent and caches are skipped.",
+    explainRequest: "Request",
+    explainHeaders: "Headers",
+    explainBody: "Body",
+    explainRun: "Explain",
+    explainInvalidBody: "Request body must be valid JSON",
+    explainUpstream: "Upstream Request",
+    explainAnsweredLocally:
+      "The request was answered by the proxy with status {status} and did not reach upstream",
+    explainStagesTitle: "Pipeline Stages",
+    explainChanged: "Changed",
+    explainStages: {
+      client_request: "Client request",
+      path_rewrite: "Path rewrite",
+      model_mapping: "Model mapping",
+      param_overrides: "Parameter overrides",
+      cc_conversion: "Claude conversion",
+      codex_conversion: "Codex conversion",
+      parallel_tool_calls: "Parallel tool calls",
+      function_call_rewrite: "Function call injection",
+      stream_override: "Stream override",
+      responses_include: "Responses include",
+      stream_mode: "Stream mode",
+      model_redirect: "Model redirect",
+    },
+    experiments: "Experiments",
+    experimentsTitle: "Experiments - {name}",
+    experimentsEmpty: "This group has no experiments",
+    experimentTraffic: "{percentage}% to candidate",
+    experimentVariant: "Variant",
+    experimentControl: "Control",
+    experimentCandidate: "Candidate",
+    experimentRequests: "Requests",
+    experimentSuccessRate: "Success Rate",
+    experimentAvgLatency: "Avg Latency",
+    experimentAvgTokens: "Avg Tokens",
+    experimentStatus: {
+      running: "Running",
+      promoted: "Promoted",
+      aborted: "Aborted",
+    },
     groupName: "Group Name",
     groupDescription: "Group Description",
     groupEndpoint: "Group Endpoint",
@@ -420,6 +464,22 @@ export default {
     codexAffinityMaxRetries: "Codex Affinity Max Retries",
     codexAffinityMaxRetriesHint:
       "Total affinity attempts including the first request. Default 5 = 1 initial request + up to 4 affinity retries; sub-group failover begins only after this budget is exhausted and remains limited by Max Retries, while the other two fields count only failovers or key retries after the initial request.",
+    sessionAffinity: "Session Affinity",
+    sessionAffinityHint:
+      "Keeps requests from the same client session on a stable sub-group and/or key for prompt-cache locality. Bindings are shared through the store across nodes. Codex Affinity takes precedence when enabled.",
+    sessionAffinitySource: "Session ID Source",
+    sessionAffinitySourceAuto: "Auto (prompt_cache_key, metadata.user_id, cachedContent, user)",
+    sessionAffinitySourceHeader: "Request header",
+    sessionAffinitySourceJsonPath: "JSON path in request body",
+    sessionAffinitySourceProxyKey: "Proxy key",
+    sessionAffinityKey: "Header Name / JSON Path",
+    sessionAffinityKeyRequired: "Enter the header name or JSON path",
+    sessionAffinityScope: "Affinity Scope",
+    sessionAffinityScopeSubGroup: "Sub-group",
+    sessionAffinityScopeKey: "Key",
+    sessionAffinityScopeBoth: "Sub-group and key",
+    sessionAffinityTTL: "Binding TTL (seconds)",
+    sessionAffinityMaxEntries: "Max Bindings",
     precondition: "Precondition",
     preconditions: "Preconditions",
     preconditionMaxRequestSize: "Request Size Limit",
@@ -851,6 +911,8 @@ export default {
     retryRequest: "Retry Request",
     finalRequest: "Final Request",
     validationRequest: "Validation Request",
+    hedgeRequest: "Hedge Request",
+    cacheHitRequest: "Cache Hit",
     time: "Time",
     requestType: "Request Type",
     responseType: "Response Type",
@@ -865,7 +927,31 @@ export default {
     parentGroup: "Aggregate Group",
     parentGroupName: "Aggregate Group Name",
     errorMessage: "Error Message",
+    fullTextSearch: "Search bodies & errors",
+    searchMatches: "Search Matches",
+    replayRequest: "Replay",
+    replayTargetGroup: "Target Group",
+    replayDryRun: "Dry Run",
+    replayDryRunHint: "Show the transformed upstream request without sending it",
+    replayUpstreamRequest: "Upstream Request",
+    replayAggregateHint:
+      "Aggregate group: sub-group redirects and overrides are applied per attempt and are not shown.",
+    replayComparison: "Response Comparison",
+    replayOriginal: "Original",
+    replayNew: "Replay",
+    replayNoOriginalBody:
+      "The original response body was not logged; only the new response is shown.",
+    replayPreview: "Preview",
+    replaySend: "Send",
+    replayInvalidBody: "Request body must be valid JSON",
     exportLogs: "Export Keys",
+    exportFullLogs: "Export Logs",
+    liveTail: "Live tail",
+    liveTailStop: "Stop live tail",
+    exportFullCSV: "Full logs (CSV)",
+    exportFullJSONL: "Full logs (JSONL)",
+    exportFullColumnar: "Full logs (columnar JSON)",
+    exportFullJSONLWithBodies: "Full logs with bodies (JSONL)",
     totalRecords: "Total {total} records",
     currentPageRange: "Showing records {start}-{end}",
     currentPageNoRecords: "No records on this page",
@@ -932,6 +1018,8 @@ export default {
     minValueError: "Value cannot be less than {value}",
     inputNumber: "Please input number",
     inputContent: "Please input content",
+    logRedactionRulesPlaceholder:
+      "JSON rules with detectors, patterns and drop_paths, e.g. drop messages[*].content",
     noProxy: "No proxy",
     saveSettings: "Save Settings",
     saving: "Saving...",
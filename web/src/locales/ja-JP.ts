@@ -194,6 +194,48 @@ export default {
     toConfirmDeletion: " とすべてのキーを削除します。確認のためグループ名を入力してください：",
     confirmDelete: "削除を確認",
     copyGroup: "グループコピー",
+    explainGroup: "リクエストパイプラインの確認",
+    explainTitle: "リクエストパイプラインの確認 - {name}",
+    explainHint: "サンプルのクライアントリクエストをこのグループの変換処理に通し、上流に送信される内容を表示します。実際には送信されず、キャッシュも使用しません。",
+    explainRequest: "リクエスト",
+    explainHeaders: "ヘッダー",
+    explainBody: "本文",
+    explainRun: "確認",
+    explainInvalidBody: "リクエスト本文は有効なJSONである必要があります",
+    explainUpstream: "上流リクエスト",
+    explainAnsweredLocally: "リクエストはプロキシによりステータス {status} で応答され、上流には到達しませんでした",
+    explainStagesTitle: "パイプラインの各段階",
+    explainChanged: "変更あり",
+    explainStages: {
+      client_request: "クライアントリクエスト",
+      path_rewrite: "パス書き換え",
+      model_mapping: "モデルマッピング",
+      param_overrides: "パラメータ上書き",
+      cc_conversion: "Claude 変換",
+      codex_conversion: "Codex 変換",
+      parallel_tool_calls: "並列ツール呼び出し",
+      function_call_rewrite: "関数呼び出しの注入",
+      stream_override: "ストリーム上書き",
+      responses_include: "Responses include",
+      stream_mode: "ストリームモード",
+      model_redirect: "モデルリダイレクト",
+    },
+    experiments: "実験",
+    experimentsTitle: "実験 - {name}",
+    experimentsEmpty: "このグループには実験がありません",
+    experimentTraffic: "{percentage}% を候補へ",
+    experimentVariant: "バリアント",
+    experimentControl: "対照",
+    experimentCandidate: "候補",
+    experimentRequests: "リクエスト数",
+    experimentSuccessRate: "成功率",
+    experimentAvgLatency: "平均レイテンシ",
+    experimentAvgTokens: "平均トークン",
+    experimentStatus: {
+      running: "実行中",
+      promoted: "昇格済み",
+      aborted: "中止",
+    },
     groupName: "グループ名",
     groupDescription: "グループ説明",
     groupEndpoint: "グループエンドポイント",
@@ -420,6 +462,22 @@ export default {
     codexAffinityMaxRetries: "Codex アフィニティ最大リトライ",
     codexAffinityMaxRetriesHint:
       "初回リクエストを含むアフィニティの総試行回数。既定値 5 = 初回 1 回 + 最大 4 回のアフィニティ再試行。この回数を使い切ってからサブグループ切り替えに入り、切り替え回数は引き続き最大リトライ回数で制限されます。ほかの 2 項目は初回後のサブグループ切り替えまたはキー再試行のみを数えます。",
+    sessionAffinity: "セッションアフィニティ",
+    sessionAffinityHint:
+      "同じクライアントセッションのリクエストを安定したサブグループやキーに固定し、プロンプトキャッシュを活かします。バインディングはストア経由で複数ノード間で共有されます。Codex アフィニティが有効な場合はそちらが優先されます。",
+    sessionAffinitySource: "セッション ID の取得元",
+    sessionAffinitySourceAuto: "自動 (prompt_cache_key, metadata.user_id, cachedContent, user)",
+    sessionAffinitySourceHeader: "リクエストヘッダー",
+    sessionAffinitySourceJsonPath: "リクエストボディの JSON パス",
+    sessionAffinitySourceProxyKey: "プロキシキー",
+    sessionAffinityKey: "ヘッダー名 / JSON パス",
+    sessionAffinityKeyRequired: "ヘッダー名または JSON パスを入力してください",
+    sessionAffinityScope: "アフィニティ範囲",
+    sessionAffinityScopeSubGroup: "サブグループ",
+    sessionAffinityScopeKey: "キー",
+    sessionAffinityScopeBoth: "サブグループとキー",
+    sessionAffinityTTL: "バインディング TTL (秒)",
+    sessionAffinityMaxEntries: "最大バインディング数",
     precondition: "前提条件",
     preconditions: "前提条件",
     preconditionMaxRequestSize: "リクエストサイズ制限",
@@ -836,6 +894,8 @@ export default {
     retryRequest: "リトライリクエスト",
     finalRequest: "最終リクエスト",
     validationRequest: "検証リクエスト",
+    hedgeRequest: "ヘッジリクエスト",
+    cacheHitRequest: "キャッシュヒット",
     time: "時間",
     requestType: "リクエストタイプ",
     responseType: "レスポンスタイプ",
@@ -850,7 +910,29 @@ export default {
     parentGroup: "集約グループ",
     parentGroupName: "集約グループ名",
     errorMessage: "エラーメッセージ",
+    fullTextSearch: "本文とエラーを全文検索",
+    searchMatches: "検索一致",
+    replayRequest: "再送",
+    replayTargetGroup: "送信先グループ",
+    replayDryRun: "ドライラン",
+    replayDryRunHint: "変換後の上流リクエストを表示するだけで送信しません",
+    replayUpstreamRequest: "上流リクエスト",
+    replayAggregateHint: "集約グループ：サブグループのリダイレクトと上書きは試行ごとに適用されるため表示されません。",
+    replayComparison: "レスポンス比較",
+    replayOriginal: "元",
+    replayNew: "再送",
+    replayNoOriginalBody: "元のレスポンス本文は記録されていないため、新しいレスポンスのみ表示します。",
+    replayPreview: "プレビュー",
+    replaySend: "送信",
+    replayInvalidBody: "リクエスト本文は有効なJSONである必要があります",
     exportLogs: "キーのエクスポート",
+    exportFullLogs: "ログのエクスポート",
+    liveTail: "ライブ表示",
+    liveTailStop: "ライブ表示を停止",
+    exportFullCSV: "全ログ (CSV)",
+    exportFullJSONL: "全ログ (JSONL)",
+    exportFullColumnar: "全ログ (列指向 JSON)",
+    exportFullJSONLWithBodies: "ボディ付き全ログ (JSONL)",
     totalRecords: "合計 {total} 件",
     currentPageRange: "{start}-{end} 件を表示",
     currentPageNoRecords: "このページに記録はありません",
@@ -917,6 +999,8 @@ export default {
     minValueError: "値は{value}より小さくできません",
     inputNumber: "数値を入力してください",
     inputContent: "内容を入力してください",
+    logRedactionRulesPlaceholder:
+      "detectors、patterns、drop_paths を含む JSON ルール（例: messages[*].content を削除）",
     noProxy: "プロキシなし",
     saveSettings: "設定を保存",
     saving: "保存中...",
@@ -191,6 +191,48 @@ export default {
     toConfirmDeletion: " 下的所有密钥。请输入分组名称以确认：",
     confirmDelete: "确认删除",
     copyGroup: "复制分组",
+    explainGroup: "解析请求管道",
+    explainTitle: "解析请求管道 - {name}",
+    explainHint: "将示例客户端请求通过该分组的各项转换，并显示将发送到上游的内容。不会实际发送，也不会使用缓存。",
+    explainRequest: "请求",
+    explainHeaders: "请求头",
+    explainBody: "请求体",
+    explainRun: "解析",
+    explainInvalidBody: "请求体必须是有效的 JSON",
+    explainUpstream: "上游请求",
+    explainAnsweredLocally: "该请求由代理直接响应（状态码 {status}），未到达上游",
+    explainStagesTitle: "管道阶段",
+    explainChanged: "有变更",
+    explainStages: {
+      client_request: "客户端请求",
+      path_rewrite: "路径重写",
+      model_mapping: "模型映射",
+      param_overrides: "参数覆盖",
+      cc_conversion: "Claude 转换",
+      codex_conversion: "Codex 转换",
+      parallel_tool_calls: "并行工具调用",
+      function_call_rewrite: "函数调用注入",
+      stream_override: "流式覆盖",
+      responses_include: "Responses include",
+      stream_mode: "流式模式",
+      model_redirect: "模型重定向",
+    },
+    experiments: "实验",
+    experimentsTitle: "实验 - {name}",
+    experimentsEmpty: "该分组暂无实验",
+    experimentTraffic: "{percentage}% 流量进入候选",
+    experimentVariant: "变体",
+    experimentControl: "对照组",
+    experimentCandidate: "候选组",
+    experimentRequests: "请求数",
+    experimentSuccessRate: "成功率",
+    experimentAvgLatency: "平均延迟",
+    experimentAvgTokens: "平均 Token",
+    experimentStatus: {
+      running: "运行中",
+      promoted: "已推广",
+      aborted: "已中止",
+    },
     groupName: "分组名称",
     groupDescription: "分组描述",
     groupEndpoint: "分组端点",
@@ -412,6 +454,22 @@ export default {
     codexAffinityMaxRetries: "Codex 亲和最大重试",
     codexAffinityMaxRetriesHint:
       "包含首次请求的总尝试次数；默认 5 = 首次请求 1 次 + 最多 4 次亲和重试。该次数耗尽后才进入子组切换，切组次数仍受最大重试次数限制；另两个字段只计算首次请求后的切组或 Key 重试。",
+    sessionAffinity: "会话亲和",
+    sessionAffinityHint:
+      "让同一客户端会话的请求稳定落在同一子组和/或 Key 上，以利用提示词缓存。绑定关系通过存储在多节点间共享。启用 Codex 亲和力时以其为准。",
+    sessionAffinitySource: "会话标识来源",
+    sessionAffinitySourceAuto: "自动（prompt_cache_key、metadata.user_id、cachedContent、user）",
+    sessionAffinitySourceHeader: "请求头",
+    sessionAffinitySourceJsonPath: "请求体 JSON 路径",
+    sessionAffinitySourceProxyKey: "代理密钥",
+    sessionAffinityKey: "请求头名称 / JSON 路径",
+    sessionAffinityKeyRequired: "请输入请求头名称或 JSON 路径",
+    sessionAffinityScope: "亲和范围",
+    sessionAffinityScopeSubGroup: "子组",
+    sessionAffinityScopeKey: "Key",
+    sessionAffinityScopeBoth: "子组和 Key",
+    sessionAffinityTTL: "绑定有效期（秒）",
+    sessionAffinityMaxEntries: "最大绑定数",
     precondition: "前置条件",
     preconditions: "前置条件",
     preconditionMaxRequestSize: "请求大小限制",
@@ -808,6 +866,8 @@ export default {
     retryRequest: "重试请求",
     finalRequest: "最终请求",
     validationRequest: "密钥验证",
+    hedgeRequest: "对冲请求",
+    cacheHitRequest: "缓存命中",
     time: "时间",
     requestType: "请求类型",
     responseType: "响应类型",
@@ -822,7 +882,29 @@ export default {
     parentGroup: "聚合分组",
     parentGroupName: "聚合分组名",
     errorMessage: "错误信息",
+    fullTextSearch: "全文搜索请求/响应",
+    searchMatches: "搜索匹配",
+    replayRequest: "重放",
+    replayTargetGroup: "目标分组",
+    replayDryRun: "试运行",
+    replayDryRunHint: "仅显示转换后的上游请求，不实际发送",
+    replayUpstreamRequest: "上游请求",
+    replayAggregateHint: "聚合分组：子分组的模型重定向和参数覆盖在每次尝试时应用，此处不显示。",
+    replayComparison: "响应对比",
+    replayOriginal: "原始",
+    replayNew: "重放",
+    replayNoOriginalBody: "原始响应体未被记录，仅显示新的响应。",
+    replayPreview: "预览",
+    replaySend: "发送",
+    replayInvalidBody: "请求体必须是有效的 JSON",
     exportLogs: "导出密钥",
+    exportFullLogs: "导出日志",
+    liveTail: "实时日志",
+    liveTailStop: "停止实时日志",
+    exportFullCSV: "完整日志 (CSV)",
+    exportFullJSONL: "完整日志 (JSONL)",
+    exportFullColumnar: "完整日志 (列式 JSON)",
+    exportFullJSONLWithBodies: "含请求/响应体的完整日志 (JSONL)",
     totalRecords: "共 {total} 条记录",
     currentPageRange: "显示第 {start}-{end} 条",
     currentPageNoRecords: "当前页暂无记录",
@@ -889,6 +971,8 @@ export default {
     minValueError: "值不能小于{value}",
     inputNumber: "请输入数值",
     inputContent: "请输入内容",
+    logRedactionRulesPlaceholder:
+      "包含 detectors、patterns、drop_paths 的 JSON 规则，例如删除 messages[*].content",
     noProxy: "不使用代理",
     saveSettings: "保存设置",
     saving: "保存中...",
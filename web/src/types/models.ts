@@ -112,7 +112,7 @@ export interface ModelRedirectTarget {
 
 export interface ModelRedirectRuleV2 {
   targets: ModelRedirectTarget[];
-  fallback?: string[]; // P1 extension
+  fallback?: string[]; // Ordered models tried after the selected target fails
 }
 
 // V2 rules map: source model -> rule
@@ -300,12 +300,13 @@ export interface RequestLog {
   source_ip: string;
   status_code: number;
   request_path: string;
+  request_method?: string;
   duration_ms: number;
   error_message: string;
   user_agent: string;
   upstream_user_agent?: string;
   simulated_client_enabled?: boolean;
-  request_type: "retry" | "final" | "validation";
+  request_type: "retry" | "final" | "validation" | "hedge" | "cache_hit";
   group_name?: string;
   parent_group_name?: string;
   key_value?: string;
@@ -323,6 +324,80 @@ export interface RequestLog {
   cache_write_tokens?: number;
   thinking_tokens?: number;
   token_usage_source?: "" | "upstream" | "estimated";
+  search_snippets?: LogSearchSnippet[];
+}
+
+export interface LogReplayOptions {
+  group_name?: string;
+  model?: string;
+  path?: string;
+  body?: unknown;
+  dry_run?: boolean;
+}
+
+export interface LogReplayResponse {
+  status_code: number;
+  body: string;
+  truncated?: boolean;
+}
+
+export interface LogReplayDiffRow {
+  op: "equal" | "change" | "delete" | "insert";
+  left?: string;
+  right?: string;
+}
+
+export interface LogReplayResult {
+  group_name: string;
+  method: string;
+  path: string;
+  request_body: string;
+  dry_run: boolean;
+  upstream_path?: string;
+  upstream_body?: string;
+  is_stream: boolean;
+  aggregate?: boolean;
+  original?: LogReplayResponse;
+  replayed?: LogReplayResponse;
+  diff?: LogReplayDiffRow[];
+  duration_ms: number;
+}
+
+export interface GroupExplainOptions {
+  method?: string;
+  path: string;
+  headers?: Record<string, string>;
+  body?: unknown;
+}
+
+export interface GroupExplainStage {
+  name: string;
+  group: string;
+  method: string;
+  path: string;
+  body?: string;
+  changed: boolean;
+}
+
+export interface GroupExplainUpstream {
+  group: string;
+  method: string;
+  url: string;
+  headers: Record<string, string>;
+  body?: string;
+  is_stream: boolean;
+}
+
+export interface GroupExplainResult {
+  group_name: string;
+  stages: GroupExplainStage[];
+  upstream?: GroupExplainUpstream;
+  response?: LogReplayResponse;
+}
+
+export interface LogSearchSnippet {
+  field: "request_body" | "response_body" | "error_message";
+  text: string;
 }
 
 export interface Pagination {
@@ -349,9 +424,10 @@ export interface LogFilter {
   status_code?: number | null;
   source_ip?: string;
   error_contains?: string;
+  q?: string;
   start_time?: string | null;
   end_time?: string | null;
-  request_type?: "retry" | "final" | "validation";
+  request_type?: "retry" | "final" | "validation" | "hedge" | "cache_hit";
 }
 
 export interface DashboardStats {
@@ -417,6 +493,39 @@ export interface DashboardTokenUsageResponse {
   chart: ChartData;
 }
 
+export type GroupExperimentStatus = "running" | "promoted" | "aborted";
+
+export interface GroupExperiment {
+  id: number;
+  group_id: number;
+  name: string;
+  status: GroupExperimentStatus;
+  percentage: number;
+  proxy_keys: string;
+  session_affinity: boolean;
+  ended_at?: string;
+  promoted_revision?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ExperimentVariantStats {
+  variant: "control" | "candidate";
+  requests: number;
+  successes: number;
+  success_rate: number;
+  avg_duration_ms: number;
+  input_tokens: number;
+  output_tokens: number;
+  total_tokens: number;
+  avg_tokens: number;
+}
+
+export interface ExperimentComparison {
+  experiment: GroupExperiment;
+  variants: ExperimentVariantStats[];
+}
+
 // Chart dataset definition
 export interface ChartDataset {
   label: string;